@@ -98,6 +98,22 @@ func assembleConfdbSchema(assert assertionBase) (Assertion, error) {
 		return nil, err
 	}
 
+	if migrationsRaw, ok := bodyMap["migrations"]; ok {
+		migrations, err := confdb.ParseMigrations(migrationsRaw)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(migrations) > 0 && migrations[len(migrations)-1].Revision > assert.Revision() {
+			return nil, fmt.Errorf("cannot declare migration for revision %d in confdb-schema revision %d", migrations[len(migrations)-1].Revision, assert.Revision())
+		}
+
+		if err := confdb.ValidateMigrations(migrations, schema); err != nil {
+			return nil, err
+		}
+		confdbSchema.Migrations = migrations
+	}
+
 	timestamp, err := checkRFC3339Date(assert.headers, "timestamp")
 	if err != nil {
 		return nil, err
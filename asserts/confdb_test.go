@@ -27,6 +27,7 @@ import (
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/confdb"
 )
 
 type confdbSuite struct {
@@ -201,6 +202,68 @@ func (s *confdbSuite) TestAssembleAndSignChecksSchemaFormatFail(c *C) {
 	c.Assert(err, ErrorMatches, `assertion confdb-schema: JSON in body must be indented with 2 spaces and sort object entries by key`)
 }
 
+func (s *confdbSuite) TestAssembleMigrations(c *C) {
+	headers := map[string]any{
+		"authority-id": "brand-id1",
+		"account-id":   "brand-id1",
+		"name":         "my-network",
+		"revision":     "2",
+		"views": map[string]any{
+			"foo": map[string]any{
+				"rules": []any{
+					map[string]any{"request": "ssid", "storage": "wifi.name"},
+				},
+			},
+		},
+		"timestamp": s.ts.Format(time.RFC3339),
+	}
+
+	body := `{
+  "migrations": [
+    {
+      "revision": 2,
+      "rules": [
+        {
+          "from": "wifi.ssid",
+          "op": "rename",
+          "to": "wifi.name"
+        }
+      ]
+    }
+  ],
+  "storage": {
+    "schema": {
+      "wifi": {
+        "schema": {
+          "name": "string"
+        }
+      }
+    }
+  }
+}`
+	a, err := asserts.AssembleAndSignInTest(asserts.ConfdbSchemaType, headers, []byte(body), testPrivKey0)
+	c.Assert(err, IsNil)
+
+	migrations := a.(*asserts.ConfdbSchema).Schema().Migrations
+	c.Assert(migrations, HasLen, 1)
+	c.Check(migrations[0].Revision, Equals, 2)
+	c.Assert(migrations[0].Rules, HasLen, 1)
+	c.Check(migrations[0].Rules[0].Op, Equals, confdb.MigrationRename)
+	c.Check(confdb.JoinAccessors(migrations[0].Rules[0].From), Equals, "wifi.ssid")
+	c.Check(confdb.JoinAccessors(migrations[0].Rules[0].To), Equals, "wifi.name")
+
+	// migrations cannot be declared for future revisions
+	headers["revision"] = "1"
+	_, err = asserts.AssembleAndSignInTest(asserts.ConfdbSchemaType, headers, []byte(body), testPrivKey0)
+	c.Assert(err, ErrorMatches, `cannot assemble assertion confdb-schema: cannot declare migration for revision 2 in confdb-schema revision 1`)
+
+	// migrations must leave data in the storage schema
+	headers["revision"] = "2"
+	body = strings.Replace(body, `"to": "wifi.name"`, `"to": "wifi.other"`, 1)
+	_, err = asserts.AssembleAndSignInTest(asserts.ConfdbSchemaType, headers, []byte(body), testPrivKey0)
+	c.Assert(err, ErrorMatches, `cannot assemble assertion confdb-schema: invalid migration for revision 2: cannot rename "wifi.ssid" to "wifi.other": .*`)
+}
+
 type confdbCtrlSuite struct {
 	db *asserts.Database
 }
@@ -56,6 +56,9 @@ type AppInfo struct {
 	Active      bool             `json:"active,omitempty"`
 	CommonID    string           `json:"common-id,omitempty"`
 	Activators  []AppActivator   `json:"activators,omitempty"`
+	// OOMKills is how many processes of the service were killed for
+	// running out of the memory of its quota group.
+	OOMKills int `json:"oom-kills,omitempty"`
 }
 
 // IsService returns true if the application is a background daemon.
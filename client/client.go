@@ -670,6 +670,22 @@ type RefreshInfo struct {
 	Last     string `json:"last,omitempty"`
 	Hold     string `json:"hold,omitempty"`
 	Next     string `json:"next,omitempty"`
+	// Waves contains the refresh.waves setting.
+	Waves string `json:"waves,omitempty"`
+	// PendingWaves contains the snaps with updates in each of the waves
+	// of a staged auto-refresh which are still to be refreshed.
+	PendingWaves [][]string `json:"pending-waves,omitempty"`
+	// Windows contains the refresh windows of the snaps which have one.
+	Windows []RefreshWindow `json:"windows,omitempty"`
+}
+
+// RefreshWindow contains the refresh window of a snap.
+type RefreshWindow struct {
+	Snap string `json:"snap"`
+	// Window contains the refresh.windows.<snap> setting.
+	Window string `json:"window"`
+	// Next is the start of the next window.
+	Next string `json:"next,omitempty"`
 }
 
 // SysInfo holds system information
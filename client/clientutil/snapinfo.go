@@ -22,6 +22,7 @@
 package clientutil
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 
@@ -121,6 +122,9 @@ func ClientAppInfoNotes(app *client.AppInfo) string {
 	if seenDbus {
 		notes = append(notes, "dbus-activated")
 	}
+	if app.OOMKills != 0 {
+		notes = append(notes, fmt.Sprintf("oom-killed:%d", app.OOMKills))
+	}
 	if len(notes) == 0 {
 		return "-"
 	}
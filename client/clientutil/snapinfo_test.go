@@ -328,4 +328,9 @@ func (*cmdSuite) TestAppStatusNotes(c *C) {
 		},
 	}
 	c.Check(clientutil.ClientAppInfoNotes(&ai), Equals, "user,timer-activated,socket-activated,dbus-activated")
+	ai = client.AppInfo{
+		Daemon:   "simple",
+		OOMKills: 3,
+	}
+	c.Check(clientutil.ClientAppInfoNotes(&ai), Equals, "oom-killed:3")
 }
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 )
 
 func (c *Client) ConfdbGetViaView(viewID string, requests []string) (changeID string, err error) {
@@ -47,3 +48,115 @@ func (c *Client) ConfdbSetViaView(viewID string, requestValues map[string]any) (
 	endpoint := fmt.Sprintf("/v2/confdb/%s", viewID)
 	return c.doAsync("PUT", endpoint, nil, headers, bytes.NewReader(body))
 }
+
+// ConfdbAuthor identifies who made changes to a confdb.
+type ConfdbAuthor struct {
+	// Snap is the snap which made the changes, if they were made by a snap.
+	Snap string `json:"snap,omitempty"`
+	// UID is the user which made the changes, if they were made through the
+	// API.
+	UID *uint32 `json:"uid,omitempty"`
+}
+
+// ConfdbPathChange is a change to the value stored at a confdb storage path.
+type ConfdbPathChange struct {
+	Path string `json:"path"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// ConfdbHistoryEntry describes a transaction committed into a confdb.
+type ConfdbHistoryEntry struct {
+	ID      int                `json:"id"`
+	Time    time.Time          `json:"time"`
+	View    string             `json:"view,omitempty"`
+	Author  ConfdbAuthor       `json:"author"`
+	Changes []ConfdbPathChange `json:"changes"`
+}
+
+// ConfdbHistory returns the most recent transactions committed into the
+// confdb which the view belongs to, from the oldest to the most recent.
+func (c *Client) ConfdbHistory(viewID string) ([]*ConfdbHistoryEntry, error) {
+	query := url.Values{}
+	query.Set("history", "true")
+	endpoint := fmt.Sprintf("/v2/confdb/%s", viewID)
+
+	var entries []*ConfdbHistoryEntry
+	if _, err := c.doSync("GET", endpoint, query, nil, nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ConfdbRevert restores the data of the confdb to how it was before the
+// given transaction, through the view.
+func (c *Client) ConfdbRevert(viewID string, transaction int) (changeID string, err error) {
+	body, err := json.Marshal(map[string]any{
+		"action":      "revert",
+		"transaction": transaction,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	endpoint := fmt.Sprintf("/v2/confdb/%s", viewID)
+	return c.doAsync("POST", endpoint, nil, headers, bytes.NewReader(body))
+}
+
+// ConfdbViewChange describes a committed transaction which changed data
+// accessible through a watched view.
+type ConfdbViewChange struct {
+	Time    time.Time          `json:"time"`
+	Author  ConfdbAuthor       `json:"author"`
+	Changes []ConfdbPathChange `json:"changes"`
+	// Values holds the values of the watched requests after the transaction,
+	// or nil if none of them has any data left.
+	Values map[string]any `json:"values,omitempty"`
+}
+
+// ConfdbWatchStream iterates over the changes to a watched view.
+type ConfdbWatchStream struct {
+	seq *seqStream
+}
+
+// ConfdbWatch opens a long-lived stream of the changes committed to the
+// values of the requests through the view, or to the whole view if no
+// requests are given. The caller must call Close on the returned stream once
+// done with it.
+func (c *Client) ConfdbWatch(viewID string, requests []string) (*ConfdbWatchStream, error) {
+	query := url.Values{}
+	query.Set("watch", "true")
+	if len(requests) > 0 {
+		query.Set("keys", strings.Join(requests, ","))
+	}
+	endpoint := fmt.Sprintf("/v2/confdb/%s", viewID)
+
+	seq, err := c.openSeqStream(endpoint, query)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfdbWatchStream{seq: seq}, nil
+}
+
+// Next blocks until the next change is received and returns it. It returns
+// io.EOF once snapd ends the stream, and ErrStreamOverflow if snapd ended it
+// because changes were dropped while the caller was not keeping up.
+func (s *ConfdbWatchStream) Next() (*ConfdbViewChange, error) {
+	var record struct {
+		ConfdbViewChange
+		Overflow bool `json:"overflow"`
+	}
+	if err := s.seq.next(&record); err != nil {
+		return nil, err
+	}
+	if record.Overflow {
+		return nil, ErrStreamOverflow
+	}
+	return &record.ConfdbViewChange, nil
+}
+
+// Close ends the stream.
+func (s *ConfdbWatchStream) Close() error {
+	return s.seq.close()
+}
@@ -23,8 +23,11 @@ import (
 	"encoding/json"
 	"io"
 	"net/url"
+	"time"
 
 	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
 )
 
 func (cs *clientSuite) TestConfdbGet(c *C) {
@@ -63,3 +66,113 @@ func (cs *clientSuite) TestConfdbSet(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(res, DeepEquals, map[string]any{"foo": "bar", "baz": float64(1)})
 }
+
+func (cs *clientSuite) TestConfdbHistory(c *C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [
+			{
+				"id": 2,
+				"time": "2025-03-01T12:00:00Z",
+				"view": "c",
+				"author": {"uid": 1000},
+				"changes": [{"path": "wifi.ssid", "old": "foo", "new": "bar"}]
+			}
+		]
+	}`
+
+	entries, err := cs.cli.ConfdbHistory("a/b/c")
+	c.Assert(err, IsNil)
+	c.Check(cs.reqs[0].Method, Equals, "GET")
+	c.Check(cs.reqs[0].URL.Path, Equals, "/v2/confdb/a/b/c")
+	c.Check(cs.reqs[0].URL.Query(), DeepEquals, url.Values{"history": []string{"true"}})
+
+	uid := uint32(1000)
+	c.Check(entries, DeepEquals, []*client.ConfdbHistoryEntry{{
+		ID:      2,
+		Time:    time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		View:    "c",
+		Author:  client.ConfdbAuthor{UID: &uid},
+		Changes: []client.ConfdbPathChange{{Path: "wifi.ssid", Old: "foo", New: "bar"}},
+	}})
+}
+
+func (cs *clientSuite) TestConfdbRevert(c *C) {
+	cs.status = 202
+	cs.rsp = `{"type": "async", "status-code": 202, "change": "123"}`
+
+	chgID, err := cs.cli.ConfdbRevert("a/b/c", 2)
+	c.Assert(err, IsNil)
+	c.Check(chgID, Equals, "123")
+	c.Check(cs.reqs[0].Method, Equals, "POST")
+	c.Check(cs.reqs[0].URL.Path, Equals, "/v2/confdb/a/b/c")
+	data, err := io.ReadAll(cs.reqs[0].Body)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"action":"revert","transaction":2}`)
+}
+
+func (cs *clientSuite) TestConfdbWatch(c *C) {
+	cs.rsp = "\x1e" + `{"time":"2025-03-01T12:00:00Z","author":{"snap":"some-snap"},"changes":[{"path":"wifi.ssid","new":"foo"}],"values":{"ssid":"foo"}}` + "\n" +
+		"\x1e" + `{"truncated` + "\n" +
+		"\x1e" + `{"time":"2025-03-01T12:00:01Z","author":{"uid":1000},"changes":[{"path":"wifi.ssid","old":"foo"}]}` + "\n"
+
+	stream, err := cs.cli.ConfdbWatch("a/b/c", []string{"ssid", "status"})
+	c.Assert(err, IsNil)
+	defer stream.Close()
+	c.Check(cs.req.Method, Equals, "GET")
+	c.Check(cs.req.URL.Path, Equals, "/v2/confdb/a/b/c")
+	c.Check(cs.req.URL.Query(), DeepEquals, url.Values{
+		"watch": {"true"},
+		"keys":  {"ssid,status"},
+	})
+
+	change, err := stream.Next()
+	c.Assert(err, IsNil)
+	c.Check(change, DeepEquals, &client.ConfdbViewChange{
+		Time:    time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Author:  client.ConfdbAuthor{Snap: "some-snap"},
+		Changes: []client.ConfdbPathChange{{Path: "wifi.ssid", New: "foo"}},
+		Values:  map[string]any{"ssid": "foo"},
+	})
+
+	// the truncated record is skipped
+	change, err = stream.Next()
+	c.Assert(err, IsNil)
+	uid := uint32(1000)
+	c.Check(change, DeepEquals, &client.ConfdbViewChange{
+		Time:    time.Date(2025, 3, 1, 12, 0, 1, 0, time.UTC),
+		Author:  client.ConfdbAuthor{UID: &uid},
+		Changes: []client.ConfdbPathChange{{Path: "wifi.ssid", Old: "foo"}},
+	})
+
+	_, err = stream.Next()
+	c.Check(err, Equals, io.EOF)
+}
+
+func (cs *clientSuite) TestConfdbWatchOverflow(c *C) {
+	cs.rsp = "\x1e" + `{"time":"2025-03-01T12:00:00Z","author":{"snap":"some-snap"},"changes":[{"path":"wifi.ssid","new":"foo"}]}` + "\n" +
+		"\x1e" + `{"overflow":true}` + "\n"
+
+	stream, err := cs.cli.ConfdbWatch("a/b/c", nil)
+	c.Assert(err, IsNil)
+	defer stream.Close()
+
+	change, err := stream.Next()
+	c.Assert(err, IsNil)
+	c.Check(change.Author.Snap, Equals, "some-snap")
+
+	_, err = stream.Next()
+	c.Check(err, Equals, client.ErrStreamOverflow)
+}
+
+func (cs *clientSuite) TestConfdbWatchError(c *C) {
+	cs.status = 400
+	cs.rsp = `{"type": "error", "status-code": 400, "result": {"message": "cannot get \"foo\" through a/b/c: no matching rule", "kind": "option-not-available"}}`
+
+	_, err := cs.cli.ConfdbWatch("a/b/c", []string{"foo"})
+	c.Assert(err, ErrorMatches, `cannot get "foo" through a/b/c: no matching rule`)
+	c.Check(cs.req.URL.Query(), DeepEquals, url.Values{
+		"watch": {"true"},
+		"keys":  {"foo"},
+	})
+}
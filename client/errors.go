@@ -171,6 +171,9 @@ const (
 	// ErrorKindInterfacesRequestsRuleConflict: a rule with conflicting path pattern and permissions already exists.
 	ErrorKindInterfacesRequestsRuleConflict ErrorKind = "interfaces-requests-rule-conflict"
 
+	// ErrorKindInterfacesRequestsRuleIsSystem: the rule was provisioned by the system administrator and cannot be modified.
+	ErrorKindInterfacesRequestsRuleIsSystem ErrorKind = "interfaces-requests-rule-is-system"
+
 	// ErrorKindMissingSnapResourcePair: cannot find a snap-resource-pair when attempting to sideload a component.
 	ErrorKindMissingSnapResourcePair ErrorKind = "missing-snap-resource-pair"
 
@@ -0,0 +1,231 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EventType is the type of an event streamed by snapd.
+type EventType string
+
+const (
+	// ChangeStatusEvent is sent when the status of a change is updated.
+	ChangeStatusEvent EventType = "change-status"
+	// TaskStatusEvent is sent when the status of a task is updated.
+	TaskStatusEvent EventType = "task-status"
+	// TaskProgressEvent is sent when the progress of a task is updated.
+	TaskProgressEvent EventType = "task-progress"
+	// NoticeEvent is sent when a notice occurs.
+	NoticeEvent EventType = "notice"
+
+	// overflowEvent ends a stream whose events were dropped, it is turned
+	// into ErrStreamOverflow.
+	overflowEvent EventType = "overflow"
+)
+
+// Event is a single event streamed by snapd.
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+
+	// ChangeID, Kind, Summary and the statuses are set for change and task
+	// events; TaskID is only set for task events.
+	ChangeID  string `json:"change-id,omitempty"`
+	TaskID    string `json:"task-id,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Summary   string `json:"summary,omitempty"`
+	OldStatus string `json:"old-status,omitempty"`
+	Status    string `json:"status,omitempty"`
+	// Ready is set for change events once the change is ready.
+	Ready bool `json:"ready,omitempty"`
+
+	// Progress is set for task progress events.
+	Progress *TaskProgress `json:"progress,omitempty"`
+
+	// Notice is set for notice events.
+	Notice *Notice `json:"notice,omitempty"`
+}
+
+// EventsOptions holds the options for streaming events.
+type EventsOptions struct {
+	// Types, if not empty, includes only events whose type is one of these.
+	Types []EventType
+
+	// ChangeID, if set, includes only events related to this change, and
+	// makes snapd end the stream once the change is ready.
+	ChangeID string
+}
+
+// EventStream iterates over the events streamed by snapd.
+type EventStream struct {
+	seq *seqStream
+}
+
+// StreamEvents opens a long-lived stream of events from snapd. The caller
+// must call Close on the returned stream once done with it.
+func (client *Client) StreamEvents(opts *EventsOptions) (*EventStream, error) {
+	query := url.Values{}
+	if opts != nil {
+		if len(opts.Types) > 0 {
+			types := make([]string, len(opts.Types))
+			for i, t := range opts.Types {
+				types[i] = string(t)
+			}
+			query.Set("types", strings.Join(types, ","))
+		}
+		if opts.ChangeID != "" {
+			query.Set("change-id", opts.ChangeID)
+		}
+	}
+
+	seq, err := client.openSeqStream("/v2/events", query)
+	if err != nil {
+		return nil, err
+	}
+	return &EventStream{seq: seq}, nil
+}
+
+// Next blocks until the next event is received and returns it. It returns
+// io.EOF once snapd ends the stream, and ErrStreamOverflow if snapd ended it
+// because events were dropped while the caller was not keeping up.
+func (s *EventStream) Next() (*Event, error) {
+	var ev Event
+	if err := s.seq.next(&ev); err != nil {
+		return nil, err
+	}
+	if ev.Type == overflowEvent {
+		return nil, ErrStreamOverflow
+	}
+	return &ev, nil
+}
+
+// Close ends the stream.
+func (s *EventStream) Close() error {
+	return s.seq.close()
+}
+
+// ErrStreamOverflow is returned when snapd ends a stream because the caller
+// did not keep up with it and records had to be dropped. Whatever the caller
+// built from the stream is stale and must be fetched again.
+var ErrStreamOverflow = errors.New("snapd dropped records that were not read in time")
+
+// maxSeqRecordSize is the size of the largest record read from a json-seq
+// stream; larger records are skipped.
+var maxSeqRecordSize = 16 * 1024 * 1024
+
+// seqStream reads the records of a long-lived application/json-seq
+// response from snapd.
+type seqStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	cancel  context.CancelFunc
+}
+
+func (client *Client) openSeqStream(urlpath string, query url.Values) (*seqStream, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rsp, err := client.raw(ctx, "GET", urlpath, query, nil, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if rsp.StatusCode != 200 {
+		defer cancel()
+		defer rsp.Body.Close()
+		var r response
+		if err := decodeInto(rsp.Body, &r); err != nil {
+			return nil, err
+		}
+		return nil, r.err(client, rsp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(rsp.Body)
+	scanner.Buffer(nil, maxSeqRecordSize)
+	scanner.Split(scanLinesSkippingLong(maxSeqRecordSize))
+	return &seqStream{
+		body:    rsp.Body,
+		scanner: scanner,
+		cancel:  cancel,
+	}, nil
+}
+
+// scanLinesSkippingLong returns a split function like bufio.ScanLines,
+// except that lines which do not fit in max bytes are dropped instead of
+// stopping the scan with bufio.ErrTooLong.
+func scanLinesSkippingLong(max int) bufio.SplitFunc {
+	skipping := false
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if skipping {
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				skipping = false
+				return i + 1, nil, nil
+			}
+			return len(data), nil, nil
+		}
+		advance, token, err = bufio.ScanLines(data, atEOF)
+		if advance == 0 && token == nil && err == nil && len(data) >= max {
+			// the line does not fit in the buffer, drop what we
+			// have so far and the rest of it once it arrives
+			skipping = true
+			return len(data), nil, nil
+		}
+		return advance, token, err
+	}
+}
+
+// next blocks until the next record is received and decodes it into v. It
+// returns io.EOF once snapd ends the stream.
+func (s *seqStream) next(v any) error {
+	// records come in application/json-seq, described in RFC7464: it's
+	// a series of <RS><arbitrary, valid JSON><LF>. Decoders are
+	// expected to skip invalid or truncated or empty records.
+	for s.scanner.Scan() {
+		buf := s.scanner.Bytes()          // the scanner prunes the ending LF
+		idx := bytes.IndexByte(buf, 0x1E) // find the initial RS
+		if idx < 0 {
+			// no RS? skip
+			continue
+		}
+		buf = buf[idx+1:] // drop the initial RS
+		if !json.Valid(buf) {
+			// truncated/corrupted/binary record? skip
+			continue
+		}
+		return json.Unmarshal(buf, v)
+	}
+	if err := s.scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func (s *seqStream) close() error {
+	s.cancel()
+	return s.body.Close()
+}
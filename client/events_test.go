@@ -0,0 +1,137 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestStreamEvents(c *check.C) {
+	cs.rsp = "\x1e" + `{"type":"change-status","time":"2025-01-02T03:04:05Z","change-id":"1","kind":"install-snap","summary":"Install foo","old-status":"Default","status":"Doing"}` + "\n" +
+		"\x1e" + `{"type":"task-progress","time":"2025-01-02T03:04:06Z","change-id":"1","task-id":"2","kind":"download","status":"Doing","progress":{"label":"foo","done":1,"total":2}}` + "\n" +
+		"\x1e" + `{"truncated` + "\n" +
+		"\x1e" + `{"type":"notice","time":"2025-01-02T03:04:07Z","notice":{"id":"3","type":"warning","key":"danger","expire-after":"1h0m0s"}}` + "\n"
+
+	stream, err := cs.cli.StreamEvents(&client.EventsOptions{
+		Types:    []client.EventType{client.ChangeStatusEvent, client.TaskProgressEvent, client.NoticeEvent},
+		ChangeID: "1",
+	})
+	c.Assert(err, check.IsNil)
+	defer stream.Close()
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/events")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"types":     {"change-status,task-progress,notice"},
+		"change-id": {"1"},
+	})
+
+	ev, err := stream.Next()
+	c.Assert(err, check.IsNil)
+	c.Check(ev, check.DeepEquals, &client.Event{
+		Type:      client.ChangeStatusEvent,
+		Time:      time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		ChangeID:  "1",
+		Kind:      "install-snap",
+		Summary:   "Install foo",
+		OldStatus: "Default",
+		Status:    "Doing",
+	})
+
+	ev, err = stream.Next()
+	c.Assert(err, check.IsNil)
+	c.Check(ev.Type, check.Equals, client.TaskProgressEvent)
+	c.Check(ev.TaskID, check.Equals, "2")
+	c.Check(ev.Progress, check.DeepEquals, &client.TaskProgress{Label: "foo", Done: 1, Total: 2})
+
+	// the truncated record is skipped
+	ev, err = stream.Next()
+	c.Assert(err, check.IsNil)
+	c.Check(ev.Type, check.Equals, client.NoticeEvent)
+	c.Check(ev.Notice, check.DeepEquals, &client.Notice{
+		ID:          "3",
+		Type:        client.WarningNotice,
+		Key:         "danger",
+		ExpireAfter: time.Hour,
+	})
+
+	_, err = stream.Next()
+	c.Check(err, check.Equals, io.EOF)
+}
+
+func (cs *clientSuite) TestStreamEventsLargeRecords(c *check.C) {
+	restore := client.MockMaxSeqRecordSize(128 * 1024)
+	defer restore()
+
+	// bigger than the default bufio.Scanner limit, but within ours
+	large := strings.Repeat("x", 100*1024)
+	// does not fit in the buffer at all
+	tooLarge := strings.Repeat("y", 300*1024)
+	cs.rsp = "\x1e" + `{"type":"notice","notice":{"id":"1","type":"warning","key":"` + large + `"}}` + "\n" +
+		"\x1e" + `{"type":"notice","notice":{"id":"2","type":"warning","key":"` + tooLarge + `"}}` + "\n" +
+		"\x1e" + `{"type":"notice","notice":{"id":"3","type":"warning","key":"small"}}` + "\n"
+
+	stream, err := cs.cli.StreamEvents(nil)
+	c.Assert(err, check.IsNil)
+	defer stream.Close()
+
+	ev, err := stream.Next()
+	c.Assert(err, check.IsNil)
+	c.Check(ev.Notice.ID, check.Equals, "1")
+	c.Check(ev.Notice.Key, check.Equals, large)
+
+	// the record that is too large is skipped
+	ev, err = stream.Next()
+	c.Assert(err, check.IsNil)
+	c.Check(ev.Notice.ID, check.Equals, "3")
+
+	_, err = stream.Next()
+	c.Check(err, check.Equals, io.EOF)
+}
+
+func (cs *clientSuite) TestStreamEventsOverflow(c *check.C) {
+	cs.rsp = "\x1e" + `{"type":"change-status","time":"2025-01-02T03:04:05Z","change-id":"1","status":"Doing"}` + "\n" +
+		"\x1e" + `{"type":"overflow","time":"2025-01-02T03:04:06Z"}` + "\n"
+
+	stream, err := cs.cli.StreamEvents(nil)
+	c.Assert(err, check.IsNil)
+	defer stream.Close()
+
+	ev, err := stream.Next()
+	c.Assert(err, check.IsNil)
+	c.Check(ev.Type, check.Equals, client.ChangeStatusEvent)
+
+	_, err = stream.Next()
+	c.Check(err, check.Equals, client.ErrStreamOverflow)
+}
+
+func (cs *clientSuite) TestStreamEventsError(c *check.C) {
+	cs.status = 404
+	cs.rsp = `{"type": "error", "status-code": 404, "result": {"message": "cannot find change with id \"42\""}}`
+
+	_, err := cs.cli.StreamEvents(&client.EventsOptions{ChangeID: "42"})
+	c.Check(err, check.ErrorMatches, `cannot find change with id "42"`)
+}
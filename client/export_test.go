@@ -61,3 +61,11 @@ func MockStdinReadLimit(new int64) (restore func()) {
 		stdinReadLimit = oldStdinReadLimit
 	}
 }
+
+func MockMaxSeqRecordSize(new int) (restore func()) {
+	old := maxSeqRecordSize
+	maxSeqRecordSize = new
+	return func() {
+		maxSeqRecordSize = old
+	}
+}
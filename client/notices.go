@@ -17,6 +17,11 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
 )
 
 type NotifyOptions struct {
@@ -59,6 +64,227 @@ func (client *Client) Notify(opts *NotifyOptions) (string, error) {
 type NoticeType string
 
 const (
+	// ChangeUpdateNotice is recorded whenever a change is spawned or its
+	// status is updated.
+	ChangeUpdateNotice NoticeType = "change-update"
+
+	// WarningNotice is recorded for human-readable warnings.
+	WarningNotice NoticeType = "warning"
+
+	// RefreshInhibitNotice is recorded when an auto-refresh is inhibited for
+	// one or more snaps.
+	RefreshInhibitNotice NoticeType = "refresh-inhibit"
+
 	// SnapRunInhibitNotice is recorded when "snap run" is inhibited due refresh.
 	SnapRunInhibitNotice NoticeType = "snap-run-inhibit"
+
+	// SnapHealthChangeNotice is recorded when the health status of a snap
+	// changes.
+	SnapHealthChangeNotice NoticeType = "snap-health-change"
+
+	// QuotaOOMNotice is recorded when processes of a quota group are killed
+	// for running out of memory.
+	QuotaOOMNotice NoticeType = "quota-oom"
 )
+
+// Notice holds the details of a notice as returned by snapd.
+type Notice struct {
+	ID            string            `json:"id"`
+	UserID        *uint32           `json:"user-id"`
+	Type          NoticeType        `json:"type"`
+	Key           string            `json:"key"`
+	FirstOccurred time.Time         `json:"first-occurred"`
+	LastOccurred  time.Time         `json:"last-occurred"`
+	LastRepeated  time.Time         `json:"last-repeated"`
+	Occurrences   int               `json:"occurrences"`
+	LastData      map[string]string `json:"last-data,omitempty"`
+	RepeatAfter   time.Duration     `json:"-"`
+	ExpireAfter   time.Duration     `json:"-"`
+}
+
+type jsonNotice struct {
+	noticeAlias
+	RepeatAfter string `json:"repeat-after,omitempty"`
+	ExpireAfter string `json:"expire-after,omitempty"`
+}
+
+type noticeAlias Notice
+
+func (n *Notice) UnmarshalJSON(data []byte) error {
+	var jn jsonNotice
+	if err := json.Unmarshal(data, &jn); err != nil {
+		return err
+	}
+	*n = Notice(jn.noticeAlias)
+	n.RepeatAfter, _ = time.ParseDuration(jn.RepeatAfter)
+	n.ExpireAfter, _ = time.ParseDuration(jn.ExpireAfter)
+	return nil
+}
+
+// NoticesOptions contains options for querying snapd for notices.
+type NoticesOptions struct {
+	// Types, if not empty, includes only notices whose type is one of these.
+	Types []NoticeType
+
+	// Keys, if not empty, includes only notices whose key is one of these.
+	Keys []string
+
+	// KeyGlobs, if not empty, includes only notices whose key matches one
+	// of these shell patterns.
+	KeyGlobs []string
+
+	// Data, if not empty, includes only notices whose last data contains all
+	// of these key-value pairs.
+	Data map[string]string
+
+	// After, if set, includes only notices that were last repeated after
+	// this time.
+	After time.Time
+
+	// Subscription, if set, returns the notices matching the named
+	// subscription which have not been acknowledged yet. It cannot be
+	// combined with the other filters.
+	Subscription string
+
+	// Timeout, if set, makes snapd wait up to this long for at least one
+	// matching notice to occur.
+	Timeout time.Duration
+}
+
+func (opts *NoticesOptions) query() url.Values {
+	q := make(url.Values)
+	if opts == nil {
+		return q
+	}
+	if len(opts.Types) > 0 {
+		types := make([]string, len(opts.Types))
+		for i, t := range opts.Types {
+			types[i] = string(t)
+		}
+		q.Set("types", strings.Join(types, ","))
+	}
+	if len(opts.Keys) > 0 {
+		q.Set("keys", strings.Join(opts.Keys, ","))
+	}
+	if len(opts.KeyGlobs) > 0 {
+		q.Set("key-globs", strings.Join(opts.KeyGlobs, ","))
+	}
+	if len(opts.Data) > 0 {
+		keys := make([]string, 0, len(opts.Data))
+		for k := range opts.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			q.Add("data", fmt.Sprintf("%s=%s", k, opts.Data[k]))
+		}
+	}
+	if !opts.After.IsZero() {
+		q.Set("after", opts.After.Format(time.RFC3339Nano))
+	}
+	if opts.Subscription != "" {
+		q.Set("subscription", opts.Subscription)
+	}
+	if opts.Timeout != 0 {
+		q.Set("timeout", opts.Timeout.String())
+	}
+	return q
+}
+
+// Notices returns the notices matching the given options, ordered by their
+// last-repeated time.
+func (client *Client) Notices(opts *NoticesOptions) ([]*Notice, error) {
+	var doOpts *doOptions
+	if opts != nil && opts.Timeout != 0 {
+		// the server does the waiting, so don't time out on the client
+		doOpts = doNoTimeoutAndRetry
+	}
+	var notices []*Notice
+	if _, err := client.doSyncWithOpts("GET", "/v2/notices", opts.query(), nil, nil, &notices, doOpts); err != nil {
+		return nil, err
+	}
+	return notices, nil
+}
+
+// NoticeSubscription is a named, persistent notice filter kept by snapd
+// together with a cursor pointing at the last acknowledged notice.
+type NoticeSubscription struct {
+	Name        string            `json:"name"`
+	UserID      uint32            `json:"user-id"`
+	Types       []NoticeType      `json:"types,omitempty"`
+	KeyGlobs    []string          `json:"key-globs,omitempty"`
+	Data        map[string]string `json:"data,omitempty"`
+	LastAckedID string            `json:"last-acked-id,omitempty"`
+	LastAcked   time.Time         `json:"last-acked,omitzero"`
+}
+
+// NoticeSubscriptionOptions holds the filter of a notice subscription.
+type NoticeSubscriptionOptions struct {
+	Types    []NoticeType
+	KeyGlobs []string
+	Data     map[string]string
+}
+
+type noticeSubscriptionAction struct {
+	Action   string            `json:"action"`
+	Name     string            `json:"name"`
+	Types    []NoticeType      `json:"types,omitempty"`
+	KeyGlobs []string          `json:"key-globs,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+	NoticeID string            `json:"notice-id,omitempty"`
+}
+
+func (client *Client) noticeSubscriptionAction(action *noticeSubscriptionAction) (*NoticeSubscription, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(action); err != nil {
+		return nil, err
+	}
+	var sub *NoticeSubscription
+	if _, err := client.doSync("POST", "/v2/notice-subscriptions", nil, nil, &body, &sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// SubscribeNotices registers a notice subscription with the given name, or
+// updates the filter of an existing one while keeping its cursor.
+func (client *Client) SubscribeNotices(name string, opts *NoticeSubscriptionOptions) (*NoticeSubscription, error) {
+	action := &noticeSubscriptionAction{
+		Action: "add",
+		Name:   name,
+	}
+	if opts != nil {
+		action.Types = opts.Types
+		action.KeyGlobs = opts.KeyGlobs
+		action.Data = opts.Data
+	}
+	return client.noticeSubscriptionAction(action)
+}
+
+// AckNotice moves the cursor of the named subscription to the given notice,
+// so that it is not returned again for that subscription.
+func (client *Client) AckNotice(subscription, noticeID string) (*NoticeSubscription, error) {
+	return client.noticeSubscriptionAction(&noticeSubscriptionAction{
+		Action:   "ack",
+		Name:     subscription,
+		NoticeID: noticeID,
+	})
+}
+
+// UnsubscribeNotices removes the notice subscription with the given name.
+func (client *Client) UnsubscribeNotices(name string) error {
+	_, err := client.noticeSubscriptionAction(&noticeSubscriptionAction{
+		Action: "remove",
+		Name:   name,
+	})
+	return err
+}
+
+// NoticeSubscriptions returns the notice subscriptions of the current user.
+func (client *Client) NoticeSubscriptions() ([]*NoticeSubscription, error) {
+	var subs []*NoticeSubscription
+	if _, err := client.doSync("GET", "/v2/notice-subscriptions", nil, nil, nil, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
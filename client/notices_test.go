@@ -22,6 +22,8 @@ package client_test
 import (
 	"encoding/json"
 	"io"
+	"net/url"
+	"time"
 
 	"github.com/snapcore/snapd/client"
 	. "gopkg.in/check.v1"
@@ -48,3 +50,135 @@ func (cs *clientSuite) TestNotify(c *C) {
 		"key":    "snap-name",
 	})
 }
+
+func (cs *clientSuite) TestNotices(c *C) {
+	cs.rsp = `{"type": "sync", "result": [{
+		"id": "1",
+		"user-id": null,
+		"type": "change-update",
+		"key": "42",
+		"first-occurred": "2025-01-02T03:04:05Z",
+		"last-occurred": "2025-01-02T03:04:06Z",
+		"last-repeated": "2025-01-02T03:04:06Z",
+		"occurrences": 2,
+		"last-data": {"kind": "refresh-snap"},
+		"expire-after": "168h0m0s"
+	}]}`
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	notices, err := cs.cli.Notices(&client.NoticesOptions{
+		Types:    []client.NoticeType{client.ChangeUpdateNotice, client.WarningNotice},
+		Keys:     []string{"42", "43"},
+		KeyGlobs: []string{"4*"},
+		Data:     map[string]string{"kind": "refresh-snap", "status": "Done"},
+		After:    after,
+	})
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "GET")
+	c.Check(cs.req.URL.Path, Equals, "/v2/notices")
+	c.Check(cs.req.URL.Query(), DeepEquals, url.Values{
+		"types":     {"change-update,warning"},
+		"keys":      {"42,43"},
+		"key-globs": {"4*"},
+		"data":      {"kind=refresh-snap", "status=Done"},
+		"after":     {"2025-01-01T00:00:00Z"},
+	})
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0], DeepEquals, &client.Notice{
+		ID:            "1",
+		Type:          client.ChangeUpdateNotice,
+		Key:           "42",
+		FirstOccurred: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		LastOccurred:  time.Date(2025, 1, 2, 3, 4, 6, 0, time.UTC),
+		LastRepeated:  time.Date(2025, 1, 2, 3, 4, 6, 0, time.UTC),
+		Occurrences:   2,
+		LastData:      map[string]string{"kind": "refresh-snap"},
+		ExpireAfter:   168 * time.Hour,
+	})
+}
+
+func (cs *clientSuite) TestNoticesSubscriptionWithTimeout(c *C) {
+	cs.rsp = `{"type": "sync", "result": []}`
+	notices, err := cs.cli.Notices(&client.NoticesOptions{
+		Subscription: "monitor",
+		Timeout:      time.Minute,
+	})
+	c.Assert(err, IsNil)
+	c.Check(notices, HasLen, 0)
+	c.Check(cs.req.URL.Query(), DeepEquals, url.Values{
+		"subscription": {"monitor"},
+		"timeout":      {"1m0s"},
+	})
+}
+
+func (cs *clientSuite) TestSubscribeNotices(c *C) {
+	cs.rsp = `{"type": "sync", "result": {"name": "monitor", "user-id": 1000, "types": ["warning"], "last-acked-id": "3"}}`
+	sub, err := cs.cli.SubscribeNotices("monitor", &client.NoticeSubscriptionOptions{
+		Types:    []client.NoticeType{client.WarningNotice},
+		KeyGlobs: []string{"foo*"},
+		Data:     map[string]string{"k": "v"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(sub, DeepEquals, &client.NoticeSubscription{
+		Name:        "monitor",
+		UserID:      1000,
+		Types:       []client.NoticeType{client.WarningNotice},
+		LastAckedID: "3",
+	})
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/notice-subscriptions")
+
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, IsNil)
+	var m map[string]any
+	c.Assert(json.Unmarshal(body, &m), IsNil)
+	c.Check(m, DeepEquals, map[string]any{
+		"action":    "add",
+		"name":      "monitor",
+		"types":     []any{"warning"},
+		"key-globs": []any{"foo*"},
+		"data":      map[string]any{"k": "v"},
+	})
+}
+
+func (cs *clientSuite) TestAckNotice(c *C) {
+	cs.rsp = `{"type": "sync", "result": {"name": "monitor", "user-id": 1000, "last-acked-id": "7"}}`
+	sub, err := cs.cli.AckNotice("monitor", "7")
+	c.Assert(err, IsNil)
+	c.Check(sub.LastAckedID, Equals, "7")
+
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, IsNil)
+	var m map[string]any
+	c.Assert(json.Unmarshal(body, &m), IsNil)
+	c.Check(m, DeepEquals, map[string]any{
+		"action":    "ack",
+		"name":      "monitor",
+		"notice-id": "7",
+	})
+}
+
+func (cs *clientSuite) TestUnsubscribeNotices(c *C) {
+	cs.rsp = `{"type": "sync", "result": null}`
+	err := cs.cli.UnsubscribeNotices("monitor")
+	c.Assert(err, IsNil)
+
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, IsNil)
+	var m map[string]any
+	c.Assert(json.Unmarshal(body, &m), IsNil)
+	c.Check(m, DeepEquals, map[string]any{
+		"action": "remove",
+		"name":   "monitor",
+	})
+}
+
+func (cs *clientSuite) TestNoticeSubscriptions(c *C) {
+	cs.rsp = `{"type": "sync", "result": [{"name": "a", "user-id": 1000}, {"name": "b", "user-id": 1000}]}`
+	subs, err := cs.cli.NoticeSubscriptions()
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "GET")
+	c.Check(cs.req.URL.Path, Equals, "/v2/notice-subscriptions")
+	c.Assert(subs, HasLen, 2)
+	c.Check(subs[0].Name, Equals, "a")
+	c.Check(subs[1].Name, Equals, "b")
+}
@@ -105,6 +105,8 @@ type SnapHealth struct {
 	Status    string        `json:"status"`
 	Message   string        `json:"message,omitempty"`
 	Code      string        `json:"code,omitempty"`
+	// LastRun is when the check-health hook of the snap last ran.
+	LastRun *time.Time `json:"last-run,omitempty"`
 }
 
 type SnapRefreshInhibit struct {
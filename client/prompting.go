@@ -0,0 +1,199 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// PromptingPermissionEntry holds the outcome and lifespan of a permission of
+// a prompting rule.
+type PromptingPermissionEntry struct {
+	Outcome  string `json:"outcome"`
+	Lifespan string `json:"lifespan"`
+	// Duration is only used when adding rules with a "timespan" lifespan
+	Duration string `json:"duration,omitempty"`
+	// Expiration is only set for rules with a "timespan" lifespan
+	Expiration *time.Time `json:"expiration,omitempty"`
+}
+
+type PromptingRuleConstraints struct {
+	PathPattern string                               `json:"path-pattern"`
+	Permissions map[string]*PromptingPermissionEntry `json:"permissions"`
+}
+
+// PromptingRule is a rule which automatically replies to the requests of a
+// snap through an interface for which prompting is enabled.
+type PromptingRule struct {
+	ID          string                    `json:"id"`
+	Timestamp   time.Time                 `json:"timestamp"`
+	User        uint32                    `json:"user"`
+	Snap        string                    `json:"snap"`
+	Interface   string                    `json:"interface"`
+	Constraints *PromptingRuleConstraints `json:"constraints"`
+	// System is true for rules provisioned by the system administrator,
+	// which apply to every user and cannot be modified.
+	System bool `json:"system,omitempty"`
+}
+
+// PromptingRuleContents holds the contents of a rule to be added or
+// imported.
+type PromptingRuleContents struct {
+	Snap        string                    `json:"snap"`
+	Interface   string                    `json:"interface"`
+	Constraints *PromptingRuleConstraints `json:"constraints"`
+}
+
+// PromptingRuleSet is the format of exported prompting rules, which is also
+// that of the system rules file.
+type PromptingRuleSet struct {
+	Rules []*PromptingRuleContents `json:"rules"`
+}
+
+type PromptingRulesOptions struct {
+	// Snap limits the rules to those of the given snap
+	Snap string
+	// Interface limits the rules to those of the given interface
+	Interface string
+}
+
+type postPromptingRulesData struct {
+	Action   string                   `json:"action"`
+	Rule     *PromptingRuleContents   `json:"rule,omitempty"`
+	Rules    []*PromptingRuleContents `json:"rules,omitempty"`
+	Selector *promptingRulesSelector  `json:"selector,omitempty"`
+}
+
+type promptingRulesSelector struct {
+	Snap      string `json:"snap"`
+	Interface string `json:"interface,omitempty"`
+}
+
+type postPromptingRuleData struct {
+	Action string `json:"action"`
+}
+
+// PromptingRules returns the prompting rules which apply to the current user,
+// including those provisioned by the system administrator.
+func (client *Client) PromptingRules(opts *PromptingRulesOptions) ([]*PromptingRule, error) {
+	if opts == nil {
+		opts = &PromptingRulesOptions{}
+	}
+	query := url.Values{}
+	if opts.Snap != "" {
+		query.Set("snap", opts.Snap)
+	}
+	if opts.Interface != "" {
+		query.Set("interface", opts.Interface)
+	}
+
+	var rules []*PromptingRule
+	if _, err := client.doSync("GET", "/v2/interfaces/requests/rules", query, nil, nil, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (client *Client) postPromptingRules(data *postPromptingRulesData, v any) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		return err
+	}
+	_, err := client.doSync("POST", "/v2/interfaces/requests/rules", nil, nil, &body, v)
+	return err
+}
+
+// AddPromptingRule adds a prompting rule with the given contents for the
+// current user.
+func (client *Client) AddPromptingRule(contents *PromptingRuleContents) (*PromptingRule, error) {
+	if contents == nil {
+		return nil, fmt.Errorf("cannot add prompting rule without contents")
+	}
+	var rule *PromptingRule
+	if err := client.postPromptingRules(&postPromptingRulesData{Action: "add", Rule: contents}, &rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// RemovePromptingRule removes the prompting rule with the given ID.
+func (client *Client) RemovePromptingRule(id string) (*PromptingRule, error) {
+	if id == "" {
+		return nil, fmt.Errorf("cannot remove prompting rule without an ID")
+	}
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(&postPromptingRuleData{Action: "remove"}); err != nil {
+		return nil, err
+	}
+	var rule *PromptingRule
+	path := fmt.Sprintf("/v2/interfaces/requests/rules/%s", url.PathEscape(id))
+	if _, err := client.doSync("POST", path, nil, nil, &body, &rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// RemovePromptingRules removes all prompting rules of the current user for
+// the given snap and/or interface.
+func (client *Client) RemovePromptingRules(snap, iface string) ([]*PromptingRule, error) {
+	if snap == "" && iface == "" {
+		return nil, fmt.Errorf("cannot remove prompting rules without a snap or interface")
+	}
+	data := &postPromptingRulesData{
+		Action:   "remove",
+		Selector: &promptingRulesSelector{Snap: snap, Interface: iface},
+	}
+	var rules []*PromptingRule
+	if err := client.postPromptingRules(data, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ExportPromptingRules returns the prompting rules of the current user in a
+// form which can be passed to ImportPromptingRules. Only permissions which
+// never expire are exported.
+func (client *Client) ExportPromptingRules() (*PromptingRuleSet, error) {
+	query := url.Values{}
+	query.Set("export", "true")
+
+	var ruleSet *PromptingRuleSet
+	if _, err := client.doSync("GET", "/v2/interfaces/requests/rules", query, nil, nil, &ruleSet); err != nil {
+		return nil, err
+	}
+	return ruleSet, nil
+}
+
+// ImportPromptingRules adds the rules of the given rule set for the current
+// user. Either all rules are imported, or none is.
+func (client *Client) ImportPromptingRules(ruleSet *PromptingRuleSet) ([]*PromptingRule, error) {
+	if ruleSet == nil || len(ruleSet.Rules) == 0 {
+		return nil, fmt.Errorf("cannot import prompting rules: no rules given")
+	}
+	var imported []*PromptingRule
+	if err := client.postPromptingRules(&postPromptingRulesData{Action: "import", Rules: ruleSet.Rules}, &imported); err != nil {
+		return nil, err
+	}
+	return imported, nil
+}
@@ -0,0 +1,200 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestPromptingRules(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [
+			{
+				"id": "0000000000000002",
+				"timestamp": "2025-03-01T12:00:00Z",
+				"user": 4294967295,
+				"snap": "firefox",
+				"interface": "home",
+				"constraints": {
+					"path-pattern": "/home/*/Documents/**",
+					"permissions": {"read": {"outcome": "deny", "lifespan": "forever"}}
+				},
+				"system": true
+			}
+		]
+	}`
+
+	rules, err := cs.cli.PromptingRules(&client.PromptingRulesOptions{Snap: "firefox", Interface: "home"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/interfaces/requests/rules")
+	c.Check(cs.req.URL.Query().Get("snap"), check.Equals, "firefox")
+	c.Check(cs.req.URL.Query().Get("interface"), check.Equals, "home")
+	c.Check(rules, check.DeepEquals, []*client.PromptingRule{
+		{
+			ID:        "0000000000000002",
+			Timestamp: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+			User:      4294967295,
+			Snap:      "firefox",
+			Interface: "home",
+			Constraints: &client.PromptingRuleConstraints{
+				PathPattern: "/home/*/Documents/**",
+				Permissions: map[string]*client.PromptingPermissionEntry{
+					"read": {Outcome: "deny", Lifespan: "forever"},
+				},
+			},
+			System: true,
+		},
+	})
+}
+
+func (cs *clientSuite) TestAddPromptingRule(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"id": "0000000000000003", "snap": "firefox", "interface": "home"}
+	}`
+
+	rule, err := cs.cli.AddPromptingRule(&client.PromptingRuleContents{
+		Snap:      "firefox",
+		Interface: "home",
+		Constraints: &client.PromptingRuleConstraints{
+			PathPattern: "/home/test/Downloads/**",
+			Permissions: map[string]*client.PromptingPermissionEntry{
+				"write": {Outcome: "allow", Lifespan: "forever"},
+			},
+		},
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(rule.ID, check.Equals, "0000000000000003")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/interfaces/requests/rules")
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]any
+	c.Assert(json.Unmarshal(body, &req), check.IsNil)
+	c.Check(req, check.DeepEquals, map[string]any{
+		"action": "add",
+		"rule": map[string]any{
+			"snap":      "firefox",
+			"interface": "home",
+			"constraints": map[string]any{
+				"path-pattern": "/home/test/Downloads/**",
+				"permissions": map[string]any{
+					"write": map[string]any{"outcome": "allow", "lifespan": "forever"},
+				},
+			},
+		},
+	})
+}
+
+func (cs *clientSuite) TestRemovePromptingRule(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"id": "0000000000000003", "snap": "firefox", "interface": "home"}
+	}`
+
+	rule, err := cs.cli.RemovePromptingRule("0000000000000003")
+	c.Assert(err, check.IsNil)
+	c.Check(rule.ID, check.Equals, "0000000000000003")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/interfaces/requests/rules/0000000000000003")
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"remove"}`+"\n")
+
+	_, err = cs.cli.RemovePromptingRule("")
+	c.Check(err, check.ErrorMatches, "cannot remove prompting rule without an ID")
+}
+
+func (cs *clientSuite) TestRemovePromptingRules(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [{"id": "0000000000000003", "snap": "firefox", "interface": "home"}]
+	}`
+
+	rules, err := cs.cli.RemovePromptingRules("firefox", "")
+	c.Assert(err, check.IsNil)
+	c.Check(rules, check.HasLen, 1)
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/interfaces/requests/rules")
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"remove","selector":{"snap":"firefox"}}`+"\n")
+
+	_, err = cs.cli.RemovePromptingRules("", "")
+	c.Check(err, check.ErrorMatches, "cannot remove prompting rules without a snap or interface")
+}
+
+func (cs *clientSuite) TestExportImportPromptingRules(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {
+			"rules": [
+				{
+					"snap": "firefox",
+					"interface": "home",
+					"constraints": {
+						"path-pattern": "/home/test/Downloads/**",
+						"permissions": {"write": {"outcome": "allow", "lifespan": "forever"}}
+					}
+				}
+			]
+		}
+	}`
+
+	ruleSet, err := cs.cli.ExportPromptingRules()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/interfaces/requests/rules")
+	c.Check(cs.req.URL.Query().Get("export"), check.Equals, "true")
+	c.Assert(ruleSet.Rules, check.HasLen, 1)
+	c.Check(ruleSet.Rules[0].Constraints.PathPattern, check.Equals, "/home/test/Downloads/**")
+
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [{"id": "0000000000000004", "snap": "firefox", "interface": "home"}]
+	}`
+	imported, err := cs.cli.ImportPromptingRules(ruleSet)
+	c.Assert(err, check.IsNil)
+	c.Assert(imported, check.HasLen, 1)
+	c.Check(imported[0].ID, check.Equals, "0000000000000004")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]any
+	c.Assert(json.Unmarshal(body, &req), check.IsNil)
+	c.Check(req["action"], check.Equals, "import")
+	c.Check(req["rules"], check.HasLen, 1)
+
+	_, err = cs.cli.ImportPromptingRules(&client.PromptingRuleSet{})
+	c.Check(err, check.ErrorMatches, "cannot import prompting rules: no rules given")
+}
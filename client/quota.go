@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/snapcore/snapd/gadget/quantity"
@@ -45,6 +46,10 @@ type QuotaGroupResult struct {
 	Services    []string     `json:"services,omitempty"`
 	Constraints *QuotaValues `json:"constraints,omitempty"`
 	Current     *QuotaValues `json:"current,omitempty"`
+	// OOMKills is how many processes of the group were killed for
+	// running out of memory, LastOOMKill when it last happened.
+	OOMKills    int        `json:"oom-kills,omitempty"`
+	LastOOMKill *time.Time `json:"last-oom-kill,omitempty"`
 }
 
 type QuotaCPUValues struct {
@@ -66,12 +71,48 @@ type QuotaJournalValues struct {
 	*QuotaJournalRate
 }
 
+// QuotaIODeviceValues holds the IO limits of a block device, bandwidths
+// being in bytes per second.
+type QuotaIODeviceValues struct {
+	Device         string        `json:"device"`
+	ReadBandwidth  quantity.Size `json:"read-bandwidth,omitempty"`
+	WriteBandwidth quantity.Size `json:"write-bandwidth,omitempty"`
+	ReadIOPS       int           `json:"read-iops,omitempty"`
+	WriteIOPS      int           `json:"write-iops,omitempty"`
+}
+
+type QuotaIOValues struct {
+	Weight  int                   `json:"weight,omitempty"`
+	Devices []QuotaIODeviceValues `json:"devices,omitempty"`
+}
+
 type QuotaValues struct {
-	Memory  quantity.Size       `json:"memory,omitempty"`
-	CPU     *QuotaCPUValues     `json:"cpu,omitempty"`
-	CPUSet  *QuotaCPUSetValues  `json:"cpu-set,omitempty"`
-	Threads int                 `json:"threads,omitempty"`
-	Journal *QuotaJournalValues `json:"journal,omitempty"`
+	Memory quantity.Size `json:"memory,omitempty"`
+	// MemoryHigh is the memory usage above which the processes of the
+	// group are throttled and put under reclaim pressure.
+	MemoryHigh quantity.Size `json:"memory-high,omitempty"`
+	// OOMPolicy is what happens when processes of the group are killed
+	// for running out of memory, one of "notify", "restart-service" or
+	// "restart-group".
+	OOMPolicy string `json:"oom-policy,omitempty"`
+	// MemoryPressure is the percentage of time the processes of the
+	// group can be stalled waiting for memory, over 10 seconds, above
+	// which the OOM policy is applied.
+	MemoryPressure int                 `json:"memory-pressure,omitempty"`
+	CPU            *QuotaCPUValues     `json:"cpu,omitempty"`
+	CPUSet         *QuotaCPUSetValues  `json:"cpu-set,omitempty"`
+	Threads        int                 `json:"threads,omitempty"`
+	Journal        *QuotaJournalValues `json:"journal,omitempty"`
+	IO             *QuotaIOValues      `json:"io,omitempty"`
+}
+
+// QuotaUsageSample holds the resource usage of a quota group at a given time.
+type QuotaUsageSample struct {
+	Time   time.Time     `json:"time"`
+	Memory quantity.Size `json:"memory,omitempty"`
+	// CPU is the usage as a percentage of a single CPU
+	CPU     int `json:"cpu,omitempty"`
+	Threads int `json:"threads,omitempty"`
 }
 
 type EnsureQuotaOptions struct {
@@ -133,6 +174,28 @@ func (client *Client) GetQuotaGroup(groupName string) (*QuotaGroupResult, error)
 	return res, nil
 }
 
+// QuotaUsage returns the recorded resource usage samples of the given quota
+// group, oldest first. If since is not zero only the samples taken after it
+// are returned.
+func (client *Client) QuotaUsage(groupName string, since time.Time) ([]QuotaUsageSample, error) {
+	if groupName == "" {
+		return nil, fmt.Errorf("cannot get quota group usage without a name")
+	}
+
+	query := url.Values{}
+	if !since.IsZero() {
+		query.Set("since", since.Format(time.RFC3339Nano))
+	}
+
+	var res []QuotaUsageSample
+	path := fmt.Sprintf("/v2/quotas/%s/usage", groupName)
+	if _, err := client.doSync("GET", path, query, nil, nil, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
 func (client *Client) RemoveQuotaGroup(groupName string) (changeID string, err error) {
 	if groupName == "" {
 		return "", fmt.Errorf("cannot remove quota group without a name")
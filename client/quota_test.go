@@ -162,6 +162,35 @@ func (cs *clientSuite) TestGetQuotaGroupError(c *check.C) {
 	c.Check(err, check.ErrorMatches, `server error: "Internal Server Error"`)
 }
 
+func (cs *clientSuite) TestQuotaUsage(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [
+			{"time": "2025-03-01T12:00:00Z", "memory": 1000, "threads": 3},
+			{"time": "2025-03-01T12:01:00Z", "memory": 2000, "cpu": 50, "threads": 4}
+		]
+	}`
+
+	since := time.Date(2025, 3, 1, 11, 0, 0, 0, time.UTC)
+	samples, err := cs.cli.QuotaUsage("foo", since)
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/quotas/foo/usage")
+	c.Check(cs.req.URL.Query().Get("since"), check.Equals, "2025-03-01T11:00:00Z")
+	c.Check(samples, check.DeepEquals, []client.QuotaUsageSample{
+		{Time: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), Memory: 1000, Threads: 3},
+		{Time: time.Date(2025, 3, 1, 12, 1, 0, 0, time.UTC), Memory: 2000, CPU: 50, Threads: 4},
+	})
+
+	_, err = cs.cli.QuotaUsage("foo", time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.RawQuery, check.Equals, "")
+
+	_, err = cs.cli.QuotaUsage("", time.Time{})
+	c.Check(err, check.ErrorMatches, `cannot get quota group usage without a name`)
+}
+
 func (cs *clientSuite) TestRemoveQuotaGroup(c *check.C) {
 	cs.status = 202
 	cs.rsp = `{
@@ -23,11 +23,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+
+	"github.com/snapcore/snapd/snap"
 )
 
 // TransactionType says whether we want to treat each snap separately
@@ -64,6 +67,7 @@ type SnapOptions struct {
 	Time             string          `json:"time,omitempty"`
 	HoldLevel        string          `json:"hold-level,omitempty"`
 	Users            []string        `json:"users,omitempty"`
+	SnapshotTarget   string          `json:"snapshot-target,omitempty"`
 }
 
 func writeFieldBool(mw *multipart.Writer, key string, val bool) error {
@@ -122,6 +126,9 @@ type actionData struct {
 	Name       string   `json:"name,omitempty"`
 	SnapPath   string   `json:"snap-path,omitempty"`
 	Components []string `json:"components,omitempty"`
+	// BaseRevision is set when sending a delta, to the installed
+	// revision it applies to.
+	BaseRevision string `json:"-"`
 	*SnapOptions
 }
 
@@ -136,6 +143,8 @@ type multiActionData struct {
 	Time           string              `json:"time,omitempty"`
 	HoldLevel      string              `json:"hold-level,omitempty"`
 	Components     map[string][]string `json:"components,omitempty"`
+	SnapshotTarget string              `json:"snapshot-target,omitempty"`
+	AutoRefresh    bool                `json:"auto-refresh,omitempty"`
 }
 
 // Install adds the snap with the given name from the given channel (or
@@ -170,6 +179,82 @@ func (client *Client) RefreshMany(names []string, components map[string][]string
 	return client.doMultiSnapAction("refresh", names, components, options)
 }
 
+// RefreshPlan describes what a refresh would do.
+type RefreshPlan struct {
+	Snaps    []PlannedRefresh `json:"snaps,omitempty"`
+	TaskSets []PlannedTaskSet `json:"task-sets,omitempty"`
+	// Held maps snaps kept back by refresh holds to the snaps holding
+	// them.
+	Held          map[string][]string `json:"held,omitempty"`
+	Prerequisites []string            `json:"prerequisites,omitempty"`
+}
+
+// PlannedRefresh describes the refresh of one snap in a RefreshPlan.
+type PlannedRefresh struct {
+	Name           string        `json:"name"`
+	Type           string        `json:"type"`
+	Channel        string        `json:"channel,omitempty"`
+	Current        snap.Revision `json:"current"`
+	Revision       snap.Revision `json:"revision"`
+	DownloadSize   int64         `json:"download-size,omitempty"`
+	Reboot         bool          `json:"reboot,omitempty"`
+	Services       []string      `json:"services,omitempty"`
+	ValidationSets []string      `json:"validation-sets,omitempty"`
+}
+
+// PlannedTaskSet is one of the task sets of a RefreshPlan.
+type PlannedTaskSet struct {
+	Snap  string        `json:"snap,omitempty"`
+	Tasks []PlannedTask `json:"tasks"`
+}
+
+// PlannedTask is a task of a PlannedTaskSet.
+type PlannedTask struct {
+	Kind    string `json:"kind"`
+	Summary string `json:"summary"`
+}
+
+// RefreshPlan returns what refreshing the snaps with the given names, or
+// all snaps if no names are given, would do, without refreshing anything.
+func (client *Client) RefreshPlan(names []string, options *SnapOptions) (*RefreshPlan, error) {
+	action := multiActionData{
+		Action: "plan",
+		Snaps:  names,
+	}
+	if options != nil {
+		action.Transaction = options.Transaction
+		action.IgnoreRunning = options.IgnoreRunning
+	}
+	return client.doRefreshPlan(&action)
+}
+
+// AutoRefreshPlan returns what the next auto-refresh would do, including
+// the snaps kept back by holds that only apply to auto-refreshes, without
+// refreshing anything.
+func (client *Client) AutoRefreshPlan() (*RefreshPlan, error) {
+	return client.doRefreshPlan(&multiActionData{
+		Action:      "plan",
+		AutoRefresh: true,
+	})
+}
+
+func (client *Client) doRefreshPlan(action *multiActionData) (*RefreshPlan, error) {
+	data, err := json.Marshal(action)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal multi-snap action: %s", err)
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	var plan RefreshPlan
+	if _, err := client.doSync("POST", "/v2/snaps", nil, headers, bytes.NewBuffer(data), &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
 func (client *Client) HoldRefreshes(name string, options *SnapOptions) (changeID string, err error) {
 	return client.doSnapAction("hold", name, nil, options)
 }
@@ -206,7 +291,15 @@ func (client *Client) Switch(name string, options *SnapOptions) (changeID string
 
 // SnapshotMany snapshots many snaps (all, if names empty) for many users (all, if users is empty).
 func (client *Client) SnapshotMany(names []string, users []string) (setID uint64, changeID string, err error) {
-	result, changeID, err := client.doMultiSnapActionFull("snapshot", names, nil, &SnapOptions{Users: users})
+	return client.SnapshotManyTo(names, users, "")
+}
+
+// SnapshotManyTo snapshots the given snaps like SnapshotMany, and then
+// moves the snapshot set to the given snapshot target, if it is not
+// empty. Targets are either the absolute path of a directory or an
+// s3://<bucket>[/<prefix>] URL.
+func (client *Client) SnapshotManyTo(names []string, users []string, target string) (setID uint64, changeID string, err error) {
+	result, changeID, err := client.doMultiSnapActionFull("snapshot", names, nil, &SnapOptions{Users: users, SnapshotTarget: target})
 	if err != nil {
 		return 0, "", err
 	}
@@ -269,6 +362,7 @@ func (client *Client) doMultiSnapActionFull(actionName string, snaps []string, c
 		action.ValidationSets = options.ValidationSets
 		action.Time = options.Time
 		action.HoldLevel = options.HoldLevel
+		action.SnapshotTarget = options.SnapshotTarget
 	}
 
 	data, err := json.Marshal(&action)
@@ -301,6 +395,37 @@ func (client *Client) InstallPath(path, name string, options *SnapOptions) (chan
 	return client.sendLocalSnaps([]string{path}, []*os.File{f}, action)
 }
 
+// InstallDelta installs the snap reconstructed by applying the delta with
+// the given path to the baseRevision of the installed snap name, returning
+// the UUID of the background operation upon success. The reconstructed snap
+// must have pre-acknowledged signatures.
+func (client *Client) InstallDelta(path, name string, baseRevision snap.Revision, options *SnapOptions) (changeID string, err error) {
+	if options != nil && options.Dangerous {
+		return "", errors.New("cannot install delta with the dangerous option, the reconstructed snap must be verified")
+	}
+	if name == "" {
+		return "", errors.New("cannot install delta without the name of the snap it applies to")
+	}
+	if baseRevision.Unset() {
+		return "", errors.New("cannot install delta without the revision it applies to")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot open %q: %w", path, err)
+	}
+
+	action := actionData{
+		Action:       "install",
+		Name:         name,
+		SnapPath:     path,
+		BaseRevision: baseRevision.String(),
+		SnapOptions:  options,
+	}
+
+	return client.sendLocalSnaps([]string{path}, []*os.File{f}, action)
+}
+
 // InstallPathMany sideloads the snaps with the given paths,
 // returning the UUID of the background operation upon success.
 func (client *Client) InstallPathMany(paths []string, options *SnapOptions) (changeID string, err error) {
@@ -382,7 +507,8 @@ func sendSnapFiles(paths []string, files []*os.File, pw *io.PipeWriter, mw *mult
 		fields = append(fields, []field{
 			{"name", action.Name},
 			{"snap-path", action.SnapPath},
-			{"channel", action.Channel}}...)
+			{"channel", action.Channel},
+			{"base-revision", action.BaseRevision}}...)
 	}
 
 	for _, s := range fields {
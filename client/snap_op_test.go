@@ -33,6 +33,7 @@ import (
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -309,6 +310,31 @@ func (cs *clientSuite) TestClientMultiSnapshot(c *check.C) {
 	c.Check(changeID, check.Equals, "d728")
 }
 
+func (cs *clientSuite) TestClientMultiSnapshotTo(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"result": {"set-id": 42},
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	setID, changeID, err := cs.cli.SnapshotManyTo([]string{pkgName}, nil, "/mnt/snapshots")
+	c.Assert(err, check.IsNil)
+
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	jsonBody := make(map[string]any)
+	err = json.Unmarshal(body, &jsonBody)
+	c.Assert(err, check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]any{
+		"action":          "snapshot",
+		"snaps":           []any{pkgName},
+		"snapshot-target": "/mnt/snapshots",
+	})
+	c.Check(setID, check.Equals, uint64(42))
+	c.Check(changeID, check.Equals, "d728")
+}
+
 func (cs *clientSuite) TestClientOpInstallPath(c *check.C) {
 	cs.status = 202
 	cs.rsp = `{
@@ -339,6 +365,50 @@ func (cs *clientSuite) TestClientOpInstallPath(c *check.C) {
 	c.Check(id, check.Equals, "66b3")
 }
 
+func (cs *clientSuite) TestClientOpInstallDelta(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"change": "66b3",
+		"status-code": 202,
+		"type": "async"
+	}`
+
+	delta := filepath.Join(c.MkDir(), "foo_2.delta")
+	err := os.WriteFile(delta, []byte("delta-data"), 0644)
+	c.Assert(err, check.IsNil)
+
+	id, err := cs.cli.InstallDelta(delta, "foo", snap.R(1), nil)
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "66b3")
+
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+
+	c.Check(string(body), testutil.Contains, "\r\ndelta-data\r\n")
+	c.Check(string(body), testutil.Contains, "Content-Disposition: form-data; name=\"action\"\r\n\r\ninstall\r\n")
+	c.Check(string(body), testutil.Contains, "Content-Disposition: form-data; name=\"name\"\r\n\r\nfoo\r\n")
+	c.Check(string(body), testutil.Contains, "Content-Disposition: form-data; name=\"base-revision\"\r\n\r\n1\r\n")
+	c.Check(string(body), testutil.Contains, "Content-Disposition: form-data; name=\"snap-path\"\r\n\r\n"+delta+"\r\n")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+}
+
+func (cs *clientSuite) TestClientOpInstallDeltaErrors(c *check.C) {
+	delta := filepath.Join(c.MkDir(), "foo_2.delta")
+	err := os.WriteFile(delta, []byte("delta-data"), 0644)
+	c.Assert(err, check.IsNil)
+
+	_, err = cs.cli.InstallDelta(delta, "foo", snap.R(1), &client.SnapOptions{Dangerous: true})
+	c.Check(err, check.ErrorMatches, "cannot install delta with the dangerous option, the reconstructed snap must be verified")
+	_, err = cs.cli.InstallDelta(delta, "", snap.R(1), nil)
+	c.Check(err, check.ErrorMatches, "cannot install delta without the name of the snap it applies to")
+	_, err = cs.cli.InstallDelta(delta, "foo", snap.R(0), nil)
+	c.Check(err, check.ErrorMatches, "cannot install delta without the revision it applies to")
+	_, err = cs.cli.InstallDelta(filepath.Join(c.MkDir(), "missing.delta"), "foo", snap.R(1), nil)
+	c.Check(err, check.ErrorMatches, `cannot open ".*/missing.delta": .*`)
+	c.Check(cs.req, check.IsNil)
+}
+
 func (cs *clientSuite) TestClientOpInstallPathIgnoreRunning(c *check.C) {
 	cs.status = 202
 	cs.rsp = `{
@@ -945,6 +1015,75 @@ func (cs *clientSuite) TestClientHoldMany(c *check.C) {
 	c.Check(cs.req.Header["Content-Type"], check.DeepEquals, []string{"application/json"})
 }
 
+func (cs *clientSuite) TestClientRefreshPlan(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"snaps": [{"name": "foo", "type": "app", "channel": "stable", "current": "1", "revision": "2", "download-size": 1024, "services": ["foo.svc"]}],
+			"task-sets": [{"snap": "foo", "tasks": [{"kind": "download-snap", "summary": "Download snap foo"}]}],
+			"held": {"bar": ["system"]},
+			"prerequisites": ["core24"]
+		}
+	}`
+
+	plan, err := cs.cli.RefreshPlan([]string{"foo", "bar"}, &client.SnapOptions{IgnoreRunning: true})
+	c.Assert(err, check.IsNil)
+	c.Check(plan, check.DeepEquals, &client.RefreshPlan{
+		Snaps: []client.PlannedRefresh{{
+			Name:         "foo",
+			Type:         "app",
+			Channel:      "stable",
+			Current:      snap.R(1),
+			Revision:     snap.R(2),
+			DownloadSize: 1024,
+			Services:     []string{"foo.svc"},
+		}},
+		TaskSets: []client.PlannedTaskSet{{
+			Snap:  "foo",
+			Tasks: []client.PlannedTask{{Kind: "download-snap", Summary: "Download snap foo"}},
+		}},
+		Held:          map[string][]string{"bar": {"system"}},
+		Prerequisites: []string{"core24"},
+	})
+
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+	c.Check(cs.req.Header["Content-Type"], check.DeepEquals, []string{"application/json"})
+
+	var body map[string]any
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]any{
+		"action":         "plan",
+		"snaps":          []any{"foo", "bar"},
+		"ignore-running": true,
+	})
+}
+
+func (cs *clientSuite) TestClientAutoRefreshPlan(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"held": {"foo": ["bar"]}
+		}
+	}`
+
+	plan, err := cs.cli.AutoRefreshPlan()
+	c.Assert(err, check.IsNil)
+	c.Check(plan, check.DeepEquals, &client.RefreshPlan{
+		Held: map[string][]string{"foo": {"bar"}},
+	})
+
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+
+	var body map[string]any
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]any{
+		"action":       "plan",
+		"auto-refresh": true,
+	})
+}
+
 func (cs *clientSuite) testClientOpWithComponents(c *check.C, action func(name string, components []string, options *client.SnapOptions) (changeID string, err error)) {
 	cs.status = 202
 	cs.rsp = `{
@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -42,14 +43,31 @@ const SnapshotExportMediaType = "application/x.snapd.snapshot"
 var (
 	ErrSnapshotSetNotFound   = errors.New("no snapshot set with the given ID")
 	ErrSnapshotSnapsNotFound = errors.New("no snapshot for the requested snaps found in the set with the given ID")
+	ErrSnapshotKeyRequired   = errors.New("snapshot is encrypted and no key was given")
 )
 
+// SnapshotKeyHeader is the header carrying the key used to encrypt a
+// snapshot export or to decrypt a snapshot import, as the base64 encoded
+// JSON form of a SnapshotKey.
+const SnapshotKeyHeader = "X-Snapd-Snapshot-Key"
+
 // A snapshotAction is used to request an operation on a snapshot.
 type snapshotAction struct {
 	SetID  uint64   `json:"set"`
 	Action string   `json:"action"`
 	Snaps  []string `json:"snaps,omitempty"`
 	Users  []string `json:"users,omitempty"`
+
+	Key *SnapshotKey `json:"key,omitempty"`
+	// the snapshot target to restore the snapshot set from
+	From string `json:"from,omitempty"`
+}
+
+// A SnapshotKey is what encrypted snapshots are encrypted with, either a
+// passphrase or the content of a key file.
+type SnapshotKey struct {
+	Passphrase string `json:"passphrase,omitempty"`
+	KeyFile    []byte `json:"key-file,omitempty"`
 }
 
 // A Snapshot is a collection of archives with a simple metadata json file
@@ -78,6 +96,17 @@ type Snapshot struct {
 	// the sum of the archive sizes
 	Size int64 `json:"size,omitempty"`
 
+	// the files whose content is kept in the snapshot chunk store
+	// rather than in the archives, keyed by archive path and then by
+	// the path of the file within the archive
+	Files map[string]map[string]SnapshotFile `json:"files,omitempty"`
+	// deduplication statistics, set if any files were put in the
+	// chunk store
+	Dedup *SnapshotDedup `json:"dedup,omitempty"`
+
+	// how the archives are encrypted, if they are
+	Encryption *SnapshotEncryption `json:"encryption,omitempty"`
+
 	// dynamic snapshot options
 	Options *snap.SnapshotOptions `json:"options,omitempty"`
 
@@ -89,6 +118,44 @@ type Snapshot struct {
 	// newer snapd just updates this flag on the fly for snapshots
 	// returned by List().
 	Auto bool `json:"auto,omitempty"`
+
+	// set if the snapshot is to be removed automatically, either
+	// because it is an automatic snapshot or because the retention
+	// policies do not keep it; it is removed the first time expired
+	// snapshots are looked for after that time
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// A SnapshotFile is the content of a file in a snapshot chunk store.
+type SnapshotFile struct {
+	SHA3_384 string `json:"sha3-384"`
+	Size     int64  `json:"size"`
+}
+
+// SnapshotDedup holds the deduplication statistics of a snapshot, as
+// of when it was saved.
+type SnapshotDedup struct {
+	// the size of the content that was added to the chunk store
+	Stored int64 `json:"stored"`
+	// the size of the content that was in the chunk store already
+	Reused int64 `json:"reused"`
+}
+
+// SnapshotEncryption describes how the archives of an encrypted snapshot
+// are encrypted, and how the key is derived from a SnapshotKey.
+type SnapshotEncryption struct {
+	Cipher string `json:"cipher"`
+	// the key derivation function, "argon2id" for passphrases and
+	// "hkdf-sha256" for key files
+	KDF  string `json:"kdf"`
+	Salt []byte `json:"salt"`
+	// the argon2id parameters, the memory being in KiB
+	KDFTime    uint32 `json:"kdf-time,omitempty"`
+	KDFMemory  uint32 `json:"kdf-memory,omitempty"`
+	KDFThreads uint8  `json:"kdf-threads,omitempty"`
+	// KeyCheck is derived alongside the encryption key, and tells
+	// whether a key is the right one without decrypting anything
+	KeyCheck []byte `json:"key-check"`
 }
 
 // IsValid checks whether the snapshot is missing information that
@@ -104,7 +171,9 @@ func (sh *Snapshot) ContentHash() ([]byte, error) {
 	sh2.SetID = 0
 	sh2.Time = time.Time{}
 	sh2.Auto = false
+	sh2.Expires = nil
 	sh2.Options = nil
+	sh2.Dedup = nil
 	h := sha256.New()
 	enc := json.NewEncoder(h)
 	if err := enc.Encode(&sh2); err != nil {
@@ -195,26 +264,47 @@ func (client *Client) ForgetSnapshots(setID uint64, snaps []string) (changeID st
 // CheckSnapshots verifies the archive checksums in the given snapshot set.
 //
 // If snaps or users are non-empty, limit to checking only those
-// archives of the snapshot.
-func (client *Client) CheckSnapshots(setID uint64, snaps []string, users []string) (changeID string, err error) {
+// archives of the snapshot. The content of encrypted snapshots can only
+// be verified if their key is given.
+func (client *Client) CheckSnapshots(setID uint64, snaps []string, users []string, key *SnapshotKey) (changeID string, err error) {
 	return client.snapshotAction(&snapshotAction{
 		SetID:  setID,
 		Action: "check",
 		Snaps:  snaps,
 		Users:  users,
+		Key:    key,
 	})
 }
 
 // RestoreSnapshots extracts the given snapshot set.
 //
 // If snaps or users are non-empty, limit to checking only those
-// archives of the snapshot.
-func (client *Client) RestoreSnapshots(setID uint64, snaps []string, users []string) (changeID string, err error) {
+// archives of the snapshot. Encrypted snapshots need their key to be
+// restored.
+func (client *Client) RestoreSnapshots(setID uint64, snaps []string, users []string, key *SnapshotKey) (changeID string, err error) {
 	return client.snapshotAction(&snapshotAction{
 		SetID:  setID,
 		Action: "restore",
 		Snaps:  snaps,
 		Users:  users,
+		Key:    key,
+	})
+}
+
+// RestoreSnapshotsFrom extracts the given snapshot set, stored in the
+// given snapshot target with "snap save --to". The snapshot set is
+// imported first, with a new set ID.
+//
+// Targets are either the absolute path of a directory or an
+// s3://<bucket>[/<prefix>] URL.
+func (client *Client) RestoreSnapshotsFrom(target string, setID uint64, snaps []string, users []string, key *SnapshotKey) (changeID string, err error) {
+	return client.snapshotAction(&snapshotAction{
+		SetID:  setID,
+		Action: "restore",
+		Snaps:  snaps,
+		Users:  users,
+		Key:    key,
+		From:   target,
 	})
 }
 
@@ -231,11 +321,31 @@ func (client *Client) snapshotAction(action *snapshotAction) (changeID string, e
 	return client.doAsync("POST", "/v2/snapshots", nil, headers, bytes.NewBuffer(data))
 }
 
-// SnapshotExport streams the requested snapshot set.
+func snapshotKeyHeaders(key *SnapshotKey, headers map[string]string) (map[string]string, error) {
+	if key == nil {
+		return headers, nil
+	}
+	data, err := json.Marshal(key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal snapshot key: %v", err)
+	}
+	if headers == nil {
+		headers = make(map[string]string, 1)
+	}
+	headers[SnapshotKeyHeader] = base64.StdEncoding.EncodeToString(data)
+	return headers, nil
+}
+
+// SnapshotExport streams the requested snapshot set, encrypted with the
+// given key if it is not nil.
 //
 // The return value includes the length of the returned stream.
-func (client *Client) SnapshotExport(setID uint64) (stream io.ReadCloser, contentLength int64, err error) {
-	rsp, err := client.raw(context.Background(), "GET", fmt.Sprintf("/v2/snapshots/%v/export", setID), nil, nil, nil)
+func (client *Client) SnapshotExport(setID uint64, key *SnapshotKey) (stream io.ReadCloser, contentLength int64, err error) {
+	headers, err := snapshotKeyHeaders(key, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	rsp, err := client.raw(context.Background(), "GET", fmt.Sprintf("/v2/snapshots/%v/export", setID), nil, headers, nil)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -266,14 +376,18 @@ type SnapshotImportSet struct {
 	Snaps []string `json:"snaps"`
 }
 
-// SnapshotImport imports an exported snapshot set.
-func (client *Client) SnapshotImport(exportStream io.Reader, size int64) (SnapshotImportSet, error) {
-	headers := map[string]string{
+// SnapshotImport imports an exported snapshot set. Encrypted snapshots
+// are decrypted with the given key, or are kept encrypted if it is nil.
+func (client *Client) SnapshotImport(exportStream io.Reader, size int64, key *SnapshotKey) (SnapshotImportSet, error) {
+	var importSet SnapshotImportSet
+	headers, err := snapshotKeyHeaders(key, map[string]string{
 		"Content-Type":   SnapshotExportMediaType,
 		"Content-Length": strconv.FormatInt(size, 10),
+	})
+	if err != nil {
+		return importSet, err
 	}
 
-	var importSet SnapshotImportSet
 	if _, err := client.doSync("POST", "/v2/snapshots", nil, headers, exportStream, &importSet); err != nil {
 		return importSet, err
 	}
@@ -98,7 +98,7 @@ func (cs *clientSuite) TestClientSnapshotSets(c *check.C) {
 	})
 }
 
-func (cs *clientSuite) testClientSnapshotActionFull(c *check.C, action string, users []string, f func() (string, error)) {
+func (cs *clientSuite) testClientSnapshotActionFull(c *check.C, action string, users []string, key *client.SnapshotKey, f func() (string, error)) {
 	cs.status = 202
 	cs.rsp = `{
 		"status-code": 202,
@@ -117,6 +117,7 @@ func (cs *clientSuite) testClientSnapshotActionFull(c *check.C, action string, u
 	c.Check(act.Action, check.Equals, action)
 	c.Check(act.Snaps, check.DeepEquals, []string{"asnap", "bsnap"})
 	c.Check(act.Users, check.DeepEquals, users)
+	c.Check(act.Key, check.DeepEquals, key)
 
 	c.Check(cs.req.Method, check.Equals, "POST")
 	c.Check(cs.req.URL.Path, check.Equals, "/v2/snapshots")
@@ -124,23 +125,51 @@ func (cs *clientSuite) testClientSnapshotActionFull(c *check.C, action string, u
 }
 
 func (cs *clientSuite) TestClientForgetSnapshot(c *check.C) {
-	cs.testClientSnapshotActionFull(c, "forget", nil, func() (string, error) {
+	cs.testClientSnapshotActionFull(c, "forget", nil, nil, func() (string, error) {
 		return cs.cli.ForgetSnapshots(42, []string{"asnap", "bsnap"})
 	})
 }
 
-func (cs *clientSuite) testClientSnapshotAction(c *check.C, action string, f func(uint64, []string, []string) (string, error)) {
-	cs.testClientSnapshotActionFull(c, action, []string{"auser", "buser"}, func() (string, error) {
-		return f(42, []string{"asnap", "bsnap"}, []string{"auser", "buser"})
+func (cs *clientSuite) testClientSnapshotAction(c *check.C, action string, key *client.SnapshotKey, f func(uint64, []string, []string, *client.SnapshotKey) (string, error)) {
+	cs.testClientSnapshotActionFull(c, action, []string{"auser", "buser"}, key, func() (string, error) {
+		return f(42, []string{"asnap", "bsnap"}, []string{"auser", "buser"}, key)
 	})
 }
 
 func (cs *clientSuite) TestClientCheckSnapshots(c *check.C) {
-	cs.testClientSnapshotAction(c, "check", cs.cli.CheckSnapshots)
+	cs.testClientSnapshotAction(c, "check", nil, cs.cli.CheckSnapshots)
+}
+
+func (cs *clientSuite) TestClientCheckSnapshotsWithKey(c *check.C) {
+	cs.testClientSnapshotAction(c, "check", &client.SnapshotKey{Passphrase: "secret"}, cs.cli.CheckSnapshots)
 }
 
 func (cs *clientSuite) TestClientRestoreSnapshots(c *check.C) {
-	cs.testClientSnapshotAction(c, "restore", cs.cli.RestoreSnapshots)
+	cs.testClientSnapshotAction(c, "restore", nil, cs.cli.RestoreSnapshots)
+}
+
+func (cs *clientSuite) TestClientRestoreSnapshotsWithKey(c *check.C) {
+	cs.testClientSnapshotAction(c, "restore", &client.SnapshotKey{KeyFile: []byte("key file content")}, cs.cli.RestoreSnapshots)
+}
+
+func (cs *clientSuite) TestClientRestoreSnapshotsFrom(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"status-code": 202,
+		"type": "async",
+		"change": "1too3"
+	}`
+	id, err := cs.cli.RestoreSnapshotsFrom("s3://bucket/prefix", 42, []string{"asnap"}, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "1too3")
+
+	act, err := client.UnmarshalSnapshotAction(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(act.SetID, check.Equals, uint64(42))
+	c.Check(act.Action, check.Equals, "restore")
+	c.Check(act.Snaps, check.DeepEquals, []string{"asnap"})
+	c.Check(act.From, check.Equals, "s3://bucket/prefix")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snapshots")
 }
 
 func (cs *clientSuite) TestClientExportSnapshotSpecificErr(c *check.C) {
@@ -149,7 +178,7 @@ func (cs *clientSuite) TestClientExportSnapshotSpecificErr(c *check.C) {
 	cs.rsp = content
 	cs.status = 400
 	cs.header = http.Header{"Content-Type": []string{"application/json"}}
-	_, _, err := cs.cli.SnapshotExport(42)
+	_, _, err := cs.cli.SnapshotExport(42, nil)
 	c.Check(err, check.ErrorMatches, "boom")
 }
 
@@ -174,7 +203,7 @@ func (cs *clientSuite) TestClientExportSnapshot(c *check.C) {
 		cs.rsp = t.content
 		cs.status = t.status
 
-		r, size, err := cs.cli.SnapshotExport(42)
+		r, size, err := cs.cli.SnapshotExport(42, nil)
 		if t.status == 200 {
 			c.Assert(err, check.IsNil, comm)
 			c.Assert(cs.countingCloser.closeCalled, check.Equals, 0)
@@ -212,7 +241,7 @@ func (cs *clientSuite) TestClientSnapshotImport(c *check.C) {
 
 		fakeSnapshotData := "fake"
 		r := strings.NewReader(fakeSnapshotData)
-		importSet, err := cs.cli.SnapshotImport(r, int64(len(fakeSnapshotData)))
+		importSet, err := cs.cli.SnapshotImport(r, int64(len(fakeSnapshotData)), nil)
 		if t.error != "" {
 			c.Assert(err, check.NotNil, comm)
 			c.Check(err.Error(), check.Equals, t.error, comm)
@@ -229,6 +258,30 @@ func (cs *clientSuite) TestClientSnapshotImport(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientExportSnapshotWithKey(c *check.C) {
+	cs.contentLength = int64(len("test-export"))
+	cs.header = http.Header{"Content-Type": []string{client.SnapshotExportMediaType}}
+	cs.rsp = "test-export"
+	cs.status = 200
+
+	_, _, err := cs.cli.SnapshotExport(42, &client.SnapshotKey{Passphrase: "secret"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Header.Get("Content-Type"), check.Equals, "")
+	// base64 of {"passphrase":"secret"}
+	c.Check(cs.req.Header.Get(client.SnapshotKeyHeader), check.Equals, "eyJwYXNzcGhyYXNlIjoic2VjcmV0In0=")
+}
+
+func (cs *clientSuite) TestClientSnapshotImportWithKey(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": {"set-id": 42, "snaps": ["foo"]}}`
+	cs.status = 200
+
+	_, err := cs.cli.SnapshotImport(strings.NewReader("fake"), 4, &client.SnapshotKey{KeyFile: []byte("key")})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Header.Get("Content-Type"), check.Equals, client.SnapshotExportMediaType)
+	// base64 of {"key-file":"a2V5"}
+	c.Check(cs.req.Header.Get(client.SnapshotKeyHeader), check.Equals, "eyJrZXktZmlsZSI6ImEyVjUifQ==")
+}
+
 func (cs *clientSuite) TestClientSnapshotContentHash(c *check.C) {
 	now := time.Now()
 	revno := snap.R(1)
@@ -274,6 +327,12 @@ func (cs *clientSuite) TestClientSnapshotContentHash(c *check.C) {
 	h5, err := sh5.ContentHash()
 	c.Assert(err, check.IsNil)
 	c.Check(h5, check.DeepEquals, h1)
+
+	// same except deduplication statistics means same hash
+	sh6 := &client.Snapshot{SetID: 1, Time: now, Snap: "asnap", Revision: revno, SHA3_384: sums, Dedup: &client.SnapshotDedup{Stored: 1, Reused: 2}}
+	h6, err := sh6.ContentHash()
+	c.Assert(err, check.IsNil)
+	c.Check(h6, check.DeepEquals, h1)
 }
 
 func (cs *clientSuite) TestClientSnapshotSetContentHash(c *check.C) {
@@ -21,6 +21,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"regexp"
 	"sort"
 
@@ -34,6 +35,9 @@ var shortChangesHelp = i18n.G("List system changes")
 var shortTasksHelp = i18n.G("List a change's tasks")
 var longChangesHelp = i18n.G(`
 The changes command displays a summary of system changes performed recently.
+
+With --follow, the command keeps running after the summary and reports the
+status updates of changes as they happen.
 `)
 var longTasksHelp = i18n.G(`
 The tasks command displays a summary of tasks associated with an individual
@@ -43,6 +47,7 @@ change.
 type cmdChanges struct {
 	clientMixin
 	timeMixin
+	Follow     bool `long:"follow"`
 	Positional struct {
 		Snap string `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -55,7 +60,10 @@ type cmdTasks struct {
 
 func init() {
 	addCommand("changes", shortChangesHelp, longChangesHelp,
-		func() flags.Commander { return &cmdChanges{} }, timeDescs, nil)
+		func() flags.Commander { return &cmdChanges{} }, timeDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"follow": i18n.G("Keep reporting status updates of changes as they happen"),
+		}), nil)
 	addCommand("tasks", shortTasksHelp, longTasksHelp,
 		func() flags.Commander { return &cmdTasks{} },
 		changeIDMixinOptDesc.also(timeDescs),
@@ -96,6 +104,10 @@ func (c *cmdChanges) Execute(args []string) error {
 		return nil
 	}
 
+	if c.Follow && c.Positional.Snap != "" {
+		return fmt.Errorf(i18n.G("cannot use --follow together with a snap name"))
+	}
+
 	opts := client.ChangesOptions{
 		SnapName: c.Positional.Snap,
 		Selector: client.ChangesAll,
@@ -106,7 +118,7 @@ func (c *cmdChanges) Execute(args []string) error {
 		return err
 	}
 
-	if len(changes) == 0 {
+	if len(changes) == 0 && !c.Follow {
 		fmt.Fprintln(Stderr, i18n.G("no changes found"))
 		return nil
 	}
@@ -122,15 +134,71 @@ func (c *cmdChanges) Execute(args []string) error {
 		if chg.ReadyTime.IsZero() {
 			readyTime = "-"
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", chg.ID, chg.Status, spawnTime, readyTime, chg.Summary)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s%s\n", chg.ID, chg.Status, spawnTime, readyTime, chg.Summary, changeProgress(chg))
 	}
 
 	w.Flush()
 	fmt.Fprintln(Stdout)
 
+	if c.Follow {
+		return c.follow()
+	}
+
 	return nil
 }
 
+// changeProgress returns the progress of the tasks of a change in flight
+// which report it, like downloads, to be shown after its summary.
+func changeProgress(chg *client.Change) string {
+	if chg.Ready {
+		return ""
+	}
+	var done, total int
+	var doing bool
+	for _, t := range chg.Tasks {
+		if t.Progress.Total <= 1 {
+			continue
+		}
+		done += t.Progress.Done
+		total += t.Progress.Total
+		if t.Status == "Doing" {
+			doing = true
+		}
+	}
+	if !doing {
+		return ""
+	}
+	return fmt.Sprintf(" (%.2f%%)", float64(done)/float64(total)*100.0)
+}
+
+// follow reports change status updates as snapd streams them, until the
+// stream ends.
+func (c *cmdChanges) follow() error {
+	stream, err := c.client.StreamEvents(&client.EventsOptions{
+		Types: []client.EventType{client.ChangeStatusEvent},
+	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	for {
+		ev, err := stream.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err == client.ErrStreamOverflow {
+			return fmt.Errorf(i18n.G("cannot follow changes: some updates were missed, see 'snap changes' for the current state"))
+		}
+		if err != nil {
+			return err
+		}
+		w := tabWriter()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", ev.ChangeID, ev.Status, c.fmtTime(ev.Time), ev.Summary)
+		w.Flush()
+	}
+}
+
 func (c *cmdTasks) Execute([]string) error {
 	chid, err := c.GetChangeID()
 	if err != nil {
@@ -232,6 +232,43 @@ Doing +2016-04-21T01:02:03Z +2016-04-21T01:02:04Z +some summary \(50.00%\)
 	c.Check(s.Stderr(), check.Equals, "")
 }
 
+func (s *SnapSuite) TestChangesProgress(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/changes")
+		fmt.Fprintln(w, `{"type": "sync", "result": [
+  {
+    "id":   "one",
+    "kind": "pre-download",
+    "summary": "Pre-download \"foo\", \"bar\" for auto-refresh",
+    "status": "Doing",
+    "ready": false,
+    "spawn-time": "2016-04-21T01:02:03Z",
+    "tasks": [
+      {"kind": "pre-download-snap", "summary": "foo", "status": "Done", "progress": {"done": 300, "total": 300}, "spawn-time": "2016-04-21T01:02:03Z"},
+      {"kind": "pre-download-snap", "summary": "bar", "status": "Doing", "progress": {"done": 0, "total": 100}, "spawn-time": "2016-04-21T01:02:03Z"}
+    ]
+  },
+  {
+    "id":   "two",
+    "kind": "install-snap",
+    "summary": "Install \"baz\"",
+    "status": "Do",
+    "ready": false,
+    "spawn-time": "2016-04-21T01:02:04Z",
+    "tasks": [{"kind": "download-snap", "summary": "baz", "status": "Do", "progress": {"done": 0, "total": 100}, "spawn-time": "2016-04-21T01:02:04Z"}]
+  }
+]}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `(?ms)ID +Status +Spawn +Ready +Summary
+one +Doing +2016-04-21T01:02:03Z +- +Pre-download "foo", "bar" for auto-refresh \(75.00%\)
+two +Do +2016-04-21T01:02:04Z +- +Install "baz"
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
 func (s *SnapSuite) TestNoChanges(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -250,3 +287,69 @@ func (s *SnapSuite) TestNoChanges(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Check(s.Stderr(), check.Equals, "no changes found\n")
 }
+
+func (s *SnapSuite) TestChangesFollow(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		switch n {
+		case 0:
+			c.Check(r.URL.Path, check.Equals, "/v2/changes")
+			fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+		case 1:
+			c.Check(r.URL.Path, check.Equals, "/v2/events")
+			c.Check(r.URL.Query().Get("types"), check.Equals, "change-status")
+			w.Header().Set("Content-Type", "application/json-seq")
+			fmt.Fprint(w, "\x1e"+`{"type":"change-status","time":"2016-04-21T01:02:03Z","change-id":"42","kind":"install-snap","summary":"Install foo","old-status":"Default","status":"Doing"}`+"\n")
+			fmt.Fprint(w, "\x1e"+`{"type":"change-status","time":"2016-04-21T01:02:04Z","change-id":"42","kind":"install-snap","summary":"Install foo","old-status":"Doing","status":"Done","ready":true}`+"\n")
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--follow", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Check(n, check.Equals, 2)
+	c.Check(s.Stdout(), check.Equals, `ID   Status  Spawn  Ready  Summary
+
+42   Doing  2016-04-21T01:02:03Z  Install foo
+42   Done  2016-04-21T01:02:04Z  Install foo
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestChangesFollowOverflow(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.URL.Path, check.Equals, "/v2/changes")
+			fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+		case 1:
+			c.Check(r.URL.Path, check.Equals, "/v2/events")
+			w.Header().Set("Content-Type", "application/json-seq")
+			fmt.Fprint(w, "\x1e"+`{"type":"change-status","time":"2016-04-21T01:02:03Z","change-id":"42","kind":"install-snap","summary":"Install foo","old-status":"Default","status":"Doing"}`+"\n")
+			fmt.Fprint(w, "\x1e"+`{"type":"overflow","time":"2016-04-21T01:02:04Z"}`+"\n")
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--follow", "--abs-time"})
+	c.Assert(err, check.ErrorMatches, `cannot follow changes: some updates were missed, see 'snap changes' for the current state`)
+	c.Check(n, check.Equals, 2)
+	c.Check(s.Stdout(), check.Equals, `ID   Status  Spawn  Ready  Summary
+
+42   Doing  2016-04-21T01:02:03Z  Install foo
+`)
+}
+
+func (s *SnapSuite) TestChangesFollowSnapName(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to %s", r.URL.Path)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--follow", "foo"})
+	c.Assert(err, check.ErrorMatches, "cannot use --follow together with a snap name")
+}
@@ -0,0 +1,204 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdConfdb struct{}
+
+var shortConfdbHelp = i18n.G("Manage confdb data")
+var longConfdbHelp = i18n.G(`
+The confdb command contains a selection of sub-commands to inspect and manage
+the data stored in confdbs, beyond reading and writing it with get and set.
+`)
+
+var shortConfdbHistoryHelp = i18n.G("Show the history of changes to a confdb")
+var longConfdbHistoryHelp = i18n.G(`
+The history command shows the most recent transactions committed into the
+confdb that the view belongs to, including when they were made, by whom and
+which storage paths they changed.
+`)
+
+var shortConfdbRevertHelp = i18n.G("Revert a confdb to before a transaction")
+var longConfdbRevertHelp = i18n.G(`
+The revert command restores the data of the confdb that the view belongs to
+as it was before the given transaction, undoing it and all the transactions
+committed after it. The changes are made through the view, so the snaps
+managing it can check them like any other change.
+`)
+
+type cmdConfdbHistory struct {
+	clientMixin
+	timeMixin
+	Positional struct {
+		View string `positional-arg-name:"<view-id>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type cmdConfdbRevert struct {
+	waitMixin
+	Positional struct {
+		View        string `positional-arg-name:"<view-id>"`
+		Transaction string `positional-arg-name:"<transaction>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+var confdbViewArgDesc = argDesc{
+	// TRANSLATORS: This needs to begin with < and end with >
+	name: i18n.G("<view-id>"),
+	// TRANSLATORS: This should not start with a lowercase letter.
+	desc: i18n.G("Confdb view in the format <account-id>/<confdb-schema>/<view>"),
+}
+
+func init() {
+	addConfdbCommand("history", shortConfdbHistoryHelp, longConfdbHistoryHelp, func() flags.Commander {
+		return &cmdConfdbHistory{}
+	}, timeDescs, []argDesc{confdbViewArgDesc})
+
+	addConfdbCommand("revert", shortConfdbRevertHelp, longConfdbRevertHelp, func() flags.Commander {
+		return &cmdConfdbRevert{}
+	}, waitDescs, []argDesc{
+		confdbViewArgDesc,
+		{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<transaction>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("ID of the first transaction to undo, as shown by 'snap confdb history'"),
+		},
+	})
+}
+
+func (x *cmdConfdb) Execute(args []string) error {
+	return flags.ErrHelp
+}
+
+func checkConfdbViewID(viewID string) error {
+	if err := validateConfdbFeatureFlag(); err != nil {
+		return err
+	}
+	if !isConfdbViewID(viewID) {
+		return errors.New(i18n.G("confdb-schema view id must conform to format: <account-id>/<confdb-schema>/<view>"))
+	}
+	return validateConfdbViewID(viewID)
+}
+
+func (x *cmdConfdbHistory) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if err := checkConfdbViewID(x.Positional.View); err != nil {
+		return err
+	}
+
+	entries, err := x.client.ConfdbHistory(x.Positional.View)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No confdb history found."))
+		return nil
+	}
+
+	w := tabWriter()
+	fmt.Fprintln(w, i18n.G("ID\tTime\tAuthor\tView\tChanges"))
+	for _, entry := range entries {
+		for i, change := range entry.Changes {
+			if i == 0 {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", entry.ID, x.fmtTime(entry.Time), fmtConfdbAuthor(entry.Author), fmtConfdbView(entry.View), fmtConfdbChange(change))
+			} else {
+				fmt.Fprintf(w, "\t\t\t\t%s\n", fmtConfdbChange(change))
+			}
+		}
+	}
+	w.Flush()
+	return nil
+}
+
+func fmtConfdbAuthor(author client.ConfdbAuthor) string {
+	switch {
+	case author.Snap != "":
+		return author.Snap
+	case author.UID != nil:
+		return fmt.Sprintf(i18n.G("uid %d"), *author.UID)
+	default:
+		return "-"
+	}
+}
+
+func fmtConfdbView(view string) string {
+	if view == "" {
+		return "-"
+	}
+	return view
+}
+
+func fmtConfdbValue(value any) string {
+	if value == nil {
+		return "-"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func fmtConfdbChange(change client.ConfdbPathChange) string {
+	return fmt.Sprintf("%s: %s -> %s", change.Path, fmtConfdbValue(change.Old), fmtConfdbValue(change.New))
+}
+
+func (x *cmdConfdbRevert) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if err := checkConfdbViewID(x.Positional.View); err != nil {
+		return err
+	}
+
+	transaction, err := strconv.Atoi(x.Positional.Transaction)
+	if err != nil || transaction <= 0 {
+		return fmt.Errorf(i18n.G("invalid transaction %q: must be a positive integer"), x.Positional.Transaction)
+	}
+
+	chgID, err := x.client.ConfdbRevert(x.Positional.View, transaction)
+	if err != nil {
+		return err
+	}
+
+	if _, err := x.wait(chgID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Reverted %s to before transaction %d.\n"), x.Positional.View, transaction)
+	return nil
+}
@@ -0,0 +1,130 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *confdbSuite) TestConfdbHistory(c *C) {
+	restore := s.mockConfdbFlag(c)
+	defer restore()
+
+	var reqs int
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		reqs++
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/confdb/foo/bar/baz")
+		c.Check(r.URL.Query().Get("history"), Equals, "true")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": [
+  {"id": 1, "time": "2025-01-02T15:04:05Z", "view": "baz", "author": {"uid": 1000}, "changes": [
+    {"path": "wifi.psk", "new": "secret"},
+    {"path": "wifi.ssid", "old": "foo", "new": "bar"}
+  ]},
+  {"id": 2, "time": "2025-01-03T15:04:05Z", "view": "baz", "author": {"snap": "some-snap"}, "changes": [
+    {"path": "wifi.psk", "old": "secret"}
+  ]}
+]}`)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"confdb", "history", "--abs-time", "foo/bar/baz"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, HasLen, 0)
+	c.Check(reqs, Equals, 1)
+	c.Check(s.Stdout(), Equals, `
+ID   Time                  Author     View  Changes
+1    2025-01-02T15:04:05Z  uid 1000   baz   wifi.psk: - -> "secret"
+                                            wifi.ssid: "foo" -> "bar"
+2    2025-01-03T15:04:05Z  some-snap  baz   wifi.psk: "secret" -> -
+`[1:])
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *confdbSuite) TestConfdbHistoryEmpty(c *C) {
+	restore := s.mockConfdbFlag(c)
+	defer restore()
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"confdb", "history", "foo/bar/baz"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "No confdb history found.\n")
+}
+
+func (s *confdbSuite) TestConfdbHistoryInvalidViewID(c *C) {
+	restore := s.mockConfdbFlag(c)
+	defer restore()
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"confdb", "history", "foo/bar"})
+	c.Assert(err, ErrorMatches, "confdb-schema view id must conform to format: <account-id>/<confdb-schema>/<view>")
+}
+
+func (s *confdbSuite) TestConfdbRevert(c *C) {
+	restore := s.mockConfdbFlag(c)
+	defer restore()
+
+	var reqs int
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch reqs {
+		case 0:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/confdb/foo/bar/baz")
+
+			var body map[string]any
+			c.Assert(json.NewDecoder(r.Body).Decode(&body), IsNil)
+			c.Check(body, DeepEquals, map[string]any{"action": "revert", "transaction": float64(3)})
+
+			w.WriteHeader(202)
+			fmt.Fprintln(w, asyncResp)
+		case 1:
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Path, Equals, "/v2/changes/123")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Errorf("unexpected request %d: %v", reqs+1, r)
+		}
+		reqs++
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"confdb", "revert", "foo/bar/baz", "3"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, HasLen, 0)
+	c.Check(reqs, Equals, 2)
+	c.Check(s.Stdout(), Equals, "Reverted foo/bar/baz to before transaction 3.\n")
+}
+
+func (s *confdbSuite) TestConfdbRevertInvalidTransaction(c *C) {
+	restore := s.mockConfdbFlag(c)
+	defer restore()
+
+	for _, txID := range []string{"foo", "0", "-1"} {
+		_, err := snap.Parser(snap.Client()).ParseArgs([]string{"confdb", "revert", "foo/bar/baz", "--", txID})
+		c.Check(err, ErrorMatches, fmt.Sprintf("invalid transaction %q: must be a positive integer", txID))
+	}
+}
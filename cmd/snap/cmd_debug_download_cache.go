@@ -0,0 +1,68 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/strutil"
+)
+
+type cmdDebugDownloadCache struct {
+	clientMixin
+}
+
+func init() {
+	addDebugCommand("download-cache",
+		"(internal) show download cache statistics",
+		"(internal) show download cache statistics",
+		func() flags.Commander {
+			return &cmdDebugDownloadCache{}
+		}, nil, nil)
+}
+
+func (x *cmdDebugDownloadCache) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	var stats struct {
+		Hits          int64 `json:"hits"`
+		SecondaryHits int64 `json:"secondary-hits"`
+		Misses        int64 `json:"misses"`
+		BytesSaved    int64 `json:"bytes-saved"`
+		Items         int   `json:"items"`
+		Size          int64 `json:"size"`
+	}
+	if err := x.client.DebugGet("download-cache", &stats, nil); err != nil {
+		return err
+	}
+
+	w := tabWriter()
+	fmt.Fprintf(w, "items:\t%d\n", stats.Items)
+	fmt.Fprintf(w, "size:\t%s\n", strutil.SizeToStr(stats.Size))
+	fmt.Fprintf(w, "hits:\t%d\n", stats.Hits)
+	fmt.Fprintf(w, "secondary-hits:\t%d\n", stats.SecondaryHits)
+	fmt.Fprintf(w, "misses:\t%d\n", stats.Misses)
+	fmt.Fprintf(w, "bytes-saved:\t%s\n", strutil.SizeToStr(stats.BytesSaved))
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,117 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap/snapdelta"
+)
+
+type cmdDelta struct{}
+
+var shortDeltaHelp = i18n.G("Manage deltas between snap files")
+var longDeltaHelp = i18n.G(`
+The delta command contains a selection of sub-commands to work with deltas
+between revisions of a snap, for distributing snaps outside of the store.
+`)
+
+var shortDeltaCreateHelp = i18n.G("Create a delta between two snap files")
+var longDeltaCreateHelp = i18n.G(`
+The create command writes the delta turning the old snap file into the new
+one. The delta can then be installed instead of the new snap file with
+'snap install --delta=<delta> --base-revision=<revision> <snap>' on systems
+where the old snap file is installed as the given revision.
+
+The revision of the new snap must be asserted, as the snap reconstructed from
+the delta is verified through its snap-revision assertion when installed.
+`)
+
+type cmdDeltaCreate struct {
+	Output     string `long:"output"`
+	Positional struct {
+		OldSnap flags.Filename `positional-arg-name:"<old-snap>"`
+		NewSnap flags.Filename `positional-arg-name:"<new-snap>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func init() {
+	cmd := addDeltaCommand("create", shortDeltaCreateHelp, longDeltaCreateHelp, func() flags.Commander {
+		return &cmdDeltaCreate{}
+	}, map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"output": i18n.G("Write the delta to this file"),
+	}, []argDesc{{
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<old-snap>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("Snap file the delta applies to"),
+	}, {
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<new-snap>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("Snap file the delta reconstructs"),
+	}})
+	cmd.extra = func(cmd *flags.Command) {
+		// TRANSLATORS: this describes the default delta filename, e.g. foo_2.delta for foo_2.snap
+		cmd.FindOptionByLongName("output").DefaultMask = i18n.G("<new-snap>.delta")
+	}
+}
+
+func (x *cmdDelta) Execute(args []string) error {
+	return flags.ErrHelp
+}
+
+var snapdeltaCreate = snapdelta.Create
+
+func (x *cmdDeltaCreate) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	oldPath := string(x.Positional.OldSnap)
+	newPath := string(x.Positional.NewSnap)
+	deltaPath := x.Output
+	if deltaPath == "" {
+		deltaPath = strings.TrimSuffix(filepath.Base(newPath), ".snap") + ".delta"
+	}
+
+	if err := snapdeltaCreate(oldPath, newPath, deltaPath); err != nil {
+		return err
+	}
+
+	deltaSt, err := os.Stat(deltaPath)
+	if err != nil {
+		return err
+	}
+	newSt, err := os.Stat(newPath)
+	if err != nil {
+		return err
+	}
+	// TRANSLATORS: %s is the path to the delta, followed by its size and the size of the new snap
+	fmt.Fprintf(Stdout, i18n.G("created: %s (%s, new snap is %s)\n"), deltaPath, strings.TrimSpace(fmtSize(deltaSt.Size())), strings.TrimSpace(fmtSize(newSt.Size())))
+	return nil
+}
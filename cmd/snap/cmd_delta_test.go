@@ -0,0 +1,95 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+type deltaSuite struct {
+	BaseSnapSuite
+}
+
+var _ = Suite(&deltaSuite{})
+
+func (s *deltaSuite) TestDeltaCreate(c *C) {
+	dir := c.MkDir()
+	oldPath := filepath.Join(dir, "foo_1.snap")
+	newPath := filepath.Join(dir, "foo_2.snap")
+	c.Assert(os.WriteFile(newPath, []byte(strings.Repeat("x", 4096)), 0644), IsNil)
+
+	var calls int
+	s.AddCleanup(snap.MockSnapdeltaCreate(func(o, n, delta string) error {
+		calls++
+		c.Check(o, Equals, oldPath)
+		c.Check(n, Equals, newPath)
+		return os.WriteFile(delta, []byte(strings.Repeat("d", 512)), 0644)
+	}))
+
+	deltaPath := filepath.Join(dir, "out.delta")
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"delta", "create", "--output", deltaPath, oldPath, newPath})
+	c.Assert(err, IsNil)
+	c.Assert(rest, HasLen, 0)
+	c.Check(calls, Equals, 1)
+	c.Check(s.Stdout(), Equals, "created: "+deltaPath+" (512B, new snap is 4096B)\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *deltaSuite) TestDeltaCreateDefaultOutput(c *C) {
+	dir := c.MkDir()
+	newPath := filepath.Join(dir, "foo_2.snap")
+	c.Assert(os.WriteFile(newPath, []byte("new"), 0644), IsNil)
+
+	cwd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	c.Assert(os.Chdir(c.MkDir()), IsNil)
+	defer os.Chdir(cwd)
+
+	s.AddCleanup(snap.MockSnapdeltaCreate(func(o, n, delta string) error {
+		c.Check(delta, Equals, "foo_2.delta")
+		return os.WriteFile(delta, []byte("d"), 0644)
+	}))
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"delta", "create", filepath.Join(dir, "foo_1.snap"), newPath})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "created: foo_2.delta (1B, new snap is 3B)\n")
+}
+
+func (s *deltaSuite) TestDeltaCreateError(c *C) {
+	s.AddCleanup(snap.MockSnapdeltaCreate(func(o, n, delta string) error {
+		return errors.New("cannot create delta: boom")
+	}))
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"delta", "create", "foo_1.snap", "foo_2.snap"})
+	c.Assert(err, ErrorMatches, "cannot create delta: boom")
+	c.Check(s.Stdout(), Equals, "")
+}
+
+func (s *deltaSuite) TestDeltaCreateMissingArgs(c *C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"delta", "create", "foo_1.snap"})
+	c.Assert(err, ErrorMatches, "the required argument `<new-snap>` was not provided")
+}
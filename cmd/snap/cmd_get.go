@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 
@@ -59,6 +60,9 @@ If the first argument passed into get is a confdb identifier matching the
 format <account-id>/<confdb>/<view>, get will use the confdb API. In this
 case, the command returns the data retrieved from the requested dot-separated
 view paths.
+
+With --watch, get prints the current values of the view paths and then prints
+them again every time a change to them is committed, until interrupted.
 `)
 
 type cmdGet struct {
@@ -72,6 +76,7 @@ type cmdGet struct {
 	Document bool   `short:"d"`
 	List     bool   `short:"l"`
 	Default  string `long:"default" unquote:"false"`
+	Watch    bool   `long:"watch"`
 }
 
 func init() {
@@ -93,6 +98,8 @@ func init() {
 			"t": i18n.G("Strict typing with nulls and quoted strings"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"default": i18n.G("A strictly typed default value to be used when none is found"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"watch": i18n.G("Keep printing the confdb values whenever they change"),
 		}, []argDesc{
 			{
 				name: "<snap>",
@@ -264,6 +271,16 @@ func (x *cmdGet) Execute(args []string) error {
 	snapName := string(x.Positional.Snap)
 	confKeys := x.Positional.Keys
 
+	if x.Watch {
+		if !isConfdbViewID(snapName) {
+			return fmt.Errorf(`cannot use --watch in non-confdb read`)
+		}
+		if x.Default != "" {
+			return fmt.Errorf("cannot use --default and --watch together")
+		}
+		return x.watchConfdb(snapName, confKeys)
+	}
+
 	var conf map[string]any
 	var err error
 	if isConfdbViewID(snapName) {
@@ -281,6 +298,10 @@ func (x *cmdGet) Execute(args []string) error {
 		return err
 	}
 
+	return x.output(conf, snapName, confKeys)
+}
+
+func (x *cmdGet) output(conf map[string]any, snapName string, confKeys []string) error {
 	switch {
 	case x.Document:
 		return x.outputJson(conf)
@@ -291,6 +312,63 @@ func (x *cmdGet) Execute(args []string) error {
 	}
 }
 
+// watchConfdb prints the current values of the confdb view paths and then
+// prints them again whenever they change, until snapd ends the stream.
+func (x *cmdGet) watchConfdb(confdbViewID string, confKeys []string) error {
+	if err := validateConfdbFeatureFlag(); err != nil {
+		return err
+	}
+
+	if err := validateConfdbViewID(confdbViewID); err != nil {
+		return err
+	}
+
+	// start watching before reading the current values so that no changes
+	// are missed in between
+	stream, err := x.client.ConfdbWatch(confdbViewID, confKeys)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	conf, err := x.getConfdb(confdbViewID, confKeys)
+	if err != nil {
+		var cerr *client.Error
+		if !errors.As(err, &cerr) || cerr.Kind != client.ErrorKindConfigNoSuchOption {
+			return err
+		}
+	}
+	if err := x.outputWatched(conf, confdbViewID, confKeys); err != nil {
+		return err
+	}
+
+	for {
+		change, err := stream.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if err := x.outputWatched(change.Values, confdbViewID, confKeys); err != nil {
+			return err
+		}
+	}
+}
+
+func (x *cmdGet) outputWatched(conf map[string]any, confdbViewID string, confKeys []string) error {
+	if len(conf) == 0 {
+		// none of the watched paths has a value (anymore)
+		if x.Document {
+			return x.outputJson(map[string]any{})
+		}
+		fmt.Fprintln(Stdout, "")
+		return nil
+	}
+	return x.output(conf, confdbViewID, confKeys)
+}
+
 func (x *cmdGet) getConfdb(confdbViewID string, confKeys []string) (map[string]any, error) {
 	if err := validateConfdbFeatureFlag(); err != nil {
 		return nil, err
@@ -339,7 +417,8 @@ func (x *cmdGet) getConfdb(confdbViewID string, confKeys []string) (map[string]a
 			return nil, fmt.Errorf(`internal error: expected "message" field under "error" in change result`)
 		}
 
-		return nil, errors.New(errMsg.(string))
+		kind, _ := errData["kind"].(string)
+		return nil, &client.Error{Kind: client.ErrorKind(kind), Message: errMsg.(string)}
 	}
 
 	return conf, nil
@@ -815,3 +815,89 @@ func (s *confdbSuite) TestForbidDefaultWithNonConfdbGet(c *check.C) {
 	c.Check(s.Stdout(), Equals, "")
 	c.Check(s.Stderr(), Equals, "")
 }
+
+func (s *confdbSuite) TestConfdbGetWatch(c *check.C) {
+	restore := s.mockConfdbFlag(c)
+	defer restore()
+
+	var watched, loaded bool
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		switch {
+		case r.URL.Path == "/v2/confdb/foo/bar/baz" && r.URL.Query().Get("watch") == "true":
+			c.Check(watched, Equals, false)
+			c.Check(loaded, Equals, false)
+			watched = true
+			c.Check(r.URL.Query().Get("keys"), Equals, "abc")
+
+			w.Header().Set("Content-Type", "application/json-seq")
+			fmt.Fprint(w, "\x1e"+`{"time":"2025-03-01T12:00:00Z","author":{"uid":1000},"changes":[{"path":"a.b.c","old":"cba","new":"xyz"}],"values":{"abc":"xyz"}}`+"\n")
+			fmt.Fprint(w, "\x1e"+`{"time":"2025-03-01T12:00:01Z","author":{"snap":"some-snap"},"changes":[{"path":"a.b.c","old":"xyz"}]}`+"\n")
+		case r.URL.Path == "/v2/confdb/foo/bar/baz":
+			c.Check(watched, Equals, true)
+			loaded = true
+			c.Check(r.URL.Query().Get("keys"), Equals, "abc")
+
+			w.WriteHeader(202)
+			fmt.Fprint(w, asyncResp)
+		case r.URL.Path == "/v2/changes/123":
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {"values": {"abc": "cba"}}}}`)
+		default:
+			c.Errorf("unexpected request %v", r)
+		}
+	})
+
+	rest, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"get", "--watch", "foo/bar/baz", "abc"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, HasLen, 0)
+	c.Check(watched, Equals, true)
+	c.Check(loaded, Equals, true)
+	c.Check(s.Stdout(), Equals, "cba\nxyz\n\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *confdbSuite) TestConfdbGetWatchNoData(c *check.C) {
+	restore := s.mockConfdbFlag(c)
+	defer restore()
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Query().Get("watch") == "true":
+			fmt.Fprint(w, "\x1e"+`{"time":"2025-03-01T12:00:00Z","author":{"uid":1000},"changes":[{"path":"a.b.c","new":"xyz"}],"values":{"abc":"xyz"}}`+"\n")
+		case r.URL.Path == "/v2/confdb/foo/bar/baz":
+			w.WriteHeader(202)
+			fmt.Fprint(w, asyncResp)
+		case r.URL.Path == "/v2/changes/123":
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {"error": {"message": "cannot get \"abc\" through foo/bar/baz: no data", "kind": "option-not-found"}}}}`)
+		default:
+			c.Errorf("unexpected request %v", r)
+		}
+	})
+
+	_, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"get", "--watch", "-d", "foo/bar/baz", "abc"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, `{}
+{
+	"abc": "xyz"
+}
+`)
+}
+
+func (s *confdbSuite) TestConfdbGetWatchErrors(c *check.C) {
+	restore := s.mockConfdbFlag(c)
+	defer restore()
+
+	_, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"get", "--watch", "snapname", "abc"})
+	c.Check(err, ErrorMatches, "cannot use --watch in non-confdb read")
+
+	_, err = snapset.Parser(snapset.Client()).ParseArgs([]string{"get", "--watch", "--default", "1", "foo/bar/baz", "abc"})
+	c.Check(err, ErrorMatches, "cannot use --default and --watch together")
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query().Get("watch"), Equals, "true")
+		w.WriteHeader(400)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 400, "result": {"message": "cannot get \"foo\" through foo/bar/baz: no matching rule", "kind": "option-not-available"}}`)
+	})
+	_, err = snapset.Parser(snapset.Client()).ParseArgs([]string{"get", "--watch", "foo/bar/baz", "foo"})
+	c.Check(err, ErrorMatches, `cannot get "foo" through foo/bar/baz: no matching rule`)
+}
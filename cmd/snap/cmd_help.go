@@ -221,10 +221,13 @@ var helpCategories = []helpCategory{
 		Label:       i18n.G("Permissions"),
 		Description: i18n.G("manage permissions"),
 		Commands:    []string{"connections", "interface", "connect", "disconnect"},
+		AllOnlyCommands: []string{"prompting-rules", "add-prompting-rule", "remove-prompting-rule",
+			"export-prompting-rules", "import-prompting-rules"},
 	}, {
-		Label:       i18n.G("Configuration"),
-		Description: i18n.G("system administration and configuration"),
-		Commands:    []string{"get", "set", "unset", "wait"},
+		Label:           i18n.G("Configuration"),
+		Description:     i18n.G("system administration and configuration"),
+		Commands:        []string{"get", "set", "unset", "wait"},
+		AllOnlyCommands: []string{"confdb"},
 	}, {
 		Label:       i18n.G("App Aliases"),
 		Description: i18n.G("manage aliases"),
@@ -262,7 +265,7 @@ var helpCategories = []helpCategory{
 		Label:           i18n.G("Development"),
 		Description:     i18n.G("developer-oriented features"),
 		Commands:        []string{"download", "pack", "run", "try", "sign"},
-		AllOnlyCommands: []string{"prepare-image", "export-key"},
+		AllOnlyCommands: []string{"prepare-image", "export-key", "delta"},
 	}, {
 		Label:       i18n.G("Quota Groups"),
 		Description: i18n.G("Manage quota groups for snaps"),
@@ -245,6 +245,9 @@ func (iw *infoWriter) maybePrintHealth() {
 	if !health.Timestamp.IsZero() {
 		fmt.Fprintf(iw, "  checked:\t%s\n", iw.fmtTime(health.Timestamp))
 	}
+	if health.LastRun != nil {
+		fmt.Fprintf(iw, "  last-run:\t%s\n", iw.fmtTime(*health.LastRun))
+	}
 	if !health.Revision.Unset() {
 		fmt.Fprintf(iw, "  revision:\t%s\n", health.Revision)
 	}
@@ -1118,6 +1118,13 @@ func (infoSuite) TestMaybePrintHealth(c *check.C) {
 		Revision:  snaplib.R("42"),
 		Timestamp: t0,
 	}
+	t1 := t0.Add(time.Hour)
+	periodicHealth := &client.SnapHealth{
+		Status:    "okay",
+		Revision:  snaplib.R("42"),
+		Timestamp: t0,
+		LastRun:   &t1,
+	}
 
 	tests := []T{
 		{snap: nil, verbose: false, expected: ""},
@@ -1149,6 +1156,12 @@ func (infoSuite) TestMaybePrintHealth(c *check.C) {
   code:	godot-is-a-lie
   checked:	10:24AM
   revision:	42
+`},
+		{snap: &client.Snap{Health: periodicHealth}, verbose: true, expected: `health:
+  status:	okay
+  checked:	10:24AM
+  last-run:	11:24AM
+  revision:	42
 `},
 	}
 
@@ -0,0 +1,353 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var shortPromptingRulesHelp = i18n.G("List prompting rules")
+var longPromptingRulesHelp = i18n.G(`
+The prompting-rules command lists the rules which automatically reply to the
+permission requests of snaps on behalf of the current user, including the rules
+provisioned by the system administrator. System rules apply to every user and
+take precedence over the rules of the user.
+`)
+
+var shortAddPromptingRuleHelp = i18n.G("Add a prompting rule")
+var longAddPromptingRuleHelp = i18n.G(`
+The add-prompting-rule command adds a rule which automatically allows or denies
+the requests of the given snap through the given interface to access paths
+matching the given pattern. The rule never expires.
+
+For example, to allow firefox to read and write files in the Downloads
+directory of the current user:
+
+    $ snap add-prompting-rule firefox home '/home/*/Downloads/**' --allow=read --allow=write
+`)
+
+var shortRemovePromptingRuleHelp = i18n.G("Remove prompting rules")
+var longRemovePromptingRuleHelp = i18n.G(`
+The remove-prompting-rule command removes the prompting rules with the given
+IDs, or all rules of the current user for the given snap and/or interface.
+Rules provisioned by the system administrator cannot be removed.
+`)
+
+var shortExportPromptingRulesHelp = i18n.G("Export prompting rules")
+var longExportPromptingRulesHelp = i18n.G(`
+The export-prompting-rules command writes the prompting rules of the current
+user to standard output as JSON, in a form which can be imported with the
+import-prompting-rules command. Only permissions which never expire are
+exported, and rules provisioned by the system administrator are not.
+`)
+
+var shortImportPromptingRulesHelp = i18n.G("Import prompting rules")
+var longImportPromptingRulesHelp = i18n.G(`
+The import-prompting-rules command adds the prompting rules from the given file,
+as written by the export-prompting-rules command, for the current user. If no
+file is given, or the file is "-", the rules are read from standard input.
+
+Either all rules are imported, or none is.
+`)
+
+func init() {
+	addCommand("prompting-rules", shortPromptingRulesHelp, longPromptingRulesHelp,
+		func() flags.Commander { return &cmdPromptingRules{} },
+		map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"snap": i18n.G("Only list the rules of the given snap"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"interface": i18n.G("Only list the rules of the given interface"),
+		}, nil)
+	addCommand("add-prompting-rule", shortAddPromptingRuleHelp, longAddPromptingRuleHelp,
+		func() flags.Commander { return &cmdAddPromptingRule{} },
+		map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"allow": i18n.G("Permission to allow (can be repeated)"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"deny": i18n.G("Permission to deny (can be repeated)"),
+		}, []argDesc{{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<snap>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("The snap to which the rule applies"),
+		}, {
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<interface>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("The interface to which the rule applies"),
+		}, {
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<path-pattern>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("The pattern of the paths to which the rule applies"),
+		}})
+	addCommand("remove-prompting-rule", shortRemovePromptingRuleHelp, longRemovePromptingRuleHelp,
+		func() flags.Commander { return &cmdRemovePromptingRule{} },
+		map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"snap": i18n.G("Remove all rules of the given snap"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"interface": i18n.G("Remove all rules of the given interface"),
+		}, []argDesc{{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<rule-id>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("The ID of the rule to remove"),
+		}})
+	addCommand("export-prompting-rules", shortExportPromptingRulesHelp, longExportPromptingRulesHelp,
+		func() flags.Commander { return &cmdExportPromptingRules{} }, nil, nil)
+	addCommand("import-prompting-rules", shortImportPromptingRulesHelp, longImportPromptingRulesHelp,
+		func() flags.Commander { return &cmdImportPromptingRules{} },
+		nil, []argDesc{{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<filename>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("File with the rules to import (defaults to stdin)"),
+		}})
+}
+
+type cmdPromptingRules struct {
+	clientMixin
+
+	Snap      string `long:"snap"`
+	Interface string `long:"interface"`
+}
+
+// fmtPromptingPermissions formats the permissions of a rule as a sorted,
+// comma separated list of <permission>:<outcome>.
+func fmtPromptingPermissions(constraints *client.PromptingRuleConstraints) string {
+	if constraints == nil || len(constraints.Permissions) == 0 {
+		return "-"
+	}
+	perms := make([]string, 0, len(constraints.Permissions))
+	for perm, entry := range constraints.Permissions {
+		if entry == nil {
+			continue
+		}
+		perms = append(perms, perm+":"+entry.Outcome)
+	}
+	sort.Strings(perms)
+	return strings.Join(perms, ",")
+}
+
+func (x *cmdPromptingRules) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	rules, err := x.client.PromptingRules(&client.PromptingRulesOptions{
+		Snap:      x.Snap,
+		Interface: x.Interface,
+	})
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No prompting rules found."))
+		return nil
+	}
+
+	w := tabWriter()
+	fmt.Fprintln(w, i18n.G("ID\tSnap\tInterface\tPath-pattern\tPermissions\tScope"))
+	for _, rule := range rules {
+		pathPattern := "-"
+		if rule.Constraints != nil && rule.Constraints.PathPattern != "" {
+			pathPattern = rule.Constraints.PathPattern
+		}
+		scope := "user"
+		if rule.System {
+			scope = "system"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", rule.ID, rule.Snap, rule.Interface,
+			pathPattern, fmtPromptingPermissions(rule.Constraints), scope)
+	}
+	w.Flush()
+	return nil
+}
+
+type cmdAddPromptingRule struct {
+	clientMixin
+
+	Allow      []string `long:"allow"`
+	Deny       []string `long:"deny"`
+	Positional struct {
+		Snap        string `required:"yes"`
+		Interface   string `required:"yes"`
+		PathPattern string `required:"yes"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (x *cmdAddPromptingRule) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if len(x.Allow) == 0 && len(x.Deny) == 0 {
+		return fmt.Errorf(i18n.G("cannot add prompting rule without any --allow or --deny permission"))
+	}
+
+	permissions := make(map[string]*client.PromptingPermissionEntry, len(x.Allow)+len(x.Deny))
+	for _, perms := range []struct {
+		names   []string
+		outcome string
+	}{
+		{x.Allow, "allow"},
+		{x.Deny, "deny"},
+	} {
+		for _, perm := range perms.names {
+			if _, ok := permissions[perm]; ok {
+				return fmt.Errorf(i18n.G("cannot add prompting rule: permission %q given more than once"), perm)
+			}
+			permissions[perm] = &client.PromptingPermissionEntry{
+				Outcome:  perms.outcome,
+				Lifespan: "forever",
+			}
+		}
+	}
+
+	rule, err := x.client.AddPromptingRule(&client.PromptingRuleContents{
+		Snap:      x.Positional.Snap,
+		Interface: x.Positional.Interface,
+		Constraints: &client.PromptingRuleConstraints{
+			PathPattern: x.Positional.PathPattern,
+			Permissions: permissions,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	// TRANSLATORS: %s is the ID of the rule
+	fmt.Fprintf(Stdout, i18n.G("Added prompting rule %s.\n"), rule.ID)
+	return nil
+}
+
+type cmdRemovePromptingRule struct {
+	clientMixin
+
+	Snap       string `long:"snap"`
+	Interface  string `long:"interface"`
+	Positional struct {
+		IDs []string
+	} `positional-args:"yes"`
+}
+
+func (x *cmdRemovePromptingRule) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	bySelector := x.Snap != "" || x.Interface != ""
+	switch {
+	case bySelector && len(x.Positional.IDs) > 0:
+		return fmt.Errorf(i18n.G("cannot use rule IDs together with --snap or --interface"))
+	case !bySelector && len(x.Positional.IDs) == 0:
+		return fmt.Errorf(i18n.G("cannot remove prompting rules: no rule IDs, --snap or --interface given"))
+	}
+
+	if bySelector {
+		rules, err := x.client.RemovePromptingRules(x.Snap, x.Interface)
+		if err != nil {
+			return err
+		}
+		// TRANSLATORS: %d is the number of removed rules
+		fmt.Fprintf(Stdout, i18n.NG("Removed %d prompting rule.\n", "Removed %d prompting rules.\n", len(rules)), len(rules))
+		return nil
+	}
+
+	for _, id := range x.Positional.IDs {
+		if _, err := x.client.RemovePromptingRule(id); err != nil {
+			return err
+		}
+		// TRANSLATORS: %s is the ID of the rule
+		fmt.Fprintf(Stdout, i18n.G("Removed prompting rule %s.\n"), id)
+	}
+	return nil
+}
+
+type cmdExportPromptingRules struct {
+	clientMixin
+}
+
+func (x *cmdExportPromptingRules) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	ruleSet, err := x.client.ExportPromptingRules()
+	if err != nil {
+		return err
+	}
+	if ruleSet.Rules == nil {
+		ruleSet.Rules = []*client.PromptingRuleContents{}
+	}
+	enc := json.NewEncoder(Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ruleSet)
+}
+
+type cmdImportPromptingRules struct {
+	clientMixin
+
+	Positional struct {
+		Filename flags.Filename
+	} `positional-args:"yes"`
+}
+
+func (x *cmdImportPromptingRules) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if x.Positional.Filename == "" || x.Positional.Filename == "-" {
+		data, err = io.ReadAll(Stdin)
+	} else {
+		data, err = os.ReadFile(string(x.Positional.Filename))
+	}
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot read prompting rules: %v"), err)
+	}
+
+	var ruleSet client.PromptingRuleSet
+	if err := json.Unmarshal(data, &ruleSet); err != nil {
+		return fmt.Errorf(i18n.G("cannot decode prompting rules: %v"), err)
+	}
+
+	rules, err := x.client.ImportPromptingRules(&ruleSet)
+	if err != nil {
+		return err
+	}
+	// TRANSLATORS: %d is the number of imported rules
+	fmt.Fprintf(Stdout, i18n.NG("Imported %d prompting rule.\n", "Imported %d prompting rules.\n", len(rules)), len(rules))
+	return nil
+}
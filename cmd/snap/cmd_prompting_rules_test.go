@@ -0,0 +1,306 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+type promptingRulesSuite struct {
+	BaseSnapSuite
+}
+
+var _ = check.Suite(&promptingRulesSuite{})
+
+func (s *promptingRulesSuite) TestPromptingRules(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/interfaces/requests/rules")
+		c.Check(r.URL.Query().Get("snap"), check.Equals, "firefox")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": [
+			{
+				"id": "0000000000000001",
+				"snap": "firefox",
+				"interface": "home",
+				"constraints": {
+					"path-pattern": "/home/*/Documents/**",
+					"permissions": {"read": {"outcome": "deny", "lifespan": "forever"}}
+				},
+				"system": true
+			},
+			{
+				"id": "0000000000000002",
+				"snap": "firefox",
+				"interface": "home",
+				"constraints": {
+					"path-pattern": "/home/test/Downloads/**",
+					"permissions": {
+						"write": {"outcome": "allow", "lifespan": "forever"},
+						"read": {"outcome": "allow", "lifespan": "forever"}
+					}
+				}
+			}
+		]}`)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prompting-rules", "--snap=firefox"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, ""+
+		"ID                Snap     Interface  Path-pattern             Permissions             Scope\n"+
+		"0000000000000001  firefox  home       /home/*/Documents/**     read:deny               system\n"+
+		"0000000000000002  firefox  home       /home/test/Downloads/**  read:allow,write:allow  user\n")
+}
+
+func (s *promptingRulesSuite) TestPromptingRulesNone(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"prompting-rules"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No prompting rules found.\n")
+}
+
+func (s *promptingRulesSuite) TestAddPromptingRule(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/interfaces/requests/rules")
+		body, err := io.ReadAll(r.Body)
+		c.Assert(err, check.IsNil)
+		var req map[string]any
+		c.Assert(json.Unmarshal(body, &req), check.IsNil)
+		c.Check(req, check.DeepEquals, map[string]any{
+			"action": "add",
+			"rule": map[string]any{
+				"snap":      "firefox",
+				"interface": "home",
+				"constraints": map[string]any{
+					"path-pattern": "/home/*/Downloads/**",
+					"permissions": map[string]any{
+						"read":    map[string]any{"outcome": "allow", "lifespan": "forever"},
+						"write":   map[string]any{"outcome": "allow", "lifespan": "forever"},
+						"execute": map[string]any{"outcome": "deny", "lifespan": "forever"},
+					},
+				},
+			},
+		})
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"id": "0000000000000003"}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"add-prompting-rule", "firefox", "home", "/home/*/Downloads/**",
+		"--allow=read", "--allow=write", "--deny=execute"})
+	c.Assert(err, check.IsNil)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "Added prompting rule 0000000000000003.\n")
+}
+
+func (s *promptingRulesSuite) TestAddPromptingRuleErrors(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"add-prompting-rule", "firefox", "home", "/home/**"})
+	c.Check(err, check.ErrorMatches, "cannot add prompting rule without any --allow or --deny permission")
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"add-prompting-rule", "firefox", "home", "/home/**",
+		"--allow=read", "--deny=read"})
+	c.Check(err, check.ErrorMatches, `cannot add prompting rule: permission "read" given more than once`)
+}
+
+func (s *promptingRulesSuite) TestRemovePromptingRule(c *check.C) {
+	var paths []string
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		c.Check(r.Method, check.Equals, "POST")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"id": "x"}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remove-prompting-rule", "0000000000000001", "0000000000000002"})
+	c.Assert(err, check.IsNil)
+	c.Check(paths, check.DeepEquals, []string{
+		"/v2/interfaces/requests/rules/0000000000000001",
+		"/v2/interfaces/requests/rules/0000000000000002",
+	})
+	c.Check(s.Stdout(), check.Equals, "Removed prompting rule 0000000000000001.\nRemoved prompting rule 0000000000000002.\n")
+}
+
+func (s *promptingRulesSuite) TestRemovePromptingRuleSystem(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 403, "result": {
+			"message": "cannot modify rule provisioned by the system administrator",
+			"kind": "interfaces-requests-rule-is-system"
+		}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remove-prompting-rule", "0000000000000001"})
+	c.Check(err, check.ErrorMatches, "cannot modify rule provisioned by the system administrator")
+	c.Check(s.Stdout(), check.Equals, "")
+}
+
+func (s *promptingRulesSuite) TestRemovePromptingRulesBySnap(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.URL.Path, check.Equals, "/v2/interfaces/requests/rules")
+		body, err := io.ReadAll(r.Body)
+		c.Assert(err, check.IsNil)
+		c.Check(string(body), check.Equals, `{"action":"remove","selector":{"snap":"firefox","interface":"home"}}`+"\n")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": [{"id": "a"}, {"id": "b"}]}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remove-prompting-rule", "--snap=firefox", "--interface=home"})
+	c.Assert(err, check.IsNil)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "Removed 2 prompting rules.\n")
+}
+
+func (s *promptingRulesSuite) TestRemovePromptingRuleErrors(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remove-prompting-rule"})
+	c.Check(err, check.ErrorMatches, "cannot remove prompting rules: no rule IDs, --snap or --interface given")
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"remove-prompting-rule", "--snap=firefox", "0000000000000001"})
+	c.Check(err, check.ErrorMatches, "cannot use rule IDs together with --snap or --interface")
+}
+
+func (s *promptingRulesSuite) TestExportPromptingRules(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Query().Get("export"), check.Equals, "true")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"rules": [
+			{
+				"snap": "firefox",
+				"interface": "home",
+				"constraints": {
+					"path-pattern": "/home/test/Downloads/**",
+					"permissions": {"write": {"outcome": "allow", "lifespan": "forever"}}
+				}
+			}
+		]}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"export-prompting-rules"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `{
+  "rules": [
+    {
+      "snap": "firefox",
+      "interface": "home",
+      "constraints": {
+        "path-pattern": "/home/test/Downloads/**",
+        "permissions": {
+          "write": {
+            "outcome": "allow",
+            "lifespan": "forever"
+          }
+        }
+      }
+    }
+  ]
+}
+`)
+}
+
+const exportedPromptingRules = `{"rules": [
+	{
+		"snap": "firefox",
+		"interface": "home",
+		"constraints": {
+			"path-pattern": "/home/test/Downloads/**",
+			"permissions": {"write": {"outcome": "allow", "lifespan": "forever"}}
+		}
+	}
+]}`
+
+func (s *promptingRulesSuite) mockImportHandler(c *check.C, n *int) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		*n++
+		c.Check(r.Method, check.Equals, "POST")
+		body, err := io.ReadAll(r.Body)
+		c.Assert(err, check.IsNil)
+		var req map[string]any
+		c.Assert(json.Unmarshal(body, &req), check.IsNil)
+		c.Check(req["action"], check.Equals, "import")
+		c.Check(req["rules"], check.HasLen, 1)
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": [{"id": "0000000000000004"}]}`)
+	})
+}
+
+func (s *promptingRulesSuite) TestImportPromptingRulesFromFile(c *check.C) {
+	n := 0
+	s.mockImportHandler(c, &n)
+
+	path := filepath.Join(c.MkDir(), "rules.json")
+	c.Assert(os.WriteFile(path, []byte(exportedPromptingRules), 0o644), check.IsNil)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"import-prompting-rules", path})
+	c.Assert(err, check.IsNil)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "Imported 1 prompting rule.\n")
+}
+
+func (s *promptingRulesSuite) TestImportPromptingRulesFromStdin(c *check.C) {
+	n := 0
+	s.mockImportHandler(c, &n)
+	s.stdin.WriteString(exportedPromptingRules)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"import-prompting-rules", "-"})
+	c.Assert(err, check.IsNil)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "Imported 1 prompting rule.\n")
+}
+
+func (s *promptingRulesSuite) TestImportPromptingRulesErrors(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"import-prompting-rules", filepath.Join(c.MkDir(), "missing")})
+	c.Check(err, check.ErrorMatches, "cannot read prompting rules: .* no such file or directory")
+
+	s.stdin.WriteString("not json")
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"import-prompting-rules"})
+	c.Check(err, check.ErrorMatches, "cannot decode prompting rules: .*")
+
+	s.stdin.WriteString(`{"rules": []}`)
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"import-prompting-rules"})
+	c.Check(err, check.ErrorMatches, "cannot import prompting rules: no rules given")
+}
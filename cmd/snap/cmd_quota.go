@@ -85,6 +85,18 @@ with the remove-quota command and recreated with a lower limit. Increasing the
 memory limit for a quota group does not restart any services associated with 
 snaps in the quota group.
 
+The memory high limit is a soft limit below the memory limit: processes of a
+quota group using more memory than it are throttled and their memory reclaimed
+aggressively, instead of being killed. It requires cgroup v2. The OOM policy
+decides what happens when processes of the group are killed for running out of
+memory anyway: "notify" (the default) only records it, "restart-service"
+restarts the services that were killed and "restart-group" restarts all the
+services of the group. Sub-groups without a policy use the one of their parent.
+The policy is also applied when the memory pressure of the group, that is the
+percentage of time its processes were stalled waiting for memory over the last
+10 seconds, goes over the value set with --memory-pressure. This requires
+cgroup v2 too.
+
 The CPU limit for a quota group can be both increased and decreased after being
 set on a quota group. The CPU limit can be specified as a single percentage which
 means that the quota group is allowed an overall percentage of the CPU resources. Setting
@@ -99,6 +111,13 @@ The threads limit for a quota group can be increased but not decreased. To
 decrease the threads limit for a quota group, the entire group must be removed
 with the remove-quota command and recreated with a lower limit.
 
+The IO limits for a quota group can be both increased and decreased after being
+set on a quota group. The IO weight is relative to the other groups and is a
+number between 1 and 10000. Bandwidth and IOPS limits are set per block device
+as <device>=<value>, for example --io-read-bandwidth=/dev/sda=10MB, and can be
+repeated to limit several devices. The per-device limits of sub-groups cannot
+exceed the limits set for that device in the parent group.
+
 The journal limits can be increased and decreased after being set on a group.
 Setting a journal limit will cause the snaps in the group to be put into the same
 journal namespace. This will affect the behaviour of the log command.
@@ -117,11 +136,19 @@ func init() {
 		func() flags.Commander { return &cmdSetQuota{} },
 		waitDescs.also(map[string]string{
 			"memory":             i18n.G("Memory quota"),
+			"memory-high":        i18n.G("Memory usage above which processes are throttled"),
+			"oom-policy":         i18n.G("What to do when processes run out of memory: notify, restart-service or restart-group"),
+			"memory-pressure":    i18n.G("Memory pressure in percent above which the OOM policy is applied"),
 			"cpu":                i18n.G("CPU quota"),
 			"cpu-set":            i18n.G("CPU set quota"),
 			"threads":            i18n.G("Threads quota"),
 			"journal-size":       i18n.G("Journal size quota"),
 			"journal-rate-limit": i18n.G("Journal rate limit as <message count>/<message period>"),
+			"io-weight":          i18n.G("IO weight quota, between 1 and 10000"),
+			"io-read-bandwidth":  i18n.G("IO read bandwidth quota of a device per second as <device>=<size>"),
+			"io-write-bandwidth": i18n.G("IO write bandwidth quota of a device per second as <device>=<size>"),
+			"io-read-iops":       i18n.G("IO read operations per second quota of a device as <device>=<count>"),
+			"io-write-iops":      i18n.G("IO write operations per second quota of a device as <device>=<count>"),
 			"parent":             i18n.G("Parent quota group"),
 		}), nil)
 	addCommand("quota", shortQuotaHelp, longQuotaHelp, func() flags.Commander { return &cmdQuota{} }, nil, nil)
@@ -133,12 +160,21 @@ type cmdSetQuota struct {
 	waitMixin
 
 	MemoryMax        string `long:"memory" optional:"true"`
+	MemoryHigh       string `long:"memory-high" optional:"true"`
+	OOMPolicy        string `long:"oom-policy" optional:"true" choice:"notify" choice:"restart-service" choice:"restart-group"`
+	MemoryPressure   string `long:"memory-pressure" optional:"true"`
 	CPUMax           string `long:"cpu" optional:"true"`
 	CPUSet           string `long:"cpu-set" optional:"true"`
 	ThreadsMax       string `long:"threads" optional:"true"`
 	JournalSizeMax   string `long:"journal-size" optional:"true"`
 	JournalRateLimit string `long:"journal-rate-limit" optional:"true"`
-	Parent           string `long:"parent" optional:"true"`
+	IOWeight         string `long:"io-weight" optional:"true"`
+	// IO bandwidth and IOPS limits are per device and can be repeated
+	IOReadBandwidth  []string `long:"io-read-bandwidth"`
+	IOWriteBandwidth []string `long:"io-write-bandwidth"`
+	IOReadIOPS       []string `long:"io-read-iops"`
+	IOWriteIOPS      []string `long:"io-write-iops"`
+	Parent           string   `long:"parent" optional:"true"`
 	Positional       struct {
 		GroupName string        `positional-arg-name:"<group-name>" required:"true"`
 		Snaps     []serviceName `positional-arg-name:"<snap-or-service>" optional:"true"`
@@ -194,6 +230,88 @@ func parseJournalRateQuota(journalRateLimit string) (count int, period time.Dura
 	return count, period, nil
 }
 
+// parseIODeviceQuota parses an io device quota string of the form
+// <device>=<value>, e.g "/dev/sda=10MB".
+func parseIODeviceQuota(quota string) (device, value string, err error) {
+	idx := strings.LastIndex(quota, "=")
+	if idx <= 0 || idx == len(quota)-1 {
+		return "", "", fmt.Errorf("io quota must be of the form <device>=<value>")
+	}
+	return quota[:idx], quota[idx+1:], nil
+}
+
+func (x *cmdSetQuota) parseIOQuotas() (*client.QuotaIOValues, error) {
+	var ioValues client.QuotaIOValues
+	if x.IOWeight != "" {
+		value, err := strconv.ParseUint(x.IOWeight, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("cannot use io weight value %q", x.IOWeight)
+		}
+		ioValues.Weight = int(value)
+	}
+
+	// keep the devices in the order they were first given
+	deviceValues := func(device string) *client.QuotaIODeviceValues {
+		for i := range ioValues.Devices {
+			if ioValues.Devices[i].Device == device {
+				return &ioValues.Devices[i]
+			}
+		}
+		ioValues.Devices = append(ioValues.Devices, client.QuotaIODeviceValues{Device: device})
+		return &ioValues.Devices[len(ioValues.Devices)-1]
+	}
+
+	bandwidths := []struct {
+		quotas []string
+		kind   string
+		set    func(dev *client.QuotaIODeviceValues, value quantity.Size)
+	}{
+		{x.IOReadBandwidth, "read bandwidth", func(dev *client.QuotaIODeviceValues, value quantity.Size) { dev.ReadBandwidth = value }},
+		{x.IOWriteBandwidth, "write bandwidth", func(dev *client.QuotaIODeviceValues, value quantity.Size) { dev.WriteBandwidth = value }},
+	}
+	for _, bw := range bandwidths {
+		for _, quota := range bw.quotas {
+			device, value, err := parseIODeviceQuota(quota)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse io %s %q: %v", bw.kind, quota, err)
+			}
+			size, err := strutil.ParseByteSize(value)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse io %s %q: %v", bw.kind, quota, err)
+			}
+			bw.set(deviceValues(device), quantity.Size(size))
+		}
+	}
+
+	iops := []struct {
+		quotas []string
+		kind   string
+		set    func(dev *client.QuotaIODeviceValues, value int)
+	}{
+		{x.IOReadIOPS, "read iops", func(dev *client.QuotaIODeviceValues, value int) { dev.ReadIOPS = value }},
+		{x.IOWriteIOPS, "write iops", func(dev *client.QuotaIODeviceValues, value int) { dev.WriteIOPS = value }},
+	}
+	for _, ops := range iops {
+		for _, quota := range ops.quotas {
+			device, value, err := parseIODeviceQuota(quota)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse io %s %q: %v", ops.kind, quota, err)
+			}
+			count, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("cannot use io %s value %q", ops.kind, value)
+			}
+			ops.set(deviceValues(device), int(count))
+		}
+	}
+	return &ioValues, nil
+}
+
+func (x *cmdSetQuota) hasIOQuotaSet() bool {
+	return x.IOWeight != "" || len(x.IOReadBandwidth) != 0 || len(x.IOWriteBandwidth) != 0 ||
+		len(x.IOReadIOPS) != 0 || len(x.IOWriteIOPS) != 0
+}
+
 func (x *cmdSetQuota) parseQuotas() (*client.QuotaValues, error) {
 	var quotaValues client.QuotaValues
 
@@ -205,6 +323,26 @@ func (x *cmdSetQuota) parseQuotas() (*client.QuotaValues, error) {
 		quotaValues.Memory = quantity.Size(value)
 	}
 
+	if x.MemoryHigh != "" {
+		value, err := strutil.ParseByteSize(x.MemoryHigh)
+		if err != nil {
+			return nil, err
+		}
+		quotaValues.MemoryHigh = quantity.Size(value)
+	}
+	quotaValues.OOMPolicy = x.OOMPolicy
+
+	if x.MemoryPressure != "" {
+		value, err := strconv.Atoi(strings.TrimSuffix(x.MemoryPressure, "%"))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse memory pressure %q: %v", x.MemoryPressure, err)
+		}
+		if value > 100 || value <= 0 {
+			return nil, fmt.Errorf("cannot use value %v: memory pressure must be between 1 and 100", value)
+		}
+		quotaValues.MemoryPressure = value
+	}
+
 	if x.CPUMax != "" {
 		countValue, percentageValue, err := parseCpuQuota(x.CPUMax)
 		if err != nil {
@@ -266,12 +404,22 @@ func (x *cmdSetQuota) parseQuotas() (*client.QuotaValues, error) {
 		}
 	}
 
+	if x.hasIOQuotaSet() {
+		ioValues, err := x.parseIOQuotas()
+		if err != nil {
+			return nil, err
+		}
+		quotaValues.IO = ioValues
+	}
+
 	return &quotaValues, nil
 }
 
 func (x *cmdSetQuota) hasQuotaSet() bool {
-	return x.MemoryMax != "" || x.CPUMax != "" || x.CPUSet != "" ||
-		x.ThreadsMax != "" || x.JournalSizeMax != "" || x.JournalRateLimit != ""
+	return x.MemoryMax != "" || x.MemoryHigh != "" || x.OOMPolicy != "" || x.MemoryPressure != "" ||
+		x.CPUMax != "" || x.CPUSet != "" ||
+		x.ThreadsMax != "" || x.JournalSizeMax != "" || x.JournalRateLimit != "" ||
+		x.hasIOQuotaSet()
 }
 
 func (x *cmdSetQuota) splitSnapsAndServices() (snaps []string, services []string) {
@@ -416,6 +564,16 @@ func (x *cmdQuota) Execute(args []string) (err error) {
 		val := strings.TrimSpace(fmtSize(int64(group.Constraints.Memory)))
 		fmt.Fprintf(w, "  memory:\t%s\n", val)
 	}
+	if group.Constraints.MemoryHigh != 0 {
+		val := strings.TrimSpace(fmtSize(int64(group.Constraints.MemoryHigh)))
+		fmt.Fprintf(w, "  memory-high:\t%s\n", val)
+	}
+	if group.Constraints.OOMPolicy != "" {
+		fmt.Fprintf(w, "  oom-policy:\t%s\n", group.Constraints.OOMPolicy)
+	}
+	if group.Constraints.MemoryPressure != 0 {
+		fmt.Fprintf(w, "  memory-pressure:\t%d%%\n", group.Constraints.MemoryPressure)
+	}
 	if group.Constraints.CPU != nil {
 		fmt.Fprintf(w, "  cpu-count:\t%d\n", group.Constraints.CPU.Count)
 		fmt.Fprintf(w, "  cpu-percentage:\t%d\n", group.Constraints.CPU.Percentage)
@@ -438,6 +596,14 @@ func (x *cmdQuota) Execute(args []string) (err error) {
 				group.Constraints.Journal.RatePeriod)
 		}
 	}
+	if group.Constraints.IO != nil {
+		if group.Constraints.IO.Weight != 0 {
+			fmt.Fprintf(w, "  io-weight:\t%d\n", group.Constraints.IO.Weight)
+		}
+		for _, kv := range fmtIODeviceQuotas(group.Constraints.IO.Devices) {
+			fmt.Fprintf(w, "  %s:\t%s\n", kv[0], kv[1])
+		}
+	}
 
 	memoryUsage := "0B"
 	currentThreads := 0
@@ -453,6 +619,9 @@ func (x *cmdQuota) Execute(args []string) (err error) {
 	if group.Constraints.Threads != 0 {
 		fmt.Fprintf(w, "  threads:\t%d\n", currentThreads)
 	}
+	if group.OOMKills != 0 {
+		fmt.Fprintf(w, "  oom-kills:\t%d\n", group.OOMKills)
+	}
 
 	if len(group.Subgroups) > 0 {
 		fmt.Fprint(w, "subgroups:\n")
@@ -527,6 +696,15 @@ func (x *cmdQuotas) Execute(args []string) (err error) {
 		if q.Constraints.Memory != 0 {
 			grpConstraints = append(grpConstraints, "memory="+strings.TrimSpace(fmtSize(int64(q.Constraints.Memory))))
 		}
+		if q.Constraints.MemoryHigh != 0 {
+			grpConstraints = append(grpConstraints, "memory-high="+strings.TrimSpace(fmtSize(int64(q.Constraints.MemoryHigh))))
+		}
+		if q.Constraints.OOMPolicy != "" {
+			grpConstraints = append(grpConstraints, "oom-policy="+q.Constraints.OOMPolicy)
+		}
+		if q.Constraints.MemoryPressure != 0 {
+			grpConstraints = append(grpConstraints, fmt.Sprintf("memory-pressure=%d%%", q.Constraints.MemoryPressure))
+		}
 
 		// format cpu constraint as cpu=NxM%,cpu-set=x,y,z
 		if q.Constraints.CPU != nil {
@@ -560,7 +738,17 @@ func (x *cmdQuotas) Execute(args []string) (err error) {
 			}
 		}
 
-		// format current resource values as memory=N,threads=N
+		// format io constraint as io-weight=N,io-read-bandwidth=/dev/sda=xMB
+		if q.Constraints.IO != nil {
+			if q.Constraints.IO.Weight != 0 {
+				grpConstraints = append(grpConstraints, "io-weight="+strconv.Itoa(q.Constraints.IO.Weight))
+			}
+			for _, kv := range fmtIODeviceQuotas(q.Constraints.IO.Devices) {
+				grpConstraints = append(grpConstraints, kv[0]+"="+kv[1])
+			}
+		}
+
+		// format current resource values as memory=N,threads=N,oom-kills=N
 		var grpCurrent []string
 		if q.Current != nil {
 			if q.Constraints.Memory != 0 && q.Current.Memory != 0 {
@@ -570,6 +758,9 @@ func (x *cmdQuotas) Execute(args []string) (err error) {
 				grpCurrent = append(grpCurrent, "threads="+fmt.Sprintf("%d", q.Current.Threads))
 			}
 		}
+		if q.OOMKills != 0 {
+			grpCurrent = append(grpCurrent, "oom-kills="+strconv.Itoa(q.OOMKills))
+		}
 
 		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", q.GroupName, q.Parent, strings.Join(grpConstraints, ","), strings.Join(grpCurrent, ","))
 
@@ -582,6 +773,27 @@ func (x *cmdQuotas) Execute(args []string) (err error) {
 	return nil
 }
 
+// fmtIODeviceQuotas returns the name and value of each io device limit,
+// with values formatted as <device>=<limit>.
+func fmtIODeviceQuotas(devices []client.QuotaIODeviceValues) [][2]string {
+	var quotas [][2]string
+	for _, dev := range devices {
+		if dev.ReadBandwidth != 0 {
+			quotas = append(quotas, [2]string{"io-read-bandwidth", dev.Device + "=" + strings.TrimSpace(fmtSize(int64(dev.ReadBandwidth)))})
+		}
+		if dev.WriteBandwidth != 0 {
+			quotas = append(quotas, [2]string{"io-write-bandwidth", dev.Device + "=" + strings.TrimSpace(fmtSize(int64(dev.WriteBandwidth)))})
+		}
+		if dev.ReadIOPS != 0 {
+			quotas = append(quotas, [2]string{"io-read-iops", dev.Device + "=" + strconv.Itoa(dev.ReadIOPS)})
+		}
+		if dev.WriteIOPS != 0 {
+			quotas = append(quotas, [2]string{"io-write-iops", dev.Device + "=" + strconv.Itoa(dev.WriteIOPS)})
+		}
+	}
+	return quotas
+}
+
 type quotaGroup struct {
 	res       *client.QuotaGroupResult
 	subGroups []*quotaGroup
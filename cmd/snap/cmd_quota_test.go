@@ -29,7 +29,9 @@ import (
 
 	"gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/client"
 	main "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/jsonutil"
 )
 
@@ -259,6 +261,80 @@ func (s *quotaSuite) TestParseQuotas(c *check.C) {
 	}
 }
 
+func (s *quotaSuite) TestParseIOQuotas(c *check.C) {
+	for _, testData := range []struct {
+		ioWeight       string
+		readBandwidth  []string
+		writeBandwidth []string
+		readIOPS       []string
+		writeIOPS      []string
+
+		// Use the JSON representation of the quota, as it's easier to handle in the test data
+		quotas string
+		err    string
+	}{
+		{ioWeight: "200", quotas: `{"io":{"weight":200}}`},
+		{readBandwidth: []string{"/dev/sda=10MB"}, quotas: `{"io":{"devices":[{"device":"/dev/sda","read-bandwidth":10000000}]}}`},
+		{
+			readBandwidth:  []string{"/dev/sda=10MB", "/dev/sdb=1MB"},
+			writeBandwidth: []string{"/dev/sdb=2MB"},
+			readIOPS:       []string{"/dev/sda=100"},
+			writeIOPS:      []string{"/dev/sdc=50"},
+			quotas:         `{"io":{"devices":[{"device":"/dev/sda","read-bandwidth":10000000,"read-iops":100},{"device":"/dev/sdb","read-bandwidth":1000000,"write-bandwidth":2000000},{"device":"/dev/sdc","write-iops":50}]}}`,
+		},
+
+		// Error cases
+		{ioWeight: "x", err: `cannot use io weight value "x"`},
+		{ioWeight: "-1", err: `cannot use io weight value "-1"`},
+		{readBandwidth: []string{"10MB"}, err: `cannot parse io read bandwidth "10MB": io quota must be of the form <device>=<value>`},
+		{writeBandwidth: []string{"/dev/sda="}, err: `cannot parse io write bandwidth "/dev/sda=": io quota must be of the form <device>=<value>`},
+		{readBandwidth: []string{"/dev/sda=10"}, err: `cannot parse io read bandwidth "/dev/sda=10": cannot parse "10": need a number with a unit as input`},
+		{readIOPS: []string{"=10"}, err: `cannot parse io read iops "=10": io quota must be of the form <device>=<value>`},
+		{writeIOPS: []string{"/dev/sda=many"}, err: `cannot use io write iops value "many"`},
+	} {
+		quotas, err := main.ParseIOQuotaValues(testData.ioWeight, testData.readBandwidth,
+			testData.writeBandwidth, testData.readIOPS, testData.writeIOPS)
+		testLabel := check.Commentf("%v", testData)
+		if testData.err == "" {
+			c.Check(err, check.IsNil, testLabel)
+			var jsonQuota bytes.Buffer
+			err := json.NewEncoder(&jsonQuota).Encode(quotas)
+			c.Assert(err, check.IsNil, testLabel)
+			c.Check(strings.TrimSpace(jsonQuota.String()), check.Equals, testData.quotas, testLabel)
+		} else {
+			c.Check(err, check.ErrorMatches, testData.err, testLabel)
+		}
+	}
+}
+
+func (s *quotaSuite) TestParseMemoryHighQuotas(c *check.C) {
+	quotas, err := main.ParseMemoryHighQuotaValues("512MB", "restart-group", "")
+	c.Assert(err, check.IsNil)
+	c.Check(quotas, check.DeepEquals, &client.QuotaValues{
+		MemoryHigh: quantity.Size(512000000),
+		OOMPolicy:  "restart-group",
+	})
+
+	quotas, err = main.ParseMemoryHighQuotaValues("", "restart-service", "40%")
+	c.Assert(err, check.IsNil)
+	c.Check(quotas, check.DeepEquals, &client.QuotaValues{
+		OOMPolicy:      "restart-service",
+		MemoryPressure: 40,
+	})
+	quotas, err = main.ParseMemoryHighQuotaValues("", "", "15")
+	c.Assert(err, check.IsNil)
+	c.Check(quotas, check.DeepEquals, &client.QuotaValues{MemoryPressure: 15})
+
+	_, err = main.ParseMemoryHighQuotaValues("lots", "", "")
+	c.Check(err, check.ErrorMatches, `cannot parse "lots": no numerical prefix`)
+	_, err = main.ParseMemoryHighQuotaValues("", "", "high")
+	c.Check(err, check.ErrorMatches, `cannot parse memory pressure "high": .*`)
+	_, err = main.ParseMemoryHighQuotaValues("", "", "0%")
+	c.Check(err, check.ErrorMatches, `cannot use value 0: memory pressure must be between 1 and 100`)
+	_, err = main.ParseMemoryHighQuotaValues("", "", "101")
+	c.Check(err, check.ErrorMatches, `cannot use value 101: memory pressure must be between 1 and 100`)
+}
+
 func (s *quotaSuite) TestSetQuotaInvalidArgs(c *check.C) {
 	const json = `{
 		"type": "sync",
@@ -445,6 +521,54 @@ current:
 	c.Check(s.quotaGetGroupHandlerCalls, check.Equals, 2)
 }
 
+func (s *quotaSuite) TestGetMemoryHighQuotaGroup(c *check.C) {
+	s.RedirectClientToTestServer(s.makeFakeGetQuotaGroupHandler(c, `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {
+			"group-name": "foo",
+			"constraints": {"memory": 2000, "memory-high": 1500, "oom-policy": "restart-service", "memory-pressure": 40},
+			"current": {"memory": 1000},
+			"oom-kills": 3,
+			"last-oom-kill": "2025-03-01T12:00:00Z"
+		}
+	}`))
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"quota", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `
+name:  foo
+constraints:
+  memory:           2000B
+  memory-high:      1500B
+  oom-policy:       restart-service
+  memory-pressure:  40%
+current:
+  memory:     1000B
+  oom-kills:  3
+`[1:])
+}
+
+func (s *quotaSuite) TestGetAllQuotaGroupsMemoryHigh(c *check.C) {
+	restore := main.MockIsStdinTTY(true)
+	defer restore()
+
+	s.RedirectClientToTestServer(s.makeFakeGetQuotaGroupsHandler(c,
+		`{"type": "sync", "status-code": 200, "result": [
+			{"group-name":"foo","constraints":{"memory":2000,"memory-high":1500,"oom-policy":"notify","memory-pressure":40},"current":{"memory":1000},"oom-kills":2}
+			]}`))
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"quotas"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `
+Quota  Parent  Constraints                                                           Current
+foo            memory=2000B,memory-high=1500B,oom-policy=notify,memory-pressure=40%  memory=1000B,oom-kills=2
+`[1:])
+}
+
 func (s *quotaSuite) TestGetCpuQuotaGroupSimple(c *check.C) {
 	const jsonTemplate = `{
 		"type": "sync",
@@ -489,6 +613,33 @@ current:
 	c.Check(s.quotaGetGroupHandlerCalls, check.Equals, 2)
 }
 
+func (s *quotaSuite) TestGetIOQuotaGroupSimple(c *check.C) {
+	const json = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {
+			"group-name": "foo",
+			"constraints": {"io":{"weight":300,"devices":[{"device":"/dev/sda","read-bandwidth":10000000,"write-iops":100}]}}
+		}
+	}`
+
+	s.RedirectClientToTestServer(s.makeFakeGetQuotaGroupHandler(c, json))
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"quota", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `
+name:  foo
+constraints:
+  io-weight:          300
+  io-read-bandwidth:  /dev/sda=10.0MB
+  io-write-iops:      /dev/sda=100
+current:
+`[1:])
+	c.Check(s.quotaGetGroupHandlerCalls, check.Equals, 1)
+}
+
 func (s *quotaSuite) TestJournalQuotaGroupSimple(c *check.C) {
 	const jsonTemplate = `{
 		"type": "sync",
@@ -71,6 +71,11 @@ override, taking the snap back to the current revision of the channel it's
 tracking.
 
 Use --name to set the instance name when installing from snap file.
+
+With --delta, the named snap is installed from the snap file reconstructed by
+applying the given delta, as created by 'snap delta create', to the installed
+revision of the snap given with --base-revision. The reconstructed snap must
+have pre-acknowledged signatures.
 `)
 
 var longRemoveHelp = i18n.G(`
@@ -96,6 +101,14 @@ channels of a snap are listed in its 'snap info' output.
 When --revision is used, a later refresh will typically undo the revision
 override.
 
+Plan (--plan) shows what refreshing the specified snaps, or all snaps, would
+do without doing it: the revisions and downloads, the tasks that would run,
+the snaps that would need to be installed first, the services that would be
+restarted, whether a reboot would be needed, and the snaps kept back by
+refresh holds. With --auto-refresh, it shows what the next auto-refresh
+would do instead, including the snaps kept back by holds that only apply
+to auto-refreshes, such as the ones set by other snaps.
+
 Hold (--hold) is used to postpone snap refresh updates for all snaps when no
 snaps are specified, or for the specified snaps.
 
@@ -728,6 +741,9 @@ type cmdInstall struct {
 
 	Name string `long:"name"`
 
+	Delta        flags.Filename `long:"delta"`
+	BaseRevision string         `long:"base-revision"`
+
 	Cohort           string                 `long:"cohort"`
 	IgnoreValidation bool                   `long:"ignore-validation"`
 	IgnoreRunning    bool                   `long:"ignore-running" hidden:"yes"`
@@ -744,7 +760,15 @@ func (x *cmdInstall) installOne(nameOrPath, desiredName string, opts *client.Sna
 	var snapName string
 	var path string
 
-	if isLocalContainer(nameOrPath) {
+	if x.Delta != "" {
+		baseRevision, e := snap.ParseRevision(x.BaseRevision)
+		if e != nil {
+			return fmt.Errorf(i18n.G("invalid base revision %q: %v"), x.BaseRevision, e)
+		}
+		// don't log the request's body because the encoded delta is large.
+		x.client.SetMayLogBody(false)
+		changeID, err = x.client.InstallDelta(string(x.Delta), nameOrPath, baseRevision, opts)
+	} else if isLocalContainer(nameOrPath) {
 		// don't log the request's body because the encoded snap is large.
 		x.client.SetMayLogBody(false)
 		path = nameOrPath
@@ -792,6 +816,24 @@ func (x *cmdInstall) installOne(nameOrPath, desiredName string, opts *client.Sna
 	return showDone(x.client, chg, changedSnaps, "install", opts, x.getEscapes())
 }
 
+// checkDeltaOptions checks the options of the installation of a delta of
+// the given snap.
+func (x *cmdInstall) checkDeltaOptions(names []string) error {
+	if len(names) != 1 || isLocalContainer(names[0]) {
+		return errors.New(i18n.G("a single installed snap name is needed to install a delta"))
+	}
+	if x.BaseRevision == "" {
+		return errors.New(i18n.G("the revision the delta applies to must be given with --base-revision"))
+	}
+	if x.Dangerous || x.ForceDangerous {
+		return errors.New(i18n.G("cannot install a delta with --dangerous, the reconstructed snap must be verified"))
+	}
+	if x.asksForChannel() || x.Revision != "" || x.Cohort != "" || x.Name != "" {
+		return errors.New(i18n.G("cannot use --channel, --revision, --cohort or --name when installing a delta"))
+	}
+	return nil
+}
+
 func isLocalContainer(name string) bool {
 	return strings.Contains(name, "/") ||
 		strings.HasSuffix(name, ".snap") || strings.Contains(name, ".snap.") ||
@@ -946,6 +988,14 @@ func (x *cmdInstall) Execute([]string) error {
 		}
 	}
 
+	if x.Delta != "" {
+		if err := x.checkDeltaOptions(names); err != nil {
+			return err
+		}
+	} else if x.BaseRevision != "" {
+		return errors.New(i18n.G("cannot use --base-revision without --delta"))
+	}
+
 	if len(names) == 1 {
 		return x.installOne(names[0], x.Name, opts)
 	}
@@ -978,7 +1028,10 @@ type cmdRefresh struct {
 	Cohort           string                 `long:"cohort"`
 	LeaveCohort      bool                   `long:"leave-cohort"`
 	List             bool                   `long:"list"`
+	Plan             bool                   `long:"plan"`
+	AutoRefresh      bool                   `long:"auto-refresh"`
 	Time             bool                   `long:"time"`
+	Verbose          bool                   `long:"verbose"`
 	IgnoreValidation bool                   `long:"ignore-validation"`
 	IgnoreRunning    bool                   `long:"ignore-running" hidden:"yes"`
 	Transaction      client.TransactionType `long:"transaction" default:"per-snap" choice:"all-snaps" choice:"per-snap"`
@@ -1104,9 +1157,36 @@ func (x *cmdRefresh) showRefreshTimes() error {
 	} else {
 		fmt.Fprintf(Stdout, "next: n/a\n")
 	}
+
+	if x.Verbose {
+		x.showRefreshWavesAndWindows(&sysinfo.Refresh)
+	}
 	return nil
 }
 
+func (x *cmdRefresh) showRefreshWavesAndWindows(refresh *client.RefreshInfo) {
+	if refresh.Waves != "" {
+		fmt.Fprintf(Stdout, "waves: %s\n", refresh.Waves)
+	}
+	if len(refresh.PendingWaves) > 0 {
+		fmt.Fprintf(Stdout, "pending waves:\n")
+		for i, wave := range refresh.PendingWaves {
+			fmt.Fprintf(Stdout, "  %d: %s\n", i+1, strings.Join(wave, ", "))
+		}
+	}
+	if len(refresh.Windows) > 0 {
+		fmt.Fprintf(Stdout, "windows:\n")
+		for _, window := range refresh.Windows {
+			next := parseSysinfoTime(window.Next)
+			if next.IsZero() {
+				fmt.Fprintf(Stdout, "  %s: %s\n", window.Snap, window.Window)
+			} else {
+				fmt.Fprintf(Stdout, "  %s: %s (next: %s)\n", window.Snap, window.Window, x.fmtTime(next))
+			}
+		}
+	}
+}
+
 func (x *cmdRefresh) listRefresh() error {
 	snaps, _, err := x.client.Find(&client.FindOptions{
 		Refresh: true,
@@ -1134,6 +1214,85 @@ func (x *cmdRefresh) listRefresh() error {
 	return nil
 }
 
+func (x *cmdRefresh) showRefreshPlan() error {
+	var plan *client.RefreshPlan
+	var err error
+	if x.AutoRefresh {
+		plan, err = x.client.AutoRefreshPlan()
+	} else {
+		names := installedSnapNames(x.Positional.Snaps)
+		plan, err = x.client.RefreshPlan(names, &client.SnapOptions{
+			IgnoreRunning: x.IgnoreRunning,
+			Transaction:   x.Transaction,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if len(plan.Snaps) == 0 && len(plan.Held) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("All snaps up to date."))
+		return nil
+	}
+
+	tasksBySnap := make(map[string][]client.PlannedTask)
+	for _, ts := range plan.TaskSets {
+		tasksBySnap[ts.Snap] = append(tasksBySnap[ts.Snap], ts.Tasks...)
+	}
+	printTasks := func(indent string, tasks []client.PlannedTask) {
+		for _, t := range tasks {
+			fmt.Fprintf(Stdout, "%s- %s: %s\n", indent, t.Kind, t.Summary)
+		}
+	}
+
+	if len(plan.Snaps) > 0 {
+		fmt.Fprintf(Stdout, "snaps:\n")
+	}
+	for _, pr := range plan.Snaps {
+		fmt.Fprintf(Stdout, "  %s:\n", pr.Name)
+		fmt.Fprintf(Stdout, "    revision: %s -> %s\n", pr.Current, pr.Revision)
+		if pr.Channel != "" {
+			fmt.Fprintf(Stdout, "    channel: %s\n", pr.Channel)
+		}
+		if pr.DownloadSize > 0 {
+			fmt.Fprintf(Stdout, "    download: %s\n", strutil.SizeToStr(pr.DownloadSize))
+		}
+		if pr.Reboot {
+			fmt.Fprintf(Stdout, "    reboot: true\n")
+		}
+		if len(pr.Services) > 0 {
+			fmt.Fprintf(Stdout, "    services: %s\n", strings.Join(pr.Services, ", "))
+		}
+		if len(pr.ValidationSets) > 0 {
+			fmt.Fprintf(Stdout, "    validation-sets: %s\n", strings.Join(pr.ValidationSets, ", "))
+		}
+		if tasks := tasksBySnap[pr.Name]; len(tasks) > 0 {
+			fmt.Fprintf(Stdout, "    tasks:\n")
+			printTasks("      ", tasks)
+		}
+	}
+
+	if len(plan.Held) > 0 {
+		fmt.Fprintf(Stdout, "held:\n")
+		held := make([]string, 0, len(plan.Held))
+		for name := range plan.Held {
+			held = append(held, name)
+		}
+		sort.Strings(held)
+		for _, name := range held {
+			fmt.Fprintf(Stdout, "  %s: %s\n", name, strings.Join(plan.Held[name], ", "))
+		}
+	}
+	if len(plan.Prerequisites) > 0 {
+		fmt.Fprintf(Stdout, "prerequisites: %s\n", strings.Join(plan.Prerequisites, ", "))
+	}
+	if tasks := tasksBySnap[""]; len(tasks) > 0 {
+		fmt.Fprintf(Stdout, "other tasks:\n")
+		printTasks("  ", tasks)
+	}
+
+	return nil
+}
+
 func (x *cmdRefresh) Execute([]string) error {
 	if err := x.setChannelFromCommandline(); err != nil {
 		return err
@@ -1148,6 +1307,9 @@ func (x *cmdRefresh) Execute([]string) error {
 		}
 		return x.showRefreshTimes()
 	}
+	if x.Verbose {
+		return errors.New(i18n.G("--verbose can only be used with --time"))
+	}
 
 	if x.List {
 		if len(x.Positional.Snaps) > 0 || x.asksForMode() || x.asksForChannel() {
@@ -1157,6 +1319,20 @@ func (x *cmdRefresh) Execute([]string) error {
 		return x.listRefresh()
 	}
 
+	if x.Plan {
+		if x.asksForMode() || x.asksForChannel() || x.Amend || x.Revision != "" ||
+			x.Cohort != "" || x.LeaveCohort || x.IgnoreValidation || x.Hold != "" || x.Unhold {
+			return errors.New(i18n.G("--plan does not take mode, channel, revision, cohort, validation or hold flags"))
+		}
+		if x.AutoRefresh && (len(x.Positional.Snaps) > 0 || x.IgnoreRunning || x.Transaction != client.TransactionPerSnap) {
+			return errors.New(i18n.G("--auto-refresh does not take snaps, --ignore-running or --transaction"))
+		}
+		return x.showRefreshPlan()
+	}
+	if x.AutoRefresh {
+		return errors.New(i18n.G("--auto-refresh can only be used with --plan"))
+	}
+
 	if len(x.Positional.Snaps) == 0 && os.Getenv("SNAP_REFRESH_FROM_TIMER") == "1" {
 		fmt.Fprintf(Stdout, "Ignoring `snap refresh` from the systemd timer")
 		return nil
@@ -1582,6 +1758,10 @@ func init() {
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"name": i18n.G("Install the snap file under the given instance name"),
 			// TRANSLATORS: This should not start with a lowercase letter.
+			"delta": i18n.G("Install the snap reconstructed from the given delta file"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"base-revision": i18n.G("Installed revision of the snap the delta applies to"),
+			// TRANSLATORS: This should not start with a lowercase letter.
 			"cohort": i18n.G("Install the snap in the given cohort"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"ignore-validation": i18n.G("Ignore validation by other snaps blocking the installation"),
@@ -1603,8 +1783,14 @@ func init() {
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"list": i18n.G("Show the new versions of snaps that would be updated with the next refresh"),
 			// TRANSLATORS: This should not start with a lowercase letter.
+			"plan": i18n.G("Show what the refresh would do, including tasks, downloads, reboots and restarted services, but do not perform it"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"auto-refresh": i18n.G("Show what the next auto-refresh would do instead, with --plan"),
+			// TRANSLATORS: This should not start with a lowercase letter.
 			"time": i18n.G("Show auto refresh information but do not perform a refresh"),
 			// TRANSLATORS: This should not start with a lowercase letter.
+			"verbose": i18n.G("Also show the refresh waves and windows, with --time"),
+			// TRANSLATORS: This should not start with a lowercase letter.
 			"ignore-validation": i18n.G("Ignore validation by other snaps blocking the refresh"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"ignore-running": i18n.G("Ignore running hooks or applications blocking the refresh"),
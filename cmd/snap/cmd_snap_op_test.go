@@ -1344,6 +1344,63 @@ func (s *SnapOpSuite) TestInstallPath(c *check.C) {
 	c.Check(s.srv.n, check.Equals, s.srv.total)
 }
 
+func (s *SnapOpSuite) TestInstallDelta(c *check.C) {
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+
+		form := testForm(r, c)
+		defer form.RemoveAll()
+
+		c.Check(form.Value["action"], check.DeepEquals, []string{"install"})
+		c.Check(form.Value["name"], check.DeepEquals, []string{"foo"})
+		c.Check(form.Value["base-revision"], check.DeepEquals, []string{"40"})
+		c.Check(form.Value["snap-path"], check.NotNil)
+		c.Check(form.Value["transaction"], check.NotNil)
+		c.Check(form.Value, check.HasLen, 5)
+
+		name, filename, body := formFile(form, c)
+		c.Check(name, check.Equals, "snap")
+		c.Check(filename, check.Equals, "foo_41.delta")
+		c.Check(string(body), check.Equals, "delta-data")
+	}
+
+	s.RedirectClientToTestServer(s.srv.handle)
+	deltaPath := filepath.Join(c.MkDir(), "foo_41.delta")
+	err := os.WriteFile(deltaPath, []byte("delta-data"), 0644)
+	c.Assert(err, check.IsNil)
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"install", "--delta", deltaPath, "--base-revision", "40", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?sm).*foo 1.0 from Bar installed`)
+	c.Check(s.Stderr(), check.Equals, "")
+	// ensure that the fake server api was actually hit
+	c.Check(s.srv.n, check.Equals, s.srv.total)
+}
+
+func (s *SnapOpSuite) TestInstallDeltaErrors(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to %s", r.URL.Path)
+	})
+
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--base-revision", "40", "foo"}, `cannot use --base-revision without --delta`},
+		{[]string{"--delta", "foo.delta", "foo"}, `the revision the delta applies to must be given with --base-revision`},
+		{[]string{"--delta", "foo.delta", "--base-revision", "40", "foo", "bar"}, `a single installed snap name is needed to install a delta`},
+		{[]string{"--delta", "foo.delta", "--base-revision", "40", "./foo.snap"}, `a single installed snap name is needed to install a delta`},
+		{[]string{"--delta", "foo.delta", "--base-revision", "40", "--dangerous", "foo"}, `cannot install a delta with --dangerous, the reconstructed snap must be verified`},
+		{[]string{"--delta", "foo.delta", "--base-revision", "40", "--channel", "edge", "foo"}, `cannot use --channel, --revision, --cohort or --name when installing a delta`},
+		{[]string{"--delta", "foo.delta", "--base-revision", "40", "--name", "foo_bar", "foo"}, `cannot use --channel, --revision, --cohort or --name when installing a delta`},
+		{[]string{"--delta", "foo.delta", "--base-revision", "zz", "foo"}, `invalid base revision "zz": invalid snap revision: "zz"`},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(append([]string{"install"}, t.args...))
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%v", t.args))
+	}
+}
+
 func (s *SnapOpSuite) TestComponentInstallPath(c *check.C) {
 	s.srv.checker = func(r *http.Request) {
 		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
@@ -1847,6 +1904,122 @@ foo +4.2update1 +17 +436MB +bar +-.*
 	c.Check(n, check.Equals, 1)
 }
 
+func (s *SnapSuite) TestRefreshPlan(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]any{
+				"action":      "plan",
+				"snaps":       []any{"foo", "bar"},
+				"transaction": "per-snap",
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": {
+"snaps": [{"name": "foo", "type": "kernel", "channel": "20/stable", "current": "1", "revision": "2", "download-size": 2048000, "reboot": true, "services": ["foo.svc1", "foo.svc2"], "validation-sets": ["16/acme/base-set/2"]}],
+"task-sets": [{"snap": "foo", "tasks": [{"kind": "download-snap", "summary": "Download snap foo"}, {"kind": "link-snap", "summary": "Make foo available"}]}, {"tasks": [{"kind": "check-rerefresh", "summary": "Monitoring foo"}]}],
+"held": {"bar": ["system"]},
+"prerequisites": ["core24"]
+}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--plan", "foo", "bar"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `snaps:
+  foo:
+    revision: 1 -> 2
+    channel: 20/stable
+    download: 2MB
+    reboot: true
+    services: foo.svc1, foo.svc2
+    validation-sets: 16/acme/base-set/2
+    tasks:
+      - download-snap: Download snap foo
+      - link-snap: Make foo available
+held:
+  bar: system
+prerequisites: core24
+other tasks:
+  - check-rerefresh: Monitoring foo
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestRefreshPlanUpToDate(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		fmt.Fprintln(w, `{"type": "sync", "result": {}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--plan"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "All snaps up to date.\n")
+}
+
+func (s *SnapSuite) TestRefreshPlanAutoRefresh(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]any{
+				"action":       "plan",
+				"auto-refresh": true,
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": {"held": {"foo": ["bar", "system"]}}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--plan", "--auto-refresh"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `held:
+  foo: bar, system
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestRefreshPlanAutoRefreshLessOptions(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatal("expected to get 0 requests")
+	})
+
+	for _, args := range [][]string{
+		{"refresh", "--plan", "--auto-refresh", "some-snap"},
+		{"refresh", "--plan", "--auto-refresh", "--ignore-running"},
+		{"refresh", "--plan", "--auto-refresh", "--transaction=all-snaps"},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(args)
+		c.Assert(err, check.ErrorMatches, "--auto-refresh does not take snaps, --ignore-running or --transaction")
+	}
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--auto-refresh"})
+	c.Assert(err, check.ErrorMatches, "--auto-refresh can only be used with --plan")
+}
+
+func (s *SnapSuite) TestRefreshPlanLessOptions(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatal("expected to get 0 requests")
+	})
+
+	for _, flag := range []string{"--beta", "--revision=2", "--cohort=foo", "--hold", "--ignore-validation"} {
+		_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--plan", flag, "some-snap"})
+		c.Assert(err, check.ErrorMatches, "--plan does not take mode, channel, revision, cohort, validation or hold flags")
+	}
+}
+
 func (s *SnapSuite) TestRefreshLegacyTime(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -1949,6 +2122,45 @@ next: 2017-04-26T00:58:00+02:00 (but held)
 	}
 }
 
+func (s *SnapSuite) TestRefreshTimeVerbose(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/system-info")
+			fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"refresh": {"timer": "0:00-24:00/4", "last": "2017-04-25T17:35:00+02:00", "next": "2017-04-26T00:58:00+02:00", "waves": "*;@kernel,@gadget", "pending-waves": [["pc-kernel", "pc"]], "windows": [{"snap": "postgres", "window": "sun,23:00-01:00", "next": "2017-04-30T23:00:00+02:00"}, {"snap": "other", "window": "mon"}]}}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--time", "--verbose", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `timer: 0:00-24:00/4
+last: 2017-04-25T17:35:00+02:00
+next: 2017-04-26T00:58:00+02:00
+waves: *;@kernel,@gadget
+pending waves:
+  1: pc-kernel, pc
+windows:
+  postgres: sun,23:00-01:00 (next: 2017-04-30T23:00:00+02:00)
+  other: mon
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestRefreshVerboseWithoutTime(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--verbose"})
+	c.Assert(err, check.ErrorMatches, "--verbose can only be used with --time")
+}
+
 func (s *SnapSuite) TestRefreshHoldAllForever(c *check.C) {
 	var n int
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
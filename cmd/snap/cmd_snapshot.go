@@ -20,14 +20,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/strutil/quantity"
@@ -50,6 +53,10 @@ var (
 var longSavedHelp = i18n.G(`
 The saved command displays a list of snapshots that have been created
 previously with the 'save' command.
+
+Snapshots that are going to be removed automatically, either because
+they were taken automatically or because the snapshot retention
+policies do not keep them, are noted with when they expire.
 `)
 var longSaveHelp = i18n.G(`
 The save command creates a snapshot of the current user, system and
@@ -62,6 +69,11 @@ for which users, or a combination of these.
 If a snap is included in a save operation, excluding its system and
 configuration data from the snapshot is not currently possible. This
 restriction may be lifted in the future.
+
+With --to, the snapshot is moved to the given target once saved. Targets
+are either the absolute path of a directory, which can be on a network
+file system, or an s3://<bucket>[/<prefix>] URL for the S3-compatible
+service set with the snapshots.s3.* system options.
 `)
 var longForgetHelp = i18n.G(`
 The forget command deletes a snapshot. This operation can not be
@@ -100,17 +112,85 @@ for which users, or a combination of these.
 If a snap is included in a restore operation, excluding its system and
 configuration data from the restore is not currently possible. This
 restriction may be lifted in the future.
+
+With --from, the snapshot is first imported from the given target, where
+it was moved with 'snap save --to', and then restored.
 `)
 
 var longExportSnapshotHelp = i18n.G(`
 Export a snapshot to the given filename.
+
+With --passphrase or --key-file, the data in the exported snapshot is
+encrypted, and the same passphrase or key file is needed to restore it.
 `)
 
 var longImportSnapshotHelp = i18n.G(`
 Import an exported snapshot set to the system. The snapshot is imported
 with a new snapshot ID and can be restored using the restore command.
+
+Encrypted snapshots are decrypted when imported if --passphrase or
+--key-file is given, and are otherwise kept encrypted.
 `)
 
+// snapshotKeyMixin is used by the commands that operate on encrypted
+// snapshots.
+type snapshotKeyMixin struct {
+	Passphrase bool   `long:"passphrase"`
+	KeyFile    string `long:"key-file"`
+}
+
+var snapshotKeyDescs = mixinDescs{
+	// TRANSLATORS: This should not start with a lowercase letter.
+	"passphrase": i18n.G("Ask for the passphrase the snapshot is encrypted with"),
+	// TRANSLATORS: This should not start with a lowercase letter.
+	"key-file": i18n.G("Use the content of the given file as the key the snapshot is encrypted with"),
+}
+
+// snapshotKey returns the snapshot key given on the command line, if any,
+// asking for a passphrase twice if confirm is set.
+func (x *snapshotKeyMixin) snapshotKey(confirm bool) (*client.SnapshotKey, error) {
+	switch {
+	case x.Passphrase && x.KeyFile != "":
+		return nil, errors.New(i18n.G("cannot use --passphrase and --key-file together"))
+	case x.KeyFile != "":
+		data, err := os.ReadFile(x.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf(i18n.G("cannot read key file: %v"), err)
+		}
+		return &client.SnapshotKey{KeyFile: data}, nil
+	case x.Passphrase:
+		passphrase, err := readPassphrase(i18n.G("Passphrase: "))
+		if err != nil {
+			return nil, err
+		}
+		if passphrase == "" {
+			return nil, errors.New(i18n.G("passphrase cannot be empty"))
+		}
+		if confirm {
+			again, err := readPassphrase(i18n.G("Repeat passphrase: "))
+			if err != nil {
+				return nil, err
+			}
+			if again != passphrase {
+				return nil, errors.New(i18n.G("passphrases do not match"))
+			}
+		}
+		return &client.SnapshotKey{Passphrase: passphrase}, nil
+	}
+	return nil, nil
+}
+
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(Stdout, prompt)
+	passphrase, err := ReadPassword(0)
+	fmt.Fprint(Stdout, "\n")
+	if err != nil {
+		return "", err
+	}
+	// strings.TrimRight needed because we get \r from the pty in the tests
+	return strings.TrimRight(string(passphrase), "\r"), nil
+}
+
 type savedCmd struct {
 	clientMixin
 	durationMixin
@@ -160,6 +240,15 @@ func (x *savedCmd) Execute([]string) error {
 			if sh.Auto {
 				notes = append(notes, "auto")
 			}
+			if sh.Encryption != nil {
+				notes = append(notes, "encrypted")
+			}
+			if sh.Expires != nil {
+				notes = append(notes, x.fmtExpiry(*sh.Expires))
+			}
+			if sh.Dedup != nil {
+				notes = append(notes, fmt.Sprintf("dedup: %s new, %s reused", fmtSize(sh.Dedup.Stored), fmtSize(sh.Dedup.Reused)))
+			}
 			if sh.Broken != "" {
 				notes = append(notes, "broken: "+sh.Broken)
 			}
@@ -175,10 +264,25 @@ func (x *savedCmd) Execute([]string) error {
 	return nil
 }
 
+// fmtExpiry describes when a snapshot is removed automatically.
+func (x *savedCmd) fmtExpiry(t time.Time) string {
+	if !t.After(timeNow()) {
+		// TRANSLATORS: the snapshot is removed the next time expired snapshots are looked for
+		return i18n.G("expiring")
+	}
+	if x.AbsTime {
+		// TRANSLATORS: %s is a time in RFC 3339 format
+		return fmt.Sprintf(i18n.G("expires %s"), t.Format(time.RFC3339))
+	}
+	// TRANSLATORS: %s is a short duration, like 2d or 5h
+	return fmt.Sprintf(i18n.G("expires in %s"), strings.TrimSpace(quantity.FormatDuration(t.Sub(timeNow()).Seconds())))
+}
+
 type saveCmd struct {
 	waitMixin
 	durationMixin
 	Users      string `long:"users"`
+	To         string `long:"to"`
 	Positional struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -187,7 +291,14 @@ type saveCmd struct {
 func (x *saveCmd) Execute([]string) error {
 	snaps := installedSnapNames(x.Positional.Snaps)
 	users := strutil.CommaSeparatedList(x.Users)
-	setID, changeID, err := x.client.SnapshotMany(snaps, users)
+	var setID uint64
+	var changeID string
+	var err error
+	if x.To != "" {
+		setID, changeID, err = x.client.SnapshotManyTo(snaps, users, x.To)
+	} else {
+		setID, changeID, err = x.client.SnapshotMany(snaps, users)
+	}
 	if err != nil {
 		return err
 	}
@@ -197,6 +308,11 @@ func (x *saveCmd) Execute([]string) error {
 		}
 		return err
 	}
+	if x.To != "" {
+		// the snapshot set is not kept here, so it cannot be listed
+		fmt.Fprintf(Stdout, i18n.G("Saved snapshot #%d to %s.\n"), setID, x.To)
+		return nil
+	}
 
 	y := &savedCmd{
 		clientMixin:   x.clientMixin,
@@ -243,6 +359,7 @@ func (x *forgetCmd) Execute([]string) error {
 
 type checkSnapshotCmd struct {
 	waitMixin
+	snapshotKeyMixin
 	Users      string `long:"users"`
 	Positional struct {
 		ID    snapshotID          `positional-arg-name:"<id>"`
@@ -257,7 +374,11 @@ func (x *checkSnapshotCmd) Execute([]string) error {
 	}
 	snaps := installedSnapNames(x.Positional.Snaps)
 	users := strutil.CommaSeparatedList(x.Users)
-	changeID, err := x.client.CheckSnapshots(setID, snaps, users)
+	key, err := x.snapshotKey(false)
+	if err != nil {
+		return err
+	}
+	changeID, err := x.client.CheckSnapshots(setID, snaps, users, key)
 	if err != nil {
 		return err
 	}
@@ -282,7 +403,9 @@ func (x *checkSnapshotCmd) Execute([]string) error {
 
 type restoreCmd struct {
 	waitMixin
+	snapshotKeyMixin
 	Users      string `long:"users"`
+	From       string `long:"from"`
 	Positional struct {
 		ID    snapshotID          `positional-arg-name:"<id>"`
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
@@ -296,7 +419,16 @@ func (x *restoreCmd) Execute([]string) error {
 	}
 	snaps := installedSnapNames(x.Positional.Snaps)
 	users := strutil.CommaSeparatedList(x.Users)
-	changeID, err := x.client.RestoreSnapshots(setID, snaps, users)
+	key, err := x.snapshotKey(false)
+	if err != nil {
+		return err
+	}
+	var changeID string
+	if x.From != "" {
+		changeID, err = x.client.RestoreSnapshotsFrom(x.From, setID, snaps, users, key)
+	} else {
+		changeID, err = x.client.RestoreSnapshots(setID, snaps, users, key)
+	}
 	if err != nil {
 		return err
 	}
@@ -309,7 +441,9 @@ func (x *restoreCmd) Execute([]string) error {
 	}
 
 	// TODO: also mention the home archives that were actually restored
-	if len(snaps) > 0 {
+	if x.From != "" {
+		fmt.Fprintf(Stdout, i18n.G("Restored snapshot #%s from %s.\n"), x.Positional.ID, x.From)
+	} else if len(snaps) > 0 {
 		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
 		fmt.Fprintf(Stdout, i18n.G("Restored snapshot #%s of snaps %s.\n"),
 			x.Positional.ID, strutil.Quoted(snaps))
@@ -340,6 +474,8 @@ func init() {
 		}, durationDescs.also(waitDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"users": i18n.G("Snapshot data of only specific users (comma-separated) (default: all users)"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"to": i18n.G("Move the snapshot to the given directory or s3://<bucket>[/<prefix>] target"),
 		}), nil)
 
 	addCommand("restore",
@@ -347,9 +483,11 @@ func init() {
 		longRestoreHelp,
 		func() flags.Commander {
 			return &restoreCmd{}
-		}, waitDescs.also(map[string]string{
+		}, waitDescs.also(snapshotKeyDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"users": i18n.G("Restore data of only specific users (comma-separated) (default: all users)"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"from": i18n.G("Restore the snapshot from the given directory or s3://<bucket>[/<prefix>] target"),
 		}), []argDesc{
 			{
 				name: "<id>",
@@ -384,7 +522,7 @@ func init() {
 		longCheckHelp,
 		func() flags.Commander {
 			return &checkSnapshotCmd{}
-		}, waitDescs.also(map[string]string{
+		}, waitDescs.also(snapshotKeyDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"users": i18n.G("Check data of only specific users (comma-separated) (default: all users)"),
 		}), []argDesc{
@@ -404,7 +542,12 @@ func init() {
 		longExportSnapshotHelp,
 		func() flags.Commander {
 			return &exportSnapshotCmd{}
-		}, nil, []argDesc{
+		}, mixinDescs{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"passphrase": i18n.G("Ask for a passphrase to encrypt the snapshot with"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"key-file": i18n.G("Encrypt the snapshot with the content of the given file as key"),
+		}, []argDesc{
 			{
 				name: "<id>",
 				// TRANSLATORS: This should not start with a lowercase letter.
@@ -423,7 +566,7 @@ func init() {
 		longImportSnapshotHelp,
 		func() flags.Commander {
 			return &importSnapshotCmd{}
-		}, nil, []argDesc{
+		}, durationDescs.also(snapshotKeyDescs), []argDesc{
 			{
 				name: "<filename>",
 				// TRANSLATORS: This should not start with a lowercase letter.
@@ -434,6 +577,7 @@ func init() {
 
 type exportSnapshotCmd struct {
 	clientMixin
+	snapshotKeyMixin
 	Positional struct {
 		ID       snapshotID `positional-arg-name:"<id>"`
 		Filename string     `long:"filename"`
@@ -446,7 +590,12 @@ func (x *exportSnapshotCmd) Execute([]string) (err error) {
 		return err
 	}
 
-	r, expectedSize, err := x.client.SnapshotExport(setID)
+	key, err := x.snapshotKey(true)
+	if err != nil {
+		return err
+	}
+
+	r, expectedSize, err := x.client.SnapshotExport(setID, key)
 	if err != nil {
 		return err
 	}
@@ -488,6 +637,7 @@ func (x *exportSnapshotCmd) Execute([]string) (err error) {
 type importSnapshotCmd struct {
 	clientMixin
 	durationMixin
+	snapshotKeyMixin
 	Positional struct {
 		Filename string `long:"filename"`
 	} `positional-args:"yes" required:"yes"`
@@ -505,7 +655,12 @@ func (x *importSnapshotCmd) Execute([]string) error {
 		return fmt.Errorf("cannot stat file: %v", err)
 	}
 
-	importSet, err := x.client.SnapshotImport(f, st.Size())
+	key, err := x.snapshotKey(false)
+	if err != nil {
+		return err
+	}
+
+	importSet, err := x.client.SnapshotImport(f, st.Size(), key)
 	if err != nil {
 		return err
 	}
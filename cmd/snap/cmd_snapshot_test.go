@@ -20,6 +20,7 @@
 package main_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -44,6 +45,21 @@ var snapshotsTests = []getCmdArgs{{
 }, {
 	args:   "saved --id=3",
 	stdout: "Set  Snap  Age    Version  Rev   Size    Notes\n3    htop  .*  2        1168      1B  auto\n",
+}, {
+	args:   "saved --id=4",
+	stdout: "Set  Snap  Age    Version  Rev   Size    Notes\n4    htop  .*  2        1168  3.00MB  auto, dedup: 1.00MB new, 2.00MB reused\n",
+}, {
+	args:   "saved --id=5",
+	stdout: "Set  Snap  Age    Version  Rev   Size    Notes\n5    htop  .*  2        1168      1B  encrypted\n",
+}, {
+	args:   "saved --id=6",
+	stdout: "Set  Snap  Age    Version  Rev   Size    Notes\n6    htop  .*  2        1168      1B  auto, expires in 2d.*\n",
+}, {
+	args:   "saved --id=6 --abs-time",
+	stdout: "Set  Snap  Age.*Version  Rev   Size    Notes\n6    htop  .*  2        1168      1B  auto, expires 20.*\n",
+}, {
+	args:   "saved --id=7",
+	stdout: "Set  Snap  Age    Version  Rev   Size    Notes\n7    htop  .*  2        1168      1B  expiring\n",
 }, {
 	args:   "saved",
 	stdout: "Set  Snap  Age    Version  Rev   Size    Notes\n1    htop  .*  2        1168      1B  -\n",
@@ -113,6 +129,142 @@ func (s *SnapSuite) TestSnapshotExportHappy(c *C) {
 	c.Check(exportedSnapshotPath+".part", testutil.FileAbsent)
 }
 
+func (s *SnapSuite) TestSnapshotExportEncrypted(c *C) {
+	var keyHeader string
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/snapshots/1/export")
+		keyHeader = r.Header.Get(client.SnapshotKeyHeader)
+		w.Header().Set("Content-Type", client.SnapshotExportMediaType)
+		fmt.Fprint(w, "Hello World!")
+	})
+	s.password = "sekrit"
+
+	exportedSnapshotPath := filepath.Join(c.MkDir(), "export-snapshot.snapshot")
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"export-snapshot", "--passphrase", "1", exportedSnapshotPath})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), testutil.MatchesWrapped, `Passphrase: \nRepeat passphrase: \nExported snapshot #1 into ".*/export-snapshot.snapshot"`)
+	// base64 of {"passphrase":"sekrit"}
+	c.Check(keyHeader, Equals, "eyJwYXNzcGhyYXNlIjoic2Vrcml0In0=")
+	c.Check(exportedSnapshotPath, testutil.FileEquals, "Hello World!")
+}
+
+func (s *SnapSuite) TestSnapshotExportPassphraseMismatch(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Errorf("unexpected request to %q", r.URL.Path)
+	})
+	passphrases := []string{"sekrit", "sercet"}
+	// restored on TearDownTest
+	main.ReadPassword = func(int) ([]byte, error) {
+		p := passphrases[0]
+		passphrases = passphrases[1:]
+		return []byte(p), nil
+	}
+
+	exportedSnapshotPath := filepath.Join(c.MkDir(), "export-snapshot.snapshot")
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"export-snapshot", "--passphrase", "1", exportedSnapshotPath})
+	c.Assert(err, ErrorMatches, "passphrases do not match")
+	c.Check(exportedSnapshotPath, testutil.FileAbsent)
+}
+
+func (s *SnapSuite) TestSnapshotRestoreWithKeyFile(c *C) {
+	var action map[string]any
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/snapshots":
+			c.Check(r.Method, Equals, "POST")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, action)
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "9"}`)
+		case "/v2/changes/9":
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {}}}`)
+		default:
+			c.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+
+	keyFile := filepath.Join(c.MkDir(), "key")
+	c.Assert(os.WriteFile(keyFile, []byte("0123456789abcdef"), 0600), IsNil)
+
+	for _, cmd := range []string{"restore", "check-snapshot"} {
+		verb := "restore"
+		if cmd == "check-snapshot" {
+			verb = "check"
+		}
+		action = map[string]any{
+			"set":    json.Number("1"),
+			"action": verb,
+			"key":    map[string]any{"key-file": "MDEyMzQ1Njc4OWFiY2RlZg=="},
+		}
+		_, err := main.Parser(main.Client()).ParseArgs([]string{cmd, "--key-file", keyFile, "1"})
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *SnapSuite) TestSnapshotSaveTo(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/snaps":
+			c.Check(r.Method, Equals, "POST")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]any{
+				"action":          "snapshot",
+				"snaps":           []any{"htop"},
+				"snapshot-target": "s3://bucket/snaps",
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "9", "result": {"set-id": 42}}`)
+		case "/v2/changes/9":
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {}}}`)
+		default:
+			c.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"save", "--to", "s3://bucket/snaps", "htop"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "Saved snapshot #42 to s3://bucket/snaps.\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestSnapshotRestoreFrom(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/snapshots":
+			c.Check(r.Method, Equals, "POST")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]any{
+				"set":    json.Number("12"),
+				"action": "restore",
+				"from":   "/mnt/backups",
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "9"}`)
+		case "/v2/changes/9":
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {}}}`)
+		default:
+			c.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"restore", "--from", "/mnt/backups", "12"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "Restored snapshot #12 from /mnt/backups.\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestSnapshotKeyErrors(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Errorf("unexpected request to %q", r.URL.Path)
+	})
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"restore", "--key-file", "foo", "--passphrase", "1"})
+	c.Check(err, ErrorMatches, "cannot use --passphrase and --key-file together")
+
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"restore", "--key-file", filepath.Join(c.MkDir(), "missing"), "1"})
+	c.Check(err, ErrorMatches, "cannot read key file: .*")
+
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"check-snapshot", "--passphrase", "1"})
+	c.Check(err, ErrorMatches, "passphrase cannot be empty")
+}
+
 func (s *SnapSuite) mockSnapshotsServer(c *C) {
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -124,6 +276,23 @@ func (s *SnapSuite) mockSnapshotsServer(c *C) {
 					fmt.Fprintf(w, `{"type":"sync","status-code":200,"status":"OK","result":[{"id":3,"snapshots":[{"set":3,"time":%q,"snap":"htop","revision":"1168","snap-id":"Z","auto":true,"epoch":{"read":[0],"write":[0]},"summary":"","version":"2","sha3-384":{"archive.tgz":""},"size":1}]}]}`, snapshotTime)
 					return
 				}
+				if r.URL.Query().Get("set") == "4" {
+					fmt.Fprintf(w, `{"type":"sync","status-code":200,"status":"OK","result":[{"id":4,"snapshots":[{"set":4,"time":%q,"snap":"htop","revision":"1168","snap-id":"Z","auto":true,"epoch":{"read":[0],"write":[0]},"summary":"","version":"2","sha3-384":{"archive.tgz":""},"size":3000000,"dedup":{"stored":1000000,"reused":2000000}}]}]}`, snapshotTime)
+					return
+				}
+				if r.URL.Query().Get("set") == "5" {
+					fmt.Fprintf(w, `{"type":"sync","status-code":200,"status":"OK","result":[{"id":5,"snapshots":[{"set":5,"time":%q,"snap":"htop","revision":"1168","snap-id":"Z","epoch":{"read":[0],"write":[0]},"summary":"","version":"2","sha3-384":{"archive.tgz":""},"size":1,"encryption":{"cipher":"aes-256-gcm-stream","kdf":"argon2id","salt":"c2FsdA==","key-check":"Y2hlY2s="}}]}]}`, snapshotTime)
+					return
+				}
+				if r.URL.Query().Get("set") == "6" {
+					expires := time.Now().Add(49 * time.Hour).Format(time.RFC3339)
+					fmt.Fprintf(w, `{"type":"sync","status-code":200,"status":"OK","result":[{"id":6,"snapshots":[{"set":6,"time":%q,"snap":"htop","revision":"1168","snap-id":"Z","auto":true,"expires":%q,"epoch":{"read":[0],"write":[0]},"summary":"","version":"2","sha3-384":{"archive.tgz":""},"size":1}]}]}`, snapshotTime, expires)
+					return
+				}
+				if r.URL.Query().Get("set") == "7" {
+					fmt.Fprintf(w, `{"type":"sync","status-code":200,"status":"OK","result":[{"id":7,"snapshots":[{"set":7,"time":%q,"snap":"htop","revision":"1168","snap-id":"Z","expires":%q,"epoch":{"read":[0],"write":[0]},"summary":"","version":"2","sha3-384":{"archive.tgz":""},"size":1}]}]}`, snapshotTime, snapshotTime)
+					return
+				}
 				fmt.Fprintf(w, `{"type":"sync","status-code":200,"status":"OK","result":[{"id":1,"snapshots":[{"set":1,"time":%q,"snap":"htop","revision":"1168","snap-id":"Z","epoch":{"read":[0],"write":[0]},"summary":"","version":"2","sha3-384":{"archive.tgz":""},"size":1}]}]}`, snapshotTime)
 			}
 			if r.Method == "POST" {
@@ -39,7 +39,7 @@ The watch command waits for the given change-id to finish and shows progress
 
 func init() {
 	addCommand("watch", shortWatchHelp, longWatchHelp, func() flags.Commander {
-		return &cmdWatch{mustWaitMixin: mustWaitMixin{skipAbort: true, waitForTasksInWaitStatus: true}}
+		return &cmdWatch{mustWaitMixin: mustWaitMixin{skipAbort: true, waitForTasksInWaitStatus: true, followEvents: true}}
 	}, changeIDMixinOptDesc, changeIDMixinArgDesc)
 }
 
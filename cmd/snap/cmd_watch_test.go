@@ -22,6 +22,7 @@ package main_test
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -48,7 +49,20 @@ func (s *SnapSuite) TestCmdWatch(c *C) {
 	defer snap.MockPollTime(time.Millisecond)()
 
 	n := 0
+	eventsReqs := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/events" {
+			eventsReqs++
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Query(), DeepEquals, url.Values{
+				"types":     {"change-status,task-status,task-progress"},
+				"change-id": {"two"},
+			})
+			w.Header().Set("Content-Type", "application/json-seq")
+			fmt.Fprint(w, "\x1e"+`{"type":"task-progress","change-id":"two","task-id":"84","progress":{"label":"my-snap","done":51200,"total":102400}}`+"\n")
+			fmt.Fprint(w, "\x1e"+`{"type":"change-status","change-id":"two","status":"Done","ready":true}`+"\n")
+			return
+		}
 		n++
 		switch n {
 		case 1:
@@ -71,6 +85,7 @@ func (s *SnapSuite) TestCmdWatch(c *C) {
 	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"watch", "two"})
 	c.Assert(err, IsNil)
 	c.Assert(rest, HasLen, 0)
+	c.Check(eventsReqs, Equals, 1)
 	c.Check(n, Equals, 3)
 	c.Check(meter.Values, DeepEquals, []float64{51200})
 	c.Check(s.Stdout(), Equals, "")
@@ -85,6 +100,12 @@ func (s *SnapSuite) TestWatchLast(c *C) {
 
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/events" {
+			// snapd too old to stream events, fall back to polling
+			w.WriteHeader(404)
+			fmt.Fprintln(w, `{"type": "error", "status-code": 404, "result": {"message": "not found"}}`)
+			return
+		}
 		n++
 		switch n {
 		case 1:
@@ -162,6 +183,11 @@ func (s *SnapOpSuite) TestWatchWaitsForWaitTasks(c *C) {
 
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/events" {
+			// the stream ends right away, so fall back to polling
+			w.Header().Set("Content-Type", "application/json-seq")
+			return
+		}
 		switch n {
 		case 0:
 			fmt.Fprintln(w, `{"type": "sync",
@@ -188,3 +214,41 @@ func (s *SnapOpSuite) TestWatchWaitsForWaitTasks(c *C) {
 	c.Check(meter.Notices, testutil.Contains, "INFO: Task set to wait until a manual system restart allows to continue")
 	c.Check(n, Equals, 2)
 }
+
+func (s *SnapSuite) TestWatchEventsWakeUp(c *C) {
+	meter := &progresstest.Meter{}
+	defer progress.MockMeter(meter)()
+	defer snap.MockMaxGoneTime(time.Millisecond)()
+	// polling would make the test time out
+	defer snap.MockPollTime(time.Hour)()
+	defer snap.MockEventsFallbackTime(time.Hour)()
+
+	eventSent := make(chan struct{})
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/events" {
+			w.Header().Set("Content-Type", "application/json-seq")
+			w.(http.Flusher).Flush()
+			fmt.Fprint(w, "\x1e"+`{"type":"change-status","change-id":"two","status":"Done","ready":true}`+"\n")
+			w.(http.Flusher).Flush()
+			// keep the stream open until the change was queried again
+			<-eventSent
+			return
+		}
+		n++
+		c.Check(r.URL.Path, Equals, "/v2/changes/two")
+		switch n {
+		case 1:
+			fmt.Fprintf(w, fmtWatchChangeJSON, 0, 100*1024)
+		case 2:
+			close(eventSent)
+			fmt.Fprintln(w, `{"type": "sync", "result": {"id": "two", "ready": true, "status": "Done"}}`)
+		default:
+			c.Errorf("expected 2 queries, currently on %d", n)
+		}
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"watch", "two"})
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+}
@@ -168,6 +168,14 @@ func MockPollTime(d time.Duration) (restore func()) {
 	}
 }
 
+func MockEventsFallbackTime(d time.Duration) (restore func()) {
+	d0 := eventsFallbackTime
+	eventsFallbackTime = d
+	return func() {
+		eventsFallbackTime = d0
+	}
+}
+
 func MockMaxGoneTime(d time.Duration) (restore func()) {
 	d0 := maxGoneTime
 	maxGoneTime = d
@@ -478,6 +486,28 @@ func ParseQuotaValues(maxMemory, cpuMax, cpuSet, threadsMax, journalSizeMax, jou
 	return quotas.parseQuotas()
 }
 
+func ParseIOQuotaValues(ioWeight string, readBandwidth, writeBandwidth, readIOPS, writeIOPS []string) (*client.QuotaValues, error) {
+	var quotas cmdSetQuota
+
+	quotas.IOWeight = ioWeight
+	quotas.IOReadBandwidth = readBandwidth
+	quotas.IOWriteBandwidth = writeBandwidth
+	quotas.IOReadIOPS = readIOPS
+	quotas.IOWriteIOPS = writeIOPS
+
+	return quotas.parseQuotas()
+}
+
+func ParseMemoryHighQuotaValues(memoryHigh, oomPolicy, memoryPressure string) (*client.QuotaValues, error) {
+	var quotas cmdSetQuota
+
+	quotas.MemoryHigh = memoryHigh
+	quotas.OOMPolicy = oomPolicy
+	quotas.MemoryPressure = memoryPressure
+
+	return quotas.parseQuotas()
+}
+
 func MockSeedWriterReadManifest(f func(manifestFile string) (*seedwriter.Manifest, error)) (restore func()) {
 	restore = testutil.Backup(&seedwriterReadManifest)
 	seedwriterReadManifest = f
@@ -491,3 +521,7 @@ func MockGetSystemKeyRetryCount(f func() int) (restore func()) {
 func MockTimeAfter(f func(d time.Duration) <-chan time.Time) (restore func()) {
 	return testutil.Mock(&timeAfter, f)
 }
+
+func MockSnapdeltaCreate(f func(oldPath, newPath, deltaPath string) error) (restore func()) {
+	return testutil.Mock(&snapdeltaCreate, f)
+}
@@ -105,6 +105,12 @@ var debugCommands []*cmdInfo
 // routineCommands holds information about all internal commands.
 var routineCommands []*cmdInfo
 
+// confdbCommands holds information about all confdb commands.
+var confdbCommands []*cmdInfo
+
+// deltaCommands holds information about all delta commands.
+var deltaCommands []*cmdInfo
+
 // addCommand replaces parser.addCommand() in a way that is compatible with
 // re-constructing a pristine parser.
 func addCommand(name, shortHelp, longHelp string, builder func() flags.Commander, optDescs map[string]string, argDescs []argDesc) *cmdInfo {
@@ -152,6 +158,38 @@ func addRoutineCommand(name, shortHelp, longHelp string, builder func() flags.Co
 	return info
 }
 
+// addConfdbCommand replaces parser.addCommand() in a way that is
+// compatible with re-constructing a pristine parser. It is meant for
+// adding "snap confdb" commands.
+func addConfdbCommand(name, shortHelp, longHelp string, builder func() flags.Commander, optDescs map[string]string, argDescs []argDesc) *cmdInfo {
+	info := &cmdInfo{
+		name:      name,
+		shortHelp: shortHelp,
+		longHelp:  longHelp,
+		builder:   builder,
+		optDescs:  optDescs,
+		argDescs:  argDescs,
+	}
+	confdbCommands = append(confdbCommands, info)
+	return info
+}
+
+// addDeltaCommand replaces parser.addCommand() in a way that is
+// compatible with re-constructing a pristine parser. It is meant for
+// adding "snap delta" commands.
+func addDeltaCommand(name, shortHelp, longHelp string, builder func() flags.Commander, optDescs map[string]string, argDescs []argDesc) *cmdInfo {
+	info := &cmdInfo{
+		name:      name,
+		shortHelp: shortHelp,
+		longHelp:  longHelp,
+		builder:   builder,
+		optDescs:  optDescs,
+		argDescs:  argDescs,
+	}
+	deltaCommands = append(deltaCommands, info)
+	return info
+}
+
 type parserSetter interface {
 	setParser(*flags.Parser)
 }
@@ -334,7 +372,7 @@ func Parser(cli *client.Client) *flags.Parser {
 	// add --help like what go-flags would do for us, but hidden
 	addHelp(parser)
 
-	seen := make(map[string]bool, len(commands)+len(debugCommands)+len(routineCommands))
+	seen := make(map[string]bool, len(commands)+len(debugCommands)+len(routineCommands)+len(confdbCommands)+len(deltaCommands))
 	checkUnique := func(ci *cmdInfo, kind string) {
 		if seen[ci.shortHelp] && ci.shortHelp != "Internal" && ci.shortHelp != "Deprecated (hidden)" {
 			logger.Panicf(`%scommand %q has an already employed description != "Internal"|"Deprecated (hidden)": %s`, kind, ci.name, ci.shortHelp)
@@ -365,6 +403,24 @@ func Parser(cli *client.Client) *flags.Parser {
 	registerCommands(cli, parser, routineCommand, routineCommands, func(ci *cmdInfo) {
 		checkUnique(ci, "routine ")
 	})
+	// Add the confdb command
+	confdbCommand, err := parser.AddCommand("confdb", shortConfdbHelp, longConfdbHelp, &cmdConfdb{})
+	if err != nil {
+		logger.Panicf("cannot add command %q: %v", "confdb", err)
+	}
+	// Add all the sub-commands of the confdb command
+	registerCommands(cli, parser, confdbCommand, confdbCommands, func(ci *cmdInfo) {
+		checkUnique(ci, "confdb ")
+	})
+	// Add the delta command
+	deltaCommand, err := parser.AddCommand("delta", shortDeltaHelp, longDeltaHelp, &cmdDelta{})
+	if err != nil {
+		logger.Panicf("cannot add command %q: %v", "delta", err)
+	}
+	// Add all the sub-commands of the delta command
+	registerCommands(cli, parser, deltaCommand, deltaCommands, func(ci *cmdInfo) {
+		checkUnique(ci, "delta ")
+	})
 	return parser
 }
 
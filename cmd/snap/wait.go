@@ -34,6 +34,9 @@ import (
 var (
 	maxGoneTime = 5 * time.Second
 	pollTime    = 100 * time.Millisecond
+	// eventsFallbackTime is how long to wait for an event from snapd before
+	// checking on the change anyway.
+	eventsFallbackTime = 5 * time.Second
 )
 
 // mustWaitMixin mixin which exposes a helper method to wait for a state change.
@@ -46,6 +49,39 @@ type mustWaitMixin struct {
 
 	// Wait also for tasks in the "wait" state.
 	waitForTasksInWaitStatus bool
+
+	// followEvents when set, the change is only queried again when snapd
+	// reports an event for it, instead of polling it continuously.
+	followEvents bool
+}
+
+// changeEvents returns a channel which receives a value whenever snapd reports
+// an event for the given change, and a function to stop following events.
+// Bursts of events are coalesced. The channel is closed once the stream of
+// events ends. If the stream cannot be opened, for example because snapd is
+// too old to provide it, a nil channel is returned.
+func changeEvents(cli *client.Client, id string) (wakeUp <-chan struct{}, stop func()) {
+	stream, err := cli.StreamEvents(&client.EventsOptions{
+		Types:    []client.EventType{client.ChangeStatusEvent, client.TaskStatusEvent, client.TaskProgressEvent},
+		ChangeID: id,
+	})
+	if err != nil {
+		return nil, func() {}
+	}
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+		for {
+			if _, err := stream.Next(); err != nil {
+				return
+			}
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return ch, func() { stream.Close() }
 }
 
 // wait waits for a given change to complete. By default, unless skipAbort is
@@ -82,6 +118,13 @@ func (wmx mustWaitMixin) wait(id string) (*client.Change, error) {
 		close(c)
 	}()
 
+	var wakeUp <-chan struct{}
+	if wmx.followEvents {
+		var stopEvents func()
+		wakeUp, stopEvents = changeEvents(cli, id)
+		defer stopEvents()
+	}
+
 	tMax := time.Time{}
 
 	var lastID string
@@ -202,6 +245,19 @@ func (wmx mustWaitMixin) wait(id string) (*client.Change, error) {
 			return nil, rebootingErr
 		}
 
+		if wakeUp != nil {
+			select {
+			case _, ok := <-wakeUp:
+				if !ok {
+					// the stream of events ended, which also happens
+					// when snapd restarts, so fall back to polling
+					wakeUp = nil
+				}
+			case <-time.After(eventsFallbackTime):
+			}
+			continue
+		}
+
 		// note this very purposely is not a ticker; we want
 		// to sleep 100ms between calls, not call once every
 		// 100ms.
@@ -37,6 +37,7 @@ import (
 	"github.com/snapcore/snapd/snapdtool"
 	"github.com/snapcore/snapd/syscheck"
 	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/timings"
 )
 
 var (
@@ -124,6 +125,19 @@ func run(ch chan os.Signal) error {
 	t0 := time.Now().Truncate(time.Millisecond)
 	snapdenv.SetUserAgentFromVersion(snapdtool.Version, sandbox.ForceDevMode)
 
+	if target := os.Getenv("SNAPD_TIMINGS_OTLP_EXPORT"); target != "" {
+		exporter, err := timings.NewOTLPExporter(target)
+		if err != nil {
+			logger.Noticef("cannot export timings: %v", err)
+		} else {
+			timings.SetExporter(exporter)
+			defer func() {
+				timings.SetExporter(nil)
+				exporter.Close()
+			}()
+		}
+	}
+
 	d, err := daemon.New()
 	if err != nil {
 		return err
@@ -231,7 +231,10 @@ type Schema struct {
 	Account       string
 	Name          string
 	DatabagSchema DatabagSchema
-	views         map[string]*View
+	// Migrations describe how to move data stored under previous revisions
+	// of the schema to the current storage layout, ordered by revision.
+	Migrations []*Migration
+	views      map[string]*View
 }
 
 // GetViewsAffectedByPath returns all the views in the confdb schema that have
@@ -255,6 +258,55 @@ func (s *Schema) GetViewsAffectedByPath(path []Accessor) []*View {
 	return views
 }
 
+// CanReadPath returns true if the view has a rule which can read the value
+// stored at the path, that is whose storage is the path or one of its parents.
+func (v *View) CanReadPath(path []Accessor) bool {
+	for _, rule := range v.rules {
+		if rule.isReadable() && storageCoversPath(rule.storage, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanWritePath returns true if the view has a rule which can write the value
+// stored at the path, that is whose storage is the path or one of its parents.
+func (v *View) CanWritePath(path []Accessor) bool {
+	for _, rule := range v.rules {
+		if rule.isWriteable() && storageCoversPath(rule.storage, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// storageCoversPath returns true if the storage of a rule is the path or one
+// of its parents. A storage accessing every element of a list also covers the
+// list itself.
+func storageCoversPath(storage, path []Accessor) bool {
+	for i, acc := range storage {
+		if len(path) <= i {
+			return i == len(storage)-1 && acc.Type() == IndexPlaceholderType
+		}
+
+		switch acc.Type() {
+		case KeyPlaceholderType:
+			if path[i].Type() != MapKeyType {
+				return false
+			}
+		case IndexPlaceholderType:
+			if path[i].Type() != ListIndexType {
+				return false
+			}
+		default:
+			if path[i] != acc {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func pathChangeAffects(modified, affected []Accessor) bool {
 	for i, affectedKey := range affected {
 		if affectedKey.Type() == IndexPlaceholderType || affectedKey.Type() == KeyPlaceholderType {
@@ -2987,6 +2987,49 @@ func (*viewSuite) TestGetAffectedViews(c *C) {
 	}
 }
 
+func (*viewSuite) TestViewCanReadWritePath(c *C) {
+	views := map[string]any{
+		"view": map[string]any{
+			"rules": []any{
+				map[string]any{"request": "ssid", "storage": "wifi.ssid"},
+				map[string]any{"request": "psk", "storage": "wifi.psk", "access": "write"},
+				map[string]any{"request": "status", "storage": "wifi.status", "access": "read"},
+				map[string]any{"request": "{n}.name", "storage": "ifaces.{n}.name"},
+				map[string]any{"request": "dns[{n}]", "storage": "dns[{n}]"},
+				map[string]any{"request": "first-route", "storage": "routes[0]"},
+			},
+		},
+	}
+	schema, err := confdb.NewSchema("acc", "db", views, confdb.NewJSONSchema())
+	c.Assert(err, IsNil)
+	view := schema.View("view")
+
+	for _, tc := range []struct {
+		path  string
+		read  bool
+		write bool
+	}{
+		{path: "wifi.ssid", read: true, write: true},
+		{path: "wifi.ssid.foo", read: true, write: true},
+		{path: "wifi.psk", write: true},
+		{path: "wifi.status", read: true},
+		// parents of the rules' storage hold values which can't be accessed
+		{path: "wifi"},
+		{path: "wifi.other"},
+		{path: "ifaces.eth0.name", read: true, write: true},
+		{path: "ifaces.eth0.mtu"},
+		// lists are stored as a whole
+		{path: "dns", read: true, write: true},
+		{path: "dns[1]", read: true, write: true},
+		{path: "routes"},
+		{path: "routes[0]", read: true, write: true},
+	} {
+		cmt := Commentf("path %q", tc.path)
+		c.Check(view.CanReadPath(parsePath(c, tc.path)), Equals, tc.read, cmt)
+		c.Check(view.CanWritePath(parsePath(c, tc.path)), Equals, tc.write, cmt)
+	}
+}
+
 func (*viewSuite) TestCheckReadEphemeralAccess(c *C) {
 	schemaStr := []byte(`{
 	"schema": {
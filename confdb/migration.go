@@ -0,0 +1,354 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package confdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// MigrationOp is the kind of change a migration rule makes to a databag.
+type MigrationOp string
+
+const (
+	// MigrationRename renames the last key of a storage path, keeping the
+	// value under the same parent.
+	MigrationRename MigrationOp = "rename"
+	// MigrationMove moves the subtree at a storage path to another path.
+	MigrationMove MigrationOp = "move"
+	// MigrationTransform converts the value at a storage path into another
+	// scalar type.
+	MigrationTransform MigrationOp = "transform"
+)
+
+// MigrationRule is a single declarative change applied to a databag when it
+// is migrated to a new confdb-schema revision.
+type MigrationRule struct {
+	Op MigrationOp
+	// From and To are the source and destination paths of rename and move
+	// rules.
+	From []Accessor
+	To   []Accessor
+	// Path and Type are the path and target type of transform rules.
+	Path []Accessor
+	Type SchemaType
+}
+
+// Migration holds the rules that take a databag from the previous revision of
+// a confdb-schema to the revision the migration is declared for.
+type Migration struct {
+	Revision int
+	Rules    []*MigrationRule
+}
+
+type migrationJSON struct {
+	Revision int               `json:"revision"`
+	Rules    []json.RawMessage `json:"rules"`
+}
+
+type migrationRuleJSON struct {
+	Op   MigrationOp `json:"op"`
+	From string      `json:"from"`
+	To   string      `json:"to"`
+	Path string      `json:"path"`
+	Type string      `json:"type"`
+}
+
+// ParseMigrations parses the migrations of a confdb-schema. The migrations
+// must be ordered by strictly increasing revision and have non-empty rules.
+func ParseMigrations(raw []byte) ([]*Migration, error) {
+	var migrationsJSON []migrationJSON
+	if err := json.Unmarshal(raw, &migrationsJSON); err != nil {
+		return nil, fmt.Errorf("cannot parse migrations: %w", err)
+	}
+
+	migrations := make([]*Migration, 0, len(migrationsJSON))
+	prevRevision := 0
+	for _, migrationJSON := range migrationsJSON {
+		if migrationJSON.Revision <= prevRevision {
+			return nil, fmt.Errorf("cannot parse migrations: revisions must be positive and strictly increasing but got %d after %d", migrationJSON.Revision, prevRevision)
+		}
+		prevRevision = migrationJSON.Revision
+
+		if len(migrationJSON.Rules) == 0 {
+			return nil, fmt.Errorf("cannot parse migration for revision %d: rules must be a non-empty list", migrationJSON.Revision)
+		}
+
+		migration := &Migration{
+			Revision: migrationJSON.Revision,
+			Rules:    make([]*MigrationRule, 0, len(migrationJSON.Rules)),
+		}
+		for _, ruleRaw := range migrationJSON.Rules {
+			rule, err := parseMigrationRule(ruleRaw)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse migration for revision %d: %w", migrationJSON.Revision, err)
+			}
+			migration.Rules = append(migration.Rules, rule)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	return migrations, nil
+}
+
+func parseMigrationRule(raw json.RawMessage) (*MigrationRule, error) {
+	var ruleJSON migrationRuleJSON
+	if err := json.Unmarshal(raw, &ruleJSON); err != nil {
+		return nil, fmt.Errorf("cannot parse rule: %w", err)
+	}
+
+	rule := &MigrationRule{Op: ruleJSON.Op}
+	switch ruleJSON.Op {
+	case MigrationRename, MigrationMove:
+		if ruleJSON.Path != "" || ruleJSON.Type != "" {
+			return nil, fmt.Errorf(`%s rule can only have "from" and "to" fields`, ruleJSON.Op)
+		}
+
+		var err error
+		if rule.From, err = parseMigrationPath(ruleJSON.From); err != nil {
+			return nil, fmt.Errorf(`%s rule has invalid "from" path: %w`, ruleJSON.Op, err)
+		}
+		if rule.To, err = parseMigrationPath(ruleJSON.To); err != nil {
+			return nil, fmt.Errorf(`%s rule has invalid "to" path: %w`, ruleJSON.Op, err)
+		}
+
+		if pathHasPrefix(rule.To, rule.From) || pathHasPrefix(rule.From, rule.To) {
+			return nil, fmt.Errorf("%s rule cannot overlap paths %q and %q", ruleJSON.Op, ruleJSON.From, ruleJSON.To)
+		}
+
+		if ruleJSON.Op == MigrationRename {
+			parent := rule.From[:len(rule.From)-1]
+			if len(rule.To) != len(rule.From) || !pathHasPrefix(rule.To, parent) {
+				return nil, fmt.Errorf("rename rule cannot change the parent of %q (use a move rule instead)", ruleJSON.From)
+			}
+		}
+
+	case MigrationTransform:
+		if ruleJSON.From != "" || ruleJSON.To != "" {
+			return nil, errors.New(`transform rule can only have "path" and "type" fields`)
+		}
+
+		var err error
+		if rule.Path, err = parseMigrationPath(ruleJSON.Path); err != nil {
+			return nil, fmt.Errorf(`transform rule has invalid "path": %w`, err)
+		}
+
+		switch ruleJSON.Type {
+		case "int":
+			rule.Type = Int
+		case "number":
+			rule.Type = Number
+		case "string":
+			rule.Type = String
+		case "bool":
+			rule.Type = Bool
+		default:
+			return nil, fmt.Errorf(`transform rule has unsupported type %q (must be one of "int", "number", "string" or "bool")`, ruleJSON.Type)
+		}
+
+	default:
+		return nil, fmt.Errorf(`unknown rule op %q (must be one of "rename", "move" or "transform")`, ruleJSON.Op)
+	}
+
+	return rule, nil
+}
+
+func parseMigrationPath(path string) ([]Accessor, error) {
+	if path == "" {
+		return nil, errors.New("path cannot be empty")
+	}
+	return ParsePathIntoAccessors(path, ParseOptions{})
+}
+
+func pathHasPrefix(path, prefix []Accessor) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i].Access() != prefix[i].Access() {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateMigrations checks that the rules of the migrations leave data in
+// places that exist in the storage schema and, for transform rules, that the
+// target type can be stored there. Rules whose destination is changed again
+// by a later migration must instead point directly to the final location.
+func ValidateMigrations(migrations []*Migration, schema DatabagSchema) error {
+	for _, migration := range migrations {
+		for _, rule := range migration.Rules {
+			if err := validateMigrationRule(rule, schema); err != nil {
+				return fmt.Errorf("invalid migration for revision %d: %w", migration.Revision, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateMigrationRule(rule *MigrationRule, schema DatabagSchema) error {
+	switch rule.Op {
+	case MigrationRename, MigrationMove:
+		if _, err := schema.SchemaAt(rule.To); err != nil {
+			return fmt.Errorf("cannot %s %q to %q: %w", rule.Op, JoinAccessors(rule.From), JoinAccessors(rule.To), err)
+		}
+
+	case MigrationTransform:
+		schemas, err := schema.SchemaAt(rule.Path)
+		if err != nil {
+			return fmt.Errorf("cannot transform %q: %w", JoinAccessors(rule.Path), err)
+		}
+
+		for _, s := range schemas {
+			if s.Type() == rule.Type || s.Type() == Any || (s.Type() == Number && rule.Type == Int) {
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot transform %q into %s: storage schema does not allow it", JoinAccessors(rule.Path), rule.Type)
+
+	default:
+		return fmt.Errorf("unknown rule op %q", rule.Op)
+	}
+
+	return nil
+}
+
+// Apply applies the migration's rules to the databag, in order. Rules whose
+// source path has no data are skipped, so that a databag which never held
+// the migrated data is left untouched. If an error is returned, the databag
+// may have been partially modified, so callers should apply migrations to a
+// copy or within a transaction.
+func (m *Migration) Apply(bag Databag) error {
+	for _, rule := range m.Rules {
+		if err := rule.apply(bag); err != nil {
+			return fmt.Errorf("cannot apply migration for revision %d: %w", m.Revision, err)
+		}
+	}
+	return nil
+}
+
+func (r *MigrationRule) apply(bag Databag) error {
+	switch r.Op {
+	case MigrationRename, MigrationMove:
+		value, err := getMigrationValue(bag, r.From)
+		if err != nil || value == nil {
+			return err
+		}
+
+		existing, err := getMigrationValue(bag, r.To)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("cannot %s %q to %q: destination already has a value", r.Op, JoinAccessors(r.From), JoinAccessors(r.To))
+		}
+
+		if err := bag.Unset(r.From); err != nil {
+			return err
+		}
+		return bag.Set(r.To, value)
+
+	case MigrationTransform:
+		value, err := getMigrationValue(bag, r.Path)
+		if err != nil || value == nil {
+			return err
+		}
+
+		converted, err := convertValue(value, r.Type)
+		if err != nil {
+			return fmt.Errorf("cannot transform %q: %w", JoinAccessors(r.Path), err)
+		}
+		return bag.Set(r.Path, converted)
+
+	default:
+		return fmt.Errorf("unknown rule op %q", r.Op)
+	}
+}
+
+// getMigrationValue returns the value at the path or nil if there is none.
+func getMigrationValue(bag Databag, path []Accessor) (any, error) {
+	value, err := bag.Get(path)
+	if err != nil {
+		if errors.Is(err, PathError("")) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+func convertValue(value any, typ SchemaType) (any, error) {
+	switch typ {
+	case String:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+
+	case Int:
+		switch v := value.(type) {
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to int", v)
+			}
+			return i, nil
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("cannot convert %v to int without losing precision", v)
+			}
+			return int64(v), nil
+		}
+
+	case Number:
+		switch v := value.(type) {
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to number", v)
+			}
+			return f, nil
+		case float64:
+			return v, nil
+		}
+
+	case Bool:
+		switch v := value.(type) {
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to bool", v)
+			}
+			return b, nil
+		case bool:
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot convert value of type %T to %s", value, typ)
+}
@@ -0,0 +1,162 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package confdb_test
+
+import (
+	"github.com/snapcore/snapd/confdb"
+	. "gopkg.in/check.v1"
+)
+
+type migrationSuite struct{}
+
+var _ = Suite(&migrationSuite{})
+
+func (*migrationSuite) TestParseMigrations(c *C) {
+	migrations, err := confdb.ParseMigrations([]byte(`[
+  {"revision": 2, "rules": [{"op": "rename", "from": "wifi.ssid", "to": "wifi.name"}]},
+  {"revision": 4, "rules": [
+    {"op": "move", "from": "wifi.psk", "to": "secrets.wifi"},
+    {"op": "transform", "path": "wifi.channel", "type": "int"}
+  ]}
+]`))
+	c.Assert(err, IsNil)
+	c.Assert(migrations, HasLen, 2)
+
+	c.Check(migrations[0].Revision, Equals, 2)
+	c.Assert(migrations[0].Rules, HasLen, 1)
+	c.Check(migrations[0].Rules[0].Op, Equals, confdb.MigrationRename)
+	c.Check(confdb.JoinAccessors(migrations[0].Rules[0].From), Equals, "wifi.ssid")
+	c.Check(confdb.JoinAccessors(migrations[0].Rules[0].To), Equals, "wifi.name")
+
+	c.Check(migrations[1].Revision, Equals, 4)
+	c.Assert(migrations[1].Rules, HasLen, 2)
+	c.Check(migrations[1].Rules[0].Op, Equals, confdb.MigrationMove)
+	c.Check(confdb.JoinAccessors(migrations[1].Rules[0].To), Equals, "secrets.wifi")
+	c.Check(migrations[1].Rules[1].Op, Equals, confdb.MigrationTransform)
+	c.Check(confdb.JoinAccessors(migrations[1].Rules[1].Path), Equals, "wifi.channel")
+	c.Check(migrations[1].Rules[1].Type, Equals, confdb.Int)
+}
+
+func (*migrationSuite) TestParseMigrationsErrors(c *C) {
+	for _, tc := range []struct {
+		raw string
+		err string
+	}{
+		{`{}`, `cannot parse migrations: .*`},
+		{`[{"revision": 0, "rules": []}]`, `cannot parse migrations: revisions must be positive and strictly increasing but got 0 after 0`},
+		{`[{"revision": 3, "rules": [{"op": "move", "from": "a", "to": "b"}]}, {"revision": 2}]`, `cannot parse migrations: revisions must be positive and strictly increasing but got 2 after 3`},
+		{`[{"revision": 2}]`, `cannot parse migration for revision 2: rules must be a non-empty list`},
+		{`[{"revision": 2, "rules": [{"op": "copy"}]}]`, `cannot parse migration for revision 2: unknown rule op "copy" .*`},
+		{`[{"revision": 2, "rules": [{"op": "move", "from": "a"}]}]`, `cannot parse migration for revision 2: move rule has invalid "to" path: path cannot be empty`},
+		{`[{"revision": 2, "rules": [{"op": "move", "from": "a.{b}", "to": "c"}]}]`, `cannot parse migration for revision 2: move rule has invalid "from" path: invalid subkey "{b}": path only supports literal keys and indexes`},
+		{`[{"revision": 2, "rules": [{"op": "move", "from": "a", "to": "a.b"}]}]`, `cannot parse migration for revision 2: move rule cannot overlap paths "a" and "a.b"`},
+		{`[{"revision": 2, "rules": [{"op": "move", "from": "a", "to": "b", "type": "int"}]}]`, `cannot parse migration for revision 2: move rule can only have "from" and "to" fields`},
+		{`[{"revision": 2, "rules": [{"op": "rename", "from": "a.b", "to": "c.b"}]}]`, `cannot parse migration for revision 2: rename rule cannot change the parent of "a.b" \(use a move rule instead\)`},
+		{`[{"revision": 2, "rules": [{"op": "transform", "path": "a", "type": "map"}]}]`, `cannot parse migration for revision 2: transform rule has unsupported type "map" .*`},
+		{`[{"revision": 2, "rules": [{"op": "transform", "path": "a", "to": "b", "type": "int"}]}]`, `cannot parse migration for revision 2: transform rule can only have "path" and "type" fields`},
+	} {
+		_, err := confdb.ParseMigrations([]byte(tc.raw))
+		c.Check(err, ErrorMatches, tc.err, Commentf("%s", tc.raw))
+	}
+}
+
+func (*migrationSuite) TestValidateMigrations(c *C) {
+	schema, err := confdb.ParseStorageSchema([]byte(`{
+  "schema": {
+    "wifi": {
+      "schema": {
+        "name": "string",
+        "channel": "int",
+        "extra": "any"
+      }
+    }
+  }
+}`))
+	c.Assert(err, IsNil)
+
+	for _, tc := range []struct {
+		raw string
+		err string
+	}{
+		{`[{"revision": 2, "rules": [{"op": "rename", "from": "wifi.ssid", "to": "wifi.name"}]}]`, ""},
+		{`[{"revision": 2, "rules": [{"op": "move", "from": "ssid", "to": "wifi.extra.ssid"}]}]`, ""},
+		{`[{"revision": 2, "rules": [{"op": "transform", "path": "wifi.channel", "type": "int"}]}]`, ""},
+		{`[{"revision": 2, "rules": [{"op": "transform", "path": "wifi.extra", "type": "bool"}]}]`, ""},
+		{`[{"revision": 2, "rules": [{"op": "rename", "from": "wifi.ssid", "to": "wifi.other"}]}]`,
+			`invalid migration for revision 2: cannot rename "wifi.ssid" to "wifi.other": .*`},
+		{`[{"revision": 2, "rules": [{"op": "transform", "path": "wifi.channel", "type": "string"}]}]`,
+			`invalid migration for revision 2: cannot transform "wifi.channel" into string: storage schema does not allow it`},
+	} {
+		migrations, err := confdb.ParseMigrations([]byte(tc.raw))
+		c.Assert(err, IsNil)
+
+		err = confdb.ValidateMigrations(migrations, schema)
+		if tc.err == "" {
+			c.Check(err, IsNil, Commentf("%s", tc.raw))
+		} else {
+			c.Check(err, ErrorMatches, tc.err, Commentf("%s", tc.raw))
+		}
+	}
+}
+
+func (*migrationSuite) TestApplyMigration(c *C) {
+	bag := confdb.NewJSONDatabag()
+	c.Assert(bag.Set(parsePath(c, "wifi.ssid"), "home"), IsNil)
+	c.Assert(bag.Set(parsePath(c, "wifi.psk"), "secret"), IsNil)
+	c.Assert(bag.Set(parsePath(c, "wifi.channel"), "11"), IsNil)
+
+	migrations, err := confdb.ParseMigrations([]byte(`[{"revision": 2, "rules": [
+  {"op": "rename", "from": "wifi.ssid", "to": "wifi.name"},
+  {"op": "move", "from": "wifi.psk", "to": "secrets.wifi"},
+  {"op": "transform", "path": "wifi.channel", "type": "int"},
+  {"op": "move", "from": "wifi.missing", "to": "other"}
+]}]`))
+	c.Assert(err, IsNil)
+
+	c.Assert(migrations[0].Apply(bag), IsNil)
+
+	data, err := bag.Data()
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"secrets":{"wifi":"secret"},"wifi":{"channel":11,"name":"home"}}`)
+}
+
+func (*migrationSuite) TestApplyMigrationErrors(c *C) {
+	for _, tc := range []struct {
+		rule string
+		err  string
+	}{
+		{`{"op": "move", "from": "a", "to": "b"}`, `cannot apply migration for revision 2: cannot move "a" to "b": destination already has a value`},
+		{`{"op": "transform", "path": "a", "type": "int"}`, `cannot apply migration for revision 2: cannot transform "a": cannot convert "foo" to int`},
+		{`{"op": "transform", "path": "c", "type": "int"}`, `cannot apply migration for revision 2: cannot transform "c": cannot convert 1.5 to int without losing precision`},
+		{`{"op": "transform", "path": "d", "type": "string"}`, `cannot apply migration for revision 2: cannot transform "d": cannot convert value of type map\[string\]interface {} to string`},
+	} {
+		bag := confdb.NewJSONDatabag()
+		c.Assert(bag.Set(parsePath(c, "a"), "foo"), IsNil)
+		c.Assert(bag.Set(parsePath(c, "b"), "bar"), IsNil)
+		c.Assert(bag.Set(parsePath(c, "c"), 1.5), IsNil)
+		c.Assert(bag.Set(parsePath(c, "d.e"), "baz"), IsNil)
+
+		migrations, err := confdb.ParseMigrations([]byte(`[{"revision": 2, "rules": [` + tc.rule + `]}]`))
+		c.Assert(err, IsNil)
+
+		err = migrations[0].Apply(bag)
+		c.Check(err, ErrorMatches, tc.err, Commentf("%s", tc.rule))
+	}
+}
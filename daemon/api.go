@@ -54,6 +54,8 @@ var api = []*Command{
 	assertsFindManyCmd,
 	stateChangeCmd,
 	stateChangesCmd,
+	eventsCmd,
+	metricsCmd,
 	createUserCmd,
 	buyCmd,
 	readyToBuyCmd,
@@ -83,14 +85,17 @@ var api = []*Command{
 	systemRecoveryKeysCmd,
 	quotaGroupsCmd,
 	quotaGroupInfoCmd,
+	quotaGroupUsageCmd,
 	confdbCmd,
 	confdbControlCmd,
 	noticesCmd,
 	noticeCmd,
+	noticeSubscriptionsCmd,
 	requestsPromptsCmd,
 	requestsPromptCmd,
 	requestsRulesCmd,
 	requestsRuleCmd,
+	requestsAuditCmd,
 	systemSecurebootCmd,
 	systemVolumesCmd,
 }
@@ -196,6 +201,8 @@ var (
 	snapstateTryPath                        = snapstate.TryPath
 	snapstateStoreUpdateGoal                = snapstate.StoreUpdateGoal
 	snapstateUpdateWithGoal                 = snapstate.UpdateWithGoal
+	snapstatePlanUpdate                     = snapstate.PlanUpdate
+	snapstatePlanAutoRefresh                = snapstate.PlanAutoRefresh
 	snapstateUpdateOne                      = snapstate.UpdateOne
 	snapstateRemove                         = snapstate.Remove
 	snapstateRemoveMany                     = snapstate.RemoveMany
@@ -215,10 +222,14 @@ var (
 	assertstateRestoreValidationSetsTracking = assertstate.RestoreValidationSetsTracking
 	assertstateFetchAllValidationSets        = assertstate.FetchAllValidationSets
 
-	confdbstateGetView             = confdbstate.GetView
-	confdbstateGetTransactionToSet = confdbstate.GetTransactionToSet
-	confdbstateSetViaView          = confdbstate.SetViaView
-	confdbstateLoadConfdbAsync     = confdbstate.LoadConfdbAsync
+	confdbstateGetView                = confdbstate.GetView
+	confdbstateGetTransactionToSet    = confdbstate.GetTransactionToSet
+	confdbstateSetViaView             = confdbstate.SetViaView
+	confdbstateLoadConfdbAsync        = confdbstate.LoadConfdbAsync
+	confdbstateHistory                = confdbstate.History
+	confdbstateGetTransactionToRevert = confdbstate.GetTransactionToRevert
+	confdbstateWatchView              = confdbstate.WatchView
+	confdbstateUnwatchView            = confdbstate.UnwatchView
 
 	devicestateSignConfdbControl = (*devicestate.DeviceManager).SignConfdbControl
 )
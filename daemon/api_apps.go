@@ -118,6 +118,20 @@ func getAppsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 		return InternalError("%v", err)
 	}
 
+	st := c.d.overlord.State()
+	st.Lock()
+	oomKills, err := servicestate.ServiceOOMKills(st)
+	st.Unlock()
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	for i := range clientAppInfos {
+		app := &clientAppInfos[i]
+		if app.IsService() {
+			app.OOMKills = oomKills[app.Snap+"."+app.Name]
+		}
+	}
+
 	return SyncResponse(clientAppInfos)
 }
 
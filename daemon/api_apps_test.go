@@ -365,6 +365,32 @@ func (s *appsSuite) TestGetAppsInfoServices(c *check.C) {
 	c.Check(sort.StringsAreSorted(appNames), check.Equals, true)
 }
 
+func (s *appsSuite) TestGetAppsInfoServicesOOMKills(c *check.C) {
+	r := daemon.MockNewStatusDecorator(func(ctx context.Context, isGlobal bool, uid string) clientutil.StatusDecorator {
+		return s
+	})
+	defer r()
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	st.Set("quota-oom-events", map[string]*servicestate.QuotaOOMEvents{
+		"grp": {Count: 3, Services: map[string]int{"snap-a.svc2": 2}},
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/apps?select=service&names=snap-a", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, check.Equals, 200)
+	svcs := rsp.Result.([]client.AppInfo)
+	c.Assert(svcs, check.HasLen, 2)
+	c.Check(svcs[0].Name, check.Equals, "svc1")
+	c.Check(svcs[0].OOMKills, check.Equals, 0)
+	c.Check(svcs[1].Name, check.Equals, "svc2")
+	c.Check(svcs[1].OOMKills, check.Equals, 2)
+}
+
 func (s *appsSuite) TestGetAppsInfoServicesWithGlobal(c *check.C) {
 	// System services from active snaps
 	svcNames := []string{"snap-a.svc1", "snap-a.svc2"}
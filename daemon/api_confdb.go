@@ -19,6 +19,8 @@
 package daemon
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -28,6 +30,7 @@ import (
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/confdb"
 	"github.com/snapcore/snapd/features"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/confdbstate"
@@ -42,6 +45,8 @@ var (
 		Path:        "/v2/confdb/{account}/{confdb-schema}/{view}",
 		GET:         getView,
 		PUT:         setView,
+		POST:        handleViewAction,
+		Actions:     []string{"revert"},
 		ReadAccess:  authenticatedAccess{Polkit: polkitActionManage},
 		WriteAccess: authenticatedAccess{Polkit: polkitActionManage},
 	}
@@ -64,7 +69,8 @@ func getView(c *Command, r *http.Request, _ *auth.UserState) Response {
 
 	vars := muxVars(r)
 	account, schemaName, viewName := vars["account"], vars["confdb-schema"], vars["view"]
-	keysStr := r.URL.Query().Get("keys")
+	query := r.URL.Query()
+	keysStr := query.Get("keys")
 
 	var keys []string
 	if keysStr != "" {
@@ -76,6 +82,20 @@ func getView(c *Command, r *http.Request, _ *auth.UserState) Response {
 		return toAPIError(err)
 	}
 
+	if _, ok := query["history"]; ok {
+		if len(keys) != 0 {
+			return BadRequest(`cannot use "keys" and "history" together`)
+		}
+		if _, ok := query["watch"]; ok {
+			return BadRequest(`cannot use "history" and "watch" together`)
+		}
+		return getHistory(st, view)
+	}
+
+	if _, ok := query["watch"]; ok {
+		return watchView(c, r, view, keys)
+	}
+
 	chgID, err := confdbstateLoadConfdbAsync(st, view, keys)
 	if err != nil {
 		return toAPIError(err)
@@ -112,6 +132,7 @@ func setView(c *Command, r *http.Request, _ *auth.UserState) Response {
 	if err != nil {
 		return toAPIError(err)
 	}
+	setTransactionAuthor(r, tx)
 
 	err = confdbstateSetViaView(tx, view, values)
 	if err != nil {
@@ -126,6 +147,203 @@ func setView(c *Command, r *http.Request, _ *auth.UserState) Response {
 	return AsyncResponse(nil, changeID)
 }
 
+// setTransactionAuthor records the user making the request as the author of
+// the changes in the transaction, if it can be determined.
+func setTransactionAuthor(r *http.Request, tx *confdbstate.Transaction) {
+	ucred, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return
+	}
+	uid := ucred.Uid
+	tx.SetAuthor(confdbstate.Author{UID: &uid})
+}
+
+func getHistory(st *state.State, view *confdb.View) Response {
+	entries, err := confdbstateHistory(st, view.Schema().Account, view.Schema().Name)
+	if err != nil {
+		return InternalError("cannot get confdb history: %v", err)
+	}
+	entries, err = confdbstate.FilterHistory(view, entries)
+	if err != nil {
+		return InternalError("cannot get confdb history: %v", err)
+	}
+	if entries == nil {
+		entries = []*confdbstate.HistoryEntry{}
+	}
+	return SyncResponse(entries)
+}
+
+// watchView starts watching the view for changes. The watcher is registered
+// right away, so that no changes are missed between the request and the start
+// of the stream, and is removed once the stream ends. It must be called with
+// the state locked.
+func watchView(c *Command, r *http.Request, view *confdb.View, keys []string) Response {
+	st := c.d.state
+	wr := &confdbWatchSeqResponse{
+		st:       st,
+		ctx:      c.d.tomb.Context(r.Context()),
+		changes:  make(chan *confdbstate.ViewChange, eventsBufferSize),
+		overflow: make(chan struct{}),
+	}
+
+	var err error
+	wr.watchID, err = confdbstateWatchView(st, view, keys, wr.push)
+	if err != nil {
+		return toAPIError(err)
+	}
+	return wr
+}
+
+// confdbWatchSeqResponse streams the changes to the data accessible through
+// a view as they are committed, in json-seq format, until the client goes
+// away or the daemon shuts down. If the client does not keep up with the
+// changes, the stream ends with an overflow record instead.
+type confdbWatchSeqResponse struct {
+	st      *state.State
+	ctx     context.Context
+	watchID int
+
+	changes chan *confdbstate.ViewChange
+	// overflow is closed once a change could not be queued
+	overflow chan struct{}
+	// dropped is only accessed with the state lock held
+	dropped int
+}
+
+// confdbWatchOverflow is the last record of a watch stream whose client fell
+// behind, so that it knows that changes were missed and that it must get the
+// values again before watching anew.
+type confdbWatchOverflow struct {
+	Overflow bool `json:"overflow"`
+}
+
+// push is called with the state lock held, so it must not block.
+func (wr *confdbWatchSeqResponse) push(change *confdbstate.ViewChange) {
+	if wr.dropped > 0 {
+		// the stream is ending, don't queue changes that come after a gap
+		wr.dropped++
+		return
+	}
+	select {
+	case wr.changes <- change:
+	default:
+		wr.dropped++
+		close(wr.overflow)
+	}
+}
+
+func (wr *confdbWatchSeqResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		wr.st.Lock()
+		defer wr.st.Unlock()
+		confdbstateUnwatchView(wr.st, wr.watchID)
+		if wr.dropped > 0 {
+			logger.Noticef("dropped %d confdb changes for slow watch client", wr.dropped)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json-seq")
+	w.WriteHeader(200)
+	flusher, hasFlusher := w.(http.Flusher)
+
+	writer := bufio.NewWriter(w)
+	enc := json.NewEncoder(writer)
+	flush := func() error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if hasFlusher {
+			flusher.Flush()
+		}
+		return nil
+	}
+	write := func(record any) error {
+		writer.WriteByte(0x1E) // RS -- see ascii(7), and RFC7464
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+		return flush()
+	}
+	if err := flush(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case change := <-wr.changes:
+			if err := write(change); err != nil {
+				logger.Debugf("cannot stream confdb changes: %v", err)
+				return
+			}
+		case <-wr.overflow:
+			// send the changes queued before the gap, then tell the
+			// client that it missed some
+			for len(wr.changes) > 0 {
+				if err := write(<-wr.changes); err != nil {
+					logger.Debugf("cannot stream confdb changes: %v", err)
+					return
+				}
+			}
+			if err := write(&confdbWatchOverflow{Overflow: true}); err != nil {
+				logger.Debugf("cannot stream confdb changes: %v", err)
+			}
+			return
+		case <-wr.ctx.Done():
+			return
+		}
+	}
+}
+
+type viewAction struct {
+	Action      string `json:"action"`
+	Transaction int    `json:"transaction"`
+}
+
+func handleViewAction(c *Command, r *http.Request, _ *auth.UserState) Response {
+	st := c.d.state
+	st.Lock()
+	defer st.Unlock()
+
+	if err := validateFeatureFlag(st, features.Confdb); err != nil {
+		return err
+	}
+
+	var a viewAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&a); err != nil {
+		return BadRequest("cannot decode request body: %v", err)
+	}
+
+	switch a.Action {
+	case "revert":
+		if a.Transaction <= 0 {
+			return BadRequest("cannot revert confdb: invalid transaction %d", a.Transaction)
+		}
+	default:
+		return BadRequest("unknown action %q", a.Action)
+	}
+
+	vars := muxVars(r)
+	account, schemaName, viewName := vars["account"], vars["confdb-schema"], vars["view"]
+	view, err := confdbstateGetView(st, account, schemaName, viewName)
+	if err != nil {
+		return toAPIError(err)
+	}
+
+	tx, commitTxFunc, err := confdbstateGetTransactionToRevert(st, view, a.Transaction)
+	if err != nil {
+		return toAPIError(err)
+	}
+	setTransactionAuthor(r, tx)
+
+	changeID, _, err := commitTxFunc()
+	if err != nil {
+		return toAPIError(err)
+	}
+
+	return AsyncResponse(nil, changeID)
+}
+
 func toAPIError(err error) *apiError {
 	switch {
 	case errors.Is(err, &asserts.NotFoundError{}):
@@ -151,7 +369,7 @@ func toAPIError(err error) *apiError {
 			Kind:    client.ErrorKindConfigNoSuchOption,
 			Value:   err,
 		}
-	case errors.Is(err, &confdb.BadRequestError{}):
+	case errors.Is(err, &confdb.BadRequestError{}), errors.Is(err, &confdbstate.RevertError{}):
 		return BadRequest(err.Error())
 
 	default:
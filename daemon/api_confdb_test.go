@@ -20,10 +20,12 @@ package daemon_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"time"
 
@@ -45,6 +47,7 @@ import (
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
 )
 
 type confdbSuite struct {
@@ -790,3 +793,318 @@ func (s *confdbControlSuite) TestConfdbControlActionInvalidRequest(c *C) {
 		c.Check(rspe.Message, Equals, tc.errMsg)
 	}
 }
+
+func (s *confdbSuite) TestGetHistory(c *C) {
+	s.setFeatureFlag(c)
+
+	restore := daemon.MockConfdbstateGetView(func(_ *state.State, acc, confdbSchema, viewName string) (*confdb.View, error) {
+		return s.schema.View(viewName), nil
+	})
+	defer restore()
+
+	uid := uint32(1000)
+	ts := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []*confdbstate.HistoryEntry{
+		{
+			ID:      1,
+			Time:    ts,
+			View:    "wifi-setup",
+			Author:  confdbstate.Author{UID: &uid},
+			Changes: []confdbstate.PathChange{{Path: "wifi.ssid", New: "foo"}},
+		},
+		{
+			ID:      2,
+			Time:    ts,
+			View:    "other-view",
+			Author:  confdbstate.Author{Snap: "some-snap"},
+			Changes: []confdbstate.PathChange{{Path: "wifi.psk", New: "secret"}},
+		},
+		{
+			ID:     3,
+			Time:   ts,
+			View:   "other-view",
+			Author: confdbstate.Author{Snap: "some-snap"},
+			Changes: []confdbstate.PathChange{
+				{Path: "wifi.psk", Old: "secret", New: "other"},
+				{Path: "wifi.ssid", Old: "foo", New: "bar"},
+			},
+		},
+	}
+	restore = daemon.MockConfdbstateHistory(func(_ *state.State, account, confdbSchema string) ([]*confdbstate.HistoryEntry, error) {
+		c.Check(account, Equals, "system")
+		c.Check(confdbSchema, Equals, "network")
+		return entries, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/confdb/system/network/wifi-setup?history", nil)
+	c.Assert(err, IsNil)
+
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, Equals, 200)
+	// changes to paths which can't be read through the view are left out
+	c.Check(rsp.Result, DeepEquals, []*confdbstate.HistoryEntry{
+		entries[0],
+		{
+			ID:      3,
+			Time:    ts,
+			View:    "other-view",
+			Author:  confdbstate.Author{Snap: "some-snap"},
+			Changes: []confdbstate.PathChange{{Path: "wifi.ssid", Old: "foo", New: "bar"}},
+		},
+	})
+
+	// an empty history is returned as an empty list
+	entries = nil
+	rsp = s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Result, DeepEquals, []*confdbstate.HistoryEntry{})
+
+	req, err = http.NewRequest("GET", "/v2/confdb/system/network/wifi-setup?history&keys=ssid", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Message, Equals, `cannot use "keys" and "history" together`)
+}
+
+// flushSignalRecorder signals each time the response is flushed.
+type flushSignalRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan struct{}
+}
+
+func (r *flushSignalRecorder) Flush() {
+	r.ResponseRecorder.Flush()
+	r.flushed <- struct{}{}
+}
+
+func (s *confdbSuite) TestWatchView(c *C) {
+	s.setFeatureFlag(c)
+
+	restore := daemon.MockConfdbstateGetView(func(_ *state.State, acc, confdbSchema, viewName string) (*confdb.View, error) {
+		return s.schema.View(viewName), nil
+	})
+	defer restore()
+
+	var handler func(*confdbstate.ViewChange)
+	restore = daemon.MockConfdbstateWatchView(func(_ *state.State, view *confdb.View, requests []string, f func(*confdbstate.ViewChange)) (int, error) {
+		c.Check(view.Name, Equals, "wifi-setup")
+		c.Check(requests, DeepEquals, []string{"ssid"})
+		handler = f
+		return 42, nil
+	})
+	defer restore()
+
+	var unwatched []int
+	restore = daemon.MockConfdbstateUnwatchView(func(_ *state.State, id int) {
+		unwatched = append(unwatched, id)
+	})
+	defer restore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", "/v2/confdb/system/network/wifi-setup?watch&keys=ssid", nil)
+	c.Assert(err, IsNil)
+
+	rsp := s.req(c, req, nil, actionIsExpected)
+	c.Assert(handler, NotNil)
+
+	rec := &flushSignalRecorder{
+		ResponseRecorder: httptest.NewRecorder(),
+		flushed:          make(chan struct{}, 3),
+	}
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		rsp.ServeHTTP(rec, req)
+	}()
+
+	uid := uint32(1000)
+	ts := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.st.Lock()
+	handler(&confdbstate.ViewChange{
+		Time:    ts,
+		Author:  confdbstate.Author{UID: &uid},
+		Changes: []confdbstate.PathChange{{Path: "wifi.ssid", New: "foo"}},
+		Values:  map[string]any{"ssid": "foo"},
+	})
+	handler(&confdbstate.ViewChange{
+		Time:    ts,
+		Author:  confdbstate.Author{Snap: "some-snap"},
+		Changes: []confdbstate.PathChange{{Path: "wifi.ssid", Old: "foo"}},
+	})
+	s.st.Unlock()
+
+	// once the header and both changes are flushed, the client goes away
+	for i := 0; i < 3; i++ {
+		select {
+		case <-rec.flushed:
+		case <-time.After(testutil.HostScaledTimeout(5 * time.Second)):
+			c.Fatal("watch stream did not flush")
+		}
+	}
+	cancel()
+	select {
+	case <-finished:
+	case <-time.After(testutil.HostScaledTimeout(5 * time.Second)):
+		c.Fatal("watch stream did not end")
+	}
+
+	c.Check(rec.Code, Equals, 200)
+	c.Check(rec.Header().Get("Content-Type"), Equals, "application/json-seq")
+	c.Check(rec.Body.String(), Equals, "\x1e"+`{"time":"2025-03-01T12:00:00Z","author":{"uid":1000},"changes":[{"path":"wifi.ssid","new":"foo"}],"values":{"ssid":"foo"}}`+"\n"+
+		"\x1e"+`{"time":"2025-03-01T12:00:00Z","author":{"snap":"some-snap"},"changes":[{"path":"wifi.ssid","old":"foo"}]}`+"\n")
+	c.Check(unwatched, DeepEquals, []int{42})
+}
+
+func (s *confdbSuite) TestWatchViewOverflow(c *C) {
+	s.setFeatureFlag(c)
+	restore := daemon.MockEventsBufferSize(2)
+	defer restore()
+
+	restore = daemon.MockConfdbstateGetView(func(_ *state.State, acc, confdbSchema, viewName string) (*confdb.View, error) {
+		return s.schema.View(viewName), nil
+	})
+	defer restore()
+
+	var handler func(*confdbstate.ViewChange)
+	restore = daemon.MockConfdbstateWatchView(func(_ *state.State, view *confdb.View, requests []string, f func(*confdbstate.ViewChange)) (int, error) {
+		handler = f
+		return 42, nil
+	})
+	defer restore()
+
+	var unwatched []int
+	restore = daemon.MockConfdbstateUnwatchView(func(_ *state.State, id int) {
+		unwatched = append(unwatched, id)
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/confdb/system/network/wifi-setup?watch", nil)
+	c.Assert(err, IsNil)
+	rsp := s.req(c, req, nil, actionIsExpected)
+	c.Assert(handler, NotNil)
+
+	// the client does not read anything until after the changes are
+	// committed, so the last two do not fit in the buffer
+	ts := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.st.Lock()
+	for _, ssid := range []string{"foo", "bar", "baz", "qux"} {
+		handler(&confdbstate.ViewChange{
+			Time:    ts,
+			Author:  confdbstate.Author{Snap: "some-snap"},
+			Changes: []confdbstate.PathChange{{Path: "wifi.ssid", New: ssid}},
+		})
+	}
+	s.st.Unlock()
+
+	rec := httptest.NewRecorder()
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		rsp.ServeHTTP(rec, req)
+	}()
+	// the stream ends by itself
+	select {
+	case <-finished:
+	case <-time.After(testutil.HostScaledTimeout(5 * time.Second)):
+		c.Fatal("watch stream did not end")
+	}
+
+	c.Check(rec.Code, Equals, 200)
+	c.Check(rec.Body.String(), Equals, "\x1e"+`{"time":"2025-03-01T12:00:00Z","author":{"snap":"some-snap"},"changes":[{"path":"wifi.ssid","new":"foo"}]}`+"\n"+
+		"\x1e"+`{"time":"2025-03-01T12:00:00Z","author":{"snap":"some-snap"},"changes":[{"path":"wifi.ssid","new":"bar"}]}`+"\n"+
+		"\x1e"+`{"overflow":true}`+"\n")
+	c.Check(unwatched, DeepEquals, []int{42})
+}
+
+func (s *confdbSuite) TestWatchViewErrors(c *C) {
+	s.setFeatureFlag(c)
+
+	restore := daemon.MockConfdbstateGetView(func(_ *state.State, acc, confdbSchema, viewName string) (*confdb.View, error) {
+		return s.schema.View(viewName), nil
+	})
+	defer restore()
+
+	restore = daemon.MockConfdbstateWatchView(func(_ *state.State, view *confdb.View, requests []string, f func(*confdbstate.ViewChange)) (int, error) {
+		return 0, confdb.NewNoMatchError(view, "read", requests)
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/confdb/system/network/wifi-setup?watch&keys=foo", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Kind, Equals, client.ErrorKindOptionNotAvailable)
+
+	req, err = http.NewRequest("GET", "/v2/confdb/system/network/wifi-setup?watch&history", nil)
+	c.Assert(err, IsNil)
+	rspe = s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Message, Equals, `cannot use "history" and "watch" together`)
+}
+
+func (s *confdbSuite) TestRevert(c *C) {
+	s.setFeatureFlag(c)
+
+	restore := daemon.MockConfdbstateGetView(func(_ *state.State, acc, confdbSchema, viewName string) (*confdb.View, error) {
+		return s.schema.View(viewName), nil
+	})
+	defer restore()
+
+	s.st.Lock()
+	tx, err := confdbstate.NewTransaction(s.st, "system", "network")
+	s.st.Unlock()
+	c.Assert(err, IsNil)
+
+	restore = daemon.MockConfdbstateGetTransactionToRevert(func(_ *state.State, view *confdb.View, id int) (*confdbstate.Transaction, confdbstate.CommitTxFunc, error) {
+		c.Check(view.Name, Equals, "wifi-setup")
+		c.Check(id, Equals, 3)
+		return tx, func() (string, <-chan struct{}, error) { return "123", nil, nil }, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("POST", "/v2/confdb/system/network/wifi-setup", strings.NewReader(`{"action": "revert", "transaction": 3}`))
+	c.Assert(err, IsNil)
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+
+	rspe := s.asyncReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 202)
+	c.Check(rspe.Change, Equals, "123")
+
+	// the requesting user is recorded as the author
+	uid := uint32(1000)
+	c.Check(tx.Author(), DeepEquals, confdbstate.Author{UID: &uid})
+}
+
+func (s *confdbSuite) TestRevertErrors(c *C) {
+	s.setFeatureFlag(c)
+
+	restore := daemon.MockConfdbstateGetView(func(_ *state.State, acc, confdbSchema, viewName string) (*confdb.View, error) {
+		return s.schema.View(viewName), nil
+	})
+	defer restore()
+
+	restore = daemon.MockConfdbstateGetTransactionToRevert(func(_ *state.State, view *confdb.View, id int) (*confdbstate.Transaction, confdbstate.CommitTxFunc, error) {
+		return nil, nil, &confdbstate.RevertError{}
+	})
+	defer restore()
+
+	for _, tc := range []struct {
+		body   string
+		errMsg string
+	}{
+		{`{`, "cannot decode request body: unexpected EOF"},
+		{`{"action": "foo"}`, `unknown action "foo"`},
+		{`{"action": "revert"}`, "cannot revert confdb: invalid transaction 0"},
+		{`{"action": "revert", "transaction": 2}`, ""},
+	} {
+		req, err := http.NewRequest("POST", "/v2/confdb/system/network/wifi-setup", strings.NewReader(tc.body))
+		c.Assert(err, IsNil)
+		req.Header.Set("Content-Type", "application/json")
+
+		rspe := s.errorReq(c, req, nil, actionExpectedBool(!strings.Contains(tc.errMsg, "unknown action")))
+		c.Check(rspe.Status, Equals, 400, Commentf("%s", tc.body))
+		c.Check(rspe.Message, Equals, tc.errMsg, Commentf("%s", tc.body))
+	}
+}
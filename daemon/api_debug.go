@@ -35,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/overlord/swfeats"
+	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/timings"
 )
 
@@ -98,6 +99,29 @@ func checkConnectivity(st *state.State) Response {
 	return SyncResponse(status)
 }
 
+// downloadCacheStats is implemented by stores which cache downloads
+type downloadCacheStats interface {
+	CacheStats() (*store.CacheStats, error)
+}
+
+func getDownloadCacheStats(st *state.State) Response {
+	theStore, ok := snapstate.Store(st, nil).(downloadCacheStats)
+	if !ok {
+		return BadRequest("download cache statistics are not available")
+	}
+	st.Unlock()
+	defer st.Lock()
+	stats, err := theStore.CacheStats()
+	if err != nil {
+		return InternalError("cannot get download cache statistics: %v", err)
+	}
+	if stats == nil {
+		// downloads are not cached
+		stats = &store.CacheStats{}
+	}
+	return SyncResponse(stats)
+}
+
 type changeTimings struct {
 	Status         string                `json:"status,omitempty"`
 	Kind           string                `json:"kind,omitempty"`
@@ -393,6 +417,8 @@ func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		return getBaseDeclaration(st)
 	case "connectivity":
 		return checkConnectivity(st)
+	case "download-cache":
+		return getDownloadCacheStats(st)
 	case "model":
 		model, err := c.d.overlord.DeviceManager().Model()
 		if err != nil {
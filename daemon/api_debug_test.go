@@ -31,8 +31,11 @@ import (
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/storetest"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timings"
 )
@@ -100,6 +103,77 @@ func (s *postDebugSuite) TestDebugConnectivityUnhappy(c *check.C) {
 	})
 }
 
+type cacheStatsStore struct {
+	storetest.Store
+
+	stats *store.CacheStats
+	err   error
+}
+
+func (s *cacheStatsStore) CacheStats() (*store.CacheStats, error) {
+	return s.stats, s.err
+}
+
+func (s *postDebugSuite) TestDebugDownloadCache(c *check.C) {
+	d := s.daemon(c)
+
+	st := d.Overlord().State()
+	st.Lock()
+	snapstate.ReplaceStore(st, &cacheStatsStore{stats: &store.CacheStats{
+		Hits:          3,
+		SecondaryHits: 1,
+		Misses:        2,
+		BytesSaved:    4096,
+		Items:         2,
+		Size:          2048,
+	}})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=download-cache", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Result, check.DeepEquals, &store.CacheStats{
+		Hits:          3,
+		SecondaryHits: 1,
+		Misses:        2,
+		BytesSaved:    4096,
+		Items:         2,
+		Size:          2048,
+	})
+}
+
+func (s *postDebugSuite) TestDebugDownloadCacheDisabled(c *check.C) {
+	d := s.daemon(c)
+
+	st := d.Overlord().State()
+	st.Lock()
+	snapstate.ReplaceStore(st, &cacheStatsStore{})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=download-cache", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Result, check.DeepEquals, &store.CacheStats{})
+}
+
+func (s *postDebugSuite) TestDebugDownloadCacheError(c *check.C) {
+	d := s.daemon(c)
+
+	st := d.Overlord().State()
+	st.Lock()
+	snapstate.ReplaceStore(st, &cacheStatsStore{err: errors.New("boom")})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=download-cache", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, check.Equals, 500)
+	c.Check(rsp.Message, check.Equals, "cannot get download cache statistics: boom")
+}
+
 func (s *postDebugSuite) TestGetDebugBaseDeclaration(c *check.C) {
 	_ = s.daemon(c)
 
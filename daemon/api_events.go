@@ -0,0 +1,365 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/notices"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var eventsCmd = &Command{
+	Path:       "/v2/events",
+	GET:        getEvents,
+	ReadAccess: interfaceOpenAccess{Interfaces: []string{"snap-refresh-observe"}},
+}
+
+const (
+	changeStatusEvent = "change-status"
+	taskStatusEvent   = "task-status"
+	taskProgressEvent = "task-progress"
+	noticeEvent       = "notice"
+)
+
+var allEventTypes = []string{changeStatusEvent, taskStatusEvent, taskProgressEvent, noticeEvent}
+
+// overflowEvent is always the last event of a stream ended because events had
+// to be dropped for a slow client, so that the client knows it missed some.
+const overflowEvent = "overflow"
+
+// eventsBufferSize is the number of events which may be queued for a single
+// client before further events are dropped. Event producers run with the
+// state lock held, so they must never block on slow clients.
+var eventsBufferSize = 256
+
+// event is the wire representation of a streamed event, see client.Event.
+type event struct {
+	Type      string            `json:"type"`
+	Time      time.Time         `json:"time"`
+	ChangeID  string            `json:"change-id,omitempty"`
+	TaskID    string            `json:"task-id,omitempty"`
+	Kind      string            `json:"kind,omitempty"`
+	Summary   string            `json:"summary,omitempty"`
+	OldStatus string            `json:"old-status,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	Ready     bool              `json:"ready,omitempty"`
+	Progress  *taskInfoProgress `json:"progress,omitempty"`
+	Notice    *state.Notice     `json:"notice,omitempty"`
+}
+
+func getEvents(c *Command, r *http.Request, user *auth.UserState) Response {
+	query := r.URL.Query()
+
+	types := strutil.MultiCommaSeparatedList(query["types"])
+	for _, typ := range types {
+		if !strutil.ListContains(allEventTypes, typ) {
+			return BadRequest("invalid event type %q", typ)
+		}
+	}
+	if len(types) == 0 {
+		types = allEventTypes
+	}
+
+	changeID := query.Get("change-id")
+	if changeID != "" {
+		st := c.d.overlord.State()
+		st.Lock()
+		chg := st.Change(changeID)
+		st.Unlock()
+		if chg == nil {
+			return NotFound("cannot find change with id %q", changeID)
+		}
+	}
+
+	var noticeFilter *state.NoticeFilter
+	if strutil.ListContains(types, noticeEvent) {
+		requestUID, err := uidFromRequest(r)
+		if err != nil {
+			return Forbidden("cannot determine UID of request, so cannot stream notices")
+		}
+		noticeFilter = &state.NoticeFilter{
+			UserID: &requestUID,
+			// Only stream notices which occur from now on.
+			After: time.Now(),
+		}
+		if changeID != "" {
+			noticeFilter.Types = []state.NoticeType{state.ChangeUpdateNotice}
+			noticeFilter.Keys = []string{changeID}
+		} else {
+			noticeTypes, err := sanitizeNoticeTypesFilter(nil, r)
+			if err != nil {
+				return Forbidden("snap cannot access any notice type")
+			}
+			noticeFilter.Types = noticeTypes
+		}
+		if !noticeTypesViewableBySnap(noticeFilter.Types, r) {
+			return Forbidden("snap cannot access specified notice types")
+		}
+	}
+
+	return &eventsSeqResponse{
+		st:           c.d.overlord.State(),
+		noticeMgr:    c.d.overlord.NoticeManager(),
+		ctx:          c.d.tomb.Context(r.Context()),
+		types:        types,
+		changeID:     changeID,
+		noticeFilter: noticeFilter,
+	}
+}
+
+// eventsSeqResponse streams change, task and notice events as they happen, in
+// json-seq format, until the client goes away, the daemon shuts down or, if
+// a change ID was given, the change is ready. If the client does not keep up
+// with the events, the stream ends with an overflow event instead.
+type eventsSeqResponse struct {
+	st        *state.State
+	noticeMgr *notices.NoticeManager
+	ctx       context.Context
+
+	types        []string
+	changeID     string
+	noticeFilter *state.NoticeFilter
+}
+
+func (er *eventsSeqResponse) wants(typ string) bool {
+	return strutil.ListContains(er.types, typ)
+}
+
+func (er *eventsSeqResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(er.ctx)
+	defer cancel()
+
+	events := make(chan *event, eventsBufferSize)
+	// done is closed once the watched change is ready
+	done := make(chan struct{})
+	// overflow is closed once an event could not be queued
+	overflow := make(chan struct{})
+	dropped := 0
+
+	// push is only called with the state lock held
+	push := func(ev *event) {
+		if dropped > 0 {
+			// the stream is ending, don't queue events that come
+			// after a gap
+			dropped++
+			return
+		}
+		select {
+		case events <- ev:
+		default:
+			dropped++
+			close(overflow)
+		}
+	}
+
+	st := er.st
+	st.Lock()
+	if er.changeID != "" {
+		if chg := st.Change(er.changeID); chg == nil || chg.IsReady() {
+			close(done)
+		}
+	}
+	changeHandlerID := st.AddChangeStatusChangedHandler(func(chg *state.Change, old, new state.Status) {
+		if er.changeID != "" && chg.ID() != er.changeID {
+			return
+		}
+		if er.wants(changeStatusEvent) {
+			push(&event{
+				Type:      changeStatusEvent,
+				Time:      time.Now(),
+				ChangeID:  chg.ID(),
+				Kind:      chg.Kind(),
+				Summary:   chg.Summary(),
+				OldStatus: old.String(),
+				Status:    new.String(),
+				Ready:     new.Ready(),
+			})
+		}
+		if er.changeID != "" && new.Ready() {
+			select {
+			case <-done:
+			default:
+				close(done)
+			}
+		}
+	})
+	taskHandlerID := st.AddTaskStatusChangedHandler(func(t *state.Task, old, new state.Status) (remove bool) {
+		if !er.wants(taskStatusEvent) {
+			return false
+		}
+		ev := er.taskEvent(taskStatusEvent, t)
+		if ev == nil {
+			return false
+		}
+		ev.OldStatus = old.String()
+		ev.Status = new.String()
+		push(ev)
+		return false
+	})
+	progressHandlerID := st.AddTaskProgressChangedHandler(func(t *state.Task, label string, done, total int) {
+		if !er.wants(taskProgressEvent) {
+			return
+		}
+		ev := er.taskEvent(taskProgressEvent, t)
+		if ev == nil {
+			return
+		}
+		ev.Status = t.Status().String()
+		ev.Progress = &taskInfoProgress{Label: label, Done: done, Total: total}
+		push(ev)
+	})
+	st.Unlock()
+
+	defer func() {
+		st.Lock()
+		defer st.Unlock()
+		st.RemoveChangeStatusChangedHandler(changeHandlerID)
+		st.RemoveTaskStatusChangedHandler(taskHandlerID)
+		st.RemoveTaskProgressChangedHandler(progressHandlerID)
+	}()
+
+	noticeEvents := make(chan *event)
+	if er.noticeFilter != nil {
+		go er.streamNotices(ctx, noticeEvents)
+	}
+
+	w.Header().Set("Content-Type", "application/json-seq")
+	w.WriteHeader(200)
+	flusher, hasFlusher := w.(http.Flusher)
+	flush := func(writer *bufio.Writer) error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if hasFlusher {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	writer := bufio.NewWriter(w)
+	enc := json.NewEncoder(writer)
+	write := func(ev *event) error {
+		writer.WriteByte(0x1E) // RS -- see ascii(7), and RFC7464
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+		return flush(writer)
+	}
+	if err := flush(writer); err != nil {
+		return
+	}
+
+	for {
+		var ev *event
+		select {
+		case ev = <-events:
+		case ev = <-noticeEvents:
+		case <-done:
+			// Send whatever is still queued before ending the stream.
+			for len(events) > 0 {
+				if err := write(<-events); err != nil {
+					break
+				}
+			}
+			er.logDropped(&dropped)
+			return
+		case <-overflow:
+			// Send the events queued before the gap, then tell the
+			// client that it missed some.
+			for len(events) > 0 {
+				if err := write(<-events); err != nil {
+					logger.Debugf("cannot stream events: %v", err)
+					return
+				}
+			}
+			if err := write(&event{Type: overflowEvent, Time: time.Now()}); err != nil {
+				logger.Debugf("cannot stream events: %v", err)
+			}
+			er.logDropped(&dropped)
+			return
+		case <-ctx.Done():
+			er.logDropped(&dropped)
+			return
+		}
+		if err := write(ev); err != nil {
+			logger.Debugf("cannot stream events: %v", err)
+			return
+		}
+	}
+}
+
+func (er *eventsSeqResponse) logDropped(dropped *int) {
+	// dropped is only updated with the state lock held
+	er.st.Lock()
+	n := *dropped
+	er.st.Unlock()
+	if n > 0 {
+		logger.Noticef("dropped %d events for slow events client", n)
+	}
+}
+
+// taskEvent returns a new event of the given type for the given task, or nil
+// if the task is not relevant for the stream. It must be called with the
+// state lock held.
+func (er *eventsSeqResponse) taskEvent(typ string, t *state.Task) *event {
+	var changeID string
+	if chg := t.Change(); chg != nil {
+		changeID = chg.ID()
+	}
+	if er.changeID != "" && changeID != er.changeID {
+		return nil
+	}
+	return &event{
+		Type:     typ,
+		Time:     time.Now(),
+		ChangeID: changeID,
+		TaskID:   t.ID(),
+		Kind:     t.Kind(),
+		Summary:  t.Summary(),
+	}
+}
+
+// streamNotices sends an event for each notice matching the notice filter
+// until the context is done.
+func (er *eventsSeqResponse) streamNotices(ctx context.Context, out chan<- *event) {
+	filter := *er.noticeFilter
+	for {
+		found, err := er.noticeMgr.WaitNotices(ctx, &filter)
+		if err != nil {
+			return
+		}
+		for _, n := range found {
+			select {
+			case out <- &event{Type: noticeEvent, Time: n.LastRepeated(), Notice: n}:
+			case <-ctx.Done():
+				return
+			}
+			filter.After = n.LastRepeated()
+		}
+	}
+}
@@ -0,0 +1,372 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
+)
+
+var _ = check.Suite(&eventsSuite{})
+
+type eventsSuite struct {
+	apiBaseSuite
+}
+
+func (s *eventsSuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.expectReadAccess(daemon.InterfaceOpenAccess{Interfaces: []string{"snap-refresh-observe"}})
+}
+
+// headerSignalRecorder signals when the response header was written, at
+// which point the events stream is ready to receive events.
+type headerSignalRecorder struct {
+	*httptest.ResponseRecorder
+	headerWritten chan struct{}
+}
+
+func (r *headerSignalRecorder) WriteHeader(code int) {
+	r.ResponseRecorder.WriteHeader(code)
+	close(r.headerWritten)
+}
+
+// serveStream serves the events response in the background and returns a
+// function that waits for it to end and returns the decoded events.
+func (s *eventsSuite) serveStream(c *check.C, req *http.Request) (wait func() []map[string]any) {
+	rsp := s.req(c, req, nil, actionIsExpected)
+	rec := &headerSignalRecorder{
+		ResponseRecorder: httptest.NewRecorder(),
+		headerWritten:    make(chan struct{}),
+	}
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		rsp.ServeHTTP(rec, req)
+	}()
+	select {
+	case <-rec.headerWritten:
+	case <-time.After(testutil.HostScaledTimeout(5 * time.Second)):
+		c.Fatal("events stream did not start")
+	}
+
+	return func() []map[string]any {
+		select {
+		case <-finished:
+		case <-time.After(testutil.HostScaledTimeout(5 * time.Second)):
+			c.Fatal("events stream did not end")
+		}
+		c.Check(rec.Code, check.Equals, 200)
+		c.Check(rec.Header().Get("Content-Type"), check.Equals, "application/json-seq")
+
+		var events []map[string]any
+		for _, record := range bytes.Split(rec.Body.Bytes(), []byte{0x1E}) {
+			if len(record) == 0 {
+				continue
+			}
+			var ev map[string]any
+			c.Assert(json.Unmarshal(record, &ev), check.IsNil)
+			c.Check(ev["time"], check.NotNil)
+			delete(ev, "time")
+			events = append(events, ev)
+		}
+		return events
+	}
+}
+
+func (s *eventsSuite) TestEventsForChange(c *check.C) {
+	s.daemon(c)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	chg := st.NewChange("install-snap", "Install foo")
+	t1 := st.NewTask("download", "Download foo")
+	chg.AddTask(t1)
+	other := st.NewChange("remove-snap", "Remove bar")
+	t2 := st.NewTask("unlink", "Unlink bar")
+	other.AddTask(t2)
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/events?types=change-status,task-status,task-progress&change-id="+chg.ID(), nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1000;socket=%s;", dirs.SnapdSocket)
+	wait := s.serveStream(c, req)
+
+	st.Lock()
+	t2.SetStatus(state.DoingStatus)
+	t1.SetStatus(state.DoingStatus)
+	t1.SetProgress("foo", 1, 2)
+	t1.SetStatus(state.DoneStatus)
+	st.Unlock()
+
+	events := wait()
+	c.Check(events, check.DeepEquals, []map[string]any{{
+		"type":       "change-status",
+		"change-id":  chg.ID(),
+		"kind":       "install-snap",
+		"summary":    "Install foo",
+		"old-status": "Default",
+		"status":     "Doing",
+	}, {
+		"type":       "task-status",
+		"change-id":  chg.ID(),
+		"task-id":    t1.ID(),
+		"kind":       "download",
+		"summary":    "Download foo",
+		"old-status": "Default",
+		"status":     "Doing",
+	}, {
+		"type":      "task-progress",
+		"change-id": chg.ID(),
+		"task-id":   t1.ID(),
+		"kind":      "download",
+		"summary":   "Download foo",
+		"status":    "Doing",
+		"progress":  map[string]any{"label": "foo", "done": 1.0, "total": 2.0},
+	}, {
+		"type":       "change-status",
+		"change-id":  chg.ID(),
+		"kind":       "install-snap",
+		"summary":    "Install foo",
+		"old-status": "Doing",
+		"status":     "Done",
+		"ready":      true,
+	}, {
+		"type":       "task-status",
+		"change-id":  chg.ID(),
+		"task-id":    t1.ID(),
+		"kind":       "download",
+		"summary":    "Download foo",
+		"old-status": "Doing",
+		"status":     "Done",
+	}})
+
+	// handlers are gone once the stream ends
+	st.Lock()
+	t2.SetStatus(state.DoneStatus)
+	st.Unlock()
+}
+
+func (s *eventsSuite) TestEventsForReadyChangeEndsImmediately(c *check.C) {
+	s.daemon(c)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	chg := st.NewChange("install-snap", "Install foo")
+	t1 := st.NewTask("download", "Download foo")
+	chg.AddTask(t1)
+	t1.SetStatus(state.DoneStatus)
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/events?change-id="+chg.ID(), nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1000;socket=%s;", dirs.SnapdSocket)
+	events := s.serveStream(c, req)()
+	c.Check(events, check.HasLen, 0)
+}
+
+func (s *eventsSuite) TestEventsTypesAndNotices(c *check.C) {
+	s.daemon(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "/v2/events?types=notice,change-status", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1000;socket=%s;", dirs.SnapdSocket)
+	wait := s.serveStream(c, req)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	chg := st.NewChange("install-snap", "Install foo")
+	t1 := st.NewTask("download", "Download foo")
+	chg.AddTask(t1)
+	// task events are filtered out
+	t1.SetStatus(state.DoingStatus)
+	uid := uint32(1001)
+	_, err = st.AddNotice(&uid, state.WarningNotice, "not for us", nil)
+	c.Assert(err, check.IsNil)
+	_, err = st.AddNotice(nil, state.WarningNotice, "danger", nil)
+	c.Assert(err, check.IsNil)
+	st.Unlock()
+
+	// the notice is delivered asynchronously, so wait for it before
+	// ending the stream
+	noticeMgr := s.d.Overlord().NoticeManager()
+	found, err := noticeMgr.WaitNotices(ctx, &state.NoticeFilter{Keys: []string{"danger"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(found, check.HasLen, 1)
+	time.Sleep(testutil.HostScaledTimeout(50 * time.Millisecond))
+	cancel()
+
+	// change and notice events are sent from different sources, so their
+	// relative order is not defined
+	events := wait()
+	c.Assert(events, check.HasLen, 3)
+	var noticeKeys []string
+	for _, ev := range events {
+		switch ev["type"] {
+		case "change-status":
+			c.Check(ev["change-id"], check.Equals, chg.ID())
+		case "notice":
+			notice, ok := ev["notice"].(map[string]any)
+			c.Assert(ok, check.Equals, true)
+			noticeKeys = append(noticeKeys, notice["key"].(string))
+		default:
+			c.Errorf("unexpected event %v", ev)
+		}
+	}
+	c.Check(noticeKeys, check.DeepEquals, []string{chg.ID(), "danger"})
+}
+
+// blockingRecorder holds off writes to the response until unblocked, like a
+// client that does not read the stream.
+type blockingRecorder struct {
+	*headerSignalRecorder
+	unblock chan struct{}
+}
+
+func (r *blockingRecorder) Write(data []byte) (int, error) {
+	<-r.unblock
+	return r.headerSignalRecorder.Write(data)
+}
+
+func (s *eventsSuite) TestEventsOverflow(c *check.C) {
+	s.daemon(c)
+	restore := daemon.MockEventsBufferSize(2)
+	defer restore()
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	chg := st.NewChange("install-snap", "Install foo")
+	var tasks []*state.Task
+	for i := 0; i < 10; i++ {
+		t := st.NewTask("download", fmt.Sprintf("Download foo %d", i))
+		chg.AddTask(t)
+		tasks = append(tasks, t)
+	}
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/events?types=task-status", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1000;socket=%s;", dirs.SnapdSocket)
+	rsp := s.req(c, req, nil, actionIsExpected)
+	rec := &blockingRecorder{
+		headerSignalRecorder: &headerSignalRecorder{
+			ResponseRecorder: httptest.NewRecorder(),
+			headerWritten:    make(chan struct{}),
+		},
+		unblock: make(chan struct{}),
+	}
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		rsp.ServeHTTP(rec, req)
+	}()
+	select {
+	case <-rec.headerWritten:
+	case <-time.After(testutil.HostScaledTimeout(5 * time.Second)):
+		c.Fatal("events stream did not start")
+	}
+
+	// at most one event is being written and two are queued, so the rest
+	// overflow
+	st.Lock()
+	for _, t := range tasks {
+		t.SetStatus(state.DoingStatus)
+	}
+	st.Unlock()
+	close(rec.unblock)
+
+	// the stream ends by itself
+	select {
+	case <-finished:
+	case <-time.After(testutil.HostScaledTimeout(5 * time.Second)):
+		c.Fatal("events stream did not end")
+	}
+
+	var events []map[string]any
+	for _, record := range bytes.Split(rec.Body.Bytes(), []byte{0x1E}) {
+		if len(record) == 0 {
+			continue
+		}
+		var ev map[string]any
+		c.Assert(json.Unmarshal(record, &ev), check.IsNil)
+		events = append(events, ev)
+	}
+	c.Assert(len(events) >= 3, check.Equals, true)
+	c.Assert(len(events) <= 4, check.Equals, true)
+	// the events sent are the first ones, in order
+	for i, ev := range events[:len(events)-1] {
+		c.Check(ev["task-id"], check.Equals, tasks[i].ID())
+	}
+	c.Check(events[len(events)-1]["type"], check.Equals, "overflow")
+	c.Check(events[len(events)-1]["time"], check.NotNil)
+
+	// handlers are gone once the stream ends
+	st.Lock()
+	tasks[0].SetStatus(state.DoneStatus)
+	st.Unlock()
+}
+
+func (s *eventsSuite) TestEventsInvalidType(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/events?types=foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Message, check.Equals, `invalid event type "foo"`)
+}
+
+func (s *eventsSuite) TestEventsChangeNotFound(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/events?change-id=42", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Message, check.Equals, `cannot find change with id "42"`)
+}
+
+func (s *eventsSuite) TestEventsNoticesForbiddenForSnap(c *check.C) {
+	s.daemon(c)
+
+	restore := daemon.MockNoticeReadInterfaces(map[state.NoticeType][]string{})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/events?types=notice", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1000;socket=%s;iface=snap-refresh-observe;", dirs.SnapSocket)
+	rsp := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, check.Equals, 403)
+}
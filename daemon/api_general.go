@@ -122,15 +122,32 @@ func sysInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	if err != nil {
 		return InternalError("cannot get refresh schedule: %s", err)
 	}
+	refreshWaves, pendingRefreshWaves, err := snapMgr.RefreshWaves()
+	if err != nil {
+		return InternalError("cannot get refresh waves: %s", err)
+	}
+	refreshWindows, err := snapMgr.RefreshWindows()
+	if err != nil {
+		return InternalError("cannot get refresh windows: %s", err)
+	}
 	users, err := auth.Users(st)
 	if err != nil && !errors.Is(err, state.ErrNoState) {
 		return InternalError("cannot get user auth data: %s", err)
 	}
 
 	refreshInfo := client.RefreshInfo{
-		Last: formatRefreshTime(lastRefresh),
-		Hold: formatRefreshTime(refreshHold),
-		Next: formatRefreshTime(nextRefresh),
+		Last:         formatRefreshTime(lastRefresh),
+		Hold:         formatRefreshTime(refreshHold),
+		Next:         formatRefreshTime(nextRefresh),
+		Waves:        refreshWaves,
+		PendingWaves: pendingRefreshWaves,
+	}
+	for _, window := range refreshWindows {
+		refreshInfo.Windows = append(refreshInfo.Windows, client.RefreshWindow{
+			Snap:   window.Snap,
+			Window: window.Window,
+			Next:   formatRefreshTime(window.Next),
+		})
 	}
 	if !legacySchedule {
 		refreshInfo.Timer = refreshScheduleStr
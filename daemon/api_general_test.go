@@ -33,6 +33,7 @@ import (
 
 	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/dirs/dirstest"
@@ -419,6 +420,36 @@ func (s *generalSuite) TestSysInfoIsManaged(c *check.C) {
 	c.Check(rsp.Result.(map[string]any)["managed"], check.Equals, true)
 }
 
+func (s *generalSuite) TestSysInfoRefreshWavesAndWindows(c *check.C) {
+	s.expectSystemInfoReadAccess()
+	d := s.daemon(c)
+
+	st := d.Overlord().State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "refresh.waves", "*;@kernel")
+	tr.Set("core", "refresh.windows.foo", "sun,23:00-01:00")
+	tr.Commit()
+	st.Set("staged-auto-refresh", map[string]any{
+		"waves": [][]string{{"pc-kernel"}},
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/system-info", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	refreshInfo := rsp.Result.(map[string]any)["refresh"].(client.RefreshInfo)
+	c.Check(refreshInfo.Waves, check.Equals, "*;@kernel")
+	c.Check(refreshInfo.PendingWaves, check.DeepEquals, [][]string{{"pc-kernel"}})
+	c.Assert(refreshInfo.Windows, check.HasLen, 1)
+	c.Check(refreshInfo.Windows[0].Snap, check.Equals, "foo")
+	c.Check(refreshInfo.Windows[0].Window, check.Equals, "sun,23:00-01:00")
+	next, err := time.Parse(time.RFC3339, refreshInfo.Windows[0].Next)
+	c.Assert(err, check.IsNil)
+	c.Check(next.Weekday(), check.Equals, time.Sunday)
+}
+
 func (s *generalSuite) TestSysInfoWorksDegraded(c *check.C) {
 	s.expectSystemInfoReadAccess()
 	d := s.daemon(c)
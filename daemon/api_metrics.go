@@ -0,0 +1,58 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/metrics"
+	"github.com/snapcore/snapd/overlord/auth"
+)
+
+var metricsCmd = &Command{
+	Path: "/v2/metrics",
+	GET:  getMetrics,
+	// metrics do not include details about the snaps or users, but they
+	// tell about the activity of the system
+	ReadAccess: interfaceAuthenticatedAccess{Interfaces: []string{"snap-refresh-observe"}},
+}
+
+// metricsContentType is the content type of the Prometheus text exposition
+// format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+func getMetrics(c *Command, r *http.Request, user *auth.UserState) Response {
+	return metricsResponse{metrics.Default}
+}
+
+// A metricsResponse's ServeHTTP method writes the metrics of the registry in
+// the Prometheus text exposition format.
+type metricsResponse struct {
+	reg *metrics.Registry
+}
+
+// ServeHTTP from the Response interface
+func (mr metricsResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	if err := mr.reg.WriteText(w); err != nil {
+		logger.Debugf("cannot write metrics: %v", err)
+	}
+}
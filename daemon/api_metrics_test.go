@@ -0,0 +1,84 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
+)
+
+var _ = check.Suite(&metricsSuite{})
+
+type metricsSuite struct {
+	apiBaseSuite
+}
+
+func (s *metricsSuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.expectReadAccess(daemon.InterfaceAuthenticatedAccess{Interfaces: []string{"snap-refresh-observe"}})
+}
+
+func (s *metricsSuite) TestGetMetrics(c *check.C) {
+	d := s.daemon(c)
+
+	st := d.Overlord().State()
+	runner := d.Overlord().TaskRunner()
+	runner.AddHandler("metrics-test", func(t *state.Task, _ *tomb.Tomb) error {
+		return nil
+	}, nil)
+	// keep the tasks pending
+	runner.AddBlocked(func(t *state.Task, running []*state.Task) bool {
+		return true
+	})
+	st.Lock()
+	chg := st.NewChange("metrics-test-change", "...")
+	t1 := st.NewTask("metrics-test", "...")
+	t2 := st.NewTask("metrics-test", "...")
+	t2.WaitFor(t1)
+	chg.AddTask(t1)
+	chg.AddTask(t2)
+	st.Unlock()
+	c.Assert(runner.Ensure(), check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/metrics", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.req(c, req, nil, actionIsExpected)
+	rec := httptest.NewRecorder()
+	rsp.ServeHTTP(rec, req)
+
+	c.Check(rec.Code, check.Equals, 200)
+	c.Check(rec.Header().Get("Content-Type"), check.Equals, "text/plain; version=0.0.4; charset=utf-8")
+	body := rec.Body.String()
+	c.Check(body, testutil.Contains, "# TYPE snapd_changes_in_flight gauge\n")
+	c.Check(body, testutil.Contains, `snapd_changes_in_flight{kind="metrics-test-change"} 1`+"\n")
+	c.Check(body, testutil.Contains, "snapd_taskrunner_pending_tasks 2\n")
+	c.Check(body, testutil.Contains, "# TYPE snapd_taskrunner_ensure_duration_seconds histogram\n")
+	c.Check(body, testutil.Contains, "# TYPE snapd_ensure_duration_seconds histogram\n")
+	c.Check(body, testutil.Contains, "# TYPE snapd_store_requests_total counter\n")
+	c.Check(body, testutil.Contains, "# TYPE snapd_hook_duration_seconds histogram\n")
+}
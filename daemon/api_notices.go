@@ -22,9 +22,11 @@ import (
 	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/notices"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap/naming"
 	"github.com/snapcore/snapd/strutil"
@@ -53,6 +55,15 @@ var (
 		GET:        getNotice,
 		ReadAccess: interfaceOpenAccess{Interfaces: []string{"snap-refresh-observe", "snap-interfaces-requests-control"}},
 	}
+
+	noticeSubscriptionsCmd = &Command{
+		Path:        "/v2/notice-subscriptions",
+		GET:         getNoticeSubscriptions,
+		POST:        postNoticeSubscriptions,
+		Actions:     []string{"add", "ack", "remove"},
+		ReadAccess:  interfaceOpenAccess{Interfaces: []string{"snap-refresh-observe", "snap-interfaces-requests-control"}},
+		WriteAccess: interfaceOpenAccess{Interfaces: []string{"snap-refresh-observe", "snap-interfaces-requests-control"}},
+	}
 )
 
 // addedNotice is the result of adding a new notice.
@@ -96,28 +107,63 @@ func getNotices(c *Command, r *http.Request, user *auth.UserState) Response {
 		userID = nil
 	}
 
-	types, err := sanitizeNoticeTypesFilter(query["types"], r)
-	if err != nil {
-		// Caller did provide a types filter, but they're all invalid notice types.
-		// Return no notices, rather than the default of all notices.
-		return SyncResponse([]*state.Notice{})
-	}
-	if !noticeTypesViewableBySnap(types, r) {
-		return Forbidden("snap cannot access specified notice types")
-	}
+	var filter *state.NoticeFilter
+	if subName := query.Get("subscription"); subName != "" {
+		for _, param := range []string{"user-id", "users", "types", "keys", "key-globs", "data", "after"} {
+			if len(query[param]) > 0 {
+				return BadRequest(`cannot use %q filter together with "subscription"`, param)
+			}
+		}
+		st := c.d.overlord.State()
+		st.Lock()
+		sub, err := notices.GetSubscription(st, requestUID, subName)
+		st.Unlock()
+		if errors.Is(err, notices.ErrNoSubscription) {
+			return NotFound("cannot find notice subscription %q", subName)
+		}
+		if err != nil {
+			return InternalError("cannot retrieve notice subscription: %v", err)
+		}
+		if !noticeTypesViewableBySnap(sub.Types, r) {
+			return Forbidden("snap cannot access specified notice types")
+		}
+		filter = sub.Filter()
+	} else {
+		types, err := sanitizeNoticeTypesFilter(query["types"], r)
+		if err != nil {
+			// Caller did provide a types filter, but they're all invalid notice types.
+			// Return no notices, rather than the default of all notices.
+			return SyncResponse([]*state.Notice{})
+		}
+		if !noticeTypesViewableBySnap(types, r) {
+			return Forbidden("snap cannot access specified notice types")
+		}
 
-	keys := strutil.MultiCommaSeparatedList(query["keys"])
+		keys := strutil.MultiCommaSeparatedList(query["keys"])
 
-	after, err := parseOptionalTime(query.Get("after"))
-	if err != nil {
-		return BadRequest(`invalid "after" timestamp: %v`, err)
-	}
+		keyGlobs := strutil.MultiCommaSeparatedList(query["key-globs"])
+		if err := state.ValidateKeyGlobs(keyGlobs); err != nil {
+			return BadRequest(`invalid "key-globs" filter: %v`, err)
+		}
+
+		data, err := parseNoticeDataFilter(query["data"])
+		if err != nil {
+			return BadRequest(`invalid "data" filter: %v`, err)
+		}
+
+		after, err := parseOptionalTime(query.Get("after"))
+		if err != nil {
+			return BadRequest(`invalid "after" timestamp: %v`, err)
+		}
 
-	filter := &state.NoticeFilter{
-		UserID: userID,
-		Types:  types,
-		Keys:   keys,
-		After:  after,
+		filter = &state.NoticeFilter{
+			UserID:   userID,
+			Types:    types,
+			Keys:     keys,
+			KeyGlobs: keyGlobs,
+			Data:     data,
+			After:    after,
+		}
 	}
 
 	timeout, err := parseOptionalDuration(query.Get("timeout"))
@@ -159,6 +205,27 @@ func getNotices(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(notices)
 }
 
+// parseNoticeDataFilter parses "key=value" pairs, which may also be given as
+// a comma-separated list, into a data filter.
+func parseNoticeDataFilter(queryData []string) (map[string]string, error) {
+	pairs := strutil.MultiCommaSeparatedList(queryData)
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	data := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf(`expected "key=value", got %q`, pair)
+		}
+		if existing, ok := data[k]; ok && existing != v {
+			return nil, fmt.Errorf("conflicting values for key %q", k)
+		}
+		data[k] = v
+	}
+	return data, nil
+}
+
 // Get the UID of the request. If the UID is not known, return an error.
 func uidFromRequest(r *http.Request) (uint32, error) {
 	cred, err := ucrednetGet(r.RemoteAddr)
@@ -379,3 +446,137 @@ InterfaceTypeLoop:
 	}
 	return true
 }
+
+// noticeSubscriptionInstruction holds the action and parameters for a
+// request to manage notice subscriptions.
+type noticeSubscriptionInstruction struct {
+	Action string `json:"action"`
+	Name   string `json:"name"`
+
+	// Used by the "add" action.
+	Types    []state.NoticeType `json:"types,omitempty"`
+	KeyGlobs []string           `json:"key-globs,omitempty"`
+	Data     map[string]string  `json:"data,omitempty"`
+
+	// Used by the "ack" action.
+	NoticeID string `json:"notice-id,omitempty"`
+}
+
+func getNoticeSubscriptions(c *Command, r *http.Request, user *auth.UserState) Response {
+	requestUID, err := uidFromRequest(r)
+	if err != nil {
+		return Forbidden("cannot determine UID of request, so cannot retrieve notice subscriptions")
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	subs, err := notices.Subscriptions(st, &requestUID)
+	if err != nil {
+		return InternalError("cannot retrieve notice subscriptions: %v", err)
+	}
+	return SyncResponse(subs)
+}
+
+func postNoticeSubscriptions(c *Command, r *http.Request, user *auth.UserState) Response {
+	requestUID, err := uidFromRequest(r)
+	if err != nil {
+		return Forbidden("cannot determine UID of request, so cannot manage notice subscriptions")
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var inst noticeSubscriptionInstruction
+	if err := decoder.Decode(&inst); err != nil {
+		return BadRequest("cannot decode request body into notice subscription instruction: %v", err)
+	}
+	if inst.Name == "" {
+		return BadRequest("notice subscription name must be provided")
+	}
+
+	switch inst.Action {
+	case "add":
+		return addNoticeSubscription(c, r, requestUID, &inst)
+	case "ack":
+		return ackNoticeSubscription(c, r, requestUID, &inst)
+	case "remove":
+		return removeNoticeSubscription(c, requestUID, &inst)
+	default:
+		return BadRequest("invalid action %q", inst.Action)
+	}
+}
+
+func addNoticeSubscription(c *Command, r *http.Request, requestUID uint32, inst *noticeSubscriptionInstruction) Response {
+	if !noticeTypesViewableBySnap(inst.Types, r) {
+		return Forbidden("snap cannot access specified notice types")
+	}
+
+	sub := &notices.Subscription{
+		Name:     inst.Name,
+		UserID:   requestUID,
+		Types:    inst.Types,
+		KeyGlobs: inst.KeyGlobs,
+		Data:     inst.Data,
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if err := notices.AddSubscription(st, sub); err != nil {
+		return BadRequest("%v", err)
+	}
+	sub, err := notices.GetSubscription(st, requestUID, inst.Name)
+	if err != nil {
+		return InternalError("cannot retrieve notice subscription: %v", err)
+	}
+	return SyncResponse(sub)
+}
+
+func ackNoticeSubscription(c *Command, r *http.Request, requestUID uint32, inst *noticeSubscriptionInstruction) Response {
+	if inst.NoticeID == "" {
+		return BadRequest("notice ID must be provided")
+	}
+
+	// The notice manager may need to take the state lock, so look up the
+	// notice before taking it.
+	notice := c.d.overlord.NoticeManager().Notice(inst.NoticeID)
+	if notice == nil {
+		return NotFound("cannot find notice with id %q", inst.NoticeID)
+	}
+	if !noticeViewableByUser(notice, requestUID) || !noticeTypesViewableBySnap([]state.NoticeType{notice.Type()}, r) {
+		return Forbidden("not allowed to access notice with id %q", inst.NoticeID)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	err := notices.AckSubscription(st, requestUID, inst.Name, notice)
+	if errors.Is(err, notices.ErrNoSubscription) {
+		return NotFound("cannot find notice subscription %q", inst.Name)
+	}
+	if err != nil {
+		return InternalError("cannot acknowledge notice: %v", err)
+	}
+	sub, err := notices.GetSubscription(st, requestUID, inst.Name)
+	if err != nil {
+		return InternalError("cannot retrieve notice subscription: %v", err)
+	}
+	return SyncResponse(sub)
+}
+
+func removeNoticeSubscription(c *Command, requestUID uint32, inst *noticeSubscriptionInstruction) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	err := notices.RemoveSubscription(st, requestUID, inst.Name)
+	if errors.Is(err, notices.ErrNoSubscription) {
+		return NotFound("cannot find notice subscription %q", inst.Name)
+	}
+	if err != nil {
+		return InternalError("cannot remove notice subscription: %v", err)
+	}
+	return SyncResponse(nil)
+}
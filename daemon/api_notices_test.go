@@ -30,6 +30,7 @@ import (
 
 	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/notices"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
 	"github.com/snapcore/snapd/overlord/state"
@@ -1216,3 +1217,220 @@ func addNotice(c *C, st *state.State, userID *uint32, noticeType state.NoticeTyp
 	_, err := st.AddNotice(userID, noticeType, key, options)
 	c.Assert(err, IsNil)
 }
+
+func (s *noticesSuite) TestNoticesFilterKeyGlobs(c *C) {
+	s.testNoticesFilter(c, func(after time.Time) url.Values {
+		return url.Values{"key-globs": {"foo*,12?"}}
+	})
+}
+
+func (s *noticesSuite) TestNoticesFilterData(c *C) {
+	s.testNoticesFilter(c, func(after time.Time) url.Values {
+		return url.Values{"data": {"k=v"}}
+	})
+}
+
+func (s *noticesSuite) TestNoticesInvalidKeyGlobs(c *C) {
+	s.testNoticesBadRequest(c, "key-globs=foo[", `invalid "key-globs" filter: invalid key pattern "foo\[".*`)
+}
+
+func (s *noticesSuite) TestNoticesInvalidData(c *C) {
+	s.testNoticesBadRequest(c, "data=foo", `invalid "data" filter: expected "key=value", got "foo"`)
+}
+
+func (s *noticesSuite) TestNoticesInvalidDataEmptyKey(c *C) {
+	s.testNoticesBadRequest(c, "data==bar", `invalid "data" filter: expected "key=value", got "=bar"`)
+}
+
+func (s *noticesSuite) TestNoticesInvalidDataConflict(c *C) {
+	s.testNoticesBadRequest(c, "data=a=b&data=a=c", `invalid "data" filter: conflicting values for key "a"`)
+}
+
+func (s *noticesSuite) TestNoticesSubscriptionWithOtherFilters(c *C) {
+	s.testNoticesBadRequest(c, "subscription=foo&types=warning", `cannot use "types" filter together with "subscription"`)
+}
+
+func (s *noticesSuite) TestNoticesSubscriptionNotFound(c *C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/notices?subscription=foo", nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1000;socket=%s;", dirs.SnapdSocket)
+	rsp := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, Equals, 404)
+	c.Check(rsp.Message, Equals, `cannot find notice subscription "foo"`)
+}
+
+func (s *noticesSuite) TestNoticesSubscription(c *C) {
+	s.daemon(c)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	err := notices.AddSubscription(st, &notices.Subscription{
+		Name:   "monitor",
+		UserID: 1000,
+		Types:  []state.NoticeType{state.ChangeUpdateNotice},
+		Data:   map[string]string{"kind": "refresh-snap"},
+	})
+	c.Assert(err, IsNil)
+	acked, err := st.AddNotice(nil, state.ChangeUpdateNotice, "1", &state.AddNoticeOptions{
+		Data: map[string]string{"kind": "refresh-snap"},
+	})
+	c.Assert(err, IsNil)
+	time.Sleep(time.Microsecond)
+	addNotice(c, st, nil, state.ChangeUpdateNotice, "2", &state.AddNoticeOptions{
+		Data: map[string]string{"kind": "install-snap"},
+	})
+	time.Sleep(time.Microsecond)
+	addNotice(c, st, nil, state.WarningNotice, "danger", nil)
+	time.Sleep(time.Microsecond)
+	pending, err := st.AddNotice(nil, state.ChangeUpdateNotice, "3", &state.AddNoticeOptions{
+		Data: map[string]string{"kind": "refresh-snap"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(notices.AckSubscription(st, 1000, "monitor", st.Notice(acked)), IsNil)
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/notices?subscription=monitor", nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1000;socket=%s;", dirs.SnapdSocket)
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, Equals, 200)
+
+	result, ok := rsp.Result.([]*state.Notice)
+	c.Assert(ok, Equals, true)
+	c.Assert(result, HasLen, 1)
+	c.Check(result[0].ID(), Equals, pending)
+
+	// subscriptions are per-user
+	req, err = http.NewRequest("GET", "/v2/notices?subscription=monitor", nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1001;socket=%s;", dirs.SnapdSocket)
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 404)
+}
+
+var _ = Suite(&noticeSubscriptionsSuite{})
+
+type noticeSubscriptionsSuite struct {
+	apiBaseSuite
+}
+
+func (s *noticeSubscriptionsSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.expectReadAccess(daemon.InterfaceOpenAccess{Interfaces: []string{"snap-refresh-observe", "snap-interfaces-requests-control"}})
+	s.expectWriteAccess(daemon.InterfaceOpenAccess{Interfaces: []string{"snap-refresh-observe", "snap-interfaces-requests-control"}})
+}
+
+func (s *noticeSubscriptionsSuite) post(c *C, uid int, body string) *http.Request {
+	req, err := http.NewRequest("POST", "/v2/notice-subscriptions", strings.NewReader(body))
+	c.Assert(err, IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=%d;socket=%s;", uid, dirs.SnapdSocket)
+	return req
+}
+
+func (s *noticeSubscriptionsSuite) TestAddAckRemove(c *C) {
+	s.daemon(c)
+
+	rsp := s.syncReq(c, s.post(c, 1000, `{"action": "add", "name": "monitor", "types": ["warning"], "key-globs": ["dang*"]}`), nil, actionIsExpected)
+	c.Assert(rsp.Status, Equals, 200)
+	sub, ok := rsp.Result.(*notices.Subscription)
+	c.Assert(ok, Equals, true)
+	c.Check(sub, DeepEquals, &notices.Subscription{
+		Name:     "monitor",
+		UserID:   1000,
+		Types:    []state.NoticeType{state.WarningNotice},
+		KeyGlobs: []string{"dang*"},
+	})
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	noticeID, err := st.AddNotice(nil, state.WarningNotice, "danger", nil)
+	c.Assert(err, IsNil)
+	st.Unlock()
+
+	rsp = s.syncReq(c, s.post(c, 1000, fmt.Sprintf(`{"action": "ack", "name": "monitor", "notice-id": %q}`, noticeID)), nil, actionIsExpected)
+	c.Assert(rsp.Status, Equals, 200)
+	sub, ok = rsp.Result.(*notices.Subscription)
+	c.Assert(ok, Equals, true)
+	c.Check(sub.LastAckedID, Equals, noticeID)
+
+	req, err := http.NewRequest("GET", "/v2/notice-subscriptions", nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1000;socket=%s;", dirs.SnapdSocket)
+	rsp = s.syncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, Equals, 200)
+	subs, ok := rsp.Result.([]*notices.Subscription)
+	c.Assert(ok, Equals, true)
+	c.Assert(subs, HasLen, 1)
+	c.Check(subs[0].LastAckedID, Equals, noticeID)
+
+	// other users don't see it
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1001;socket=%s;", dirs.SnapdSocket)
+	rsp = s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Result, HasLen, 0)
+
+	rsp = s.syncReq(c, s.post(c, 1000, `{"action": "remove", "name": "monitor"}`), nil, actionIsExpected)
+	c.Assert(rsp.Status, Equals, 200)
+
+	st.Lock()
+	defer st.Unlock()
+	_, err = notices.GetSubscription(st, 1000, "monitor")
+	c.Check(err, Equals, notices.ErrNoSubscription)
+}
+
+func (s *noticeSubscriptionsSuite) TestAckOtherUsersNotice(c *C) {
+	s.daemon(c)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	c.Assert(notices.AddSubscription(st, &notices.Subscription{Name: "monitor", UserID: 1000}), IsNil)
+	uid := uint32(1001)
+	noticeID, err := st.AddNotice(&uid, state.WarningNotice, "danger", nil)
+	c.Assert(err, IsNil)
+	st.Unlock()
+
+	rsp := s.errorReq(c, s.post(c, 1000, fmt.Sprintf(`{"action": "ack", "name": "monitor", "notice-id": %q}`, noticeID)), nil, actionIsExpected)
+	c.Check(rsp.Status, Equals, 403)
+}
+
+func (s *noticeSubscriptionsSuite) TestErrors(c *C) {
+	s.daemon(c)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	noticeID, err := st.AddNotice(nil, state.WarningNotice, "danger", nil)
+	c.Assert(err, IsNil)
+	st.Unlock()
+
+	for _, tc := range []struct {
+		body    string
+		status  int
+		message string
+		action  actionExpectedBool
+	}{
+		{`{"action": "add"}`, 400, "notice subscription name must be provided", actionIsExpected},
+		{`{"action": "foo", "name": "x"}`, 400, `invalid action "foo"`, actionIsUnexpected},
+		{`{"action": "add", "name": "X"}`, 400, `invalid subscription name "X"`, actionIsExpected},
+		{`{"action": "add", "name": "x", "key-globs": ["["]}`, 400, `invalid key pattern "\[".*`, actionIsExpected},
+		{`{"action": "ack", "name": "x"}`, 400, "notice ID must be provided", actionIsExpected},
+		{`{"action": "ack", "name": "x", "notice-id": "1234"}`, 404, `cannot find notice with id "1234"`, actionIsExpected},
+		{fmt.Sprintf(`{"action": "ack", "name": "x", "notice-id": %q}`, noticeID), 404, `cannot find notice subscription "x"`, actionIsExpected},
+		{`{"action": "remove", "name": "x"}`, 404, `cannot find notice subscription "x"`, actionIsExpected},
+	} {
+		rsp := s.errorReq(c, s.post(c, 1000, tc.body), nil, tc.action)
+		c.Check(rsp.Status, Equals, tc.status, Commentf(tc.body))
+		c.Check(rsp.Message, Matches, tc.message, Commentf(tc.body))
+	}
+}
+
+func (s *noticeSubscriptionsSuite) TestAddForbiddenTypesForSnap(c *C) {
+	s.daemon(c)
+
+	req := s.post(c, 1000, `{"action": "add", "name": "monitor", "types": ["warning"]}`)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1000;socket=%s;iface=snap-refresh-observe;", dirs.SnapSocket)
+	rsp := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, Equals, 403)
+	c.Check(rsp.Message, Equals, "snap cannot access specified notice types")
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/interfaces/prompting"
 	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+	"github.com/snapcore/snapd/interfaces/prompting/requestaudit"
 	"github.com/snapcore/snapd/interfaces/prompting/requestprompts"
 	"github.com/snapcore/snapd/interfaces/prompting/requestrules"
 	"github.com/snapcore/snapd/overlord/auth"
@@ -56,7 +57,7 @@ var (
 		Path:        "/v2/interfaces/requests/rules",
 		GET:         getRules,
 		POST:        postRules,
-		Actions:     []string{"add", "remove"},
+		Actions:     []string{"add", "remove", "import"},
 		ReadAccess:  interfaceOpenAccess{Interfaces: []string{"snap-interfaces-requests-control"}},
 		WriteAccess: interfaceAuthenticatedAccess{Interfaces: []string{"snap-interfaces-requests-control"}, Polkit: polkitActionManage},
 	}
@@ -69,6 +70,12 @@ var (
 		ReadAccess:  interfaceOpenAccess{Interfaces: []string{"snap-interfaces-requests-control"}},
 		WriteAccess: interfaceAuthenticatedAccess{Interfaces: []string{"snap-interfaces-requests-control"}, Polkit: polkitActionManage},
 	}
+
+	requestsAuditCmd = &Command{
+		Path:       "/v2/interfaces/requests/audit",
+		GET:        getAudit,
+		ReadAccess: interfaceOpenAccess{Interfaces: []string{"snap-interfaces-requests-control"}},
+	}
 )
 
 // getUserID returns the UID specified by the user-id parameter of the query,
@@ -251,6 +258,9 @@ func promptingError(err error) *apiError {
 	case errors.Is(err, prompting_errors.ErrPatchedRuleHasNoPerms):
 		apiErr.Status = 400
 		apiErr.Kind = client.ErrorKindInterfacesRequestsPatchedRuleHasNoPermissions
+	case errors.Is(err, prompting_errors.ErrRuleIsSystem):
+		apiErr.Status = 403
+		apiErr.Kind = client.ErrorKindInterfacesRequestsRuleIsSystem
 	case errors.Is(err, prompting_errors.ErrNewSessionRuleNoSession):
 		apiErr.Status = 400
 		apiErr.Kind = client.ErrorKindInterfacesRequestsNewSessionRuleNoSession
@@ -321,9 +331,10 @@ type patchRuleContents struct {
 }
 
 type postRulesRequestBody struct {
-	Action         string               `json:"action"`
-	AddRule        *addRuleContents     `json:"rule,omitempty"`
-	RemoveSelector *removeRulesSelector `json:"selector,omitempty"`
+	Action         string                       `json:"action"`
+	AddRule        *addRuleContents             `json:"rule,omitempty"`
+	RemoveSelector *removeRulesSelector         `json:"selector,omitempty"`
+	ImportRules    []*requestrules.RuleContents `json:"rules,omitempty"`
 }
 
 type postRuleRequestBody struct {
@@ -434,6 +445,17 @@ func getRules(c *Command, r *http.Request, user *auth.UserState) Response {
 	snap := query.Get("snap")
 	iface := query.Get("interface")
 
+	if query.Get("export") == "true" {
+		if snap != "" || iface != "" {
+			return BadRequest(`cannot use "snap" or "interface" parameters when exporting rules`)
+		}
+		ruleSet, err := getInterfaceManager(c).InterfacesRequestsManager().ExportRules(userID)
+		if err != nil {
+			return promptingError(err)
+		}
+		return SyncResponse(ruleSet)
+	}
+
 	rules, err := getInterfaceManager(c).InterfacesRequestsManager().Rules(userID, snap, iface)
 	if err != nil {
 		// Should be impossible, Rules() always returns nil error
@@ -485,8 +507,17 @@ func postRules(c *Command, r *http.Request, user *auth.UserState) Response {
 			return promptingError(err)
 		}
 		return SyncResponse(removedRules)
+	case "import":
+		if postBody.ImportRules == nil {
+			return BadRequest(`must include "rules" field in request body when action is "import"`)
+		}
+		importedRules, err := getInterfaceManager(c).InterfacesRequestsManager().ImportRules(userID, postBody.ImportRules)
+		if err != nil {
+			return promptingError(err)
+		}
+		return SyncResponse(importedRules)
 	default:
-		return BadRequest(`"action" field must be "create" or "remove"`)
+		return BadRequest(`"action" field must be "add", "remove" or "import"`)
 	}
 }
 
@@ -560,3 +591,60 @@ func postRule(c *Command, r *http.Request, user *auth.UserState) Response {
 		return BadRequest(`action must be "add" or "remove"`)
 	}
 }
+
+// getAudit returns the entries of the prompting audit log for the user making
+// the request, or for the user given by the "user-id" parameter. Entries of
+// every user are returned to admins which do not use the "user-id" parameter.
+func getAudit(c *Command, r *http.Request, user *auth.UserState) Response {
+	userID, errorResp := getUserID(r)
+	if errorResp != nil {
+		return errorResp
+	}
+
+	if !getInterfaceManager(c).AppArmorPromptingRunning() {
+		return promptingNotRunningError()
+	}
+
+	query := r.URL.Query()
+	filter := &requestaudit.Filter{
+		User:      userID,
+		AllUsers:  userID == 0 && len(query["user-id"]) == 0,
+		Snap:      query.Get("snap"),
+		Interface: query.Get("interface"),
+		Source:    requestaudit.SourceType(query.Get("source")),
+		Outcome:   prompting.OutcomeType(query.Get("outcome")),
+	}
+	switch filter.Source {
+	case "", requestaudit.SourceRule, requestaudit.SourceReply, requestaudit.SourceTimeout:
+	default:
+		return BadRequest(`invalid "source" parameter: must be "rule", "reply" or "timeout"`)
+	}
+	switch filter.Outcome {
+	case prompting.OutcomeUnset, prompting.OutcomeAllow, prompting.OutcomeDeny:
+	default:
+		return BadRequest(`invalid "outcome" parameter: must be "allow" or "deny"`)
+	}
+	since, err := parseOptionalTime(query.Get("since"))
+	if err != nil {
+		return BadRequest(`invalid "since" timestamp: %v`, err)
+	}
+	filter.Since = since
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return BadRequest(`invalid "limit" parameter: %q`, limitStr)
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := getInterfaceManager(c).InterfacesRequestsManager().AuditEntries(filter)
+	if err != nil {
+		return InternalError("cannot read prompting audit log: %v", err)
+	}
+
+	if len(entries) == 0 {
+		entries = []*requestaudit.Entry{}
+	}
+
+	return SyncResponse(entries)
+}
@@ -22,9 +22,11 @@ package daemon_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -34,6 +36,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/prompting"
 	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
 	"github.com/snapcore/snapd/interfaces/prompting/patterns"
+	"github.com/snapcore/snapd/interfaces/prompting/requestaudit"
 	"github.com/snapcore/snapd/interfaces/prompting/requestprompts"
 	"github.com/snapcore/snapd/interfaces/prompting/requestrules"
 	"github.com/snapcore/snapd/overlord/ifacestate/apparmorprompting"
@@ -49,6 +52,8 @@ type fakeInterfacesRequestsManager struct {
 	prompt       *requestprompts.Prompt
 	rule         *requestrules.Rule
 	satisfiedIDs []prompting.IDType
+	ruleSet      *requestrules.RuleSet
+	auditEntries []*requestaudit.Entry
 	err          error
 
 	// Store most recent received values
@@ -63,6 +68,8 @@ type fakeInterfacesRequestsManager struct {
 	lifespan         prompting.LifespanType
 	duration         string
 	clientActivity   bool
+	importContents   []*requestrules.RuleContents
+	auditFilter      *requestaudit.Filter
 }
 
 func (m *fakeInterfacesRequestsManager) Prompts(userID uint32, clientActivity bool) ([]*requestprompts.Prompt, error) {
@@ -130,6 +137,22 @@ func (m *fakeInterfacesRequestsManager) RemoveRule(userID uint32, ruleID prompti
 	return m.rule, m.err
 }
 
+func (m *fakeInterfacesRequestsManager) ExportRules(userID uint32) (*requestrules.RuleSet, error) {
+	m.userID = userID
+	return m.ruleSet, m.err
+}
+
+func (m *fakeInterfacesRequestsManager) ImportRules(userID uint32, contents []*requestrules.RuleContents) ([]*requestrules.Rule, error) {
+	m.userID = userID
+	m.importContents = contents
+	return m.rules, m.err
+}
+
+func (m *fakeInterfacesRequestsManager) AuditEntries(filter *requestaudit.Filter) ([]*requestaudit.Entry, error) {
+	m.auditFilter = filter
+	return m.auditEntries, m.err
+}
+
 type promptingSuite struct {
 	apiBaseSuite
 
@@ -315,6 +338,18 @@ func (s *promptingSuite) TestPromptingError(c *C) {
 				"type":        "error",
 			},
 		},
+		{
+			err: prompting_errors.ErrRuleIsSystem,
+			body: map[string]any{
+				"result": map[string]any{
+					"message": prompting_errors.ErrRuleIsSystem.Error(),
+					"kind":    string(client.ErrorKindInterfacesRequestsRuleIsSystem),
+				},
+				"status":      "Forbidden",
+				"status-code": 403.0,
+				"type":        "error",
+			},
+		},
 		{
 			err: prompting_errors.ErrPromptsClosed,
 			body: map[string]any{
@@ -870,6 +905,115 @@ func (s *promptingSuite) TestGetRulesHappy(c *C) {
 	}
 }
 
+func (s *promptingSuite) TestGetRulesExportHappy(c *C) {
+	s.daemon(c)
+
+	s.manager.ruleSet = &requestrules.RuleSet{
+		Rules: []*requestrules.RuleContents{
+			{
+				Snap:      "firefox",
+				Interface: "home",
+				Constraints: &prompting.Constraints{
+					PathPattern: mustParsePathPattern(c, "/home/test/Documents/**"),
+					Permissions: prompting.PermissionMap{
+						"read": &prompting.PermissionEntry{
+							Outcome:  prompting.OutcomeAllow,
+							Lifespan: prompting.LifespanForever,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	rsp := s.makeSyncReq(c, "GET", "/v2/interfaces/requests/rules?export=true", 1234, nil)
+
+	c.Check(s.manager.userID, Equals, uint32(1234))
+	ruleSet, ok := rsp.Result.(*requestrules.RuleSet)
+	c.Check(ok, Equals, true)
+	c.Check(ruleSet, DeepEquals, s.manager.ruleSet)
+}
+
+func (s *promptingSuite) TestGetRulesExportUnhappy(c *C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/interfaces/requests/rules?export=true&snap=firefox", nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=1234;socket=;"
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Message, Equals, `cannot use "snap" or "interface" parameters when exporting rules`)
+}
+
+func (s *promptingSuite) TestPostRulesImportHappy(c *C) {
+	s.expectWriteAccess(daemon.InterfaceAuthenticatedAccess{Interfaces: []string{"snap-interfaces-requests-control"}, Polkit: "io.snapcraft.snapd.manage"})
+
+	s.daemon(c)
+
+	s.manager.rules = []*requestrules.Rule{
+		{
+			ID:        prompting.IDType(1234),
+			Timestamp: time.Now(),
+			User:      11235,
+			Snap:      "firefox",
+			Interface: "home",
+			Constraints: &prompting.RuleConstraints{
+				PathPattern: mustParsePathPattern(c, "/home/test/Documents/**"),
+				Permissions: prompting.RulePermissionMap{
+					"read": &prompting.RulePermissionEntry{
+						Outcome:  prompting.OutcomeAllow,
+						Lifespan: prompting.LifespanForever,
+					},
+				},
+			},
+		},
+	}
+
+	contents := []*requestrules.RuleContents{
+		{
+			Snap:      "firefox",
+			Interface: "home",
+			Constraints: &prompting.Constraints{
+				PathPattern: mustParsePathPattern(c, "/home/test/Documents/**"),
+				Permissions: prompting.PermissionMap{
+					"read": &prompting.PermissionEntry{
+						Outcome:  prompting.OutcomeAllow,
+						Lifespan: prompting.LifespanForever,
+					},
+				},
+			},
+		},
+	}
+	postBody := &daemon.PostRulesRequestBody{
+		Action:      "import",
+		ImportRules: contents,
+	}
+	marshalled, err := json.Marshal(postBody)
+	c.Assert(err, IsNil)
+
+	rsp := s.makeSyncReq(c, "POST", "/v2/interfaces/requests/rules", 11235, marshalled)
+
+	c.Check(s.manager.userID, Equals, uint32(11235))
+	c.Check(s.manager.importContents, DeepEquals, contents)
+
+	rules, ok := rsp.Result.([]*requestrules.Rule)
+	c.Check(ok, Equals, true)
+	c.Check(rules, DeepEquals, s.manager.rules)
+}
+
+func (s *promptingSuite) TestPostRulesImportUnhappy(c *C) {
+	s.expectWriteAccess(daemon.InterfaceAuthenticatedAccess{Interfaces: []string{"snap-interfaces-requests-control"}, Polkit: "io.snapcraft.snapd.manage"})
+
+	s.daemon(c)
+
+	req, err := http.NewRequest("POST", "/v2/interfaces/requests/rules", strings.NewReader(`{"action": "import"}`))
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=1234;socket=;"
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Message, Equals, `must include "rules" field in request body when action is "import"`)
+}
+
 func (s *promptingSuite) TestPostRulesAddHappy(c *C) {
 	s.expectWriteAccess(daemon.InterfaceAuthenticatedAccess{Interfaces: []string{"snap-interfaces-requests-control"}, Polkit: "io.snapcraft.snapd.manage"})
 
@@ -1158,3 +1302,90 @@ func (s *promptingSuite) TestPostRuleRemoveHappy(c *C) {
 	c.Check(ok, Equals, true)
 	c.Check(rule, DeepEquals, s.manager.rule)
 }
+
+func (s *promptingSuite) TestGetAuditHappy(c *C) {
+	s.daemon(c)
+
+	s.manager.auditEntries = []*requestaudit.Entry{
+		{
+			Timestamp:   time.Now(),
+			User:        1234,
+			Snap:        "firefox",
+			Interface:   "home",
+			Path:        "/home/test/foo",
+			Permissions: []string{"read"},
+			Outcome:     prompting.OutcomeAllow,
+			Source:      requestaudit.SourceRule,
+			RuleIDs:     []prompting.IDType{5},
+		},
+	}
+
+	rsp := s.makeSyncReq(c, "GET", "/v2/interfaces/requests/audit?snap=firefox&interface=home&source=rule&outcome=allow&since=2025-03-01T12:00:00Z&limit=10", 1234, nil)
+
+	c.Check(s.manager.auditFilter, DeepEquals, &requestaudit.Filter{
+		User:      1234,
+		Snap:      "firefox",
+		Interface: "home",
+		Source:    requestaudit.SourceRule,
+		Outcome:   prompting.OutcomeAllow,
+		Since:     time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Limit:     10,
+	})
+	entries, ok := rsp.Result.([]*requestaudit.Entry)
+	c.Check(ok, Equals, true)
+	c.Check(entries, DeepEquals, s.manager.auditEntries)
+}
+
+func (s *promptingSuite) TestGetAuditUsers(c *C) {
+	s.daemon(c)
+
+	// No entries results in an empty list
+	rsp := s.makeSyncReq(c, "GET", "/v2/interfaces/requests/audit", 1000, nil)
+	c.Check(s.manager.auditFilter, DeepEquals, &requestaudit.Filter{User: 1000})
+	c.Check(rsp.Result, DeepEquals, []*requestaudit.Entry{})
+
+	// Admins get the entries of every user by default
+	s.makeSyncReq(c, "GET", "/v2/interfaces/requests/audit", 0, nil)
+	c.Check(s.manager.auditFilter, DeepEquals, &requestaudit.Filter{AllUsers: true})
+
+	// Or of a particular user
+	s.makeSyncReq(c, "GET", "/v2/interfaces/requests/audit?user-id=1001", 0, nil)
+	c.Check(s.manager.auditFilter, DeepEquals, &requestaudit.Filter{User: 1001})
+
+	// Other users cannot see the entries of other users
+	req, err := http.NewRequest("GET", "/v2/interfaces/requests/audit?user-id=1001", nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 403)
+}
+
+func (s *promptingSuite) TestGetAuditUnhappy(c *C) {
+	s.daemon(c)
+
+	for _, testCase := range []struct {
+		query   string
+		message string
+	}{
+		{"source=foo", `invalid "source" parameter: must be "rule", "reply" or "timeout"`},
+		{"outcome=maybe", `invalid "outcome" parameter: must be "allow" or "deny"`},
+		{"since=yesterday", `invalid "since" timestamp: .*`},
+		{"limit=-1", `invalid "limit" parameter: "-1"`},
+		{"limit=many", `invalid "limit" parameter: "many"`},
+	} {
+		req, err := http.NewRequest("GET", "/v2/interfaces/requests/audit?"+testCase.query, nil)
+		c.Assert(err, IsNil)
+		req.RemoteAddr = "pid=100;uid=1000;socket=;"
+		rspe := s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Status, Equals, 400, Commentf("query: %s", testCase.query))
+		c.Check(rspe.Message, Matches, testCase.message, Commentf("query: %s", testCase.query))
+	}
+
+	s.manager.err = errors.New("boom")
+	req, err := http.NewRequest("GET", "/v2/interfaces/requests/audit", nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 500)
+	c.Check(rspe.Message, Equals, "cannot read prompting audit log: boom")
+}
@@ -22,6 +22,7 @@ package daemon
 import (
 	"net/http"
 	"sort"
+	"time"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/jsonutil"
@@ -47,6 +48,11 @@ var (
 		GET:        getQuotaGroupInfo,
 		ReadAccess: openAccess{},
 	}
+	quotaGroupUsageCmd = &Command{
+		Path:       "/v2/quotas/{group}/usage",
+		GET:        getQuotaGroupUsage,
+		ReadAccess: openAccess{},
+	}
 )
 
 type postQuotaGroupData struct {
@@ -63,6 +69,7 @@ var (
 	servicestateCreateQuota = servicestate.CreateQuota
 	servicestateUpdateQuota = servicestate.UpdateQuota
 	servicestateRemoveQuota = servicestate.RemoveQuota
+	servicestateQuotaUsage  = servicestate.QuotaUsage
 )
 
 var quoteControlChangeKind = swfeats.RegisterChangeKind("quota-control")
@@ -92,6 +99,9 @@ var getQuotaUsage = func(grp *quota.Group) (*client.QuotaValues, error) {
 func createQuotaValues(grp *quota.Group) *client.QuotaValues {
 	var constraints client.QuotaValues
 	constraints.Memory = grp.MemoryLimit
+	constraints.MemoryHigh = grp.MemoryHighLimit
+	constraints.OOMPolicy = string(grp.OOMPolicy)
+	constraints.MemoryPressure = grp.MemoryPressureThreshold
 	constraints.Threads = grp.ThreadLimit
 
 	if grp.CPULimit != nil {
@@ -114,9 +124,38 @@ func createQuotaValues(grp *quota.Group) *client.QuotaValues {
 			}
 		}
 	}
+	if grp.IOLimit != nil {
+		constraints.IO = &client.QuotaIOValues{
+			Weight: grp.IOLimit.Weight,
+		}
+		for _, dev := range grp.IOLimit.Devices {
+			constraints.IO.Devices = append(constraints.IO.Devices, client.QuotaIODeviceValues{
+				Device:         dev.Device,
+				ReadBandwidth:  dev.ReadBandwidth,
+				WriteBandwidth: dev.WriteBandwidth,
+				ReadIOPS:       dev.ReadIOPS,
+				WriteIOPS:      dev.WriteIOPS,
+			})
+		}
+	}
 	return &constraints
 }
 
+// addQuotaOOMKills adds to the result how many processes of the group were
+// killed for running out of memory.
+func addQuotaOOMKills(st *state.State, res *client.QuotaGroupResult) error {
+	events, err := servicestate.QuotaOOMKills(st, res.GroupName)
+	if err != nil {
+		return err
+	}
+	if events != nil {
+		res.OOMKills = events.Count
+		last := events.Last
+		res.LastOOMKill = &last
+	}
+	return nil
+}
+
 // getQuotaGroups returns all quota groups sorted by name.
 func getQuotaGroups(c *Command, r *http.Request, _ *auth.UserState) Response {
 	st := c.d.overlord.State()
@@ -154,6 +193,9 @@ func getQuotaGroups(c *Command, r *http.Request, _ *auth.UserState) Response {
 			Constraints: createQuotaValues(group),
 			Current:     currentUsage,
 		}
+		if err := addQuotaOOMKills(st, &results[i]); err != nil {
+			return InternalError(err.Error())
+		}
 	}
 	return SyncResponse(results)
 }
@@ -192,6 +234,50 @@ func getQuotaGroupInfo(c *Command, r *http.Request, _ *auth.UserState) Response
 		Constraints: createQuotaValues(group),
 		Current:     currentUsage,
 	}
+	if err := addQuotaOOMKills(st, &res); err != nil {
+		return InternalError(err.Error())
+	}
+	return SyncResponse(res)
+}
+
+// getQuotaGroupUsage returns the recorded usage history of a single quota
+// group, optionally only the samples taken after the "since" time.
+func getQuotaGroupUsage(c *Command, r *http.Request, _ *auth.UserState) Response {
+	vars := muxVars(r)
+	groupName := vars["group"]
+	if err := naming.ValidateQuotaGroup(groupName); err != nil {
+		return BadRequest(err.Error())
+	}
+
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return BadRequest("invalid since parameter %q: must be in RFC 3339 format", s)
+		}
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if _, err := servicestate.GetQuota(st, groupName); err == servicestate.ErrQuotaNotFound {
+		return NotFound("cannot find quota group %q", groupName)
+	} else if err != nil {
+		return InternalError(err.Error())
+	}
+
+	samples := servicestateQuotaUsage(st, groupName, since)
+	res := make([]client.QuotaUsageSample, len(samples))
+	for i, sample := range samples {
+		res[i] = client.QuotaUsageSample{
+			Time:    sample.Time,
+			Memory:  sample.Memory,
+			CPU:     sample.CPU,
+			Threads: sample.Threads,
+		}
+	}
 	return SyncResponse(res)
 }
 
@@ -200,6 +286,15 @@ func quotaValuesToResources(values client.QuotaValues) quota.Resources {
 	if values.Memory != 0 {
 		resourcesBuilder.WithMemoryLimit(values.Memory)
 	}
+	if values.MemoryHigh != 0 {
+		resourcesBuilder.WithMemoryHighLimit(values.MemoryHigh)
+	}
+	if values.OOMPolicy != "" {
+		resourcesBuilder.WithOOMPolicy(quota.OOMPolicy(values.OOMPolicy))
+	}
+	if values.MemoryPressure != 0 {
+		resourcesBuilder.WithMemoryPressureThreshold(values.MemoryPressure)
+	}
 	if values.CPU != nil {
 		if values.CPU.Count != 0 {
 			resourcesBuilder.WithCPUCount(values.CPU.Count)
@@ -223,6 +318,24 @@ func quotaValuesToResources(values client.QuotaValues) quota.Resources {
 			resourcesBuilder.WithJournalRate(values.Journal.RateCount, values.Journal.RatePeriod)
 		}
 	}
+	if values.IO != nil {
+		if values.IO.Weight != 0 {
+			resourcesBuilder.WithIOWeight(values.IO.Weight)
+		}
+		if len(values.IO.Devices) != 0 {
+			devices := make([]quota.ResourceIODevice, 0, len(values.IO.Devices))
+			for _, dev := range values.IO.Devices {
+				devices = append(devices, quota.ResourceIODevice{
+					Device:         dev.Device,
+					ReadBandwidth:  dev.ReadBandwidth,
+					WriteBandwidth: dev.WriteBandwidth,
+					ReadIOPS:       dev.ReadIOPS,
+					WriteIOPS:      dev.WriteIOPS,
+				})
+			}
+			resourcesBuilder.WithIODeviceLimits(devices)
+		}
+	}
 	return resourcesBuilder.Build()
 }
 
@@ -328,6 +328,80 @@ func (s *apiQuotaSuite) TestPostEnsureQuotaCreateJournalRateZeroHappy(c *check.C
 	c.Assert(s.ensureSoonCalled, check.Equals, 1)
 }
 
+func (s *apiQuotaSuite) TestPostEnsureQuotaCreateIOHappy(c *check.C) {
+	var createCalled int
+	r := daemon.MockServicestateCreateQuota(func(st *state.State, name string, createOpts servicestate.CreateQuotaOptions) (*state.TaskSet, error) {
+		createCalled++
+		c.Check(name, check.Equals, "booze")
+		c.Check(createOpts.ResourceLimits, check.DeepEquals, quota.NewResourcesBuilder().
+			WithIOWeight(300).
+			WithIODeviceLimits([]quota.ResourceIODevice{
+				{Device: "/dev/sda", ReadBandwidth: 10 * quantity.SizeMiB, WriteIOPS: 100},
+			}).
+			Build())
+		ts := state.NewTaskSet(st.NewTask("foo-quota", "..."))
+		return ts, nil
+	})
+	defer r()
+
+	data, err := json.Marshal(daemon.PostQuotaGroupData{
+		Action:    "ensure",
+		GroupName: "booze",
+		Snaps:     []string{"some-snap"},
+		Constraints: client.QuotaValues{
+			IO: &client.QuotaIOValues{
+				Weight: 300,
+				Devices: []client.QuotaIODeviceValues{
+					{Device: "/dev/sda", ReadBandwidth: 10 * quantity.SizeMiB, WriteIOPS: 100},
+				},
+			},
+		},
+	})
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/quotas", bytes.NewBuffer(data))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, check.Equals, 202)
+	c.Assert(createCalled, check.Equals, 1)
+}
+
+func (s *apiQuotaSuite) TestPostEnsureQuotaCreateMemoryHighHappy(c *check.C) {
+	var createCalled int
+	r := daemon.MockServicestateCreateQuota(func(st *state.State, name string, createOpts servicestate.CreateQuotaOptions) (*state.TaskSet, error) {
+		createCalled++
+		c.Check(name, check.Equals, "booze")
+		c.Check(createOpts.ResourceLimits, check.DeepEquals, quota.NewResourcesBuilder().
+			WithMemoryLimit(quantity.SizeGiB).
+			WithMemoryHighLimit(768*quantity.SizeMiB).
+			WithOOMPolicy(quota.OOMPolicyRestartService).
+			WithMemoryPressureThreshold(40).
+			Build())
+		ts := state.NewTaskSet(st.NewTask("foo-quota", "..."))
+		return ts, nil
+	})
+	defer r()
+
+	data, err := json.Marshal(daemon.PostQuotaGroupData{
+		Action:    "ensure",
+		GroupName: "booze",
+		Snaps:     []string{"some-snap"},
+		Constraints: client.QuotaValues{
+			Memory:         quantity.SizeGiB,
+			MemoryHigh:     768 * quantity.SizeMiB,
+			OOMPolicy:      "restart-service",
+			MemoryPressure: 40,
+		},
+	})
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/quotas", bytes.NewBuffer(data))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, check.Equals, 202)
+	c.Assert(createCalled, check.Equals, 1)
+}
+
 func (s *apiQuotaSuite) TestPostEnsureQuotaUpdateCpuHappy(c *check.C) {
 	st := s.d.Overlord().State()
 	st.Lock()
@@ -810,6 +884,39 @@ func (s *apiQuotaSuite) TestListJournalQuotas(c *check.C) {
 	c.Check(s.ensureSoonCalled, check.Equals, 0)
 }
 
+func (s *apiQuotaSuite) TestListIOQuotas(c *check.C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	err := servicestatetest.MockQuotaInState(st, "foo", "", nil, nil, quota.NewResourcesBuilder().
+		WithIOWeight(50).
+		WithIODeviceLimits([]quota.ResourceIODevice{{Device: "/dev/sda", WriteBandwidth: quantity.SizeMiB, ReadIOPS: 10}}).
+		Build())
+	c.Assert(err, check.IsNil)
+	st.Unlock()
+
+	r := daemon.MockGetQuotaUsage(func(grp *quota.Group) (*client.QuotaValues, error) {
+		return &client.QuotaValues{}, nil
+	})
+	defer r()
+
+	req, err := http.NewRequest("GET", "/v2/quotas", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []client.QuotaGroupResult{
+		{
+			GroupName: "foo",
+			Constraints: &client.QuotaValues{IO: &client.QuotaIOValues{
+				Weight: 50,
+				Devices: []client.QuotaIODeviceValues{
+					{Device: "/dev/sda", WriteBandwidth: quantity.SizeMiB, ReadIOPS: 10},
+				},
+			}},
+			Current: &client.QuotaValues{},
+		},
+	})
+}
+
 func (s *apiQuotaSuite) TestGetQuota(c *check.C) {
 	st := s.d.Overlord().State()
 	st.Lock()
@@ -846,6 +953,104 @@ func (s *apiQuotaSuite) TestGetQuota(c *check.C) {
 	c.Check(s.ensureSoonCalled, check.Equals, 0)
 }
 
+func (s *apiQuotaSuite) TestGetQuotaOOMKills(c *check.C) {
+	last := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	st := s.d.Overlord().State()
+	st.Lock()
+	err := servicestatetest.MockQuotaInState(st, "foo", "", nil, nil, quota.NewResourcesBuilder().
+		WithMemoryLimit(quantity.SizeGiB).
+		WithMemoryHighLimit(768*quantity.SizeMiB).
+		WithOOMPolicy(quota.OOMPolicyRestartGroup).
+		WithMemoryPressureThreshold(60).
+		Build())
+	c.Assert(err, check.IsNil)
+	st.Set("quota-oom-events", map[string]*servicestate.QuotaOOMEvents{
+		"foo": {Count: 2, Last: last},
+	})
+	st.Unlock()
+
+	r := daemon.MockGetQuotaUsage(func(grp *quota.Group) (*client.QuotaValues, error) {
+		return &client.QuotaValues{Memory: quantity.SizeMiB}, nil
+	})
+	defer r()
+
+	req, err := http.NewRequest("GET", "/v2/quotas/foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, client.QuotaGroupResult{
+		GroupName: "foo",
+		Constraints: &client.QuotaValues{
+			Memory:         quantity.SizeGiB,
+			MemoryHigh:     768 * quantity.SizeMiB,
+			OOMPolicy:      "restart-group",
+			MemoryPressure: 60,
+		},
+		Current:     &client.QuotaValues{Memory: quantity.SizeMiB},
+		OOMKills:    2,
+		LastOOMKill: &last,
+	})
+}
+
+func (s *apiQuotaSuite) TestGetQuotaUsage(c *check.C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	mockQuotas(st, c)
+	st.Unlock()
+
+	t0 := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	var gotSince time.Time
+	r := daemon.MockServicestateQuotaUsage(func(st *state.State, name string, since time.Time) []servicestate.QuotaUsageSample {
+		c.Check(name, check.Equals, "foo")
+		gotSince = since
+		return []servicestate.QuotaUsageSample{
+			{Time: t0, Memory: quantity.SizeMiB},
+			{Time: t0.Add(time.Minute), Memory: 2 * quantity.SizeMiB, CPU: 20, Threads: 4},
+		}
+	})
+	defer r()
+
+	req, err := http.NewRequest("GET", "/v2/quotas/foo/usage?since=2025-03-01T11:30:00Z", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(gotSince.Equal(time.Date(2025, 3, 1, 11, 30, 0, 0, time.UTC)), check.Equals, true)
+	c.Check(rsp.Result, check.DeepEquals, []client.QuotaUsageSample{
+		{Time: t0, Memory: quantity.SizeMiB},
+		{Time: t0.Add(time.Minute), Memory: 2 * quantity.SizeMiB, CPU: 20, Threads: 4},
+	})
+
+	// without since all samples are requested
+	req, err = http.NewRequest("GET", "/v2/quotas/foo/usage", nil)
+	c.Assert(err, check.IsNil)
+	rsp = s.syncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(gotSince.IsZero(), check.Equals, true)
+}
+
+func (s *apiQuotaSuite) TestGetQuotaUsageErrors(c *check.C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	mockQuotas(st, c)
+	st.Unlock()
+
+	for _, t := range []struct {
+		url     string
+		status  int
+		message string
+	}{
+		{"/v2/quotas/000/usage", 400, `invalid quota group name: .*`},
+		{"/v2/quotas/foo/usage?since=yesterday", 400, `invalid since parameter "yesterday": must be in RFC 3339 format`},
+		{"/v2/quotas/unknown/usage", 404, `cannot find quota group "unknown"`},
+	} {
+		req, err := http.NewRequest("GET", t.url, nil)
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Status, check.Equals, t.status, check.Commentf(t.url))
+		c.Check(rspe.Message, check.Matches, t.message, check.Commentf(t.url))
+	}
+}
+
 func (s *apiQuotaSuite) TestGetQuotaInvalidName(c *check.C) {
 	st := s.d.Overlord().State()
 	st.Lock()
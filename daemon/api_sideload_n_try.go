@@ -44,6 +44,7 @@ import (
 	"github.com/snapcore/snapd/overlord/swfeats"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/naming"
+	"github.com/snapcore/snapd/snap/snapdelta"
 	"github.com/snapcore/snapd/snap/snapfile"
 	"github.com/snapcore/snapd/strutil"
 )
@@ -144,6 +145,9 @@ func (f *Form) GetSnapFiles() ([]*uploadedContainer, *apiError) {
 type sideloadFlags struct {
 	snapstate.Flags
 	dangerousOK bool
+	// fromDelta is set when the snap was reconstructed from a delta, it
+	// must then be asserted even in devmode
+	fromDelta bool
 }
 
 func sideloadOrTrySnap(ctx context.Context, c *Command, body io.ReadCloser, boundary string, user *auth.UserState) Response {
@@ -204,6 +208,16 @@ func sideloadOrTrySnap(ctx context.Context, c *Command, body io.ReadCloser, boun
 		dangerousOK: isTrue(form, "dangerous"),
 	}
 
+	if len(form.Values["base-revision"]) > 0 {
+		if sideloadFlags.dangerousOK {
+			return BadRequest("cannot install delta with the dangerous option, the reconstructed snap must be verified")
+		}
+		if errRsp := reconstructSnapFromDelta(c.d.overlord.State(), form); errRsp != nil {
+			return errRsp
+		}
+		sideloadFlags.fromDelta = true
+	}
+
 	snapFiles, errRsp := form.GetSnapFiles()
 	if errRsp != nil {
 		return errRsp
@@ -237,6 +251,65 @@ func sideloadOrTrySnap(ctx context.Context, c *Command, body io.ReadCloser, boun
 	return AsyncResponse(nil, chg.ID())
 }
 
+var snapdeltaApply = snapdelta.Apply
+
+// reconstructSnapFromDelta replaces the delta uploaded with the form with the
+// snap obtained by applying it to the installed revision of the snap given
+// by the "base-revision" value. The reconstructed snap is not trusted, it is
+// verified like any other sideloaded snap.
+func reconstructSnapFromDelta(st *state.State, form *Form) *apiError {
+	refs := form.FileRefs["snap"]
+	if len(refs) != 1 || len(form.Values["name"]) == 0 {
+		return BadRequest("cannot install delta: a single delta and the name of the snap it applies to are needed")
+	}
+	instanceName := form.Values["name"][0]
+	baseRev, err := snap.ParseRevision(form.Values["base-revision"][0])
+	if err != nil {
+		return BadRequest("cannot install delta: invalid base revision: %v", err)
+	}
+
+	st.Lock()
+	var snapst snapstate.SnapState
+	err = snapstate.Get(st, instanceName, &snapst)
+	st.Unlock()
+	if err != nil && !errors.Is(err, state.ErrNoState) {
+		return InternalError("cannot install delta: %v", err)
+	}
+	if !snapst.IsInstalled() {
+		return SnapNotInstalled(instanceName, fmt.Errorf("cannot install delta: snap %q is not installed", instanceName))
+	}
+	if snapst.LastIndex(baseRev) < 0 {
+		return BadRequest("cannot install delta: revision %s of snap %q is not installed", baseRev, instanceName)
+	}
+	sourcePath := snap.MinimalPlaceInfo(instanceName, baseRev).MountFile()
+
+	tmpf, err := os.CreateTemp(dirs.SnapBlobDir, dirs.LocalInstallBlobTempPrefix+"*")
+	if err != nil {
+		return InternalError("cannot create temp file for reconstructed snap: %v", err)
+	}
+	tmpf.Close()
+
+	// the reconstructed snap takes the place of the delta in the form so
+	// that it's cleaned up along with it
+	delta := refs[0]
+	refs[0] = &FileReference{Filename: delta.Filename, TmpPath: tmpf.Name()}
+	defer func() {
+		if err := os.Remove(delta.TmpPath); err != nil {
+			logger.Noticef("cannot remove temporary file: %v", err)
+		}
+	}()
+
+	if err := snapdeltaApply(sourcePath, delta.TmpPath, tmpf.Name()); err != nil {
+		if errors.Is(err, snapdelta.ErrNoXdelta3) {
+			return InternalError("cannot install delta: %v", err)
+		}
+		return BadRequest("cannot install delta: %v", err)
+	}
+	logger.Debugf("reconstructed snap %q from delta %q against revision %s", instanceName, delta.Filename, baseRev)
+
+	return nil
+}
+
 // sideloadedInfo contains information from a bunch of sideloaded snaps
 type sideloadedInfo struct {
 	// snaps contains the set of snaps that should be sideloaded. Any components
@@ -633,7 +706,7 @@ func readInfoAndDeriveSideInfo(st *state.State, tempPath string, origPath string
 
 		// with devmode we try to find assertions but it's ok
 		// if they are not there (implies --dangerous)
-		if !flags.DevMode {
+		if !flags.DevMode || flags.fromDelta {
 			msg := "cannot find signatures with metadata for snap/component"
 			if origPath != "" {
 				msg = fmt.Sprintf("%s %q", msg, origPath)
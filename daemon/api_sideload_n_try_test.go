@@ -1090,6 +1090,177 @@ version: 1`, nil)
 	})
 }
 
+func (s *sideloadSuite) setupDeltaInstall(c *check.C, reconstructed string) *daemon.Daemon {
+	d := s.daemonWithOverlordMockAndStore()
+	s.markSeeded(d)
+
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	snapstate.Set(st, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: snapstatetest.NewSequenceFromRevisionSideInfos(
+			[]*sequence.RevisionSideState{sequence.NewRevisionSideState(&snap.SideInfo{RealName: "foo", SnapID: "foo-id", Revision: snap.R(40)}, nil)},
+		),
+		Current: snap.R(40),
+	})
+
+	s.AddCleanup(daemon.MockSnapdeltaApply(func(sourcePath, deltaPath, targetPath string) error {
+		c.Check(sourcePath, check.Equals, filepath.Join(dirs.SnapBlobDir, "foo_40.snap"))
+		c.Check(deltaPath, testutil.FileEquals, "delta")
+		return os.WriteFile(targetPath, []byte(reconstructed), 0600)
+	}))
+	return d
+}
+
+func deltaInstallRequest(c *check.C, extraFields string) *http.Request {
+	body := "----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap\"; filename=\"foo_41.delta\"\r\n\r\n" +
+		"delta\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap-path\"\r\n\r\n" +
+		"a/b/foo_41.delta\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"name\"\r\n\r\n" +
+		"foo\r\n" +
+		"----hello--\r\n" +
+		extraFields
+	req, err := http.NewRequest("POST", "/v2/snaps", bytes.NewBufferString(body))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "multipart/thing; boundary=--hello--")
+	return req
+}
+
+func (s *sideloadSuite) TestSideloadDelta(c *check.C) {
+	fooSnap := snaptest.MakeTestSnapWithFiles(c, `name: foo
+version: 1`, nil)
+	digest, size, err := asserts.SnapFileSHA3_384(fooSnap)
+	c.Assert(err, check.IsNil)
+	fooSnapBytes, err := os.ReadFile(fooSnap)
+	c.Assert(err, check.IsNil)
+
+	d := s.setupDeltaInstall(c, string(fooSnapBytes))
+
+	dev1Acct := assertstest.NewAccount(s.StoreSigning, "devel1", nil, "")
+	snapDecl, err := s.StoreSigning.Sign(asserts.SnapDeclarationType, map[string]any{
+		"series":       "16",
+		"snap-id":      "foo-id",
+		"snap-name":    "foo",
+		"publisher-id": dev1Acct.AccountID(),
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+	snapRev, err := s.StoreSigning.Sign(asserts.SnapRevisionType, map[string]any{
+		"snap-sha3-384": digest,
+		"snap-size":     fmt.Sprintf("%d", size),
+		"snap-id":       "foo-id",
+		"snap-revision": "41",
+		"developer-id":  dev1Acct.AccountID(),
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	st := d.Overlord().State()
+	st.Lock()
+	assertstatetest.AddMany(st, s.StoreSigning.StoreAccountKey(""), dev1Acct, snapDecl, snapRev)
+	st.Unlock()
+
+	var installedPath string
+	defer daemon.MockSnapstateInstallPath(func(s *state.State, si *snap.SideInfo, path, name, channel string, flags snapstate.Flags, prqt snapstate.PrereqTracker) (*state.TaskSet, *snap.Info, error) {
+		c.Check(name, check.Equals, "foo")
+		c.Check(si, check.DeepEquals, &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "foo-id",
+			Revision: snap.R(41),
+		})
+		c.Check(path, testutil.FileEquals, fooSnapBytes)
+		installedPath = path
+		return state.NewTaskSet(), &snap.Info{SuggestedName: "foo"}, nil
+	})()
+
+	req := deltaInstallRequest(c, "Content-Disposition: form-data; name=\"base-revision\"\r\n\r\n40\r\n----hello--\r\n")
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Summary(), check.Equals, `Install "foo" snap from file "a/b/foo_41.delta"`)
+
+	// only the reconstructed snap is left, for the change
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapBlobDir, dirs.LocalInstallBlobTempPrefix+"*"))
+	c.Assert(err, check.IsNil)
+	c.Check(matches, check.DeepEquals, []string{installedPath})
+}
+
+func (s *sideloadSuite) TestSideloadDeltaNotAsserted(c *check.C) {
+	s.setupDeltaInstall(c, "reconstructed-foo")
+
+	// assertions are needed even in devmode
+	req := deltaInstallRequest(c, "Content-Disposition: form-data; name=\"base-revision\"\r\n\r\n40\r\n----hello--\r\n"+
+		"Content-Disposition: form-data; name=\"devmode\"\r\n\r\ntrue\r\n----hello--\r\n")
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Message, check.Equals, `cannot find signatures with metadata for snap/component "a/b/foo_41.delta"`)
+
+	// both the delta and the reconstructed snap are gone
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapBlobDir, dirs.LocalInstallBlobTempPrefix+"*"))
+	c.Assert(err, check.IsNil)
+	c.Check(matches, check.HasLen, 0)
+}
+
+func (s *sideloadSuite) TestSideloadDeltaErrors(c *check.C) {
+	s.setupDeltaInstall(c, "reconstructed-foo")
+
+	for _, t := range []struct {
+		fields string
+		err    string
+	}{
+		{"base-revision\"\r\n\r\nzz", `cannot install delta: invalid base revision: invalid snap revision: "zz"`},
+		{"base-revision\"\r\n\r\n39", `cannot install delta: revision 39 of snap "foo" is not installed`},
+	} {
+		req := deltaInstallRequest(c, "Content-Disposition: form-data; name=\""+t.fields+"\r\n----hello--\r\n")
+		rspe := s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Message, check.Equals, t.err)
+	}
+
+	body := "----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap\"; filename=\"bar_2.delta\"\r\n\r\n" +
+		"delta\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap-path\"\r\n\r\n" +
+		"bar_2.delta\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"name\"\r\n\r\n" +
+		"bar\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"base-revision\"\r\n\r\n" +
+		"1\r\n" +
+		"----hello--\r\n"
+	req, err := http.NewRequest("POST", "/v2/snaps", bytes.NewBufferString(body))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "multipart/thing; boundary=--hello--")
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Message, check.Equals, `cannot install delta: snap "bar" is not installed`)
+	c.Check(rspe.Kind, check.Equals, client.ErrorKindSnapNotInstalled)
+
+	req = deltaInstallRequest(c, "Content-Disposition: form-data; name=\"base-revision\"\r\n\r\n40\r\n----hello--\r\n"+
+		"Content-Disposition: form-data; name=\"dangerous\"\r\n\r\ntrue\r\n----hello--\r\n")
+	rspe = s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Message, check.Equals, `cannot install delta with the dangerous option, the reconstructed snap must be verified`)
+
+	s.AddCleanup(daemon.MockSnapdeltaApply(func(sourcePath, deltaPath, targetPath string) error {
+		return errors.New("cannot apply delta: xdelta3: target window checksum mismatch")
+	}))
+	req = deltaInstallRequest(c, "Content-Disposition: form-data; name=\"base-revision\"\r\n\r\n40\r\n----hello--\r\n")
+	rspe = s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `cannot install delta: cannot apply delta: xdelta3: target window checksum mismatch`)
+
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapBlobDir, dirs.LocalInstallBlobTempPrefix+"*"))
+	c.Assert(err, check.IsNil)
+	c.Check(matches, check.HasLen, 0)
+}
+
 func (s *sideloadSuite) TestSideloadSnapNoSignaturesDangerOff(c *check.C) {
 	body := "" +
 		"----hello--\r\n" +
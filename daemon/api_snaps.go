@@ -57,6 +57,7 @@ const (
 	removeCmdAction   = "remove"
 	enableCmdAction   = "enable"
 	disableCmdAction  = "disable"
+	planCmdAction     = "plan"
 )
 
 var (
@@ -82,7 +83,7 @@ var (
 			installCmdAction, refreshCmdAction, revertCmdAction,
 			switchCmdAction, holdCmdAction, unholdCmdAction,
 			snapshotCmdAction, removeCmdAction, enableCmdAction,
-			disableCmdAction,
+			disableCmdAction, planCmdAction,
 		},
 		ReadAccess:  interfaceOpenAccess{Interfaces: []string{"snap-refresh-observe", "desktop-launch"}},
 		WriteAccess: authenticatedAccess{Polkit: polkitActionManage},
@@ -298,10 +299,12 @@ type snapInstruction struct {
 	Snaps                  []string                         `json:"snaps"`
 	Users                  []string                         `json:"users"`
 	SnapshotOptions        map[string]*snap.SnapshotOptions `json:"snapshot-options"`
+	SnapshotTarget         string                           `json:"snapshot-target"`
 	ValidationSets         []string                         `json:"validation-sets"`
 	QuotaGroupName         string                           `json:"quota-group"`
 	Time                   string                           `json:"time"`
 	HoldLevel              string                           `json:"hold-level"`
+	AutoRefresh            bool                             `json:"auto-refresh"`
 
 	// The fields below should not be unmarshalled into. Do not export them.
 	userID int
@@ -427,7 +430,7 @@ func (inst *snapInstruction) validate() error {
 	switch inst.Transaction {
 	case "":
 	case client.TransactionPerSnap, client.TransactionAllSnaps:
-		if inst.Action != installCmdAction && inst.Action != refreshCmdAction {
+		if inst.Action != installCmdAction && inst.Action != refreshCmdAction && inst.Action != planCmdAction {
 			return fmt.Errorf(`transaction type is unsupported for %q actions`, inst.Action)
 		}
 	default:
@@ -452,6 +455,10 @@ func (inst *snapInstruction) validate() error {
 		}
 	}
 
+	if inst.AutoRefresh && inst.Action != planCmdAction {
+		return errors.New(`auto-refresh can only be specified for the "plan" action`)
+	}
+
 	if inst.Action != holdCmdAction {
 		if inst.Time != "" {
 			return errors.New(`time can only be specified for the "hold" action`)
@@ -478,6 +485,9 @@ func (inst *snapInstruction) validate() error {
 	if err := inst.validateSnapshotOptions(); err != nil {
 		return err
 	}
+	if inst.SnapshotTarget != "" && inst.Action != snapshotCmdAction {
+		return fmt.Errorf("snapshot-target can only be specified for snapshot action")
+	}
 
 	if inst.Action == snapshotCmdAction {
 		inst.cleanSnapshotOptions()
@@ -881,6 +891,10 @@ func snapOpMany(c *Command, r *http.Request, user *auth.UserState) Response {
 		inst.userID = user.ID
 	}
 
+	if inst.Action == planCmdAction {
+		return snapPlanMany(r.Context(), &inst, st)
+	}
+
 	op := inst.dispatchForMany()
 	if op == nil {
 		return BadRequest("unsupported multi-snap operation %q", inst.Action)
@@ -1114,6 +1128,82 @@ func snapUpdateMany(ctx context.Context, inst *snapInstruction, st *state.State)
 	}, nil
 }
 
+// snapPlanMany works out what refreshing the snaps of the instruction, or
+// the next auto-refresh if requested, would do and reports it, without
+// creating a change.
+func snapPlanMany(ctx context.Context, inst *snapInstruction, st *state.State) Response {
+	if inst.AutoRefresh {
+		if len(inst.Snaps) != 0 {
+			return BadRequest("cannot plan an auto-refresh of specific snaps")
+		}
+		// auto-refreshes don't ignore running snaps and always use a
+		// transaction per snap
+		if inst.IgnoreRunning || inst.Transaction == client.TransactionAllSnaps {
+			return BadRequest("cannot plan an auto-refresh ignoring running snaps or with an all-snaps transaction")
+		}
+		plan, err := snapstatePlanAutoRefresh(ctx, st)
+		if err != nil {
+			return inst.errToResponse(err)
+		}
+		return SyncResponse(clientRefreshPlan(plan))
+	}
+
+	updates := make([]snapstate.StoreUpdate, 0, len(inst.Snaps))
+	for _, name := range inst.Snaps {
+		updates = append(updates, snapstate.StoreUpdate{
+			InstanceName:         name,
+			AdditionalComponents: inst.CompsForSnaps[name],
+		})
+	}
+
+	flags := snapstate.Flags{
+		IgnoreRunning: inst.IgnoreRunning,
+		Transaction:   inst.Transaction,
+	}
+	if flags.Transaction == "" {
+		flags.Transaction = client.TransactionPerSnap
+	}
+
+	goal := snapstateStoreUpdateGoal(updates...)
+	plan, err := snapstatePlanUpdate(ctx, st, goal, snapstate.Options{
+		Flags:  flags,
+		UserID: inst.userID,
+	})
+	if err != nil {
+		return inst.errToResponse(err)
+	}
+
+	return SyncResponse(clientRefreshPlan(plan))
+}
+
+func clientRefreshPlan(plan *snapstate.RefreshPlan) *client.RefreshPlan {
+	res := &client.RefreshPlan{
+		Held:          plan.Held,
+		Prerequisites: plan.Prerequisites,
+	}
+	for _, pr := range plan.Snaps {
+		res.Snaps = append(res.Snaps, client.PlannedRefresh{
+			Name:           pr.InstanceName,
+			Type:           string(pr.Type),
+			Channel:        pr.Channel,
+			Current:        pr.Current,
+			Revision:       pr.Revision,
+			DownloadSize:   pr.DownloadSize,
+			Reboot:         pr.Reboot,
+			Services:       pr.Services,
+			ValidationSets: pr.ValidationSets,
+		})
+	}
+	for _, pts := range plan.TaskSets {
+		ts := client.PlannedTaskSet{Snap: pts.Snap}
+		for _, t := range pts.Tasks {
+			ts.Tasks = append(ts.Tasks, client.PlannedTask{Kind: t.Kind, Summary: t.Summary})
+		}
+		res.TaskSets = append(res.TaskSets, ts)
+	}
+	return res
+}
+
 func snapEnforceValidationSets(ctx context.Context, inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
 	if len(inst.ValidationSets) > 0 && len(inst.Snaps) != 0 {
 		return nil, fmt.Errorf("snap names cannot be specified with validation sets to enforce")
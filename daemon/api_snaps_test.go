@@ -221,6 +221,33 @@ func (s *snapsSuite) TestSnapsInfoOnlyLocal(c *check.C) {
 	})
 }
 
+func (s *snapsSuite) TestSnapsInfoHealthLastRun(c *check.C) {
+	s.expectSnapsReadAccess()
+	d := s.daemon(c)
+
+	s.mkInstalledInState(c, d, "local", "foo", "v1", snap.R(10), true, "")
+	st := d.Overlord().State()
+	st.Lock()
+	t0 := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	st.Set("health", map[string]healthstate.HealthState{
+		"local": {Revision: snap.R(10), Status: healthstate.OkayStatus, Timestamp: t0, LastRun: t0.Add(time.Second)},
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/snaps?sources=local", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+
+	snaps := snapList(rsp.Result)
+	c.Assert(snaps, check.HasLen, 1)
+	c.Check(snaps[0]["health"], check.DeepEquals, map[string]any{
+		"status":    "okay",
+		"revision":  "10",
+		"timestamp": "2025-06-10T12:00:00Z",
+		"last-run":  "2025-06-10T12:00:01Z",
+	})
+}
+
 func (s *snapsSuite) TestSnapsInfoAllMixedPublishers(c *check.C) {
 	s.expectSnapsReadAccess()
 	d := s.daemon(c)
@@ -856,6 +883,135 @@ func (s *snapsSuite) TestRefreshManyTransactionally(c *check.C) {
 	c.Check(calledFlags.Transaction, check.Equals, client.TransactionAllSnaps)
 }
 
+func (s *snapsSuite) TestPostSnapsPlan(c *check.C) {
+	d := s.daemon(c)
+
+	var calledOpts snapstate.Options
+	defer daemon.MockSnapstatePlanUpdate(func(_ context.Context, st *state.State, g snapstate.UpdateGoal, opts snapstate.Options) (*snapstate.RefreshPlan, error) {
+		goal := g.(*storeUpdateGoalRecorder)
+		c.Check(goal.names(), check.DeepEquals, []string{"foo", "bar"})
+		calledOpts = opts
+		return &snapstate.RefreshPlan{
+			Snaps: []snapstate.PlannedRefresh{{
+				InstanceName:   "foo",
+				Type:           snap.TypeApp,
+				Channel:        "stable",
+				Current:        snap.R(1),
+				Revision:       snap.R(2),
+				DownloadSize:   1024,
+				Services:       []string{"foo.svc"},
+				ValidationSets: []string{"16/acme/base-set/2"},
+			}},
+			TaskSets: []snapstate.PlannedTaskSet{{
+				Snap:  "foo",
+				Tasks: []snapstate.PlannedTask{{Kind: "download-snap", Summary: "Download snap foo"}},
+			}},
+			Held:          map[string][]string{"bar": {"system"}},
+			Prerequisites: []string{"core24"},
+		}, nil
+	})()
+
+	buf := strings.NewReader(`{"action": "plan", "snaps": ["foo", "bar"], "ignore-running": true, "transaction": "all-snaps"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Result, check.DeepEquals, &client.RefreshPlan{
+		Snaps: []client.PlannedRefresh{{
+			Name:           "foo",
+			Type:           "app",
+			Channel:        "stable",
+			Current:        snap.R(1),
+			Revision:       snap.R(2),
+			DownloadSize:   1024,
+			Services:       []string{"foo.svc"},
+			ValidationSets: []string{"16/acme/base-set/2"},
+		}},
+		TaskSets: []client.PlannedTaskSet{{
+			Snap:  "foo",
+			Tasks: []client.PlannedTask{{Kind: "download-snap", Summary: "Download snap foo"}},
+		}},
+		Held:          map[string][]string{"bar": {"system"}},
+		Prerequisites: []string{"core24"},
+	})
+	c.Check(calledOpts.Flags.IgnoreRunning, check.Equals, true)
+	c.Check(calledOpts.Flags.Transaction, check.Equals, client.TransactionAllSnaps)
+
+	// no change was created
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 0)
+}
+
+func (s *snapsSuite) TestPostSnapsPlanAutoRefresh(c *check.C) {
+	s.daemon(c)
+
+	defer daemon.MockSnapstatePlanUpdate(func(_ context.Context, st *state.State, g snapstate.UpdateGoal, opts snapstate.Options) (*snapstate.RefreshPlan, error) {
+		c.Fatalf("unexpected call to PlanUpdate")
+		return nil, nil
+	})()
+	defer daemon.MockSnapstatePlanAutoRefresh(func(_ context.Context, st *state.State) (*snapstate.RefreshPlan, error) {
+		return &snapstate.RefreshPlan{
+			Held: map[string][]string{"foo": {"bar"}},
+		}, nil
+	})()
+
+	buf := strings.NewReader(`{"action": "plan", "auto-refresh": true}`)
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Result, check.DeepEquals, &client.RefreshPlan{
+		Held: map[string][]string{"foo": {"bar"}},
+	})
+
+	// the per-snap transaction of auto-refreshes can be requested
+	buf = strings.NewReader(`{"action": "plan", "auto-refresh": true, "transaction": "per-snap"}`)
+	req, err = http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+	s.syncReq(c, req, nil, actionIsExpected)
+
+	for _, tc := range []struct {
+		body string
+		err  string
+	}{
+		{`{"action": "plan", "auto-refresh": true, "snaps": ["foo"]}`, "cannot plan an auto-refresh of specific snaps"},
+		{`{"action": "plan", "auto-refresh": true, "ignore-running": true}`, "cannot plan an auto-refresh ignoring running snaps or with an all-snaps transaction"},
+		{`{"action": "plan", "auto-refresh": true, "transaction": "all-snaps"}`, "cannot plan an auto-refresh ignoring running snaps or with an all-snaps transaction"},
+		{`{"action": "refresh", "auto-refresh": true}`, `auto-refresh can only be specified for the "plan" action`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/snaps", strings.NewReader(tc.body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+
+		rspe := s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Status, check.Equals, 400)
+		c.Check(rspe.Message, check.Equals, tc.err, check.Commentf(tc.body))
+	}
+}
+
+func (s *snapsSuite) TestPostSnapsPlanError(c *check.C) {
+	s.daemon(c)
+
+	defer daemon.MockSnapstatePlanUpdate(func(_ context.Context, st *state.State, g snapstate.UpdateGoal, opts snapstate.Options) (*snapstate.RefreshPlan, error) {
+		return nil, &snap.NotInstalledError{Snap: "foo"}
+	})()
+
+	buf := strings.NewReader(`{"action": "plan", "snaps": ["foo"]}`)
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Kind, check.Equals, client.ErrorKindSnapNotInstalled)
+	c.Check(rspe.Message, check.Equals, `snap "foo" is not installed`)
+}
+
 func (s *snapsSuite) TestRefreshMany(c *check.C) {
 	refreshSnapAssertions := false
 	var refreshAssertionsOpts *assertstate.RefreshAssertionsOptions
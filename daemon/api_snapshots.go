@@ -21,7 +21,9 @@ package daemon
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -59,6 +61,8 @@ var (
 	snapshotForget  = snapshotstate.Forget
 	snapshotRestore = snapshotstate.Restore
 	snapshotSave    = snapshotstate.Save
+	snapshotSaveTo  = snapshotstate.SaveTo
+	snapshotFetch   = snapshotstate.Fetch
 	snapshotExport  = snapshotstate.Export
 	snapshotImport  = snapshotstate.Import
 )
@@ -97,6 +101,9 @@ type snapshotAction struct {
 	Action string   `json:"action"`
 	Snaps  []string `json:"snaps,omitempty"`
 	Users  []string `json:"users,omitempty"`
+
+	Key  *client.SnapshotKey `json:"key,omitempty"`
+	From string              `json:"from,omitempty"`
 }
 
 func (action snapshotAction) String() string {
@@ -135,26 +142,46 @@ func changeSnapshots(c *Command, r *http.Request, user *auth.UserState) Response
 		return BadRequest("snapshot operation requires action")
 	}
 
+	if action.From != "" && action.Action != "restore" {
+		return BadRequest(`snapshot %q operation cannot specify a target to restore from`, action.Action)
+	}
+
 	var affected []string
 	var ts *state.TaskSet
 	var err error
 
 	st := c.d.overlord.State()
+	if action.From != "" {
+		// the snapshot set is imported from the target first, which
+		// can take a while, like for imports
+		setID, _, err := snapshotFetch(r.Context(), st, action.From, action.SetID, action.Key)
+		if err != nil {
+			if err == client.ErrSnapshotSetNotFound {
+				return NotFound("%v", err)
+			}
+			return BadRequest("%v", err)
+		}
+		action.SetID = setID
+	}
+
 	st.Lock()
 	defer st.Unlock()
 
 	var changeKind string
 	switch action.Action {
 	case "check":
-		affected, ts, err = snapshotCheck(st, action.SetID, action.Snaps, action.Users)
+		affected, ts, err = snapshotCheck(st, action.SetID, action.Snaps, action.Users, action.Key)
 		changeKind = checkSnapshotChangeKind
 	case "restore":
-		affected, ts, err = snapshotRestore(st, action.SetID, action.Snaps, action.Users)
+		affected, ts, err = snapshotRestore(st, action.SetID, action.Snaps, action.Users, action.Key)
 		changeKind = restoreSnapshotChangeKind
 	case "forget":
 		if len(action.Users) != 0 {
 			return BadRequest(`snapshot "forget" operation cannot specify users`)
 		}
+		if action.Key != nil {
+			return BadRequest(`snapshot "forget" operation cannot specify a key`)
+		}
 		affected, ts, err = snapshotForget(st, action.SetID, action.Snaps)
 		changeKind = forgetSnapshotChangeKind
 	default:
@@ -167,21 +194,52 @@ func changeSnapshots(c *Command, r *http.Request, user *auth.UserState) Response
 	case client.ErrSnapshotSetNotFound, client.ErrSnapshotSnapsNotFound:
 		return NotFound("%v", err)
 	default:
+		if errors.Is(err, client.ErrSnapshotKeyRequired) {
+			return BadRequest("%v", err)
+		}
 		return InternalError("%v", err)
 	}
 
 	chg := newChange(st, changeKind, action.String(), []*state.TaskSet{ts}, affected)
-	chg.Set("api-data", map[string]any{"snap-names": affected})
+	apiData := map[string]any{"snap-names": affected}
+	if action.From != "" {
+		apiData["set-id"] = action.SetID
+	}
+	chg.Set("api-data", apiData)
 	ensureStateSoon(st)
 
 	return AsyncResponse(nil, chg.ID())
 }
 
+// snapshotKeyFromHeader returns the snapshot key given in the request, if
+// any, see client.SnapshotKeyHeader.
+func snapshotKeyFromHeader(r *http.Request) (*client.SnapshotKey, error) {
+	value := r.Header.Get(client.SnapshotKeyHeader)
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode snapshot key: %v", err)
+	}
+	var key client.SnapshotKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("cannot decode snapshot key: %v", err)
+	}
+	return &key, nil
+}
+
 // getSnapshotExport streams an archive containing an export of existing snapshots.
 //
 // The snapshots are re-packaged into a single uncompressed tar archive and
-// internally contain multiple zip files.
+// internally contain multiple zip files. If a key is given, the archives of
+// the snapshots are encrypted with it.
 func getSnapshotExport(c *Command, r *http.Request, user *auth.UserState) Response {
+	key, err := snapshotKeyFromHeader(r)
+	if err != nil {
+		return BadRequest("%v", err)
+	}
+
 	st := c.d.overlord.State()
 	st.Lock()
 	defer st.Unlock()
@@ -193,7 +251,7 @@ func getSnapshotExport(c *Command, r *http.Request, user *auth.UserState) Respon
 		return BadRequest("'id' must be a positive base 10 number; got %q", sid)
 	}
 
-	export, err := snapshotExport(r.Context(), st, setID)
+	export, err := snapshotExport(r.Context(), st, setID, key)
 	if err != nil {
 		return BadRequest("cannot export %v: %v", setID, err)
 	}
@@ -218,9 +276,14 @@ func doSnapshotImport(c *Command, r *http.Request, user *auth.UserState) Respons
 	// ensure we don't read more than we expect
 	limitedBodyReader := io.LimitReader(r.Body, expectedSize)
 
+	key, err := snapshotKeyFromHeader(r)
+	if err != nil {
+		return BadRequest("%v", err)
+	}
+
 	// XXX: check that we have enough space to import the compressed snapshots
 	st := c.d.overlord.State()
-	setID, snapNames, err := snapshotImport(r.Context(), st, limitedBodyReader)
+	setID, snapNames, err := snapshotImport(r.Context(), st, limitedBodyReader, key)
 	if err != nil {
 		return BadRequest(err.Error())
 	}
@@ -230,7 +293,15 @@ func doSnapshotImport(c *Command, r *http.Request, user *auth.UserState) Respons
 }
 
 func snapshotMany(_ context.Context, inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
-	setID, snapshotted, ts, err := snapshotSave(st, inst.Snaps, inst.Users, inst.SnapshotOptions)
+	var setID uint64
+	var snapshotted []string
+	var ts *state.TaskSet
+	var err error
+	if inst.SnapshotTarget != "" {
+		setID, snapshotted, ts, err = snapshotSaveTo(st, inst.Snaps, inst.Users, inst.SnapshotOptions, inst.SnapshotTarget)
+	} else {
+		setID, snapshotted, ts, err = snapshotSave(st, inst.Snaps, inst.Users, inst.SnapshotOptions)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +99,36 @@ func (s *snapshotSuite) TestSnapshotManyOptionsFull(c *check.C) {
 	c.Check(snapshotSaveCalled, check.Equals, 1)
 }
 
+func (s *snapshotSuite) TestSnapshotManyTo(c *check.C) {
+	defer daemon.MockSnapshotSave(func(*state.State, []string, []string, map[string]*snap.SnapshotOptions) (uint64, []string, *state.TaskSet, error) {
+		c.Fatal("unexpected call to snapshotSave")
+		return 0, nil, nil, nil
+	})()
+	var snapshotSaveToCalled int
+	defer daemon.MockSnapshotSaveTo(func(s *state.State, snaps, users []string,
+		options map[string]*snap.SnapshotOptions, target string) (uint64, []string, *state.TaskSet, error) {
+		snapshotSaveToCalled++
+		c.Check(snaps, check.DeepEquals, []string{"foo"})
+		c.Check(target, check.Equals, "s3://bucket/prefix")
+		t := s.NewTask("fake-snapshot", "Snapshot")
+		return 1, snaps, state.NewTaskSet(t), nil
+	})()
+
+	inst := daemon.MustUnmarshalSnapInstruction(c, `{"action": "snapshot", "snaps": ["foo"], "snapshot-target": "s3://bucket/prefix"}`)
+	c.Assert(inst.Validate(), check.IsNil)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	res, err := inst.DispatchForMany()(context.Background(), inst, st)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(res.Affected, check.DeepEquals, []string{"foo"})
+	c.Check(snapshotSaveToCalled, check.Equals, 1)
+
+	inst = daemon.MustUnmarshalSnapInstruction(c, `{"action": "refresh", "snaps": ["foo"], "snapshot-target": "s3://bucket/prefix"}`)
+	c.Check(inst.Validate(), check.ErrorMatches, "snapshot-target can only be specified for snapshot action")
+}
+
 func (s *snapshotSuite) TestSnapshotManyError(c *check.C) {
 	defer daemon.MockSnapshotSave(func(s *state.State, snaps, users []string,
 		options map[string]*snap.SnapshotOptions) (uint64, []string, *state.TaskSet, error) {
@@ -236,6 +266,9 @@ func (s *snapshotSuite) TestChangeSnapshots400(c *check.C) {
 		}, {
 			body:  `{"set": 42, "action": "forget", "users": ["foo"]}`,
 			error: `snapshot "forget" operation cannot specify users`,
+		}, {
+			body:  `{"set": 42, "action": "forget", "key": {"passphrase": "foo"}}`,
+			error: `snapshot "forget" operation cannot specify a key`,
 		},
 	}
 
@@ -253,11 +286,11 @@ func (s *snapshotSuite) TestChangeSnapshots400(c *check.C) {
 func (s *snapshotSuite) TestChangeSnapshots404(c *check.C) {
 	var done string
 	expectedError := errors.New("bzzt")
-	defer daemon.MockSnapshotCheck(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+	defer daemon.MockSnapshotCheck(func(*state.State, uint64, []string, []string, *client.SnapshotKey) ([]string, *state.TaskSet, error) {
 		done = "check"
 		return nil, nil, expectedError
 	})()
-	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string, *client.SnapshotKey) ([]string, *state.TaskSet, error) {
 		done = "restore"
 		return nil, nil, expectedError
 	})()
@@ -284,11 +317,11 @@ func (s *snapshotSuite) TestChangeSnapshots404(c *check.C) {
 func (s *snapshotSuite) TestChangeSnapshots500(c *check.C) {
 	var done string
 	expectedError := errors.New("bzzt")
-	defer daemon.MockSnapshotCheck(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+	defer daemon.MockSnapshotCheck(func(*state.State, uint64, []string, []string, *client.SnapshotKey) ([]string, *state.TaskSet, error) {
 		done = "check"
 		return nil, nil, expectedError
 	})()
-	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string, *client.SnapshotKey) ([]string, *state.TaskSet, error) {
 		done = "restore"
 		return nil, nil, expectedError
 	})()
@@ -311,11 +344,11 @@ func (s *snapshotSuite) TestChangeSnapshots500(c *check.C) {
 
 func (s *snapshotSuite) TestChangeSnapshot(c *check.C) {
 	var done string
-	defer daemon.MockSnapshotCheck(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+	defer daemon.MockSnapshotCheck(func(*state.State, uint64, []string, []string, *client.SnapshotKey) ([]string, *state.TaskSet, error) {
 		done = "check"
 		return []string{"foo"}, state.NewTaskSet(), nil
 	})()
-	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string, *client.SnapshotKey) ([]string, *state.TaskSet, error) {
 		done = "restore"
 		return []string{"foo"}, state.NewTaskSet(), nil
 	})()
@@ -355,10 +388,93 @@ func (s *snapshotSuite) TestChangeSnapshot(c *check.C) {
 	}
 }
 
+func (s *snapshotSuite) TestChangeSnapshotWithKey(c *check.C) {
+	var gotKey *client.SnapshotKey
+	defer daemon.MockSnapshotRestore(func(_ *state.State, _ uint64, _, _ []string, key *client.SnapshotKey) ([]string, *state.TaskSet, error) {
+		gotKey = key
+		return []string{"foo"}, state.NewTaskSet(), nil
+	})()
+
+	body := `{"set": 42, "action": "restore", "key": {"passphrase": "sekrit"}}`
+	req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, check.Equals, 202)
+	c.Check(gotKey, check.DeepEquals, &client.SnapshotKey{Passphrase: "sekrit"})
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Summary(), check.Equals, "Restore of snapshot set #42")
+}
+
+func (s *snapshotSuite) TestChangeSnapshotRestoreFrom(c *check.C) {
+	defer daemon.MockSnapshotFetch(func(_ context.Context, _ *state.State, target string, setID uint64, key *client.SnapshotKey) (uint64, []string, error) {
+		c.Check(target, check.Equals, "/mnt/snapshots")
+		c.Check(setID, check.Equals, uint64(42))
+		c.Check(key, check.DeepEquals, &client.SnapshotKey{Passphrase: "sekrit"})
+		return 7, []string{"foo"}, nil
+	})()
+	var restoredSetID uint64
+	defer daemon.MockSnapshotRestore(func(_ *state.State, setID uint64, _, _ []string, key *client.SnapshotKey) ([]string, *state.TaskSet, error) {
+		restoredSetID = setID
+		c.Check(key, check.DeepEquals, &client.SnapshotKey{Passphrase: "sekrit"})
+		return []string{"foo"}, state.NewTaskSet(), nil
+	})()
+
+	body := `{"set": 42, "action": "restore", "from": "/mnt/snapshots", "key": {"passphrase": "sekrit"}}`
+	req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, check.Equals, 202)
+	c.Check(restoredSetID, check.Equals, uint64(7))
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Summary(), check.Equals, "Restore of snapshot set #7")
+	var apiData map[string]any
+	c.Assert(chg.Get("api-data", &apiData), check.IsNil)
+	c.Check(apiData["set-id"], check.Equals, 7.0)
+}
+
+func (s *snapshotSuite) TestChangeSnapshotRestoreFromErrors(c *check.C) {
+	defer daemon.MockSnapshotFetch(func(context.Context, *state.State, string, uint64, *client.SnapshotKey) (uint64, []string, error) {
+		return 0, nil, client.ErrSnapshotSetNotFound
+	})()
+
+	req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(`{"set": 42, "action": "restore", "from": "/mnt/snapshots"}`))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, check.Equals, 404)
+
+	req, err = http.NewRequest("POST", "/v2/snapshots", strings.NewReader(`{"set": 42, "action": "check", "from": "/mnt/snapshots"}`))
+	c.Assert(err, check.IsNil)
+	rspe = s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `snapshot "check" operation cannot specify a target to restore from`)
+}
+
+func (s *snapshotSuite) TestChangeSnapshotKeyRequired(c *check.C) {
+	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string, *client.SnapshotKey) ([]string, *state.TaskSet, error) {
+		return nil, nil, fmt.Errorf(`cannot restore snapshot for "foo": %w`, client.ErrSnapshotKeyRequired)
+	})()
+
+	req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(`{"set": 42, "action": "restore"}`))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `cannot restore snapshot for "foo": snapshot is encrypted and no key was given`)
+}
+
 func (s *snapshotSuite) TestExportSnapshots(c *check.C) {
 	var snapshotExportCalled int
 
-	defer daemon.MockSnapshotExport(func(ctx context.Context, st *state.State, setID uint64) (*snapshotstate.SnapshotExport, error) {
+	defer daemon.MockSnapshotExport(func(ctx context.Context, st *state.State, setID uint64, key *client.SnapshotKey) (*snapshotstate.SnapshotExport, error) {
 		snapshotExportCalled++
 		c.Check(setID, check.Equals, uint64(1))
 		return &snapshotstate.SnapshotExport{}, nil
@@ -372,6 +488,23 @@ func (s *snapshotSuite) TestExportSnapshots(c *check.C) {
 	c.Check(snapshotExportCalled, check.Equals, 1)
 }
 
+func (s *snapshotSuite) TestExportSnapshotsWithKey(c *check.C) {
+	var gotKey *client.SnapshotKey
+	defer daemon.MockSnapshotExport(func(ctx context.Context, st *state.State, setID uint64, key *client.SnapshotKey) (*snapshotstate.SnapshotExport, error) {
+		gotKey = key
+		return &snapshotstate.SnapshotExport{}, nil
+	})()
+
+	req, err := http.NewRequest("GET", "/v2/snapshots/1/export", nil)
+	c.Assert(err, check.IsNil)
+	// base64 of {"passphrase":"secret"}
+	req.Header.Set(client.SnapshotKeyHeader, "eyJwYXNzcGhyYXNlIjoic2VjcmV0In0=")
+
+	rsp := s.req(c, req, nil, actionIsExpected)
+	c.Check(rsp, check.FitsTypeOf, &daemon.SnapshotExportResponse{})
+	c.Check(gotKey, check.DeepEquals, &client.SnapshotKey{Passphrase: "secret"})
+}
+
 func (s *snapshotSuite) TestExportSnapshotsBadRequestOnNonNumericID(c *check.C) {
 	req, err := http.NewRequest("GET", "/v2/snapshots/xxx/export", nil)
 	c.Assert(err, check.IsNil)
@@ -384,7 +517,7 @@ func (s *snapshotSuite) TestExportSnapshotsBadRequestOnNonNumericID(c *check.C)
 func (s *snapshotSuite) TestExportSnapshotsBadRequestOnError(c *check.C) {
 	var snapshotExportCalled int
 
-	defer daemon.MockSnapshotExport(func(ctx context.Context, st *state.State, setID uint64) (*snapshotstate.SnapshotExport, error) {
+	defer daemon.MockSnapshotExport(func(ctx context.Context, st *state.State, setID uint64, key *client.SnapshotKey) (*snapshotstate.SnapshotExport, error) {
 		snapshotExportCalled++
 		return nil, fmt.Errorf("boom")
 	})()
@@ -403,7 +536,7 @@ func (s *snapshotSuite) TestImportSnapshot(c *check.C) {
 
 	setID := uint64(3)
 	snapNames := []string{"baz", "bar", "foo"}
-	defer daemon.MockSnapshotImport(func(context.Context, *state.State, io.Reader) (uint64, []string, error) {
+	defer daemon.MockSnapshotImport(func(context.Context, *state.State, io.Reader, *client.SnapshotKey) (uint64, []string, error) {
 		return setID, snapNames, nil
 	})()
 
@@ -417,8 +550,46 @@ func (s *snapshotSuite) TestImportSnapshot(c *check.C) {
 	c.Check(rsp.Result, check.DeepEquals, map[string]any{"set-id": setID, "snaps": snapNames})
 }
 
+func (s *snapshotSuite) TestImportSnapshotWithKey(c *check.C) {
+	var gotKey *client.SnapshotKey
+	defer daemon.MockSnapshotImport(func(_ context.Context, _ *state.State, _ io.Reader, key *client.SnapshotKey) (uint64, []string, error) {
+		gotKey = key
+		return 3, []string{"foo"}, nil
+	})()
+
+	data := []byte("mocked snapshot export data file")
+	req, err := http.NewRequest("POST", "/v2/snapshots", bytes.NewReader(data))
+	c.Assert(err, check.IsNil)
+	req.Header.Add("Content-Length", strconv.Itoa(len(data)))
+	req.Header.Set("Content-Type", client.SnapshotExportMediaType)
+	// base64 of {"key-file":"a2V5"}
+	req.Header.Set(client.SnapshotKeyHeader, "eyJrZXktZmlsZSI6ImEyVjUifQ==")
+
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(gotKey, check.DeepEquals, &client.SnapshotKey{KeyFile: []byte("key")})
+}
+
+func (s *snapshotSuite) TestImportSnapshotBadKey(c *check.C) {
+	defer daemon.MockSnapshotImport(func(context.Context, *state.State, io.Reader, *client.SnapshotKey) (uint64, []string, error) {
+		c.Fatal("unexpected import")
+		return 0, nil, nil
+	})()
+
+	data := []byte("mocked snapshot export data file")
+	req, err := http.NewRequest("POST", "/v2/snapshots", bytes.NewReader(data))
+	c.Assert(err, check.IsNil)
+	req.Header.Add("Content-Length", strconv.Itoa(len(data)))
+	req.Header.Set("Content-Type", client.SnapshotExportMediaType)
+	req.Header.Set(client.SnapshotKeyHeader, "not base64!")
+
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Matches, "cannot decode snapshot key: .*")
+}
+
 func (s *snapshotSuite) TestImportSnapshotError(c *check.C) {
-	defer daemon.MockSnapshotImport(func(context.Context, *state.State, io.Reader) (uint64, []string, error) {
+	defer daemon.MockSnapshotImport(func(context.Context, *state.State, io.Reader, *client.SnapshotKey) (uint64, []string, error) {
 		return uint64(0), nil, errors.New("no")
 	})()
 
@@ -447,7 +618,7 @@ func (s *snapshotSuite) TestImportSnapshotNoContentLengthError(c *check.C) {
 func (s *snapshotSuite) TestImportSnapshotLimits(c *check.C) {
 	var dataRead int
 
-	defer daemon.MockSnapshotImport(func(ctx context.Context, st *state.State, r io.Reader) (uint64, []string, error) {
+	defer daemon.MockSnapshotImport(func(ctx context.Context, st *state.State, r io.Reader, key *client.SnapshotKey) (uint64, []string, error) {
 		data, err := io.ReadAll(r)
 		c.Assert(err, check.IsNil)
 		dataRead = len(data)
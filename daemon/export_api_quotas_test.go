@@ -20,6 +20,8 @@
 package daemon
 
 import (
+	"time"
+
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -61,3 +63,11 @@ func MockGetQuotaUsage(f func(grp *quota.Group) (*client.QuotaValues, error)) (r
 		getQuotaUsage = old
 	}
 }
+
+func MockServicestateQuotaUsage(f func(st *state.State, name string, since time.Time) []servicestate.QuotaUsageSample) (restore func()) {
+	old := servicestateQuotaUsage
+	servicestateQuotaUsage = f
+	return func() {
+		servicestateQuotaUsage = old
+	}
+}
@@ -40,6 +40,22 @@ func MockSnapshotSave(newSave func(*state.State, []string, []string, map[string]
 	}
 }
 
+func MockSnapshotSaveTo(newSave func(*state.State, []string, []string, map[string]*snap.SnapshotOptions, string) (uint64, []string, *state.TaskSet, error)) (restore func()) {
+	oldSave := snapshotSaveTo
+	snapshotSaveTo = newSave
+	return func() {
+		snapshotSaveTo = oldSave
+	}
+}
+
+func MockSnapshotFetch(newFetch func(context.Context, *state.State, string, uint64, *client.SnapshotKey) (uint64, []string, error)) (restore func()) {
+	oldFetch := snapshotFetch
+	snapshotFetch = newFetch
+	return func() {
+		snapshotFetch = oldFetch
+	}
+}
+
 func MockSnapshotList(newList func(context.Context, *state.State, uint64, []string) ([]client.SnapshotSet, error)) (restore func()) {
 	oldList := snapshotList
 	snapshotList = newList
@@ -48,7 +64,7 @@ func MockSnapshotList(newList func(context.Context, *state.State, uint64, []stri
 	}
 }
 
-func MockSnapshotExport(newExport func(context.Context, *state.State, uint64) (*snapshotstate.SnapshotExport, error)) (restore func()) {
+func MockSnapshotExport(newExport func(context.Context, *state.State, uint64, *client.SnapshotKey) (*snapshotstate.SnapshotExport, error)) (restore func()) {
 	oldExport := snapshotExport
 	snapshotExport = newExport
 	return func() {
@@ -56,7 +72,7 @@ func MockSnapshotExport(newExport func(context.Context, *state.State, uint64) (*
 	}
 }
 
-func MockSnapshotCheck(newCheck func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error)) (restore func()) {
+func MockSnapshotCheck(newCheck func(*state.State, uint64, []string, []string, *client.SnapshotKey) ([]string, *state.TaskSet, error)) (restore func()) {
 	oldCheck := snapshotCheck
 	snapshotCheck = newCheck
 	return func() {
@@ -64,7 +80,7 @@ func MockSnapshotCheck(newCheck func(*state.State, uint64, []string, []string) (
 	}
 }
 
-func MockSnapshotRestore(newRestore func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error)) (restore func()) {
+func MockSnapshotRestore(newRestore func(*state.State, uint64, []string, []string, *client.SnapshotKey) ([]string, *state.TaskSet, error)) (restore func()) {
 	oldRestore := snapshotRestore
 	snapshotRestore = newRestore
 	return func() {
@@ -80,7 +96,7 @@ func MockSnapshotForget(newForget func(*state.State, uint64, []string) ([]string
 	}
 }
 
-func MockSnapshotImport(newImport func(context.Context, *state.State, io.Reader) (uint64, []string, error)) (restore func()) {
+func MockSnapshotImport(newImport func(context.Context, *state.State, io.Reader, *client.SnapshotKey) (uint64, []string, error)) (restore func()) {
 	oldImport := snapshotImport
 	snapshotImport = newImport
 	return func() {
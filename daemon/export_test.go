@@ -126,6 +126,14 @@ func MockShutdownTimeout(tm time.Duration) (restore func()) {
 	}
 }
 
+func MockEventsBufferSize(size int) (restore func()) {
+	old := eventsBufferSize
+	eventsBufferSize = size
+	return func() {
+		eventsBufferSize = old
+	}
+}
+
 func MockUnsafeReadSnapInfo(mock func(string) (*snap.Info, error)) (restore func()) {
 	oldUnsafeReadSnapInfo := unsafeReadSnapInfo
 	unsafeReadSnapInfo = mock
@@ -134,6 +142,10 @@ func MockUnsafeReadSnapInfo(mock func(string) (*snap.Info, error)) (restore func
 	}
 }
 
+func MockSnapdeltaApply(f func(sourcePath, deltaPath, targetPath string) error) (restore func()) {
+	return testutil.Mock(&snapdeltaApply, f)
+}
+
 func MockReadComponentInfoFromCont(mock func(tempPath string, csi *snap.ComponentSideInfo) (*snap.ComponentInfo, error)) (restore func()) {
 	oldUnsafeReadSnapInfo := readComponentInfoFromCont
 	readComponentInfoFromCont = mock
@@ -168,6 +180,14 @@ func MockSnapstateUpdateWithGoal(mock func(ctx context.Context, st *state.State,
 	return testutil.Mock(&snapstateUpdateWithGoal, mock)
 }
 
+func MockSnapstatePlanUpdate(mock func(ctx context.Context, st *state.State, goal snapstate.UpdateGoal, opts snapstate.Options) (*snapstate.RefreshPlan, error)) (restore func()) {
+	return testutil.Mock(&snapstatePlanUpdate, mock)
+}
+
+func MockSnapstatePlanAutoRefresh(mock func(ctx context.Context, st *state.State) (*snapstate.RefreshPlan, error)) (restore func()) {
+	return testutil.Mock(&snapstatePlanAutoRefresh, mock)
+}
+
 func MockSnapstatePathUpdateGoal(mock func(snaps ...snapstate.PathSnap) snapstate.UpdateGoal) (restore func()) {
 	return testutil.Mock(&snapstatePathUpdateGoal, mock)
 }
@@ -391,6 +411,10 @@ func (inst *snapInstruction) ErrToResponse(err error) *APIError {
 	return inst.errToResponse(err)
 }
 
+func (inst *snapInstruction) Validate() error {
+	return inst.validate()
+}
+
 var (
 	UserFromRequest = userFromRequest
 	IsTrue          = isTrue
@@ -401,6 +425,22 @@ var (
 	MaxReadBuflen = maxReadBuflen
 )
 
+func MockConfdbstateHistory(f func(*state.State, string, string) ([]*confdbstate.HistoryEntry, error)) (restore func()) {
+	return testutil.Mock(&confdbstateHistory, f)
+}
+
+func MockConfdbstateGetTransactionToRevert(f func(*state.State, *confdb.View, int) (*confdbstate.Transaction, confdbstate.CommitTxFunc, error)) (restore func()) {
+	return testutil.Mock(&confdbstateGetTransactionToRevert, f)
+}
+
+func MockConfdbstateWatchView(f func(*state.State, *confdb.View, []string, func(*confdbstate.ViewChange)) (int, error)) (restore func()) {
+	return testutil.Mock(&confdbstateWatchView, f)
+}
+
+func MockConfdbstateUnwatchView(f func(*state.State, int)) (restore func()) {
+	return testutil.Mock(&confdbstateUnwatchView, f)
+}
+
 func MockConfdbstateGetTransaction(f func(*hookstate.Context, *state.State, *confdb.View) (*confdbstate.Transaction, confdbstate.CommitTxFunc, error)) (restore func()) {
 	return testutil.Mock(&confdbstateGetTransactionToSet, f)
 }
@@ -215,13 +215,18 @@ func clientHealthFromHealthstate(h *healthstate.HealthState) *client.SnapHealth
 	if h == nil {
 		return nil
 	}
-	return &client.SnapHealth{
+	health := &client.SnapHealth{
 		Revision:  h.Revision,
 		Timestamp: h.Timestamp,
 		Status:    h.Status.String(),
 		Message:   h.Message,
 		Code:      h.Code,
 	}
+	if !h.LastRun.IsZero() {
+		lastRun := h.LastRun
+		health.LastRun = &lastRun
+	}
+	return health
 }
 
 func clientSnapRefreshInhibit(st *state.State, snapst *snapstate.SnapState, instanceName string) *client.SnapRefreshInhibit {
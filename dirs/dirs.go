@@ -66,8 +66,9 @@ var (
 	SnapBootstrapRunDir  string
 	SnapVoidDir          string
 
-	SnapInterfacesRequestsRunDir   string
-	SnapInterfacesRequestsStateDir string
+	SnapInterfacesRequestsRunDir          string
+	SnapInterfacesRequestsStateDir        string
+	SnapInterfacesRequestsSystemRulesFile string
 
 	SnapdMaintenanceFile string
 
@@ -582,6 +583,7 @@ func SetRootDir(rootdir string) {
 
 	SnapInterfacesRequestsRunDir = filepath.Join(SnapRunDir, "interfaces-requests")
 	SnapInterfacesRequestsStateDir = filepath.Join(rootdir, snappyDir, "interfaces-requests")
+	SnapInterfacesRequestsSystemRulesFile = filepath.Join(rootdir, "/etc/snapd/interfaces-requests/system-rules.json")
 
 	SnapdStoreSSLCertsDir = filepath.Join(rootdir, snappyDir, "ssl/store-certs")
 
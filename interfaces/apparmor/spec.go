@@ -287,6 +287,21 @@ func MetadataTagSnippet(snippet string, tags []MetadataTag) string {
 	return b.String()
 }
 
+// PromptSnippet wraps the given AppArmor rule snippet, whose rules to be
+// prompted for are prefixed with ###PROMPT###, in the given metadata tags,
+// so that prompting requests triggered by those rules are attributed to the
+// interface the tags are registered with.
+//
+// If tagging is not supported, the requests could only be attributed to the
+// home interface, so the ###PROMPT### prefixes are dropped instead and the
+// rules are never prompted for.
+func PromptSnippet(snippet string, tags []MetadataTag) string {
+	if len(tags) == 0 || !metadataTagsSupported() {
+		return promptReplacer.ReplaceAllLiteralString(snippet, "")
+	}
+	return MetadataTagSnippet(snippet, tags)
+}
+
 // AddSnippet adds a new apparmor snippet to all applications and hooks using the interface.
 func (spec *Specification) AddSnippet(snippet string) {
 	if len(spec.securityTags) == 0 {
@@ -228,6 +228,36 @@ tags=(foo bar) {
 	}
 }
 
+func (s *specSuite) TestPromptSnippet(c *C) {
+	tag := apparmor.RegisterMetadataTagWithInterface("prompt-foo", "an-interface")
+
+	snippet := `/foo/ r,
+###PROMPT### /foo/** rw,
+###PROMPT### owner /bar/** r,`
+
+	restore := apparmor.MockMetadataTagsSupported(func() bool { return true })
+	defer restore()
+
+	c.Check(apparmor.PromptSnippet(snippet, []apparmor.MetadataTag{tag}), Equals, `
+tags=(prompt-foo) {
+/foo/ r,
+###PROMPT### /foo/** rw,
+###PROMPT### owner /bar/** r,
+}
+`)
+
+	// without tags the requests could not be attributed to the interface
+	unprompted := `/foo/ r,
+/foo/** rw,
+owner /bar/** r,`
+	c.Check(apparmor.PromptSnippet(snippet, nil), Equals, unprompted)
+
+	restore = apparmor.MockMetadataTagsSupported(func() bool { return false })
+	defer restore()
+
+	c.Check(apparmor.PromptSnippet(snippet, []apparmor.MetadataTag{tag}), Equals, unprompted)
+}
+
 // AddSnippet adds a snippet for the given security tag.
 func (s *specSuite) TestAddSnippet(c *C) {
 	restore := apparmor.SetSpecScope(s.spec, []string{"snap.demo.command", "snap.demo.service"})
@@ -22,6 +22,9 @@ package builtin
 import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
+	"github.com/snapcore/snapd/sandbox/apparmor/notify"
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -54,6 +57,19 @@ const audioRecordConnectedPlugAppArmor = `
 # interface is connected.
 `
 
+// When prompting is enabled, recording straight from the ALSA capture devices
+// is prompted for. The device cgroup must still allow the devices, e.g. via
+// the alsa interface.
+const audioRecordConnectedPlugAppArmorPrompt = `
+# Description: Can record from audio capture devices once allowed by the user.
+/dev/snd/ r,
+###PROMPT### /dev/snd/pcmC[0-9]*D[0-9]*c rw,
+`
+
+// audioRecordPromptTag attributes the prompting requests for recording from
+// capture devices to the audio-record interface.
+var audioRecordPromptTag = apparmor.RegisterMetadataTagWithInterface("snapd-audio-record", "audio-record")
+
 type audioRecordInterface struct{}
 
 func (iface *audioRecordInterface) Name() string {
@@ -70,6 +86,12 @@ func (iface *audioRecordInterface) StaticInfo() interfaces.StaticInfo {
 
 func (iface *audioRecordInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
 	spec.AddSnippet(audioRecordConnectedPlugAppArmor)
+	// The capture devices are not accessible otherwise, so only add the
+	// rules when the accesses are both prompted for and attributed to
+	// this interface.
+	if spec.UsePromptPrefix() && apparmor_sandbox.MetadataTagsSupported() {
+		spec.AddSnippet(apparmor.PromptSnippet(audioRecordConnectedPlugAppArmorPrompt, []apparmor.MetadataTag{audioRecordPromptTag}))
+	}
 	return nil
 }
 
@@ -79,4 +101,11 @@ func (iface *audioRecordInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) b
 
 func init() {
 	registerIface(&audioRecordInterface{})
+	prompting.RegisterInterface("audio-record", prompting.InterfaceDeclaration{
+		Permissions: []string{"access"},
+		FilePermissions: map[string]notify.FilePermission{
+			"access": prompting.DeviceAccessPermission,
+		},
+		PathPatterns: []string{"/dev/snd/pcmC*D*c"},
+	})
 }
@@ -25,7 +25,10 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/prompting"
 	"github.com/snapcore/snapd/release"
+	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
+	"github.com/snapcore/snapd/sandbox/apparmor/notify"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -134,6 +137,45 @@ func (s *AudioRecordInterfaceSuite) TestAppArmorOnClassic(c *C) {
 	c.Assert(spec.SecurityTags(), HasLen, 0)
 }
 
+func (s *AudioRecordInterfaceSuite) TestPromptingDeclaration(c *C) {
+	available, err := prompting.AvailablePermissions("audio-record")
+	c.Assert(err, IsNil)
+	c.Check(available, DeepEquals, []string{"access"})
+
+	perms, err := prompting.AbstractPermissionsFromAppArmorPermissions("audio-record", notify.AA_MAY_READ|notify.AA_MAY_WRITE)
+	c.Assert(err, IsNil)
+	c.Check(perms, DeepEquals, []string{"access"})
+
+	c.Check(prompting.ValidateInterfacePath("audio-record", "/dev/snd/pcmC0D0c"), IsNil)
+	// playback devices are not recording
+	c.Check(prompting.ValidateInterfacePath("audio-record", "/dev/snd/pcmC0D0p"), ErrorMatches,
+		`cannot prompt for path "/dev/snd/pcmC0D0p": not granted by the audio-record interface`)
+}
+
+func (s *AudioRecordInterfaceSuite) TestAppArmorPrompting(c *C) {
+	restore := apparmor_sandbox.MockFeatures([]string{"policy:notify:user:tags"}, nil, []string{"tags"}, nil)
+	defer restore()
+
+	backend := &apparmor.Backend{}
+	spec := backend.NewSpecification(s.plug.AppSet(), interfaces.ConfinementOptions{AppArmorPrompting: true}).(*apparmor.Specification)
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.classicSlot), IsNil)
+	snippet := spec.SnippetForTag("snap.consumer.app")
+	c.Check(snippet, testutil.Contains, "\ntags=(snapd-audio-record) {\n")
+	c.Check(snippet, testutil.Contains, "\n###PROMPT### /dev/snd/pcmC[0-9]*D[0-9]*c rw,\n")
+
+	// capture devices are not accessible without prompting
+	spec = backend.NewSpecification(s.plug.AppSet(), interfaces.ConfinementOptions{}).(*apparmor.Specification)
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.classicSlot), IsNil)
+	c.Check(spec.SnippetForTag("snap.consumer.app"), Not(testutil.Contains), "/dev/snd/")
+
+	// nor when the requests cannot be attributed to the interface
+	restore = apparmor_sandbox.MockFeatures(nil, nil, nil, nil)
+	defer restore()
+	spec = backend.NewSpecification(s.plug.AppSet(), interfaces.ConfinementOptions{AppArmorPrompting: true}).(*apparmor.Specification)
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.classicSlot), IsNil)
+	c.Check(spec.SnippetForTag("snap.consumer.app"), Not(testutil.Contains), "/dev/snd/")
+}
+
 func (s *AudioRecordInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
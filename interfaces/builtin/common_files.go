@@ -36,6 +36,9 @@ type commonFilesInterface struct {
 
 	apparmorHeader    string
 	extraPathValidate func(string) error
+	// promptTag, when set, makes the accesses to the paths prompted for
+	// and attributed to the interface the tag is registered with
+	promptTag *apparmor.MetadataTag
 }
 
 // filesAAPerm can either be files{Read,Write} and converted to a string
@@ -76,12 +79,15 @@ func formatPath(ip any) (string, error) {
 	return fmt.Sprintf("%s%q", prefix, p), nil
 }
 
-func allowPathAccess(buf *bytes.Buffer, perm filesAAPerm, paths []any) error {
+func allowPathAccess(buf *bytes.Buffer, perm filesAAPerm, paths []any, prompt bool) error {
 	for _, rawPath := range paths {
 		p, err := formatPath(rawPath)
 		if err != nil {
 			return err
 		}
+		if prompt {
+			buf.WriteString("###PROMPT### ")
+		}
 		fmt.Fprintf(buf, "%s %s,\n", p, perm)
 	}
 	return nil
@@ -161,14 +167,19 @@ func (iface *commonFilesInterface) AppArmorConnectedPlug(spec *apparmor.Specific
 	_ = plug.Attr("write", &writes)
 
 	errPrefix := fmt.Sprintf(`cannot connect plug %s: `, plug.Name())
+	prompt := iface.promptTag != nil
 	buf := bytes.NewBufferString(iface.apparmorHeader)
-	if err := allowPathAccess(buf, filesRead, reads); err != nil {
+	if err := allowPathAccess(buf, filesRead, reads, prompt); err != nil {
 		return fmt.Errorf("%s%v", errPrefix, err)
 	}
-	if err := allowPathAccess(buf, filesWrite, writes); err != nil {
+	if err := allowPathAccess(buf, filesWrite, writes, prompt); err != nil {
 		return fmt.Errorf("%s%v", errPrefix, err)
 	}
-	spec.AddSnippet(buf.String())
+	if prompt {
+		spec.AddSnippet(apparmor.PromptSnippet(buf.String(), []apparmor.MetadataTag{*iface.promptTag}))
+	} else {
+		spec.AddSnippet(buf.String())
+	}
 
 	return nil
 }
@@ -27,6 +27,8 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	"github.com/snapcore/snapd/sandbox/apparmor/notify"
 )
 
 const personalFilesSummary = `allows access to personal files or directories`
@@ -51,6 +53,10 @@ const personalFilesConnectedPlugAppArmor = `
 # This is restricted because it gives file access to arbitrary locations.
 `
 
+// personalFilesPromptTag attributes the prompting requests for accesses to
+// personal files to the personal-files interface.
+var personalFilesPromptTag = apparmor.RegisterMetadataTagWithInterface("snapd-personal-files", "personal-files")
+
 type personalFilesInterface struct {
 	commonFilesInterface
 }
@@ -78,6 +84,16 @@ func init() {
 			},
 			apparmorHeader:    personalFilesConnectedPlugAppArmor,
 			extraPathValidate: validateSinglePathHome,
+			promptTag:         &personalFilesPromptTag,
+		},
+	})
+	// the paths are declared by the plugs, so they are only restricted by
+	// the AppArmor rules of the connections
+	prompting.RegisterInterface("personal-files", prompting.InterfaceDeclaration{
+		Permissions: []string{"read", "write"},
+		FilePermissions: map[string]notify.FilePermission{
+			"read":  prompting.FileReadPermission,
+			"write": prompting.FileWritePermission,
 		},
 	})
 }
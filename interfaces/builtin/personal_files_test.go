@@ -28,7 +28,10 @@ import (
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
 	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/prompting"
 	"github.com/snapcore/snapd/osutil"
+	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
+	"github.com/snapcore/snapd/sandbox/apparmor/notify"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
@@ -74,6 +77,9 @@ func (s *personalFilesInterfaceSuite) TestName(c *C) {
 }
 
 func (s *personalFilesInterfaceSuite) TestConnectedPlugAppArmorHappy(c *C) {
+	restore := apparmor_sandbox.MockFeatures(nil, nil, nil, nil)
+	defer restore()
+
 	apparmorSpec := apparmor.NewSpecification(s.plug.AppSet())
 	err := apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
 	c.Assert(err, IsNil)
@@ -100,6 +106,48 @@ owner "@{HOME}/.local/share/dir1/dir2/target{,/,/**}" rwkl,
   owner @{HOME}/.local/share/dir1/dir2/ rw,`)
 }
 
+func (s *personalFilesInterfaceSuite) TestPromptingDeclaration(c *C) {
+	available, err := prompting.AvailablePermissions("personal-files")
+	c.Assert(err, IsNil)
+	c.Check(available, DeepEquals, []string{"read", "write"})
+
+	perms, err := prompting.AbstractPermissionsFromAppArmorPermissions("personal-files", notify.AA_MAY_READ|notify.AA_MAY_CREATE)
+	c.Assert(err, IsNil)
+	c.Check(perms, DeepEquals, []string{"read", "write"})
+
+	// the paths are restricted by the AppArmor rules of the plugs
+	c.Check(prompting.ValidateInterfacePath("personal-files", "/home/user/.read-dir/foo"), IsNil)
+}
+
+func (s *personalFilesInterfaceSuite) TestConnectedPlugAppArmorPromptMetadataTags(c *C) {
+	restore := apparmor_sandbox.MockFeatures([]string{"policy:notify:user:tags"}, nil, []string{"tags"}, nil)
+	defer restore()
+
+	apparmorSpec := apparmor.NewSpecification(s.plug.AppSet())
+	err := apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Check(apparmorSpec.SnippetForTag("snap.other.app"), Equals, `
+tags=(snapd-personal-files) {
+
+# Description: Can access specific personal files or directories in the 
+# users's home directory.
+# This is restricted because it gives file access to arbitrary locations.
+###PROMPT### owner "@{HOME}/.read-dir{,/,/**}" rk,
+###PROMPT### owner "@{HOME}/.read-file{,/,/**}" rk,
+###PROMPT### owner "@{HOME}/.local/share/target{,/,/**}" rk,
+###PROMPT### owner "@{HOME}/.write-dir{,/,/**}" rwkl,
+###PROMPT### owner "@{HOME}/.write-file{,/,/**}" rwkl,
+###PROMPT### owner "@{HOME}/.local/share/target{,/,/**}" rwkl,
+###PROMPT### owner "@{HOME}/.local/share/dir1/dir2/target{,/,/**}" rwkl,
+
+}
+`)
+
+	iface, ok := apparmor.InterfaceForMetadataTag("snapd-personal-files")
+	c.Check(ok, Equals, true)
+	c.Check(iface, Equals, "personal-files")
+}
+
 func (s *personalFilesInterfaceSuite) TestConnectedPlugApparmorErrorNotString(c *C) {
 	const mockPlugSnapInfo = `name: other
 version: 1.0
@@ -19,6 +19,13 @@
 
 package builtin
 
+import (
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	"github.com/snapcore/snapd/sandbox/apparmor/notify"
+)
+
 const removableMediaSummary = `allows access to mounted removable storage`
 
 const removableMediaBaseDeclarationSlots = `
@@ -41,21 +48,47 @@ const removableMediaConnectedPlugAppArmor = `
 
 # Mount points could be in /run/media/<user>/* or /media/<user>/*
 /{,run/}media/*/ r,
-/{,run/}media/*/** mrwklix,
+###PROMPT### /{,run/}media/*/** mrwklix,
 
 # Allow read-only access to /mnt to enumerate items.
 /mnt/ r,
 # Allow write access to anything under /mnt
-/mnt/** mrwklix,
+###PROMPT### /mnt/** mrwklix,
 `
 
+// removableMediaPromptTag attributes the prompting requests for accesses to
+// removable media to the removable-media interface.
+var removableMediaPromptTag = apparmor.RegisterMetadataTagWithInterface("snapd-removable-media", "removable-media")
+
+type removableMediaInterface struct {
+	commonInterface
+}
+
+func (iface *removableMediaInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if err := iface.commonInterface.AppArmorConnectedPlug(spec, plug, slot); err != nil {
+		return err
+	}
+	spec.AddSnippet(apparmor.PromptSnippet(removableMediaConnectedPlugAppArmor, []apparmor.MetadataTag{removableMediaPromptTag}))
+	return nil
+}
+
 func init() {
-	registerIface(&commonInterface{
-		name:                  "removable-media",
-		summary:               removableMediaSummary,
-		implicitOnCore:        true,
-		implicitOnClassic:     true,
-		baseDeclarationSlots:  removableMediaBaseDeclarationSlots,
-		connectedPlugAppArmor: removableMediaConnectedPlugAppArmor,
+	registerIface(&removableMediaInterface{
+		commonInterface{
+			name:                 "removable-media",
+			summary:              removableMediaSummary,
+			implicitOnCore:       true,
+			implicitOnClassic:    true,
+			baseDeclarationSlots: removableMediaBaseDeclarationSlots,
+		},
+	})
+	prompting.RegisterInterface("removable-media", prompting.InterfaceDeclaration{
+		Permissions: []string{"read", "write", "execute"},
+		FilePermissions: map[string]notify.FilePermission{
+			"read":    prompting.FileReadPermission,
+			"write":   prompting.FileWritePermission,
+			"execute": prompting.FileExecutePermission,
+		},
+		PathPatterns: []string{"/media/*/**", "/run/media/*/**", "/mnt/**"},
 	})
 }
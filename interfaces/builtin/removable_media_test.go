@@ -25,6 +25,9 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
+	"github.com/snapcore/snapd/sandbox/apparmor/notify"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -82,6 +85,51 @@ func (s *RemovableMediaInterfaceSuite) TestUsedSecuritySystems(c *C) {
 	c.Check(apparmorSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "/mnt/** mrwklix,")
 }
 
+func (s *RemovableMediaInterfaceSuite) TestPromptingDeclaration(c *C) {
+	available, err := prompting.AvailablePermissions("removable-media")
+	c.Assert(err, IsNil)
+	c.Check(available, DeepEquals, []string{"read", "write", "execute"})
+
+	perms, err := prompting.AbstractPermissionsFromAppArmorPermissions("removable-media", notify.AA_MAY_EXEC|notify.AA_MAY_WRITE|notify.AA_MAY_READ)
+	c.Assert(err, IsNil)
+	c.Check(perms, DeepEquals, []string{"read", "write", "execute"})
+
+	for _, path := range []string{"/media/user/usb/foo", "/run/media/user/usb/foo", "/mnt/foo/bar"} {
+		c.Check(prompting.ValidateInterfacePath("removable-media", path), IsNil, Commentf("%s", path))
+	}
+	c.Check(prompting.ValidateInterfacePath("removable-media", "/home/user/foo"), ErrorMatches,
+		`cannot prompt for path "/home/user/foo": not granted by the removable-media interface`)
+}
+
+func (s *RemovableMediaInterfaceSuite) TestAppArmorPromptMetadataTags(c *C) {
+	restore := apparmor_sandbox.MockFeatures([]string{"policy:notify:user:tags"}, nil, []string{"tags"}, nil)
+	defer restore()
+
+	apparmorSpec := apparmor.NewSpecification(s.plug.AppSet())
+	err := apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	snippet := apparmorSpec.SnippetForTag("snap.client-snap.other")
+	c.Check(snippet, testutil.Contains, "\ntags=(snapd-removable-media) {\n")
+	c.Check(snippet, testutil.Contains, "\n###PROMPT### /{,run/}media/*/** mrwklix,\n")
+	c.Check(snippet, testutil.Contains, "\n###PROMPT### /mnt/** mrwklix,\n")
+
+	iface, ok := apparmor.InterfaceForMetadataTag("snapd-removable-media")
+	c.Check(ok, Equals, true)
+	c.Check(iface, Equals, "removable-media")
+
+	// without metadata tags the accesses are not prompted for
+	restore = apparmor_sandbox.MockFeatures(nil, nil, nil, nil)
+	defer restore()
+
+	apparmorSpec = apparmor.NewSpecification(s.plug.AppSet())
+	err = apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	snippet = apparmorSpec.SnippetForTag("snap.client-snap.other")
+	c.Check(snippet, Not(testutil.Contains), "###PROMPT###")
+	c.Check(snippet, Not(testutil.Contains), "tags=")
+	c.Check(snippet, testutil.Contains, "\n/mnt/** mrwklix,\n")
+}
+
 func (s *RemovableMediaInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
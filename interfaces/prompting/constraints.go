@@ -536,9 +536,22 @@ func (e *RulePermissionEntry) Supersedes(other *RulePermissionEntry, currSession
 	return false
 }
 
+// AppArmor file permissions of the abstract permissions shared by the
+// interfaces granting access to files and directories.
+const (
+	FileReadPermission    = notify.AA_MAY_READ | notify.AA_MAY_GETATTR
+	FileWritePermission   = notify.AA_MAY_WRITE | notify.AA_MAY_APPEND | notify.AA_MAY_CREATE | notify.AA_MAY_DELETE | notify.AA_MAY_RENAME | notify.AA_MAY_SETATTR | notify.AA_MAY_CHMOD | notify.AA_MAY_LOCK | notify.AA_MAY_LINK
+	FileExecutePermission = notify.AA_MAY_EXEC | notify.AA_EXEC_MMAP
+	// DeviceAccessPermission holds the AppArmor file permissions of
+	// interfaces granting access to devices as a whole, such as cameras or
+	// audio capture devices.
+	DeviceAccessPermission = notify.AA_MAY_READ | notify.AA_MAY_GETATTR | notify.AA_MAY_WRITE | notify.AA_MAY_APPEND
+)
+
 var (
 	// List of permissions available for each interface. This also defines the
-	// order in which the permissions should be presented.
+	// order in which the permissions should be presented. Interfaces other
+	// than the ones below are added with RegisterInterface.
 	interfacePermissionsAvailable = map[string][]string{
 		"home":   {"read", "write", "execute"},
 		"camera": {"access"},
@@ -552,12 +565,12 @@ var (
 	// and if it does not, it should be interpreted as AA_MAY_READ.
 	interfaceFilePermissionsMaps = map[string]map[string]notify.FilePermission{
 		"home": {
-			"read":    notify.AA_MAY_READ | notify.AA_MAY_GETATTR,
-			"write":   notify.AA_MAY_WRITE | notify.AA_MAY_APPEND | notify.AA_MAY_CREATE | notify.AA_MAY_DELETE | notify.AA_MAY_RENAME | notify.AA_MAY_SETATTR | notify.AA_MAY_CHMOD | notify.AA_MAY_LOCK | notify.AA_MAY_LINK,
-			"execute": notify.AA_MAY_EXEC | notify.AA_EXEC_MMAP,
+			"read":    FileReadPermission,
+			"write":   FileWritePermission,
+			"execute": FileExecutePermission,
 		},
 		"camera": {
-			"access": notify.AA_MAY_READ | notify.AA_MAY_GETATTR | notify.AA_MAY_WRITE | notify.AA_MAY_APPEND,
+			"access": DeviceAccessPermission,
 		},
 	}
 )
@@ -20,6 +20,8 @@
 package prompting
 
 import (
+	"github.com/snapcore/snapd/interfaces/prompting/patterns"
+	"github.com/snapcore/snapd/sandbox/apparmor/notify"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -31,3 +33,28 @@ var (
 func MockApparmorInterfaceForMetadataTag(f func(tag string) (string, bool)) (restore func()) {
 	return testutil.Mock(&apparmorInterfaceForMetadataTag, f)
 }
+
+// MockRegisteredInterfaces makes interfaces registered until restore is called
+// to be forgotten again.
+func MockRegisteredInterfaces() (restore func()) {
+	oldPermissions := interfacePermissionsAvailable
+	oldFilePermissions := interfaceFilePermissionsMaps
+	oldPathPatterns := interfacePathPatterns
+	interfacePermissionsAvailable = make(map[string][]string, len(oldPermissions))
+	for iface, perms := range oldPermissions {
+		interfacePermissionsAvailable[iface] = perms
+	}
+	interfaceFilePermissionsMaps = make(map[string]map[string]notify.FilePermission, len(oldFilePermissions))
+	for iface, perms := range oldFilePermissions {
+		interfaceFilePermissionsMaps[iface] = perms
+	}
+	interfacePathPatterns = make(map[string][]*patterns.PathPattern, len(oldPathPatterns))
+	for iface, pathPatterns := range oldPathPatterns {
+		interfacePathPatterns[iface] = pathPatterns
+	}
+	return func() {
+		interfacePermissionsAvailable = oldPermissions
+		interfaceFilePermissionsMaps = oldFilePermissions
+		interfacePathPatterns = oldPathPatterns
+	}
+}
@@ -0,0 +1,109 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prompting
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/interfaces/prompting/patterns"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/sandbox/apparmor/notify"
+)
+
+// InterfaceDeclaration declares how the accesses granted by an interface are
+// prompted for.
+type InterfaceDeclaration struct {
+	// Permissions is the list of abstract permissions of the interface, in
+	// the order in which they should be presented.
+	Permissions []string
+	// FilePermissions maps each of the abstract permissions to the AppArmor
+	// file permissions it covers. The file permissions of different
+	// abstract permissions must not overlap, and never include AA_MAY_OPEN.
+	FilePermissions map[string]notify.FilePermission
+	// PathPatterns match the paths the interface grants access to. Requests
+	// attributed to the interface for other paths are never prompted for.
+	// When empty, the paths are only restricted by the AppArmor rules of the
+	// interface, e.g. because they depend on the plug attributes.
+	PathPatterns []string
+}
+
+// interfacePathPatterns holds the parsed path patterns of the interfaces
+// which declared some.
+var interfacePathPatterns = make(map[string][]*patterns.PathPattern)
+
+// RegisterInterface declares that the accesses granted by the given interface
+// can be prompted for. If the declaration is invalid or the interface is
+// already registered, this function panics.
+func RegisterInterface(iface string, decl InterfaceDeclaration) {
+	if _, ok := interfacePermissionsAvailable[iface]; ok {
+		logger.Panicf("cannot register prompting interface %q twice", iface)
+	}
+	if len(decl.Permissions) == 0 {
+		logger.Panicf("cannot register prompting interface %q without permissions", iface)
+	}
+	if len(decl.FilePermissions) != len(decl.Permissions) {
+		logger.Panicf("cannot register prompting interface %q: permissions and file permissions do not match", iface)
+	}
+	seen := notify.FilePermission(0)
+	for _, perm := range decl.Permissions {
+		mask, ok := decl.FilePermissions[perm]
+		if !ok {
+			logger.Panicf("cannot register prompting interface %q: no file permissions for permission %q", iface, perm)
+		}
+		if mask&notify.AA_MAY_OPEN != 0 || mask&seen != 0 {
+			logger.Panicf("cannot register prompting interface %q: invalid file permissions for permission %q: %s", iface, perm, mask)
+		}
+		seen |= mask
+	}
+	pathPatterns := make([]*patterns.PathPattern, 0, len(decl.PathPatterns))
+	for _, pattern := range decl.PathPatterns {
+		pathPattern, err := patterns.ParsePathPattern(pattern)
+		if err != nil {
+			logger.Panicf("cannot register prompting interface %q: %v", iface, err)
+		}
+		pathPatterns = append(pathPatterns, pathPattern)
+	}
+
+	interfacePermissionsAvailable[iface] = decl.Permissions
+	interfaceFilePermissionsMaps[iface] = decl.FilePermissions
+	if len(pathPatterns) != 0 {
+		interfacePathPatterns[iface] = pathPatterns
+	}
+}
+
+// ValidateInterfacePath checks that the given interface grants access to the
+// given path, according to the path patterns it was registered with. Paths
+// of interfaces without path patterns are always valid.
+func ValidateInterfacePath(iface string, path string) error {
+	pathPatterns := interfacePathPatterns[iface]
+	if len(pathPatterns) == 0 {
+		return nil
+	}
+	for _, pathPattern := range pathPatterns {
+		match, err := pathPattern.Match(path)
+		if err != nil {
+			return err
+		}
+		if match {
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot prompt for path %q: not granted by the %s interface", path, iface)
+}
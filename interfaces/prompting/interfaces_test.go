@@ -0,0 +1,135 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prompting_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+	"github.com/snapcore/snapd/sandbox/apparmor/notify"
+)
+
+type interfacesSuite struct{}
+
+var _ = Suite(&interfacesSuite{})
+
+func (*interfacesSuite) TestRegisterInterface(c *C) {
+	restore := prompting.MockRegisteredInterfaces()
+	defer restore()
+
+	prompting.RegisterInterface("media", prompting.InterfaceDeclaration{
+		Permissions: []string{"read", "write"},
+		FilePermissions: map[string]notify.FilePermission{
+			"read":  prompting.FileReadPermission,
+			"write": prompting.FileWritePermission,
+		},
+		PathPatterns: []string{"/media/*/**", "/mnt/**"},
+	})
+
+	available, err := prompting.AvailablePermissions("media")
+	c.Assert(err, IsNil)
+	c.Check(available, DeepEquals, []string{"read", "write"})
+
+	perms, err := prompting.AbstractPermissionsFromAppArmorPermissions("media", notify.AA_MAY_READ|notify.AA_MAY_CREATE)
+	c.Assert(err, IsNil)
+	c.Check(perms, DeepEquals, []string{"read", "write"})
+	aaPerms, err := prompting.AbstractPermissionsToAppArmorPermissions("media", []string{"read"})
+	c.Assert(err, IsNil)
+	c.Check(aaPerms, Equals, prompting.FileReadPermission|notify.AA_MAY_OPEN)
+
+	c.Check(prompting.ValidateInterfacePath("media", "/media/test/usb/foo"), IsNil)
+	c.Check(prompting.ValidateInterfacePath("media", "/mnt/foo"), IsNil)
+	c.Check(prompting.ValidateInterfacePath("media", "/home/test/foo"), ErrorMatches,
+		`cannot prompt for path "/home/test/foo": not granted by the media interface`)
+
+	// interfaces without path patterns accept any path
+	c.Check(prompting.ValidateInterfacePath("home", "/mnt/foo"), IsNil)
+}
+
+func (*interfacesSuite) TestRegisterInterfaceInvalid(c *C) {
+	restore := prompting.MockRegisteredInterfaces()
+	defer restore()
+
+	for _, testCase := range []struct {
+		iface string
+		decl  prompting.InterfaceDeclaration
+		err   string
+	}{
+		{
+			iface: "home",
+			decl: prompting.InterfaceDeclaration{
+				Permissions:     []string{"read"},
+				FilePermissions: map[string]notify.FilePermission{"read": prompting.FileReadPermission},
+			},
+			err: `cannot register prompting interface "home" twice`,
+		},
+		{
+			iface: "foo",
+			err:   `cannot register prompting interface "foo" without permissions`,
+		},
+		{
+			iface: "foo",
+			decl: prompting.InterfaceDeclaration{
+				Permissions: []string{"read", "write"},
+				FilePermissions: map[string]notify.FilePermission{
+					"read":    prompting.FileReadPermission,
+					"execute": prompting.FileExecutePermission,
+				},
+			},
+			err: `cannot register prompting interface "foo": no file permissions for permission "write"`,
+		},
+		{
+			iface: "foo",
+			decl: prompting.InterfaceDeclaration{
+				Permissions: []string{"read", "access"},
+				FilePermissions: map[string]notify.FilePermission{
+					"read":   prompting.FileReadPermission,
+					"access": prompting.DeviceAccessPermission,
+				},
+			},
+			err: `cannot register prompting interface "foo": invalid file permissions for permission "access": .*`,
+		},
+		{
+			iface: "foo",
+			decl: prompting.InterfaceDeclaration{
+				Permissions: []string{"read"},
+				FilePermissions: map[string]notify.FilePermission{
+					"read": notify.AA_MAY_READ | notify.AA_MAY_OPEN,
+				},
+			},
+			err: `cannot register prompting interface "foo": invalid file permissions for permission "read": .*`,
+		},
+		{
+			iface: "foo",
+			decl: prompting.InterfaceDeclaration{
+				Permissions: []string{"read"},
+				FilePermissions: map[string]notify.FilePermission{
+					"read": prompting.FileReadPermission,
+				},
+				PathPatterns: []string{"foo/**"},
+			},
+			err: `cannot register prompting interface "foo": invalid path pattern.*`,
+		},
+	} {
+		c.Check(func() { prompting.RegisterInterface(testCase.iface, testCase.decl) }, PanicMatches, testCase.err)
+	}
+	_, err := prompting.AvailablePermissions("foo")
+	c.Check(err, NotNil)
+}
@@ -0,0 +1,221 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package requestaudit provides an append-only log of the decisions made for
+// the requests of snaps through interfaces for which prompting is enabled.
+package requestaudit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	"github.com/snapcore/snapd/logger"
+)
+
+// maxLogSize is the size above which the audit log file is rotated. At most
+// one rotated file is kept, so the audit log never takes more than twice
+// this size on disk.
+var maxLogSize int64 = 4 * 1024 * 1024
+
+// SourceType describes what caused a decision to be made for a request.
+type SourceType string
+
+const (
+	// SourceRule indicates that the request was decided by existing rules,
+	// either when it was received or when a new rule was added while it was
+	// waiting for a reply.
+	SourceRule SourceType = "rule"
+	// SourceReply indicates that the request was decided by a reply of the
+	// user to a prompt.
+	SourceReply SourceType = "reply"
+	// SourceTimeout indicates that the request was denied because the prompt
+	// for it expired before the user replied.
+	SourceTimeout SourceType = "timeout"
+)
+
+// Entry is a single decision recorded in the audit log.
+type Entry struct {
+	Timestamp   time.Time             `json:"timestamp"`
+	User        uint32                `json:"user"`
+	Snap        string                `json:"snap"`
+	Interface   string                `json:"interface"`
+	Path        string                `json:"path"`
+	Permissions []string              `json:"permissions"`
+	Outcome     prompting.OutcomeType `json:"outcome"`
+	Source      SourceType            `json:"source"`
+	// RuleIDs are the IDs of the rules which decided the request, if the
+	// source is SourceRule.
+	RuleIDs []prompting.IDType `json:"rule-ids,omitempty"`
+	// PromptID is the ID of the prompt which was created for the request, if
+	// any.
+	PromptID prompting.IDType `json:"prompt-id,omitempty"`
+}
+
+// Filter selects the entries returned by Log.Entries. Zero values match any
+// entry, except for User, which only matches entries of that user unless
+// AllUsers is set.
+type Filter struct {
+	User      uint32
+	AllUsers  bool
+	Snap      string
+	Interface string
+	Source    SourceType
+	Outcome   prompting.OutcomeType
+	// Since only matches entries recorded after the given time.
+	Since time.Time
+	// Limit restricts the result to the most recent matching entries.
+	Limit int
+}
+
+func (f *Filter) matches(entry *Entry) bool {
+	switch {
+	case !f.AllUsers && entry.User != f.User:
+		return false
+	case f.Snap != "" && entry.Snap != f.Snap:
+		return false
+	case f.Interface != "" && entry.Interface != f.Interface:
+		return false
+	case f.Source != "" && entry.Source != f.Source:
+		return false
+	case f.Outcome != prompting.OutcomeUnset && entry.Outcome != f.Outcome:
+		return false
+	case !f.Since.IsZero() && !entry.Timestamp.After(f.Since):
+		return false
+	}
+	return true
+}
+
+// Log is the audit log of prompting decisions.
+//
+// Entries are stored as one JSON object per line in a file which is only
+// readable by root. When the file grows beyond a fixed size, it is rotated
+// and the previously rotated file is discarded.
+type Log struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// New returns the audit log stored in the interfaces requests state directory.
+func New() (*Log, error) {
+	if err := os.MkdirAll(dirs.SnapInterfacesRequestsStateDir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create interfaces requests state directory: %w", err)
+	}
+	return &Log{
+		path: filepath.Join(dirs.SnapInterfacesRequestsStateDir, "audit.log"),
+	}, nil
+}
+
+func (l *Log) rotatedPath() string {
+	return l.path + ".1"
+}
+
+// Record appends the given entry to the audit log. If the entry timestamp is
+// not set, it is set to the current time.
+func (l *Log) Record(entry *Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if fi, err := os.Stat(l.path); err == nil && fi.Size()+int64(len(line)) > maxLogSize {
+		if err := os.Rename(l.path, l.rotatedPath()); err != nil {
+			return fmt.Errorf("cannot rotate audit log: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot open audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("cannot write to audit log: %w", err)
+	}
+	return nil
+}
+
+// Entries returns the entries of the audit log which match the given filter,
+// from the oldest to the most recent.
+func (l *Log) Entries(filter *Filter) ([]*Entry, error) {
+	if filter == nil {
+		filter = &Filter{AllUsers: true}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var entries []*Entry
+	for _, path := range []string{l.rotatedPath(), l.path} {
+		fileEntries, err := readEntries(path, filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+func readEntries(path string, filter *Filter) ([]*Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []*Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// The last line may be truncated if snapd was interrupted while
+			// writing it, so skip malformed entries rather than failing.
+			logger.Debugf("cannot decode audit log entry: %v", err)
+			continue
+		}
+		if filter.matches(&entry) {
+			entries = append(entries, &entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read audit log: %w", err)
+	}
+	return entries, nil
+}
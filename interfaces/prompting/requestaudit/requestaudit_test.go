@@ -0,0 +1,206 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package requestaudit_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	"github.com/snapcore/snapd/interfaces/prompting/requestaudit"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type requestauditSuite struct {
+	logPath string
+}
+
+var _ = Suite(&requestauditSuite{})
+
+func (s *requestauditSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	s.logPath = filepath.Join(dirs.SnapInterfacesRequestsStateDir, "audit.log")
+}
+
+func (s *requestauditSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+}
+
+func (s *requestauditSuite) TestRecordEntries(c *C) {
+	auditLog, err := requestaudit.New()
+	c.Assert(err, IsNil)
+
+	entries, err := auditLog.Entries(nil)
+	c.Assert(err, IsNil)
+	c.Check(entries, HasLen, 0)
+
+	before := time.Now()
+	ruleEntry := &requestaudit.Entry{
+		User:        1000,
+		Snap:        "firefox",
+		Interface:   "home",
+		Path:        "/home/test/foo",
+		Permissions: []string{"read"},
+		Outcome:     prompting.OutcomeAllow,
+		Source:      requestaudit.SourceRule,
+		RuleIDs:     []prompting.IDType{3},
+	}
+	c.Assert(auditLog.Record(ruleEntry), IsNil)
+	c.Check(ruleEntry.Timestamp.After(before), Equals, true)
+
+	replyEntry := &requestaudit.Entry{
+		Timestamp:   time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		User:        1001,
+		Snap:        "thunderbird",
+		Interface:   "camera",
+		Path:        "/dev/video0",
+		Permissions: []string{"access"},
+		Outcome:     prompting.OutcomeDeny,
+		Source:      requestaudit.SourceReply,
+		PromptID:    5,
+	}
+	c.Assert(auditLog.Record(replyEntry), IsNil)
+
+	fi, err := os.Stat(s.logPath)
+	c.Assert(err, IsNil)
+	c.Check(fi.Mode().Perm(), Equals, os.FileMode(0o600))
+
+	// Entries are read back from disk by a new log
+	auditLog, err = requestaudit.New()
+	c.Assert(err, IsNil)
+	entries, err = auditLog.Entries(nil)
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 2)
+	c.Check(entries[0].Timestamp.Equal(ruleEntry.Timestamp), Equals, true)
+	entries[0].Timestamp = ruleEntry.Timestamp
+	c.Check(entries, DeepEquals, []*requestaudit.Entry{ruleEntry, replyEntry})
+}
+
+func (s *requestauditSuite) TestEntriesFilter(c *C) {
+	auditLog, err := requestaudit.New()
+	c.Assert(err, IsNil)
+
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, entry := range []*requestaudit.Entry{
+		{User: 1000, Snap: "firefox", Interface: "home", Outcome: prompting.OutcomeAllow, Source: requestaudit.SourceRule},
+		{User: 1000, Snap: "firefox", Interface: "camera", Outcome: prompting.OutcomeDeny, Source: requestaudit.SourceTimeout},
+		{User: 1001, Snap: "firefox", Interface: "home", Outcome: prompting.OutcomeDeny, Source: requestaudit.SourceReply},
+		{User: 1000, Snap: "thunderbird", Interface: "home", Outcome: prompting.OutcomeAllow, Source: requestaudit.SourceReply},
+	} {
+		entry.Timestamp = start.Add(time.Duration(i) * time.Minute)
+		entry.Path = "/home/test/foo"
+		entry.Permissions = []string{"read"}
+		c.Assert(auditLog.Record(entry), IsNil)
+	}
+
+	for _, testCase := range []struct {
+		filter   *requestaudit.Filter
+		expected []string
+	}{
+		{&requestaudit.Filter{AllUsers: true}, []string{"firefox/home", "firefox/camera", "firefox/home", "thunderbird/home"}},
+		{&requestaudit.Filter{User: 1000}, []string{"firefox/home", "firefox/camera", "thunderbird/home"}},
+		{&requestaudit.Filter{User: 1001}, []string{"firefox/home"}},
+		{&requestaudit.Filter{User: 1002}, nil},
+		{&requestaudit.Filter{User: 1000, Snap: "firefox"}, []string{"firefox/home", "firefox/camera"}},
+		{&requestaudit.Filter{AllUsers: true, Interface: "home"}, []string{"firefox/home", "firefox/home", "thunderbird/home"}},
+		{&requestaudit.Filter{AllUsers: true, Source: requestaudit.SourceReply}, []string{"firefox/home", "thunderbird/home"}},
+		{&requestaudit.Filter{User: 1000, Outcome: prompting.OutcomeDeny}, []string{"firefox/camera"}},
+		{&requestaudit.Filter{AllUsers: true, Since: start.Add(time.Minute)}, []string{"firefox/home", "thunderbird/home"}},
+		{&requestaudit.Filter{User: 1000, Limit: 2}, []string{"firefox/camera", "thunderbird/home"}},
+	} {
+		entries, err := auditLog.Entries(testCase.filter)
+		c.Assert(err, IsNil)
+		var got []string
+		for _, entry := range entries {
+			got = append(got, entry.Snap+"/"+entry.Interface)
+		}
+		c.Check(got, DeepEquals, testCase.expected, Commentf("filter: %+v", testCase.filter))
+	}
+}
+
+func (s *requestauditSuite) TestRotation(c *C) {
+	restore := requestaudit.MockMaxLogSize(1024)
+	defer restore()
+
+	auditLog, err := requestaudit.New()
+	c.Assert(err, IsNil)
+
+	const count = 40
+	for i := 0; i < count; i++ {
+		entry := &requestaudit.Entry{
+			User:        1000,
+			Snap:        "firefox",
+			Interface:   "home",
+			Path:        "/home/test/foo",
+			Permissions: []string{"read"},
+			Outcome:     prompting.OutcomeAllow,
+			Source:      requestaudit.SourceRule,
+			RuleIDs:     []prompting.IDType{prompting.IDType(i + 1)},
+		}
+		c.Assert(auditLog.Record(entry), IsNil)
+	}
+
+	// The log and its rotated file never exceed the maximum size
+	for _, path := range []string{s.logPath, s.logPath + ".1"} {
+		fi, err := os.Stat(path)
+		c.Assert(err, IsNil)
+		c.Check(fi.Size() <= 1024, Equals, true)
+	}
+
+	// The oldest entries were discarded, and the most recent ones are kept
+	// in order across both files
+	entries, err := auditLog.Entries(nil)
+	c.Assert(err, IsNil)
+	c.Assert(len(entries) < count, Equals, true)
+	c.Assert(len(entries) > 1, Equals, true)
+	for i, entry := range entries {
+		c.Check(entry.RuleIDs, DeepEquals, []prompting.IDType{prompting.IDType(count - len(entries) + i + 1)})
+	}
+}
+
+func (s *requestauditSuite) TestEntriesSkipsMalformed(c *C) {
+	auditLog, err := requestaudit.New()
+	c.Assert(err, IsNil)
+
+	entry := &requestaudit.Entry{
+		User:    1000,
+		Snap:    "firefox",
+		Outcome: prompting.OutcomeAllow,
+		Source:  requestaudit.SourceReply,
+	}
+	c.Assert(auditLog.Record(entry), IsNil)
+
+	// Simulate a truncated final entry
+	f, err := os.OpenFile(s.logPath, os.O_WRONLY|os.O_APPEND, 0o600)
+	c.Assert(err, IsNil)
+	_, err = f.WriteString(`{"timestamp":"2025-03-01T12:00:00Z","user":10`)
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	entries, err := auditLog.Entries(&requestaudit.Filter{User: 1000})
+	c.Assert(err, IsNil)
+	c.Check(entries, HasLen, 1)
+}
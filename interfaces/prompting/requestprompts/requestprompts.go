@@ -331,6 +331,12 @@ func (pc *promptConstraints) OutstandingPermissions() []string {
 	return pc.outstandingPermissions
 }
 
+// RequestedPermissions returns the permissions which were originally requested
+// by the request associated with the prompt.
+func (pc *promptConstraints) RequestedPermissions() []string {
+	return pc.originalPermissions
+}
+
 // userPromptDB maps prompt IDs to prompts for a single user.
 type userPromptDB struct {
 	// ids maps from id to the corresponding prompt's index in the prompts list.
@@ -417,6 +423,7 @@ func (udb *userPromptDB) timeoutCallback(pdb *PromptDB, user uint32) {
 		return
 	}
 	expiredPrompts := udb.prompts
+	expiredCallback := pdb.expiredCallback
 	// Clear all outstanding prompts for the user
 	udb.prompts = nil
 	udb.ids = make(map[prompting.IDType]int) // TODO: clear() once we're on Go 1.21+
@@ -435,6 +442,9 @@ func (udb *userPromptDB) timeoutCallback(pdb *PromptDB, user uint32) {
 	for _, p := range expiredPrompts {
 		pdb.notifyPrompt(user, p.ID, data)
 		p.sendReply(prompting.OutcomeDeny) // ignore any error, should not occur
+		if expiredCallback != nil {
+			expiredCallback(user, p)
+		}
 	}
 }
 
@@ -465,6 +475,10 @@ type PromptDB struct {
 	// notifyPrompt is a closure which will be called to record a notice when a
 	// prompt is added, merged, modified, or resolved.
 	notifyPrompt func(userID uint32, promptID prompting.IDType, data map[string]string) error
+	// expiredCallback is an optional closure which will be called, without
+	// the prompt DB lock held, for each prompt which was denied because it
+	// expired before the user replied to it.
+	expiredCallback func(userID uint32, prompt *Prompt)
 
 	// The filepath at which the ID map is stored on disk.
 	requestIDMapFilepath string
@@ -525,6 +539,14 @@ type idMappingJSON struct {
 	RequestIDMap map[uint64]idMapEntry `json:"id-mapping"`
 }
 
+// SetExpiredCallback sets the closure which is called for each prompt which
+// is denied because it expired before the user replied to it.
+func (pdb *PromptDB) SetExpiredCallback(f func(userID uint32, prompt *Prompt)) {
+	pdb.mutex.Lock()
+	defer pdb.mutex.Unlock()
+	pdb.expiredCallback = f
+}
+
 // loadRequestIDPromptIDMapping loads from disk the mapping from request ID to
 // prompt ID, and sets the prompt DB's map to the result.
 //
@@ -644,7 +666,8 @@ var timeAfterFunc = func(d time.Duration, f func()) timeutil.Timer {
 // merged.
 //
 // The caller must ensure that the given permissions are in the order in which
-// they appear in the available permissions list for the given interface.
+// they appear in the available permissions list for the given interface, and
+// returns an error if the path is not one the interface grants access to.
 func (pdb *PromptDB) AddOrMerge(metadata *prompting.Metadata, path string, requestedPermissions []string, outstandingPermissions []string, listenerReq *listener.Request) (*Prompt, bool, error) {
 	availablePermissions, err := prompting.AvailablePermissions(metadata.Interface)
 	if err != nil {
@@ -653,6 +676,9 @@ func (pdb *PromptDB) AddOrMerge(metadata *prompting.Metadata, path string, reque
 		// available permissions returned by AvailablePermissions.
 		return nil, false, err
 	}
+	if err := prompting.ValidateInterfacePath(metadata.Interface, path); err != nil {
+		return nil, false, err
+	}
 
 	pdb.mutex.Lock()
 	defer pdb.mutex.Unlock()
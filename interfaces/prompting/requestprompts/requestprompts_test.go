@@ -51,6 +51,15 @@ import (
 
 func Test(t *testing.T) { TestingT(t) }
 
+func init() {
+	// an interface which only grants access to some paths
+	prompting.RegisterInterface("test-media", prompting.InterfaceDeclaration{
+		Permissions:     []string{"read"},
+		FilePermissions: map[string]notify.FilePermission{"read": prompting.FileReadPermission},
+		PathPatterns:    []string{"/media/**"},
+	})
+}
+
 type noticeInfo struct {
 	promptID prompting.IDType
 	data     map[string]string
@@ -833,6 +842,38 @@ func sortSliceParams(list []*noticeInfo) ([]*noticeInfo, func(i, j int) bool) {
 	return list, less
 }
 
+func (s *requestpromptsSuite) TestAddOrMergePathNotGranted(c *C) {
+	restore := requestprompts.MockSendReply(func(listenerReq *listener.Request, allowedPermission notify.AppArmorPermission) error {
+		c.Fatalf("should not have called sendReply")
+		return nil
+	})
+	defer restore()
+
+	pdb, err := requestprompts.New(s.defaultNotifyPrompt)
+	c.Assert(err, IsNil)
+	defer pdb.Close()
+
+	metadata := &prompting.Metadata{
+		User:      s.defaultUser,
+		Snap:      "nextcloud",
+		PID:       42,
+		Cgroup:    "some-cgroup",
+		Interface: "test-media",
+	}
+	permissions := []string{"read"}
+
+	prompt, merged, err := pdb.AddOrMerge(metadata, "/home/test/foo", permissions, permissions, &listener.Request{ID: 1})
+	c.Check(err, ErrorMatches, `cannot prompt for path "/home/test/foo": not granted by the test-media interface`)
+	c.Check(prompt, IsNil)
+	c.Check(merged, Equals, false)
+	s.checkNewNoticesSimple(c, nil, nil)
+
+	prompt, merged, err = pdb.AddOrMerge(metadata, "/media/foo", permissions, permissions, &listener.Request{ID: 2})
+	c.Assert(err, IsNil)
+	c.Check(merged, Equals, false)
+	c.Check(prompt.Interface, Equals, "test-media")
+}
+
 func (s *requestpromptsSuite) TestAddOrMergeTooMany(c *C) {
 	restore := requestprompts.MockSendReply(func(listenerReq *listener.Request, allowedPermission notify.AppArmorPermission) error {
 		c.Fatalf("should not have called sendReply")
@@ -2037,6 +2078,56 @@ func (s *requestpromptsSuite) TestPromptExpiration(c *C) {
 	s.checkWrittenIDMap(c, expectedMap)
 }
 
+func (s *requestpromptsSuite) TestPromptExpirationCallback(c *C) {
+	var timer *testtime.TestTimer
+	restore := requestprompts.MockTimeAfterFunc(func(d time.Duration, f func()) timeutil.Timer {
+		timer = testtime.AfterFunc(d, f)
+		return timer
+	})
+	defer restore()
+
+	restore = requestprompts.MockSendReply(func(listenerReq *listener.Request, allowedPermission notify.AppArmorPermission) error {
+		return nil
+	})
+	defer restore()
+
+	pdb, err := requestprompts.New(s.defaultNotifyPrompt)
+	c.Assert(err, IsNil)
+	defer pdb.Close()
+
+	type expiredInfo struct {
+		user   uint32
+		prompt *requestprompts.Prompt
+	}
+	expiredChan := make(chan expiredInfo, 1)
+	pdb.SetExpiredCallback(func(userID uint32, prompt *requestprompts.Prompt) {
+		expiredChan <- expiredInfo{userID, prompt}
+	})
+
+	metadata := &prompting.Metadata{
+		User:      s.defaultUser,
+		Snap:      "firefox",
+		Interface: "home",
+	}
+	requestedPermissions := []string{"read", "write"}
+	outstandingPermissions := []string{"write"}
+	prompt, _, err := pdb.AddOrMerge(metadata, "/home/test/foo", requestedPermissions, outstandingPermissions, &listener.Request{ID: 123})
+	c.Assert(err, IsNil)
+	c.Check(prompt.Constraints.RequestedPermissions(), DeepEquals, requestedPermissions)
+
+	timer.Elapse(requestprompts.InitialTimeout - time.Nanosecond)
+	c.Check(timer.FireCount(), Equals, 0)
+
+	timer.Elapse(time.Nanosecond)
+	select {
+	case info := <-expiredChan:
+		c.Check(info.user, Equals, s.defaultUser)
+		c.Check(info.prompt, Equals, prompt)
+	case <-time.NewTimer(10 * time.Second).C:
+		c.Fatalf("expired callback was not called")
+	}
+}
+
 func (s *requestpromptsSuite) TestPromptExpirationRace(c *C) {
 	callbackSignaller := make(chan bool, 0)
 	var timer *testtime.TestTimer
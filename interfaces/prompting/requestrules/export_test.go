@@ -65,3 +65,7 @@ func (rdb *RuleDB) ReadOrAssignUserSessionID(user uint32) (userSessionID prompti
 func MockIsPathPermAllowed(f func(rdb *RuleDB, user uint32, snap string, iface string, path string, permission string, at prompting.At) (bool, error)) func() {
 	return testutil.Mock(&isPathPermAllowed, f)
 }
+
+func MockSystemRulesFileOwner(uid uint32) (restore func()) {
+	return testutil.Mock(&systemRulesFileOwner, uid)
+}
@@ -28,6 +28,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -61,6 +62,10 @@ type Rule struct {
 	Snap        string                     `json:"snap"`
 	Interface   string                     `json:"interface"`
 	Constraints *prompting.RuleConstraints `json:"constraints"`
+	// System is true if the rule was provisioned by the system administrator
+	// and applies to every user. System rules take precedence over the rules
+	// of a user and cannot be modified through the rule database.
+	System bool `json:"system,omitempty"`
 }
 
 // Validate verifies internal correctness of the rule's constraints and
@@ -190,6 +195,9 @@ func New(notifyRule func(userID uint32, ruleID prompting.IDType, data map[string
 	if err = rdb.load(); err != nil {
 		logger.Noticef("cannot load rule database: %v; using new empty rule database", err)
 	}
+	if err = rdb.loadSystemRules(); err != nil {
+		logger.Noticef("cannot load system rules: %v", err)
+	}
 	return rdb, nil
 }
 
@@ -314,10 +322,18 @@ func (rdb *RuleDB) load() (retErr error) {
 }
 
 // save writes the current state of the rule database to the database file.
+// System rules are not written, as they are read from the system rules file
+// every time the database is created.
 //
 // The caller must ensure that the database lock is held.
 func (rdb *RuleDB) save() error {
-	b, err := json.Marshal(rulesDBJSON{Rules: rdb.rules})
+	rules := make([]*Rule, 0, len(rdb.rules))
+	for _, rule := range rdb.rules {
+		if !rule.System {
+			rules = append(rules, rule)
+		}
+	}
+	b, err := json.Marshal(rulesDBJSON{Rules: rules})
 	if err != nil {
 		// Should not occur, marshalling should always succeed
 		logger.Noticef("cannot marshal rule DB: %v", err)
@@ -995,6 +1011,10 @@ func (rdb *RuleDB) AddRule(user uint32, snap string, iface string, constraints *
 		return nil, prompting_errors.ErrRulesClosed
 	}
 
+	if user == SystemUser {
+		return nil, prompting_errors.ErrRuleIsSystem
+	}
+
 	currSession, err := readOrAssignUserSessionID(rdb, user)
 	if err != nil && !errors.Is(err, errNoUserSession) {
 		return nil, err
@@ -1043,14 +1063,168 @@ func (rdb *RuleDB) makeNewRule(user uint32, snap string, iface string, constrain
 	return &newRule, nil
 }
 
+// ExportRules returns the contents of the rules of the given user, in a form
+// which can later be passed to ImportRules.
+//
+// Only permissions with a lifespan of "forever" are exported, since other
+// lifespans are tied to the current time or user session. Rules without any
+// such permission are omitted, as are system rules.
+func (rdb *RuleDB) ExportRules(user uint32) *RuleSet {
+	rdb.mutex.RLock()
+	defer rdb.mutex.RUnlock()
+	ruleFilter := func(rule *Rule) bool {
+		return rule.User == user && !rule.System
+	}
+	ruleSet := &RuleSet{
+		Rules: []*RuleContents{},
+	}
+	for _, rule := range rdb.rulesInternal(ruleFilter) {
+		permissions := make(prompting.PermissionMap)
+		for perm, entry := range rule.Constraints.Permissions {
+			if entry.Lifespan != prompting.LifespanForever {
+				continue
+			}
+			permissions[perm] = &prompting.PermissionEntry{
+				Outcome:  entry.Outcome,
+				Lifespan: entry.Lifespan,
+			}
+		}
+		if len(permissions) == 0 {
+			continue
+		}
+		ruleSet.Rules = append(ruleSet.Rules, &RuleContents{
+			Snap:      rule.Snap,
+			Interface: rule.Interface,
+			Constraints: &prompting.Constraints{
+				PathPattern: rule.Constraints.PathPattern,
+				Permissions: permissions,
+			},
+		})
+	}
+	return ruleSet
+}
+
+// ImportRules creates rules with the given contents for the given user and
+// adds them to the rule database, merging them with existing rules with
+// identical path patterns as AddRule does.
+//
+// Either all rules are imported, or none is: if any of the given rules is
+// invalid or conflicts with an existing rule or another imported rule, then
+// the rule database is left unchanged and an error is returned. Otherwise,
+// returns the added or merged rules, and saves the database to disk.
+func (rdb *RuleDB) ImportRules(user uint32, contents []*RuleContents) ([]*Rule, error) {
+	rdb.mutex.Lock()
+	defer rdb.mutex.Unlock()
+
+	if rdb.maxIDMmap.IsClosed() {
+		return nil, prompting_errors.ErrRulesClosed
+	}
+
+	if user == SystemUser {
+		return nil, prompting_errors.ErrRuleIsSystem
+	}
+
+	currSession, err := readOrAssignUserSessionID(rdb, user)
+	if err != nil && !errors.Is(err, errNoUserSession) {
+		return nil, err
+	}
+	at := prompting.At{
+		Time:      time.Now(),
+		SessionID: currSession,
+	}
+
+	// Validate every rule before touching the database.
+	newRules := make([]*Rule, 0, len(contents))
+	for i, c := range contents {
+		if c == nil {
+			return nil, fmt.Errorf("cannot import rule %d: no rule contents", i)
+		}
+		newRule, err := rdb.makeNewRule(user, c.Snap, c.Interface, c.Constraints, at)
+		if err != nil {
+			return nil, fmt.Errorf("cannot import rule %d: %w", i, err)
+		}
+		newRules = append(newRules, newRule)
+	}
+
+	origRules := make([]*Rule, len(rdb.rules))
+	copy(origRules, rdb.rules)
+
+	importedRules := make([]*Rule, 0, len(newRules))
+	var importErr error
+	for i, newRule := range newRules {
+		const save = false
+		addedRule, _, err := rdb.addOrMergeRule(newRule, at, save)
+		if err != nil {
+			importErr = fmt.Errorf("cannot import rule %d: %w", i, err)
+			break
+		}
+		importedRules = append(importedRules, addedRule)
+	}
+	if importErr == nil {
+		importErr = rdb.save()
+	}
+	if importErr != nil {
+		// Rules are never mutated once added to the database, so restoring
+		// the original list of rules restores the original state.
+		if restoreErr := rdb.restoreRules(origRules, at); restoreErr != nil {
+			importErr = strutil.JoinErrors(importErr, restoreErr)
+		}
+		return nil, importErr
+	}
+
+	// A rule may have been merged into by a later rule in the import, so
+	// look up the final version of each rule.
+	finalRules := make([]*Rule, 0, len(importedRules))
+	seen := make(map[prompting.IDType]bool, len(importedRules))
+	for _, rule := range importedRules {
+		if seen[rule.ID] {
+			continue
+		}
+		seen[rule.ID] = true
+		finalRule, err := rdb.lookupRuleByID(rule.ID)
+		if err != nil {
+			// Should not occur, we just added the rule
+			return nil, err
+		}
+		finalRules = append(finalRules, finalRule)
+		rdb.notifyRule(user, finalRule.ID, nil)
+	}
+	return finalRules, nil
+}
+
+// restoreRules resets the rule database so that it contains exactly the given
+// rules, which must previously have been in the database together.
+//
+// The caller must ensure that the database lock is held for writing.
+func (rdb *RuleDB) restoreRules(rules []*Rule, at prompting.At) error {
+	rdb.indexByID = make(map[prompting.IDType]int)
+	rdb.rules = make([]*Rule, 0, len(rules))
+	rdb.perUser = make(map[uint32]*userDB)
+
+	var errs []error
+	for _, rule := range rules {
+		const save = false
+		if err := rdb.addNewRule(rule, at, save); err != nil {
+			errs = append(errs, fmt.Errorf("cannot restore rule %s: %w", rule.ID, err))
+		}
+	}
+	return joinInternalErrors(errs)
+}
+
 // IsRequestAllowed checks whether a request with the given parameters is
-// allowed or denied by existing rules.
+// allowed or denied by existing rules. Rules provisioned by the system
+// administrator take precedence over the rules of the given user.
 //
 // If any of the given permissions are allowed, they are returned as
 // allowedPerms. If any permissions are denied, then returns anyDenied as true.
 // If any of the given permissions were not matched by an existing rule, then
 // they are returned as outstandingPerms. If an error occurred, returns it.
+//
+// Returns an error if the path is not one the interface grants access to.
 func (rdb *RuleDB) IsRequestAllowed(user uint32, snap string, iface string, path string, permissions []string) (allowedPerms []string, anyDenied bool, outstandingPerms []string, err error) {
+	if err := prompting.ValidateInterfacePath(iface, path); err != nil {
+		return nil, false, nil, err
+	}
 	allowedPerms = make([]string, 0, len(permissions))
 	outstandingPerms = make([]string, 0, len(permissions))
 	currSession, err := readOrAssignUserSessionID(rdb, user)
@@ -1061,9 +1235,17 @@ func (rdb *RuleDB) IsRequestAllowed(user uint32, snap string, iface string, path
 		Time:      time.Now(),
 		SessionID: currSession,
 	}
+	checkSystemRules := rdb.hasSystemRules()
 	var errs []error
 	for _, perm := range permissions {
-		allowed, err := isPathPermAllowed(rdb, user, snap, iface, path, perm, at)
+		var allowed bool
+		err := prompting_errors.ErrNoMatchingRule
+		if checkSystemRules {
+			allowed, err = isPathPermAllowed(rdb, SystemUser, snap, iface, path, perm, at)
+		}
+		if errors.Is(err, prompting_errors.ErrNoMatchingRule) {
+			allowed, err = isPathPermAllowed(rdb, user, snap, iface, path, perm, at)
+		}
 		switch {
 		case err == nil:
 			if allowed {
@@ -1091,9 +1273,24 @@ var isPathPermAllowed = (*RuleDB).isPathPermAllowed
 func (rdb *RuleDB) isPathPermAllowed(user uint32, snap string, iface string, path string, permission string, at prompting.At) (bool, error) {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
+	matchingEntry, err := rdb.matchingVariantEntry(user, snap, iface, path, permission, at)
+	if err != nil {
+		return false, err
+	}
+	return matchingEntry.Outcome.AsBool()
+}
+
+// matchingVariantEntry returns the variant entry with the highest precedence
+// among those of the rules for the given user, snap, interface, and permission
+// which match the given path at the given point in time.
+//
+// If no rule applies, returns prompting_errors.ErrNoMatchingRule.
+//
+// The caller must ensure that the database lock is held.
+func (rdb *RuleDB) matchingVariantEntry(user uint32, snap string, iface string, path string, permission string, at prompting.At) (*variantEntry, error) {
 	permissionMap := rdb.permissionDBForUserSnapInterfacePermission(user, snap, iface, permission)
 	if permissionMap == nil {
-		return false, prompting_errors.ErrNoMatchingRule
+		return nil, prompting_errors.ErrNoMatchingRule
 	}
 	variantMap := permissionMap.VariantEntries
 	var matchingVariants []patterns.PatternVariant
@@ -1108,21 +1305,65 @@ func (rdb *RuleDB) isPathPermAllowed(user uint32, snap string, iface string, pat
 		matched, err := patterns.PathPatternMatches(variantStr, path)
 		if err != nil {
 			// Only possible error is ErrBadPattern, which should not occur
-			return false, fmt.Errorf("internal error: while matching path pattern: %w", err)
+			return nil, fmt.Errorf("internal error: while matching path pattern: %w", err)
 		}
 		if matched {
 			matchingVariants = append(matchingVariants, variantEntry.Variant)
 		}
 	}
 	if len(matchingVariants) == 0 {
-		return false, prompting_errors.ErrNoMatchingRule
+		return nil, prompting_errors.ErrNoMatchingRule
 	}
 	highestPrecedenceVariant, err := patterns.HighestPrecedencePattern(matchingVariants, path)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	matchingEntry := variantMap[highestPrecedenceVariant.String()]
-	return matchingEntry.Outcome.AsBool()
+	return &matchingEntry, nil
+}
+
+// RuleIDsForRequest returns the IDs of the rules which decide the given
+// permissions of a request with the given parameters, as IsRequestAllowed
+// would, sorted in ascending order. Permissions which are not matched by any
+// rule are ignored.
+func (rdb *RuleDB) RuleIDsForRequest(user uint32, snap string, iface string, path string, permissions []string) []prompting.IDType {
+	currSession, err := readOrAssignUserSessionID(rdb, user)
+	if err != nil && !errors.Is(err, errNoUserSession) {
+		return nil
+	}
+	at := prompting.At{
+		Time:      time.Now(),
+		SessionID: currSession,
+	}
+
+	rdb.mutex.RLock()
+	defer rdb.mutex.RUnlock()
+	_, checkSystemRules := rdb.perUser[SystemUser]
+	ruleIDs := make(map[prompting.IDType]bool)
+	for _, perm := range permissions {
+		var entry *variantEntry
+		err := prompting_errors.ErrNoMatchingRule
+		if checkSystemRules {
+			entry, err = rdb.matchingVariantEntry(SystemUser, snap, iface, path, perm, at)
+		}
+		if errors.Is(err, prompting_errors.ErrNoMatchingRule) {
+			entry, err = rdb.matchingVariantEntry(user, snap, iface, path, perm, at)
+		}
+		if err != nil {
+			continue
+		}
+		for id, rulePermissionEntry := range entry.RuleEntries {
+			if !rulePermissionEntry.Expired(at) {
+				ruleIDs[id] = true
+			}
+		}
+	}
+	ids := make([]prompting.IDType, 0, len(ruleIDs))
+	for id := range ruleIDs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
 }
 
 // RuleWithID returns the rule with the given ID.
@@ -1132,15 +1373,23 @@ func (rdb *RuleDB) isPathPermAllowed(user uint32, snap string, iface string, pat
 func (rdb *RuleDB) RuleWithID(user uint32, id prompting.IDType) (*Rule, error) {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
-	return rdb.lookupRuleByIDForUser(user, id)
+	rule, err := rdb.lookupRuleByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if rule.User != user && !rule.System {
+		return nil, prompting_errors.ErrRuleNotAllowed
+	}
+	return rule, nil
 }
 
-// Rules returns all rules which apply to the given user.
+// Rules returns all rules which apply to the given user, including system
+// rules.
 func (rdb *RuleDB) Rules(user uint32) []*Rule {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
 	ruleFilter := func(rule *Rule) bool {
-		return rule.User == user
+		return rule.User == user || rule.System
 	}
 	return rdb.rulesInternal(ruleFilter)
 }
@@ -1183,40 +1432,42 @@ func (rdb *RuleDB) rulesInternal(ruleFilter func(rule *Rule) bool) []*Rule {
 	return rules
 }
 
-// RulesForSnap returns all rules which apply to the given user and snap.
+// RulesForSnap returns all rules which apply to the given user and snap,
+// including system rules.
 func (rdb *RuleDB) RulesForSnap(user uint32, snap string) []*Rule {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
 	ruleFilter := func(rule *Rule) bool {
-		return rule.User == user && rule.Snap == snap
+		return (rule.User == user || rule.System) && rule.Snap == snap
 	}
 	return rdb.rulesInternal(ruleFilter)
 }
 
 // RulesForInterface returns all rules which apply to the given user and
-// interface.
+// interface, including system rules.
 func (rdb *RuleDB) RulesForInterface(user uint32, iface string) []*Rule {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
 	ruleFilter := func(rule *Rule) bool {
-		return rule.User == user && rule.Interface == iface
+		return (rule.User == user || rule.System) && rule.Interface == iface
 	}
 	return rdb.rulesInternal(ruleFilter)
 }
 
 // RulesForSnapInterface returns all rules which apply to the given user, snap,
-// and interface.
+// and interface, including system rules.
 func (rdb *RuleDB) RulesForSnapInterface(user uint32, snap string, iface string) []*Rule {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
 	ruleFilter := func(rule *Rule) bool {
-		return rule.User == user && rule.Snap == snap && rule.Interface == iface
+		return (rule.User == user || rule.System) && rule.Snap == snap && rule.Interface == iface
 	}
 	return rdb.rulesInternal(ruleFilter)
 }
 
 // lookupRuleByIDForUser returns the rule with the given ID, if it exists, for the
-// given user. Otherwise, returns an error.
+// given user. Otherwise, returns an error. System rules are never returned, as
+// they cannot be modified by any user.
 //
 // The caller must ensure that the database lock is held.
 func (rdb *RuleDB) lookupRuleByIDForUser(user uint32, id prompting.IDType) (*Rule, error) {
@@ -1224,6 +1475,9 @@ func (rdb *RuleDB) lookupRuleByIDForUser(user uint32, id prompting.IDType) (*Rul
 	if err != nil {
 		return nil, err
 	}
+	if rule.System {
+		return nil, prompting_errors.ErrRuleIsSystem
+	}
 	if rule.User != user {
 		return nil, prompting_errors.ErrRuleNotAllowed
 	}
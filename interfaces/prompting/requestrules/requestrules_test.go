@@ -42,11 +42,21 @@ import (
 	"github.com/snapcore/snapd/interfaces/prompting/patterns"
 	"github.com/snapcore/snapd/interfaces/prompting/requestrules"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/sandbox/apparmor/notify"
 	"github.com/snapcore/snapd/testutil"
 )
 
 func Test(t *testing.T) { TestingT(t) }
 
+func init() {
+	// an interface which only grants access to some paths
+	prompting.RegisterInterface("test-media", prompting.InterfaceDeclaration{
+		Permissions:     []string{"read"},
+		FilePermissions: map[string]notify.FilePermission{"read": prompting.FileReadPermission},
+		PathPatterns:    []string{"/media/**"},
+	})
+}
+
 type noticeInfo struct {
 	userID uint32
 	ruleID prompting.IDType
@@ -1928,6 +1938,23 @@ func (s *requestrulesSuite) TestIsRequestAllowed(c *C) {
 	}
 }
 
+func (s *requestrulesSuite) TestIsRequestAllowedPathNotGranted(c *C) {
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+
+	allowed, anyDenied, outstanding, err := rdb.IsRequestAllowed(s.defaultUser, "firefox", "test-media", "/home/test/foo", []string{"read"})
+	c.Check(err, ErrorMatches, `cannot prompt for path "/home/test/foo": not granted by the test-media interface`)
+	c.Check(allowed, IsNil)
+	c.Check(anyDenied, Equals, false)
+	c.Check(outstanding, IsNil)
+
+	allowed, anyDenied, outstanding, err = rdb.IsRequestAllowed(s.defaultUser, "firefox", "test-media", "/media/foo", []string{"read"})
+	c.Check(err, IsNil)
+	c.Check(allowed, HasLen, 0)
+	c.Check(anyDenied, Equals, false)
+	c.Check(outstanding, DeepEquals, []string{"read"})
+}
+
 func (s *requestrulesSuite) TestIsPathPermAllowedSimple(c *C) {
 	currSession := prompting.IDType(0x12345)
 	restore := requestrules.MockReadOrAssignUserSessionID(func(rdb *requestrules.RuleDB, user uint32) (prompting.IDType, error) {
@@ -2114,6 +2141,53 @@ func (s *requestrulesSuite) TestIsPathPermAllowedPrecedence(c *C) {
 	}
 }
 
+func (s *requestrulesSuite) TestRuleIDsForRequest(c *C) {
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+
+	template := &addRuleContents{
+		User:      s.defaultUser,
+		Snap:      "firefox",
+		Interface: "home",
+		Outcome:   prompting.OutcomeAllow,
+		Lifespan:  prompting.LifespanForever,
+	}
+	broadRule, err := addRuleFromTemplate(c, rdb, template, &addRuleContents{
+		PathPattern: "/home/test/**",
+		Permissions: []string{"read", "write"},
+	})
+	c.Assert(err, IsNil)
+	narrowRule, err := addRuleFromTemplate(c, rdb, template, &addRuleContents{
+		PathPattern: "/home/test/Documents/**",
+		Permissions: []string{"write"},
+		Outcome:     prompting.OutcomeDeny,
+	})
+	c.Assert(err, IsNil)
+	otherUserRule, err := addRuleFromTemplate(c, rdb, template, &addRuleContents{
+		User:        s.defaultUser + 1,
+		PathPattern: "/home/test/**",
+		Permissions: []string{"execute"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(otherUserRule, NotNil)
+
+	for _, testCase := range []struct {
+		path        string
+		permissions []string
+		expected    []prompting.IDType
+	}{
+		{"/home/test/foo", []string{"read"}, []prompting.IDType{broadRule.ID}},
+		{"/home/test/Documents/foo", []string{"read"}, []prompting.IDType{broadRule.ID}},
+		{"/home/test/Documents/foo", []string{"write"}, []prompting.IDType{narrowRule.ID}},
+		{"/home/test/Documents/foo", []string{"write", "read"}, []prompting.IDType{broadRule.ID, narrowRule.ID}},
+		{"/home/test/Documents/foo", []string{"execute"}, []prompting.IDType{}},
+		{"/tmp/foo", []string{"read", "write"}, []prompting.IDType{}},
+	} {
+		ids := rdb.RuleIDsForRequest(s.defaultUser, "firefox", "home", testCase.path, testCase.permissions)
+		c.Check(ids, DeepEquals, testCase.expected, Commentf("path: %s, permissions: %v", testCase.path, testCase.permissions))
+	}
+}
+
 func (s *requestrulesSuite) TestIsPathPermAllowedExpiration(c *C) {
 	// Target
 	user := s.defaultUser
@@ -3260,3 +3334,161 @@ func (s *requestrulesSuite) TestUserSessionIDCache(c *C) {
 	c.Assert(count, Equals, 1)
 	c.Assert(ok, Equals, true)
 }
+
+func (s *requestrulesSuite) TestExportImportRules(c *C) {
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+
+	template := &addRuleContents{
+		User:        s.defaultUser,
+		Snap:        "lxd",
+		Interface:   "home",
+		PathPattern: "/home/test/Documents/**",
+		Permissions: []string{"read", "write"},
+		Outcome:     prompting.OutcomeAllow,
+		Lifespan:    prompting.LifespanForever,
+	}
+	for _, ruleContents := range []*addRuleContents{
+		{},
+		{PathPattern: "/home/test/Downloads/**", Outcome: prompting.OutcomeDeny, Permissions: []string{"execute"}},
+		// Not exported since its lifespan is not forever
+		{PathPattern: "/home/test/Music/**", Lifespan: prompting.LifespanTimespan, Duration: "10m"},
+		// Not exported since it's for another user
+		{User: s.defaultUser + 1, PathPattern: "/home/other/**"},
+	} {
+		rule, err := addRuleFromTemplate(c, rdb, template, ruleContents)
+		c.Assert(err, IsNil)
+		s.checkNewNoticesSimple(c, nil, rule)
+	}
+
+	exported := rdb.ExportRules(s.defaultUser)
+	c.Assert(exported.Rules, HasLen, 2)
+	allowForever := &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever}
+	denyForever := &prompting.PermissionEntry{Outcome: prompting.OutcomeDeny, Lifespan: prompting.LifespanForever}
+	c.Check(exported.Rules[0], DeepEquals, &requestrules.RuleContents{
+		Snap:      "lxd",
+		Interface: "home",
+		Constraints: &prompting.Constraints{
+			PathPattern: mustParsePathPattern(c, "/home/test/Documents/**"),
+			Permissions: prompting.PermissionMap{"read": allowForever, "write": allowForever},
+		},
+	})
+	c.Check(exported.Rules[1], DeepEquals, &requestrules.RuleContents{
+		Snap:      "lxd",
+		Interface: "home",
+		Constraints: &prompting.Constraints{
+			PathPattern: mustParsePathPattern(c, "/home/test/Downloads/**"),
+			Permissions: prompting.PermissionMap{"execute": denyForever},
+		},
+	})
+
+	// The exported rules can be serialized and imported by another user
+	data, err := json.Marshal(exported)
+	c.Assert(err, IsNil)
+	var ruleSet requestrules.RuleSet
+	c.Assert(json.Unmarshal(data, &ruleSet), IsNil)
+
+	otherUser := s.defaultUser + 2
+	imported, err := rdb.ImportRules(otherUser, ruleSet.Rules)
+	c.Assert(err, IsNil)
+	c.Assert(imported, HasLen, 2)
+	for i, rule := range imported {
+		c.Check(rule.User, Equals, otherUser)
+		c.Check(rule.Snap, Equals, "lxd")
+		c.Check(rule.Constraints.PathPattern, DeepEquals, exported.Rules[i].Constraints.PathPattern)
+	}
+	c.Check(rdb.Rules(otherUser), DeepEquals, imported)
+	s.checkNewNoticesSimple(c, nil, imported...)
+	c.Check(rdb.ExportRules(otherUser), DeepEquals, exported)
+
+	// Importing the same rules again merges them with the existing ones
+	reimported, err := rdb.ImportRules(otherUser, ruleSet.Rules)
+	c.Assert(err, IsNil)
+	c.Assert(reimported, HasLen, 2)
+	c.Check(reimported[0].ID, Equals, imported[0].ID)
+	c.Check(reimported[1].ID, Equals, imported[1].ID)
+	c.Check(rdb.Rules(otherUser), HasLen, 2)
+	s.checkNewNoticesSimple(c, nil, reimported...)
+}
+
+func (s *requestrulesSuite) TestImportRulesAllOrNothing(c *C) {
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+
+	existing, err := rdb.AddRule(s.defaultUser, "lxd", "home", &prompting.Constraints{
+		PathPattern: mustParsePathPattern(c, "/home/test/Documents/**"),
+		Permissions: prompting.PermissionMap{
+			"read": &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever},
+		},
+	})
+	c.Assert(err, IsNil)
+	s.checkNewNoticesSimple(c, nil, existing)
+
+	contents := func(pattern string, perm string, outcome prompting.OutcomeType) *requestrules.RuleContents {
+		return &requestrules.RuleContents{
+			Snap:      "lxd",
+			Interface: "home",
+			Constraints: &prompting.Constraints{
+				PathPattern: mustParsePathPattern(c, pattern),
+				Permissions: prompting.PermissionMap{
+					perm: &prompting.PermissionEntry{Outcome: outcome, Lifespan: prompting.LifespanForever},
+				},
+			},
+		}
+	}
+
+	for _, testCase := range []struct {
+		rules  []*requestrules.RuleContents
+		errStr string
+	}{
+		{
+			rules: []*requestrules.RuleContents{
+				contents("/home/test/Music/**", "read", prompting.OutcomeAllow),
+				// merged into the existing rule first
+				contents("/home/test/Documents/**", "write", prompting.OutcomeAllow),
+				// conflicts with the existing rule
+				contents("/home/test/Documents/**", "read", prompting.OutcomeDeny),
+			},
+			errStr: "cannot import rule 2: .*" + prompting_errors.ErrRuleConflict.Error(),
+		},
+		{
+			rules: []*requestrules.RuleContents{
+				contents("/home/test/Music/**", "read", prompting.OutcomeAllow),
+				contents("/home/test/Music/**", "read", prompting.OutcomeDeny),
+			},
+			errStr: "cannot import rule 1: .*" + prompting_errors.ErrRuleConflict.Error(),
+		},
+		{
+			rules: []*requestrules.RuleContents{
+				contents("/home/test/Music/**", "read", prompting.OutcomeAllow),
+				contents("/home/test/Music/**", "foo", prompting.OutcomeAllow),
+			},
+			errStr: `cannot import rule 1: invalid permissions for home interface: "foo"`,
+		},
+		{
+			rules: []*requestrules.RuleContents{
+				nil,
+			},
+			errStr: `cannot import rule 0: no rule contents`,
+		},
+	} {
+		imported, err := rdb.ImportRules(s.defaultUser, testCase.rules)
+		c.Check(err, ErrorMatches, testCase.errStr)
+		c.Check(imported, IsNil)
+
+		// The rule database is left unchanged
+		c.Check(rdb.Rules(s.defaultUser), DeepEquals, []*requestrules.Rule{existing})
+		s.checkWrittenRuleDB(c, []*requestrules.Rule{existing})
+		s.checkNewNoticesSimple(c, nil)
+		allowed, _, outstanding, err := rdb.IsRequestAllowed(s.defaultUser, "lxd", "home", "/home/test/Music/foo", []string{"read"})
+		c.Check(err, IsNil)
+		c.Check(allowed, HasLen, 0)
+		c.Check(outstanding, DeepEquals, []string{"read"})
+		allowed, _, _, err = rdb.IsRequestAllowed(s.defaultUser, "lxd", "home", "/home/test/Documents/foo", []string{"read", "write"})
+		c.Check(err, IsNil)
+		c.Check(allowed, DeepEquals, []string{"read"})
+	}
+
+	_, err = rdb.ImportRules(requestrules.SystemUser, nil)
+	c.Check(err, Equals, prompting_errors.ErrRuleIsSystem)
+}
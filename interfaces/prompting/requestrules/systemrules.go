@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package requestrules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	"github.com/snapcore/snapd/logger"
+)
+
+// SystemUser is the user ID under which rules provisioned by the system
+// administrator are stored. It is (uid_t)-1, which is never the UID of an
+// actual user.
+const SystemUser uint32 = math.MaxUint32
+
+// systemRulesFileOwner is the UID which must own the system rules file.
+var systemRulesFileOwner uint32 = 0
+
+// RuleContents holds the contents of a rule which are independent of the
+// user to which it applies and of the point in time when it was created, as
+// used in the system rules file and when exporting and importing rules.
+type RuleContents struct {
+	Snap        string                 `json:"snap"`
+	Interface   string                 `json:"interface"`
+	Constraints *prompting.Constraints `json:"constraints"`
+}
+
+// RuleSet holds a list of rule contents. It is the format of the system rules
+// file as well as that of rules exported by a user.
+type RuleSet struct {
+	Rules []*RuleContents `json:"rules"`
+}
+
+// readSystemRules reads the rule set from the system rules file.
+//
+// The file must be owned by root and must not be writable by anyone else,
+// since the rules it contains apply to every user. If the file does not
+// exist, returns an empty rule set.
+func readSystemRules() (*RuleSet, error) {
+	f, err := os.Open(dirs.SnapInterfacesRequestsSystemRulesFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &RuleSet{}, nil
+		}
+		return nil, fmt.Errorf("cannot open system rules file: %w", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("cannot stat system rules file: %w", err)
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || stat.Uid != systemRulesFileOwner {
+		return nil, fmt.Errorf("system rules file %s is not owned by root", f.Name())
+	}
+	if fi.Mode().Perm()&0o022 != 0 {
+		return nil, fmt.Errorf("system rules file %s must not be writable by group or others", f.Name())
+	}
+
+	var ruleSet RuleSet
+	if err := json.NewDecoder(f).Decode(&ruleSet); err != nil {
+		return nil, fmt.Errorf("cannot read system rules file: %w", err)
+	}
+	return &ruleSet, nil
+}
+
+// loadSystemRules reads the rules provisioned by the system administrator and
+// adds them to the rule database as rules for the system user.
+//
+// System rules must have a lifespan of "forever". Rules which are invalid or
+// which conflict with previous system rules are skipped and logged. System
+// rules are never saved to the rule database file.
+//
+// The caller must ensure that the database lock is held for writing.
+func (rdb *RuleDB) loadSystemRules() error {
+	ruleSet, err := readSystemRules()
+	if err != nil {
+		return err
+	}
+
+	at := prompting.At{
+		Time: time.Now(),
+	}
+	for i, contents := range ruleSet.Rules {
+		if err := validateSystemRuleContents(contents); err != nil {
+			logger.Noticef("cannot add system rule %d: %v", i, err)
+			continue
+		}
+		rule, err := rdb.makeNewRule(SystemUser, contents.Snap, contents.Interface, contents.Constraints, at)
+		if err != nil {
+			logger.Noticef("cannot add system rule %d: %v", i, err)
+			continue
+		}
+		rule.System = true
+		const save = false
+		if _, _, err := rdb.addOrMergeRule(rule, at, save); err != nil {
+			logger.Noticef("cannot add system rule %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func validateSystemRuleContents(contents *RuleContents) error {
+	if contents == nil || contents.Constraints == nil {
+		return fmt.Errorf("no constraints")
+	}
+	for perm, entry := range contents.Constraints.Permissions {
+		if entry != nil && entry.Lifespan != prompting.LifespanForever {
+			return fmt.Errorf("lifespan of permission %q must be %q", perm, prompting.LifespanForever)
+		}
+	}
+	return nil
+}
+
+// hasSystemRules returns true if any rules provisioned by the system
+// administrator are in the rule database.
+func (rdb *RuleDB) hasSystemRules() bool {
+	rdb.mutex.RLock()
+	defer rdb.mutex.RUnlock()
+	_, exists := rdb.perUser[SystemUser]
+	return exists
+}
@@ -0,0 +1,309 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package requestrules_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+	"github.com/snapcore/snapd/interfaces/prompting/requestrules"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *requestrulesSuite) writeSystemRules(c *C, content string, mode os.FileMode) {
+	s.AddCleanup(requestrules.MockSystemRulesFileOwner(uint32(os.Getuid())))
+	path := dirs.SnapInterfacesRequestsSystemRulesFile
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0o755), IsNil)
+	c.Assert(os.WriteFile(path, []byte(content), mode), IsNil)
+	c.Assert(os.Chmod(path, mode), IsNil)
+}
+
+const systemRulesJSON = `{
+	"rules": [
+		{
+			"snap": "firefox",
+			"interface": "home",
+			"constraints": {
+				"path-pattern": "/home/*/Documents/secret/**",
+				"permissions": {
+					"read": {"outcome": "deny", "lifespan": "forever"},
+					"write": {"outcome": "deny", "lifespan": "forever"}
+				}
+			}
+		},
+		{
+			"snap": "firefox",
+			"interface": "home",
+			"constraints": {
+				"path-pattern": "/home/*/Downloads/**",
+				"permissions": {
+					"write": {"outcome": "allow", "lifespan": "forever"}
+				}
+			}
+		}
+	]
+}`
+
+func (s *requestrulesSuite) TestSystemRulesLoaded(c *C) {
+	s.writeSystemRules(c, systemRulesJSON, 0o644)
+
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+
+	// System rules do not record notices
+	s.checkNewNoticesSimple(c, nil)
+
+	rules := rdb.Rules(s.defaultUser)
+	c.Assert(rules, HasLen, 2)
+	for _, rule := range rules {
+		c.Check(rule.System, Equals, true)
+		c.Check(rule.User, Equals, requestrules.SystemUser)
+		c.Check(rule.Snap, Equals, "firefox")
+		c.Check(rule.Interface, Equals, "home")
+	}
+	c.Check(rules[0].Constraints.PathPattern.String(), Equals, "/home/*/Documents/secret/**")
+	c.Check(rules[0].Constraints.Permissions, DeepEquals, prompting.RulePermissionMap{
+		"read":  &prompting.RulePermissionEntry{Outcome: prompting.OutcomeDeny, Lifespan: prompting.LifespanForever},
+		"write": &prompting.RulePermissionEntry{Outcome: prompting.OutcomeDeny, Lifespan: prompting.LifespanForever},
+	})
+
+	// System rules apply to every user
+	c.Check(rdb.Rules(s.defaultUser+1), DeepEquals, rules)
+	c.Check(rdb.RulesForSnap(s.defaultUser, "firefox"), DeepEquals, rules)
+	c.Check(rdb.RulesForSnap(s.defaultUser, "thunderbird"), HasLen, 0)
+	c.Check(rdb.RulesForInterface(s.defaultUser, "home"), DeepEquals, rules)
+	c.Check(rdb.RulesForSnapInterface(s.defaultUser, "firefox", "camera"), HasLen, 0)
+	rule, err := rdb.RuleWithID(s.defaultUser+1, rules[1].ID)
+	c.Check(err, IsNil)
+	c.Check(rule, DeepEquals, rules[1])
+
+	// System rules are not saved in the rule database file
+	userRule, err := rdb.AddRule(s.defaultUser, "firefox", "home", &prompting.Constraints{
+		PathPattern: mustParsePathPattern(c, "/home/test/Pictures/**"),
+		Permissions: prompting.PermissionMap{
+			"read": &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever},
+		},
+	})
+	c.Assert(err, IsNil)
+	s.checkWrittenRuleDB(c, []*requestrules.Rule{userRule})
+	s.checkNewNoticesSimple(c, nil, userRule)
+	c.Check(rdb.Rules(s.defaultUser), DeepEquals, append(rules, userRule))
+	c.Check(rdb.Rules(s.defaultUser+1), DeepEquals, rules)
+
+	// Reloading the database reads the system rules again
+	c.Assert(rdb.Close(), IsNil)
+	rdb, err = requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	c.Check(rdb.Rules(s.defaultUser), HasLen, 3)
+}
+
+func (s *requestrulesSuite) TestSystemRulesPrecedence(c *C) {
+	s.writeSystemRules(c, systemRulesJSON, 0o644)
+
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+
+	for _, contents := range []struct {
+		pattern string
+		outcome prompting.OutcomeType
+	}{
+		{"/home/test/Documents/**", prompting.OutcomeAllow},
+		{"/home/test/Downloads/**", prompting.OutcomeDeny},
+	} {
+		_, err := rdb.AddRule(s.defaultUser, "firefox", "home", &prompting.Constraints{
+			PathPattern: mustParsePathPattern(c, contents.pattern),
+			Permissions: prompting.PermissionMap{
+				"read":  &prompting.PermissionEntry{Outcome: contents.outcome, Lifespan: prompting.LifespanForever},
+				"write": &prompting.PermissionEntry{Outcome: contents.outcome, Lifespan: prompting.LifespanForever},
+			},
+		})
+		c.Assert(err, IsNil)
+	}
+
+	for _, testCase := range []struct {
+		user        uint32
+		path        string
+		allowed     []string
+		anyDenied   bool
+		outstanding []string
+	}{
+		// Only the user rule matches
+		{s.defaultUser, "/home/test/Documents/foo.txt", []string{"read", "write"}, false, []string{}},
+		// The system deny rule takes precedence over the user allow rule
+		{s.defaultUser, "/home/test/Documents/secret/foo.txt", []string{}, true, []string{}},
+		// The system allow rule takes precedence over the user deny rule,
+		// which applies to the permission not covered by the system rule
+		{s.defaultUser, "/home/test/Downloads/foo.txt", []string{"write"}, true, []string{}},
+		// System rules apply to users without any rules of their own
+		{s.defaultUser + 1, "/home/other/Documents/secret/foo.txt", []string{}, true, []string{}},
+		{s.defaultUser + 1, "/home/other/Downloads/foo.txt", []string{"write"}, false, []string{"read"}},
+	} {
+		allowed, anyDenied, outstanding, err := rdb.IsRequestAllowed(testCase.user, "firefox", "home", testCase.path, []string{"read", "write"})
+		c.Check(err, IsNil)
+		c.Check(allowed, DeepEquals, testCase.allowed, Commentf("path: %s", testCase.path))
+		c.Check(anyDenied, Equals, testCase.anyDenied, Commentf("path: %s", testCase.path))
+		c.Check(outstanding, DeepEquals, testCase.outstanding, Commentf("path: %s", testCase.path))
+	}
+
+	// System rules only apply to the snap for which they were provisioned
+	allowed, anyDenied, outstanding, err := rdb.IsRequestAllowed(s.defaultUser, "thunderbird", "home", "/home/test/Documents/secret/foo.txt", []string{"read"})
+	c.Check(err, IsNil)
+	c.Check(allowed, HasLen, 0)
+	c.Check(anyDenied, Equals, false)
+	c.Check(outstanding, DeepEquals, []string{"read"})
+}
+
+func (s *requestrulesSuite) TestSystemRulesCannotBeModified(c *C) {
+	s.writeSystemRules(c, systemRulesJSON, 0o644)
+
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	rules := rdb.Rules(s.defaultUser)
+	c.Assert(rules, HasLen, 2)
+
+	for _, user := range []uint32{s.defaultUser, requestrules.SystemUser} {
+		_, err = rdb.RemoveRule(user, rules[0].ID)
+		c.Check(err, Equals, prompting_errors.ErrRuleIsSystem)
+
+		_, err = rdb.PatchRule(user, rules[0].ID, &prompting.RuleConstraintsPatch{})
+		c.Check(err, Equals, prompting_errors.ErrRuleIsSystem)
+	}
+
+	removed, err := rdb.RemoveRulesForSnap(s.defaultUser, "firefox")
+	c.Check(err, IsNil)
+	c.Check(removed, HasLen, 0)
+
+	// Users cannot add rules on behalf of the system
+	_, err = rdb.AddRule(requestrules.SystemUser, "firefox", "home", &prompting.Constraints{
+		PathPattern: mustParsePathPattern(c, "/home/*/Pictures/**"),
+		Permissions: prompting.PermissionMap{
+			"read": &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever},
+		},
+	})
+	c.Check(err, Equals, prompting_errors.ErrRuleIsSystem)
+
+	c.Check(rdb.Rules(s.defaultUser), DeepEquals, rules)
+	s.checkNewNoticesSimple(c, nil)
+}
+
+func (s *requestrulesSuite) TestSystemRulesInvalidSkipped(c *C) {
+	s.writeSystemRules(c, `{
+	"rules": [
+		{
+			"snap": "firefox",
+			"interface": "home",
+			"constraints": {
+				"path-pattern": "/home/*/Documents/**",
+				"permissions": {"read": {"outcome": "allow", "lifespan": "timespan", "duration": "10m"}}
+			}
+		},
+		{
+			"snap": "firefox",
+			"interface": "foo",
+			"constraints": {
+				"path-pattern": "/home/*/Documents/**",
+				"permissions": {"read": {"outcome": "allow", "lifespan": "forever"}}
+			}
+		},
+		{
+			"snap": "firefox",
+			"interface": "home"
+		},
+		{
+			"snap": "firefox",
+			"interface": "home",
+			"constraints": {
+				"path-pattern": "/home/*/Music/**",
+				"permissions": {"read": {"outcome": "allow", "lifespan": "forever"}}
+			}
+		},
+		{
+			"snap": "firefox",
+			"interface": "home",
+			"constraints": {
+				"path-pattern": "/home/*/Music/{,**}",
+				"permissions": {"read": {"outcome": "deny", "lifespan": "forever"}}
+			}
+		}
+	]
+}`, 0o600)
+
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+
+	rules := rdb.Rules(s.defaultUser)
+	c.Assert(rules, HasLen, 1)
+	c.Check(rules[0].Constraints.PathPattern.String(), Equals, "/home/*/Music/**")
+
+	c.Check(logbuf.String(), testutil.Contains, `cannot add system rule 0: lifespan of permission "read" must be "forever"`)
+	c.Check(logbuf.String(), testutil.Contains, `cannot add system rule 1: invalid interface: "foo"`)
+	c.Check(logbuf.String(), testutil.Contains, `cannot add system rule 2: no constraints`)
+	c.Check(logbuf.String(), testutil.Contains, `cannot add system rule 4: `+prompting_errors.ErrRuleConflict.Error())
+}
+
+func (s *requestrulesSuite) TestSystemRulesFileErrors(c *C) {
+	for _, testCase := range []struct {
+		content string
+		mode    os.FileMode
+		owner   uint32
+		errStr  string
+	}{
+		{
+			content: systemRulesJSON,
+			mode:    0o644,
+			owner:   uint32(os.Getuid()) + 1,
+			errStr:  `cannot load system rules: system rules file .*/system-rules.json is not owned by root`,
+		},
+		{
+			content: systemRulesJSON,
+			mode:    0o664,
+			owner:   uint32(os.Getuid()),
+			errStr:  `cannot load system rules: system rules file .*/system-rules.json must not be writable by group or others`,
+		},
+		{
+			content: `{"rules": [`,
+			mode:    0o644,
+			owner:   uint32(os.Getuid()),
+			errStr:  `cannot load system rules: cannot read system rules file: unexpected EOF`,
+		},
+	} {
+		s.writeSystemRules(c, testCase.content, testCase.mode)
+		restore := requestrules.MockSystemRulesFileOwner(testCase.owner)
+
+		logbuf, restoreLogger := logger.MockLogger()
+		rdb, err := requestrules.New(s.defaultNotifyRule)
+		c.Assert(err, IsNil)
+		c.Check(rdb.Rules(s.defaultUser), HasLen, 0)
+		c.Check(logbuf.String(), Matches, `(?s).*`+testCase.errStr+`\n`)
+		c.Assert(rdb.Close(), IsNil)
+
+		restoreLogger()
+		restore()
+	}
+}
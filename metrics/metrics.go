@@ -0,0 +1,395 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package metrics implements counters, gauges and histograms describing the
+// internals of snapd, which can be written out in the Prometheus text
+// exposition format.
+//
+// Metrics are registered once, usually in package variables, and are safe
+// for concurrent use:
+//
+//	var storeRequests = metrics.NewCounter("snapd_store_requests_total",
+//		"Number of requests sent to the store.", "endpoint")
+//	...
+//	storeRequests.Inc("snaps/refresh")
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var validName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+var validLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type metricType string
+
+const (
+	counterType   metricType = "counter"
+	gaugeType     metricType = "gauge"
+	histogramType metricType = "histogram"
+)
+
+// Registry holds a set of metrics.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// NewRegistry returns a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+// Default is the registry used by the package level functions, and exposed
+// by the snapd API.
+var Default = NewRegistry()
+
+type metric interface {
+	desc() *desc
+	// write writes the samples of the metric, without the HELP and TYPE
+	// comments.
+	write(w *bufio.Writer)
+}
+
+type desc struct {
+	name       string
+	help       string
+	typ        metricType
+	labelNames []string
+}
+
+func (r *Registry) register(m metric) {
+	d := m.desc()
+	if !validName.MatchString(d.name) {
+		panic(fmt.Sprintf("internal error: invalid metric name %q", d.name))
+	}
+	for _, l := range d.labelNames {
+		if !validLabelName.MatchString(l) || l == "le" {
+			panic(fmt.Sprintf("internal error: invalid label name %q for metric %q", l, d.name))
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[d.name]; ok {
+		panic(fmt.Sprintf("internal error: metric %q registered twice", d.name))
+	}
+	r.metrics[d.name] = m
+}
+
+// WriteText writes all the metrics of the registry, sorted by name, in the
+// Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	metrics := make([]metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mu.Unlock()
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].desc().name < metrics[j].desc().name
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		d := m.desc()
+		if d.help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", d.name, d.typ)
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatLabels returns the {name="value",...} part of a sample, extra
+// label pairs are appended after the ones of the metric.
+func formatLabels(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	sep := ""
+	for i, name := range names {
+		fmt.Fprintf(&sb, `%s%s="%s"`, sep, name, labelValueEscaper.Replace(values[i]))
+		sep = ","
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		fmt.Fprintf(&sb, `%s%s="%s"`, sep, extra[i], labelValueEscaper.Replace(extra[i+1]))
+		sep = ","
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// series holds the per label values state of a metric.
+type series[T any] struct {
+	d *desc
+
+	mu     sync.Mutex
+	values map[string]*T
+	labels map[string][]string
+}
+
+func newSeries[T any](name, help string, typ metricType, labelNames []string) series[T] {
+	return series[T]{
+		d:      &desc{name: name, help: help, typ: typ, labelNames: labelNames},
+		values: make(map[string]*T),
+		labels: make(map[string][]string),
+	}
+}
+
+func (s *series[T]) desc() *desc {
+	return s.d
+}
+
+// get returns the value for the given label values, creating it if needed.
+// It must be called with s.mu held.
+func (s *series[T]) get(labelValues []string) *T {
+	if len(labelValues) != len(s.d.labelNames) {
+		panic(fmt.Sprintf("internal error: metric %q expects %d label values, got %d", s.d.name, len(s.d.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	v, ok := s.values[key]
+	if !ok {
+		v = new(T)
+		s.values[key] = v
+		s.labels[key] = append([]string(nil), labelValues...)
+	}
+	return v
+}
+
+// each calls f for each series sorted by label values. It must be called
+// with s.mu held. Metrics without labels always have a single series.
+func (s *series[T]) each(f func(labelValues []string, v *T)) {
+	if len(s.d.labelNames) == 0 {
+		f(nil, s.get(nil))
+		return
+	}
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f(s.labels[k], s.values[k])
+	}
+}
+
+// Counter is a metric whose value only ever increases.
+type Counter struct {
+	series[float64]
+}
+
+// NewCounter registers and returns a new counter with the given label names
+// in the registry.
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{newSeries[float64](name, help, counterType, labelNames)}
+	r.register(c)
+	return c
+}
+
+// NewCounter registers and returns a new counter in the Default registry.
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	return Default.NewCounter(name, help, labelNames...)
+}
+
+// Inc increments the counter for the given label values by one.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds the given non-negative value to the counter for the given label
+// values.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("internal error: cannot decrease counter %q", c.d.name))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.get(labelValues) += v
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.each(func(labelValues []string, v *float64) {
+		fmt.Fprintf(w, "%s%s %s\n", c.d.name, formatLabels(c.d.labelNames, labelValues), formatValue(*v))
+	})
+}
+
+// Gauge is a metric whose value may go up and down.
+type Gauge struct {
+	series[float64]
+}
+
+// NewGauge registers and returns a new gauge with the given label names in
+// the registry.
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{newSeries[float64](name, help, gaugeType, labelNames)}
+	r.register(g)
+	return g
+}
+
+// NewGauge registers and returns a new gauge in the Default registry.
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	return Default.NewGauge(name, help, labelNames...)
+}
+
+// Set sets the gauge for the given label values.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	*g.get(labelValues) = v
+}
+
+// Add adds the given, possibly negative, value to the gauge for the given
+// label values.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	*g.get(labelValues) += v
+}
+
+// Reset forgets the values of the gauge for all label values. This is
+// useful for gauges which are recomputed as a whole, so that label values
+// which went away are no longer reported.
+func (g *Gauge) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values = make(map[string]*float64)
+	g.labels = make(map[string][]string)
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.each(func(labelValues []string, v *float64) {
+		fmt.Fprintf(w, "%s%s %s\n", g.d.name, formatLabels(g.d.labelNames, labelValues), formatValue(*v))
+	})
+}
+
+// DefaultBuckets are the default histogram buckets, in seconds, suitable to
+// measure the duration of most operations in snapd.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type histogramValue struct {
+	// counts holds the number of observations in each bucket, the last
+	// one being the +Inf bucket; counts are not cumulative
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Histogram is a metric counting observations in configurable buckets.
+type Histogram struct {
+	series[histogramValue]
+	buckets []float64
+}
+
+// NewHistogram registers and returns a new histogram with the given bucket
+// upper bounds and label names in the registry. If buckets is nil,
+// DefaultBuckets are used.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("internal error: buckets of histogram %q are not sorted", name))
+	}
+	h := &Histogram{
+		series:  newSeries[histogramValue](name, help, histogramType, labelNames),
+		buckets: buckets,
+	}
+	r.register(h)
+	return h
+}
+
+// NewHistogram registers and returns a new histogram in the Default registry.
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labelNames...)
+}
+
+// Observe adds an observation to the histogram for the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv := h.get(labelValues)
+	if hv.counts == nil {
+		hv.counts = make([]uint64, len(h.buckets)+1)
+	}
+	hv.counts[sort.SearchFloat64s(h.buckets, v)]++
+	hv.sum += v
+	hv.count++
+}
+
+// ObserveDuration adds an observation of the given duration, in seconds, to
+// the histogram for the given label values.
+func (h *Histogram) ObserveDuration(d time.Duration, labelValues ...string) {
+	h.Observe(d.Seconds(), labelValues...)
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.each(func(labelValues []string, hv *histogramValue) {
+		labels := formatLabels(h.d.labelNames, labelValues)
+		cumulative := uint64(0)
+		for i := 0; i <= len(h.buckets); i++ {
+			if hv.counts != nil {
+				cumulative += hv.counts[i]
+			}
+			le := "+Inf"
+			if i < len(h.buckets) {
+				le = formatValue(h.buckets[i])
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.d.name, formatLabels(h.d.labelNames, labelValues, "le", le), cumulative)
+		}
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.d.name, labels, formatValue(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.d.name, labels, hv.count)
+	})
+}
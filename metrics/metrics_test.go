@@ -0,0 +1,170 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package metrics_test
+
+import (
+	"bytes"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/metrics"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type metricsSuite struct {
+	reg *metrics.Registry
+}
+
+var _ = Suite(&metricsSuite{})
+
+func (s *metricsSuite) SetUpTest(c *C) {
+	s.reg = metrics.NewRegistry()
+}
+
+func (s *metricsSuite) text(c *C) string {
+	var buf bytes.Buffer
+	c.Assert(s.reg.WriteText(&buf), IsNil)
+	return buf.String()
+}
+
+func (s *metricsSuite) TestCounter(c *C) {
+	plain := s.reg.NewCounter("snapd_things_total", "Number of things.")
+	labelled := s.reg.NewCounter("snapd_requests_total", "Number of requests,\nby \"endpoint\".", "endpoint", "code")
+
+	// unlabelled metrics are always reported, labelled ones only once used
+	c.Check(s.text(c), Equals, `# HELP snapd_requests_total Number of requests,\nby "endpoint".
+# TYPE snapd_requests_total counter
+# HELP snapd_things_total Number of things.
+# TYPE snapd_things_total counter
+snapd_things_total 0
+`)
+
+	plain.Inc()
+	plain.Add(1.5)
+	labelled.Inc("snaps/refresh", "200")
+	labelled.Inc("snaps/refresh", "200")
+	labelled.Inc("download", "5\"0\\3\n")
+	c.Check(s.text(c), Equals, `# HELP snapd_requests_total Number of requests,\nby "endpoint".
+# TYPE snapd_requests_total counter
+snapd_requests_total{endpoint="download",code="5\"0\\3\n"} 1
+snapd_requests_total{endpoint="snaps/refresh",code="200"} 2
+# HELP snapd_things_total Number of things.
+# TYPE snapd_things_total counter
+snapd_things_total 2.5
+`)
+
+	c.Check(func() { plain.Add(-1) }, PanicMatches, `internal error: cannot decrease counter "snapd_things_total"`)
+	c.Check(func() { labelled.Inc("foo") }, PanicMatches, `internal error: metric "snapd_requests_total" expects 2 label values, got 1`)
+}
+
+func (s *metricsSuite) TestGauge(c *C) {
+	g := s.reg.NewGauge("snapd_changes", "Number of changes.", "kind")
+	g.Set(3, "install-snap")
+	g.Add(2, "refresh-snap")
+	g.Add(-1, "refresh-snap")
+	c.Check(s.text(c), Equals, `# HELP snapd_changes Number of changes.
+# TYPE snapd_changes gauge
+snapd_changes{kind="install-snap"} 3
+snapd_changes{kind="refresh-snap"} 1
+`)
+
+	g.Reset()
+	g.Set(1, "remove-snap")
+	c.Check(s.text(c), Equals, `# HELP snapd_changes Number of changes.
+# TYPE snapd_changes gauge
+snapd_changes{kind="remove-snap"} 1
+`)
+}
+
+func (s *metricsSuite) TestHistogram(c *C) {
+	h := s.reg.NewHistogram("snapd_hook_duration_seconds", "Duration of hooks.", []float64{0.1, 1}, "hook")
+	s.reg.NewHistogram("snapd_ensure_duration_seconds", "Duration of ensures.", nil)
+	h.Observe(0.05, "configure")
+	h.Observe(0.1, "configure")
+	h.ObserveDuration(500*time.Millisecond, "configure")
+	h.Observe(3, "configure")
+	h.Observe(math.Inf(1), "install")
+	c.Check(s.text(c), Equals, `# HELP snapd_ensure_duration_seconds Duration of ensures.
+# TYPE snapd_ensure_duration_seconds histogram
+snapd_ensure_duration_seconds_bucket{le="0.005"} 0
+snapd_ensure_duration_seconds_bucket{le="0.01"} 0
+snapd_ensure_duration_seconds_bucket{le="0.025"} 0
+snapd_ensure_duration_seconds_bucket{le="0.05"} 0
+snapd_ensure_duration_seconds_bucket{le="0.1"} 0
+snapd_ensure_duration_seconds_bucket{le="0.25"} 0
+snapd_ensure_duration_seconds_bucket{le="0.5"} 0
+snapd_ensure_duration_seconds_bucket{le="1"} 0
+snapd_ensure_duration_seconds_bucket{le="2.5"} 0
+snapd_ensure_duration_seconds_bucket{le="5"} 0
+snapd_ensure_duration_seconds_bucket{le="10"} 0
+snapd_ensure_duration_seconds_bucket{le="30"} 0
+snapd_ensure_duration_seconds_bucket{le="60"} 0
+snapd_ensure_duration_seconds_bucket{le="+Inf"} 0
+snapd_ensure_duration_seconds_sum 0
+snapd_ensure_duration_seconds_count 0
+# HELP snapd_hook_duration_seconds Duration of hooks.
+# TYPE snapd_hook_duration_seconds histogram
+snapd_hook_duration_seconds_bucket{hook="configure",le="0.1"} 2
+snapd_hook_duration_seconds_bucket{hook="configure",le="1"} 3
+snapd_hook_duration_seconds_bucket{hook="configure",le="+Inf"} 4
+snapd_hook_duration_seconds_sum{hook="configure"} 3.65
+snapd_hook_duration_seconds_count{hook="configure"} 4
+snapd_hook_duration_seconds_bucket{hook="install",le="0.1"} 0
+snapd_hook_duration_seconds_bucket{hook="install",le="1"} 0
+snapd_hook_duration_seconds_bucket{hook="install",le="+Inf"} 1
+snapd_hook_duration_seconds_sum{hook="install"} +Inf
+snapd_hook_duration_seconds_count{hook="install"} 1
+`)
+}
+
+func (s *metricsSuite) TestRegisterErrors(c *C) {
+	s.reg.NewCounter("snapd_foo", "")
+	c.Check(func() { s.reg.NewGauge("snapd_foo", "") }, PanicMatches, `internal error: metric "snapd_foo" registered twice`)
+	c.Check(func() { s.reg.NewGauge("snapd-bar", "") }, PanicMatches, `internal error: invalid metric name "snapd-bar"`)
+	c.Check(func() { s.reg.NewGauge("snapd_bar", "", "a-b") }, PanicMatches, `internal error: invalid label name "a-b" for metric "snapd_bar"`)
+	c.Check(func() { s.reg.NewHistogram("snapd_baz", "", nil, "le") }, PanicMatches, `internal error: invalid label name "le" for metric "snapd_baz"`)
+	c.Check(func() { s.reg.NewHistogram("snapd_baz", "", []float64{2, 1}) }, PanicMatches, `internal error: buckets of histogram "snapd_baz" are not sorted`)
+}
+
+func (s *metricsSuite) TestConcurrentUse(c *C) {
+	counter := s.reg.NewCounter("snapd_things_total", "", "kind")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				counter.Inc("foo")
+			}
+			var buf bytes.Buffer
+			s.reg.WriteText(&buf)
+		}()
+	}
+	wg.Wait()
+	// no help is fine
+	c.Check(s.text(c), Equals, `# TYPE snapd_things_total counter
+snapd_things_total{kind="foo"} 1000
+`)
+}
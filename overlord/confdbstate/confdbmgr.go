@@ -46,12 +46,14 @@ func setupConfdbHook(st *state.State, snapName, hookName string, ignoreError boo
 	return task
 }
 
-type ConfdbManager struct{}
+type ConfdbManager struct {
+	state *state.State
+}
 
 func Manager(st *state.State, hookMgr *hookstate.HookManager, runner *state.TaskRunner) *ConfdbManager {
 	snapstate.IsConfdbHookname = IsConfdbHookname
 
-	m := &ConfdbManager{}
+	m := &ConfdbManager{state: st}
 
 	// no undo since if we commit there's no rolling back
 	runner.AddHandler("commit-confdb-tx", m.doCommitTransaction, nil)
@@ -80,7 +82,20 @@ func Manager(st *state.State, hookMgr *hookstate.HookManager, runner *state.Task
 	return m
 }
 
-func (m *ConfdbManager) Ensure() error { return nil }
+// Ensure is part of the overlord.StateManager interface.
+func (m *ConfdbManager) Ensure() error {
+	return m.ensureDatabagsMigrated()
+}
+
+// ensureDatabagsMigrated migrates the stored databags to the latest known
+// revisions of their confdb-schemas.
+func (m *ConfdbManager) ensureDatabagsMigrated() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	logger.Trace("ensure", "manager", "ConfdbManager", "func", "ensureDatabagsMigrated")
+	return migrateDatabags(m.state)
+}
 
 func (m *ConfdbManager) doCommitTransaction(t *state.Task, _ *tomb.Tomb) (err error) {
 	st := t.State()
@@ -98,7 +113,31 @@ func (m *ConfdbManager) doCommitTransaction(t *state.Task, _ *tomb.Tomb) (err er
 	}
 	schema := confdbAssert.Schema().DatabagSchema
 
-	return tx.Commit(st, schema)
+	previous, err := readDatabag(st, tx.ConfdbAccount, tx.ConfdbName)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(st, schema); err != nil {
+		return err
+	}
+
+	current, err := readDatabag(st, tx.ConfdbAccount, tx.ConfdbName)
+	if err != nil {
+		return err
+	}
+
+	var viewName string
+	if err := t.Get("view-name", &viewName); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+
+	if err := recordHistory(st, tx.ConfdbAccount, tx.ConfdbName, viewName, tx.Author(), previous, current); err != nil {
+		return err
+	}
+
+	notifyViewWatchers(st, tx.ConfdbAccount, tx.ConfdbName, tx.Author(), previous, current)
+	return nil
 }
 
 func (m *ConfdbManager) clearOngoingTransaction(t *state.Task, _ *tomb.Tomb) error {
@@ -85,7 +85,10 @@ func (e *NoViewError) Error() string {
 
 // GetView returns the view identified by the account, confdb schema and view
 // name. Returns asserts.NotFoundError if no confdb-schema assertion can be
-// fetched and NoViewError if the known confdb-schema has no such view.
+// fetched and NoViewError if the known confdb-schema has no such view. If the
+// confdb-schema has a newer revision than the stored databag, the databag is
+// migrated first, and an error is returned if that must wait for ongoing
+// transactions to finish.
 func GetView(st *state.State, account, schemaName, viewName string) (*confdb.View, error) {
 	confdbSchemaAs, err := assertstateConfdbSchema(st, account, schemaName)
 	if err != nil {
@@ -110,6 +113,11 @@ func GetView(st *state.State, account, schemaName, viewName string) (*confdb.Vie
 		}
 	}
 
+	// make sure the data is accessed using the layout of the current revision
+	if err := migrateDatabag(st, confdbSchemaAs); err != nil {
+		return nil, err
+	}
+
 	dbSchema := confdbSchemaAs.Schema()
 
 	view := dbSchema.View(viewName)
@@ -241,6 +249,9 @@ func GetTransactionToSet(ctx *hookstate.Context, st *state.State, view *confdb.V
 	if err != nil {
 		return nil, nil, fmt.Errorf("cannot modify confdb through view %s: cannot create transaction: %v", view.ID(), err)
 	}
+	if ctx != nil {
+		tx.SetAuthor(Author{Snap: ctx.InstanceName()})
+	}
 
 	commitTx := func() (string, <-chan struct{}, error) {
 		var chg *state.Change
@@ -388,6 +399,7 @@ func createChangeConfdbTasks(st *state.State, tx *Transaction, view *confdb.View
 	// commit after custodians save ephemeral data
 	commitTask := st.NewTask("commit-confdb-tx", fmt.Sprintf("Commit changes to confdb (%s)", view.ID()))
 	commitTask.Set("confdb-transaction", tx)
+	commitTask.Set("view-name", view.Name)
 	// link all previous tasks to the commit task that carries the transaction
 	for _, t := range ts.Tasks() {
 		t.Set("tx-task", commitTask.ID())
@@ -57,9 +57,11 @@ import (
 type confdbTestSuite struct {
 	state *state.State
 	o     *overlord.Overlord
+	mgr   *confdbstate.ConfdbManager
 
-	dbSchema *confdb.Schema
-	devAccID string
+	dbSchema  *confdb.Schema
+	devAccID  string
+	signingDB *assertstest.SigningDB
 
 	repo *interfaces.Repository
 }
@@ -84,8 +86,8 @@ func (s *confdbTestSuite) SetUpTest(c *C) {
 	s.o.AddManager(hookMgr)
 
 	// to test the confdbManager
-	mgr := confdbstate.Manager(s.state, hookMgr, runner)
-	s.o.AddManager(mgr)
+	s.mgr = confdbstate.Manager(s.state, hookMgr, runner)
+	s.o.AddManager(s.mgr)
 
 	storeSigning := assertstest.NewStoreStack("can0nical", nil)
 	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
@@ -157,6 +159,7 @@ func (s *confdbTestSuite) SetUpTest(c *C) {
 	c.Assert(assertstate.Add(s.state, as), IsNil)
 
 	s.devAccID = devAccKey.AccountID()
+	s.signingDB = signingDB
 	s.dbSchema = as.(*asserts.ConfdbSchema).Schema()
 
 	tr := config.NewTransaction(s.state)
@@ -1979,5 +1982,5 @@ func (s *confdbTestSuite) TestBadPathHookChecks(c *C) {
 }
 
 func (s *confdbTestSuite) TestEnsureLoopLogging(c *C) {
-	testutil.CheckEnsureLoopLogging("confdbmgr.go", c, false)
+	testutil.CheckEnsureLoopLogging("confdbmgr.go", c, true)
 }
@@ -21,6 +21,7 @@ package confdbstate
 import (
 	"time"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/confdb"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -35,6 +36,10 @@ var (
 	SetWriteTransaction     = setWriteTransaction
 	AddReadTransaction      = addReadTransaction
 	UnsetOngoingTransaction = unsetOngoingTransaction
+	DatabagRevision         = databagRevision
+	SetDatabagRevision      = setDatabagRevision
+	RecordHistory           = recordHistory
+	DiffDatabags            = diffDatabags
 )
 
 type (
@@ -70,6 +75,14 @@ func MockWriteDatabag(f func(st *state.State, databag confdb.JSONDatabag, accoun
 	}
 }
 
+func MockAssertstateConfdbSchema(f func(st *state.State, account, name string) (*asserts.ConfdbSchema, error)) func() {
+	old := assertstateConfdbSchema
+	assertstateConfdbSchema = f
+	return func() {
+		assertstateConfdbSchema = old
+	}
+}
+
 func MockEnsureNow(f func(*state.State)) func() {
 	old := ensureNow
 	ensureNow = f
@@ -85,3 +98,11 @@ func MockTransactionTimeout(dur time.Duration) func() {
 		transactionTimeout = old
 	}
 }
+
+func MockMaxHistoryEntries(n int) func() {
+	old := maxHistoryEntries
+	maxHistoryEntries = n
+	return func() {
+		maxHistoryEntries = old
+	}
+}
@@ -0,0 +1,335 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package confdbstate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/confdb"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// maxHistoryEntries is the number of committed transactions kept in the
+// history of each databag. Older entries are discarded.
+var maxHistoryEntries = 32
+
+var timeNow = time.Now
+
+// Author identifies who made changes to a confdb.
+type Author struct {
+	// Snap is the snap which made the changes, if they were made by a snap.
+	Snap string `json:"snap,omitempty"`
+	// UID is the user which made the changes, if they were made through the
+	// API.
+	UID *uint32 `json:"uid,omitempty"`
+}
+
+// PathChange is a change to the value stored at a storage path. A nil Old
+// value means that the path was set for the first time and a nil New value
+// that it was unset.
+type PathChange struct {
+	Path string `json:"path"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// HistoryEntry describes a transaction committed into a databag.
+type HistoryEntry struct {
+	ID   int       `json:"id"`
+	Time time.Time `json:"time"`
+	// View is the name of the view through which the changes were made.
+	View    string       `json:"view,omitempty"`
+	Author  Author       `json:"author"`
+	Changes []PathChange `json:"changes"`
+}
+
+// databagHistory holds the most recent committed transactions of a databag.
+// LastID is kept even when the entries are discarded, so that IDs are never
+// reused.
+type databagHistory struct {
+	LastID  int             `json:"last-id"`
+	Entries []*HistoryEntry `json:"entries,omitempty"`
+}
+
+func getHistories(st *state.State) (map[string]map[string]*databagHistory, error) {
+	var histories map[string]map[string]*databagHistory
+	if err := st.Get("confdb-history", &histories); err != nil && !errors.Is(err, &state.NoStateError{}) {
+		return nil, err
+	}
+	if histories == nil {
+		histories = make(map[string]map[string]*databagHistory, 1)
+	}
+	return histories, nil
+}
+
+// History returns the most recent transactions committed into the databag of
+// the confdb-schema, from the oldest to the most recent. The state must be
+// locked by the caller.
+func History(st *state.State, account, dbSchemaName string) ([]*HistoryEntry, error) {
+	histories, err := getHistories(st)
+	if err != nil {
+		return nil, err
+	}
+
+	if history := histories[account][dbSchemaName]; history != nil {
+		return history.Entries, nil
+	}
+	return nil, nil
+}
+
+// FilterHistory returns the history entries with only the changes to paths
+// which can be read through the view, so that the values of other paths are
+// not leaked. Entries left without changes are omitted.
+func FilterHistory(view *confdb.View, entries []*HistoryEntry) ([]*HistoryEntry, error) {
+	var filtered []*HistoryEntry
+	for _, entry := range entries {
+		var changes []PathChange
+		for _, change := range entry.Changes {
+			path, err := confdb.ParsePathIntoAccessors(change.Path, confdb.ParseOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("internal error: cannot parse path %q: %v", change.Path, err)
+			}
+			if view.CanReadPath(path) {
+				changes = append(changes, change)
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+
+		visible := *entry
+		visible.Changes = changes
+		filtered = append(filtered, &visible)
+	}
+	return filtered, nil
+}
+
+// recordHistory adds an entry with the differences between the previous and
+// the current databag to its history. Nothing is recorded if the databag
+// didn't change.
+func recordHistory(st *state.State, account, dbSchemaName, viewName string, author Author, previous, current confdb.JSONDatabag) error {
+	changes, err := diffDatabags(previous, current)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	histories, err := getHistories(st)
+	if err != nil {
+		return err
+	}
+	if histories[account] == nil {
+		histories[account] = make(map[string]*databagHistory, 1)
+	}
+	history := histories[account][dbSchemaName]
+	if history == nil {
+		history = &databagHistory{}
+		histories[account][dbSchemaName] = history
+	}
+
+	history.LastID++
+	history.Entries = append(history.Entries, &HistoryEntry{
+		ID:      history.LastID,
+		Time:    timeNow(),
+		View:    viewName,
+		Author:  author,
+		Changes: changes,
+	})
+	if len(history.Entries) > maxHistoryEntries {
+		history.Entries = history.Entries[len(history.Entries)-maxHistoryEntries:]
+	}
+
+	st.Set("confdb-history", histories)
+	return nil
+}
+
+// clearHistory discards the history of the databag. It's used once the
+// databag is migrated, since the recorded changes refer to paths of the
+// previous storage layout and can no longer be reverted.
+func clearHistory(st *state.State, account, dbSchemaName string) error {
+	histories, err := getHistories(st)
+	if err != nil {
+		return err
+	}
+
+	if history := histories[account][dbSchemaName]; history != nil {
+		history.Entries = nil
+		st.Set("confdb-history", histories)
+	}
+	return nil
+}
+
+// diffDatabags returns the changes to the leaf values of the databag, sorted
+// by path. Lists are compared as a whole.
+func diffDatabags(previous, current confdb.JSONDatabag) ([]PathChange, error) {
+	oldValues, err := flattenDatabag(previous)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := flattenDatabag(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []PathChange
+	for path, oldValue := range oldValues {
+		newValue, ok := newValues[path]
+		if !ok || !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, PathChange{Path: path, Old: oldValue, New: newValue})
+		}
+	}
+	for path, newValue := range newValues {
+		if _, ok := oldValues[path]; !ok {
+			changes = append(changes, PathChange{Path: path, New: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func flattenDatabag(bag confdb.JSONDatabag) (map[string]any, error) {
+	values := make(map[string]any)
+	if bag == nil {
+		return values, nil
+	}
+
+	data, err := bag.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	var value map[string]any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	flattenValue("", value, values)
+	return values, nil
+}
+
+func flattenValue(prefix string, value any, values map[string]any) {
+	obj, ok := value.(map[string]any)
+	if !ok || (len(obj) == 0 && prefix != "") {
+		values[prefix] = value
+		return
+	}
+
+	for k, v := range obj {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		flattenValue(path, v, values)
+	}
+}
+
+// RevertError is returned when the changes of a transaction cannot be
+// reverted.
+type RevertError struct {
+	msg string
+}
+
+func (e *RevertError) Error() string { return e.msg }
+
+func (e *RevertError) Is(err error) bool {
+	_, ok := err.(*RevertError)
+	return ok
+}
+
+// GetTransactionToRevert returns a transaction which undoes the changes of
+// the committed transaction with the given ID and of all the ones committed
+// after it, restoring the data to how it was before that transaction. Like
+// for any other write, the returned CommitTxFunc must be called to commit the
+// changes through the view, running the hooks of its custodian snaps. The
+// state must be locked by the caller.
+func GetTransactionToRevert(st *state.State, view *confdb.View, id int) (*Transaction, CommitTxFunc, error) {
+	account, dbSchemaName := view.Schema().Account, view.Schema().Name
+	entries, err := History(st, account, dbSchemaName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	first := -1
+	for i, entry := range entries {
+		if entry.ID == id {
+			first = i
+			break
+		}
+	}
+	if first == -1 {
+		return nil, nil, &RevertError{msg: fmt.Sprintf("cannot find transaction %d in the history of confdb %s/%s", id, account, dbSchemaName)}
+	}
+
+	// the value to restore for each path is the one it had before the first
+	// reverted transaction which changed it
+	var unsets, sets []pathValuePair
+	seen := make(map[string]bool)
+	for _, entry := range entries[first:] {
+		for _, change := range entry.Changes {
+			if seen[change.Path] {
+				continue
+			}
+			seen[change.Path] = true
+
+			path, err := confdb.ParsePathIntoAccessors(change.Path, confdb.ParseOptions{})
+			if err != nil {
+				return nil, nil, fmt.Errorf("internal error: cannot parse path %q: %v", change.Path, err)
+			}
+
+			if !view.CanWritePath(path) {
+				return nil, nil, &RevertError{msg: fmt.Sprintf("cannot revert transaction %d through view %s: %q cannot be written through the view", id, view.ID(), change.Path)}
+			}
+
+			if change.Old == nil {
+				unsets = append(unsets, pathValuePair{path: path})
+			} else {
+				sets = append(sets, pathValuePair{path: path, value: change.Old})
+			}
+		}
+	}
+
+	tx, commitTx, err := GetTransactionToSet(nil, st, view)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// unset the paths created by the reverted transactions first, so they
+	// don't remove the values restored under them
+	for _, unset := range unsets {
+		if err := tx.Unset(unset.path); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, set := range sets {
+		if err := tx.Set(set.path, set.value); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return tx, commitTx, nil
+}
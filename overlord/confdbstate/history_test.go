@@ -0,0 +1,287 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package confdbstate_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/confdb"
+	"github.com/snapcore/snapd/overlord/confdbstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *confdbTestSuite) TestDiffDatabags(c *C) {
+	previous := confdb.NewJSONDatabag()
+	c.Assert(previous.Set(parsePath(c, "wifi.ssid"), "foo"), IsNil)
+	c.Assert(previous.Set(parsePath(c, "wifi.psk"), "secret"), IsNil)
+	c.Assert(previous.Set(parsePath(c, "wifi.ssids"), []any{"a", "b"}), IsNil)
+	c.Assert(previous.Set(parsePath(c, "private.a.b"), "c"), IsNil)
+
+	current := previous.Copy()
+	c.Assert(current.Set(parsePath(c, "wifi.ssid"), "bar"), IsNil)
+	c.Assert(current.Unset(parsePath(c, "wifi.psk")), IsNil)
+	c.Assert(current.Set(parsePath(c, "wifi.ssids"), []any{"a"}), IsNil)
+	c.Assert(current.Set(parsePath(c, "private.a"), "d"), IsNil)
+	c.Assert(current.Set(parsePath(c, "wifi.status"), "up"), IsNil)
+
+	changes, err := confdbstate.DiffDatabags(previous, current)
+	c.Assert(err, IsNil)
+	c.Check(changes, DeepEquals, []confdbstate.PathChange{
+		{Path: "private.a", New: "d"},
+		{Path: "private.a.b", Old: "c"},
+		{Path: "wifi.psk", Old: "secret"},
+		{Path: "wifi.ssid", Old: "foo", New: "bar"},
+		{Path: "wifi.ssids", Old: []any{"a", "b"}, New: []any{"a"}},
+		{Path: "wifi.status", New: "up"},
+	})
+
+	changes, err = confdbstate.DiffDatabags(current, current)
+	c.Assert(err, IsNil)
+	c.Check(changes, HasLen, 0)
+}
+
+func (s *confdbTestSuite) commitThroughTask(c *C, values map[string]any, author confdbstate.Author) {
+	chg := s.state.NewChange("test", "")
+	t := s.state.NewTask("commit-confdb-tx", "")
+	t.Set("view-name", "setup-wifi")
+	chg.AddTask(t)
+
+	tx, err := confdbstate.NewTransaction(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	tx.SetAuthor(author)
+	for path, value := range values {
+		c.Assert(tx.Set(parsePath(c, path), value), IsNil)
+	}
+	setTransaction(t, tx)
+
+	s.state.Unlock()
+	err = s.o.Settle(testutil.HostScaledTimeout(5 * time.Second))
+	s.state.Lock()
+	c.Assert(err, IsNil)
+	c.Assert(t.Status(), Equals, state.DoneStatus, Commentf(strings.Join(t.Log(), "\n")))
+}
+
+func (s *confdbTestSuite) TestCommitRecordsHistory(c *C) {
+	restore := confdbstate.MockMaxHistoryEntries(2)
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	uid := uint32(1000)
+	s.commitThroughTask(c, map[string]any{"wifi.ssid": "foo"}, confdbstate.Author{UID: &uid})
+
+	entries, err := confdbstate.History(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+	c.Check(entries[0].ID, Equals, 1)
+	c.Check(entries[0].Time.IsZero(), Equals, false)
+	c.Check(entries[0].View, Equals, "setup-wifi")
+	c.Check(entries[0].Author, DeepEquals, confdbstate.Author{UID: &uid})
+	c.Check(entries[0].Changes, DeepEquals, []confdbstate.PathChange{{Path: "wifi.ssid", New: "foo"}})
+
+	// commits which don't change anything aren't recorded
+	s.commitThroughTask(c, map[string]any{"wifi.ssid": "foo"}, confdbstate.Author{Snap: "some-snap"})
+	entries, err = confdbstate.History(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+
+	s.commitThroughTask(c, map[string]any{"wifi.ssid": "bar"}, confdbstate.Author{Snap: "some-snap"})
+	s.commitThroughTask(c, map[string]any{"wifi.psk": "secret"}, confdbstate.Author{Snap: "other-snap"})
+
+	// only the most recent entries are kept
+	entries, err = confdbstate.History(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 2)
+	c.Check(entries[0].ID, Equals, 2)
+	c.Check(entries[0].Author, DeepEquals, confdbstate.Author{Snap: "some-snap"})
+	c.Check(entries[0].Changes, DeepEquals, []confdbstate.PathChange{{Path: "wifi.ssid", Old: "foo", New: "bar"}})
+	c.Check(entries[1].ID, Equals, 3)
+	c.Check(entries[1].Author, DeepEquals, confdbstate.Author{Snap: "other-snap"})
+	c.Check(entries[1].Changes, DeepEquals, []confdbstate.PathChange{{Path: "wifi.psk", New: "secret"}})
+}
+
+func (s *confdbTestSuite) TestMigrationClearsHistory(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.commitThroughTask(c, map[string]any{"wifi.ssid": "foo"}, confdbstate.Author{Snap: "some-snap"})
+	s.addSchemaRevision(c, 1, "string")
+
+	_, err := confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+	c.Assert(err, IsNil)
+
+	entries, err := confdbstate.History(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Check(entries, HasLen, 0)
+}
+
+// setupHistory stores a databag and a history with three transactions:
+// setting wifi.ssid, changing it and setting wifi.psk.
+func (s *confdbTestSuite) setupHistory(c *C) {
+	var bags []confdb.JSONDatabag
+	bag := confdb.NewJSONDatabag()
+	bags = append(bags, bag.Copy())
+	for _, change := range []struct {
+		path  string
+		value any
+	}{
+		{"wifi.ssid", "foo"},
+		{"wifi.ssid", "bar"},
+		{"wifi.psk", "secret"},
+	} {
+		c.Assert(bag.Set(parsePath(c, change.path), change.value), IsNil)
+		bags = append(bags, bag.Copy())
+	}
+	s.state.Set("confdb-databags", map[string]map[string]confdb.JSONDatabag{s.devAccID: {"network": bag}})
+
+	for i := 1; i < len(bags); i++ {
+		err := confdbstate.RecordHistory(s.state, s.devAccID, "network", "setup-wifi", confdbstate.Author{Snap: "some-snap"}, bags[i-1], bags[i])
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *confdbTestSuite) TestGetTransactionToRevert(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	custodians := map[string]confdbHooks{"custodian-snap": changeView}
+	s.setupConfdbScenario(c, custodians, nil)
+	s.setupHistory(c)
+
+	view := s.dbSchema.View("setup-wifi")
+	for _, tc := range []struct {
+		id       int
+		expected string
+	}{
+		{1, `{"wifi":{}}`},
+		{2, `{"wifi":{"ssid":"foo"}}`},
+		{3, `{"wifi":{"ssid":"bar"}}`},
+	} {
+		tx, _, err := confdbstate.GetTransactionToRevert(s.state, view, tc.id)
+		c.Assert(err, IsNil)
+
+		data, err := tx.Data()
+		c.Assert(err, IsNil)
+		c.Check(string(data), Equals, tc.expected, Commentf("reverting transaction %d", tc.id))
+	}
+
+	// the revert goes through the custodian's change-view hook
+	tx, commitTx, err := confdbstate.GetTransactionToRevert(s.state, view, 2)
+	c.Assert(err, IsNil)
+	uid := uint32(0)
+	tx.SetAuthor(confdbstate.Author{UID: &uid})
+
+	chgID, _, err := commitTx()
+	c.Assert(err, IsNil)
+
+	chg := s.state.Change(chgID)
+	c.Assert(chg, NotNil)
+	var hooks []string
+	for _, t := range chg.Tasks() {
+		if t.Kind() == "run-hook" {
+			var hooksup hookstate.HookSetup
+			c.Assert(t.Get("hook-setup", &hooksup), IsNil)
+			hooks = append(hooks, hooksup.Snap+":"+hooksup.Hook)
+		}
+	}
+	c.Check(hooks, testutil.Contains, "custodian-snap:change-view-setup")
+}
+
+func (s *confdbTestSuite) TestGetTransactionToRevertErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	custodians := map[string]confdbHooks{"custodian-snap": changeView}
+	s.setupConfdbScenario(c, custodians, nil)
+	s.setupHistory(c)
+
+	view := s.dbSchema.View("setup-wifi")
+	_, _, err := confdbstate.GetTransactionToRevert(s.state, view, 4)
+	c.Assert(err, ErrorMatches, `cannot find transaction 4 in the history of confdb .*/network`)
+	c.Assert(err, testutil.ErrorIs, &confdbstate.RevertError{})
+
+	// a later transaction changed data which isn't accessible through the view
+	bag, err := confdbstate.ReadDatabag(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	previous := bag.Copy()
+	c.Assert(bag.Set(parsePath(c, "other"), "value"), IsNil)
+	err = confdbstate.RecordHistory(s.state, s.devAccID, "network", "", confdbstate.Author{}, previous, bag)
+	c.Assert(err, IsNil)
+
+	_, _, err = confdbstate.GetTransactionToRevert(s.state, view, 3)
+	c.Assert(err, ErrorMatches, `cannot revert transaction 3 through view .*/network/setup-wifi: "other" cannot be written through the view`)
+	c.Assert(err, testutil.ErrorIs, &confdbstate.RevertError{})
+
+	// the view must be able to write the exact paths that changed
+	for _, path := range []string{"wifi.status", "wifi"} {
+		previous = bag.Copy()
+		c.Assert(bag.Set(parsePath(c, path), "value"), IsNil)
+		err = confdbstate.RecordHistory(s.state, s.devAccID, "network", "", confdbstate.Author{}, previous, bag)
+		c.Assert(err, IsNil)
+
+		entries, err := confdbstate.History(s.state, s.devAccID, "network")
+		c.Assert(err, IsNil)
+		id := entries[len(entries)-1].ID
+		_, _, err = confdbstate.GetTransactionToRevert(s.state, view, id)
+		c.Assert(err, ErrorMatches, fmt.Sprintf(`cannot revert transaction %d through view .*/network/setup-wifi: %q cannot be written through the view`, id, path))
+	}
+}
+
+func (s *confdbTestSuite) TestFilterHistory(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupHistory(c)
+	entries, err := confdbstate.History(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 3)
+
+	// the password is write-only so the transaction setting it is left out
+	view := s.dbSchema.View("setup-wifi")
+	filtered, err := confdbstate.FilterHistory(view, entries)
+	c.Assert(err, IsNil)
+	c.Check(filtered, DeepEquals, entries[:2])
+
+	// entries with some readable changes only keep those
+	bag, err := confdbstate.ReadDatabag(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	previous := bag.Copy()
+	c.Assert(bag.Set(parsePath(c, "wifi.psk"), "other"), IsNil)
+	c.Assert(bag.Set(parsePath(c, "wifi.status"), "up"), IsNil)
+	err = confdbstate.RecordHistory(s.state, s.devAccID, "network", "setup-wifi", confdbstate.Author{Snap: "some-snap"}, previous, bag)
+	c.Assert(err, IsNil)
+
+	entries, err = confdbstate.History(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	filtered, err = confdbstate.FilterHistory(view, entries)
+	c.Assert(err, IsNil)
+	c.Assert(filtered, HasLen, 3)
+	c.Check(filtered[2].ID, Equals, 4)
+	c.Check(filtered[2].Changes, DeepEquals, []confdbstate.PathChange{{Path: "wifi.status", New: "up"}})
+	// the stored entries aren't modified
+	c.Check(entries[3].Changes, HasLen, 2)
+}
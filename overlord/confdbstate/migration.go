@@ -0,0 +1,199 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package confdbstate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/confdb"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// errMigrationPending is returned when a databag cannot be migrated yet
+// because transactions using its current layout are ongoing.
+var errMigrationPending = errors.New("transactions are ongoing")
+
+// databagRevision returns the revision of the confdb-schema whose storage
+// layout the databag conforms to, and whether it was recorded at all, which
+// is not the case for databags written before revisions were tracked.
+func databagRevision(st *state.State, account, dbSchemaName string) (rev int, tracked bool, err error) {
+	var revisions map[string]map[string]int
+	if err := st.Get("confdb-databag-revisions", &revisions); err != nil {
+		if errors.Is(err, &state.NoStateError{}) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	rev, tracked = revisions[account][dbSchemaName]
+	return rev, tracked, nil
+}
+
+// trackDatabagRevision records that the databag conforms to the current
+// revision of its confdb-schema, unless a revision was already recorded.
+func trackDatabagRevision(st *state.State, account, dbSchemaName string) error {
+	_, tracked, err := databagRevision(st, account, dbSchemaName)
+	if err != nil || tracked {
+		return err
+	}
+
+	confdbSchemaAs, err := assertstateConfdbSchema(st, account, dbSchemaName)
+	if err != nil {
+		if errors.Is(err, &asserts.NotFoundError{}) {
+			return nil
+		}
+		return err
+	}
+	return setDatabagRevision(st, account, dbSchemaName, confdbSchemaAs.Revision())
+}
+
+func setDatabagRevision(st *state.State, account, dbSchemaName string, revision int) error {
+	var revisions map[string]map[string]int
+	err := st.Get("confdb-databag-revisions", &revisions)
+	if err != nil && !errors.Is(err, &state.NoStateError{}) {
+		return err
+	}
+
+	if revisions == nil {
+		revisions = make(map[string]map[string]int, 1)
+	}
+	if revisions[account] == nil {
+		revisions[account] = make(map[string]int, 1)
+	}
+
+	revisions[account][dbSchemaName] = revision
+	st.Set("confdb-databag-revisions", revisions)
+	return nil
+}
+
+// migrateDatabag brings the databag of the confdb-schema up to date with the
+// assertion's revision, applying any migrations declared for the revisions
+// the databag hasn't seen yet. The migrations are applied in a transaction
+// which is validated against the storage schema before being committed, so
+// the databag is left untouched if any of them fails. Databags with ongoing
+// transactions are not migrated, since those may still be using the previous
+// layout, and errMigrationPending is returned instead. Databags without a
+// recorded revision were written before revisions were tracked, and are
+// assumed to conform to the current revision. The state must be locked by the
+// caller.
+func migrateDatabag(st *state.State, confdbSchemaAs *asserts.ConfdbSchema) error {
+	account, dbSchemaName := confdbSchemaAs.AccountID(), confdbSchemaAs.Name()
+	targetRev := confdbSchemaAs.Revision()
+
+	currentRev, tracked, err := databagRevision(st, account, dbSchemaName)
+	if err != nil {
+		return err
+	}
+	if !tracked {
+		// the revision the data was written with is unknown, so applying
+		// migrations to it could corrupt it
+		return setDatabagRevision(st, account, dbSchemaName, targetRev)
+	}
+	if currentRev >= targetRev {
+		return nil
+	}
+
+	txs, _, err := getOngoingTxs(st, account, dbSchemaName)
+	if err != nil {
+		return err
+	}
+	if txs != nil {
+		return fmt.Errorf("cannot migrate confdb %s/%s to revision %d yet: %w", account, dbSchemaName, targetRev, errMigrationPending)
+	}
+
+	dbSchema := confdbSchemaAs.Schema()
+	var pending []*confdb.Migration
+	for _, migration := range dbSchema.Migrations {
+		if migration.Revision > currentRev && migration.Revision <= targetRev {
+			pending = append(pending, migration)
+		}
+	}
+
+	bag, err := readDatabag(st, account, dbSchemaName)
+	if err != nil {
+		return err
+	}
+
+	// nothing to migrate, just record that the data matches the new revision
+	if len(pending) == 0 || len(bag) == 0 {
+		return setDatabagRevision(st, account, dbSchemaName, targetRev)
+	}
+
+	tx, err := NewTransaction(st, account, dbSchemaName)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range pending {
+		if err := migration.Apply(tx); err != nil {
+			return fmt.Errorf("cannot migrate confdb %s/%s to revision %d: %w", account, dbSchemaName, targetRev, err)
+		}
+	}
+
+	if err := tx.Commit(st, dbSchema.DatabagSchema); err != nil {
+		return fmt.Errorf("cannot migrate confdb %s/%s to revision %d: %w", account, dbSchemaName, targetRev, err)
+	}
+
+	// the recorded changes refer to the previous layout so they can't be reverted
+	if err := clearHistory(st, account, dbSchemaName); err != nil {
+		return err
+	}
+
+	logger.Noticef("migrated confdb %s/%s from revision %d to %d", account, dbSchemaName, currentRev, targetRev)
+	return setDatabagRevision(st, account, dbSchemaName, targetRev)
+}
+
+// migrateDatabags migrates all stored databags whose confdb-schema assertion
+// has a newer revision than the one they were last migrated to. Failures are
+// logged and the migration is retried the next time.
+func migrateDatabags(st *state.State) error {
+	var databags map[string]map[string]confdb.JSONDatabag
+	if err := st.Get("confdb-databags", &databags); err != nil {
+		if errors.Is(err, &state.NoStateError{}) {
+			return nil
+		}
+		return err
+	}
+
+	for account, bags := range databags {
+		for dbSchemaName := range bags {
+			confdbSchemaAs, err := assertstateConfdbSchema(st, account, dbSchemaName)
+			if err != nil {
+				if !errors.Is(err, &asserts.NotFoundError{}) {
+					logger.Noticef("cannot find confdb-schema %s/%s: %v", account, dbSchemaName, err)
+				}
+				continue
+			}
+
+			if err := migrateDatabag(st, confdbSchemaAs); err != nil {
+				if errors.Is(err, errMigrationPending) {
+					logger.Debugf("%v", err)
+					continue
+				}
+				logger.Noticef("%v", err)
+			}
+		}
+	}
+
+	return nil
+}
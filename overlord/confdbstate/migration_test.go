@@ -0,0 +1,284 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package confdbstate_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/confdb"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/confdbstate"
+)
+
+// addSchemaRevision adds a new revision of the network confdb-schema with a
+// migration which renames wifi.ssid to wifi.name and converts wifi.channel
+// into an int.
+func (s *confdbTestSuite) addSchemaRevision(c *C, revision int, statusType string) {
+	headers := map[string]any{
+		"authority-id": s.devAccID,
+		"account-id":   s.devAccID,
+		"name":         "network",
+		"revision":     fmt.Sprint(revision),
+		"views": map[string]any{
+			"setup-wifi": map[string]any{
+				"rules": []any{
+					map[string]any{"request": "ssid", "storage": "wifi.name"},
+					map[string]any{"request": "channel", "storage": "wifi.channel"},
+					map[string]any{"request": "status", "storage": "wifi.status"},
+				},
+			},
+		},
+		"timestamp": "2030-11-06T09:16:26Z",
+	}
+	body := []byte(fmt.Sprintf(`{
+  "migrations": [
+    {
+      "revision": 1,
+      "rules": [
+        {
+          "from": "wifi.ssid",
+          "op": "rename",
+          "to": "wifi.name"
+        },
+        {
+          "op": "transform",
+          "path": "wifi.channel",
+          "type": "int"
+        }
+      ]
+    }
+  ],
+  "storage": {
+    "schema": {
+      "wifi": {
+        "schema": {
+          "channel": "int",
+          "name": "string",
+          "status": "%s"
+        }
+      }
+    }
+  }
+}`, statusType))
+
+	as, err := s.signingDB.Sign(asserts.ConfdbSchemaType, headers, body, "")
+	c.Assert(err, IsNil)
+	c.Assert(assertstate.Add(s.state, as), IsNil)
+}
+
+func (s *confdbTestSuite) setNetworkDatabag(c *C, values map[string]any) confdb.JSONDatabag {
+	bag := confdb.NewJSONDatabag()
+	for path, value := range values {
+		c.Assert(bag.Set(parsePath(c, path), value), IsNil)
+	}
+	s.state.Set("confdb-databags", map[string]map[string]confdb.JSONDatabag{s.devAccID: {"network": bag}})
+	return bag
+}
+
+func (s *confdbTestSuite) TestGetViewMigratesDatabag(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setNetworkDatabag(c, map[string]any{"wifi.ssid": "foo", "wifi.channel": "11", "wifi.status": "up"})
+	c.Assert(confdbstate.SetDatabagRevision(s.state, s.devAccID, "network", 0), IsNil)
+	s.addSchemaRevision(c, 1, "string")
+
+	view, err := confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+	c.Assert(err, IsNil)
+
+	bag, err := confdbstate.ReadDatabag(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	data, err := bag.Data()
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"wifi":{"channel":11,"name":"foo","status":"up"}}`)
+
+	res, err := confdbstate.GetViaView(bag, view, []string{"ssid", "channel"})
+	c.Assert(err, IsNil)
+	c.Check(res, DeepEquals, map[string]any{"ssid": "foo", "channel": float64(11)})
+
+	rev, tracked, err := confdbstate.DatabagRevision(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Check(tracked, Equals, true)
+	c.Check(rev, Equals, 1)
+
+	// the migrations are not applied again
+	s.setNetworkDatabag(c, map[string]any{"wifi.ssid": "bar"})
+	_, err = confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+	c.Assert(err, IsNil)
+
+	bag, err = confdbstate.ReadDatabag(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	data, err = bag.Data()
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"wifi":{"ssid":"bar"}}`)
+}
+
+func (s *confdbTestSuite) TestGetViewEmptyDatabagOnlyRecordsRevision(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.addSchemaRevision(c, 1, "string")
+
+	_, err := confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+	c.Assert(err, IsNil)
+
+	rev, tracked, err := confdbstate.DatabagRevision(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Check(tracked, Equals, true)
+	c.Check(rev, Equals, 1)
+
+	var databags map[string]map[string]confdb.JSONDatabag
+	c.Check(s.state.Get("confdb-databags", &databags), ErrorMatches, `no state entry for key "confdb-databags"`)
+}
+
+func (s *confdbTestSuite) TestMigrationFailureKeepsDatabag(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for i, tc := range []struct {
+		values     map[string]any
+		statusType string
+		err        string
+	}{
+		{
+			// a rule fails midway, after the rename was applied
+			values:     map[string]any{"wifi.ssid": "foo", "wifi.channel": "eleven"},
+			statusType: "string",
+			err:        `cannot migrate confdb .*/network to revision 1: cannot apply migration for revision 1: cannot transform "wifi.channel": cannot convert "eleven" to int`,
+		},
+		{
+			// the migrated data doesn't match the new storage schema
+			values:     map[string]any{"wifi.ssid": "foo", "wifi.status": "up"},
+			statusType: "int",
+			err:        `cannot migrate confdb .*/network to revision 2: .*`,
+		},
+	} {
+		s.state.Set("confdb-databag-revisions", nil)
+		c.Assert(confdbstate.SetDatabagRevision(s.state, s.devAccID, "network", 0), IsNil)
+		bag := s.setNetworkDatabag(c, tc.values)
+		expected, err := bag.Data()
+		c.Assert(err, IsNil)
+		s.addSchemaRevision(c, i+1, tc.statusType)
+
+		_, err = confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+		c.Assert(err, ErrorMatches, tc.err)
+
+		bag, err = confdbstate.ReadDatabag(s.state, s.devAccID, "network")
+		c.Assert(err, IsNil)
+		data, err := bag.Data()
+		c.Assert(err, IsNil)
+		c.Check(string(data), Equals, string(expected))
+
+		rev, _, err := confdbstate.DatabagRevision(s.state, s.devAccID, "network")
+		c.Assert(err, IsNil)
+		c.Check(rev, Equals, 0)
+	}
+}
+
+func (s *confdbTestSuite) TestEnsureMigratesDatabags(c *C) {
+	s.state.Lock()
+	s.setNetworkDatabag(c, map[string]any{"wifi.ssid": "foo"})
+	c.Assert(confdbstate.SetDatabagRevision(s.state, s.devAccID, "network", 0), IsNil)
+	s.addSchemaRevision(c, 1, "string")
+
+	// databags with ongoing transactions are left for later
+	c.Assert(confdbstate.SetWriteTransaction(s.state, s.devAccID, "network", "1"), IsNil)
+	s.state.Unlock()
+
+	c.Assert(s.mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	// and cannot be accessed through the views of the new revision meanwhile
+	_, err := confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+	c.Assert(err, ErrorMatches, `cannot migrate confdb .*/network to revision 1 yet: transactions are ongoing`)
+
+	rev, _, err := confdbstate.DatabagRevision(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Check(rev, Equals, 0)
+
+	c.Assert(confdbstate.UnsetOngoingTransaction(s.state, s.devAccID, "network", "1"), IsNil)
+	s.state.Unlock()
+	c.Assert(s.mgr.Ensure(), IsNil)
+	s.state.Lock()
+
+	rev, _, err = confdbstate.DatabagRevision(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Check(rev, Equals, 1)
+
+	bag, err := confdbstate.ReadDatabag(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	data, err := bag.Data()
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"wifi":{"name":"foo"}}`)
+}
+
+func (s *confdbTestSuite) TestGetViewUntrackedDatabagNotMigrated(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// written before revisions were tracked, so the layout is unknown
+	s.setNetworkDatabag(c, map[string]any{"wifi.name": "foo", "wifi.channel": 11})
+	s.addSchemaRevision(c, 1, "string")
+
+	_, err := confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+	c.Assert(err, IsNil)
+
+	bag, err := confdbstate.ReadDatabag(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	data, err := bag.Data()
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"wifi":{"channel":11,"name":"foo"}}`)
+
+	rev, tracked, err := confdbstate.DatabagRevision(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Check(tracked, Equals, true)
+	c.Check(rev, Equals, 1)
+}
+
+func (s *confdbTestSuite) TestCommitTracksDatabagRevision(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, tracked, err := confdbstate.DatabagRevision(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Check(tracked, Equals, false)
+
+	s.commitThroughTask(c, map[string]any{"wifi.ssid": "foo"}, confdbstate.Author{Snap: "some-snap"})
+
+	rev, tracked, err := confdbstate.DatabagRevision(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	c.Check(tracked, Equals, true)
+	c.Check(rev, Equals, 0)
+
+	// so the data written at revision 0 gets migrated
+	s.addSchemaRevision(c, 1, "string")
+	_, err = confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+	c.Assert(err, IsNil)
+
+	bag, err := confdbstate.ReadDatabag(s.state, s.devAccID, "network")
+	c.Assert(err, IsNil)
+	data, err := bag.Data()
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"wifi":{"name":"foo"}}`)
+}
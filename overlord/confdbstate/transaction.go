@@ -47,6 +47,10 @@ type Transaction struct {
 	abortingSnap string
 	abortReason  string
 
+	// author is who made the changes, recorded in the history once they're
+	// committed
+	author Author
+
 	mu sync.RWMutex
 }
 
@@ -85,6 +89,8 @@ type marshalledTransaction struct {
 
 	AbortingSnap string `json:"aborting-snap,omitempty"`
 	AbortReason  string `json:"abort-reason,omitempty"`
+
+	Author *Author `json:"author,omitempty"`
 }
 
 func (t *Transaction) MarshalJSON() ([]byte, error) {
@@ -95,6 +101,11 @@ func (t *Transaction) MarshalJSON() ([]byte, error) {
 		})
 	}
 
+	var author *Author
+	if t.author != (Author{}) {
+		author = &t.author
+	}
+
 	return json.Marshal(marshalledTransaction{
 		Pristine:      t.pristine,
 		Previous:      t.previous,
@@ -105,6 +116,7 @@ func (t *Transaction) MarshalJSON() ([]byte, error) {
 		AppliedDeltas: t.appliedDeltas,
 		AbortingSnap:  t.abortingSnap,
 		AbortReason:   t.abortReason,
+		Author:        author,
 	})
 }
 
@@ -135,6 +147,9 @@ func (t *Transaction) UnmarshalJSON(data []byte) error {
 	t.appliedDeltas = mt.AppliedDeltas
 	t.abortingSnap = mt.AbortingSnap
 	t.abortReason = mt.AbortReason
+	if mt.Author != nil {
+		t.author = *mt.Author
+	}
 
 	return nil
 }
@@ -222,6 +237,11 @@ func (t *Transaction) Commit(st *state.State, schema confdb.DatabagSchema) error
 		return err
 	}
 
+	// the data was validated against the storage of the current revision
+	if err := trackDatabagRevision(st, t.ConfdbAccount, t.ConfdbName); err != nil {
+		return err
+	}
+
 	t.pristine = pristine
 	t.modified = nil
 	t.deltas = nil
@@ -329,6 +349,22 @@ func (t *Transaction) AbortInfo() (snap, reason string) {
 	return t.abortingSnap, t.abortReason
 }
 
+// SetAuthor sets who made the changes in the transaction.
+func (t *Transaction) SetAuthor(author Author) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.author = author
+}
+
+// Author returns who made the changes in the transaction.
+func (t *Transaction) Author() Author {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.author
+}
+
 func (t *Transaction) Previous() confdb.Databag {
 	return t.previous
 }
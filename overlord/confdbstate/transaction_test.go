@@ -24,6 +24,7 @@ import (
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/confdb"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/confdbstate"
@@ -61,6 +62,12 @@ func (s *transactionTestSuite) SetUpTest(c *C) {
 		return confdbstate.WriteDatabag(st, bag, account, confdbName)
 	})
 	s.AddCleanup(restore)
+
+	// there is no assertion to track the revision of the databags with
+	restore = confdbstate.MockAssertstateConfdbSchema(func(*state.State, string, string) (*asserts.ConfdbSchema, error) {
+		return nil, &asserts.NotFoundError{Type: asserts.ConfdbSchemaType}
+	})
+	s.AddCleanup(restore)
 }
 
 var _ = Suite(&transactionTestSuite{})
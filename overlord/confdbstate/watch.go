@@ -0,0 +1,206 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package confdbstate
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/snapcore/snapd/confdb"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// ViewChange describes a committed transaction which changed data accessible
+// through a watched view.
+type ViewChange struct {
+	Time   time.Time `json:"time"`
+	Author Author    `json:"author"`
+	// Changes are the changes to the storage paths which can be accessed
+	// through the view.
+	Changes []PathChange `json:"changes"`
+	// Values holds the values of the watched requests after the transaction,
+	// as they would be returned by GetViaView. It's nil if none of them has
+	// any data left.
+	Values any `json:"values,omitempty"`
+}
+
+type viewWatcher struct {
+	account      string
+	dbSchemaName string
+	viewName     string
+	requests     []string
+	handler      func(*ViewChange)
+}
+
+type viewWatchers struct {
+	lastID   int
+	watchers map[int]*viewWatcher
+}
+
+type viewWatchersKey struct{}
+
+func cachedViewWatchers(st *state.State) *viewWatchers {
+	if watchers, ok := st.Cached(viewWatchersKey{}).(*viewWatchers); ok {
+		return watchers
+	}
+
+	watchers := &viewWatchers{watchers: make(map[int]*viewWatcher)}
+	st.Cache(viewWatchersKey{}, watchers)
+	return watchers
+}
+
+// WatchView registers a handler to be called after each committed
+// transaction which changes the values of the requests through the view, or
+// of the whole view if there are no requests. The handler is called with the
+// state locked, so it must not block. The values are read directly from the
+// databag, without running any load-view or query-view hooks. It returns an
+// ID which can be passed to UnwatchView or an error if the requests can't be
+// read through the view. The state must be locked by the caller.
+//
+// Migrations of the databag are not notified, since they don't change the
+// data accessible through the view. After those, the values are read through
+// the view as defined in the latest revision of the confdb-schema.
+func WatchView(st *state.State, view *confdb.View, requests []string, handler func(*ViewChange)) (int, error) {
+	// check that the requests match the view
+	if _, err := GetViaView(confdb.NewJSONDatabag(), view, requests); err != nil && !errors.Is(err, &confdb.NoDataError{}) {
+		return 0, err
+	}
+
+	watchers := cachedViewWatchers(st)
+	watchers.lastID++
+	watchers.watchers[watchers.lastID] = &viewWatcher{
+		account:      view.Schema().Account,
+		dbSchemaName: view.Schema().Name,
+		viewName:     view.Name,
+		requests:     requests,
+		handler:      handler,
+	}
+	return watchers.lastID, nil
+}
+
+// UnwatchView removes the handler registered by WatchView with the given ID.
+// The state must be locked by the caller.
+func UnwatchView(st *state.State, id int) {
+	delete(cachedViewWatchers(st).watchers, id)
+}
+
+// notifyViewWatchers calls the handlers watching views of the confdb-schema
+// whose values changed between the previous and the current databag.
+func notifyViewWatchers(st *state.State, account, dbSchemaName string, author Author, previous, current confdb.JSONDatabag) {
+	watchers := cachedViewWatchers(st)
+	if len(watchers.watchers) == 0 {
+		return
+	}
+
+	var dbSchema *confdb.Schema
+	var changes []PathChange
+	for id, watcher := range watchers.watchers {
+		if watcher.account != account || watcher.dbSchemaName != dbSchemaName {
+			continue
+		}
+
+		if dbSchema == nil {
+			confdbSchemaAs, err := assertstateConfdbSchema(st, account, dbSchemaName)
+			if err != nil {
+				logger.Noticef("cannot notify confdb %s/%s watchers: %v", account, dbSchemaName, err)
+				return
+			}
+			dbSchema = confdbSchemaAs.Schema()
+
+			changes, err = diffDatabags(previous, current)
+			if err != nil {
+				logger.Noticef("cannot notify confdb %s/%s watchers: %v", account, dbSchemaName, err)
+				return
+			}
+		}
+
+		view := dbSchema.View(watcher.viewName)
+		if view == nil {
+			// the view was removed in a later revision of the confdb-schema
+			continue
+		}
+
+		change, err := watcher.viewChange(view, changes, author, previous, current)
+		if err != nil {
+			logger.Noticef("cannot notify watcher %d of view %s: %v", id, view.ID(), err)
+			continue
+		}
+		if change != nil {
+			watcher.handler(change)
+		}
+	}
+}
+
+// viewChange returns the change to notify the watcher about or nil if the
+// watched values didn't change.
+func (w *viewWatcher) viewChange(view *confdb.View, changes []PathChange, author Author, previous, current confdb.JSONDatabag) (*ViewChange, error) {
+	var affected []PathChange
+	for _, change := range changes {
+		path, err := confdb.ParsePathIntoAccessors(change.Path, confdb.ParseOptions{})
+		if err != nil {
+			return nil, err
+		}
+		// the values of paths which can't be read through the view must not
+		// be leaked to the watcher
+		if view.CanReadPath(path) {
+			affected = append(affected, change)
+		}
+	}
+	if len(affected) == 0 {
+		return nil, nil
+	}
+
+	oldValues, err := w.values(view, previous)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := w.values(view, current)
+	if err != nil {
+		return nil, err
+	}
+	// the path may be accessible through the view but not by the requests
+	if reflect.DeepEqual(oldValues, newValues) {
+		return nil, nil
+	}
+
+	return &ViewChange{
+		Time:    timeNow(),
+		Author:  author,
+		Changes: affected,
+		Values:  newValues,
+	}, nil
+}
+
+func (w *viewWatcher) values(view *confdb.View, bag confdb.JSONDatabag) (any, error) {
+	if bag == nil {
+		return nil, nil
+	}
+
+	values, err := GetViaView(bag, view, w.requests)
+	if err != nil {
+		if errors.Is(err, &confdb.NoDataError{}) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,128 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package confdbstate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/confdb"
+	"github.com/snapcore/snapd/overlord/confdbstate"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *confdbTestSuite) TestWatchView(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	view, err := confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+	c.Assert(err, IsNil)
+
+	var ssidChanges, viewChanges []*confdbstate.ViewChange
+	ssidID, err := confdbstate.WatchView(s.state, view, []string{"ssid"}, func(change *confdbstate.ViewChange) {
+		ssidChanges = append(ssidChanges, change)
+	})
+	c.Assert(err, IsNil)
+	viewID, err := confdbstate.WatchView(s.state, view, nil, func(change *confdbstate.ViewChange) {
+		viewChanges = append(viewChanges, change)
+	})
+	c.Assert(err, IsNil)
+	c.Check(ssidID, Not(Equals), viewID)
+
+	uid := uint32(1000)
+	s.commitThroughTask(c, map[string]any{"wifi.ssid": "foo"}, confdbstate.Author{UID: &uid})
+	c.Assert(ssidChanges, HasLen, 1)
+	c.Check(ssidChanges[0].Time.IsZero(), Equals, false)
+	c.Check(ssidChanges[0].Author, DeepEquals, confdbstate.Author{UID: &uid})
+	c.Check(ssidChanges[0].Changes, DeepEquals, []confdbstate.PathChange{{Path: "wifi.ssid", New: "foo"}})
+	c.Check(ssidChanges[0].Values, DeepEquals, map[string]any{"ssid": "foo"})
+	c.Assert(viewChanges, HasLen, 1)
+	c.Check(viewChanges[0].Values, DeepEquals, map[string]any{"ssid": "foo"})
+
+	// the status isn't watched by the first watcher
+	s.commitThroughTask(c, map[string]any{"wifi.status": "up"}, confdbstate.Author{Snap: "some-snap"})
+	c.Check(ssidChanges, HasLen, 1)
+	c.Assert(viewChanges, HasLen, 2)
+	c.Check(viewChanges[1].Author, DeepEquals, confdbstate.Author{Snap: "some-snap"})
+	c.Check(viewChanges[1].Changes, DeepEquals, []confdbstate.PathChange{{Path: "wifi.status", New: "up"}})
+	c.Check(viewChanges[1].Values, DeepEquals, map[string]any{"ssid": "foo", "status": "up"})
+
+	// the password can't be read through the view so its value isn't streamed
+	s.commitThroughTask(c, map[string]any{"wifi.psk": "secret"}, confdbstate.Author{Snap: "some-snap"})
+	c.Check(ssidChanges, HasLen, 1)
+	c.Check(viewChanges, HasLen, 2)
+
+	// nor is it when it's changed along with readable paths
+	s.commitThroughTask(c, map[string]any{"wifi.ssid": "bar", "wifi.psk": "other"}, confdbstate.Author{Snap: "some-snap"})
+	c.Assert(ssidChanges, HasLen, 2)
+	c.Check(ssidChanges[1].Changes, DeepEquals, []confdbstate.PathChange{{Path: "wifi.ssid", Old: "foo", New: "bar"}})
+	c.Check(ssidChanges[1].Values, DeepEquals, map[string]any{"ssid": "bar"})
+	c.Assert(viewChanges, HasLen, 3)
+	c.Check(viewChanges[2].Changes, DeepEquals, []confdbstate.PathChange{{Path: "wifi.ssid", Old: "foo", New: "bar"}})
+
+	confdbstate.UnwatchView(s.state, viewID)
+	s.commitThroughTask(c, map[string]any{"wifi.ssid": "baz"}, confdbstate.Author{Snap: "some-snap"})
+	c.Assert(ssidChanges, HasLen, 3)
+	c.Check(ssidChanges[2].Changes, DeepEquals, []confdbstate.PathChange{{Path: "wifi.ssid", Old: "bar", New: "baz"}})
+	c.Check(ssidChanges[2].Values, DeepEquals, map[string]any{"ssid": "baz"})
+	c.Check(viewChanges, HasLen, 3)
+
+	confdbstate.UnwatchView(s.state, ssidID)
+	s.commitThroughTask(c, map[string]any{"wifi.ssid": "qux"}, confdbstate.Author{Snap: "some-snap"})
+	c.Check(ssidChanges, HasLen, 3)
+}
+
+func (s *confdbTestSuite) TestWatchViewAfterMigration(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setNetworkDatabag(c, map[string]any{"wifi": map[string]any{"ssid": "foo"}})
+
+	view, err := confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+	c.Assert(err, IsNil)
+
+	var changes []*confdbstate.ViewChange
+	_, err = confdbstate.WatchView(s.state, view, []string{"ssid"}, func(change *confdbstate.ViewChange) {
+		changes = append(changes, change)
+	})
+	c.Assert(err, IsNil)
+
+	s.addSchemaRevision(c, 1, "string")
+	_, err = confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+	c.Assert(err, IsNil)
+
+	c.Check(changes, HasLen, 0)
+
+	// later changes are read through the view of the new revision
+	s.commitThroughTask(c, map[string]any{"wifi.name": "bar"}, confdbstate.Author{Snap: "some-snap"})
+	c.Assert(changes, HasLen, 1)
+	c.Check(changes[0].Changes, DeepEquals, []confdbstate.PathChange{{Path: "wifi.name", Old: "foo", New: "bar"}})
+	c.Check(changes[0].Values, DeepEquals, map[string]any{"ssid": "bar"})
+}
+
+func (s *confdbTestSuite) TestWatchViewBadRequest(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	view, err := confdbstate.GetView(s.state, s.devAccID, "network", "setup-wifi")
+	c.Assert(err, IsNil)
+
+	_, err = confdbstate.WatchView(s.state, view, []string{"foo"}, func(*confdbstate.ViewChange) {})
+	c.Assert(err, testutil.ErrorIs, &confdb.NoMatchError{})
+}
@@ -0,0 +1,73 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cacheconf
+
+import (
+	"time"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/strutil"
+)
+
+type CacheSettings struct {
+	st *state.State
+}
+
+func New(st *state.State) *CacheSettings {
+	return &CacheSettings{st: st}
+}
+
+// Limits returns the download cache limits as configured via the
+// store.cache.* core options. The values are validated when they are set.
+func (c *CacheSettings) Limits() (*store.CacheLimits, error) {
+	c.st.Lock()
+	tr := config.NewTransaction(c.st)
+	c.st.Unlock()
+
+	var maxSize, maxAge, secondaryDir string
+	for key, value := range map[string]*string{
+		"store.cache.max-size":      &maxSize,
+		"store.cache.max-age":       &maxAge,
+		"store.cache.secondary-dir": &secondaryDir,
+	} {
+		if err := tr.GetMaybe("core", key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	limits := &store.CacheLimits{SecondaryDir: secondaryDir}
+	if maxSize != "" {
+		size, err := strutil.ParseByteSize(maxSize)
+		if err != nil {
+			return nil, err
+		}
+		limits.MaxSize = size
+	}
+	if maxAge != "" {
+		age, err := time.ParseDuration(maxAge)
+		if err != nil {
+			return nil, err
+		}
+		limits.MaxAge = age
+	}
+	return limits, nil
+}
@@ -0,0 +1,79 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cacheconf_test
+
+import (
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/cacheconf"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/store"
+)
+
+func TestT(t *testing.T) { TestingT(t) }
+
+type cacheconfSuite struct{}
+
+var _ = Suite(&cacheconfSuite{})
+
+func (s *cacheconfSuite) TestLimitsNoSetting(c *C) {
+	st := state.New(nil)
+
+	limits, err := cacheconf.New(st).Limits()
+	c.Assert(err, IsNil)
+	c.Check(limits, DeepEquals, &store.CacheLimits{})
+}
+
+func (s *cacheconfSuite) TestLimits(c *C) {
+	st := state.New(nil)
+
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "store.cache.max-size", "2KB")
+	tr.Set("core", "store.cache.max-age", "48h")
+	tr.Set("core", "store.cache.secondary-dir", "/srv/snap-cache")
+	tr.Commit()
+	st.Unlock()
+
+	limits, err := cacheconf.New(st).Limits()
+	c.Assert(err, IsNil)
+	c.Check(limits, DeepEquals, &store.CacheLimits{
+		MaxSize:      2000,
+		MaxAge:       48 * time.Hour,
+		SecondaryDir: "/srv/snap-cache",
+	})
+}
+
+func (s *cacheconfSuite) TestLimitsInvalid(c *C) {
+	st := state.New(nil)
+
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "store.cache.max-age", "forever")
+	tr.Commit()
+	st.Unlock()
+
+	_, err := cacheconf.New(st).Limits()
+	c.Check(err, ErrorMatches, `time: invalid duration "forever"`)
+}
@@ -0,0 +1,44 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"strconv"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.quota.usage-alert-threshold"] = true
+}
+
+func validateQuotaUsageAlertThreshold(tr RunTransaction) error {
+	threshold, err := coreCfg(tr, "quota.usage-alert-threshold")
+	if err != nil {
+		return err
+	}
+	if threshold == "" {
+		return nil
+	}
+	if n, err := strconv.ParseUint(threshold, 10, 8); err != nil || n == 0 || n > 100 {
+		return fmt.Errorf("quota.usage-alert-threshold must be a percentage between 1 and 100, not %q", threshold)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type quotasSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&quotasSuite{})
+
+func (s *quotasSuite) TestConfigureQuotaUsageAlertThresholdHappy(c *C) {
+	for _, value := range []any{"80", 1, 100} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]any{
+				"quota.usage-alert-threshold": value,
+			},
+		})
+		c.Check(err, IsNil)
+	}
+}
+
+func (s *quotasSuite) TestConfigureQuotaUsageAlertThresholdInvalid(c *C) {
+	for _, value := range []string{"0", "101", "-1", "80%", "high"} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]any{
+				"quota.usage-alert-threshold": value,
+			},
+		})
+		c.Check(err, ErrorMatches, `quota.usage-alert-threshold must be a percentage between 1 and 100, not "`+value+`"`)
+	}
+}
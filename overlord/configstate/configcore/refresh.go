@@ -23,9 +23,12 @@ package configcore
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/timeutil"
 )
@@ -42,7 +45,12 @@ func init() {
 	supportedConfigurations["core.refresh.metered"] = true
 	supportedConfigurations["core.refresh.retain"] = true
 	supportedConfigurations["core.refresh.rate-limit"] = true
+	supportedConfigurations["core.refresh.rate-schedule"] = true
+	supportedConfigurations["core.refresh.daily-download-limit"] = true
 	supportedConfigurations["core.refresh.max-inhibition-days"] = true
+	supportedConfigurations["core.refresh.health-rollback"] = true
+	supportedConfigurations["core.refresh.health-rollback-window"] = true
+	supportedConfigurations["core.refresh.waves"] = true
 }
 
 func reportOrIgnoreInvalidManageRefreshes(tr RunTransaction, optName string) error {
@@ -163,3 +171,90 @@ func validateRefreshRateLimit(tr RunTransaction) error {
 	}
 	return nil
 }
+
+func validateRefreshDownloadLimits(tr RunTransaction) error {
+	schedule, err := coreCfg(tr, "refresh.rate-schedule")
+	if err != nil {
+		return err
+	}
+	if err := snapstate.ValidateRefreshRateSchedule(schedule); err != nil {
+		return err
+	}
+
+	dailyLimit, err := coreCfg(tr, "refresh.daily-download-limit")
+	if err != nil {
+		return err
+	}
+	if dailyLimit == "" {
+		return nil
+	}
+	limit, err := strutil.ParseByteSize(dailyLimit)
+	if err != nil {
+		return fmt.Errorf("refresh.daily-download-limit: %v", err)
+	}
+	if limit == 0 {
+		return fmt.Errorf("refresh.daily-download-limit cannot be 0")
+	}
+	return nil
+}
+
+func validateRefreshHealthRollback(tr RunTransaction) error {
+	snaps, err := coreCfg(tr, "refresh.health-rollback")
+	if err != nil {
+		return err
+	}
+	for _, snapName := range strutil.CommaSeparatedList(snaps) {
+		if err := snap.ValidateInstanceName(snapName); err != nil {
+			return fmt.Errorf("refresh.health-rollback: %v", err)
+		}
+	}
+
+	window, err := coreCfg(tr, "refresh.health-rollback-window")
+	if err != nil {
+		return err
+	}
+	if window == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(window); err != nil || d <= 0 {
+		return fmt.Errorf("refresh.health-rollback-window must be a positive duration, not %q", window)
+	}
+	return nil
+}
+
+const refreshWindowsPrefix = "core.refresh.windows."
+
+// isRefreshWindowOption tells whether the given key is
+//
+//	core.refresh.windows.<snap>
+func isRefreshWindowOption(key string) bool {
+	if !strings.HasPrefix(key, refreshWindowsPrefix) {
+		return false
+	}
+	return snap.ValidateInstanceName(strings.TrimPrefix(key, refreshWindowsPrefix)) == nil
+}
+
+func validateRefreshWindowsAndWaves(tr RunTransaction) error {
+	for _, key := range tr.Changes() {
+		if !strings.HasPrefix(key, refreshWindowsPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, "core.")
+		window, err := coreCfg(tr, name)
+		if err != nil {
+			return err
+		}
+		if window == "" {
+			continue
+		}
+		if _, err := timeutil.ParseSchedule(window); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	waves, err := coreCfg(tr, "refresh.waves")
+	if err != nil {
+		return err
+	}
+	return snapstate.ValidateRefreshWaves(waves)
+}
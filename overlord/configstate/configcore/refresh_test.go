@@ -252,3 +252,118 @@ func (s *refreshSuite) TestConfigureRefreshMaxInhibitionDays(c *C) {
 		}
 	}
 }
+
+func (s *refreshSuite) TestConfigureRefreshHealthRollback(c *C) {
+	data := []struct {
+		key string
+		val any
+		err string
+	}{
+		{key: "refresh.health-rollback", val: "foo,bar_instance"},
+		{key: "refresh.health-rollback", val: ""},
+		{key: "refresh.health-rollback", val: "foo,-bad", err: `refresh.health-rollback: invalid snap name: "-bad"`},
+		{key: "refresh.health-rollback-window", val: "10m"},
+		{key: "refresh.health-rollback-window", val: "0s", err: `refresh.health-rollback-window must be a positive duration, not "0s"`},
+		{key: "refresh.health-rollback-window", val: "soon", err: `refresh.health-rollback-window must be a positive duration, not "soon"`},
+	}
+	for _, tc := range data {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]any{
+				tc.key: tc.val,
+			},
+		})
+		if tc.err != "" {
+			c.Check(err, ErrorMatches, tc.err)
+		} else {
+			c.Check(err, IsNil)
+		}
+	}
+}
+
+func (s *refreshSuite) TestConfigureRefreshWindows(c *C) {
+	data := []struct {
+		key string
+		val any
+		err string
+	}{
+		{key: "refresh.windows.foo", val: "sun,23:00-01:00"},
+		{key: "refresh.windows.foo_instance", val: "mon1-fri,10:00-11:00"},
+		{key: "refresh.windows.foo", val: ""},
+		{key: "refresh.windows.foo", val: "someday", err: `refresh.windows.foo: cannot parse "someday": .*`},
+		{key: "refresh.windows.Foo", val: "sun", err: `cannot set "core.refresh.windows.Foo": unsupported system option`},
+		{key: "refresh.windows.foo.bar", val: "sun", err: `cannot set "core.refresh.windows.foo.bar": unsupported system option`},
+	}
+	for _, tc := range data {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			changes: map[string]any{
+				tc.key: tc.val,
+			},
+		})
+		if tc.err != "" {
+			c.Check(err, ErrorMatches, tc.err, Commentf("%s=%v", tc.key, tc.val))
+		} else {
+			c.Check(err, IsNil, Commentf("%s=%v", tc.key, tc.val))
+		}
+	}
+}
+
+func (s *refreshSuite) TestConfigureRefreshWaves(c *C) {
+	data := []struct {
+		val any
+		err string
+	}{
+		{val: "*;@kernel,@gadget"},
+		{val: "foo,bar;baz"},
+		{val: ""},
+		{val: "foo;;bar", err: `cannot parse refresh waves "foo;;bar": wave 2 is empty`},
+		{val: "@potato", err: `cannot parse refresh waves "@potato": unknown snap type "potato"`},
+	}
+	for _, tc := range data {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			changes: map[string]any{
+				"refresh.waves": tc.val,
+			},
+		})
+		if tc.err != "" {
+			c.Check(err, ErrorMatches, tc.err, Commentf("%v", tc.val))
+		} else {
+			c.Check(err, IsNil, Commentf("%v", tc.val))
+		}
+	}
+}
+
+func (s *refreshSuite) TestConfigureRefreshDownloadLimits(c *C) {
+	data := []struct {
+		key string
+		val any
+		err string
+	}{
+		{key: "refresh.rate-schedule", val: "01:00-05:00=unlimited"},
+		{key: "refresh.rate-schedule", val: "01:00-05:00=unlimited;mon-fri,9:00-17:00=20KB"},
+		{key: "refresh.rate-schedule", val: ""},
+		{key: "refresh.rate-schedule", val: "01:00-05:00", err: `cannot parse rate schedule "01:00-05:00": "01:00-05:00" is not <timer>=<rate>`},
+		{key: "refresh.rate-schedule", val: "someday=1MB", err: `cannot parse rate schedule "someday=1MB": cannot parse "someday": .*`},
+		{key: "refresh.rate-schedule", val: "01:00-05:00=fast", err: `cannot parse rate schedule "01:00-05:00=fast": cannot parse "fast": .*`},
+		{key: "refresh.rate-schedule", val: "01:00-05:00=0B", err: `cannot parse rate schedule "01:00-05:00=0B": rate cannot be 0, use "unlimited" for no limit`},
+		{key: "refresh.daily-download-limit", val: "200MB"},
+		{key: "refresh.daily-download-limit", val: ""},
+		{key: "refresh.daily-download-limit", val: "lots", err: `refresh.daily-download-limit: cannot parse "lots": .*`},
+		{key: "refresh.daily-download-limit", val: "0B", err: `refresh.daily-download-limit cannot be 0`},
+	}
+	for _, tc := range data {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			changes: map[string]any{
+				tc.key: tc.val,
+			},
+		})
+		if tc.err != "" {
+			c.Check(err, ErrorMatches, tc.err, Commentf("%s=%v", tc.key, tc.val))
+		} else {
+			c.Check(err, IsNil, Commentf("%s=%v", tc.key, tc.val))
+		}
+	}
+}
@@ -59,7 +59,15 @@ func init() {
 	validateOnly := &flags{validatedOnlyStateConfig: true}
 	addWithStateHandler(validateRefreshSchedule, nil, validateOnly)
 	addWithStateHandler(validateRefreshRateLimit, nil, validateOnly)
+	addWithStateHandler(validateRefreshDownloadLimits, nil, validateOnly)
+	addWithStateHandler(validateRefreshHealthRollback, nil, validateOnly)
+	addWithStateHandler(validateRefreshWindowsAndWaves, nil, validateOnly)
 	addWithStateHandler(validateAutomaticSnapshotsExpiration, nil, validateOnly)
+	addWithStateHandler(validateSnapshotsRetention, nil, validateOnly)
+	addWithStateHandler(validateSnapshotsS3Endpoint, nil, validateOnly)
+	addWithStateHandler(validateQuotaUsageAlertThreshold, nil, validateOnly)
+	addWithStateHandler(validateStoreCacheSettings, nil, validateOnly)
+	addWithStateHandler(validateStorePeersSettings, nil, validateOnly)
 
 	// netplan.*
 	addWithStateHandler(validateNetplanSettings, handleNetplanConfiguration, coreOnly)
@@ -169,6 +177,10 @@ func applyHandlers(dev sysconfig.Device, cfg RunTransaction, handlers []configHa
 			if release.OnClassic {
 				return fmt.Errorf("cannot set netplan configuration on classic")
 			}
+		case isSnapshotsRetentionOption(k):
+			// the options are per snap, checked by validateSnapshotsRetention
+		case isRefreshWindowOption(k):
+			// the options are per snap, checked by validateRefreshWindowsAndWaves
 		case !supportedConfigurations[k]:
 			return fmt.Errorf("cannot set %q: unsupported system option", k)
 		}
@@ -22,12 +22,22 @@ package configcore
 
 import (
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
 )
 
 func init() {
 	// add supported configuration of this module
 	supportedConfigurations["core.snapshots.automatic.retention"] = true
+	supportedConfigurations["core.snapshots.s3.endpoint"] = true
+	supportedConfigurations["core.snapshots.s3.region"] = true
+	supportedConfigurations["core.snapshots.s3.access-key-id"] = true
+	supportedConfigurations["core.snapshots.s3.secret-access-key"] = true
 }
 
 func validateAutomaticSnapshotsExpiration(tr RunTransaction) error {
@@ -46,3 +56,69 @@ func validateAutomaticSnapshotsExpiration(tr RunTransaction) error {
 	}
 	return nil
 }
+
+const snapshotsRetentionPrefix = "core.snapshots.retention."
+
+// isSnapshotsRetentionOption tells whether the given key is either
+//
+//	core.snapshots.retention.<kind>.<rule>
+//	core.snapshots.retention.snaps.<snap>.<kind>.<rule>
+func isSnapshotsRetentionOption(key string) bool {
+	if !strings.HasPrefix(key, snapshotsRetentionPrefix) {
+		return false
+	}
+	parts := strings.Split(strings.TrimPrefix(key, snapshotsRetentionPrefix), ".")
+	if len(parts) == 4 && parts[0] == "snaps" && snap.ValidateInstanceName(parts[1]) == nil {
+		parts = parts[2:]
+	}
+	if len(parts) != 2 || (parts[0] != "manual" && parts[0] != "automatic") {
+		return false
+	}
+	switch parts[1] {
+	case "keep-last", "keep-daily", "max-size":
+		return true
+	}
+	return false
+}
+
+func validateSnapshotsRetention(tr RunTransaction) error {
+	for _, key := range tr.Changes() {
+		if !strings.HasPrefix(key, snapshotsRetentionPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, "core.")
+		value, err := coreCfg(tr, name)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, ".keep-last"), strings.HasSuffix(name, ".keep-daily"):
+			if n, err := strconv.ParseUint(value, 10, 16); err != nil || n == 0 {
+				return fmt.Errorf("%s must be a positive number, not %q", name, value)
+			}
+		case strings.HasSuffix(name, ".max-size"):
+			if n, err := strutil.ParseByteSize(value); err != nil || n <= 0 {
+				return fmt.Errorf("%s must be a positive size, not %q", name, value)
+			}
+		}
+	}
+	return nil
+}
+
+func validateSnapshotsS3Endpoint(tr RunTransaction) error {
+	endpoint, err := coreCfg(tr, "snapshots.s3.endpoint")
+	if err != nil {
+		return err
+	}
+	if endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.RawQuery != "" {
+		return fmt.Errorf("snapshots.s3.endpoint must be an http or https URL, not %q", endpoint)
+	}
+	return nil
+}
@@ -71,3 +71,78 @@ func (s *refreshSuite) TestConfigureAutomaticSnapshotsExpirationInvalid(c *C) {
 	})
 	c.Assert(err, ErrorMatches, `snapshots.automatic.retention cannot be parsed:.*`)
 }
+
+func (s *snapshotsSuite) TestConfigureSnapshotsRetentionHappy(c *C) {
+	err := configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]any{
+			"snapshots.retention.manual.keep-last":               "3",
+			"snapshots.retention.automatic.keep-daily":           7,
+			"snapshots.retention.automatic.max-size":             "2GB",
+			"snapshots.retention.snaps.foo_bar.manual.keep-last": 1,
+		},
+		changes: map[string]any{
+			"snapshots.retention.manual.keep-last":               "3",
+			"snapshots.retention.automatic.keep-daily":           7,
+			"snapshots.retention.automatic.max-size":             "2GB",
+			"snapshots.retention.snaps.foo_bar.manual.keep-last": 1,
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *snapshotsSuite) TestConfigureSnapshotsRetentionUnsupported(c *C) {
+	for _, key := range []string{
+		"snapshots.retention.manual",
+		"snapshots.retention.other.keep-last",
+		"snapshots.retention.manual.keep-all",
+		"snapshots.retention.snaps.foo.keep-last",
+		"snapshots.retention.snaps.Foo.manual.keep-last",
+		"snapshots.retention.snaps.foo.manual.keep-last.more",
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state:   s.state,
+			changes: map[string]any{key: "1"},
+		})
+		c.Check(err, ErrorMatches, `cannot set "core.`+key+`": unsupported system option`)
+	}
+}
+
+func (s *snapshotsSuite) TestConfigureSnapshotsRetentionInvalid(c *C) {
+	for _, t := range []struct {
+		key, value, err string
+	}{
+		{"snapshots.retention.manual.keep-last", "0", `snapshots.retention.manual.keep-last must be a positive number, not "0"`},
+		{"snapshots.retention.snaps.foo.automatic.keep-daily", "x", `snapshots.retention.snaps.foo.automatic.keep-daily must be a positive number, not "x"`},
+		{"snapshots.retention.manual.max-size", "10", `snapshots.retention.manual.max-size must be a positive size, not "10"`},
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state:   s.state,
+			conf:    map[string]any{t.key: t.value},
+			changes: map[string]any{t.key: t.value},
+		})
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
+func (s *snapshotsSuite) TestConfigureSnapshotsS3Endpoint(c *C) {
+	for _, endpoint := range []string{"https://s3.example.com", "http://localhost:9000/"} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]any{
+				"snapshots.s3.endpoint": endpoint,
+			},
+		})
+		c.Check(err, IsNil)
+	}
+
+	for _, endpoint := range []string{"s3.example.com", "ftp://s3.example.com", "https://"} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]any{
+				"snapshots.s3.endpoint": endpoint,
+			},
+		})
+		c.Check(err, ErrorMatches, `snapshots.s3.endpoint must be an http or https URL, not ".*"`)
+	}
+}
@@ -22,16 +22,28 @@ package configcore
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/store/peers"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/sysconfig"
 )
 
 func init() {
 	supportedConfigurations["core.store.access"] = true
+	supportedConfigurations["core.store.cache.max-size"] = true
+	supportedConfigurations["core.store.cache.max-age"] = true
+	supportedConfigurations["core.store.cache.secondary-dir"] = true
+	supportedConfigurations["core.store.peers.enabled"] = true
+	supportedConfigurations["core.store.peers.mdns"] = true
+	supportedConfigurations["core.store.peers.port"] = true
+	supportedConfigurations["core.store.peers.static"] = true
 }
 
 func validateStoreAccess(cfg ConfGetter) error {
@@ -48,6 +60,71 @@ func validateStoreAccess(cfg ConfGetter) error {
 	}
 }
 
+func validateStoreCacheSettings(tr RunTransaction) error {
+	maxSize, err := coreCfg(tr, "store.cache.max-size")
+	if err != nil {
+		return err
+	}
+	if maxSize != "" {
+		if _, err := strutil.ParseByteSize(maxSize); err != nil {
+			return fmt.Errorf("store.cache.max-size: %v", err)
+		}
+	}
+
+	maxAge, err := coreCfg(tr, "store.cache.max-age")
+	if err != nil {
+		return err
+	}
+	if maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil {
+			return fmt.Errorf("store.cache.max-age: %v", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("store.cache.max-age must be positive: %q", maxAge)
+		}
+	}
+
+	secondaryDir, err := coreCfg(tr, "store.cache.secondary-dir")
+	if err != nil {
+		return err
+	}
+	if secondaryDir != "" && !filepath.IsAbs(secondaryDir) {
+		return fmt.Errorf("store.cache.secondary-dir must be an absolute path: %q", secondaryDir)
+	}
+
+	return nil
+}
+
+func validateStorePeersSettings(tr RunTransaction) error {
+	for _, flag := range []string{"store.peers.enabled", "store.peers.mdns"} {
+		if err := validateBoolFlag(tr, flag); err != nil {
+			return err
+		}
+	}
+
+	port, err := coreCfg(tr, "store.peers.port")
+	if err != nil {
+		return err
+	}
+	if port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("store.peers.port must be a port number between 1 and 65535: %q", port)
+		}
+	}
+
+	static, err := coreCfg(tr, "store.peers.static")
+	if err != nil {
+		return err
+	}
+	if _, err := peers.ParseStaticPeers(static); err != nil {
+		return fmt.Errorf("store.peers.static: %v", err)
+	}
+
+	return nil
+}
+
 // repairConfig is a set of configuration data that is consumed by the
 // snap-repair command. This struct is duplicated in cmd/snap-repair.
 type repairConfig struct {
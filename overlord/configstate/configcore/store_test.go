@@ -78,6 +78,72 @@ func (s *storeSuite) TestStoreAccessUnhappy(c *C) {
 	c.Assert(err, ErrorMatches, ".*store access can only be set to 'offline'")
 }
 
+func (s *storeSuite) TestStoreCacheSettingsHappy(c *C) {
+	err := configcore.Run(coreDev, &mockConf{
+		state: s.state,
+		changes: map[string]any{
+			"store.cache.max-size":      "2GB",
+			"store.cache.max-age":       "720h",
+			"store.cache.secondary-dir": "/srv/snap-cache",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *storeSuite) TestStoreCacheSettingsUnhappy(c *C) {
+	for _, tc := range []struct {
+		key, value, err string
+	}{
+		{"store.cache.max-size", "lots", `store.cache.max-size: cannot parse "lots": no numerical prefix`},
+		{"store.cache.max-age", "forever", `store.cache.max-age: time: invalid duration "forever"`},
+		{"store.cache.max-age", "-1h", `store.cache.max-age must be positive: "-1h"`},
+		{"store.cache.secondary-dir", "srv/cache", `store.cache.secondary-dir must be an absolute path: "srv/cache"`},
+	} {
+		err := configcore.Run(coreDev, &mockConf{
+			state: s.state,
+			changes: map[string]any{
+				tc.key: tc.value,
+			},
+		})
+		c.Check(err, ErrorMatches, tc.err, Commentf("%s=%s", tc.key, tc.value))
+	}
+}
+
+func (s *storeSuite) TestStorePeersSettingsHappy(c *C) {
+	err := configcore.Run(coreDev, &mockConf{
+		state: s.state,
+		changes: map[string]any{
+			"store.peers.enabled": true,
+			"store.peers.mdns":    "false",
+			"store.peers.port":    8000,
+			"store.peers.static":  "10.0.0.2,10.0.0.3:8000",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *storeSuite) TestStorePeersSettingsUnhappy(c *C) {
+	for _, tc := range []struct {
+		key   string
+		value any
+		err   string
+	}{
+		{"store.peers.enabled", "yes", `store.peers.enabled can only be set to 'true' or 'false'`},
+		{"store.peers.mdns", 1, `store.peers.mdns can only be set to 'true' or 'false'`},
+		{"store.peers.port", 0, `store.peers.port must be a port number between 1 and 65535: "0"`},
+		{"store.peers.port", "http", `store.peers.port must be a port number between 1 and 65535: "http"`},
+		{"store.peers.static", "10.0.0.2:http", `store.peers.static: invalid port of peer "10.0.0.2:http"`},
+	} {
+		err := configcore.Run(coreDev, &mockConf{
+			state: s.state,
+			changes: map[string]any{
+				tc.key: tc.value,
+			},
+		})
+		c.Check(err, ErrorMatches, tc.err, Commentf("%s=%v", tc.key, tc.value))
+	}
+}
+
 func (s *storeSuite) TestFilesystemOnlyApply(c *C) {
 	conf := configcore.PlainCoreConfig(map[string]any{
 		"store.access": "offline",
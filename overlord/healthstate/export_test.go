@@ -32,3 +32,19 @@ func MockCheckTimeout(t time.Duration) (restore func()) {
 }
 
 var KnownStatuses = knownStatuses
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() {
+		timeNow = old
+	}
+}
+
+func MockRandomDuration(f func(time.Duration) time.Duration) (restore func()) {
+	old := randomDuration
+	randomDuration = f
+	return func() {
+		randomDuration = old
+	}
+}
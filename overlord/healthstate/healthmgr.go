@@ -0,0 +1,210 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package healthstate
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/randutil"
+	"github.com/snapcore/snapd/snap"
+)
+
+var (
+	timeNow        = time.Now
+	randomDuration = randutil.RandomDuration
+)
+
+// HealthManager periodically runs the check-health hook of the snaps
+// declaring an interval for it, so that services degrading long after
+// they were installed or refreshed are noticed. The periodic checks are
+// run as ephemeral hooks rather than through changes, as they would
+// otherwise flood the changes of the system.
+type HealthManager struct {
+	state   *state.State
+	hookMgr *hookstate.HookManager
+	// nextRun holds when the check-health hook of each snap is due next
+	nextRun map[string]time.Time
+
+	wg sync.WaitGroup
+
+	// mu guards running, which holds the snaps whose check is running
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// Manager returns a new HealthManager.
+func Manager(st *state.State, hookMgr *hookstate.HookManager) *HealthManager {
+	return &HealthManager{
+		state:   st,
+		hookMgr: hookMgr,
+		nextRun: make(map[string]time.Time),
+		running: make(map[string]bool),
+	}
+}
+
+// checkJitter returns a random delay of up to a tenth of the interval, so
+// that checks declared with the same interval do not all run at once.
+func checkJitter(interval time.Duration) time.Duration {
+	return randomDuration(interval / 10)
+}
+
+// Ensure is part of the overlord.StateManager interface.
+func (m *HealthManager) Ensure() error {
+	return m.ensurePeriodicChecks()
+}
+
+// Stop is part of the overlord.StateStopper interface.
+func (m *HealthManager) Stop() {
+	// the checks are bounded by the timeout of the hook
+	m.wg.Wait()
+}
+
+// ensurePeriodicChecks runs the check-health hook of the snaps whose check
+// is due.
+func (m *HealthManager) ensurePeriodicChecks() error {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	var seeded bool
+	if err := st.Get("seeded", &seeded); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	if !seeded {
+		return nil
+	}
+
+	logger.Trace("ensure", "manager", "HealthManager", "func", "ensurePeriodicChecks")
+
+	snaps, err := snapstate.All(st)
+	if err != nil {
+		return err
+	}
+	healths, err := All(st)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := timeNow()
+	var earliest time.Time
+	for name := range m.nextRun {
+		if _, ok := snaps[name]; !ok {
+			delete(m.nextRun, name)
+		}
+	}
+	for name, snapst := range snaps {
+		if !snapst.Active {
+			delete(m.nextRun, name)
+			continue
+		}
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			logger.Noticef("cannot get information about snap %q: %v", name, err)
+			continue
+		}
+		hook := info.Hooks["check-health"]
+		if hook == nil || hook.Interval == 0 {
+			delete(m.nextRun, name)
+			continue
+		}
+		interval := time.Duration(hook.Interval)
+
+		when, ok := m.nextRun[name]
+		if !ok {
+			when = now
+			if health := healths[name]; health != nil && health.Revision == info.Revision && !health.LastRun.IsZero() {
+				when = health.LastRun.Add(interval)
+			}
+			when = when.Add(checkJitter(interval))
+			m.nextRun[name] = when
+		}
+
+		if !when.After(now) && !m.running[name] {
+			if err := snapstate.CheckChangeConflict(st, name, nil); err != nil {
+				// try again once the snap is left alone
+				logger.Debugf("cannot run periodic health check of snap %q yet: %v", name, err)
+			} else {
+				m.running[name] = true
+				m.wg.Add(1)
+				go m.runCheck(name, info.Revision)
+				when = now.Add(interval + checkJitter(interval))
+				m.nextRun[name] = when
+			}
+		}
+		if !when.After(now) {
+			continue
+		}
+		if earliest.IsZero() || when.Before(earliest) {
+			earliest = when
+		}
+	}
+
+	if !earliest.IsZero() {
+		st.EnsureBefore(earliest.Sub(now))
+	}
+	return nil
+}
+
+// runCheck runs the check-health hook of the given snap revision, which
+// records the health of the snap.
+func (m *HealthManager) runCheck(snapName string, rev snap.Revision) {
+	defer m.wg.Done()
+	defer func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.running, snapName)
+	}()
+
+	if _, err := m.hookMgr.EphemeralRunHook(context.Background(), hookSetup(snapName, rev), nil); err != nil {
+		logger.Noticef("cannot run periodic health check of snap %q: %v", snapName, err)
+	}
+}
+
+// healthChanged records a notice about the health status of the snap
+// changing, if it did.
+func healthChanged(st *state.State, snapName string, prev, cur *HealthState) {
+	prevStatus := UnknownStatus
+	if prev != nil {
+		prevStatus = prev.Status
+	}
+	if prevStatus == cur.Status {
+		return
+	}
+	data := map[string]string{
+		"old-status": prevStatus.String(),
+		"status":     cur.Status.String(),
+		"revision":   cur.Revision.String(),
+	}
+	if cur.Message != "" {
+		data["message"] = cur.Message
+	}
+	if _, err := st.AddNotice(nil, state.SnapHealthChangeNotice, snapName, &state.AddNoticeOptions{Data: data}); err != nil {
+		logger.Noticef("cannot record health change of snap %q: %v", snapName, err)
+	}
+}
@@ -0,0 +1,192 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package healthstate_test
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/overlord/healthstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+func (s *healthSuite) mockPeriodicSnap(c *check.C, now time.Time) {
+	s.AddCleanup(healthstate.MockTimeNow(func() time.Time { return now }))
+	s.AddCleanup(healthstate.MockRandomDuration(func(d time.Duration) time.Duration {
+		c.Check(d, check.Equals, 6*time.Minute)
+		return time.Minute
+	}))
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded", true)
+	sideInfo := &snap.SideInfo{RealName: "periodic-snap", Revision: snap.R(7)}
+	snapstate.Set(s.state, "periodic-snap", &snapstate.SnapState{
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{sideInfo}),
+		Current:  snap.R(7),
+		Active:   true,
+		SnapType: "app",
+	})
+	snaptest.MockSnapCurrent(c, `name: periodic-snap
+version: v1
+hooks:
+  check-health:
+    interval: 1h
+`, sideInfo)
+}
+
+func (s *healthSuite) mockHook(c *check.C) (ran chan string, release chan struct{}) {
+	ran = make(chan string, 2)
+	release = make(chan struct{})
+	s.AddCleanup(hookstate.MockRunHook(func(ctx *hookstate.Context, tomb *tomb.Tomb) ([]byte, error) {
+		c.Check(ctx.HookName(), check.Equals, "check-health")
+		ran <- ctx.InstanceName()
+		<-release
+		return nil, nil
+	}))
+	return ran, release
+}
+
+func (s *healthSuite) TestEnsureRunsDueChecks(c *check.C) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	s.mockPeriodicSnap(c, now)
+	ran, release := s.mockHook(c)
+
+	s.state.Lock()
+	s.state.Set("health", map[string]*healthstate.HealthState{
+		"periodic-snap": {Revision: snap.R(7), Status: healthstate.OkayStatus, LastRun: now.Add(-2 * time.Hour)},
+	})
+	s.state.Unlock()
+
+	mgr := healthstate.Manager(s.state, s.hookMgr)
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	// test-snap has no interval and is left alone
+	select {
+	case name := <-ran:
+		c.Check(name, check.Equals, "periodic-snap")
+	case <-time.After(5 * time.Second):
+		c.Fatal("check-health hook was not run")
+	}
+
+	// a check in progress is not run again, even when due
+	s.AddCleanup(healthstate.MockTimeNow(func() time.Time { return now.Add(2 * time.Hour) }))
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	close(release)
+	mgr.Stop()
+	c.Check(ran, check.HasLen, 0)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	// no change is created for the periodic checks
+	c.Check(s.state.Changes(), check.HasLen, 0)
+	health, err := healthstate.Get(s.state, "periodic-snap")
+	c.Assert(err, check.IsNil)
+	c.Check(health.Status, check.Equals, healthstate.UnknownStatus)
+	c.Check(health.Code, check.Equals, "snapd-hook-no-health-set")
+	c.Check(health.LastRun.Equal(now.Add(2*time.Hour)), check.Equals, true)
+}
+
+func (s *healthSuite) TestEnsureWaitsForInterval(c *check.C) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	s.mockPeriodicSnap(c, now)
+	ran, release := s.mockHook(c)
+	close(release)
+
+	s.state.Lock()
+	// due at 12:31, with the jitter
+	s.state.Set("health", map[string]*healthstate.HealthState{
+		"periodic-snap": {Revision: snap.R(7), Status: healthstate.OkayStatus, LastRun: now.Add(-30 * time.Minute)},
+	})
+	s.state.Unlock()
+
+	mgr := healthstate.Manager(s.state, s.hookMgr)
+	c.Assert(mgr.Ensure(), check.IsNil)
+	mgr.Stop()
+	c.Check(ran, check.HasLen, 0)
+
+	s.AddCleanup(healthstate.MockTimeNow(func() time.Time { return now.Add(31 * time.Minute) }))
+	mgr = healthstate.Manager(s.state, s.hookMgr)
+	c.Assert(mgr.Ensure(), check.IsNil)
+	mgr.Stop()
+	c.Check(ran, check.HasLen, 1)
+}
+
+func (s *healthSuite) TestEnsureNotSeeded(c *check.C) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	s.mockPeriodicSnap(c, now)
+	ran, release := s.mockHook(c)
+	close(release)
+
+	s.state.Lock()
+	s.state.Set("seeded", false)
+	s.state.Unlock()
+
+	mgr := healthstate.Manager(s.state, s.hookMgr)
+	c.Assert(mgr.Ensure(), check.IsNil)
+	mgr.Stop()
+	c.Check(ran, check.HasLen, 0)
+}
+
+func (s *healthSuite) TestSetFromHookContextHealthChangeNotice(c *check.C) {
+	lastRun := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	ctx, err := hookstate.NewContext(nil, s.state, &hookstate.HookSetup{Snap: "foo", Revision: snap.R(1)}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	ctx.Lock()
+	defer ctx.Unlock()
+
+	s.state.Set("health", map[string]*healthstate.HealthState{
+		"foo": {Revision: snap.R(1), Status: healthstate.OkayStatus, LastRun: lastRun},
+	})
+
+	ctx.Set("health", &healthstate.HealthState{Revision: snap.R(1), Status: healthstate.ErrorStatus, Message: "disk is full"})
+	c.Assert(healthstate.SetFromHookContext(ctx), check.IsNil)
+
+	health, err := healthstate.Get(s.state, "foo")
+	c.Assert(err, check.IsNil)
+	// the last run of the hook is kept
+	c.Check(health.LastRun.Equal(lastRun), check.Equals, true)
+
+	notices := s.state.Notices(&state.NoticeFilter{Types: []state.NoticeType{state.SnapHealthChangeNotice}})
+	c.Assert(notices, check.HasLen, 1)
+	c.Check(notices[0].Key(), check.Equals, "foo")
+	c.Check(notices[0].LastData(), check.DeepEquals, map[string]string{
+		"old-status": "okay",
+		"status":     "error",
+		"revision":   "1",
+		"message":    "disk is full",
+	})
+
+	// no notice if the status stays the same
+	ctx.Set("health", &healthstate.HealthState{Revision: snap.R(1), Status: healthstate.ErrorStatus, Message: "disk is still full"})
+	c.Assert(healthstate.SetFromHookContext(ctx), check.IsNil)
+	notices = s.state.Notices(&state.NoticeFilter{Types: []state.NoticeType{state.SnapHealthChangeNotice}})
+	c.Assert(notices, check.HasLen, 1)
+	c.Check(notices[0].LastData()["message"], check.Equals, "disk is full")
+}
@@ -47,19 +47,22 @@ func init() {
 	}
 
 	snapstate.CheckHealthHook = Hook
+	snapstate.SnapHealth = snapHealth
 }
 
-func Hook(st *state.State, snapName string, snapRev snap.Revision) *state.Task {
-	summary := fmt.Sprintf("Run health check of %q snap", snapName)
-	hooksup := &hookstate.HookSetup{
+func hookSetup(snapName string, snapRev snap.Revision) *hookstate.HookSetup {
+	return &hookstate.HookSetup{
 		Snap:     snapName,
 		Revision: snapRev,
 		Hook:     "check-health",
 		Optional: true,
 		Timeout:  checkTimeout,
 	}
+}
 
-	return hookstate.HookTask(st, summary, hooksup, nil)
+func Hook(st *state.State, snapName string, snapRev snap.Revision) *state.Task {
+	summary := fmt.Sprintf("Run health check of %q snap", snapName)
+	return hookstate.HookTask(st, summary, hookSetup(snapName, snapRev), nil)
 }
 
 type HealthStatus int
@@ -96,6 +99,8 @@ type HealthState struct {
 	Status    HealthStatus  `json:"status"`
 	Message   string        `json:"message,omitempty"`
 	Code      string        `json:"code,omitempty"`
+	// LastRun is when the check-health hook of the snap last ran.
+	LastRun time.Time `json:"last-run"`
 }
 
 func Init(hookManager *hookstate.HookManager) {
@@ -161,6 +166,8 @@ func (h *healthHandler) appendHealth(health *HealthState) error {
 	st.Lock()
 	defer st.Unlock()
 
+	health.LastRun = timeNow()
+
 	return appendHealth(h.context, health)
 }
 
@@ -174,8 +181,14 @@ func appendHealth(ctx *hookstate.Context, health *HealthState) error {
 		}
 		hs = map[string]*HealthState{}
 	}
+	old := hs[ctx.InstanceName()]
+	if health.LastRun.IsZero() && old != nil && old.Revision == health.Revision {
+		// set-health called from outside the hook
+		health.LastRun = old.LastRun
+	}
 	hs[ctx.InstanceName()] = health
 	st.Set("health", hs)
+	healthChanged(st, ctx.InstanceName(), old, health)
 
 	return nil
 }
@@ -196,6 +209,19 @@ func SetFromHookContext(ctx *hookstate.Context) error {
 	return appendHealth(ctx, &health)
 }
 
+// snapHealth returns the health status last reported by the given revision
+// of the snap, which is unknown if another revision reported it.
+func snapHealth(st *state.State, snapName string, rev snap.Revision) (status, message string, err error) {
+	health, err := Get(st, snapName)
+	if err != nil {
+		return "", "", err
+	}
+	if health == nil || health.Revision != rev {
+		return UnknownStatus.String(), "", nil
+	}
+	return health.Status.String(), health.Message, nil
+}
+
 func All(st *state.State) (map[string]*HealthState, error) {
 	var hs map[string]*HealthState
 	if err := st.Get("health", &hs); err != nil && !errors.Is(err, state.ErrNoState) {
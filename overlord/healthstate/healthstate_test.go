@@ -187,6 +187,7 @@ func (s *healthSuite) testHealth(c *check.C, cond healthHookTestCondition) {
 		}
 		com := check.Commentf("%s ⩼ %s ⩼ %s", t0.Format(time.StampNano), health.Timestamp.Format(time.StampNano), tf.Format(time.StampNano))
 		c.Check(health.Timestamp.After(t0) && health.Timestamp.Before(tf), check.Equals, true, com)
+		c.Check(health.LastRun.IsZero(), check.Equals, false)
 		c.Check(cmd.Calls(), check.DeepEquals, [][]string{{"snap", "run", "--hook", "check-health", "-r", "42", "test-snap"}})
 	} else {
 		// no script -> no health
@@ -251,3 +252,30 @@ func (s *healthSuite) TestSetFromHookContextEmpty(c *check.C) {
 	// no health in the context -> no health in state
 	c.Check(s.state.Get("health", &hs), testutil.ErrorIs, state.ErrNoState)
 }
+
+func (s *healthSuite) TestSnapHealth(c *check.C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	status, message, err := snapstate.SnapHealth(s.state, "test-snap", snap.R(42))
+	c.Assert(err, check.IsNil)
+	c.Check(status, check.Equals, "unknown")
+	c.Check(message, check.Equals, "")
+
+	s.state.Set("health", map[string]*healthstate.HealthState{
+		"test-snap": {Revision: snap.R(42), Status: healthstate.ErrorStatus, Message: "not good", Timestamp: time.Now()},
+	})
+	status, message, err = snapstate.SnapHealth(s.state, "test-snap", snap.R(42))
+	c.Assert(err, check.IsNil)
+	c.Check(status, check.Equals, "error")
+	c.Check(message, check.Equals, "not good")
+
+	// the health of another revision is not known
+	status, _, err = snapstate.SnapHealth(s.state, "test-snap", snap.R(43))
+	c.Assert(err, check.IsNil)
+	c.Check(status, check.Equals, "unknown")
+}
+
+func (s *healthSuite) TestEnsureLoopLogging(c *check.C) {
+	testutil.CheckEnsureLoopLogging("healthmgr.go", c, true)
+}
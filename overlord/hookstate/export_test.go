@@ -38,3 +38,5 @@ func MockDefaultHookTimeout(timeout time.Duration) func() {
 		defaultHookTimeout = oldDefaultTimeout
 	}
 }
+
+var HookMetricsLabel = hookMetricsLabel
@@ -35,6 +35,7 @@ import (
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/metrics"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/restart"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -44,6 +45,26 @@ import (
 )
 
 type hijackFunc func(ctx *Context) error
+
+var (
+	hookDuration = metrics.NewHistogram("snapd_hook_duration_seconds",
+		"Duration of hook executions, by hook.", nil, "hook")
+	hookFailures = metrics.NewCounter("snapd_hook_failures_total",
+		"Number of failed hook executions, by hook.", "hook")
+)
+
+var interfaceHookName = regexp.MustCompile(`^((?:un)?prepare|connect|disconnect)-(plug|slot)-`)
+
+// hookMetricsLabel returns the hook name as used in metrics labels. The
+// names of the interface hooks include the name of the plug or slot, which
+// are not included so that the number of label values stays bounded.
+func hookMetricsLabel(hook string) string {
+	if m := interfaceHookName.FindStringSubmatch(hook); m != nil {
+		return m[0] + "*"
+	}
+	return hook
+}
+
 type hijackKey struct{ hook, snap string }
 
 // HookManager is responsible for the maintenance of hooks in the system state.
@@ -467,11 +488,18 @@ func (m *HookManager) runHook(context *Context, snapst *snapstate.SnapState, hoo
 	// some hooks get hijacked, e.g. the core configuration
 	var err error
 	var output []byte
+	t0 := time.Now()
 	if f := m.hijacked(hooksup.Hook, hooksup.Snap); f != nil {
 		err = f(context)
 	} else if hookExists {
 		output, err = runHook(context, tomb)
 	}
+	if hookExists || mustHijack {
+		hookDuration.ObserveDuration(time.Since(t0), hookMetricsLabel(hooksup.Hook))
+		if err != nil {
+			hookFailures.Inc(hookMetricsLabel(hooksup.Hook))
+		}
+	}
 	if err != nil {
 		// TODO: telemetry about errors here
 		err = osutil.OutputErr(output, err)
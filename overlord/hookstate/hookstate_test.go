@@ -20,11 +20,14 @@
 package hookstate_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -33,6 +36,7 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/metrics"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
@@ -417,6 +421,49 @@ func (s *hookManagerSuite) TestHookTaskHandlesHookError(c *C) {
 	c.Check(s.manager.NumRunningHooks(), Equals, 0)
 }
 
+// hookMetric returns the value of the given hook metric sample, or 0 if it
+// was not reported yet.
+func hookMetric(c *C, sample string) float64 {
+	var buf bytes.Buffer
+	c.Assert(metrics.Default.WriteText(&buf), IsNil)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, sample+" "); ok {
+			v, err := strconv.ParseFloat(value, 64)
+			c.Assert(err, IsNil)
+			return v
+		}
+	}
+	return 0
+}
+
+func (s *hookManagerSuite) TestHookTaskMetrics(c *C) {
+	runs := hookMetric(c, `snapd_hook_duration_seconds_count{hook="configure"}`)
+	failures := hookMetric(c, `snapd_hook_failures_total{hook="configure"}`)
+
+	cmd := testutil.MockCommand(c, "snap", "exit 1")
+	defer cmd.Restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	c.Check(hookMetric(c, `snapd_hook_duration_seconds_count{hook="configure"}`), Equals, runs+1)
+	c.Check(hookMetric(c, `snapd_hook_failures_total{hook="configure"}`), Equals, failures+1)
+}
+
+func (s *hookManagerSuite) TestHookMetricsLabel(c *C) {
+	for hook, label := range map[string]string{
+		"configure":                "configure",
+		"install":                  "install",
+		"prepare-plug-home":        "prepare-plug-*",
+		"unprepare-slot-foo-bar":   "unprepare-slot-*",
+		"connect-plug-network":     "connect-plug-*",
+		"disconnect-slot-x":        "disconnect-slot-*",
+		"disconnect-something-odd": "disconnect-something-odd",
+	} {
+		c.Check(hookstate.HookMetricsLabel(hook), Equals, label, Commentf(hook))
+	}
+}
+
 func (s *hookManagerSuite) TestHookTaskHandleIgnoreErrorWorks(c *C) {
 	s.state.Lock()
 	var hooksup hookstate.HookSetup
@@ -299,6 +299,8 @@ func appendNotice(notices []*state.Notice, newNotice *state.Notice, existingInde
 type ntbFilter struct {
 	UserID     *uint32
 	Keys       []string
+	KeyGlobs   []string
+	Data       map[string]string
 	After      time.Time
 	BeforeOrAt time.Time
 }
@@ -336,6 +338,8 @@ func (ntb *noticeTypeBackend) simplifyFilter(filter *state.NoticeFilter) (simpli
 	simplified = ntbFilter{
 		UserID:     filter.UserID,
 		Keys:       keys,
+		KeyGlobs:   filter.KeyGlobs,
+		Data:       filter.Data,
 		After:      filter.After,
 		BeforeOrAt: filter.BeforeOrAt,
 	}
@@ -387,23 +391,36 @@ func (f ntbFilter) filterNotices(notices []*state.Notice, now time.Time) []*stat
 	}
 
 	// Now have non-expired notices matching After/BeforeOrAt filters.
-	// If filter has no keys, we're done.
-	if len(f.Keys) == 0 {
-		return filteredNotices
+	if len(f.Keys) > 0 {
+		// Look for the keys from the filter
+		keyNotices := make([]*state.Notice, 0, len(f.Keys))
+		for _, notice := range filteredNotices {
+			if !slicesContains(f.Keys, notice.Key()) {
+				continue
+			}
+			keyNotices = append(keyNotices, notice)
+			if len(keyNotices) == len(f.Keys) {
+				break
+			}
+		}
+		filteredNotices = keyNotices
 	}
 
-	// Look for the keys from the filter
-	keyNotices := make([]*state.Notice, 0, len(f.Keys))
+	// If filter has no key patterns or data, we're done.
+	if len(f.KeyGlobs) == 0 && len(f.Data) == 0 {
+		return filteredNotices
+	}
+	var matchingNotices []*state.Notice
 	for _, notice := range filteredNotices {
-		if !slicesContains(f.Keys, notice.Key()) {
+		if len(f.KeyGlobs) > 0 && !state.KeyMatchesGlobs(notice.Key(), f.KeyGlobs) {
 			continue
 		}
-		keyNotices = append(keyNotices, notice)
-		if len(keyNotices) == len(f.Keys) {
-			break
+		if !state.DataContains(notice.LastData(), f.Data) {
+			continue
 		}
+		matchingNotices = append(matchingNotices, notice)
 	}
-	return keyNotices
+	return matchingNotices
 }
 
 // TODO: remove in favor of slices.Contains once we're on Go 1.21+
@@ -687,6 +687,17 @@ func (s *noticebackendSuite) TestSimplifyFilter(c *C) {
 			},
 			matchPossible: true,
 		},
+		{
+			stateFilter: &state.NoticeFilter{
+				KeyGlobs: []string{"00000000000012*"},
+				Data:     map[string]string{"resolved": "replied"},
+			},
+			simpleFilter: apparmorprompting.NtbFilter{
+				KeyGlobs: []string{"00000000000012*"},
+				Data:     map[string]string{"resolved": "replied"},
+			},
+			matchPossible: true,
+		},
 	} {
 		simplified, matchPossible := promptBackend.SimplifyFilter(testCase.stateFilter)
 		c.Check(simplified, DeepEquals, testCase.simpleFilter, Commentf("testCase: %+v", testCase))
@@ -847,6 +858,102 @@ func (s *noticebackendSuite) TestBackendNotices(c *C) {
 	}
 }
 
+func (s *noticebackendSuite) TestBackendNoticesKeyGlobs(c *C) {
+	noticeBackend, err := apparmorprompting.NewNoticeBackends(s.noticeMgr)
+	c.Assert(err, IsNil)
+	promptBackend := noticeBackend.PromptBackend()
+
+	userID := uint32(1000)
+	for _, id := range []prompting.IDType{0x11, 0x12, 0x21} {
+		c.Assert(promptBackend.AddNotice(userID, id, nil), IsNil)
+	}
+
+	for i, testCase := range []struct {
+		filter      *state.NoticeFilter
+		expectedIDs []prompting.IDType
+	}{
+		{
+			filter:      &state.NoticeFilter{KeyGlobs: []string{"00000000000000[1]*"}},
+			expectedIDs: []prompting.IDType{0x11, 0x12},
+		},
+		{
+			filter:      &state.NoticeFilter{KeyGlobs: []string{"*2", "*21"}},
+			expectedIDs: []prompting.IDType{0x12, 0x21},
+		},
+		{
+			filter:      &state.NoticeFilter{KeyGlobs: []string{"*1", "nope"}},
+			expectedIDs: []prompting.IDType{0x11, 0x21},
+		},
+		{
+			filter: &state.NoticeFilter{
+				Keys:     []string{"0000000000000011", "0000000000000021"},
+				KeyGlobs: []string{"*21"},
+			},
+			expectedIDs: []prompting.IDType{0x21},
+		},
+		{
+			filter:      &state.NoticeFilter{KeyGlobs: []string{"foo*"}},
+			expectedIDs: nil,
+		},
+	} {
+		notices := promptBackend.BackendNotices(testCase.filter)
+		c.Assert(notices, HasLen, len(testCase.expectedIDs), Commentf("testCase %d: %+v", i, testCase))
+		for j, n := range notices {
+			c.Check(n.Key(), Equals, testCase.expectedIDs[j].String())
+		}
+	}
+}
+
+func (s *noticebackendSuite) TestBackendNoticesData(c *C) {
+	noticeBackend, err := apparmorprompting.NewNoticeBackends(s.noticeMgr)
+	c.Assert(err, IsNil)
+	promptBackend := noticeBackend.PromptBackend()
+
+	userID := uint32(1000)
+	c.Assert(promptBackend.AddNotice(userID, 1, map[string]string{"resolved": "replied"}), IsNil)
+	c.Assert(promptBackend.AddNotice(userID, 2, map[string]string{"resolved": "expired", "foo": "bar"}), IsNil)
+	c.Assert(promptBackend.AddNotice(userID, 3, nil), IsNil)
+	// data filters match against the last data of a notice
+	c.Assert(promptBackend.AddNotice(userID, 4, map[string]string{"resolved": "replied"}), IsNil)
+	c.Assert(promptBackend.AddNotice(userID, 4, map[string]string{"foo": "bar"}), IsNil)
+
+	for i, testCase := range []struct {
+		filter      *state.NoticeFilter
+		expectedIDs []prompting.IDType
+	}{
+		{
+			filter:      &state.NoticeFilter{Data: map[string]string{"resolved": "replied"}},
+			expectedIDs: []prompting.IDType{1},
+		},
+		{
+			filter:      &state.NoticeFilter{Data: map[string]string{"foo": "bar"}},
+			expectedIDs: []prompting.IDType{2, 4},
+		},
+		{
+			filter:      &state.NoticeFilter{Data: map[string]string{"foo": "bar", "resolved": "expired"}},
+			expectedIDs: []prompting.IDType{2},
+		},
+		{
+			filter:      &state.NoticeFilter{Data: map[string]string{"resolved": "cancelled"}},
+			expectedIDs: nil,
+		},
+		{
+			filter: &state.NoticeFilter{
+				UserID:   &userID,
+				KeyGlobs: []string{"*4"},
+				Data:     map[string]string{"foo": "bar"},
+			},
+			expectedIDs: []prompting.IDType{4},
+		},
+	} {
+		notices := promptBackend.BackendNotices(testCase.filter)
+		c.Assert(notices, HasLen, len(testCase.expectedIDs), Commentf("testCase %d: %+v", i, testCase))
+		for j, n := range notices {
+			c.Check(n.Key(), Equals, testCase.expectedIDs[j].String())
+		}
+	}
+}
+
 func (s *noticebackendSuite) TestBackendNotice(c *C) {
 	noticeBackend, err := apparmorprompting.NewNoticeBackends(s.noticeMgr)
 	c.Assert(err, IsNil)
@@ -28,6 +28,7 @@ import (
 
 	"github.com/snapcore/snapd/interfaces/prompting"
 	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+	"github.com/snapcore/snapd/interfaces/prompting/requestaudit"
 	"github.com/snapcore/snapd/interfaces/prompting/requestprompts"
 	"github.com/snapcore/snapd/interfaces/prompting/requestrules"
 	"github.com/snapcore/snapd/logger"
@@ -67,6 +68,9 @@ type Manager interface {
 	RuleWithID(userID uint32, ruleID prompting.IDType) (*requestrules.Rule, error)
 	PatchRule(userID uint32, ruleID prompting.IDType, constraintsPatch *prompting.RuleConstraintsPatch) (*requestrules.Rule, error)
 	RemoveRule(userID uint32, ruleID prompting.IDType) (*requestrules.Rule, error)
+	ExportRules(userID uint32) (*requestrules.RuleSet, error)
+	ImportRules(userID uint32, rules []*requestrules.RuleContents) ([]*requestrules.Rule, error)
+	AuditEntries(filter *requestaudit.Filter) ([]*requestaudit.Entry, error)
 }
 
 // verify that InterfacesRequestsManager implements Manager
@@ -82,6 +86,7 @@ type InterfacesRequestsManager struct {
 	listener *listener.Listener
 	prompts  *requestprompts.PromptDB
 	rules    *requestrules.RuleDB
+	audit    *requestaudit.Log
 
 	// ready should block method calls which depend on the manager having re-
 	// received all pending requests which were previously sent before snapd
@@ -152,14 +157,21 @@ func New(s *state.State) (m *InterfacesRequestsManager, retErr error) {
 		}
 	}()
 
+	auditLog, err := requestaudit.New()
+	if err != nil {
+		return nil, fmt.Errorf("cannot open request audit log: %w", err)
+	}
+
 	m = &InterfacesRequestsManager{
 		listener:     listenerBackend,
 		prompts:      promptsBackend,
 		rules:        rulesBackend,
+		audit:        auditLog,
 		ready:        make(chan struct{}),
 		notifyPrompt: notifyPrompt,
 		notifyRule:   notifyRule,
 	}
+	promptsBackend.SetExpiredCallback(m.recordExpiredPrompt)
 
 	m.tomb.Go(m.run)
 
@@ -306,6 +318,22 @@ func (m *InterfacesRequestsManager) handleListenerReq(req *listener.Request) err
 		case len(outstandingPerms) == 0:
 			logger.Debugf("request allowed by existing rule: %+v", req)
 		}
+		if err == nil {
+			outcome := prompting.OutcomeAllow
+			if matchedDenyRule {
+				outcome = prompting.OutcomeDeny
+			}
+			m.recordDecision(&requestaudit.Entry{
+				User:        userID,
+				Snap:        snap,
+				Interface:   iface,
+				Path:        path,
+				Permissions: permissions,
+				Outcome:     outcome,
+				Source:      requestaudit.SourceRule,
+				RuleIDs:     m.rules.RuleIDsForRequest(userID, snap, iface, path, permissions),
+			})
+		}
 		// Allow any requested permissions which were explicitly allowed by
 		// existing rules (there may be no such permissions) and let the
 		// listener deny all permissions which were not explicitly included in
@@ -487,6 +515,16 @@ func (m *InterfacesRequestsManager) HandleReply(userID uint32, promptID promptin
 		// Error should not occur unless the listener has closed
 		return nil, retErr
 	}
+	m.recordDecision(&requestaudit.Entry{
+		User:        userID,
+		Snap:        prompt.Snap,
+		Interface:   prompt.Interface,
+		Path:        prompt.Constraints.Path(),
+		Permissions: prompt.Constraints.RequestedPermissions(),
+		Outcome:     outcome,
+		Source:      requestaudit.SourceReply,
+		PromptID:    prompt.ID,
+	})
 
 	if lifespan == prompting.LifespanSingle {
 		return []prompting.IDType{}, nil
@@ -504,15 +542,70 @@ func (m *InterfacesRequestsManager) applyRuleToOutstandingPrompts(rule *requestr
 		Snap:      rule.Snap,
 		Interface: rule.Interface,
 	}
+	// Keep the outstanding permissions of the prompts as they were before the
+	// rule was applied, so the outcome of satisfied prompts can be audited.
+	outstandingPerms := make(map[prompting.IDType][]string)
+	prompts, _ := m.prompts.Prompts(rule.User, false)
+	for _, prompt := range prompts {
+		outstandingPerms[prompt.ID] = append([]string(nil), prompt.Constraints.OutstandingPermissions()...)
+	}
 	satisfiedPromptIDs, err := m.prompts.HandleNewRule(metadata, rule.Constraints)
 	if err != nil {
 		// The rule's constraints and outcome were already validated, so an
 		// error should not occur here unless the prompt DB was already closed.
 		logger.Noticef("error when handling new rule: %v", err)
 	}
+	for _, id := range satisfiedPromptIDs {
+		for _, prompt := range prompts {
+			if prompt.ID != id {
+				continue
+			}
+			outcome := prompting.OutcomeAllow
+			for _, perm := range outstandingPerms[id] {
+				if entry, ok := rule.Constraints.Permissions[perm]; ok && entry.Outcome == prompting.OutcomeDeny {
+					outcome = prompting.OutcomeDeny
+					break
+				}
+			}
+			m.recordDecision(&requestaudit.Entry{
+				User:        rule.User,
+				Snap:        prompt.Snap,
+				Interface:   prompt.Interface,
+				Path:        prompt.Constraints.Path(),
+				Permissions: prompt.Constraints.RequestedPermissions(),
+				Outcome:     outcome,
+				Source:      requestaudit.SourceRule,
+				RuleIDs:     []prompting.IDType{rule.ID},
+				PromptID:    prompt.ID,
+			})
+		}
+	}
 	return satisfiedPromptIDs
 }
 
+// recordExpiredPrompt records in the audit log that the given prompt was
+// denied because it expired before the user replied to it.
+func (m *InterfacesRequestsManager) recordExpiredPrompt(userID uint32, prompt *requestprompts.Prompt) {
+	m.recordDecision(&requestaudit.Entry{
+		User:        userID,
+		Snap:        prompt.Snap,
+		Interface:   prompt.Interface,
+		Path:        prompt.Constraints.Path(),
+		Permissions: prompt.Constraints.RequestedPermissions(),
+		Outcome:     prompting.OutcomeDeny,
+		Source:      requestaudit.SourceTimeout,
+		PromptID:    prompt.ID,
+	})
+}
+
+// recordDecision records the given decision in the audit log. Errors are
+// logged, since they should not prevent the decision from being applied.
+func (m *InterfacesRequestsManager) recordDecision(entry *requestaudit.Entry) {
+	if err := m.audit.Record(entry); err != nil {
+		logger.Noticef("cannot record prompting decision in audit log: %v", err)
+	}
+}
+
 // Rules returns all rules for the user with the given user ID and,
 // optionally, only those for the given snap and/or interface.
 func (m *InterfacesRequestsManager) Rules(userID uint32, snap string, iface string) ([]*requestrules.Rule, error) {
@@ -623,3 +716,40 @@ func (m *InterfacesRequestsManager) RemoveRule(userID uint32, ruleID prompting.I
 	rule, err := m.rules.RemoveRule(userID, ruleID)
 	return rule, err
 }
+
+// ExportRules returns the contents of the rules of the user with the given
+// user ID in a form which can be imported again using ImportRules.
+func (m *InterfacesRequestsManager) ExportRules(userID uint32) (*requestrules.RuleSet, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.rules.ExportRules(userID), nil
+}
+
+// ImportRules creates rules with the given contents for the user with the
+// given user ID and then checks them against outstanding prompts, resolving
+// any prompts which they satisfy. Either all rules are imported, or none is.
+func (m *InterfacesRequestsManager) ImportRules(userID uint32, rules []*requestrules.RuleContents) ([]*requestrules.Rule, error) {
+	// Wait until the listener has re-sent pending requests and prompts have
+	// been re-created.
+	<-m.ready
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	importedRules, err := m.rules.ImportRules(userID, rules)
+	if err != nil {
+		return nil, err
+	}
+	// Apply imported rules to outstanding prompts.
+	for _, rule := range importedRules {
+		m.applyRuleToOutstandingPrompts(rule)
+	}
+	return importedRules, nil
+}
+
+// AuditEntries returns the entries of the prompting audit log which match the
+// given filter.
+func (m *InterfacesRequestsManager) AuditEntries(filter *requestaudit.Filter) ([]*requestaudit.Entry, error) {
+	return m.audit.Entries(filter)
+}
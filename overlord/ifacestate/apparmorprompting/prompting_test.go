@@ -35,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/prompting"
 	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
 	"github.com/snapcore/snapd/interfaces/prompting/patterns"
+	"github.com/snapcore/snapd/interfaces/prompting/requestaudit"
 	"github.com/snapcore/snapd/interfaces/prompting/requestprompts"
 	"github.com/snapcore/snapd/interfaces/prompting/requestrules"
 	"github.com/snapcore/snapd/logger"
@@ -1553,6 +1554,174 @@ func (s *apparmorpromptingSuite) TestAddRuleWithIDPatchRemove(c *C) {
 	c.Assert(mgr.Stop(), IsNil)
 }
 
+func (s *apparmorpromptingSuite) TestExportImportRules(c *C) {
+	readyChan, reqChan, replyChan, restore := apparmorprompting.MockListener()
+	defer restore()
+
+	mgr, err := apparmorprompting.New(s.st)
+	c.Assert(err, IsNil)
+
+	// simulateRequest checks mgr.Prompts, so make sure we close readyChan first
+	close(readyChan)
+
+	whenAdded := time.Now()
+	constraints := &prompting.Constraints{
+		PathPattern: mustParsePathPattern(c, "/home/test/**"),
+		Permissions: prompting.PermissionMap{
+			"read": &prompting.PermissionEntry{
+				Outcome:  prompting.OutcomeAllow,
+				Lifespan: prompting.LifespanForever,
+			},
+		},
+	}
+	otherUser := s.defaultUser + 1
+	_, err = mgr.AddRule(otherUser, "firefox", "home", constraints)
+	c.Assert(err, IsNil)
+	s.checkRecordedRuleUpdateNotices(c, whenAdded, 1)
+
+	exported, err := mgr.ExportRules(otherUser)
+	c.Assert(err, IsNil)
+	c.Check(exported, DeepEquals, &requestrules.RuleSet{
+		Rules: []*requestrules.RuleContents{
+			{
+				Snap:        "firefox",
+				Interface:   "home",
+				Constraints: constraints,
+			},
+		},
+	})
+
+	// Add read request for the default user
+	req := &listener.Request{
+		Permission: notify.AA_MAY_READ,
+	}
+	_, prompt := s.simulateRequest(c, reqChan, mgr, req, false)
+
+	// Importing the rules satisfies the outstanding prompt
+	whenImported := time.Now()
+	imported, err := mgr.ImportRules(s.defaultUser, exported.Rules)
+	c.Assert(err, IsNil)
+	c.Assert(imported, HasLen, 1)
+	c.Check(imported[0].User, Equals, s.defaultUser)
+	s.checkRecordedRuleUpdateNotices(c, whenImported, 1)
+
+	clientActivity := false
+	_, err = mgr.PromptWithID(s.defaultUser, prompt.ID, clientActivity)
+	c.Check(err, Equals, prompting_errors.ErrPromptNotFound)
+	resp, err := waitForReply(replyChan)
+	c.Assert(err, IsNil)
+	c.Check(resp.Request, Equals, req)
+
+	rules, err := mgr.Rules(s.defaultUser, "", "")
+	c.Assert(err, IsNil)
+	c.Check(rules, DeepEquals, imported)
+
+	c.Assert(mgr.Stop(), IsNil)
+}
+
+func (s *apparmorpromptingSuite) TestAuditEntries(c *C) {
+	readyChan, reqChan, replyChan, restore := apparmorprompting.MockListener()
+	defer restore()
+
+	mgr, err := apparmorprompting.New(s.st)
+	c.Assert(err, IsNil)
+
+	// simulateRequest checks mgr.Prompts, so make sure we close readyChan first
+	close(readyChan)
+
+	readRule, err := mgr.AddRule(s.defaultUser, "firefox", "home", &prompting.Constraints{
+		PathPattern: mustParsePathPattern(c, "/home/test/**"),
+		Permissions: prompting.PermissionMap{
+			"read": &prompting.PermissionEntry{
+				Outcome:  prompting.OutcomeAllow,
+				Lifespan: prompting.LifespanForever,
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	// Request decided by an existing rule
+	req := &listener.Request{Permission: notify.AA_MAY_READ}
+	s.fillInPartialRequest(req)
+	reqChan <- req
+	_, err = waitForReply(replyChan)
+	c.Assert(err, IsNil)
+
+	// Request decided by a reply
+	_, prompt := s.simulateRequest(c, reqChan, mgr, &listener.Request{Permission: notify.AA_MAY_WRITE}, false)
+	replyConstraints := &prompting.ReplyConstraints{
+		PathPattern: mustParsePathPattern(c, "/home/test/foo"),
+		Permissions: []string{"write"},
+	}
+	_, err = mgr.HandleReply(s.defaultUser, prompt.ID, replyConstraints, prompting.OutcomeDeny, prompting.LifespanSingle, "", true)
+	c.Assert(err, IsNil)
+	_, err = waitForReply(replyChan)
+	c.Assert(err, IsNil)
+
+	// Request decided by a new rule while it was waiting for a reply
+	_, prompt2 := s.simulateRequest(c, reqChan, mgr, &listener.Request{Permission: notify.AA_MAY_WRITE}, false)
+	writeRule, err := mgr.AddRule(s.defaultUser, "firefox", "home", &prompting.Constraints{
+		PathPattern: mustParsePathPattern(c, "/home/test/**"),
+		Permissions: prompting.PermissionMap{
+			"write": &prompting.PermissionEntry{
+				Outcome:  prompting.OutcomeDeny,
+				Lifespan: prompting.LifespanForever,
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+	_, err = waitForReply(replyChan)
+	c.Assert(err, IsNil)
+
+	entries, err := mgr.AuditEntries(&requestaudit.Filter{User: s.defaultUser})
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 3)
+	for _, entry := range entries {
+		c.Check(entry.Timestamp.IsZero(), Equals, false)
+		entry.Timestamp = time.Time{}
+	}
+	c.Check(entries, DeepEquals, []*requestaudit.Entry{
+		{
+			User:        s.defaultUser,
+			Snap:        "firefox",
+			Interface:   "home",
+			Path:        "/home/test/foo",
+			Permissions: []string{"read"},
+			Outcome:     prompting.OutcomeAllow,
+			Source:      requestaudit.SourceRule,
+			RuleIDs:     []prompting.IDType{readRule.ID},
+		},
+		{
+			User:        s.defaultUser,
+			Snap:        "firefox",
+			Interface:   "home",
+			Path:        "/home/test/foo",
+			Permissions: []string{"write"},
+			Outcome:     prompting.OutcomeDeny,
+			Source:      requestaudit.SourceReply,
+			PromptID:    prompt.ID,
+		},
+		{
+			User:        s.defaultUser,
+			Snap:        "firefox",
+			Interface:   "home",
+			Path:        "/home/test/foo",
+			Permissions: []string{"write"},
+			Outcome:     prompting.OutcomeDeny,
+			Source:      requestaudit.SourceRule,
+			RuleIDs:     []prompting.IDType{writeRule.ID},
+			PromptID:    prompt2.ID,
+		},
+	})
+
+	// Entries of other users are filtered out
+	entries, err = mgr.AuditEntries(&requestaudit.Filter{User: s.defaultUser + 1})
+	c.Assert(err, IsNil)
+	c.Check(entries, HasLen, 0)
+
+	c.Assert(mgr.Stop(), IsNil)
+}
+
 func (s *apparmorpromptingSuite) TestListenerReadyCausesPromptsHandleReadying(c *C) {
 	readyChan, _, _, restore := apparmorprompting.MockListener()
 	defer restore()
@@ -0,0 +1,239 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+// Copyright (c) 2025 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package notices
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// ErrNoSubscription is returned when a notice subscription cannot be found.
+var ErrNoSubscription = errors.New("no such notice subscription")
+
+// maxSubscriptionsPerUser limits how many named subscriptions a single user
+// may register, so that clients cannot grow the state without bound.
+const maxSubscriptionsPerUser = 64
+
+var validSubscriptionName = regexp.MustCompile(`^[a-z0-9](?:-?[a-z0-9])*$`)
+
+// Subscription is a named, persistent notice filter with a cursor recording
+// the last notice acknowledged by its owner. Subscriptions are stored in the
+// state, so the cursor survives restarts of both the client and snapd.
+type Subscription struct {
+	// Name identifies the subscription among those of the same user.
+	Name string `json:"name"`
+	// UserID is the UID of the user who registered the subscription.
+	UserID uint32 `json:"user-id"`
+
+	// Types, KeyGlobs and Data are used to build the notice filter, see
+	// state.NoticeFilter for their meaning.
+	Types    []state.NoticeType `json:"types,omitempty"`
+	KeyGlobs []string           `json:"key-globs,omitempty"`
+	Data     map[string]string  `json:"data,omitempty"`
+
+	// LastAckedID is the ID of the last notice acknowledged through this
+	// subscription.
+	LastAckedID string `json:"last-acked-id,omitempty"`
+	// LastAcked is the last-repeated timestamp of the last acknowledged
+	// notice. Only notices repeated after it are returned for the
+	// subscription.
+	LastAcked time.Time `json:"last-acked,omitzero"`
+}
+
+// Filter returns the notice filter which selects the notices which have not
+// yet been acknowledged through the subscription.
+func (sub *Subscription) Filter() *state.NoticeFilter {
+	userID := sub.UserID
+	return &state.NoticeFilter{
+		UserID:   &userID,
+		Types:    sub.Types,
+		KeyGlobs: sub.KeyGlobs,
+		Data:     sub.Data,
+		After:    sub.LastAcked,
+	}
+}
+
+func (sub *Subscription) validate() error {
+	if !validSubscriptionName.MatchString(sub.Name) || len(sub.Name) > 40 {
+		return fmt.Errorf("invalid subscription name %q", sub.Name)
+	}
+	for _, typ := range sub.Types {
+		if !typ.Valid() {
+			return fmt.Errorf("invalid notice type %q", typ)
+		}
+	}
+	if err := state.ValidateKeyGlobs(sub.KeyGlobs); err != nil {
+		return err
+	}
+	for k := range sub.Data {
+		if k == "" {
+			return fmt.Errorf("invalid empty data key")
+		}
+	}
+	return nil
+}
+
+type subscriptionKey struct {
+	UserID uint32
+	Name   string
+}
+
+func loadSubscriptions(st *state.State) (map[subscriptionKey]*Subscription, error) {
+	var subs []*Subscription
+	if err := st.Get("notice-subscriptions", &subs); err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+	m := make(map[subscriptionKey]*Subscription, len(subs))
+	for _, sub := range subs {
+		m[subscriptionKey{sub.UserID, sub.Name}] = sub
+	}
+	return m, nil
+}
+
+func saveSubscriptions(st *state.State, m map[subscriptionKey]*Subscription) {
+	subs := make([]*Subscription, 0, len(m))
+	for _, sub := range m {
+		subs = append(subs, sub)
+	}
+	sortSubscriptions(subs)
+	st.Set("notice-subscriptions", subs)
+}
+
+func sortSubscriptions(subs []*Subscription) {
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].UserID != subs[j].UserID {
+			return subs[i].UserID < subs[j].UserID
+		}
+		return subs[i].Name < subs[j].Name
+	})
+}
+
+// AddSubscription registers the given subscription. If the user already has
+// a subscription with the same name, its filter is replaced and its cursor is
+// kept, so that clients may re-register their subscriptions on every start.
+//
+// The caller must hold the state lock.
+func AddSubscription(st *state.State, sub *Subscription) error {
+	if err := sub.validate(); err != nil {
+		return err
+	}
+	subs, err := loadSubscriptions(st)
+	if err != nil {
+		return err
+	}
+	key := subscriptionKey{sub.UserID, sub.Name}
+	newSub := *sub
+	if old, ok := subs[key]; ok {
+		newSub.LastAckedID = old.LastAckedID
+		newSub.LastAcked = old.LastAcked
+	} else {
+		count := 0
+		for k := range subs {
+			if k.UserID == sub.UserID {
+				count++
+			}
+		}
+		if count >= maxSubscriptionsPerUser {
+			return fmt.Errorf("cannot add notice subscription: user already has %d subscriptions", count)
+		}
+	}
+	subs[key] = &newSub
+	saveSubscriptions(st, subs)
+	return nil
+}
+
+// GetSubscription returns the subscription with the given name registered by
+// the given user, or ErrNoSubscription.
+//
+// The caller must hold the state lock.
+func GetSubscription(st *state.State, userID uint32, name string) (*Subscription, error) {
+	subs, err := loadSubscriptions(st)
+	if err != nil {
+		return nil, err
+	}
+	sub, ok := subs[subscriptionKey{userID, name}]
+	if !ok {
+		return nil, ErrNoSubscription
+	}
+	return sub, nil
+}
+
+// Subscriptions returns the subscriptions registered by the given user, or by
+// all users if userID is nil, sorted by user and name.
+//
+// The caller must hold the state lock.
+func Subscriptions(st *state.State, userID *uint32) ([]*Subscription, error) {
+	subs, err := loadSubscriptions(st)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Subscription, 0, len(subs))
+	for key, sub := range subs {
+		if userID != nil && key.UserID != *userID {
+			continue
+		}
+		result = append(result, sub)
+	}
+	sortSubscriptions(result)
+	return result, nil
+}
+
+// AckSubscription moves the cursor of the given subscription to the given
+// notice, so that it and any notices repeated before it are no longer
+// returned for the subscription. The cursor never moves backwards.
+//
+// The caller must hold the state lock.
+func AckSubscription(st *state.State, userID uint32, name string, notice *state.Notice) error {
+	subs, err := loadSubscriptions(st)
+	if err != nil {
+		return err
+	}
+	sub, ok := subs[subscriptionKey{userID, name}]
+	if !ok {
+		return ErrNoSubscription
+	}
+	if !notice.LastRepeated().After(sub.LastAcked) {
+		return nil
+	}
+	sub.LastAckedID = notice.ID()
+	sub.LastAcked = notice.LastRepeated()
+	saveSubscriptions(st, subs)
+	return nil
+}
+
+// RemoveSubscription removes the subscription with the given name registered
+// by the given user.
+//
+// The caller must hold the state lock.
+func RemoveSubscription(st *state.State, userID uint32, name string) error {
+	subs, err := loadSubscriptions(st)
+	if err != nil {
+		return err
+	}
+	key := subscriptionKey{userID, name}
+	if _, ok := subs[key]; !ok {
+		return ErrNoSubscription
+	}
+	delete(subs, key)
+	saveSubscriptions(st, subs)
+	return nil
+}
@@ -0,0 +1,222 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+// Copyright (c) 2025 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package notices_test
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/notices"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type subscriptionsSuite struct {
+	st *state.State
+}
+
+var _ = Suite(&subscriptionsSuite{})
+
+func (s *subscriptionsSuite) SetUpTest(c *C) {
+	s.st = state.New(nil)
+}
+
+func (s *subscriptionsSuite) TestAddGetRemove(c *C) {
+	st := s.st
+	st.Lock()
+	defer st.Unlock()
+
+	sub := &notices.Subscription{
+		Name:     "monitor",
+		UserID:   1000,
+		Types:    []state.NoticeType{state.ChangeUpdateNotice},
+		KeyGlobs: []string{"1*"},
+		Data:     map[string]string{"kind": "refresh-snap"},
+	}
+	c.Assert(notices.AddSubscription(st, sub), IsNil)
+
+	got, err := notices.GetSubscription(st, 1000, "monitor")
+	c.Assert(err, IsNil)
+	c.Check(got, DeepEquals, sub)
+
+	_, err = notices.GetSubscription(st, 1001, "monitor")
+	c.Check(err, Equals, notices.ErrNoSubscription)
+
+	c.Assert(notices.RemoveSubscription(st, 1000, "monitor"), IsNil)
+	_, err = notices.GetSubscription(st, 1000, "monitor")
+	c.Check(err, Equals, notices.ErrNoSubscription)
+	c.Check(notices.RemoveSubscription(st, 1000, "monitor"), Equals, notices.ErrNoSubscription)
+}
+
+func (s *subscriptionsSuite) TestAddInvalid(c *C) {
+	st := s.st
+	st.Lock()
+	defer st.Unlock()
+
+	for _, tc := range []struct {
+		sub *notices.Subscription
+		err string
+	}{
+		{&notices.Subscription{Name: ""}, `invalid subscription name ""`},
+		{&notices.Subscription{Name: "Foo"}, `invalid subscription name "Foo"`},
+		{&notices.Subscription{Name: "foo--bar"}, `invalid subscription name "foo--bar"`},
+		{&notices.Subscription{Name: "foo", Types: []state.NoticeType{"bar"}}, `invalid notice type "bar"`},
+		{&notices.Subscription{Name: "foo", KeyGlobs: []string{"["}}, `invalid key pattern "\[": .*`},
+		{&notices.Subscription{Name: "foo", Data: map[string]string{"": "x"}}, `invalid empty data key`},
+	} {
+		c.Check(notices.AddSubscription(st, tc.sub), ErrorMatches, tc.err)
+	}
+}
+
+func (s *subscriptionsSuite) TestAddLimit(c *C) {
+	st := s.st
+	st.Lock()
+	defer st.Unlock()
+
+	for i := 0; i < 64; i++ {
+		err := notices.AddSubscription(st, &notices.Subscription{Name: fmt.Sprintf("sub-%d", i), UserID: 1000})
+		c.Assert(err, IsNil)
+	}
+	err := notices.AddSubscription(st, &notices.Subscription{Name: "one-more", UserID: 1000})
+	c.Check(err, ErrorMatches, "cannot add notice subscription: user already has 64 subscriptions")
+	// re-registering an existing subscription is fine
+	c.Check(notices.AddSubscription(st, &notices.Subscription{Name: "sub-0", UserID: 1000}), IsNil)
+	// other users are not affected
+	c.Check(notices.AddSubscription(st, &notices.Subscription{Name: "one-more", UserID: 1001}), IsNil)
+}
+
+func (s *subscriptionsSuite) TestSubscriptionsList(c *C) {
+	st := s.st
+	st.Lock()
+	defer st.Unlock()
+
+	for _, sub := range []*notices.Subscription{
+		{Name: "b", UserID: 1000},
+		{Name: "a", UserID: 1000},
+		{Name: "a", UserID: 0},
+	} {
+		c.Assert(notices.AddSubscription(st, sub), IsNil)
+	}
+
+	subs, err := notices.Subscriptions(st, nil)
+	c.Assert(err, IsNil)
+	c.Assert(subs, HasLen, 3)
+	c.Check(subs[0].UserID, Equals, uint32(0))
+	c.Check(subs[1].Name, Equals, "a")
+	c.Check(subs[2].Name, Equals, "b")
+
+	uid := uint32(1000)
+	subs, err = notices.Subscriptions(st, &uid)
+	c.Assert(err, IsNil)
+	c.Check(subs, HasLen, 2)
+
+	uid = 42
+	subs, err = notices.Subscriptions(st, &uid)
+	c.Assert(err, IsNil)
+	c.Check(subs, HasLen, 0)
+}
+
+func (s *subscriptionsSuite) TestAckAndReplay(c *C) {
+	st := s.st
+	st.Lock()
+	defer st.Unlock()
+
+	sub := &notices.Subscription{
+		Name:   "monitor",
+		UserID: 1000,
+		Types:  []state.NoticeType{state.ChangeUpdateNotice},
+		Data:   map[string]string{"kind": "refresh-snap"},
+	}
+	c.Assert(notices.AddSubscription(st, sub), IsNil)
+
+	addChangeNotice := func(key, kind string) string {
+		id, err := st.AddNotice(nil, state.ChangeUpdateNotice, key, &state.AddNoticeOptions{
+			Data: map[string]string{"kind": kind},
+		})
+		c.Assert(err, IsNil)
+		time.Sleep(time.Microsecond)
+		return id
+	}
+	id1 := addChangeNotice("1", "refresh-snap")
+	addChangeNotice("2", "install-snap")
+	id3 := addChangeNotice("3", "refresh-snap")
+
+	sub, err := notices.GetSubscription(st, 1000, "monitor")
+	c.Assert(err, IsNil)
+	pending := st.Notices(sub.Filter())
+	c.Assert(pending, HasLen, 2)
+	c.Check(pending[0].ID(), Equals, id1)
+	c.Check(pending[1].ID(), Equals, id3)
+
+	c.Assert(notices.AckSubscription(st, 1000, "monitor", st.Notice(id1)), IsNil)
+	sub, err = notices.GetSubscription(st, 1000, "monitor")
+	c.Assert(err, IsNil)
+	c.Check(sub.LastAckedID, Equals, id1)
+	pending = st.Notices(sub.Filter())
+	c.Assert(pending, HasLen, 1)
+	c.Check(pending[0].ID(), Equals, id3)
+
+	// acking an older notice does not move the cursor backwards
+	c.Assert(notices.AckSubscription(st, 1000, "monitor", st.Notice(id3)), IsNil)
+	c.Assert(notices.AckSubscription(st, 1000, "monitor", st.Notice(id1)), IsNil)
+	sub, err = notices.GetSubscription(st, 1000, "monitor")
+	c.Assert(err, IsNil)
+	c.Check(sub.LastAckedID, Equals, id3)
+	c.Check(st.Notices(sub.Filter()), HasLen, 0)
+
+	// re-registering keeps the cursor
+	c.Assert(notices.AddSubscription(st, &notices.Subscription{Name: "monitor", UserID: 1000}), IsNil)
+	sub, err = notices.GetSubscription(st, 1000, "monitor")
+	c.Assert(err, IsNil)
+	c.Check(sub.LastAckedID, Equals, id3)
+	c.Check(sub.Types, HasLen, 0)
+
+	c.Check(notices.AckSubscription(st, 1000, "other", st.Notice(id1)), Equals, notices.ErrNoSubscription)
+}
+
+type memBackend struct {
+	data []byte
+}
+
+func (b *memBackend) Checkpoint(data []byte) error {
+	b.data = append([]byte(nil), data...)
+	return nil
+}
+
+func (b *memBackend) EnsureBefore(d time.Duration) {}
+
+func (s *subscriptionsSuite) TestCursorSurvivesRestart(c *C) {
+	backend := &memBackend{}
+	st := state.New(backend)
+	st.Lock()
+	c.Assert(notices.AddSubscription(st, &notices.Subscription{Name: "monitor", UserID: 1000}), IsNil)
+	id, err := st.AddNotice(nil, state.WarningNotice, "danger", nil)
+	c.Assert(err, IsNil)
+	c.Assert(notices.AckSubscription(st, 1000, "monitor", st.Notice(id)), IsNil)
+	st.Unlock()
+
+	st2, err := state.ReadState(nil, bytes.NewReader(backend.data))
+	c.Assert(err, IsNil)
+	st2.Lock()
+	defer st2.Unlock()
+	sub, err := notices.GetSubscription(st2, 1000, "monitor")
+	c.Assert(err, IsNil)
+	c.Check(sub.LastAckedID, Equals, id)
+	c.Check(st2.Notices(sub.Filter()), HasLen, 0)
+}
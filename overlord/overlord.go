@@ -41,6 +41,7 @@ import (
 	"github.com/snapcore/snapd/overlord/cmdstate"
 	"github.com/snapcore/snapd/overlord/confdbstate"
 	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/configstate/cacheconf"
 	"github.com/snapcore/snapd/overlord/configstate/proxyconf"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/fdestate"
@@ -49,6 +50,7 @@ import (
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/notices"
 	"github.com/snapcore/snapd/overlord/patch"
+	"github.com/snapcore/snapd/overlord/peerstate"
 	"github.com/snapcore/snapd/overlord/restart"
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/snapshotstate"
@@ -117,8 +119,11 @@ type Overlord struct {
 	shotMgr    *snapshotstate.SnapshotManager
 	fdeMgr     *fdestate.FDEManager
 	noticeMgr  *notices.NoticeManager
+	peerMgr    *peerstate.PeerManager
 	// proxyConf mediates the http proxy config
 	proxyConf func(req *http.Request) (*url.URL, error)
+	// cacheLimits mediates the download cache config
+	cacheLimits func() (*store.CacheLimits, error)
 }
 
 var storeNew = store.New
@@ -199,6 +204,8 @@ func New(restartHandler restart.Handler) (*Overlord, error) {
 		return nil, err
 	}
 	healthstate.Init(hookMgr)
+	o.addManager(healthstate.Manager(s, hookMgr))
+	o.addManager(peerstate.Manager(s))
 
 	// the shared task runner should be added last!
 	o.stateEng.AddManager(o.runner)
@@ -207,6 +214,7 @@ func New(restartHandler restart.Handler) (*Overlord, error) {
 	defer s.Unlock()
 	// setting up the store
 	o.proxyConf = proxyconf.New(s).Conf
+	o.cacheLimits = cacheconf.New(s).Limits
 	storeCtx := storecontext.New(s, o.deviceMgr.StoreContextBackend())
 	sto := o.newStoreWithContext(storeCtx)
 
@@ -237,6 +245,8 @@ func (o *Overlord) addManager(mgr StateManager) {
 		o.restartMgr = x
 	case *fdestate.FDEManager:
 		o.fdeMgr = x
+	case *peerstate.PeerManager:
+		o.peerMgr = x
 	}
 	o.stateEng.AddManager(mgr)
 }
@@ -354,6 +364,10 @@ func initRestart(s *state.State, curBootID string, restartHandler restart.Handle
 func (o *Overlord) newStoreWithContext(storeCtx store.DeviceAndAuthContext) snapstate.StoreService {
 	cfg := store.DefaultConfig()
 	cfg.Proxy = o.proxyConf
+	cfg.CacheLimits = o.cacheLimits
+	if o.peerMgr != nil {
+		cfg.Peers = o.peerMgr.Peers
+	}
 	sto := storeNew(cfg, storeCtx)
 	sto.SetCacheDownloads(defaultCachedDownloads)
 	return sto
@@ -704,6 +718,12 @@ func (o *Overlord) NoticeManager() *notices.NoticeManager {
 	return o.noticeMgr
 }
 
+// PeerManager returns the manager responsible for sharing snaps with peers
+// on the local network.
+func (o *Overlord) PeerManager() *peerstate.PeerManager {
+	return o.peerMgr
+}
+
 // Mock creates an Overlord without any managers and with a backend
 // not using disk. Managers can be added with AddManager. For testing.
 func Mock() *Overlord {
@@ -128,6 +128,7 @@ func (ovs *overlordSuite) TestNew(c *C) {
 	c.Check(o.CommandManager(), NotNil)
 	c.Check(o.SnapshotManager(), NotNil)
 	c.Check(o.FDEManager(), NotNil)
+	c.Check(o.PeerManager(), NotNil)
 	c.Check(configstateInitCalled, Equals, true)
 
 	o.InterfaceManager().DisableUDevMonitor()
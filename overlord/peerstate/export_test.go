@@ -0,0 +1,73 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peerstate
+
+import (
+	"net"
+	"time"
+
+	"github.com/snapcore/snapd/testutil"
+)
+
+func MockNetListen(f func(network, address string) (net.Listener, error)) (restore func()) {
+	return testutil.Mock(&netListen, f)
+}
+
+func MockPeersListenMulticast(f func() (net.PacketConn, error)) (restore func()) {
+	return testutil.Mock(&peersListenMulticast, f)
+}
+
+func MockOsHostname(f func() (string, error)) (restore func()) {
+	return testutil.Mock(&osHostname, f)
+}
+
+func MockMDNSAddr(addr net.Addr) (restore func()) {
+	return testutil.Mock(&mdnsAddr, addr)
+}
+
+func MockDiscoveryTimeout(d time.Duration) (restore func()) {
+	return testutil.Mock(&discoveryTimeout, d)
+}
+
+var LocalNetHost = localNetHost
+
+func MockLocalNetHosts(f func() ([]string, error)) (restore func()) {
+	return testutil.Mock(&localNetHosts, f)
+}
+
+// SharingAddrs returns the addresses snaps are shared on.
+func (m *PeerManager) SharingAddrs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var addrs []string
+	for _, l := range m.listeners {
+		addrs = append(addrs, l.Addr().String())
+	}
+	return addrs
+}
+
+// SharingAddr returns the first address snaps are shared on, if any.
+func (m *PeerManager) SharingAddr() string {
+	addrs := m.SharingAddrs()
+	if len(addrs) == 0 {
+		return ""
+	}
+	return addrs[0]
+}
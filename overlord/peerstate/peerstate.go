@@ -0,0 +1,440 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package peerstate implements the manager sharing downloaded snaps with,
+// and finding, the peers on the local network, as configured via the
+// store.peers.* core options.
+//
+// When sharing is enabled, anyone who can reach the private and link-local
+// addresses of the host on the configured port can fetch the snaps served
+// there, without authentication. Only the installed revisions of the snaps
+// that anyone can download from the global store are served, and only
+// given their SHA3-384 digest, so what is exposed is which of those snap
+// revisions are installed.
+package peerstate
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store/peers"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var (
+	netListen            = net.Listen
+	peersListenMulticast = peers.ListenMulticast
+	osHostname           = os.Hostname
+
+	// mdnsAddr is where discovery queries are sent
+	mdnsAddr net.Addr = peers.MulticastAddr
+
+	discoveryTimeout = 1 * time.Second
+	// discoveryTTL is how long discovered peers are remembered for
+	discoveryTTL = 1 * time.Minute
+)
+
+type settings struct {
+	enabled bool
+	mdns    bool
+	port    int
+	static  []string
+	// hosts are the local network addresses snaps are shared on
+	hosts []string
+}
+
+// PeerManager shares the download cache with the peers on the local network
+// and finds the peers to download snaps from.
+type PeerManager struct {
+	state *state.State
+
+	mu       sync.Mutex
+	settings settings
+
+	listeners []net.Listener
+	server    *http.Server
+	mdnsConn  net.PacketConn
+
+	discovered   []string
+	discoveredAt time.Time
+
+	// sharedMu guards shared, so that serving peers doesn't need the
+	// state lock
+	sharedMu sync.Mutex
+	// shared are the snap files that can be served to peers
+	shared []os.FileInfo
+}
+
+// Manager returns a new PeerManager.
+func Manager(st *state.State) *PeerManager {
+	return &PeerManager{state: st}
+}
+
+func (m *PeerManager) readSettings() (*settings, error) {
+	m.state.Lock()
+	defer m.state.Unlock()
+	tr := config.NewTransaction(m.state)
+
+	var enabled, mdns bool
+	for key, value := range map[string]*bool{
+		"store.peers.enabled": &enabled,
+		"store.peers.mdns":    &mdns,
+	} {
+		if err := tr.GetMaybe("core", key, value); err != nil {
+			return nil, err
+		}
+	}
+	var port any
+	var static string
+	if err := tr.GetMaybe("core", "store.peers.port", &port); err != nil {
+		return nil, err
+	}
+	if err := tr.GetMaybe("core", "store.peers.static", &static); err != nil {
+		return nil, err
+	}
+
+	s := &settings{enabled: enabled, mdns: mdns, port: peers.DefaultPort}
+	if port != nil {
+		n, err := strconv.Atoi(fmt.Sprint(port))
+		if err != nil {
+			return nil, fmt.Errorf("invalid store.peers.port: %v", port)
+		}
+		s.port = n
+	}
+	staticPeers, err := peers.ParseStaticPeers(static)
+	if err != nil {
+		return nil, err
+	}
+	s.static = staticPeers
+	return s, nil
+}
+
+// Ensure starts or stops sharing snaps to match the configuration and the
+// addresses of the host.
+func (m *PeerManager) Ensure() error {
+	s, err := m.readSettings()
+	if err != nil {
+		logger.Noticef("cannot read the peers configuration: %v", err)
+		return nil
+	}
+	if s.enabled {
+		s.hosts, err = localNetHosts()
+		if err != nil {
+			logger.Noticef("cannot get the local network addresses: %v", err)
+			return nil
+		}
+	}
+	m.updateShared(s.enabled)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if reflect.DeepEqual(*s, m.settings) {
+		return nil
+	}
+	m.stopSharing()
+	m.settings = *s
+	m.discovered = nil
+	m.discoveredAt = time.Time{}
+	if s.enabled {
+		if err := m.startSharing(); err != nil {
+			logger.Noticef("cannot share snaps with peers: %v", err)
+			m.stopSharing()
+		}
+	}
+	return nil
+}
+
+// startSharing serves the shared snaps on the local network addresses of
+// the host only, so that they are not exposed beyond the local networks.
+func (m *PeerManager) startSharing() error {
+	if len(m.settings.hosts) == 0 {
+		return fmt.Errorf("no private or link-local address to share snaps on")
+	}
+
+	m.server = &http.Server{
+		Handler:           peers.Handler(dirs.SnapDownloadCacheDir, m.sharedBlob),
+		ReadHeaderTimeout: 10 * time.Second,
+		// long enough to send big snaps over a slow local network
+		WriteTimeout: 30 * time.Minute,
+		IdleTimeout:  1 * time.Minute,
+	}
+	port := m.settings.port
+	for _, host := range m.settings.hosts {
+		l, err := netListen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err != nil {
+			return err
+		}
+		m.listeners = append(m.listeners, l)
+		// listen on the same port everywhere if any was picked
+		if tcpAddr, ok := l.Addr().(*net.TCPAddr); ok {
+			port = tcpAddr.Port
+		}
+		go func(server *http.Server, l net.Listener) {
+			if err := server.Serve(l); err != nil && err != http.ErrServerClosed {
+				logger.Noticef("cannot serve snaps to peers: %v", err)
+			}
+		}(m.server, l)
+		logger.Debugf("sharing snaps with peers on %s", l.Addr())
+	}
+
+	if !m.settings.mdns {
+		return nil
+	}
+	conn, err := peersListenMulticast()
+	if err != nil {
+		return fmt.Errorf("cannot advertise over mDNS: %v", err)
+	}
+	m.mdnsConn = conn
+	hostname, err := osHostname()
+	if err != nil {
+		hostname = ""
+	}
+	responder := peers.NewResponder(conn, hostname, port)
+	go func() {
+		if err := responder.Serve(); err != nil {
+			logger.Noticef("cannot answer mDNS queries: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (m *PeerManager) stopSharing() {
+	if m.server != nil {
+		m.server.Close()
+		m.server = nil
+	}
+	// the server only closes the listeners it started serving on
+	for _, l := range m.listeners {
+		l.Close()
+	}
+	m.listeners = nil
+	if m.mdnsConn != nil {
+		m.mdnsConn.Close()
+		m.mdnsConn = nil
+	}
+}
+
+// Stop stops sharing snaps.
+func (m *PeerManager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopSharing()
+}
+
+// Peers returns the host:port addresses of the peers to try to download
+// snaps from, the statically configured ones first. It returns nothing if
+// sharing with peers is disabled.
+func (m *PeerManager) Peers() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.settings.enabled {
+		return nil
+	}
+	addrs := append([]string(nil), m.settings.static...)
+	if !m.settings.mdns {
+		return addrs
+	}
+
+	if m.discoveredAt.IsZero() || time.Since(m.discoveredAt) > discoveryTTL {
+		discovered, err := m.discover()
+		if err != nil {
+			logger.Debugf("cannot discover peers: %v", err)
+		}
+		m.discovered = discovered
+		m.discoveredAt = time.Now()
+	}
+	for _, addr := range m.discovered {
+		if !strutil.ListContains(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+func (m *PeerManager) discover() ([]string, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	found, err := peers.Discover(ctx, conn, mdnsAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// do not download from ourselves
+	var self string
+	if len(m.listeners) != 0 {
+		if tcpAddr, ok := m.listeners[0].Addr().(*net.TCPAddr); ok {
+			self = strconv.Itoa(tcpAddr.Port)
+		}
+	}
+	addrs := make([]string, 0, len(found))
+	for _, addr := range found {
+		host, port, err := net.SplitHostPort(addr)
+		if err == nil && port == self && isLocalAddr(host) {
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+var netInterfaceAddrs = net.InterfaceAddrs
+
+func isLocalAddr(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	ifaceAddrs, err := netInterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range ifaceAddrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateShared works out which snap files can be served to peers, that is
+// the installed revisions of the snaps that anyone can download from the
+// global store. Snaps that are private or come from a brand store need the
+// authentication of the user or device to be downloaded, so they are never
+// shared. This is done in Ensure, which runs regularly and after snaps
+// change, so that serving peers doesn't need the state.
+func (m *PeerManager) updateShared(enabled bool) {
+	var shared []os.FileInfo
+	if enabled {
+		shared = m.shareableSnapFiles()
+	}
+
+	m.sharedMu.Lock()
+	defer m.sharedMu.Unlock()
+	m.shared = shared
+}
+
+func (m *PeerManager) shareableSnapFiles() []os.FileInfo {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	deviceCtx, err := snapstate.DeviceCtx(m.state, nil, nil)
+	if err != nil || deviceCtx.Model().Store() != "" {
+		return nil
+	}
+	snapStates, err := snapstate.All(m.state)
+	if err != nil {
+		logger.Noticef("cannot get the snaps to share with peers: %v", err)
+		return nil
+	}
+	var shared []os.FileInfo
+	for name, snapst := range snapStates {
+		for _, si := range snapst.Sequence.SideInfos() {
+			if si.Private || si.SnapID == "" {
+				continue
+			}
+			if fi, err := os.Stat(snap.MountFile(name, si.Revision)); err == nil {
+				shared = append(shared, fi)
+			}
+		}
+	}
+	return shared
+}
+
+// sharedBlob returns whether the file of the download cache at the given
+// path can be served to peers.
+func (m *PeerManager) sharedBlob(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	m.sharedMu.Lock()
+	defer m.sharedMu.Unlock()
+	for _, snapFi := range m.shared {
+		// the cached files are hard links to the snap files
+		if os.SameFile(fi, snapFi) {
+			return true
+		}
+	}
+	return false
+}
+
+// localNetHosts returns the private and link-local addresses of the host,
+// with the zone of the link-local IPv6 ones.
+var localNetHosts = func() ([]string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var hosts []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if host, ok := localNetHost(ipNet.IP, iface.Name); ok {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts, nil
+}
+
+func localNetHost(ip net.IP, iface string) (host string, ok bool) {
+	switch {
+	case ip.IsPrivate():
+		return ip.String(), true
+	case ip.IsLinkLocalUnicast():
+		if ip.To4() == nil {
+			return ip.String() + "%" + iface, true
+		}
+		return ip.String(), true
+	}
+	return "", false
+}
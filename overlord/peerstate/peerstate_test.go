@@ -0,0 +1,351 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peerstate_test
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/peerstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store/peers"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func TestPeerState(t *testing.T) { TestingT(t) }
+
+type peerStateSuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&peerStateSuite{})
+
+var testDigest = strings.Repeat("0123456789abcdef", 6)
+
+func (s *peerStateSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	// the cached files are hard links to the snap files
+	c.Assert(os.MkdirAll(dirs.SnapDownloadCacheDir, 0755), IsNil)
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	snapPath := snap.MountFile("some-snap", snap.R(1))
+	c.Assert(os.WriteFile(snapPath, []byte("snap data"), 0644), IsNil)
+	c.Assert(os.Link(snapPath, filepath.Join(dirs.SnapDownloadCacheDir, testDigest)), IsNil)
+
+	s.AddCleanup(snapstatetest.UseFallbackDeviceModel())
+	s.AddCleanup(peerstate.MockLocalNetHosts(func() ([]string, error) {
+		return []string{"127.0.0.1"}, nil
+	}))
+	s.AddCleanup(peerstate.MockNetListen(func(network, address string) (net.Listener, error) {
+		c.Check(network, Equals, "tcp")
+		return net.Listen("tcp", "127.0.0.1:0")
+	}))
+	s.AddCleanup(peerstate.MockPeersListenMulticast(func() (net.PacketConn, error) {
+		return net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	}))
+	s.AddCleanup(peerstate.MockOsHostname(func() (string, error) {
+		return "some-host", nil
+	}))
+	s.AddCleanup(peerstate.MockDiscoveryTimeout(200 * time.Millisecond))
+}
+
+func (s *peerStateSuite) newManager(c *C, conf map[string]any) (*peerstate.PeerManager, *state.State) {
+	st := state.New(nil)
+	s.setConfig(st, conf)
+	setSnap(st, "some-snap", false)
+	m := peerstate.Manager(st)
+	s.AddCleanup(m.Stop)
+	return m, st
+}
+
+func (s *peerStateSuite) setConfig(st *state.State, conf map[string]any) {
+	st.Lock()
+	defer st.Unlock()
+	tr := config.NewTransaction(st)
+	for k, v := range conf {
+		tr.Set("core", k, v)
+	}
+	tr.Commit()
+}
+
+func setSnap(st *state.State, name string, private bool) {
+	st.Lock()
+	defer st.Unlock()
+	si := &snap.SideInfo{
+		RealName: name,
+		SnapID:   name + "-id",
+		Revision: snap.R(1),
+		Private:  private,
+	}
+	snapstate.Set(st, name, &snapstate.SnapState{
+		Active:   true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{si}),
+		Current:  si.Revision,
+	})
+}
+
+func getBlob(addr string) (int, string, error) {
+	resp, err := http.Get(peers.BlobURL(addr, testDigest))
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data), err
+}
+
+func (s *peerStateSuite) TestDisabledByDefault(c *C) {
+	restore := peerstate.MockNetListen(func(network, address string) (net.Listener, error) {
+		c.Fatal("unexpected listen")
+		return nil, nil
+	})
+	defer restore()
+
+	m, _ := s.newManager(c, nil)
+	c.Assert(m.Ensure(), IsNil)
+	c.Check(m.SharingAddr(), Equals, "")
+	c.Check(m.Peers(), IsNil)
+}
+
+func (s *peerStateSuite) TestSharing(c *C) {
+	restore := peerstate.MockNetListen(func(network, address string) (net.Listener, error) {
+		c.Check(address, Equals, "127.0.0.1:8000")
+		return net.Listen("tcp", "127.0.0.1:0")
+	})
+	defer restore()
+
+	m, st := s.newManager(c, map[string]any{
+		"store.peers.enabled": true,
+		"store.peers.port":    8000,
+		"store.peers.static":  "10.0.0.2,10.0.0.3:9000",
+	})
+	c.Assert(m.Ensure(), IsNil)
+	addr := m.SharingAddr()
+	c.Assert(addr, Not(Equals), "")
+
+	status, data, err := getBlob(addr)
+	c.Assert(err, IsNil)
+	c.Check(status, Equals, 200)
+	c.Check(data, Equals, "snap data")
+
+	c.Check(m.Peers(), DeepEquals, []string{"10.0.0.2:7380", "10.0.0.3:9000"})
+
+	// nothing changes if the configuration does not
+	c.Assert(m.Ensure(), IsNil)
+	c.Check(m.SharingAddr(), Equals, addr)
+
+	s.setConfig(st, map[string]any{"store.peers.enabled": false})
+	c.Assert(m.Ensure(), IsNil)
+	c.Check(m.SharingAddr(), Equals, "")
+	c.Check(m.Peers(), IsNil)
+	_, _, err = getBlob(addr)
+	c.Check(err, NotNil)
+}
+
+func (s *peerStateSuite) TestSharingOnlyPublicSnaps(c *C) {
+	m, st := s.newManager(c, map[string]any{"store.peers.enabled": true})
+	c.Assert(m.Ensure(), IsNil)
+	addr := m.SharingAddr()
+	c.Assert(addr, Not(Equals), "")
+
+	status, _, err := getBlob(addr)
+	c.Assert(err, IsNil)
+	c.Check(status, Equals, 200)
+
+	// private snaps need the user's authentication to be downloaded
+	setSnap(st, "some-snap", true)
+	c.Assert(m.Ensure(), IsNil)
+	status, _, err = getBlob(addr)
+	c.Assert(err, IsNil)
+	c.Check(status, Equals, 404)
+
+	// as do the snaps of brand stores, with the device's
+	setSnap(st, "some-snap", false)
+	restore := snapstatetest.MockDeviceModel(assertstest.FakeAssertion(map[string]any{
+		"type":         "model",
+		"authority-id": "my-brand",
+		"series":       "16",
+		"brand-id":     "my-brand",
+		"model":        "my-model",
+		"architecture": "amd64",
+		"store":        "my-brand-store",
+		"gadget":       "gadget",
+		"kernel":       "kernel",
+	}).(*asserts.Model))
+	defer restore()
+	c.Assert(m.Ensure(), IsNil)
+	status, _, err = getBlob(addr)
+	c.Assert(err, IsNil)
+	c.Check(status, Equals, 404)
+}
+
+func (s *peerStateSuite) TestSharingOnlyInstalledSnaps(c *C) {
+	m, st := s.newManager(c, map[string]any{"store.peers.enabled": true})
+	c.Assert(m.Ensure(), IsNil)
+
+	st.Lock()
+	snapstate.Set(st, "some-snap", nil)
+	st.Unlock()
+	c.Assert(m.Ensure(), IsNil)
+
+	status, _, err := getBlob(m.SharingAddr())
+	c.Assert(err, IsNil)
+	c.Check(status, Equals, 404)
+}
+
+func (s *peerStateSuite) TestSharingWithoutStateLock(c *C) {
+	m, st := s.newManager(c, map[string]any{"store.peers.enabled": true})
+	c.Assert(m.Ensure(), IsNil)
+
+	// peers are served from what Ensure worked out
+	st.Lock()
+	defer st.Unlock()
+	status, data, err := getBlob(m.SharingAddr())
+	c.Assert(err, IsNil)
+	c.Check(status, Equals, 200)
+	c.Check(data, Equals, "snap data")
+}
+
+func (s *peerStateSuite) TestSharingOnLocalNetworks(c *C) {
+	hosts := []string{"127.0.0.1", "127.0.0.2"}
+	restore := peerstate.MockLocalNetHosts(func() ([]string, error) {
+		return hosts, nil
+	})
+	defer restore()
+	var addresses []string
+	restore = peerstate.MockNetListen(func(network, address string) (net.Listener, error) {
+		addresses = append(addresses, address)
+		return net.Listen("tcp", "127.0.0.1:0")
+	})
+	defer restore()
+
+	m, _ := s.newManager(c, map[string]any{
+		"store.peers.enabled": true,
+		"store.peers.port":    0,
+	})
+	c.Assert(m.Ensure(), IsNil)
+	c.Assert(addresses, HasLen, 2)
+	c.Check(addresses[0], Equals, "127.0.0.1:0")
+	// the port picked for the first address is used for the others
+	_, port, err := net.SplitHostPort(m.SharingAddr())
+	c.Assert(err, IsNil)
+	c.Check(addresses[1], Equals, "127.0.0.2:"+port)
+
+	// sharing restarts when the addresses change
+	hosts = []string{"127.0.0.1"}
+	addresses = nil
+	c.Assert(m.Ensure(), IsNil)
+	c.Check(addresses, DeepEquals, []string{"127.0.0.1:0"})
+	c.Check(m.SharingAddrs(), HasLen, 1)
+
+	// nothing is shared without local network addresses
+	hosts = nil
+	c.Assert(m.Ensure(), IsNil)
+	c.Check(m.SharingAddrs(), HasLen, 0)
+}
+
+func (s *peerStateSuite) TestLocalNetHost(c *C) {
+	for _, tc := range []struct {
+		ip   string
+		host string
+	}{
+		{"10.0.0.2", "10.0.0.2"},
+		{"192.168.1.2", "192.168.1.2"},
+		{"169.254.1.2", "169.254.1.2"},
+		{"fd00::1", "fd00::1"},
+		{"fe80::1", "fe80::1%eth0"},
+		{"127.0.0.1", ""},
+		{"::1", ""},
+		{"203.0.113.1", ""},
+		{"2001:db8::1", ""},
+	} {
+		host, ok := peerstate.LocalNetHost(net.ParseIP(tc.ip), "eth0")
+		c.Check(host, Equals, tc.host, Commentf(tc.ip))
+		c.Check(ok, Equals, tc.host != "", Commentf(tc.ip))
+	}
+}
+
+func (s *peerStateSuite) TestSharingListenError(c *C) {
+	restore := peerstate.MockNetListen(func(network, address string) (net.Listener, error) {
+		return nil, fmt.Errorf("address already in use")
+	})
+	defer restore()
+
+	m, _ := s.newManager(c, map[string]any{
+		"store.peers.enabled": true,
+		"store.peers.static":  "10.0.0.2",
+	})
+	c.Assert(m.Ensure(), IsNil)
+	c.Check(m.SharingAddr(), Equals, "")
+	// peers are still used to download from
+	c.Check(m.Peers(), DeepEquals, []string{"10.0.0.2:7380"})
+}
+
+func (s *peerStateSuite) TestDiscovery(c *C) {
+	var mdnsConns []net.PacketConn
+	restore := peerstate.MockPeersListenMulticast(func() (net.PacketConn, error) {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		mdnsConns = append(mdnsConns, conn)
+		return conn, err
+	})
+	defer restore()
+
+	conf := map[string]any{
+		"store.peers.enabled": true,
+		"store.peers.mdns":    true,
+	}
+	m1, _ := s.newManager(c, conf)
+	c.Assert(m1.Ensure(), IsNil)
+	m2, _ := s.newManager(c, conf)
+	c.Assert(m2.Ensure(), IsNil)
+	c.Assert(mdnsConns, HasLen, 2)
+
+	// the second instance finds the first one
+	restore = peerstate.MockMDNSAddr(mdnsConns[0].LocalAddr())
+	defer restore()
+	c.Check(m2.Peers(), DeepEquals, []string{m1.SharingAddr()})
+
+	// which finds itself only
+	c.Check(m1.Peers(), HasLen, 0)
+
+	// and can serve the second one
+	status, data, err := getBlob(m2.Peers()[0])
+	c.Assert(err, IsNil)
+	c.Check(status, Equals, 200)
+	c.Check(data, Equals, "snap data")
+}
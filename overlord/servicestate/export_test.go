@@ -20,9 +20,13 @@
 package servicestate
 
 import (
+	"time"
+
 	tomb "gopkg.in/tomb.v2"
 
+	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/cgroup"
 	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -63,3 +67,29 @@ func MockResourcesCheckFeatureRequirements(f func(*quota.Resources) error) (rest
 	resourcesCheckFeatureRequirements = f
 	return r
 }
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	return testutil.Mock(&timeNow, f)
+}
+
+func MockQuotaGroupUsage(f func(grp *quota.Group) (quantity.Size, time.Duration, int, error)) (restore func()) {
+	return testutil.Mock(&quotaGroupUsage, f)
+}
+
+func (m *ServiceManager) EnsureQuotaUsageSampled() error {
+	return m.ensureQuotaUsageSampled()
+}
+
+const QuotaUsageHistorySize = quotaUsageHistorySize
+
+func MockCgroupReadMemoryEvents(f func(cgroupPath string) (*cgroup.MemoryEvents, error)) (restore func()) {
+	return testutil.Mock(&cgroupReadMemoryEvents, f)
+}
+
+func MockCgroupReadMemoryPressure(f func(cgroupPath string) (*cgroup.MemoryPressure, error)) (restore func()) {
+	return testutil.Mock(&cgroupReadMemoryPressure, f)
+}
+
+func (m *ServiceManager) EnsureQuotaOOMEventsHandled() error {
+	return m.ensureQuotaOOMEventsHandled()
+}
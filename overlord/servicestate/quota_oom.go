@@ -0,0 +1,440 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
+)
+
+// quotaOOMCheckInterval is how often the memory events of the quota groups
+// are checked for processes killed for running out of memory.
+const quotaOOMCheckInterval = 30 * time.Second
+
+var (
+	cgroupReadMemoryEvents   = cgroup.ReadMemoryEvents
+	cgroupReadMemoryPressure = cgroup.ReadMemoryPressure
+)
+
+// QuotaOOMEvents holds how many processes of a quota group were killed by
+// the kernel for running out of memory.
+type QuotaOOMEvents struct {
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+	// Services holds how many of the killed processes belonged to each
+	// service of the group, by "<snap>.<app>" name. Killed processes are
+	// not always attributed to a service, e.g. when the service was
+	// restarted since.
+	Services map[string]int `json:"services,omitempty"`
+}
+
+func allQuotaOOMEvents(st *state.State) (map[string]*QuotaOOMEvents, error) {
+	var events map[string]*QuotaOOMEvents
+	if err := st.Get("quota-oom-events", &events); err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+	if events == nil {
+		events = make(map[string]*QuotaOOMEvents)
+	}
+	return events, nil
+}
+
+// QuotaOOMKills returns the processes of the given quota group which were
+// killed for running out of memory, or nil if there were none.
+func QuotaOOMKills(st *state.State, group string) (*QuotaOOMEvents, error) {
+	events, err := allQuotaOOMEvents(st)
+	if err != nil {
+		return nil, err
+	}
+	return events[group], nil
+}
+
+// ServiceOOMKills returns how many processes of each service, by
+// "<snap>.<app>" name, were killed for running out of the memory of their
+// quota group.
+func ServiceOOMKills(st *state.State) (map[string]int, error) {
+	events, err := allQuotaOOMEvents(st)
+	if err != nil {
+		return nil, err
+	}
+	kills := make(map[string]int)
+	for _, ev := range events {
+		for svc, n := range ev.Services {
+			kills[svc] += n
+		}
+	}
+	return kills, nil
+}
+
+type quotaOOMCountersKey struct{}
+
+// cachedOOMCounters returns the last seen count of OOM kills of each cgroup
+// by path, which is only kept in memory.
+func cachedOOMCounters(st *state.State) map[string]uint64 {
+	if counters, ok := st.Cached(quotaOOMCountersKey{}).(map[string]uint64); ok {
+		return counters
+	}
+	counters := make(map[string]uint64)
+	st.Cache(quotaOOMCountersKey{}, counters)
+	return counters
+}
+
+type quotaMemoryPressureKey struct{}
+
+// cachedOverPressure returns whether the memory pressure of each quota group
+// by name was over its threshold when last checked, which is only kept in
+// memory.
+func cachedOverPressure(st *state.State) map[string]bool {
+	if overPressure, ok := st.Cached(quotaMemoryPressureKey{}).(map[string]bool); ok {
+		return overPressure
+	}
+	overPressure := make(map[string]bool)
+	st.Cache(quotaMemoryPressureKey{}, overPressure)
+	return overPressure
+}
+
+// quotaGroupServices returns the services which are directly in the quota
+// group, that is not in one of its sub-groups.
+func quotaGroupServices(st *state.State, grp *quota.Group, allGrps map[string]*quota.Group) ([]*snap.AppInfo, error) {
+	inSubGroup := make(map[string]bool)
+	for _, sub := range grp.SubGroups {
+		if subGrp := allGrps[sub]; subGrp != nil {
+			for _, svc := range subGrp.Services {
+				inSubGroup[svc] = true
+			}
+		}
+	}
+
+	var apps []*snap.AppInfo
+	for _, snapName := range grp.Snaps {
+		info, err := snapstate.CurrentInfo(st, snapName)
+		if err != nil {
+			return nil, err
+		}
+		for _, app := range info.Services() {
+			if !inSubGroup[snapName+"."+app.Name] {
+				apps = append(apps, app)
+			}
+		}
+	}
+	for _, svc := range grp.Services {
+		snapName, appName, err := splitSnapServiceName(svc)
+		if err != nil {
+			return nil, err
+		}
+		info, err := snapstate.CurrentInfo(st, snapName)
+		if err != nil {
+			return nil, err
+		}
+		if app := info.Apps[appName]; app != nil && app.IsService() {
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
+// quotaOOMPolicy returns the OOM policy of the group, which is inherited
+// from the closest parent group with one when not set.
+func quotaOOMPolicy(grp *quota.Group, allGrps map[string]*quota.Group) quota.OOMPolicy {
+	for grp != nil {
+		if grp.OOMPolicy != "" {
+			return grp.OOMPolicy
+		}
+		grp = allGrps[grp.ParentGroup]
+	}
+	return quota.OOMPolicyNotify
+}
+
+// readOOMKills returns how many processes were killed in the given cgroup
+// since last checked. The first time a cgroup is seen only its counter is
+// recorded, unless countFirst is set.
+func readOOMKills(counters map[string]uint64, cgroupPath string, countFirst bool) (uint64, error) {
+	events, err := cgroupReadMemoryEvents(cgroupPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// the slice or service is not running
+			return 0, nil
+		}
+		return 0, err
+	}
+	prev, seen := counters[cgroupPath]
+	counters[cgroupPath] = events.OOMKill
+	switch {
+	case !seen && !countFirst:
+		return 0, nil
+	case events.OOMKill < prev:
+		// the cgroup was re-created meanwhile
+		return events.OOMKill, nil
+	default:
+		return events.OOMKill - prev, nil
+	}
+}
+
+type quotaOOMKills struct {
+	count    uint64
+	services map[*snap.AppInfo]uint64
+	pressure *cgroup.MemoryPressure
+	// overPressure is set when the memory pressure of the group went over
+	// its threshold since last checked
+	overPressure bool
+}
+
+// ensureQuotaOOMEventsHandled looks for processes of quota groups killed for
+// running out of memory since last checked, records them and applies the
+// OOM policy of their groups. The policy is also applied when the memory
+// pressure of a group goes over its threshold, once until it goes back
+// under it.
+func (m *ServiceManager) ensureQuotaOOMEventsHandled() error {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	now := timeNow()
+	if !m.nextOOMCheck.IsZero() && now.Before(m.nextOOMCheck) {
+		return nil
+	}
+
+	var seeded bool
+	if err := st.Get("seeded", &seeded); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	if !seeded {
+		return nil
+	}
+
+	allGrps, err := AllQuotas(st)
+	if err != nil {
+		return err
+	}
+	m.nextOOMCheck = now.Add(quotaOOMCheckInterval)
+	if len(allGrps) == 0 {
+		return nil
+	}
+
+	logger.Trace("ensure", "manager", "ServiceManager", "func", "ensureQuotaOOMEventsHandled")
+
+	groupServices := make(map[string][]*snap.AppInfo, len(allGrps))
+	for name, grp := range allGrps {
+		apps, err := quotaGroupServices(st, grp, allGrps)
+		if err != nil {
+			logger.Noticef("cannot get services of quota group %q: %v", name, err)
+			continue
+		}
+		groupServices[name] = apps
+	}
+	counters := cachedOOMCounters(st)
+	overPressure := cachedOverPressure(st)
+
+	// reading the cgroup files does not need the state
+	st.Unlock()
+	slicePaths := make(map[string]string, len(allGrps))
+	for name, grp := range allGrps {
+		slicePaths[name] = cgroup.SlicePath(grp.SliceFileName())
+	}
+	// the counters of a slice include the ones of its sub-groups
+	sliceKills := make(map[string]uint64, len(allGrps))
+	for name := range allGrps {
+		kills, err := readOOMKills(counters, slicePaths[name], false)
+		if err != nil {
+			logger.Debugf("cannot read memory events of quota group %q: %v", name, err)
+			continue
+		}
+		sliceKills[name] = kills
+	}
+	killed := make(map[string]*quotaOOMKills)
+	for name, grp := range allGrps {
+		count := sliceKills[name]
+		for _, sub := range grp.SubGroups {
+			if subCount := sliceKills[sub]; subCount < count {
+				count -= subCount
+			} else {
+				count = 0
+			}
+		}
+		var pressure *cgroup.MemoryPressure
+		var pressured bool
+		if count != 0 || grp.MemoryPressureThreshold != 0 {
+			if p, err := cgroupReadMemoryPressure(slicePaths[name]); err == nil {
+				pressure = p
+			}
+		}
+		if grp.MemoryPressureThreshold != 0 {
+			over := pressure != nil && pressure.Avg10 > float64(grp.MemoryPressureThreshold)
+			pressured = over && !overPressure[name]
+			overPressure[name] = over
+		}
+		if count == 0 && !pressured {
+			continue
+		}
+		grpKills := &quotaOOMKills{
+			count:        count,
+			services:     make(map[*snap.AppInfo]uint64),
+			pressure:     pressure,
+			overPressure: pressured,
+		}
+		for _, app := range groupServices[name] {
+			// a service cgroup seen for the first time was just
+			// created, so all of its kills are new
+			kills, err := readOOMKills(counters, filepath.Join(slicePaths[name], app.ServiceName()), true)
+			if err != nil {
+				logger.Debugf("cannot read memory events of service %q: %v", app.ServiceName(), err)
+				continue
+			}
+			if kills != 0 {
+				grpKills.services[app] = kills
+			}
+		}
+		killed[name] = grpKills
+	}
+	st.Lock()
+
+	if len(killed) == 0 {
+		return nil
+	}
+	allEvents, err := allQuotaOOMEvents(st)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(killed))
+	for name := range killed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		handleQuotaOOMKills(st, allGrps[name], allGrps, groupServices[name], killed[name], allEvents, now)
+	}
+	st.Set("quota-oom-events", allEvents)
+	return nil
+}
+
+func handleQuotaOOMKills(st *state.State, grp *quota.Group, allGrps map[string]*quota.Group, groupApps []*snap.AppInfo, kills *quotaOOMKills, allEvents map[string]*QuotaOOMEvents, now time.Time) {
+	var apps []*snap.AppInfo
+	var svcNames []string
+	if kills.count != 0 {
+		events := allEvents[grp.Name]
+		if events == nil {
+			events = &QuotaOOMEvents{}
+			allEvents[grp.Name] = events
+		}
+		events.Count += int(kills.count)
+		events.Last = now
+
+		for app, n := range kills.services {
+			name := app.Snap.InstanceName() + "." + app.Name
+			if events.Services == nil {
+				events.Services = make(map[string]int)
+			}
+			events.Services[name] += int(n)
+			apps = append(apps, app)
+			svcNames = append(svcNames, name)
+		}
+		sort.Strings(svcNames)
+	}
+
+	policy := quotaOOMPolicy(grp, allGrps)
+	data := map[string]string{
+		"oom-kills": strconv.FormatUint(kills.count, 10),
+		"policy":    string(policy),
+	}
+	if len(svcNames) != 0 {
+		data["services"] = strings.Join(svcNames, ",")
+	}
+	if kills.pressure != nil {
+		data["memory-pressure"] = strconv.FormatFloat(kills.pressure.Avg10, 'f', 2, 64)
+	}
+	if grp.MemoryPressureThreshold != 0 {
+		data["memory-pressure-threshold"] = strconv.Itoa(grp.MemoryPressureThreshold)
+	}
+	if _, err := st.AddNotice(nil, state.QuotaOOMNotice, grp.Name, &state.AddNoticeOptions{Data: data}); err != nil {
+		logger.Noticef("cannot record processes of quota group %q running out of memory: %v", grp.Name, err)
+	}
+	if kills.count != 0 {
+		logger.Noticef("%d processes of quota group %q were killed for running out of memory", kills.count, grp.Name)
+	}
+	if kills.overPressure {
+		logger.Noticef("memory pressure of quota group %q went over %d%%", grp.Name, grp.MemoryPressureThreshold)
+	}
+
+	switch policy {
+	case quota.OOMPolicyRestartService:
+		// when the killed processes cannot be attributed to services
+		// restart all of them
+		if len(apps) == 0 {
+			apps = groupApps
+		}
+	case quota.OOMPolicyRestartGroup:
+		apps = groupApps
+	default:
+		return
+	}
+	// the kills take precedence when describing why services get restarted
+	summary := fmt.Sprintf("Restart services of quota group %q after running out of memory", grp.Name)
+	if kills.count == 0 {
+		summary = fmt.Sprintf("Restart services of quota group %q under memory pressure", grp.Name)
+	}
+	if err := restartServicesAfterOOM(st, summary, grp.Name, apps); err != nil {
+		logger.Noticef("cannot restart services of quota group %q after running out of memory: %v", grp.Name, err)
+	}
+}
+
+// restartServicesAfterOOM creates a change with the given summary restarting
+// the given services of the quota group.
+func restartServicesAfterOOM(st *state.State, summary, grpName string, apps []*snap.AppInfo) error {
+	if len(apps) == 0 {
+		return nil
+	}
+	// the services of a snap may come from different infos of it
+	infos := make(map[string]*snap.Info)
+	servicesBySnap := make(map[*snap.Info][]*snap.AppInfo)
+	var snapNames []string
+	for _, app := range apps {
+		name := app.Snap.InstanceName()
+		info := infos[name]
+		if info == nil {
+			info = app.Snap
+			infos[name] = info
+			snapNames = append(snapNames, name)
+		}
+		servicesBySnap[info] = append(servicesBySnap[info], app)
+	}
+	sort.Strings(snapNames)
+	if err := snapstate.CheckChangeConflictMany(st, snapNames, ""); err != nil {
+		return err
+	}
+
+	chg := st.NewChange("service-control", summary)
+	addRestartServicesTasks(st, chg.AddTask, grpName, servicesBySnap)
+	chg.Set("snap-names", snapNames)
+	st.EnsureBefore(0)
+	return nil
+}
@@ -0,0 +1,356 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate_test
+
+import (
+	"os"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/servicestate/servicestatetest"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/snapdenv"
+)
+
+type quotaOOMSuite struct {
+	baseServiceMgrTestSuite
+
+	now time.Time
+	// oomKills holds the oom_kill counter of each cgroup by path, cgroups
+	// not in there do not exist
+	oomKills map[string]uint64
+	// pressure holds the memory pressure of each cgroup by path
+	pressure map[string]float64
+}
+
+var _ = Suite(&quotaOOMSuite{})
+
+func (s *quotaOOMSuite) SetUpTest(c *C) {
+	s.baseServiceMgrTestSuite.SetUpTest(c)
+
+	servicestate.MockEnsuredSnapServices(s.mgr, true)
+
+	s.now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.AddCleanup(servicestate.MockTimeNow(func() time.Time { return s.now }))
+
+	s.oomKills = make(map[string]uint64)
+	s.AddCleanup(servicestate.MockCgroupReadMemoryEvents(func(cgroupPath string) (*cgroup.MemoryEvents, error) {
+		kills, ok := s.oomKills[cgroupPath]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return &cgroup.MemoryEvents{OOM: kills, OOMKill: kills}, nil
+	}))
+	s.pressure = make(map[string]float64)
+	s.AddCleanup(servicestate.MockCgroupReadMemoryPressure(func(cgroupPath string) (*cgroup.MemoryPressure, error) {
+		if avg10, ok := s.pressure[cgroupPath]; ok {
+			return &cgroup.MemoryPressure{Avg10: avg10}, nil
+		}
+		return &cgroup.MemoryPressure{Avg10: 42.5}, nil
+	}))
+	s.AddCleanup(snapdenv.MockPreseeding(false))
+}
+
+func (s *quotaOOMSuite) mockGroups(c *C, policy quota.OOMPolicy) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "test-snap", s.testSnapState)
+	snaptest.MockSnapCurrent(c, testYamlTwoServices, s.testSnapSideInfo)
+
+	_, err := servicestatetest.PatchQuotas(s.state,
+		&quota.Group{
+			Name:        "foo",
+			MemoryLimit: quantity.SizeGiB,
+			OOMPolicy:   policy,
+			Snaps:       []string{"test-snap"},
+			SubGroups:   []string{"bar"},
+		},
+		&quota.Group{
+			Name:        "bar",
+			MemoryLimit: 512 * quantity.SizeMiB,
+			ParentGroup: "foo",
+			Services:    []string{"test-snap.svc2"},
+		},
+	)
+	c.Assert(err, IsNil)
+}
+
+const (
+	testYamlTwoServices = `name: test-snap
+version: v1
+apps:
+  svc1:
+    command: bin.sh
+    daemon: simple
+  svc2:
+    command: bin.sh
+    daemon: simple
+`
+
+	fooSlicePath = "snap.foo.slice"
+	barSlicePath = "snap.foo.slice/snap.foo-bar.slice"
+)
+
+func (s *quotaOOMSuite) TestEnsureRecordsOOMKills(c *C) {
+	s.mockGroups(c, "")
+
+	// counters from before snapd started are not counted
+	s.oomKills[fooSlicePath] = 3
+	s.oomKills[barSlicePath] = 1
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+
+	s.state.Lock()
+	events, err := servicestate.QuotaOOMKills(s.state, "foo")
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+	c.Check(events, IsNil)
+
+	// not checked again before the interval elapsed
+	s.oomKills[fooSlicePath] = 5
+	s.oomKills[fooSlicePath+"/snap.test-snap.svc1.service"] = 2
+	s.now = s.now.Add(10 * time.Second)
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+	s.state.Lock()
+	events, err = servicestate.QuotaOOMKills(s.state, "foo")
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+	c.Check(events, IsNil)
+
+	s.now = s.now.Add(time.Minute)
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	events, err = servicestate.QuotaOOMKills(s.state, "foo")
+	c.Assert(err, IsNil)
+	c.Check(events, DeepEquals, &servicestate.QuotaOOMEvents{
+		Count:    2,
+		Last:     s.now,
+		Services: map[string]int{"test-snap.svc1": 2},
+	})
+	events, err = servicestate.QuotaOOMKills(s.state, "bar")
+	c.Assert(err, IsNil)
+	c.Check(events, IsNil)
+
+	kills, err := servicestate.ServiceOOMKills(s.state)
+	c.Assert(err, IsNil)
+	c.Check(kills, DeepEquals, map[string]int{"test-snap.svc1": 2})
+
+	notices := s.state.Notices(&state.NoticeFilter{Types: []state.NoticeType{state.QuotaOOMNotice}})
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].Key(), Equals, "foo")
+	c.Check(notices[0].LastData(), DeepEquals, map[string]string{
+		"oom-kills":       "2",
+		"policy":          "notify",
+		"services":        "test-snap.svc1",
+		"memory-pressure": "42.50",
+	})
+
+	// nothing gets restarted by default
+	c.Check(s.state.Changes(), HasLen, 0)
+}
+
+func (s *quotaOOMSuite) TestEnsureSubGroupOOMKills(c *C) {
+	s.mockGroups(c, "")
+
+	s.oomKills[fooSlicePath] = 0
+	s.oomKills[barSlicePath] = 0
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+
+	// the kills of the sub-group are also counted by the parent slice
+	s.oomKills[fooSlicePath] = 1
+	s.oomKills[barSlicePath] = 1
+	s.now = s.now.Add(time.Minute)
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	events, err := servicestate.QuotaOOMKills(s.state, "foo")
+	c.Assert(err, IsNil)
+	c.Check(events, IsNil)
+	events, err = servicestate.QuotaOOMKills(s.state, "bar")
+	c.Assert(err, IsNil)
+	// the service was not running anymore
+	c.Check(events, DeepEquals, &servicestate.QuotaOOMEvents{Count: 1, Last: s.now})
+}
+
+func (s *quotaOOMSuite) TestEnsureRestartServicePolicy(c *C) {
+	s.mockGroups(c, quota.OOMPolicyRestartService)
+
+	s.oomKills[fooSlicePath] = 0
+	s.oomKills[barSlicePath] = 0
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+
+	s.oomKills[fooSlicePath] = 1
+	s.oomKills[barSlicePath] = 1
+	s.oomKills[barSlicePath+"/snap.test-snap.svc2.service"] = 1
+	s.now = s.now.Add(time.Minute)
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the policy is inherited from the parent group
+	notices := s.state.Notices(&state.NoticeFilter{Types: []state.NoticeType{state.QuotaOOMNotice}})
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].Key(), Equals, "bar")
+	c.Check(notices[0].LastData()["policy"], Equals, "restart-service")
+
+	c.Assert(s.state.Changes(), HasLen, 1)
+	chg := s.state.Changes()[0]
+	c.Check(chg.Kind(), Equals, "service-control")
+	c.Check(chg.Summary(), Equals, `Restart services of quota group "bar" after running out of memory`)
+	var snapNames []string
+	c.Assert(chg.Get("snap-names", &snapNames), IsNil)
+	c.Check(snapNames, DeepEquals, []string{"test-snap"})
+	tasks := chg.Tasks()
+	c.Assert(tasks, HasLen, 1)
+	c.Check(tasks[0].Kind(), Equals, "service-control")
+	var action servicestate.ServiceAction
+	c.Assert(tasks[0].Get("service-action", &action), IsNil)
+	c.Check(action.Action, Equals, "restart")
+	c.Check(action.Services, DeepEquals, []string{"svc2"})
+}
+
+func (s *quotaOOMSuite) TestEnsureRestartGroupPolicy(c *C) {
+	s.mockGroups(c, quota.OOMPolicyRestartGroup)
+
+	s.oomKills[fooSlicePath] = 0
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+
+	// kills which cannot be attributed to a service
+	s.oomKills[fooSlicePath] = 4
+	s.now = s.now.Add(time.Minute)
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	events, err := servicestate.QuotaOOMKills(s.state, "foo")
+	c.Assert(err, IsNil)
+	c.Check(events, DeepEquals, &servicestate.QuotaOOMEvents{Count: 4, Last: s.now})
+
+	// the services of the sub-group are left alone
+	c.Assert(s.state.Changes(), HasLen, 1)
+	tasks := s.state.Changes()[0].Tasks()
+	c.Assert(tasks, HasLen, 1)
+	var action servicestate.ServiceAction
+	c.Assert(tasks[0].Get("service-action", &action), IsNil)
+	c.Check(action.Services, DeepEquals, []string{"svc1"})
+}
+
+func (s *quotaOOMSuite) TestEnsureMemoryPressureThreshold(c *C) {
+	s.mockGroups(c, quota.OOMPolicyRestartGroup)
+	s.state.Lock()
+	_, err := servicestatetest.PatchQuotas(s.state, &quota.Group{
+		Name:                    "foo",
+		MemoryLimit:             quantity.SizeGiB,
+		MemoryPressureThreshold: 50,
+		OOMPolicy:               quota.OOMPolicyRestartGroup,
+		Snaps:                   []string{"test-snap"},
+		SubGroups:               []string{"bar"},
+	})
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+
+	s.oomKills[fooSlicePath] = 0
+	s.pressure[fooSlicePath] = 20
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 0)
+	s.state.Unlock()
+
+	s.pressure[fooSlicePath] = 75.5
+	s.now = s.now.Add(time.Minute)
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+
+	s.state.Lock()
+	// no process was killed
+	events, err := servicestate.QuotaOOMKills(s.state, "foo")
+	c.Assert(err, IsNil)
+	c.Check(events, IsNil)
+
+	notices := s.state.Notices(&state.NoticeFilter{Types: []state.NoticeType{state.QuotaOOMNotice}})
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].Key(), Equals, "foo")
+	c.Check(notices[0].LastData(), DeepEquals, map[string]string{
+		"oom-kills":                 "0",
+		"policy":                    "restart-group",
+		"memory-pressure":           "75.50",
+		"memory-pressure-threshold": "50",
+	})
+
+	c.Assert(s.state.Changes(), HasLen, 1)
+	chg := s.state.Changes()[0]
+	c.Check(chg.Summary(), Equals, `Restart services of quota group "foo" under memory pressure`)
+	tasks := chg.Tasks()
+	c.Assert(tasks, HasLen, 1)
+	var action servicestate.ServiceAction
+	c.Assert(tasks[0].Get("service-action", &action), IsNil)
+	c.Check(action.Services, DeepEquals, []string{"svc1"})
+	chg.SetStatus(state.DoneStatus)
+	s.state.Unlock()
+
+	// the policy is applied once while the pressure stays over the threshold
+	s.pressure[fooSlicePath] = 80
+	s.now = s.now.Add(time.Minute)
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 1)
+	s.state.Unlock()
+
+	// and again after it went back under it
+	s.pressure[fooSlicePath] = 10
+	s.now = s.now.Add(time.Minute)
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+	s.pressure[fooSlicePath] = 60
+	s.now = s.now.Add(time.Minute)
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 2)
+	notices = s.state.Notices(&state.NoticeFilter{Types: []state.NoticeType{state.QuotaOOMNotice}})
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].LastData()["memory-pressure"], Equals, "60.00")
+}
+
+func (s *quotaOOMSuite) TestEnsureNotSeeded(c *C) {
+	s.mockGroups(c, "")
+	s.state.Lock()
+	s.state.Set("seeded", false)
+	s.state.Unlock()
+
+	s.AddCleanup(servicestate.MockCgroupReadMemoryEvents(func(cgroupPath string) (*cgroup.MemoryEvents, error) {
+		c.Fatalf("unexpected call")
+		return nil, nil
+	}))
+	c.Assert(s.mgr.EnsureQuotaOOMEventsHandled(), IsNil)
+}
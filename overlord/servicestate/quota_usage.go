@@ -0,0 +1,275 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"errors"
+	"time"
+
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap/quota"
+)
+
+const (
+	// quotaUsageSampleInterval is how often the resource usage of the
+	// quota groups is sampled.
+	quotaUsageSampleInterval = time.Minute
+	// quotaUsageHistorySize is how many samples are kept for each quota
+	// group, a day worth of them.
+	quotaUsageHistorySize = 24 * 60
+	// quotaUsageAlertSamples is how many consecutive samples must be above
+	// the alert threshold before warning about it.
+	quotaUsageAlertSamples = 5
+)
+
+var timeNow = time.Now
+
+// QuotaUsageSample holds the resource usage of a quota group at a given
+// time. All resources are sampled, whether the group has a limit for them
+// or not, so that limits can be picked from the actual usage.
+type QuotaUsageSample struct {
+	Time   time.Time
+	Memory quantity.Size
+	// CPU is the usage since the previous sample as a percentage of a
+	// single CPU, i.e. 200 means two CPUs were kept busy.
+	CPU     int
+	Threads int
+}
+
+// quotaUsageHistory is a ring buffer of the usage samples of a quota group.
+type quotaUsageHistory struct {
+	samples []QuotaUsageSample
+	// next is the position of the next sample in samples, once the
+	// buffer is full it is also the position of the oldest sample
+	next int
+
+	// cpuTime is the CPU time consumed by the group when last sampled
+	cpuTime time.Duration
+	// above counts the consecutive samples that were above the alert
+	// threshold for each resource
+	above map[string]int
+}
+
+func newQuotaUsageHistory() *quotaUsageHistory {
+	return &quotaUsageHistory{
+		samples: make([]QuotaUsageSample, 0, quotaUsageHistorySize),
+		above:   make(map[string]int),
+	}
+}
+
+func (h *quotaUsageHistory) add(sample QuotaUsageSample) {
+	if len(h.samples) < cap(h.samples) {
+		h.samples = append(h.samples, sample)
+	} else {
+		h.samples[h.next] = sample
+	}
+	h.next = (h.next + 1) % cap(h.samples)
+}
+
+func (h *quotaUsageHistory) last() *QuotaUsageSample {
+	if len(h.samples) == 0 {
+		return nil
+	}
+	return &h.samples[(h.next+len(h.samples)-1)%len(h.samples)]
+}
+
+// since returns the samples taken after the given time, oldest first.
+func (h *quotaUsageHistory) since(t time.Time) []QuotaUsageSample {
+	var samples []QuotaUsageSample
+	start := 0
+	if len(h.samples) == cap(h.samples) {
+		start = h.next
+	}
+	for i := 0; i < len(h.samples); i++ {
+		sample := h.samples[(start+i)%len(h.samples)]
+		if sample.Time.After(t) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+type quotaUsageKey struct{}
+
+// cachedQuotaUsage returns the usage history of the quota groups, which is
+// only kept in memory.
+func cachedQuotaUsage(st *state.State) map[string]*quotaUsageHistory {
+	if usage, ok := st.Cached(quotaUsageKey{}).(map[string]*quotaUsageHistory); ok {
+		return usage
+	}
+	usage := make(map[string]*quotaUsageHistory)
+	st.Cache(quotaUsageKey{}, usage)
+	return usage
+}
+
+// QuotaUsage returns the usage samples of the given quota group taken after
+// the given time, oldest first.
+func QuotaUsage(st *state.State, name string, since time.Time) []QuotaUsageSample {
+	history := cachedQuotaUsage(st)[name]
+	if history == nil {
+		return nil
+	}
+	return history.since(since)
+}
+
+// quotaGroupUsage returns the memory, cumulative CPU time and threads used
+// by the given quota group.
+var quotaGroupUsage = func(grp *quota.Group) (memory quantity.Size, cpuTime time.Duration, threads int, err error) {
+	if memory, err = grp.CurrentMemoryUsage(); err != nil {
+		return 0, 0, 0, err
+	}
+	if cpuTime, err = grp.CurrentCPUUsage(); err != nil {
+		return 0, 0, 0, err
+	}
+	if threads, err = grp.CurrentTaskUsage(); err != nil {
+		return 0, 0, 0, err
+	}
+	return memory, cpuTime, threads, nil
+}
+
+// quotaUsageAlertThreshold returns the percentage of a limit above which the
+// usage of a quota group is warned about, or 0 if it is not set.
+func quotaUsageAlertThreshold(st *state.State) (int, error) {
+	var threshold int
+	err := config.NewTransaction(st).Get("core", "quota.usage-alert-threshold", &threshold)
+	if err != nil && !config.IsNoOption(err) {
+		return 0, err
+	}
+	return threshold, nil
+}
+
+// ensureQuotaUsageSampled samples the resource usage of all quota groups
+// when due and warns about groups staying close to their limits.
+func (m *ServiceManager) ensureQuotaUsageSampled() error {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	now := timeNow()
+	if !m.nextUsageSample.IsZero() && now.Before(m.nextUsageSample) {
+		return nil
+	}
+
+	var seeded bool
+	if err := st.Get("seeded", &seeded); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	if !seeded {
+		return nil
+	}
+
+	allGrps, err := AllQuotas(st)
+	if err != nil {
+		return err
+	}
+	usage := cachedQuotaUsage(st)
+	for name := range usage {
+		if allGrps[name] == nil {
+			delete(usage, name)
+		}
+	}
+	m.nextUsageSample = now.Add(quotaUsageSampleInterval)
+	if len(allGrps) == 0 {
+		return nil
+	}
+
+	logger.Trace("ensure", "manager", "ServiceManager", "func", "ensureQuotaUsageSampled")
+
+	threshold, err := quotaUsageAlertThreshold(st)
+	if err != nil {
+		return err
+	}
+
+	// querying systemd can be slow, do not hold the state lock meanwhile
+	type groupUsage struct {
+		memory  quantity.Size
+		cpuTime time.Duration
+		threads int
+	}
+	sampled := make(map[string]groupUsage, len(allGrps))
+	st.Unlock()
+	for name, grp := range allGrps {
+		memory, cpuTime, threads, err := quotaGroupUsage(grp)
+		if err != nil {
+			logger.Debugf("cannot sample usage of quota group %q: %v", name, err)
+			continue
+		}
+		sampled[name] = groupUsage{memory: memory, cpuTime: cpuTime, threads: threads}
+	}
+	st.Lock()
+
+	for name, grpUsage := range sampled {
+		history := usage[name]
+		if history == nil {
+			history = newQuotaUsageHistory()
+			usage[name] = history
+		}
+		sample := QuotaUsageSample{
+			Time:    now,
+			Memory:  grpUsage.memory,
+			Threads: grpUsage.threads,
+		}
+		if prev := history.last(); prev != nil && grpUsage.cpuTime >= history.cpuTime {
+			if elapsed := now.Sub(prev.Time); elapsed > 0 {
+				sample.CPU = int((grpUsage.cpuTime - history.cpuTime) * 100 / elapsed)
+			}
+		}
+		history.cpuTime = grpUsage.cpuTime
+		history.add(sample)
+
+		if threshold != 0 {
+			checkQuotaUsageAlerts(st, allGrps[name], history, threshold)
+		}
+	}
+
+	st.EnsureBefore(quotaUsageSampleInterval)
+	return nil
+}
+
+// checkQuotaUsageAlerts adds a warning when the usage of a resource of the
+// quota group has stayed above the threshold percentage of its limit for
+// quotaUsageAlertSamples samples. Resources without a limit are never
+// warned about.
+func checkQuotaUsageAlerts(st *state.State, grp *quota.Group, history *quotaUsageHistory, threshold int) {
+	sample := history.last()
+	count, percentage := grp.GetLocalCPUQuota()
+	for _, res := range []struct {
+		name         string
+		usage, limit uint64
+	}{
+		{"memory", uint64(sample.Memory), uint64(grp.MemoryLimit)},
+		{"cpu", uint64(sample.CPU), uint64(count * percentage)},
+		{"threads", uint64(sample.Threads), uint64(grp.ThreadLimit)},
+	} {
+		if res.limit == 0 || res.usage*100 <= res.limit*uint64(threshold) {
+			history.above[res.name] = 0
+			continue
+		}
+		history.above[res.name]++
+		// warn only once each time the usage goes above the threshold
+		if history.above[res.name] == quotaUsageAlertSamples {
+			st.Warnf("%s usage of quota group %q has stayed above %d%% of its limit for %s",
+				res.name, grp.Name, threshold, quotaUsageAlertSamples*quotaUsageSampleInterval)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate_test
+
+import (
+	"fmt"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/servicestate/servicestatetest"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/systemd"
+)
+
+type quotaUsageSuite struct {
+	baseServiceMgrTestSuite
+
+	now time.Time
+}
+
+var _ = Suite(&quotaUsageSuite{})
+
+func (s *quotaUsageSuite) SetUpTest(c *C) {
+	s.baseServiceMgrTestSuite.SetUpTest(c)
+
+	servicestate.MockEnsuredSnapServices(s.mgr, true)
+
+	s.now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.AddCleanup(servicestate.MockTimeNow(func() time.Time { return s.now }))
+}
+
+func (s *quotaUsageSuite) mockGroups(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	_, err := servicestatetest.PatchQuotas(s.state,
+		&quota.Group{
+			Name:        "foo",
+			MemoryLimit: quantity.SizeGiB,
+			ThreadLimit: 100,
+		},
+		&quota.Group{
+			Name:     "bar",
+			CPULimit: &quota.GroupQuotaCPU{Count: 1, Percentage: 50},
+		},
+	)
+	c.Assert(err, IsNil)
+}
+
+func (s *quotaUsageSuite) TestEnsureSamplesUsage(c *C) {
+	s.mockGroups(c)
+
+	var cpuTime time.Duration
+	restore := servicestate.MockQuotaGroupUsage(func(grp *quota.Group) (quantity.Size, time.Duration, int, error) {
+		switch grp.Name {
+		case "foo":
+			return 100 * quantity.SizeMiB, 0, 10, nil
+		case "bar":
+			cpuTime += 30 * time.Second
+			return 0, cpuTime, 0, nil
+		}
+		c.Fatalf("unexpected group %q", grp.Name)
+		return 0, 0, 0, nil
+	})
+	defer restore()
+
+	start := s.now
+	for i := 0; i < 3; i++ {
+		c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+		// not sampled again before the interval elapsed
+		s.now = s.now.Add(30 * time.Second)
+		c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+		s.now = s.now.Add(30 * time.Second)
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(servicestate.QuotaUsage(s.state, "foo", time.Time{}), DeepEquals, []servicestate.QuotaUsageSample{
+		{Time: start, Memory: 100 * quantity.SizeMiB, Threads: 10},
+		{Time: start.Add(time.Minute), Memory: 100 * quantity.SizeMiB, Threads: 10},
+		{Time: start.Add(2 * time.Minute), Memory: 100 * quantity.SizeMiB, Threads: 10},
+	})
+	// the cpu usage is computed from the previous sample
+	c.Check(servicestate.QuotaUsage(s.state, "bar", start), DeepEquals, []servicestate.QuotaUsageSample{
+		{Time: start.Add(time.Minute), CPU: 50},
+		{Time: start.Add(2 * time.Minute), CPU: 50},
+	})
+	c.Check(servicestate.QuotaUsage(s.state, "unknown", time.Time{}), HasLen, 0)
+}
+
+func (s *quotaUsageSuite) TestEnsureSamplesUsageWithoutLimits(c *C) {
+	s.state.Lock()
+	_, err := servicestatetest.PatchQuotas(s.state, &quota.Group{
+		Name:         "foo",
+		JournalLimit: &quota.GroupQuotaJournal{Size: quantity.SizeMiB},
+	})
+	c.Assert(err, IsNil)
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "quota.usage-alert-threshold", 80)
+	tr.Commit()
+	s.state.Unlock()
+
+	cpuTime := 0
+	restore := systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		switch {
+		case args[0] == "is-active":
+			c.Check(args, DeepEquals, []string{"is-active", "snap.foo.slice"})
+			return []byte("active"), nil
+		case args[0] == "show" && args[2] == "MemoryCurrent":
+			return []byte("MemoryCurrent=4096"), nil
+		case args[0] == "show" && args[2] == "CPUUsageNSec":
+			cpuTime += 60 * int(time.Second)
+			return []byte(fmt.Sprintf("CPUUsageNSec=%d", cpuTime)), nil
+		case args[0] == "show" && args[2] == "TasksCurrent":
+			return []byte("TasksCurrent=10"), nil
+		}
+		c.Fatalf("unexpected systemctl call %v", args)
+		return nil, nil
+	})
+	defer restore()
+
+	start := s.now
+	for i := 0; i < 6; i++ {
+		c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+		s.now = s.now.Add(time.Minute)
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	// all resources are sampled
+	samples := servicestate.QuotaUsage(s.state, "foo", start)
+	c.Assert(samples, HasLen, 5)
+	c.Check(samples[0], DeepEquals, servicestate.QuotaUsageSample{
+		Time:    start.Add(time.Minute),
+		Memory:  4 * quantity.SizeKiB,
+		CPU:     100,
+		Threads: 10,
+	})
+	// but without limits there is nothing to warn about
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+}
+
+func (s *quotaUsageSuite) TestEnsureNoGroups(c *C) {
+	restore := servicestate.MockQuotaGroupUsage(func(grp *quota.Group) (quantity.Size, time.Duration, int, error) {
+		c.Fatalf("unexpected call")
+		return 0, 0, 0, nil
+	})
+	defer restore()
+
+	c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+}
+
+func (s *quotaUsageSuite) TestEnsureNotSeeded(c *C) {
+	s.mockGroups(c)
+	s.state.Lock()
+	s.state.Set("seeded", false)
+	s.state.Unlock()
+
+	restore := servicestate.MockQuotaGroupUsage(func(grp *quota.Group) (quantity.Size, time.Duration, int, error) {
+		c.Fatalf("unexpected call")
+		return 0, 0, 0, nil
+	})
+	defer restore()
+
+	c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+}
+
+func (s *quotaUsageSuite) TestHistoryIsBounded(c *C) {
+	s.mockGroups(c)
+
+	restore := servicestate.MockQuotaGroupUsage(func(grp *quota.Group) (quantity.Size, time.Duration, int, error) {
+		return quantity.SizeMiB, 0, 1, nil
+	})
+	defer restore()
+
+	start := s.now
+	for i := 0; i < servicestate.QuotaUsageHistorySize+10; i++ {
+		c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+		s.now = s.now.Add(time.Minute)
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	samples := servicestate.QuotaUsage(s.state, "foo", time.Time{})
+	c.Assert(samples, HasLen, servicestate.QuotaUsageHistorySize)
+	// the oldest samples were dropped
+	c.Check(samples[0].Time, Equals, start.Add(10*time.Minute))
+	c.Check(samples[len(samples)-1].Time, Equals, s.now.Add(-time.Minute))
+}
+
+func (s *quotaUsageSuite) TestUsageAlert(c *C) {
+	s.mockGroups(c)
+	s.state.Lock()
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "quota.usage-alert-threshold", 80)
+	tr.Commit()
+	s.state.Unlock()
+
+	memory := 900 * quantity.SizeMiB
+	restore := servicestate.MockQuotaGroupUsage(func(grp *quota.Group) (quantity.Size, time.Duration, int, error) {
+		if grp.Name == "foo" {
+			return memory, 0, 10, nil
+		}
+		return 0, 0, 0, nil
+	})
+	defer restore()
+
+	sample := func(n int) {
+		for i := 0; i < n; i++ {
+			c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+			s.now = s.now.Add(time.Minute)
+		}
+	}
+
+	// not yet above the threshold for long enough
+	sample(4)
+	s.state.Lock()
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+	s.state.Unlock()
+
+	sample(1)
+	s.state.Lock()
+	warnings := s.state.AllWarnings()
+	c.Assert(warnings, HasLen, 1)
+	c.Check(warnings[0].String(), Equals, `memory usage of quota group "foo" has stayed above 80% of its limit for 5m0s`)
+	s.state.Unlock()
+
+	// the usage going below the threshold resets the count
+	memory = 100 * quantity.SizeMiB
+	sample(1)
+	memory = 900 * quantity.SizeMiB
+	sample(4)
+	s.state.Lock()
+	c.Check(s.state.AllWarnings(), HasLen, 1)
+	s.state.Unlock()
+}
@@ -46,6 +46,8 @@ import (
 
 func init() {
 	swfeats.RegisterEnsure("ServiceManager", "ensureSnapServicesUpdated")
+	swfeats.RegisterEnsure("ServiceManager", "ensureQuotaUsageSampled")
+	swfeats.RegisterEnsure("ServiceManager", "ensureQuotaOOMEventsHandled")
 }
 
 // ServiceManager is responsible for starting and stopping snap services.
@@ -53,6 +55,11 @@ type ServiceManager struct {
 	state *state.State
 
 	ensuredSnapSvcs bool
+	// nextUsageSample is when the usage of quota groups is sampled next
+	nextUsageSample time.Time
+	// nextOOMCheck is when quota groups are checked next for processes
+	// killed for running out of memory
+	nextOOMCheck time.Time
 }
 
 // Manager returns a new service manager.
@@ -211,6 +218,12 @@ func (m *ServiceManager) Ensure() error {
 	if err := m.ensureSnapServicesUpdated(); err != nil {
 		return err
 	}
+	if err := m.ensureQuotaUsageSampled(); err != nil {
+		return err
+	}
+	if err := m.ensureQuotaOOMEventsHandled(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -23,6 +23,7 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto"
 	"encoding/json"
@@ -198,12 +199,14 @@ func Iter(ctx context.Context, f func(*Reader) error) error {
 	return err
 }
 
-// List valid snapshots sets.
+// List valid snapshots sets. The files kept in the chunk store are left out
+// of the listed snapshots.
 func List(ctx context.Context, setID uint64, snapNames []string) ([]client.SnapshotSet, error) {
 	setshots := map[uint64][]*client.Snapshot{}
 	err := Iter(ctx, func(reader *Reader) error {
 		if setID == 0 || reader.SetID == setID {
 			if len(snapNames) == 0 || strutil.ListContains(snapNames, reader.Snap) {
+				reader.Files = nil
 				setshots[reader.SetID] = append(setshots[reader.SetID], &reader.Snapshot)
 			}
 		}
@@ -335,6 +338,10 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]any, use
 		}
 	}
 
+	// keep the chunks added by this snapshot until it is committed
+	chunksLock.RLock()
+	defer chunksLock.RUnlock()
+
 	aw, err := osutil.NewAtomicFile(Filename(snapshot), 0600, 0, osutil.NoChown, osutil.NoChown)
 	if err != nil {
 		return nil, err
@@ -363,22 +370,9 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]any, use
 		}
 	}
 
-	metaWriter, err := w.Create(metadataName)
-	if err != nil {
-		return nil, err
-	}
-
-	hasher := crypto.SHA3_384.New()
-	enc := json.NewEncoder(io.MultiWriter(metaWriter, hasher))
-	if err := enc.Encode(snapshot); err != nil {
+	if err := addMetadataToZip(snapshot, w); err != nil {
 		return nil, err
 	}
-
-	hashWriter, err := w.Create(metaHashName)
-	if err != nil {
-		return nil, err
-	}
-	fmt.Fprintf(hashWriter, "%x\n", hasher.Sum(nil))
 	if err := w.Close(); err != nil {
 		return nil, err
 	}
@@ -394,6 +388,27 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]any, use
 	return snapshot, nil
 }
 
+// addMetadataToZip adds the snapshot metadata and its hash to the snapshot.
+func addMetadataToZip(snapshot *client.Snapshot, w *zip.Writer) error {
+	metaWriter, err := w.Create(metadataName)
+	if err != nil {
+		return err
+	}
+
+	hasher := crypto.SHA3_384.New()
+	enc := json.NewEncoder(io.MultiWriter(metaWriter, hasher))
+	if err := enc.Encode(snapshot); err != nil {
+		return err
+	}
+
+	hashWriter, err := w.Create(metaHashName)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(hashWriter, "%x\n", hasher.Sum(nil))
+	return err
+}
+
 var isTesting = snapdenv.Testing()
 
 // addSnapDirToZip adds the 'common' and the 'rev' revisioned dir under 'snapDir'
@@ -447,6 +462,10 @@ func addSnapDirToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writ
 
 // addToZip adds 'paths' to the snapshot. tar will change into the paths' parent
 // directory before creating the archive so that parent dirs are not added.
+//
+// The archive created by tar is deduplicated against the chunk store before
+// being added, see dedupArchive. As the content of files is rewritten, sparse
+// files are not preserved as such.
 func addToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, username, entry string, paths []string, excludePaths []string) error {
 	archiveWriter, err := w.CreateHeader(&zip.FileHeader{Name: entry})
 	if err != nil {
@@ -455,7 +474,6 @@ func addToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, use
 
 	tarArgs := []string{
 		"--create",
-		"--sparse", "--gzip",
 		"--format", "gnu",
 		"--anchored",
 		"--no-wildcards-match-slash",
@@ -474,8 +492,22 @@ func addToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, use
 	var sz osutil.Sizer
 	hasher := crypto.SHA3_384.New()
 
+	pr, pw := io.Pipe()
+	dedupDone := make(chan error, 1)
+	go func() {
+		err := dedupArchive(io.MultiWriter(archiveWriter, hasher, &sz), pr, snapshot, entry)
+		if err == nil {
+			// consume the padding tar adds after the end of the archive
+			_, err = io.Copy(io.Discard, pr)
+		}
+		// make tar fail rather than block if the archive could not be
+		// processed
+		pr.CloseWithError(err)
+		dedupDone <- err
+	}()
+
 	cmd := tarAsUser(username, tarArgs...)
-	cmd.Stdout = io.MultiWriter(archiveWriter, hasher, &sz)
+	cmd.Stdout = pw
 
 	// keep (at most) the last 5 non-empty lines of what 'tar' writes to stderr
 	// (those are the most likely contain the reason for fatal errors)
@@ -490,7 +522,14 @@ func addToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, use
 		cmd.Stderr = io.MultiWriter(os.Stderr, matchCounter)
 	}
 
-	if err := osutil.RunWithContext(ctx, cmd); err != nil {
+	err = osutil.RunWithContext(ctx, cmd)
+	pw.Close()
+	dedupErr := <-dedupDone
+	if dedupErr != nil && !errors.Is(dedupErr, io.ErrUnexpectedEOF) {
+		// tar failing is a consequence of this
+		return fmt.Errorf("cannot deduplicate archive: %v", dedupErr)
+	}
+	if err != nil {
 		matches, count := matchCounter.Matches()
 		if count > 0 {
 			note := ""
@@ -503,6 +542,9 @@ func addToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, use
 		}
 		return fmt.Errorf("tar failed: %v", err)
 	}
+	if dedupErr != nil {
+		return fmt.Errorf("cannot deduplicate archive: %v", dedupErr)
+	}
 
 	snapshot.SHA3_384[entry] = fmt.Sprintf("%x", hasher.Sum(nil))
 	snapshot.Size += sz.Size()
@@ -731,6 +773,9 @@ type ImportFlags struct {
 	// noDuplicatedImportCheck tells import not to check for existing snapshot
 	// with same content hash (and not report DuplicatedSnapshotImportError).
 	NoDuplicatedImportCheck bool
+	// Key is used to decrypt encrypted snapshots; without it they are
+	// imported as they are, once their stored data is checked.
+	Key *client.SnapshotKey
 }
 
 // Import a snapshot from the export file format
@@ -899,7 +944,27 @@ func unpackVerifySnapshotImport(ctx context.Context, r io.Reader, realSetID uint
 		if err != nil {
 			return snapNames, fmt.Errorf("cannot open snapshot: %v", err)
 		}
-		err = r.Check(context.TODO(), nil)
+		switch {
+		case len(r.Files) > 0:
+			// exported snapshots are always expanded, and must not
+			// refer to chunks of this system
+			err = errors.New("unexpected deduplicated snapshot")
+		case r.Encryption != nil && flags.Key != nil:
+			err = r.SetKey(flags.Key)
+			if err == nil {
+				err = r.Check(context.TODO(), nil)
+			}
+			if err == nil {
+				err = decryptSnapshotFile(targetPath, r)
+			}
+		default:
+			err = r.Check(context.TODO(), nil)
+			if r.Encryption != nil && errors.Is(err, client.ErrSnapshotKeyRequired) {
+				// kept encrypted, which is fine as what is
+				// stored was checked
+				err = nil
+			}
+		}
 		r.Close()
 		snapNames = append(snapNames, r.Snap)
 		if err != nil {
@@ -915,6 +980,22 @@ func unpackVerifySnapshotImport(ctx context.Context, r io.Reader, realSetID uint
 	return snapNames, nil
 }
 
+// decryptSnapshotFile replaces the snapshot file at the given path, opened
+// by the given reader with its key set, with its decrypted version.
+func decryptSnapshotFile(targetPath string, r *Reader) error {
+	aw, err := osutil.NewAtomicFile(targetPath, 0600, 0, osutil.NoChown, osutil.NoChown)
+	if err != nil {
+		return err
+	}
+	// if things worked, we'll commit (and Cancel becomes a NOP)
+	defer aw.Cancel()
+
+	if err := writeSnapshot(aw, r.File, &r.Snapshot, r.key, nil, nil); err != nil {
+		return fmt.Errorf("cannot decrypt snapshot: %v", err)
+	}
+	return aw.Commit()
+}
+
 type exportMetadata struct {
 	Format int       `json:"format"`
 	Date   time.Time `json:"date"`
@@ -924,6 +1005,12 @@ type exportMetadata struct {
 type SnapshotExport struct {
 	// open snapshot files
 	snapshotFiles []*os.File
+	// the snapshots of the files, indexed like snapshotFiles
+	snapshots []*client.Snapshot
+	// the key to encrypt the snapshots with, if any
+	key *client.SnapshotKey
+	// whether the files were prepared for export, see prepare
+	prepared bool
 
 	// contentHash of the full snapshot
 	contentHash []byte
@@ -939,6 +1026,7 @@ type SnapshotExport struct {
 // Close()ed after use to avoid leaking file descriptors.
 func NewSnapshotExport(ctx context.Context, setID uint64) (se *SnapshotExport, err error) {
 	var snapshotFiles []*os.File
+	var snapshots []*client.Snapshot
 	var snapshotSet client.SnapshotSet
 
 	defer func() {
@@ -972,6 +1060,9 @@ func NewSnapshotExport(ctx context.Context, setID uint64) (se *SnapshotExport, e
 				return fmt.Errorf("cannot open file from descriptor %d", fd)
 			}
 			snapshotFiles = append(snapshotFiles, f)
+
+			sh := reader.Snapshot
+			snapshots = append(snapshots, &sh)
 		}
 		return nil
 	})
@@ -986,7 +1077,7 @@ func NewSnapshotExport(ctx context.Context, setID uint64) (se *SnapshotExport, e
 	if err != nil {
 		return nil, fmt.Errorf("cannot calculate content hash for snapshot export %v: %v", setID, err)
 	}
-	se = &SnapshotExport{snapshotFiles: snapshotFiles, setID: setID, contentHash: h}
+	se = &SnapshotExport{snapshotFiles: snapshotFiles, snapshots: snapshots, setID: setID, contentHash: h}
 
 	// ensure we never leak FDs even if the user does not call close
 	runtime.SetFinalizer(se, (*SnapshotExport).Close)
@@ -994,6 +1085,21 @@ func NewSnapshotExport(ctx context.Context, setID uint64) (se *SnapshotExport, e
 	return se, nil
 }
 
+// Encrypt makes the export encrypt the snapshots with the given key. It must
+// be called before Init.
+func (se *SnapshotExport) Encrypt(key *client.SnapshotKey) error {
+	if err := validateSnapshotKey(key); err != nil {
+		return err
+	}
+	for i, snapshot := range se.snapshots {
+		if snapshot.Encryption != nil {
+			return fmt.Errorf("cannot encrypt %v: snapshot is encrypted already", path.Base(se.snapshotFiles[i].Name()))
+		}
+	}
+	se.key = key
+	return nil
+}
+
 // Init will calculate the snapshot size. This can take some time
 // so it should be called without any locks. The SnapshotExport
 // keeps the FDs open so even files moved/deleted will be found.
@@ -1029,7 +1135,161 @@ type contentJSON struct {
 	ContentHash []byte `json:"content-hash"`
 }
 
+// prepare replaces the snapshot files which are deduplicated, or which need
+// encrypting, with unlinked temporary files holding the full, and possibly
+// encrypted, snapshots, so that the export does not depend on the chunk store
+// of this system.
+func (se *SnapshotExport) prepare() error {
+	if se.prepared {
+		return nil
+	}
+
+	what := "expand"
+	var enc *client.SnapshotEncryption
+	var encKey []byte
+	if se.key != nil {
+		// all snapshots of the set share the same key, which is
+		// derived only once as it is expensive with passphrases
+		var err error
+		enc, encKey, err = newSnapshotEncryption(se.key)
+		if err != nil {
+			return fmt.Errorf("cannot encrypt snapshot %v: %v", se.setID, err)
+		}
+		what = "encrypt"
+	}
+
+	chunksLock.RLock()
+	defer chunksLock.RUnlock()
+
+	for i, snapshot := range se.snapshots {
+		if len(snapshot.Files) == 0 && enc == nil {
+			continue
+		}
+		f, err := rewrittenSnapshotFile(se.snapshotFiles[i], snapshot, enc, encKey)
+		if err != nil {
+			return fmt.Errorf("cannot %s %v: %v", what, se.snapshotFiles[i].Name(), err)
+		}
+		se.snapshotFiles[i].Close()
+		se.snapshotFiles[i] = f
+	}
+	se.prepared = true
+	return nil
+}
+
+// rewrittenSnapshotFile returns a file holding the given snapshot with its
+// archives expanded and, if an encryption is given, encrypted. The file is
+// already unlinked, and has the name of the given snapshot file.
+func rewrittenSnapshotFile(snapshotFile *os.File, snapshot *client.Snapshot, enc *client.SnapshotEncryption, encKey []byte) (f *os.File, e error) {
+	tmp, err := os.CreateTemp(dirs.SnapshotsDir, ".export-")
+	if err != nil {
+		return nil, err
+	}
+	defer tmp.Close()
+	if err := os.Remove(tmp.Name()); err != nil {
+		return nil, err
+	}
+
+	if err := writeSnapshot(tmp, snapshotFile, snapshot, nil, enc, encKey); err != nil {
+		return nil, err
+	}
+
+	fd, err := syscall.Dup(int(tmp.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("cannot duplicate descriptor: %v", err)
+	}
+	f = os.NewFile(uintptr(fd), snapshotFile.Name())
+	if f == nil {
+		return nil, fmt.Errorf("cannot open file from descriptor %d", fd)
+	}
+	return f, nil
+}
+
+// writeSnapshot writes the given snapshot to w, with the content of its
+// files read back from the chunk store. Its archives are decrypted with
+// decKey if it is given, and encrypted with encKey, as described by enc,
+// if that is given.
+func writeSnapshot(w io.Writer, snapshotFile *os.File, snapshot *client.Snapshot, decKey []byte, enc *client.SnapshotEncryption, encKey []byte) error {
+	full := *snapshot
+	full.Files = nil
+	full.Dedup = nil
+	full.Encryption = enc
+	full.SHA3_384 = make(map[string]string, len(snapshot.SHA3_384))
+	full.Size = 0
+
+	entries := make([]string, 0, len(snapshot.SHA3_384))
+	for entry := range snapshot.SHA3_384 {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+	for _, entry := range entries {
+		if err := writeSnapshotEntry(zw, snapshotFile, snapshot, &full, entry, decKey, encKey); err != nil {
+			return err
+		}
+	}
+	if err := addMetadataToZip(&full, zw); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeSnapshotEntry(zw *zip.Writer, snapshotFile *os.File, snapshot, full *client.Snapshot, entry string, decKey, encKey []byte) error {
+	body, _, err := zipMember(snapshotFile, entry)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	var in io.Reader = body
+	if decKey != nil {
+		in, err = newDecryptReader(body, decKey, entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	archiveWriter, err := zw.CreateHeader(&zip.FileHeader{Name: entry})
+	if err != nil {
+		return err
+	}
+	var sz osutil.Sizer
+	hasher := crypto.SHA3_384.New()
+	out := io.MultiWriter(archiveWriter, hasher, &sz)
+	var ew *encryptWriter
+	if encKey != nil {
+		ew, err = newEncryptWriter(out, encKey, entry)
+		if err != nil {
+			return err
+		}
+		out = ew
+	}
+
+	if files := snapshot.Files[entry]; len(files) > 0 {
+		gz := gzip.NewWriter(out)
+		err = expandArchive(gz, in, files)
+		if err == nil {
+			err = gz.Close()
+		}
+	} else {
+		_, err = io.Copy(out, in)
+	}
+	if err == nil && ew != nil {
+		err = ew.Close()
+	}
+	if err != nil {
+		return err
+	}
+	full.SHA3_384[entry] = fmt.Sprintf("%x", hasher.Sum(nil))
+	full.Size += sz.Size()
+	return nil
+}
+
 func (se *SnapshotExport) StreamTo(w io.Writer) error {
+	if err := se.prepare(); err != nil {
+		return err
+	}
+
 	// write out a tar
 	var files []string
 	tw := tar.NewWriter(w)
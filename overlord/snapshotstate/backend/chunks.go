@@ -0,0 +1,390 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// Snapshots are deduplicated by storing the content of the bigger files of
+// the archives in a content-addressed chunk store, shared by all snapshots,
+// instead of in the archives themselves. Within the archive such a file is
+// kept as an empty file, and the hash and size of its content are recorded
+// in the snapshot metadata, keyed by archive and by the path of the file in
+// the archive. Archives are expanded back into full archives when restoring
+// or exporting the snapshot.
+//
+// Chunks are stored gzip compressed, in files named after the SHA3-384 of
+// their content.
+
+var (
+	// dedupMinFileSize is the size from which the content of a file is
+	// kept in the chunk store rather than in the archive.
+	dedupMinFileSize int64 = 64 * 1024
+
+	// chunksLock is held for reading while chunks are added or are
+	// needed for reading, and for writing while unused chunks are
+	// removed.
+	chunksLock sync.RWMutex
+
+	validChunkSum = regexp.MustCompile("^[0-9a-f]{96}$")
+)
+
+func chunksDir() string {
+	return filepath.Join(dirs.SnapshotsDir, "chunks")
+}
+
+func chunkPath(sum string) string {
+	return filepath.Join(chunksDir(), sum[:2], sum)
+}
+
+// storeChunk adds the content read from r to the chunk store, unless a chunk
+// with the same content is there already.
+func storeChunk(r io.Reader) (file client.SnapshotFile, reused bool, err error) {
+	if err := os.MkdirAll(chunksDir(), 0700); err != nil {
+		return file, false, err
+	}
+	tmp, err := os.CreateTemp(chunksDir(), ".chunk-")
+	if err != nil {
+		return file, false, err
+	}
+	// this is a no-op once the chunk has been renamed into place
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := crypto.SHA3_384.New()
+	var sz osutil.Sizer
+	gz := gzip.NewWriter(tmp)
+	if _, err := io.Copy(gz, io.TeeReader(r, io.MultiWriter(hasher, &sz))); err != nil {
+		return file, false, err
+	}
+	if err := gz.Close(); err != nil {
+		return file, false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return file, false, err
+	}
+
+	file = client.SnapshotFile{
+		SHA3_384: fmt.Sprintf("%x", hasher.Sum(nil)),
+		Size:     sz.Size(),
+	}
+	target := chunkPath(file.SHA3_384)
+	if osutil.FileExists(target) {
+		return file, true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return file, false, err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return file, false, err
+	}
+	return file, false, nil
+}
+
+// chunkReader reads the content of a chunk, verifying it against the
+// expected hash and size once the end of it is reached.
+type chunkReader struct {
+	f      *os.File
+	gz     *gzip.Reader
+	hasher hash.Hash
+	file   client.SnapshotFile
+	read   int64
+}
+
+// openChunk opens the chunk holding the content of the given file.
+func openChunk(file client.SnapshotFile) (io.ReadCloser, error) {
+	if !validChunkSum.MatchString(file.SHA3_384) {
+		return nil, fmt.Errorf("invalid chunk hash %q", file.SHA3_384)
+	}
+	f, err := os.Open(chunkPath(file.SHA3_384))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("chunk %.7s… is missing", file.SHA3_384)
+		}
+		return nil, err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("cannot read chunk %.7s…: %v", file.SHA3_384, err)
+	}
+	return &chunkReader{
+		f:      f,
+		gz:     gz,
+		hasher: crypto.SHA3_384.New(),
+		file:   file,
+	}, nil
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	n, err := cr.gz.Read(p)
+	cr.hasher.Write(p[:n])
+	cr.read += int64(n)
+	if err == io.EOF {
+		if cr.read != cr.file.Size {
+			return n, fmt.Errorf("chunk %.7s… size (%d) does not match expected (%d)", cr.file.SHA3_384, cr.read, cr.file.Size)
+		}
+		if actualHash := fmt.Sprintf("%x", cr.hasher.Sum(nil)); actualHash != cr.file.SHA3_384 {
+			return n, fmt.Errorf("chunk %.7s… does not match its hash (%.7s…)", cr.file.SHA3_384, actualHash)
+		}
+	} else if err != nil {
+		err = fmt.Errorf("cannot read chunk %.7s…: %v", cr.file.SHA3_384, err)
+	}
+	return n, err
+}
+
+func (cr *chunkReader) Close() error {
+	return cr.f.Close()
+}
+
+// dedupArchive reads the uncompressed tar archive for the given snapshot entry
+// from r, and writes it to w as a gzip compressed archive with the content of
+// the files of at least dedupMinFileSize moved to the chunk store. The files
+// and the deduplication statistics are recorded in the snapshot.
+func dedupArchive(w io.Writer, r io.Reader, snapshot *client.Snapshot, entry string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeReg && hdr.Size >= dedupMinFileSize {
+			file, reused, err := storeChunk(tr)
+			if err != nil {
+				return fmt.Errorf("cannot store %q in the chunk store: %v", hdr.Name, err)
+			}
+			if snapshot.Files == nil {
+				snapshot.Files = make(map[string]map[string]client.SnapshotFile)
+			}
+			if snapshot.Files[entry] == nil {
+				snapshot.Files[entry] = make(map[string]client.SnapshotFile)
+			}
+			snapshot.Files[entry][hdr.Name] = file
+			if snapshot.Dedup == nil {
+				snapshot.Dedup = &client.SnapshotDedup{}
+			}
+			if reused {
+				snapshot.Dedup.Reused += file.Size
+			} else {
+				snapshot.Dedup.Stored += file.Size
+			}
+			snapshot.Size += file.Size
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if hdr.Size > 0 {
+			if _, err := io.Copy(tw, tr); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// expandArchive reads the gzip compressed, deduplicated archive from r and
+// writes it to w as an uncompressed tar archive with the content of the given
+// files read back from the chunk store. All of r is consumed.
+func expandArchive(w io.Writer, r io.Reader, files map[string]client.SnapshotFile) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		file, ok := files[hdr.Name]
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				return err
+			}
+			continue
+		}
+		chunk, err := openChunk(file)
+		if err != nil {
+			return fmt.Errorf("cannot expand %q: %v", hdr.Name, err)
+		}
+		hdr.Size = file.Size
+		if err := tw.WriteHeader(hdr); err != nil {
+			chunk.Close()
+			return err
+		}
+		_, err = io.Copy(tw, chunk)
+		chunk.Close()
+		if err != nil {
+			return fmt.Errorf("cannot expand %q: %w", hdr.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	// read the gzip trailer and anything past it so that the whole of the
+	// entry is accounted for
+	if _, err := io.Copy(io.Discard, gz); err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, r)
+	return err
+}
+
+// checkChunks verifies the content of the chunks used by the given files.
+func checkChunks(ctx context.Context, files map[string]client.SnapshotFile, checked map[string]bool) error {
+	for name, file := range files {
+		if checked[file.SHA3_384] {
+			continue
+		}
+		chunk, err := openChunk(file)
+		if err != nil {
+			return fmt.Errorf("cannot check %q: %v", name, err)
+		}
+		_, err = io.Copy(osutil.ContextWriter(ctx), chunk)
+		chunk.Close()
+		if err != nil {
+			return fmt.Errorf("cannot check %q: %v", name, err)
+		}
+		checked[file.SHA3_384] = true
+	}
+	return nil
+}
+
+// errImportInProgress is returned by usedChunks when the chunks used by
+// snapshots being imported cannot be known yet.
+var errImportInProgress = errors.New("snapshot import in progress")
+
+// usedChunks returns the chunks used by the snapshots on disk. Unlike Iter it
+// does not skip anything: it fails if any snapshot cannot be read or is
+// broken, as the chunks it uses would not be known.
+func usedChunks(ctx context.Context) (map[string]bool, error) {
+	inProgress, err := filepathGlob(filepath.Join(dirs.SnapshotsDir, importingFnGlob))
+	if err != nil {
+		return nil, err
+	}
+	if len(inProgress) > 0 {
+		return nil, errImportInProgress
+	}
+
+	entries, err := os.ReadDir(dirs.SnapshotsDir)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ok, setID := isSnapshotFilename(entry.Name())
+		if !ok {
+			continue
+		}
+		reader, err := backendOpen(filepath.Join(dirs.SnapshotsDir, entry.Name()), setID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open snapshot %q: %v", entry.Name(), err)
+		}
+		for _, files := range reader.Files {
+			for _, file := range files {
+				used[file.SHA3_384] = true
+			}
+		}
+		if err := reader.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return used, nil
+}
+
+// CleanupUnusedChunks removes the chunks not used by any snapshot from the
+// chunk store, as well as any leftovers of interrupted saves. If snapshots are
+// being saved, exported or imported, nothing is done and the chunks are
+// removed on a later call. If any snapshot cannot be read nothing is removed
+// either, as its chunks might still be needed.
+func CleanupUnusedChunks(ctx context.Context) (removed int, err error) {
+	if !chunksLock.TryLock() {
+		return 0, nil
+	}
+	defer chunksLock.Unlock()
+
+	if !osutil.IsDirectory(chunksDir()) {
+		return 0, nil
+	}
+
+	used, err := usedChunks(ctx)
+	if err == errImportInProgress {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cannot determine used chunks: %v", err)
+	}
+
+	err = filepath.WalkDir(chunksDir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || used[d.Name()] {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		logger.Debugf("Removed unused snapshot chunk %q.", d.Name())
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("cannot remove unused chunks: %v", err)
+	}
+	return removed, nil
+}
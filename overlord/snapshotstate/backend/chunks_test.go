@@ -0,0 +1,317 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil/user"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+var dedupInfo = &snap.Info{SideInfo: snap.SideInfo{RealName: "hello-snap", Revision: snap.R(42), SnapID: "hello-id"}, Version: "v1.33"}
+
+// setUpDedup adds files big enough to be deduplicated to the system data of
+// the snap, and makes snapshots save only system data, so that tar doesn't
+// need to run as another user.
+func (s *snapshotSuite) setUpDedup(c *check.C) {
+	s.restore = append(s.restore,
+		backend.MockDedupMinFileSize(1024),
+		backend.MockUsersForUsernames(func(usernames []string, opts *dirs.SnapDirOptions) ([]*user.User, error) {
+			return nil, nil
+		}),
+	)
+	logger.SimpleSetup(nil)
+
+	c.Assert(os.WriteFile(filepath.Join(dedupInfo.DataDir(), "big"), bytes.Repeat([]byte("versioned "), 200), 0644), check.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dedupInfo.CommonDataDir(), "big"), bytes.Repeat([]byte("common "), 200), 0644), check.IsNil)
+}
+
+func sortedChunks(files map[string]client.SnapshotFile) []string {
+	var chunks []string
+	for _, file := range files {
+		chunks = append(chunks, file.SHA3_384)
+	}
+	sort.Strings(chunks)
+	return chunks
+}
+
+func chunkFiles(c *check.C) []string {
+	var chunks []string
+	err := filepath.Walk(filepath.Join(dirs.SnapshotsDir, "chunks"), func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			chunks = append(chunks, fi.Name())
+		}
+		return nil
+	})
+	c.Assert(err, check.IsNil)
+	sort.Strings(chunks)
+	return chunks
+}
+
+func (s *snapshotSuite) TestDedupSave(c *check.C) {
+	s.setUpDedup(c)
+
+	sh1, err := backend.Save(context.TODO(), 1, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(hashkeys(sh1), check.DeepEquals, []string{"archive.tgz"})
+	c.Assert(sh1.Files, check.HasLen, 1)
+	files := sh1.Files["archive.tgz"]
+	c.Assert(files, check.HasLen, 2)
+	c.Check(files["42/big"].Size, check.Equals, int64(2000))
+	c.Check(files["42/big"].SHA3_384, check.HasLen, 96)
+	c.Check(files["common/big"].Size, check.Equals, int64(1400))
+	// the small files are kept in the archive
+	c.Check(files["42/foo"], check.Equals, client.SnapshotFile{})
+	c.Check(sh1.Dedup, check.DeepEquals, &client.SnapshotDedup{Stored: 3400})
+	c.Check(sh1.Size > 3400, check.Equals, true)
+	c.Check(chunkFiles(c), check.DeepEquals, sortedChunks(files))
+
+	// nothing changed, everything is reused
+	sh2, err := backend.Save(context.TODO(), 2, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(sh2.Files, check.DeepEquals, sh1.Files)
+	c.Check(sh2.Dedup, check.DeepEquals, &client.SnapshotDedup{Reused: 3400})
+	c.Check(chunkFiles(c), check.HasLen, 2)
+
+	// only the changed file is stored
+	c.Assert(os.WriteFile(filepath.Join(dedupInfo.DataDir(), "big"), bytes.Repeat([]byte("changed "), 200), 0644), check.IsNil)
+	sh3, err := backend.Save(context.TODO(), 3, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(sh3.Dedup, check.DeepEquals, &client.SnapshotDedup{Stored: 1600, Reused: 1400})
+	c.Check(chunkFiles(c), check.HasLen, 3)
+
+	// the metadata has the files, listing leaves them out
+	r, err := backend.Open(backend.Filename(sh3), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer r.Close()
+	c.Check(r.Files, check.DeepEquals, sh3.Files)
+	c.Check(r.Dedup, check.DeepEquals, sh3.Dedup)
+	c.Check(r.Check(context.TODO(), nil), check.IsNil)
+
+	sets, err := backend.List(context.TODO(), 3, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(sets, check.HasLen, 1)
+	c.Assert(sets[0].Snapshots, check.HasLen, 1)
+	c.Check(sets[0].Snapshots[0].Files, check.IsNil)
+	c.Check(sets[0].Snapshots[0].Dedup, check.DeepEquals, sh3.Dedup)
+}
+
+func (s *snapshotSuite) TestDedupRestore(c *check.C) {
+	s.setUpDedup(c)
+
+	shw, err := backend.Save(context.TODO(), 1, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	c.Assert(os.WriteFile(filepath.Join(dedupInfo.DataDir(), "big"), []byte("scribble\n"), 0644), check.IsNil)
+	c.Assert(os.RemoveAll(dedupInfo.CommonDataDir()), check.IsNil)
+
+	r, err := backend.Open(backend.Filename(shw), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer r.Close()
+	rs, err := r.Restore(context.TODO(), snap.R(0), nil, logger.Debugf, nil)
+	c.Assert(err, check.IsNil)
+	rs.Cleanup()
+
+	c.Check(filepath.Join(dedupInfo.DataDir(), "big"), testutil.FileEquals, strings.Repeat("versioned ", 200))
+	c.Check(filepath.Join(dedupInfo.DataDir(), "foo"), testutil.FileEquals, "versioned system canary\n")
+	c.Check(filepath.Join(dedupInfo.CommonDataDir(), "big"), testutil.FileEquals, strings.Repeat("common ", 200))
+	c.Check(filepath.Join(dedupInfo.CommonDataDir(), "bar"), testutil.FileEquals, "common system canary\n")
+}
+
+func (s *snapshotSuite) TestDedupBrokenChunk(c *check.C) {
+	s.setUpDedup(c)
+
+	shw, err := backend.Save(context.TODO(), 1, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	chunk := backend.ChunkPath(shw.Files["archive.tgz"]["42/big"].SHA3_384)
+
+	r, err := backend.Open(backend.Filename(shw), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer r.Close()
+
+	// a chunk with the wrong content
+	common, err := os.ReadFile(backend.ChunkPath(shw.Files["archive.tgz"]["common/big"].SHA3_384))
+	c.Assert(err, check.IsNil)
+	c.Assert(os.WriteFile(chunk, common, 0600), check.IsNil)
+	c.Check(r.Check(context.TODO(), nil), check.ErrorMatches, `snapshot entry "archive.tgz": cannot check "42/big": chunk .* size \(1400\) does not match expected \(2000\)`)
+	_, err = r.Restore(context.TODO(), snap.R(0), nil, logger.Debugf, nil)
+	c.Check(err, check.ErrorMatches, `cannot expand snapshot .* entry "archive.tgz": cannot expand "42/big": chunk .* size \(1400\) does not match expected \(2000\)`)
+	// the data was left alone
+	c.Check(filepath.Join(dedupInfo.DataDir(), "big"), testutil.FileEquals, strings.Repeat("versioned ", 200))
+
+	// a missing chunk
+	c.Assert(os.Remove(chunk), check.IsNil)
+	c.Check(r.Check(context.TODO(), nil), check.ErrorMatches, `snapshot entry "archive.tgz": cannot check "42/big": chunk .* is missing`)
+}
+
+func (s *snapshotSuite) TestCleanupUnusedChunks(c *check.C) {
+	s.setUpDedup(c)
+
+	// no chunk store yet
+	removed, err := backend.CleanupUnusedChunks(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Check(removed, check.Equals, 0)
+
+	sh1, err := backend.Save(context.TODO(), 1, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dedupInfo.DataDir(), "big"), bytes.Repeat([]byte("changed "), 200), 0644), check.IsNil)
+	sh2, err := backend.Save(context.TODO(), 2, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	// leftover of an interrupted save
+	c.Assert(os.WriteFile(filepath.Join(dirs.SnapshotsDir, "chunks", ".chunk-1234"), nil, 0600), check.IsNil)
+
+	removed, err = backend.CleanupUnusedChunks(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Check(removed, check.Equals, 1)
+	c.Check(chunkFiles(c), check.HasLen, 3)
+
+	c.Assert(os.Remove(backend.Filename(sh1)), check.IsNil)
+	removed, err = backend.CleanupUnusedChunks(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Check(removed, check.Equals, 1)
+	c.Check(chunkFiles(c), check.DeepEquals, sortedChunks(sh2.Files["archive.tgz"]))
+
+	r, err := backend.Open(backend.Filename(sh2), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer r.Close()
+	c.Check(r.Check(context.TODO(), nil), check.IsNil)
+}
+
+func (s *snapshotSuite) TestCleanupUnusedChunksUnreadableSnapshot(c *check.C) {
+	s.setUpDedup(c)
+
+	sh1, err := backend.Save(context.TODO(), 1, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dedupInfo.DataDir(), "big"), bytes.Repeat([]byte("changed "), 200), 0644), check.IsNil)
+	sh2, err := backend.Save(context.TODO(), 2, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(chunkFiles(c), check.HasLen, 3)
+
+	// the chunks of the first snapshot cannot be known anymore
+	c.Assert(os.WriteFile(backend.Filename(sh1), []byte("garbage"), 0600), check.IsNil)
+	// and those only used by the second one are unused
+	c.Assert(os.Remove(backend.Filename(sh2)), check.IsNil)
+
+	removed, err := backend.CleanupUnusedChunks(context.TODO())
+	c.Assert(err, check.ErrorMatches, `cannot determine used chunks: cannot open snapshot "1_hello-snap_v1.33_42.zip": .*`)
+	c.Check(removed, check.Equals, 0)
+	c.Check(chunkFiles(c), check.HasLen, 3)
+}
+
+func (s *snapshotSuite) TestCleanupUnusedChunksImportInProgress(c *check.C) {
+	s.setUpDedup(c)
+
+	sh1, err := backend.Save(context.TODO(), 1, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(os.Remove(backend.Filename(sh1)), check.IsNil)
+
+	// the chunks used by the snapshots being imported are not known yet
+	c.Assert(os.WriteFile(filepath.Join(dirs.SnapshotsDir, "2_importing"), nil, 0600), check.IsNil)
+	removed, err := backend.CleanupUnusedChunks(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Check(removed, check.Equals, 0)
+	c.Check(chunkFiles(c), check.HasLen, 2)
+
+	c.Assert(os.Remove(filepath.Join(dirs.SnapshotsDir, "2_importing")), check.IsNil)
+	removed, err = backend.CleanupUnusedChunks(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Check(removed, check.Equals, 2)
+	c.Check(chunkFiles(c), check.HasLen, 0)
+}
+
+func (s *snapshotSuite) TestDedupExportImportRoundtrip(c *check.C) {
+	s.setUpDedup(c)
+	ctx := context.TODO()
+
+	shw, err := backend.Save(ctx, 12, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(shw.Files, check.HasLen, 1)
+
+	export, err := backend.NewSnapshotExport(ctx, shw.SetID)
+	c.Assert(err, check.IsNil)
+	defer export.Close()
+	c.Assert(export.Init(), check.IsNil)
+	buf := bytes.NewBuffer(nil)
+	c.Assert(export.StreamTo(buf), check.IsNil)
+	c.Check(buf.Len(), check.Equals, int(export.Size()))
+
+	// the export is self-contained
+	c.Assert(os.RemoveAll(filepath.Join(dirs.SnapshotsDir, "chunks")), check.IsNil)
+	c.Assert(os.Remove(backend.Filename(shw)), check.IsNil)
+
+	names, err := backend.Import(ctx, 123, buf, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(names, check.DeepEquals, []string{"hello-snap"})
+
+	r, err := backend.Open(filepath.Join(dirs.SnapshotsDir, "123_hello-snap_v1.33_42.zip"), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer r.Close()
+	c.Check(r.Files, check.IsNil)
+	c.Check(r.Dedup, check.IsNil)
+	c.Check(r.Check(ctx, nil), check.IsNil)
+
+	c.Assert(os.RemoveAll(dedupInfo.DataDir()), check.IsNil)
+	rs, err := r.Restore(ctx, snap.R(0), nil, logger.Debugf, nil)
+	c.Assert(err, check.IsNil)
+	rs.Cleanup()
+	c.Check(filepath.Join(dedupInfo.DataDir(), "big"), testutil.FileEquals, strings.Repeat("versioned ", 200))
+}
+
+func (s *snapshotSuite) TestImportDeduplicatedFails(c *check.C) {
+	s.setUpDedup(c)
+	ctx := context.TODO()
+
+	shw, err := backend.Save(ctx, 12, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	data, err := os.ReadFile(backend.Filename(shw))
+	c.Assert(err, check.IsNil)
+
+	// an export with the deduplicated snapshot as is
+	buf := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(buf)
+	c.Assert(tw.WriteHeader(&tar.Header{Name: filepath.Base(backend.Filename(shw)), Mode: 0600, Size: int64(len(data))}), check.IsNil)
+	_, err = tw.Write(data)
+	c.Assert(err, check.IsNil)
+	c.Assert(tw.WriteHeader(&tar.Header{Name: "export.json", Mode: 0600, Size: 2}), check.IsNil)
+	_, err = tw.Write([]byte("{}"))
+	c.Assert(err, check.IsNil)
+	c.Assert(tw.Close(), check.IsNil)
+
+	_, err = backend.Import(ctx, 123, buf, nil)
+	c.Check(err, check.ErrorMatches, `cannot import snapshot 123: validation failed for ".*/123_hello-snap_v1.33_42.zip": unexpected deduplicated snapshot`)
+	c.Check(filepath.Join(dirs.SnapshotsDir, "123_hello-snap_v1.33_42.zip"), testutil.FileAbsent)
+}
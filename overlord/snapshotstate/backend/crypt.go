@@ -0,0 +1,341 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/randutil"
+)
+
+// The archives of encrypted snapshots are encrypted as a whole, the zip file
+// and the snapshot metadata are not, so that encrypted snapshots can be
+// listed and their stored data checked without the key. The hashes and sizes
+// in the metadata are those of the encrypted archives.
+//
+// An archive is encrypted in segments of cryptSegmentSize bytes with
+// AES-256-GCM, following the STREAM construction: the nonce of each segment
+// is made of a random prefix, written before the first segment, a counter
+// and a flag set only for the last segment, which detects reordered,
+// dropped and truncated segments. The name of the archive is authenticated
+// with every segment so that archives cannot be swapped.
+
+const (
+	snapshotCipher = "aes-256-gcm-stream"
+
+	kdfArgon2id   = "argon2id"
+	kdfHKDFSHA256 = "hkdf-sha256"
+
+	cryptSegmentSize     = 64 * 1024
+	cryptNoncePrefixSize = 7
+	cryptSaltSize        = 32
+
+	minKeyFileSize = 16
+	// the most memory (in KiB) argon2id is allowed to use when deriving
+	// the key of a snapshot, as the parameters come with the snapshot
+	maxKDFMemory = 1024 * 1024
+	maxKDFTime   = 16
+)
+
+var (
+	// argon2Params are the argon2id parameters used for new encrypted
+	// snapshots, those recommended by RFC 9106 for memory constrained
+	// environments.
+	argon2Params = struct {
+		Time    uint32
+		Memory  uint32
+		Threads uint8
+	}{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+	errWrongSnapshotKey = errors.New("key does not match the one the snapshot was encrypted with")
+	errSnapshotDecrypt  = errors.New("cannot decrypt snapshot data: message authentication failed")
+
+	cryptoTokenBytes = randutil.CryptoTokenBytes
+)
+
+// validateSnapshotKey checks that the given key can be used to encrypt
+// snapshots.
+func validateSnapshotKey(key *client.SnapshotKey) error {
+	switch {
+	case key.Passphrase != "" && len(key.KeyFile) > 0:
+		return errors.New("snapshot key cannot be both a passphrase and a key file")
+	case key.Passphrase != "":
+		return nil
+	case len(key.KeyFile) > 0:
+		if len(key.KeyFile) < minKeyFileSize {
+			return fmt.Errorf("snapshot key file is too short (need at least %d bytes)", minKeyFileSize)
+		}
+		return nil
+	default:
+		return errors.New("snapshot key is empty")
+	}
+}
+
+// newSnapshotEncryption returns the metadata and the encryption key for
+// encrypting snapshots with the given key.
+func newSnapshotEncryption(key *client.SnapshotKey) (*client.SnapshotEncryption, []byte, error) {
+	if err := validateSnapshotKey(key); err != nil {
+		return nil, nil, err
+	}
+	salt, err := cryptoTokenBytes(cryptSaltSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc := &client.SnapshotEncryption{
+		Cipher: snapshotCipher,
+		Salt:   salt,
+	}
+	if key.Passphrase != "" {
+		enc.KDF = kdfArgon2id
+		enc.KDFTime = argon2Params.Time
+		enc.KDFMemory = argon2Params.Memory
+		enc.KDFThreads = argon2Params.Threads
+	} else {
+		enc.KDF = kdfHKDFSHA256
+	}
+	encKey, keyCheck, err := deriveSnapshotKeys(key, enc)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc.KeyCheck = keyCheck
+	return enc, encKey, nil
+}
+
+// snapshotKeyFor returns the encryption key of the snapshot with the given
+// encryption metadata, derived from the given key.
+func snapshotKeyFor(key *client.SnapshotKey, enc *client.SnapshotEncryption) ([]byte, error) {
+	if enc.Cipher != snapshotCipher {
+		return nil, fmt.Errorf("unsupported snapshot cipher %q", enc.Cipher)
+	}
+	encKey, keyCheck, err := deriveSnapshotKeys(key, enc)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(keyCheck, enc.KeyCheck) != 1 {
+		return nil, errWrongSnapshotKey
+	}
+	return encKey, nil
+}
+
+func deriveSnapshotKeys(key *client.SnapshotKey, enc *client.SnapshotEncryption) (encKey, keyCheck []byte, err error) {
+	var secret []byte
+	switch enc.KDF {
+	case kdfArgon2id:
+		if key.Passphrase == "" {
+			return nil, nil, errors.New("snapshot is encrypted with a passphrase, not a key file")
+		}
+		if enc.KDFTime == 0 || enc.KDFTime > maxKDFTime || enc.KDFMemory == 0 || enc.KDFMemory > maxKDFMemory || enc.KDFThreads == 0 {
+			return nil, nil, errors.New("invalid snapshot key derivation parameters")
+		}
+		secret = argon2.IDKey([]byte(key.Passphrase), enc.Salt, enc.KDFTime, enc.KDFMemory, enc.KDFThreads, 32)
+	case kdfHKDFSHA256:
+		if len(key.KeyFile) == 0 {
+			return nil, nil, errors.New("snapshot is encrypted with a key file, not a passphrase")
+		}
+		secret = key.KeyFile
+	default:
+		return nil, nil, fmt.Errorf("unsupported snapshot key derivation function %q", enc.KDF)
+	}
+	keys := make([]byte, 64)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, enc.Salt, []byte("snapd snapshot encryption")), keys); err != nil {
+		return nil, nil, err
+	}
+	return keys[:32], keys[32:], nil
+}
+
+func newSnapshotAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func setSegmentNonce(nonce []byte, counter uint32, last bool) {
+	binary.BigEndian.PutUint32(nonce[cryptNoncePrefixSize:], counter)
+	nonce[len(nonce)-1] = 0
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+}
+
+// encryptWriter encrypts what is written to it into the underlying writer.
+// It must be closed to write out the last segment.
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	nonce   []byte
+	entry   []byte
+	buf     []byte
+	out     []byte
+	counter uint32
+}
+
+func newEncryptWriter(w io.Writer, key []byte, entry string) (*encryptWriter, error) {
+	aead, err := newSnapshotAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := cryptoTokenBytes(cryptNoncePrefixSize)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, prefix)
+	return &encryptWriter{
+		w:     w,
+		aead:  aead,
+		nonce: nonce,
+		entry: []byte(entry),
+		buf:   make([]byte, 0, cryptSegmentSize),
+	}, nil
+}
+
+func (ew *encryptWriter) flush(last bool) error {
+	if ew.counter == ^uint32(0) {
+		return errors.New("too much data to encrypt")
+	}
+	setSegmentNonce(ew.nonce, ew.counter, last)
+	ew.out = ew.aead.Seal(ew.out[:0], ew.nonce, ew.buf, ew.entry)
+	if _, err := ew.w.Write(ew.out); err != nil {
+		return err
+	}
+	ew.counter++
+	ew.buf = ew.buf[:0]
+	return nil
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// a full segment is only written out once it is known not to
+		// be the last one
+		if len(ew.buf) == cryptSegmentSize {
+			if err := ew.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(ew.buf[len(ew.buf):cap(ew.buf)], p)
+		ew.buf = ew.buf[:len(ew.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close writes out the last segment, it does not close the underlying
+// writer.
+func (ew *encryptWriter) Close() error {
+	return ew.flush(true)
+}
+
+// decryptReader decrypts what is read from the underlying reader, failing
+// if any of it does not authenticate, including if it is truncated.
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	nonce   []byte
+	entry   []byte
+	buf     []byte
+	plain   []byte
+	counter uint32
+	started bool
+	done    bool
+	err     error
+}
+
+func newDecryptReader(r io.Reader, key []byte, entry string) (*decryptReader, error) {
+	aead, err := newSnapshotAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		r:     bufio.NewReader(r),
+		aead:  aead,
+		nonce: make([]byte, aead.NonceSize()),
+		entry: []byte(entry),
+		buf:   make([]byte, cryptSegmentSize+aead.Overhead()),
+	}, nil
+}
+
+func (dr *decryptReader) next() error {
+	if !dr.started {
+		if _, err := io.ReadFull(dr.r, dr.nonce[:cryptNoncePrefixSize]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return errSnapshotDecrypt
+			}
+			return err
+		}
+		dr.started = true
+	}
+	n, err := io.ReadFull(dr.r, dr.buf)
+	last := false
+	switch err {
+	case nil:
+		if _, err := dr.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+	setSegmentNonce(dr.nonce, dr.counter, last)
+	plain, err := dr.aead.Open(dr.buf[:0], dr.nonce, dr.buf[:n], dr.entry)
+	if err != nil {
+		return errSnapshotDecrypt
+	}
+	dr.counter++
+	dr.plain = plain
+	dr.done = last
+	return nil
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.plain) == 0 {
+		if dr.err != nil {
+			return 0, dr.err
+		}
+		if dr.done {
+			return 0, io.EOF
+		}
+		dr.err = dr.next()
+	}
+	n := copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	return n, nil
+}
@@ -0,0 +1,207 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *snapshotSuite) TestEncryptDecryptRoundtrip(c *check.C) {
+	key := bytes.Repeat([]byte{42}, 32)
+	seg := backend.CryptSegmentSize
+	for _, size := range []int{0, 1, seg - 1, seg, seg + 1, 3 * seg} {
+		comm := check.Commentf("size %d", size)
+		data := bytes.Repeat([]byte{'x'}, size)
+		encrypted, err := backend.EncryptData(key, "archive.tgz", data)
+		c.Assert(err, check.IsNil, comm)
+		c.Check(bytes.Contains(encrypted, []byte("xxxx")), check.Equals, false, comm)
+
+		decrypted, err := backend.DecryptData(key, "archive.tgz", encrypted)
+		c.Assert(err, check.IsNil, comm)
+		c.Check(decrypted, check.DeepEquals, data, comm)
+	}
+}
+
+func (s *snapshotSuite) TestDecryptFailsOnTampering(c *check.C) {
+	key := bytes.Repeat([]byte{42}, 32)
+	seg := backend.CryptSegmentSize
+	encrypted, err := backend.EncryptData(key, "archive.tgz", bytes.Repeat([]byte{'x'}, 2*seg+10))
+	c.Assert(err, check.IsNil)
+
+	flipped := append([]byte(nil), encrypted...)
+	flipped[len(flipped)/2] ^= 1
+	// the segments are 16 bytes longer than the data, and follow the
+	// 7 bytes of the nonce prefix
+	truncated := encrypted[:7+2*(seg+16)]
+
+	for _, tc := range []struct {
+		data  []byte
+		entry string
+		key   []byte
+	}{
+		{data: flipped, entry: "archive.tgz", key: key},
+		{data: truncated, entry: "archive.tgz", key: key},
+		{data: encrypted[:3], entry: "archive.tgz", key: key},
+		{data: encrypted, entry: "user/foo.tgz", key: key},
+		{data: encrypted, entry: "archive.tgz", key: bytes.Repeat([]byte{1}, 32)},
+	} {
+		_, err := backend.DecryptData(tc.key, tc.entry, tc.data)
+		c.Check(err, check.ErrorMatches, "cannot decrypt snapshot data: message authentication failed")
+	}
+}
+
+// exportEncrypted saves a snapshot of the snap, and returns an export of
+// it encrypted with the given key.
+func (s *snapshotSuite) exportEncrypted(c *check.C, key *client.SnapshotKey) *bytes.Buffer {
+	s.restore = append(s.restore, backend.MockArgon2Params(1, 64, 1))
+	s.setUpDedup(c)
+	ctx := context.TODO()
+
+	shw, err := backend.Save(ctx, 12, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	export, err := backend.NewSnapshotExport(ctx, shw.SetID)
+	c.Assert(err, check.IsNil)
+	defer export.Close()
+	c.Assert(export.Encrypt(key), check.IsNil)
+	c.Assert(export.Init(), check.IsNil)
+	buf := bytes.NewBuffer(nil)
+	c.Assert(export.StreamTo(buf), check.IsNil)
+	c.Check(buf.Len(), check.Equals, int(export.Size()))
+	c.Check(bytes.Contains(buf.Bytes(), []byte("versioned versioned")), check.Equals, false)
+
+	c.Assert(os.WriteFile(filepath.Join(dedupInfo.DataDir(), "big"), []byte("scribble\n"), 0644), check.IsNil)
+	c.Assert(os.Remove(backend.Filename(shw)), check.IsNil)
+	return buf
+}
+
+func (s *snapshotSuite) TestEncryptedExportImportKeepsEncryption(c *check.C) {
+	key := &client.SnapshotKey{Passphrase: "sekrit"}
+	buf := s.exportEncrypted(c, key)
+	ctx := context.TODO()
+
+	names, err := backend.Import(ctx, 123, buf, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(names, check.DeepEquals, []string{"hello-snap"})
+
+	r, err := backend.Open(filepath.Join(dirs.SnapshotsDir, "123_hello-snap_v1.33_42.zip"), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer r.Close()
+	c.Assert(r.Encryption, check.NotNil)
+	c.Check(r.Encryption.Cipher, check.Equals, "aes-256-gcm-stream")
+	c.Check(r.Encryption.KDF, check.Equals, "argon2id")
+	c.Check(r.Encryption.Salt, check.HasLen, 32)
+	c.Check(r.Files, check.IsNil)
+	c.Check(r.Dedup, check.IsNil)
+
+	// the stored data is checked, the content cannot be
+	err = r.Check(ctx, nil)
+	c.Check(err, check.ErrorMatches, "stored data is intact but its content cannot be verified: snapshot is encrypted and no key was given")
+	_, err = r.Restore(ctx, snap.R(0), nil, logger.Debugf, nil)
+	c.Check(err, check.Equals, client.ErrSnapshotKeyRequired)
+
+	c.Check(r.SetKey(&client.SnapshotKey{Passphrase: "wrong"}), check.ErrorMatches, "key does not match the one the snapshot was encrypted with")
+	c.Check(r.SetKey(&client.SnapshotKey{KeyFile: []byte("0123456789abcdef")}), check.ErrorMatches, "snapshot is encrypted with a passphrase, not a key file")
+
+	c.Assert(r.SetKey(key), check.IsNil)
+	c.Check(r.Check(ctx, nil), check.IsNil)
+	rs, err := r.Restore(ctx, snap.R(0), nil, logger.Debugf, nil)
+	c.Assert(err, check.IsNil)
+	rs.Cleanup()
+	c.Check(filepath.Join(dedupInfo.DataDir(), "big"), testutil.FileEquals, strings.Repeat("versioned ", 200))
+}
+
+func (s *snapshotSuite) TestEncryptedExportImportWithKey(c *check.C) {
+	key := &client.SnapshotKey{KeyFile: []byte("0123456789abcdef")}
+	buf := s.exportEncrypted(c, key)
+	ctx := context.TODO()
+
+	names, err := backend.Import(ctx, 123, buf, &backend.ImportFlags{Key: key})
+	c.Assert(err, check.IsNil)
+	c.Check(names, check.DeepEquals, []string{"hello-snap"})
+
+	r, err := backend.Open(filepath.Join(dirs.SnapshotsDir, "123_hello-snap_v1.33_42.zip"), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer r.Close()
+	c.Check(r.Encryption, check.IsNil)
+	c.Check(r.Check(ctx, nil), check.IsNil)
+
+	rs, err := r.Restore(ctx, snap.R(0), nil, logger.Debugf, nil)
+	c.Assert(err, check.IsNil)
+	rs.Cleanup()
+	c.Check(filepath.Join(dedupInfo.DataDir(), "big"), testutil.FileEquals, strings.Repeat("versioned ", 200))
+}
+
+func (s *snapshotSuite) TestEncryptedImportWrongKey(c *check.C) {
+	buf := s.exportEncrypted(c, &client.SnapshotKey{Passphrase: "sekrit"})
+
+	_, err := backend.Import(context.TODO(), 123, buf, &backend.ImportFlags{Key: &client.SnapshotKey{Passphrase: "wrong"}})
+	c.Check(err, check.ErrorMatches, `cannot import snapshot 123: validation failed for ".*/123_hello-snap_v1.33_42.zip": key does not match the one the snapshot was encrypted with`)
+	c.Check(filepath.Join(dirs.SnapshotsDir, "123_hello-snap_v1.33_42.zip"), testutil.FileAbsent)
+}
+
+func (s *snapshotSuite) TestExportEncryptInvalidKey(c *check.C) {
+	s.setUpDedup(c)
+	ctx := context.TODO()
+
+	shw, err := backend.Save(ctx, 12, dedupInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	export, err := backend.NewSnapshotExport(ctx, shw.SetID)
+	c.Assert(err, check.IsNil)
+	defer export.Close()
+
+	for _, tc := range []struct {
+		key *client.SnapshotKey
+		err string
+	}{
+		{&client.SnapshotKey{}, "snapshot key is empty"},
+		{&client.SnapshotKey{KeyFile: []byte("short")}, `snapshot key file is too short \(need at least 16 bytes\)`},
+		{&client.SnapshotKey{Passphrase: "x", KeyFile: []byte("0123456789abcdef")}, "snapshot key cannot be both a passphrase and a key file"},
+	} {
+		c.Check(export.Encrypt(tc.key), check.ErrorMatches, tc.err)
+	}
+}
+
+func (s *snapshotSuite) TestExportEncryptedAlready(c *check.C) {
+	buf := s.exportEncrypted(c, &client.SnapshotKey{Passphrase: "sekrit"})
+	ctx := context.TODO()
+	_, err := backend.Import(ctx, 123, buf, nil)
+	c.Assert(err, check.IsNil)
+
+	// exporting as is works
+	export, err := backend.NewSnapshotExport(ctx, 123)
+	c.Assert(err, check.IsNil)
+	defer export.Close()
+	c.Check(export.Encrypt(&client.SnapshotKey{Passphrase: "other"}), check.ErrorMatches, `cannot encrypt 123_hello-snap_v1.33_42.zip: snapshot is encrypted already`)
+	c.Assert(export.Init(), check.IsNil)
+}
@@ -20,6 +20,8 @@
 package backend
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"os/exec"
 	"time"
@@ -121,6 +123,14 @@ func MockTimeNow(f func() time.Time) (restore func()) {
 	}
 }
 
+func MockS3PartSize(size int64) (restore func()) {
+	old := s3PartSize
+	s3PartSize = size
+	return func() {
+		s3PartSize = old
+	}
+}
+
 func MockSnapshot(setID uint64, snapName string, revision snap.Revision, size int64, shaSums map[string]string) *client.Snapshot {
 	return &client.Snapshot{
 		SetID:    setID,
@@ -155,3 +165,53 @@ func MockReadSnapshotYaml(f func(si *snap.Info) (*snap.SnapshotOptions, error))
 		snapReadSnapshotYaml = oldReadSnapshotYaml
 	}
 }
+
+func MockDedupMinFileSize(size int64) (restore func()) {
+	r := testutil.Backup(&dedupMinFileSize)
+	dedupMinFileSize = size
+	return r
+}
+
+func ChunkPath(sum string) string {
+	return chunkPath(sum)
+}
+
+func MockArgon2Params(time, memory uint32, threads uint8) (restore func()) {
+	r := testutil.Backup(&argon2Params)
+	argon2Params.Time = time
+	argon2Params.Memory = memory
+	argon2Params.Threads = threads
+	return r
+}
+
+const CryptSegmentSize = cryptSegmentSize
+
+func EncryptData(key []byte, entry string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	ew, err := newEncryptWriter(&buf, key, entry)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ew.Write(data); err != nil {
+		return nil, err
+	}
+	if err := ew.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func DecryptData(key []byte, entry string, data []byte) ([]byte, error) {
+	dr, err := newDecryptReader(bytes.NewReader(data), key, entry)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(dr)
+}
+
+// S3StoreLocation returns the configuration, bucket and prefix of the given
+// S3 snapshot store.
+func S3StoreLocation(st SnapshotStore) (cfg S3Config, bucket, prefix string) {
+	s := st.(*s3Store)
+	return s.cfg, s.bucket, s.prefix
+}
@@ -50,6 +50,10 @@ const ExtractFnameSetID = 0
 type Reader struct {
 	*os.File
 	client.Snapshot
+
+	// the key the archives are encrypted with, for encrypted
+	// snapshots, see SetKey
+	key []byte
 }
 
 // Open a Snapshot given its full filename.
@@ -165,11 +169,54 @@ func (r *Reader) checkOne(ctx context.Context, entry string, hasher hash.Hash) e
 	return nil
 }
 
-// Check that the data contained in the snapshot matches its hashsums.
+// SetKey sets the key of an encrypted snapshot, needed to check its content
+// and to restore it. It fails if the key is not the one the snapshot was
+// encrypted with.
+func (r *Reader) SetKey(key *client.SnapshotKey) error {
+	if r.Encryption == nil {
+		return errors.New("snapshot is not encrypted")
+	}
+	encKey, err := snapshotKeyFor(key, r.Encryption)
+	if err != nil {
+		return err
+	}
+	r.key = encKey
+	return nil
+}
+
+// checkDecrypt checks that the given entry of an encrypted snapshot
+// decrypts with the key of the reader.
+func (r *Reader) checkDecrypt(ctx context.Context, entry string) error {
+	body, _, err := zipMember(r.File, entry)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	dr, err := newDecryptReader(body, r.key, entry)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(osutil.ContextWriter(ctx), dr); err != nil {
+		return fmt.Errorf("snapshot entry %q: %v", entry, err)
+	}
+	return nil
+}
+
+// Check that the data contained in the snapshot matches its hashsums,
+// including that of the chunks used by it.
+//
+// For encrypted snapshots, the content is also checked to decrypt if the
+// key was set; if it was not, client.ErrSnapshotKeyRequired is returned once
+// the stored data is found to match its hashsums.
 func (r *Reader) Check(ctx context.Context, usernames []string) error {
 	sort.Strings(usernames)
 
+	chunksLock.RLock()
+	defer chunksLock.RUnlock()
+
 	hasher := crypto.SHA3_384.New()
+	checked := make(map[string]bool)
 	for entry := range r.SHA3_384 {
 		if len(usernames) > 0 && isUserArchive(entry) {
 			username := entryUsername(entry)
@@ -183,6 +230,20 @@ func (r *Reader) Check(ctx context.Context, usernames []string) error {
 			return err
 		}
 		hasher.Reset()
+
+		if err := checkChunks(ctx, r.Files[entry], checked); err != nil {
+			return fmt.Errorf("snapshot entry %q: %v", entry, err)
+		}
+
+		if r.Encryption != nil && r.key != nil {
+			if err := r.checkDecrypt(ctx, entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.Encryption != nil && r.key == nil {
+		return fmt.Errorf("stored data is intact but its content cannot be verified: %w", client.ErrSnapshotKeyRequired)
 	}
 
 	return nil
@@ -196,7 +257,14 @@ type Logf func(format string, args ...any)
 // If successful this will replace the existing data (for the given revision,
 // or the one in the snapshot) with that contained in the snapshot. It keeps
 // track of the old data in the task so it can be undone (or cleaned up).
+//
+// Encrypted snapshots can only be restored once their key is set, see
+// SetKey.
 func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames []string, logf Logf, opts *dirs.SnapDirOptions) (rs *RestoreState, e error) {
+	if r.Encryption != nil && r.key == nil {
+		return nil, client.ErrSnapshotKeyRequired
+	}
+
 	rs = &RestoreState{}
 	defer func() {
 		if e != nil {
@@ -206,6 +274,9 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 		}
 	}()
 
+	chunksLock.RLock()
+	defer chunksLock.RUnlock()
+
 	sort.Strings(usernames)
 	isRoot := sys.Geteuid() == 0
 	si := snap.MinimalPlaceInfo(r.Snap, r.Revision)
@@ -317,15 +388,48 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 
 		expectedHash := r.SHA3_384[entry]
 
-		tr := io.TeeReader(body, io.MultiWriter(hasher, &sz))
+		var tr io.Reader = io.TeeReader(body, io.MultiWriter(hasher, &sz))
+
+		tarArgs := []string{
+			"--extract",
+			"--preserve-permissions", "--preserve-order",
+		}
+		var decrypted *decryptReader
+		if r.Encryption != nil {
+			decrypted, err = newDecryptReader(tr, r.key, entry)
+			if err != nil {
+				return rs, err
+			}
+			tr = decrypted
+		}
+		var expanded *io.PipeReader
+		var expandDone chan error
+		if files := r.Files[entry]; len(files) > 0 {
+			// tar is given the archive with the content of the files
+			// from the chunk store put back in
+			var pw *io.PipeWriter
+			expanded, pw = io.Pipe()
+			expandDone = make(chan error, 1)
+			go func(archive io.Reader) {
+				err := expandArchive(pw, archive, files)
+				if errors.Is(err, io.ErrClosedPipe) {
+					// tar is done reading, make sure the
+					// whole entry is still checked
+					_, err = io.Copy(io.Discard, archive)
+				}
+				pw.CloseWithError(err)
+				expandDone <- err
+			}(tr)
+			tr = expanded
+		} else {
+			tarArgs = append(tarArgs, "--gunzip")
+		}
+		tarArgs = append(tarArgs, "--directory", tempdir)
 
 		// resist the temptation of using archive/tar unless it's proven
 		// that calling out to tar has issues -- there are a lot of
 		// special cases we'd need to consider otherwise
-		cmd := tarAsUser(username,
-			"--extract",
-			"--preserve-permissions", "--preserve-order", "--gunzip",
-			"--directory", tempdir)
+		cmd := tarAsUser(username, tarArgs...)
 		cmd.Env = []string{}
 		cmd.Stdin = tr
 		matchCounter := &strutil.MatchCounter{N: 1}
@@ -336,7 +440,19 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 			cmd.Stderr = io.MultiWriter(os.Stderr, matchCounter)
 		}
 
-		if err = osutil.RunWithContext(ctx, cmd); err != nil {
+		err = osutil.RunWithContext(ctx, cmd)
+		if expanded != nil {
+			// unblock the expansion if tar stopped reading early
+			expanded.Close()
+			if expandErr := <-expandDone; expandErr != nil {
+				return rs, fmt.Errorf("cannot expand snapshot %q entry %q: %v", r.Name(), entry, expandErr)
+			}
+		}
+		if decrypted != nil && decrypted.err != nil {
+			// tar failing is a consequence of this
+			return rs, fmt.Errorf("cannot decrypt snapshot %q entry %q: %v", r.Name(), entry, decrypted.err)
+		}
+		if err != nil {
 			matches, count := matchCounter.Matches()
 			if count > 0 {
 				return rs, fmt.Errorf("cannot unpack archive: %s (and %d more)", matches[0], count-1)
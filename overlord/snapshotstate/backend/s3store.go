@@ -0,0 +1,334 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/httputil"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/snapdenv"
+)
+
+const (
+	s3Service       = "s3"
+	s3DefaultRegion = "us-east-1"
+	// the payload is streamed, so it is not part of the signature; it
+	// is still checked against its length, and the export against its
+	// own hashes when imported
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// S3 does not take more parts in a multipart upload
+	s3MaxParts = 10000
+)
+
+// s3PartSize is the size of the parts of the snapshot sets uploaded in
+// parts; a single upload is limited to 5GB, smaller ones go in one piece.
+var s3PartSize int64 = 64 * 1024 * 1024
+
+type s3Store struct {
+	cfg    S3Config
+	bucket string
+	prefix string
+	client *http.Client
+}
+
+// NewS3Store returns a SnapshotStore keeping the snapshot sets in the
+// given bucket of an S3-compatible service, with names prefixed by the
+// given prefix. Requests are signed with AWS signature version 4.
+func NewS3Store(cfg *S3Config, bucket, prefix string) SnapshotStore {
+	s := &s3Store{
+		cfg:    *cfg,
+		bucket: bucket,
+		prefix: prefix,
+		client: httputil.NewHTTPClient(&httputil.ClientOptions{
+			Proxy:              cfg.Proxy,
+			ProxyConnectHeader: http.Header{"User-Agent": []string{snapdenv.UserAgent()}},
+		}),
+	}
+	if s.cfg.Region == "" {
+		s.cfg.Region = s3DefaultRegion
+	}
+	return s
+}
+
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (s *s3Store) objectURL(name string) (*url.URL, error) {
+	u, err := url.Parse(s.cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %v", s.cfg.Endpoint, err)
+	}
+	key := name
+	if s.prefix != "" {
+		key = s.prefix + "/" + name
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	u.RawPath = ""
+	return u, nil
+}
+
+// do makes a request about the given object, what being the operation
+// for error messages.
+func (s *s3Store) do(ctx context.Context, what, method, name string, query url.Values, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	u, err := s.objectURL(name)
+	if err != nil {
+		return nil, err
+	}
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("cannot %s %q: %w", what, name, ErrNotInStore)
+	case http.StatusPreconditionFailed:
+		return nil, fmt.Errorf("cannot %s %q: %w", what, name, ErrExistsInStore)
+	}
+	var s3err s3Error
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&s3err); err != nil || s3err.Code == "" {
+		return nil, fmt.Errorf("cannot %s %q: %s", what, name, resp.Status)
+	}
+	return nil, fmt.Errorf("cannot %s %q: %s: %s", what, name, s3err.Code, s3err.Message)
+}
+
+func (s *s3Store) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	if size > s3PartSize {
+		return s.putMultipart(ctx, name, r, size)
+	}
+	header := http.Header{}
+	// only store if there is nothing stored under the name already
+	header.Set("If-None-Match", "*")
+	header.Set("Content-Type", "application/x.snapd.snapshot")
+	resp, err := s.do(ctx, "store", "PUT", name, nil, r, size, header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+type s3InitiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+// putMultipart stores the snapshot set as a multipart upload, which is
+// aborted if anything goes wrong so that no parts are left behind.
+func (s *s3Store) putMultipart(ctx context.Context, name string, r io.Reader, size int64) (err error) {
+	partSize := s3PartSize
+	if size > partSize*s3MaxParts {
+		partSize = (size + s3MaxParts - 1) / s3MaxParts
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/x.snapd.snapshot")
+	resp, err := s.do(ctx, "store", "POST", name, url.Values{"uploads": {""}}, nil, 0, header)
+	if err != nil {
+		return err
+	}
+	var initiated s3InitiateMultipartUploadResult
+	err = xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("cannot store %q: invalid response to starting the upload", name)
+	}
+	uploadID := initiated.UploadID
+	defer func() {
+		if err == nil {
+			return
+		}
+		// the upload context may be what went away
+		abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if resp, abortErr := s.do(abortCtx, "store", "DELETE", name, url.Values{"uploadId": {uploadID}}, nil, 0, nil); abortErr == nil {
+			resp.Body.Close()
+		} else {
+			logger.Noticef("cannot abort the upload of %q: %v", name, abortErr)
+		}
+	}()
+
+	var complete s3CompleteMultipartUpload
+	for left, number := size, 1; left > 0; number++ {
+		n := partSize
+		if left < n {
+			n = left
+		}
+		query := url.Values{
+			"partNumber": {strconv.Itoa(number)},
+			"uploadId":   {uploadID},
+		}
+		resp, err := s.do(ctx, "store", "PUT", name, query, io.LimitReader(r, n), n, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		complete.Parts = append(complete.Parts, s3CompletedPart{
+			PartNumber: number,
+			ETag:       resp.Header.Get("ETag"),
+		})
+		left -= n
+	}
+
+	body, err := xml.Marshal(&complete)
+	if err != nil {
+		return err
+	}
+	header = http.Header{}
+	// only store if there is nothing stored under the name already
+	header.Set("If-None-Match", "*")
+	resp, err = s.do(ctx, "store", "POST", name, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body), int64(len(body)), header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// completing can fail after the response has started, in which
+	// case the error is in its body
+	var s3err s3Error
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&s3err); err == nil && s3err.Code != "" {
+		return fmt.Errorf("cannot store %q: %s: %s", name, s3err.Code, s3err.Message)
+	}
+	return nil
+}
+
+func (s *s3Store) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, "get", "GET", name, nil, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *s3Store) Remove(ctx context.Context, name string) error {
+	// deleting a missing object is not an error for S3, check first
+	resp, err := s.do(ctx, "remove", "HEAD", name, nil, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	resp, err = s.do(ctx, "remove", "DELETE", name, nil, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sign adds the AWS signature version 4 of the request to it.
+func (s *s3Store) sign(req *http.Request) {
+	now := timeNow().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	signed := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": s3UnsignedPayload,
+		"x-amz-date":           amzDate,
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, signed[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := strings.Join([]string{date, s.cfg.Region, s3Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
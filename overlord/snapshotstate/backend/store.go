@@ -0,0 +1,190 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrNotInStore is returned by a SnapshotStore when what is asked
+	// for is not in it.
+	ErrNotInStore = errors.New("not found in snapshot store")
+	// ErrExistsInStore is returned by SnapshotStore.Put when something
+	// is stored under the name already.
+	ErrExistsInStore = errors.New("already exists in snapshot store")
+)
+
+// A SnapshotStore is somewhere snapshot sets are kept, as exported by
+// SnapshotExport, away from the snapshots directory.
+type SnapshotStore interface {
+	// Put stores size bytes read from r under the given name, failing
+	// with ErrExistsInStore if something is stored under it already.
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	// Get returns what is stored under the given name, failing with
+	// ErrNotInStore if nothing is.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// Remove removes what is stored under the given name, failing with
+	// ErrNotInStore if nothing is.
+	Remove(ctx context.Context, name string) error
+}
+
+// S3Config is the configuration of the S3-compatible service used for
+// "s3://" snapshot targets.
+type S3Config struct {
+	// the URL of the service, like https://s3.amazonaws.com; buckets
+	// are addressed in the path
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Proxy, if set, selects the proxy used for the requests to the
+	// service, as configured for snapd
+	Proxy func(*http.Request) (*url.URL, error)
+}
+
+// StoreName returns the name the given snapshot set is stored under in a
+// SnapshotStore.
+func StoreName(setID uint64) string {
+	return fmt.Sprintf("%d.snapshot", setID)
+}
+
+// OpenStore returns the SnapshotStore of the given target, which is either
+// the absolute path of a directory, that can be a network file system, or
+// an s3://<bucket>[/<prefix>] URL, in which case the given configuration
+// is used.
+func OpenStore(target string, s3cfg *S3Config) (SnapshotStore, error) {
+	if filepath.IsAbs(target) {
+		return NewDirStore(target), nil
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "s3" || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("invalid snapshot target %q: must be an absolute path or an s3://<bucket>[/<prefix>] URL", target)
+	}
+	if s3cfg == nil || s3cfg.Endpoint == "" {
+		return nil, fmt.Errorf("cannot use snapshot target %q: no S3 endpoint is configured", target)
+	}
+	return NewS3Store(s3cfg, u.Host, strings.Trim(u.Path, "/")), nil
+}
+
+type dirStore struct {
+	dir string
+}
+
+// NewDirStore returns a SnapshotStore keeping the snapshot sets in the
+// given directory.
+func NewDirStore(dir string) SnapshotStore {
+	return &dirStore{dir: dir}
+}
+
+func (ds *dirStore) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid snapshot store name %q", name)
+	}
+	return filepath.Join(ds.dir, name), nil
+}
+
+func (ds *dirStore) Put(ctx context.Context, name string, r io.Reader, size int64) (e error) {
+	target, err := ds.path(name)
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(ds.dir); err != nil || !fi.IsDir() {
+		return fmt.Errorf("cannot use %q as snapshot store: not a directory", ds.dir)
+	}
+	// the data is written out under a hidden name first, so that it is
+	// never seen partially written
+	f, err := os.CreateTemp(ds.dir, "."+name+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	n, err := io.Copy(f, &ctxReader{ctx: ctx, r: r})
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("cannot store %q: expected %d bytes, got %d", name, size, n)
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// linking, unlike renaming, does not replace what is there already
+	if err := os.Link(f.Name(), target); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("cannot store %q: %w", name, ErrExistsInStore)
+		}
+		return err
+	}
+	return nil
+}
+
+func (ds *dirStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	p, err := ds.path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot get %q: %w", name, ErrNotInStore)
+	}
+	return f, err
+}
+
+func (ds *dirStore) Remove(ctx context.Context, name string) error {
+	p, err := ds.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove %q: %w", name, ErrNotInStore)
+		}
+		return err
+	}
+	return nil
+}
+
+// ctxReader stops reading from r once the context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
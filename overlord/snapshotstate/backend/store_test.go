@@ -0,0 +1,404 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type storeSuite struct{}
+
+var _ = check.Suite(&storeSuite{})
+
+func (storeSuite) TestOpenStore(c *check.C) {
+	st, err := backend.OpenStore("/mnt/backups", nil)
+	c.Assert(err, check.IsNil)
+	c.Check(st, check.DeepEquals, backend.NewDirStore("/mnt/backups"))
+
+	cfg := &backend.S3Config{Endpoint: "https://s3.example.com"}
+	st, err = backend.OpenStore("s3://bucket/some/prefix/", cfg)
+	c.Assert(err, check.IsNil)
+	s3cfg, bucket, prefix := backend.S3StoreLocation(st)
+	c.Check(s3cfg, check.DeepEquals, backend.S3Config{Endpoint: "https://s3.example.com", Region: "us-east-1"})
+	c.Check(bucket, check.Equals, "bucket")
+	c.Check(prefix, check.Equals, "some/prefix")
+
+	for _, target := range []string{"", "relative/path", "s3://", "s3:///prefix", "http://bucket", "s3://user@bucket", "s3://bucket?x=1"} {
+		_, err := backend.OpenStore(target, cfg)
+		c.Check(err, check.ErrorMatches, `invalid snapshot target .*: must be an absolute path or an s3://<bucket>\[/<prefix>\] URL`, check.Commentf(target))
+	}
+
+	_, err = backend.OpenStore("s3://bucket", nil)
+	c.Check(err, check.ErrorMatches, `cannot use snapshot target "s3://bucket": no S3 endpoint is configured`)
+	_, err = backend.OpenStore("s3://bucket", &backend.S3Config{})
+	c.Check(err, check.ErrorMatches, `cannot use snapshot target "s3://bucket": no S3 endpoint is configured`)
+}
+
+// testStore exercises the SnapshotStore contract.
+func testStore(c *check.C, st backend.SnapshotStore) {
+	ctx := context.Background()
+
+	_, err := st.Get(ctx, "1.snapshot")
+	c.Check(errors.Is(err, backend.ErrNotInStore), check.Equals, true)
+	err = st.Remove(ctx, "1.snapshot")
+	c.Check(errors.Is(err, backend.ErrNotInStore), check.Equals, true)
+
+	c.Assert(st.Put(ctx, "1.snapshot", strings.NewReader("hello"), 5), check.IsNil)
+	err = st.Put(ctx, "1.snapshot", strings.NewReader("world"), 5)
+	c.Check(errors.Is(err, backend.ErrExistsInStore), check.Equals, true)
+
+	r, err := st.Get(ctx, "1.snapshot")
+	c.Assert(err, check.IsNil)
+	data, err := io.ReadAll(r)
+	r.Close()
+	c.Assert(err, check.IsNil)
+	c.Check(string(data), check.Equals, "hello")
+
+	c.Assert(st.Remove(ctx, "1.snapshot"), check.IsNil)
+	_, err = st.Get(ctx, "1.snapshot")
+	c.Check(errors.Is(err, backend.ErrNotInStore), check.Equals, true)
+}
+
+func (storeSuite) TestDirStore(c *check.C) {
+	dir := c.MkDir()
+	st := backend.NewDirStore(dir)
+	testStore(c, st)
+
+	ctx := context.Background()
+	err := st.Put(ctx, "2.snapshot", strings.NewReader("short"), 10)
+	c.Check(err, check.ErrorMatches, `cannot store "2.snapshot": expected 10 bytes, got 5`)
+	c.Check(filepath.Join(dir, "2.snapshot"), testutil.FileAbsent)
+
+	for _, name := range []string{"", "../1.snapshot", ".hidden", "a/b"} {
+		err := st.Put(ctx, name, strings.NewReader(""), 0)
+		c.Check(err, check.ErrorMatches, `invalid snapshot store name .*`)
+	}
+
+	// nothing is left behind
+	entries, err := os.ReadDir(dir)
+	c.Assert(err, check.IsNil)
+	c.Check(entries, check.HasLen, 0)
+
+	err = backend.NewDirStore(filepath.Join(dir, "missing")).Put(ctx, "1.snapshot", strings.NewReader(""), 0)
+	c.Check(err, check.ErrorMatches, `cannot use ".*/missing" as snapshot store: not a directory`)
+}
+
+func (storeSuite) TestDirStoreCancelled(c *check.C) {
+	dir := c.MkDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := backend.NewDirStore(dir).Put(ctx, "1.snapshot", strings.NewReader("hello"), 5)
+	c.Check(err, check.Equals, context.Canceled)
+	c.Check(filepath.Join(dir, "1.snapshot"), testutil.FileAbsent)
+}
+
+const (
+	s3TestAccessKey = "AKIDEXAMPLE"
+	s3TestSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// fakeS3 is a stand-in for an S3-compatible service, checking the
+// signature of the requests.
+type fakeS3 struct {
+	c       *check.C
+	region  string
+	mu      sync.Mutex
+	objects map[string][]byte
+	// uploads has the parts of the ongoing multipart uploads
+	uploads  map[string]map[int][]byte
+	uploaded int
+	// failPart makes uploading the part with that number fail
+	failPart int
+}
+
+func (f *fakeS3) checkSignature(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) < 8 || r.Header.Get("X-Amz-Content-Sha256") != "UNSIGNED-PAYLOAD" {
+		return false
+	}
+	scope := amzDate[:8] + "/" + f.region + "/s3/aws4_request"
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		"host:" + r.Host,
+		"x-amz-content-sha256:UNSIGNED-PAYLOAD",
+		"x-amz-date:" + amzDate,
+		"",
+		"host;x-amz-content-sha256;x-amz-date",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := []byte("AWS4" + s3TestSecretKey)
+	for _, part := range []string{amzDate[:8], f.region, "s3", "aws4_request", stringToSign} {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(part))
+		key = h.Sum(nil)
+	}
+	expected := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=%x",
+		s3TestAccessKey, scope, key)
+	return auth == expected
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.checkSignature(r) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>SignatureDoesNotMatch</Code><Message>The request signature we calculated does not match the signature you provided.</Message></Error>`)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	query := r.URL.Query()
+	if query.Has("uploads") || query.Has("uploadId") {
+		f.serveMultipart(w, r, query)
+		return
+	}
+	data, exists := f.objects[r.URL.Path]
+	switch r.Method {
+	case "PUT":
+		f.c.Check(r.Header.Get("Content-Type"), check.Equals, "application/x.snapd.snapshot")
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		f.c.Assert(err, check.IsNil)
+		f.c.Check(int64(len(body)), check.Equals, r.ContentLength)
+		f.objects[r.URL.Path] = body
+	case "GET", "HEAD":
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == "GET" {
+			w.Write(data)
+		}
+	case "DELETE":
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) serveMultipart(w http.ResponseWriter, r *http.Request, query url.Values) {
+	uploadID := query.Get("uploadId")
+	parts, ongoing := f.uploads[uploadID]
+	switch {
+	case r.Method == "POST" && query.Has("uploads"):
+		f.c.Check(r.Header.Get("Content-Type"), check.Equals, "application/x.snapd.snapshot")
+		if f.uploads == nil {
+			f.uploads = make(map[string]map[int][]byte)
+		}
+		f.uploaded++
+		uploadID = fmt.Sprintf("upload-%d", f.uploaded)
+		f.uploads[uploadID] = make(map[int][]byte)
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, r.URL.Path, uploadID)
+	case !ongoing:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchUpload</Code><Message>The specified upload does not exist.</Message></Error>`)
+	case r.Method == "PUT":
+		number, err := strconv.Atoi(query.Get("partNumber"))
+		f.c.Assert(err, check.IsNil)
+		if number == f.failPart {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>InternalError</Code><Message>We encountered an internal error. Please try again.</Message></Error>`)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		f.c.Assert(err, check.IsNil)
+		f.c.Check(int64(len(body)), check.Equals, r.ContentLength)
+		parts[number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == "POST":
+		var complete struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		f.c.Assert(xml.NewDecoder(r.Body).Decode(&complete), check.IsNil)
+		if _, exists := f.objects[r.URL.Path]; exists && r.Header.Get("If-None-Match") == "*" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		f.c.Check(complete.Parts, check.HasLen, len(parts))
+		var data []byte
+		for i, part := range complete.Parts {
+			f.c.Check(part.PartNumber, check.Equals, i+1)
+			f.c.Check(part.ETag, check.Equals, fmt.Sprintf(`"etag-%d"`, i+1))
+			data = append(data, parts[part.PartNumber]...)
+		}
+		f.objects[r.URL.Path] = data
+		delete(f.uploads, uploadID)
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><CompleteMultipartUploadResult><Key>%s</Key></CompleteMultipartUploadResult>`, r.URL.Path)
+	case r.Method == "DELETE":
+		delete(f.uploads, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (storeSuite) TestS3Store(c *check.C) {
+	restore := backend.MockTimeNow(func() time.Time {
+		return time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	})
+	defer restore()
+
+	fake := &fakeS3{c: c, region: "eu-west-1", objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	st := backend.NewS3Store(&backend.S3Config{
+		Endpoint:        srv.URL + "/",
+		Region:          "eu-west-1",
+		AccessKeyID:     s3TestAccessKey,
+		SecretAccessKey: s3TestSecretKey,
+	}, "bucket", "snaps")
+	testStore(c, st)
+
+	c.Assert(st.Put(context.Background(), "2.snapshot", strings.NewReader("hello"), 5), check.IsNil)
+	c.Check(fake.objects, check.DeepEquals, map[string][]byte{
+		"/bucket/snaps/2.snapshot": []byte("hello"),
+	})
+}
+
+func (storeSuite) TestS3StoreMultipart(c *check.C) {
+	restore := backend.MockS3PartSize(4)
+	defer restore()
+
+	fake := &fakeS3{c: c, region: "us-east-1", objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	st := backend.NewS3Store(&backend.S3Config{
+		Endpoint:        srv.URL,
+		AccessKeyID:     s3TestAccessKey,
+		SecretAccessKey: s3TestSecretKey,
+	}, "bucket", "")
+	ctx := context.Background()
+	c.Assert(st.Put(ctx, "1.snapshot", strings.NewReader("hello world"), 11), check.IsNil)
+	c.Check(fake.uploaded, check.Equals, 1)
+	c.Check(fake.uploads, check.HasLen, 0)
+	c.Check(fake.objects, check.DeepEquals, map[string][]byte{
+		"/bucket/1.snapshot": []byte("hello world"),
+	})
+
+	// parts of an upload that cannot be completed are not left behind
+	err := st.Put(ctx, "1.snapshot", strings.NewReader("hello again"), 11)
+	c.Check(errors.Is(err, backend.ErrExistsInStore), check.Equals, true)
+	c.Check(fake.uploaded, check.Equals, 2)
+	c.Check(fake.uploads, check.HasLen, 0)
+
+	fake.failPart = 2
+	err = st.Put(ctx, "2.snapshot", strings.NewReader("hello world"), 11)
+	c.Check(err, check.ErrorMatches, `cannot store "2.snapshot": InternalError: We encountered an internal error. Please try again.`)
+	c.Check(fake.uploaded, check.Equals, 3)
+	c.Check(fake.uploads, check.HasLen, 0)
+
+	err = st.Put(ctx, "3.snapshot", strings.NewReader("short"), 11)
+	c.Check(err, check.ErrorMatches, `.*ContentLength=4 with Body length 1`)
+	c.Check(fake.uploads, check.HasLen, 0)
+	c.Check(fake.objects, check.HasLen, 1)
+}
+
+func (storeSuite) TestS3StoreDefaultRegion(c *check.C) {
+	fake := &fakeS3{c: c, region: "us-east-1", objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	st := backend.NewS3Store(&backend.S3Config{
+		Endpoint:        srv.URL,
+		AccessKeyID:     s3TestAccessKey,
+		SecretAccessKey: s3TestSecretKey,
+	}, "bucket", "")
+	c.Assert(st.Put(context.Background(), "1.snapshot", strings.NewReader("hello"), 5), check.IsNil)
+	c.Check(fake.objects, check.DeepEquals, map[string][]byte{
+		"/bucket/1.snapshot": []byte("hello"),
+	})
+}
+
+func (storeSuite) TestS3StoreProxy(c *check.C) {
+	fake := &fakeS3{c: c, region: "us-east-1", objects: make(map[string][]byte)}
+	// requests to the service go through the proxy, which serves them
+	proxy := httptest.NewServer(fake)
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	c.Assert(err, check.IsNil)
+
+	proxied := 0
+	st := backend.NewS3Store(&backend.S3Config{
+		Endpoint:        "http://s3.example.com",
+		AccessKeyID:     s3TestAccessKey,
+		SecretAccessKey: s3TestSecretKey,
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			c.Check(req.URL.Host, check.Equals, "s3.example.com")
+			proxied++
+			return proxyURL, nil
+		},
+	}, "bucket", "")
+	c.Assert(st.Put(context.Background(), "1.snapshot", strings.NewReader("hello"), 5), check.IsNil)
+	c.Check(proxied, check.Equals, 1)
+	c.Check(fake.objects, check.DeepEquals, map[string][]byte{
+		"/bucket/1.snapshot": []byte("hello"),
+	})
+}
+
+func (storeSuite) TestS3StoreErrors(c *check.C) {
+	fake := &fakeS3{c: c, region: "us-east-1", objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	st := backend.NewS3Store(&backend.S3Config{
+		Endpoint:        srv.URL,
+		AccessKeyID:     s3TestAccessKey,
+		SecretAccessKey: "wrong",
+	}, "bucket", "")
+	err := st.Put(context.Background(), "1.snapshot", strings.NewReader("hello"), 5)
+	c.Check(err, check.ErrorMatches, `cannot store "1.snapshot": SignatureDoesNotMatch: The request signature we calculated does not match the signature you provided.`)
+	_, err = st.Get(context.Background(), "1.snapshot")
+	c.Check(err, check.ErrorMatches, `cannot get "1.snapshot": SignatureDoesNotMatch: .*`)
+	err = st.Remove(context.Background(), "1.snapshot")
+	c.Check(err, check.ErrorMatches, `cannot remove "1.snapshot": 403 Forbidden`)
+}
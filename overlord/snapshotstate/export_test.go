@@ -22,7 +22,10 @@ package snapshotstate
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/snapcore/snapd/client"
@@ -45,6 +48,10 @@ var (
 	CleanupRestore             = cleanupRestore
 	DoCheck                    = doCheck
 	DoForget                   = doForget
+	DoPush                     = doPush
+	ForgetSnapshotKey          = forgetSnapshotKey
+	SetSnapshotKey             = setSnapshotKey
+	SnapshotKey                = snapshotKey
 	SaveExpiration             = saveExpiration
 	ExpiredSnapshotSets        = expiredSnapshotSets
 	RemoveSnapshotState        = removeSnapshotState
@@ -139,6 +146,14 @@ func MockBackendCheck(f func(*backend.Reader, context.Context, []string) error)
 	}
 }
 
+func MockBackendSetKey(f func(*backend.Reader, *client.SnapshotKey) error) (restore func()) {
+	old := backendSetKey
+	backendSetKey = f
+	return func() {
+		backendSetKey = old
+	}
+}
+
 func MockBackendRevert(f func(*backend.RestoreState)) (restore func()) {
 	old := backendRevert
 	backendRevert = f
@@ -171,6 +186,14 @@ func MockBackendCleanupAbandonedImports(f func() (int, error)) (restore func())
 	}
 }
 
+func MockBackendCleanupUnusedChunks(f func(context.Context) (int, error)) (restore func()) {
+	old := backendCleanupUnusedChunks
+	backendCleanupUnusedChunks = f
+	return func() {
+		backendCleanupUnusedChunks = old
+	}
+}
+
 func MockBackendEstimateSnapshotSize(f func(*snap.Info, []string, *dirs.SnapDirOptions) (uint64, error)) (restore func()) {
 	old := backendEstimateSnapshotSize
 	backendEstimateSnapshotSize = f
@@ -205,7 +228,7 @@ func MockConfigSetSnapConfig(f func(*state.State, string, *json.RawMessage) erro
 
 // For testing only
 func SetLastForgetExpiredSnapshotTime(mgr *SnapshotManager, t time.Time) {
-	mgr.lastForgetExpiredSnapshotTime = t
+	mgr.setLastForgetExpiredSnapshotTime(t)
 }
 
 func MockGetSnapDirOptions(f func(*state.State, string) (*dirs.SnapDirOptions, error)) (restore func()) {
@@ -215,3 +238,50 @@ func MockGetSnapDirOptions(f func(*state.State, string) (*dirs.SnapDirOptions, e
 		getSnapDirOpts = old
 	}
 }
+
+type RetentionPolicy = retentionPolicy
+
+// SnapshotRetentionPolicies returns the configured policies keyed by
+// "<kind>" or "<snap>/<kind>".
+func SnapshotRetentionPolicies(st *state.State) (map[string]RetentionPolicy, error) {
+	rp, err := snapshotRetentionPolicies(st)
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[string]RetentionPolicy, len(rp))
+	for key, p := range rp {
+		name := key.kind
+		if key.snap != "" {
+			name = key.snap + "/" + key.kind
+		}
+		policies[name] = p
+	}
+	return policies, nil
+}
+
+// ExpiredByRetention returns the snapshots the policies do not keep, as
+// sorted "<set id>/<snap>" strings.
+func ExpiredByRetention(sets []client.SnapshotSet, policies map[string]RetentionPolicy, now time.Time) []string {
+	rp := make(retentionPolicies, len(policies))
+	for name, p := range policies {
+		key := retentionKey{kind: name}
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			key = retentionKey{snap: name[:i], kind: name[i+1:]}
+		}
+		rp[key] = p
+	}
+	var expired []string
+	for ref := range expiredByRetention(sets, rp, now) {
+		expired = append(expired, fmt.Sprintf("%d/%s", ref.setID, ref.snap))
+	}
+	sort.Strings(expired)
+	return expired
+}
+
+func MockBackendOpenStore(f func(string, *backend.S3Config) (backend.SnapshotStore, error)) (restore func()) {
+	old := backendOpenStore
+	backendOpenStore = f
+	return func() {
+		backendOpenStore = old
+	}
+}
@@ -0,0 +1,259 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// Retention policies are set with the system options
+//
+//	snapshots.retention.<kind>.<rule>
+//	snapshots.retention.snaps.<snap>.<kind>.<rule>
+//
+// where kind is either "manual" or "automatic", and rule is one of
+// "keep-last", "keep-daily" or "max-size". The rules set for a snap take
+// precedence over the ones set for all snaps.
+
+const (
+	retentionManual    = "manual"
+	retentionAutomatic = "automatic"
+)
+
+// A retentionPolicy tells which of the snapshots of one kind of a snap are
+// kept. A snapshot is kept if any of the keep rules keeps it, or if there
+// are none; max-size then drops the oldest of the ones kept. Rules that are
+// zero are not set.
+type retentionPolicy struct {
+	// keep the given number of most recent snapshots
+	KeepLast int
+	// keep the most recent snapshot of each of the given number of days,
+	// today included
+	KeepDaily int
+	// keep the most recent snapshots while their total size is within
+	// the given size; the most recent snapshot is always kept
+	MaxSize int64
+}
+
+func (p retentionPolicy) isZero() bool {
+	return p == retentionPolicy{}
+}
+
+// override returns the policy with the rules set in other replacing
+// those of p.
+func (p retentionPolicy) override(other retentionPolicy) retentionPolicy {
+	if other.KeepLast != 0 {
+		p.KeepLast = other.KeepLast
+	}
+	if other.KeepDaily != 0 {
+		p.KeepDaily = other.KeepDaily
+	}
+	if other.MaxSize != 0 {
+		p.MaxSize = other.MaxSize
+	}
+	return p
+}
+
+type retentionKey struct {
+	snap string
+	kind string
+}
+
+// retentionPolicies holds the configured retention policies, keyed by
+// snap and kind, the policies for all snaps having an empty snap name.
+type retentionPolicies map[retentionKey]retentionPolicy
+
+func (rp retentionPolicies) policyFor(snapName string, auto bool) retentionPolicy {
+	kind := retentionManual
+	if auto {
+		kind = retentionAutomatic
+	}
+	return rp[retentionKey{kind: kind}].override(rp[retentionKey{snap: snapName, kind: kind}])
+}
+
+// snapshotRetentionPolicies returns the configured retention policies.
+// Invalid values are logged and ignored, they cannot be set in the first
+// place.
+// The state needs to be locked by the caller.
+func snapshotRetentionPolicies(st *state.State) (retentionPolicies, error) {
+	var cfg map[string]any
+	tr := config.NewTransaction(st)
+	if err := tr.Get("core", "snapshots.retention", &cfg); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	rp := make(retentionPolicies)
+	for name, v := range cfg {
+		if name != "snaps" {
+			rp.add("", name, v)
+			continue
+		}
+		snaps, _ := v.(map[string]any)
+		for snapName, v := range snaps {
+			kinds, _ := v.(map[string]any)
+			for kind, v := range kinds {
+				rp.add(snapName, kind, v)
+			}
+		}
+	}
+	return rp, nil
+}
+
+func (rp retentionPolicies) add(snapName, kind string, v any) {
+	rules, ok := v.(map[string]any)
+	if !ok || (kind != retentionManual && kind != retentionAutomatic) {
+		return
+	}
+	var p retentionPolicy
+	for rule, v := range rules {
+		value := fmt.Sprintf("%v", v)
+		var err error
+		switch rule {
+		case "keep-last":
+			p.KeepLast, err = strconv.Atoi(value)
+		case "keep-daily":
+			p.KeepDaily, err = strconv.Atoi(value)
+		case "max-size":
+			p.MaxSize, err = strutil.ParseByteSize(value)
+		default:
+			continue
+		}
+		if err != nil {
+			logger.Noticef("snapshots.retention %s rule %q cannot be parsed: %v", kind, rule, err)
+		}
+	}
+	if !p.isZero() {
+		rp[retentionKey{snap: snapName, kind: kind}] = p
+	}
+}
+
+type snapshotRef struct {
+	setID uint64
+	snap  string
+}
+
+// keep returns the given snapshots the policy keeps, they must be sorted
+// from the most recent one.
+func (p retentionPolicy) keep(snapshots []*client.Snapshot, now time.Time) []*client.Snapshot {
+	kept := snapshots
+	if p.KeepLast > 0 || p.KeepDaily > 0 {
+		kept = nil
+		var since time.Time
+		if p.KeepDaily > 0 {
+			y, m, d := now.Date()
+			since = time.Date(y, m, d-p.KeepDaily+1, 0, 0, 0, 0, now.Location())
+		}
+		days := make(map[string]bool)
+		for i, sh := range snapshots {
+			day := sh.Time.In(now.Location()).Format("2006-01-02")
+			switch {
+			case i < p.KeepLast:
+			case p.KeepDaily > 0 && !sh.Time.Before(since) && !days[day]:
+			default:
+				continue
+			}
+			days[day] = true
+			kept = append(kept, sh)
+		}
+	}
+	if p.MaxSize > 0 {
+		var size int64
+		for i, sh := range kept {
+			size += sh.Size
+			if i > 0 && size > p.MaxSize {
+				kept = kept[:i]
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// expiredByRetention returns the snapshots in the given sets which the
+// retention policies do not keep. Broken snapshots are left alone. The
+// sets need to have the auto flag set, see decorateSnapshotSets.
+func expiredByRetention(sets []client.SnapshotSet, rp retentionPolicies, now time.Time) map[snapshotRef]bool {
+	if len(rp) == 0 {
+		return nil
+	}
+	groups := make(map[retentionKey][]*client.Snapshot)
+	for _, sset := range sets {
+		for _, sh := range sset.Snapshots {
+			if sh.Broken != "" {
+				continue
+			}
+			key := retentionKey{snap: sh.Snap, kind: retentionManual}
+			if sh.Auto {
+				key.kind = retentionAutomatic
+			}
+			groups[key] = append(groups[key], sh)
+		}
+	}
+
+	expired := make(map[snapshotRef]bool)
+	for key, snapshots := range groups {
+		p := rp.policyFor(key.snap, key.kind == retentionAutomatic)
+		if p.isZero() {
+			continue
+		}
+		sort.SliceStable(snapshots, func(i, j int) bool {
+			if snapshots[i].Time.Equal(snapshots[j].Time) {
+				return snapshots[i].SetID > snapshots[j].SetID
+			}
+			return snapshots[i].Time.After(snapshots[j].Time)
+		})
+		kept := make(map[*client.Snapshot]bool)
+		for _, sh := range p.keep(snapshots, now) {
+			kept[sh] = true
+		}
+		for _, sh := range snapshots {
+			if !kept[sh] {
+				expired[snapshotRef{setID: sh.SetID, snap: sh.Snap}] = true
+			}
+		}
+	}
+	return expired
+}
+
+// snapshotsExpiredByRetention returns the snapshots which the configured
+// retention policies do not keep.
+// The state needs to be locked by the caller.
+func snapshotsExpiredByRetention(ctx context.Context, st *state.State, rp retentionPolicies, now time.Time) (map[snapshotRef]bool, error) {
+	if len(rp) == 0 {
+		return nil, nil
+	}
+	sets, err := backendList(ctx, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := decorateSnapshotSets(st, sets); err != nil {
+		return nil, err
+	}
+	return expiredByRetention(sets, rp, now), nil
+}
@@ -0,0 +1,260 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var retentionNow = time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+
+func retentionSet(id uint64, snapName string, age time.Duration, size int64, auto bool) client.SnapshotSet {
+	return client.SnapshotSet{
+		ID: id,
+		Snapshots: []*client.Snapshot{{
+			SetID: id,
+			Snap:  snapName,
+			Time:  retentionNow.Add(-age),
+			Size:  size,
+			Auto:  auto,
+		}},
+	}
+}
+
+func setRetentionConfig(c *check.C, st *state.State, values map[string]any) {
+	tr := config.NewTransaction(st)
+	for k, v := range values {
+		c.Assert(tr.Set("core", k, v), check.IsNil)
+	}
+	tr.Commit()
+}
+
+func (snapshotSuite) TestSnapshotRetentionPolicies(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	policies, err := snapshotstate.SnapshotRetentionPolicies(st)
+	c.Assert(err, check.IsNil)
+	c.Check(policies, check.HasLen, 0)
+
+	setRetentionConfig(c, st, map[string]any{
+		"snapshots.retention.manual.keep-last":               3,
+		"snapshots.retention.manual.max-size":                "1GB",
+		"snapshots.retention.automatic.keep-daily":           "7",
+		"snapshots.retention.snaps.foo.automatic.keep-last":  2,
+		"snapshots.retention.snaps.foo.automatic.keep-daily": "bogus",
+		"snapshots.retention.snaps.bar.other.keep-last":      2,
+	})
+
+	policies, err = snapshotstate.SnapshotRetentionPolicies(st)
+	c.Assert(err, check.IsNil)
+	c.Check(policies, check.DeepEquals, map[string]snapshotstate.RetentionPolicy{
+		"manual":        {KeepLast: 3, MaxSize: 1000 * 1000 * 1000},
+		"automatic":     {KeepDaily: 7},
+		"foo/automatic": {KeepLast: 2},
+	})
+}
+
+func (snapshotSuite) TestExpiredByRetentionKeepLast(c *check.C) {
+	sets := []client.SnapshotSet{
+		retentionSet(1, "foo", 4*time.Hour, 1, false),
+		retentionSet(2, "foo", 3*time.Hour, 1, false),
+		retentionSet(3, "foo", 2*time.Hour, 1, false),
+		retentionSet(4, "bar", 5*time.Hour, 1, false),
+		// automatic snapshots are not kept by the manual policy
+		retentionSet(5, "foo", 5*time.Hour, 1, true),
+	}
+	expired := snapshotstate.ExpiredByRetention(sets, map[string]snapshotstate.RetentionPolicy{
+		"manual": {KeepLast: 2},
+	}, retentionNow)
+	c.Check(expired, check.DeepEquals, []string{"1/foo"})
+
+	// the policy for a snap takes precedence
+	expired = snapshotstate.ExpiredByRetention(sets, map[string]snapshotstate.RetentionPolicy{
+		"manual":     {KeepLast: 2},
+		"foo/manual": {KeepLast: 1},
+	}, retentionNow)
+	c.Check(expired, check.DeepEquals, []string{"1/foo", "2/foo"})
+}
+
+func (snapshotSuite) TestExpiredByRetentionKeepDaily(c *check.C) {
+	sets := []client.SnapshotSet{
+		retentionSet(1, "foo", 10*24*time.Hour, 1, true),
+		retentionSet(2, "foo", 2*24*time.Hour, 1, true),
+		retentionSet(3, "foo", 2*24*time.Hour-time.Hour, 1, true),
+		retentionSet(4, "foo", 24*time.Hour, 1, true),
+		retentionSet(5, "foo", time.Hour, 1, true),
+		retentionSet(6, "foo", 2*time.Hour, 1, true),
+		// broken snapshots are left alone
+		{ID: 7, Snapshots: []*client.Snapshot{{SetID: 7, Snap: "foo", Auto: true, Broken: "broken"}}},
+	}
+	expired := snapshotstate.ExpiredByRetention(sets, map[string]snapshotstate.RetentionPolicy{
+		"automatic": {KeepDaily: 3},
+	}, retentionNow)
+	// the most recent of each day of the last three is kept
+	c.Check(expired, check.DeepEquals, []string{"1/foo", "2/foo", "6/foo"})
+
+	// keep rules add up
+	expired = snapshotstate.ExpiredByRetention(sets, map[string]snapshotstate.RetentionPolicy{
+		"automatic": {KeepDaily: 3, KeepLast: 2},
+	}, retentionNow)
+	c.Check(expired, check.DeepEquals, []string{"1/foo", "2/foo"})
+}
+
+func (snapshotSuite) TestExpiredByRetentionMaxSize(c *check.C) {
+	sets := []client.SnapshotSet{
+		retentionSet(1, "foo", 3*time.Hour, 10, false),
+		retentionSet(2, "foo", 2*time.Hour, 20, false),
+		retentionSet(3, "foo", time.Hour, 30, false),
+		// the most recent snapshot is kept, whatever its size
+		retentionSet(4, "bar", time.Hour, 100, false),
+	}
+	expired := snapshotstate.ExpiredByRetention(sets, map[string]snapshotstate.RetentionPolicy{
+		"manual": {MaxSize: 55},
+	}, retentionNow)
+	c.Check(expired, check.DeepEquals, []string{"1/foo"})
+
+	expired = snapshotstate.ExpiredByRetention(sets, map[string]snapshotstate.RetentionPolicy{
+		"manual": {MaxSize: 55, KeepLast: 1},
+	}, retentionNow)
+	c.Check(expired, check.DeepEquals, []string{"1/foo", "2/foo"})
+}
+
+func (snapshotSuite) TestListSetsExpires(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	expiryTime := time.Date(2037, 2, 12, 12, 50, 0, 0, time.UTC)
+	st.Set("snapshots", map[uint64]any{
+		3: map[string]any{"expiry-time": expiryTime},
+	})
+	setRetentionConfig(c, st, map[string]any{
+		"snapshots.retention.manual.keep-last": 1,
+	})
+
+	restore := snapshotstate.MockBackendList(func(ctx context.Context, setID uint64, snapNames []string) ([]client.SnapshotSet, error) {
+		sets := []client.SnapshotSet{
+			retentionSet(1, "foo", 2*time.Hour, 1, false),
+			retentionSet(2, "foo", time.Hour, 1, false),
+			retentionSet(3, "foo", 3*time.Hour, 1, false),
+		}
+		if setID != 0 {
+			return sets[setID-1 : setID], nil
+		}
+		return sets, nil
+	})
+	defer restore()
+
+	// expired snapshots were not looked for yet, so set 1 goes as soon as
+	// they are, which "snap saved" shows as "expiring"
+	before := time.Now()
+	sets, err := snapshotstate.List(context.TODO(), st, 0, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(sets, check.HasLen, 3)
+	c.Assert(sets[0].Snapshots[0].Expires, check.NotNil)
+	c.Check(sets[0].Snapshots[0].Expires.Before(before), check.Equals, false)
+	c.Check(sets[0].Snapshots[0].Expires.After(time.Now()), check.Equals, false)
+	c.Check(sets[1].Snapshots[0].Expires, check.IsNil)
+	c.Assert(sets[2].Snapshots[0].Expires, check.NotNil)
+	c.Check(sets[2].Snapshots[0].Expires.Equal(expiryTime), check.Equals, true)
+	c.Check(sets[2].Snapshots[0].Auto, check.Equals, true)
+
+	// once they were, set 1 goes on the next daily run
+	mgr := snapshotstate.Manager(st, state.NewTaskRunner(st))
+	lastRun := time.Now().Add(-time.Hour)
+	snapshotstate.SetLastForgetExpiredSnapshotTime(mgr, lastRun)
+	sets, err = snapshotstate.List(context.TODO(), st, 0, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(sets, check.HasLen, 3)
+	c.Assert(sets[0].Snapshots[0].Expires, check.NotNil)
+	c.Check(sets[0].Snapshots[0].Expires.Equal(lastRun.Add(24*time.Hour)), check.Equals, true)
+
+	// all the snapshots of the snap are looked at when listing one set
+	sets, err = snapshotstate.List(context.TODO(), st, 1, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(sets, check.HasLen, 1)
+	c.Assert(sets[0].Snapshots[0].Expires, check.NotNil)
+	c.Check(sets[0].Snapshots[0].Expires.Equal(lastRun.Add(24*time.Hour)), check.Equals, true)
+}
+
+func (snapshotSuite) TestEnsureForgetsSnapshotsByRetention(c *check.C) {
+	var removed []string
+	restore := snapshotstate.MockOsRemove(func(fileName string) error {
+		removed = append(removed, filepath.Base(fileName))
+		return nil
+	})
+	defer restore()
+
+	sets := []client.SnapshotSet{
+		retentionSet(1, "foo", 2*time.Hour, 1, false),
+		retentionSet(2, "foo", time.Hour, 1, false),
+		retentionSet(3, "bar", 3*time.Hour, 1, false),
+	}
+	restore = snapshotstate.MockBackendList(func(context.Context, uint64, []string) ([]client.SnapshotSet, error) {
+		return sets, nil
+	})
+	defer restore()
+
+	dir := c.MkDir()
+	restore = snapshotstate.MockBackendIter(func(_ context.Context, f func(*backend.Reader) error) error {
+		for _, sset := range sets {
+			shotfile, err := os.Create(filepath.Join(dir, sset.Snapshots[0].Snap+"-"+string(rune('0'+sset.ID))+".zip"))
+			c.Assert(err, check.IsNil)
+			defer shotfile.Close()
+			c.Assert(f(&backend.Reader{Snapshot: *sset.Snapshots[0], File: shotfile}), check.IsNil)
+		}
+		return nil
+	})
+	defer restore()
+
+	st := state.New(nil)
+	runner := state.NewTaskRunner(st)
+	mgr := snapshotstate.Manager(st, runner)
+
+	st.Lock()
+	setRetentionConfig(c, st, map[string]any{
+		"snapshots.retention.manual.keep-last": 1,
+	})
+	st.Unlock()
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+	sort.Strings(removed)
+	c.Check(removed, check.DeepEquals, []string{"foo-1.zip"})
+
+	// the retention policies are applied once per interval
+	removed = nil
+	sets = append(sets, retentionSet(4, "bar", time.Minute, 1, false))
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(removed, check.HasLen, 0)
+}
@@ -23,6 +23,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -48,10 +49,12 @@ var (
 	backendImport        = backend.Import
 	backendRestore       = (*backend.Reader).Restore // TODO: look into using an interface instead
 	backendCheck         = (*backend.Reader).Check
+	backendSetKey        = (*backend.Reader).SetKey
 	backendRevert        = (*backend.RestoreState).Revert // ditto
 	backendCleanup       = (*backend.RestoreState).Cleanup
 
 	backendCleanupAbandonedImports = backend.CleanupAbandonedImports
+	backendCleanupUnusedChunks     = backend.CleanupUnusedChunks
 
 	autoExpirationInterval = time.Hour * 24 // interval between forgetExpiredSnapshots runs as part of Ensure()
 
@@ -74,6 +77,9 @@ func Manager(st *state.State, runner *state.TaskRunner) *SnapshotManager {
 	runner.AddHandler("check-snapshot", doCheck, nil)
 	runner.AddHandler("restore-snapshot", doRestore, undoRestore)
 	runner.AddHandler("cleanup-after-restore", doCleanupAfterRestore, nil)
+	runner.AddHandler("push-snapshot", doPush, nil)
+	runner.AddCleanup("check-snapshot", forgetSnapshotKey)
+	runner.AddCleanup("restore-snapshot", forgetSnapshotKey)
 
 	manager := &SnapshotManager{
 		state: st,
@@ -104,12 +110,26 @@ func (mgr *SnapshotManager) forgetExpiredSnapshots() error {
 	mgr.state.Lock()
 	defer mgr.state.Unlock()
 
-	sets, err := expiredSnapshotSets(mgr.state, time.Now())
+	now := time.Now()
+	sets, err := expiredSnapshotSets(mgr.state, now)
 	if err != nil {
 		return fmt.Errorf("internal error: cannot determine expired snapshots: %v", err)
 	}
+	rp, err := snapshotRetentionPolicies(mgr.state)
+	if err != nil {
+		return fmt.Errorf("cannot get snapshot retention policies: %v", err)
+	}
+	retired, err := snapshotsExpiredByRetention(context.TODO(), mgr.state, rp, now)
+	if err != nil {
+		return fmt.Errorf("cannot apply snapshot retention policies: %v", err)
+	}
 
-	if len(sets) == 0 {
+	if len(sets) == 0 && len(retired) == 0 {
+		// going through the snapshots for the retention policies is
+		// not cheap, do it once per interval only
+		if len(rp) > 0 {
+			mgr.setLastForgetExpiredSnapshotTime(now)
+		}
 		return nil
 	}
 
@@ -120,8 +140,10 @@ func (mgr *SnapshotManager) forgetExpiredSnapshots() error {
 			// there is a conflict, do nothing and we will retry this set on next Ensure().
 			return nil
 		}
-		if sets[r.SetID] {
+		ref := snapshotRef{setID: r.SetID, snap: r.Snap}
+		if sets[r.SetID] || retired[ref] {
 			delete(sets, r.SetID)
+			delete(retired, ref)
 			// remove from state first: in case removeSnapshotState succeeds but osRemove fails we will never attempt
 			// to automatically remove this snapshot again and will leave it on the disk (so the user can still try to remove it manually);
 			// this is better than the other way around where a failing osRemove would be retried forever because snapshot would never
@@ -139,18 +161,38 @@ func (mgr *SnapshotManager) forgetExpiredSnapshots() error {
 	if err != nil {
 		return fmt.Errorf("cannot process expired snapshots: %v", err)
 	}
+	cleanupUnusedChunks()
 
 	// only reset time if there are no sets left because of conflicts
-	if len(sets) == 0 {
-		mgr.lastForgetExpiredSnapshotTime = time.Now()
+	if len(sets) == 0 && len(retired) == 0 {
+		mgr.setLastForgetExpiredSnapshotTime(time.Now())
 	}
 
 	return nil
 }
 
+// setLastForgetExpiredSnapshotTime records when expired snapshots were last
+// removed, also in the state cache for nextForgetExpiredSnapshotTime. The
+// state must be locked by the caller.
+func (mgr *SnapshotManager) setLastForgetExpiredSnapshotTime(t time.Time) {
+	mgr.lastForgetExpiredSnapshotTime = t
+	mgr.state.Cache("last-forget-expired-snapshots", t)
+}
+
+// nextForgetExpiredSnapshotTime returns when the snapshot manager is going
+// to remove expired snapshots next, which is now if that is overdue, as then
+// it happens on its next Ensure. The state must be locked by the caller.
+func nextForgetExpiredSnapshotTime(st *state.State, now time.Time) time.Time {
+	last, _ := st.Cached("last-forget-expired-snapshots").(time.Time)
+	if next := last.Add(autoExpirationInterval); next.After(now) {
+		return next
+	}
+	return now
+}
+
 func (SnapshotManager) affectedSnaps(t *state.Task) ([]string, error) {
-	if k := t.Kind(); k == "check-snapshot" || k == "forget-snapshot" {
-		// check and forget don't affect snaps
+	if k := t.Kind(); k == "check-snapshot" || k == "forget-snapshot" || k == "push-snapshot" {
+		// check, forget and push don't affect snaps
 		// (this could also be written k != save && k != restore, but it's safer this way around)
 		return nil, nil
 	}
@@ -170,6 +212,11 @@ type snapshotSetup struct {
 	Filename string                `json:"filename,omitempty"`
 	Current  snap.Revision         `json:"current"`
 	Auto     bool                  `json:"auto,omitempty"`
+	// the snapshot target the set is moved to, for push-snapshot
+	Target string `json:"target,omitempty"`
+	// KeyGiven is set if a key was given for the snapshot, which is only
+	// kept in memory, see setSnapshotKey
+	KeyGiven bool `json:"key-given,omitempty"`
 }
 
 func filename(setID uint64, si *snap.Info) string {
@@ -264,9 +311,30 @@ func prepareRestore(task *state.Task) (snapshot *snapshotSetup, oldCfg map[strin
 	}
 	// note given the Open succeeded, caller needs to close it when done
 
+	key, err := taskSnapshotKey(st, task, snapshot)
+	if err == nil {
+		err = setReaderKey(reader, key)
+	}
+	if err != nil {
+		reader.Close()
+		return nil, nil, nil, err
+	}
+
 	return snapshot, oldCfg, reader, nil
 }
 
+// setReaderKey sets the given key, if any, on the reader of an encrypted
+// snapshot.
+func setReaderKey(reader *backend.Reader, key *client.SnapshotKey) error {
+	if key == nil || reader.Encryption == nil {
+		return nil
+	}
+	if err := backendSetKey(reader, key); err != nil {
+		return fmt.Errorf("cannot use snapshot key: %v", err)
+	}
+	return nil
+}
+
 // marshalSnapConfig encodes cfg to JSON and returns raw JSON message, unless
 // cfg is nil - in this case nil is returned.
 func marshalSnapConfig(cfg map[string]any) (*json.RawMessage, error) {
@@ -422,10 +490,15 @@ func doCheck(task *state.Task, tomb *tomb.Tomb) error {
 	st := task.State()
 	st.Lock()
 	err := task.Get("snapshot-setup", &snapshot)
-	st.Unlock()
 	if err != nil {
+		st.Unlock()
 		return taskGetErrMsg(task, err, "snapshot")
 	}
+	key, err := taskSnapshotKey(st, task, &snapshot)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
 
 	reader, err := backendOpen(snapshot.Filename, backend.ExtractFnameSetID)
 	if err != nil {
@@ -433,9 +506,26 @@ func doCheck(task *state.Task, tomb *tomb.Tomb) error {
 	}
 	defer reader.Close()
 
+	if err := setReaderKey(reader, key); err != nil {
+		return err
+	}
+
 	return backendCheck(reader, tomb.Context(nil), snapshot.Users)
 }
 
+// forgetSnapshotKey drops the key of the snapshot the task operated on, if
+// any, once its change is done.
+func forgetSnapshotKey(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if keys, _ := st.Cached("snapshot-keys").(map[string]*client.SnapshotKey); keys != nil {
+		delete(keys, task.ID())
+	}
+	return nil
+}
+
 func doForget(task *state.Task, _ *tomb.Tomb) error {
 	// note this is also undoSave
 	st := task.State()
@@ -458,7 +548,97 @@ func doForget(task *state.Task, _ *tomb.Tomb) error {
 		return fmt.Errorf("internal error: cannot remove state of snapshot set %d: %v", snapshot.SetID, err)
 	}
 
-	return osRemove(snapshot.Filename)
+	if err := osRemove(snapshot.Filename); err != nil {
+		return err
+	}
+	cleanupUnusedChunks()
+	return nil
+}
+
+// preparePush does the steps of doPush that require the state lock.
+func preparePush(task *state.Task) (snapshot *snapshotSetup, store backend.SnapshotStore, se *backend.SnapshotExport, err error) {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if err := task.Get("snapshot-setup", &snapshot); err != nil {
+		return nil, nil, nil, taskGetErrMsg(task, err, "snapshot")
+	}
+	store, err = snapshotStore(st, snapshot.Target)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	se, err = Export(context.TODO(), st, snapshot.SetID, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return snapshot, store, se, nil
+}
+
+// doPush moves a snapshot set to a snapshot target, as an export of it.
+func doPush(task *state.Task, tomb *tomb.Tomb) error {
+	snapshot, store, se, err := preparePush(task)
+	if err != nil {
+		return err
+	}
+	st := task.State()
+	defer func() {
+		se.Close()
+		st.Lock()
+		UnsetSnapshotOpInProgress(st, snapshot.SetID)
+		st.Unlock()
+	}()
+
+	if err := se.Init(); err != nil {
+		return err
+	}
+	pr, pw := io.Pipe()
+	streamDone := make(chan struct{})
+	go func() {
+		defer close(streamDone)
+		pw.CloseWithError(se.StreamTo(pw))
+	}()
+	err = store.Put(tomb.Context(nil), backend.StoreName(snapshot.SetID), pr, se.Size())
+	// stop the stream if Put returned early, and wait for it before the
+	// export is closed
+	pr.Close()
+	<-streamDone
+	if err != nil {
+		return fmt.Errorf("cannot move snapshot set #%d to %s, it is kept on this device: %v", snapshot.SetID, snapshot.Target, err)
+	}
+
+	// the snapshot set is safely in the target, failing to remove it
+	// from here should not undo the change
+	summaries, err := snapSummariesInSnapshotSet(snapshot.SetID, nil)
+	if err != nil {
+		logger.Noticef("Cannot remove snapshot set #%d after moving it: %v", snapshot.SetID, err)
+		return nil
+	}
+	st.Lock()
+	defer st.Unlock()
+	if err := removeSnapshotState(st, snapshot.SetID); err != nil {
+		logger.Noticef("Cannot remove state of snapshot set #%d: %v", snapshot.SetID, err)
+	}
+	for _, summary := range summaries {
+		if err := osRemove(summary.filename); err != nil {
+			logger.Noticef("Cannot remove snapshot file %q after moving it: %v", summary.filename, err)
+		}
+	}
+	cleanupUnusedChunks()
+	return nil
+}
+
+// cleanupUnusedChunks removes the chunks which are no longer used by any
+// snapshot. Failing to do so is not fatal, they are removed later on.
+func cleanupUnusedChunks() {
+	removed, err := backendCleanupUnusedChunks(context.TODO())
+	if err != nil {
+		logger.Noticef("Cannot remove unused snapshot chunks: %v", err)
+		return
+	}
+	if removed > 0 {
+		logger.Debugf("Removed %d unused snapshot chunks.", removed)
+	}
 }
 
 func delayedCrossMgrInit() {
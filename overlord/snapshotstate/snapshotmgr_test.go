@@ -23,6 +23,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -39,6 +40,7 @@ import (
 	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -55,6 +57,7 @@ func (snapshotSuite) TestManager(c *check.C) {
 		"check-snapshot",
 		"cleanup-after-restore",
 		"forget-snapshot",
+		"push-snapshot",
 		"restore-snapshot",
 		"save-snapshot",
 	})
@@ -160,7 +163,9 @@ func (snapshotSuite) TestEnsureForgetsSnapshotsRunsRegularly(c *check.C) {
 	// pretend we haven't run for a while
 	t, err := time.Parse(time.RFC3339, "2002-03-11T11:24:00Z")
 	c.Assert(err, check.IsNil)
+	st.Lock()
 	snapshotstate.SetLastForgetExpiredSnapshotTime(mgr, t)
+	st.Unlock()
 	c.Assert(mgr.Ensure(), check.IsNil)
 	c.Check(backendIterCalls, check.Equals, 2)
 
@@ -838,14 +843,102 @@ func (rs *readerSuite) TestDoCheck(c *check.C) {
 	c.Check(rs.calls, check.DeepEquals, []string{"open", "check"})
 }
 
+func (rs *readerSuite) TestDoCheckEncrypted(c *check.C) {
+	key := &client.SnapshotKey{Passphrase: "sekrit"}
+	st := rs.task.State()
+	st.Lock()
+	snapshotstate.SetSnapshotKey(st, rs.task, key)
+	st.Unlock()
+
+	defer snapshotstate.MockBackendOpen(func(filename string, setID uint64) (*backend.Reader, error) {
+		rs.calls = append(rs.calls, "open")
+		return &backend.Reader{
+			Snapshot: client.Snapshot{Encryption: &client.SnapshotEncryption{}},
+		}, nil
+	})()
+	defer snapshotstate.MockBackendSetKey(func(_ *backend.Reader, k *client.SnapshotKey) error {
+		rs.calls = append(rs.calls, "set key")
+		c.Check(k, check.Equals, key)
+		return nil
+	})()
+
+	err := snapshotstate.DoCheck(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.IsNil)
+	c.Check(rs.calls, check.DeepEquals, []string{"open", "set key", "check"})
+
+	// the key is dropped once the change is done
+	c.Assert(snapshotstate.ForgetSnapshotKey(rs.task, &tomb.Tomb{}), check.IsNil)
+	st.Lock()
+	defer st.Unlock()
+	c.Check(snapshotstate.SnapshotKey(st, rs.task), check.IsNil)
+}
+
+func (rs *readerSuite) TestDoRestoreWrongKey(c *check.C) {
+	st := rs.task.State()
+	st.Lock()
+	snapshotstate.SetSnapshotKey(st, rs.task, &client.SnapshotKey{Passphrase: "wrong"})
+	st.Unlock()
+
+	defer snapshotstate.MockBackendOpen(func(filename string, setID uint64) (*backend.Reader, error) {
+		rs.calls = append(rs.calls, "open")
+		return &backend.Reader{
+			Snapshot: client.Snapshot{Encryption: &client.SnapshotEncryption{}},
+		}, nil
+	})()
+	defer snapshotstate.MockBackendSetKey(func(*backend.Reader, *client.SnapshotKey) error {
+		rs.calls = append(rs.calls, "set key")
+		return errors.New("bzzt")
+	})()
+
+	err := snapshotstate.DoRestore(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.ErrorMatches, "cannot use snapshot key: bzzt")
+	c.Check(rs.calls, check.DeepEquals, []string{"get config", "open", "set key"})
+}
+
 func (rs *readerSuite) TestDoRemove(c *check.C) {
 	defer snapshotstate.MockOsRemove(func(filename string) error {
 		c.Check(filename, check.Equals, "/some/1_file.zip")
 		rs.calls = append(rs.calls, "remove")
 		return nil
 	})()
+	defer snapshotstate.MockBackendCleanupUnusedChunks(func(context.Context) (int, error) {
+		rs.calls = append(rs.calls, "cleanup-chunks")
+		return 1, nil
+	})()
+	err := snapshotstate.DoForget(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.IsNil)
+	c.Check(rs.calls, check.DeepEquals, []string{"remove", "cleanup-chunks"})
+}
+
+func (rs *readerSuite) TestDoRemoveCleanupChunksFails(c *check.C) {
+	defer snapshotstate.MockOsRemove(func(filename string) error {
+		rs.calls = append(rs.calls, "remove")
+		return nil
+	})()
+	defer snapshotstate.MockBackendCleanupUnusedChunks(func(context.Context) (int, error) {
+		rs.calls = append(rs.calls, "cleanup-chunks")
+		return 0, errors.New("bzzt")
+	})()
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
 	err := snapshotstate.DoForget(rs.task, &tomb.Tomb{})
 	c.Assert(err, check.IsNil)
+	c.Check(rs.calls, check.DeepEquals, []string{"remove", "cleanup-chunks"})
+	c.Check(logbuf.String(), testutil.Contains, "Cannot remove unused snapshot chunks: bzzt")
+}
+
+func (rs *readerSuite) TestDoRemoveFailsKeepsChunks(c *check.C) {
+	defer snapshotstate.MockOsRemove(func(filename string) error {
+		rs.calls = append(rs.calls, "remove")
+		return errors.New("bzzt")
+	})()
+	defer snapshotstate.MockBackendCleanupUnusedChunks(func(context.Context) (int, error) {
+		rs.calls = append(rs.calls, "cleanup-chunks")
+		return 0, nil
+	})()
+	err := snapshotstate.DoForget(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.ErrorMatches, "bzzt")
 	c.Check(rs.calls, check.DeepEquals, []string{"remove"})
 }
 
@@ -927,3 +1020,87 @@ func (snapshotSuite) TestManagerRunCleanupAbandonedImportsAtStartupErrorLogged(c
 	c.Check(n, check.Equals, 1)
 	c.Check(logbuf.String(), testutil.Contains, "cannot cleanup incomplete imports: some error\n")
 }
+
+func (snapshotSuite) TestDoPush(c *check.C) {
+	c.Assert(os.MkdirAll(dirs.SnapshotsDir, 0755), check.IsNil)
+	sideInfo := &snap.SideInfo{RealName: "a-snap", Revision: snap.R(1)}
+	snapInfo := snaptest.MockSnap(c, "{name: a-snap, version: v1}", sideInfo)
+	c.Assert(os.MkdirAll(snapInfo.DataDir(), 0755), check.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(snapInfo.DataDir(), "canary"), []byte("hello"), 0644), check.IsNil)
+	// only system data is saved, as there are no users
+	_, err := backend.Save(context.TODO(), 42, snapInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	target := c.MkDir()
+	st := state.New(nil)
+	st.Lock()
+	st.Set("snapshots", map[uint64]any{
+		42: map[string]any{"expiry-time": time.Now().Add(time.Hour)},
+	})
+	task := st.NewTask("push-snapshot", "...")
+	task.Set("snapshot-setup", map[string]any{
+		"set-id": 42,
+		"target": target,
+	})
+	st.Unlock()
+
+	c.Assert(snapshotstate.DoPush(task, &tomb.Tomb{}), check.IsNil)
+
+	c.Check(filepath.Join(target, "42.snapshot"), testutil.FilePresent)
+	sets, err := backend.List(context.TODO(), 42, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(sets, check.HasLen, 0)
+
+	st.Lock()
+	defer st.Unlock()
+	var expirations map[uint64]any
+	c.Assert(st.Get("snapshots", &expirations), check.IsNil)
+	c.Check(expirations, check.HasLen, 0)
+	c.Check(st.Cached("snapshot-ops"), check.DeepEquals, map[uint64]string{})
+
+	// what was moved can be fetched back
+	defer snapshotstate.MockBackendImport(func(ctx context.Context, id uint64, r io.Reader, flags *backend.ImportFlags) ([]string, error) {
+		return backend.Import(ctx, id, r, flags)
+	})()
+	st.Unlock()
+	setID, names, err := snapshotstate.Fetch(context.TODO(), st, target, 42, nil)
+	st.Lock()
+	c.Assert(err, check.IsNil)
+	c.Check(names, check.DeepEquals, []string{"a-snap"})
+	sets, err = backend.List(context.TODO(), setID, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(sets, check.HasLen, 1)
+	c.Check(sets[0].Snapshots[0].Snap, check.Equals, "a-snap")
+}
+
+func (snapshotSuite) TestDoPushFailsKeepsSnapshot(c *check.C) {
+	c.Assert(os.MkdirAll(dirs.SnapshotsDir, 0755), check.IsNil)
+	sideInfo := &snap.SideInfo{RealName: "a-snap", Revision: snap.R(1)}
+	snapInfo := snaptest.MockSnap(c, "{name: a-snap, version: v1}", sideInfo)
+	c.Assert(os.MkdirAll(snapInfo.DataDir(), 0755), check.IsNil)
+	_, err := backend.Save(context.TODO(), 42, snapInfo, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	target := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(target, "42.snapshot"), nil, 0644), check.IsNil)
+
+	st := state.New(nil)
+	st.Lock()
+	task := st.NewTask("push-snapshot", "...")
+	task.Set("snapshot-setup", map[string]any{
+		"set-id": 42,
+		"target": target,
+	})
+	st.Unlock()
+
+	err = snapshotstate.DoPush(task, &tomb.Tomb{})
+	c.Assert(err, check.ErrorMatches, `cannot move snapshot set #42 to .*, it is kept on this device: cannot store "42.snapshot": already exists in snapshot store`)
+
+	sets, err := backend.List(context.TODO(), 42, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(sets, check.HasLen, 1)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Cached("snapshot-ops"), check.DeepEquals, map[uint64]string{})
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/proxyconf"
 	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -46,6 +47,7 @@ var (
 	backendEstimateSnapshotSize      = backend.EstimateSnapshotSize
 	backendList                      = backend.List
 	backendNewSnapshotExport         = backend.NewSnapshotExport
+	backendOpenStore                 = backend.OpenStore
 
 	// Default expiration time for automatic snapshots, if not set by the user
 	defaultAutomaticSnapshotExpiration = time.Hour * 24 * 31
@@ -228,10 +230,11 @@ func (summaries snapshotSnapSummaries) snapNames() []string {
 }
 
 type snapshotSnapSummary struct {
-	snap     string
-	snapID   string
-	filename string
-	epoch    snap.Epoch
+	snap      string
+	snapID    string
+	filename  string
+	epoch     snap.Epoch
+	encrypted bool
 }
 
 // snapSummariesInSnapshotSet goes looking for the requested snaps in the
@@ -244,10 +247,11 @@ func snapSummariesInSnapshotSet(setID uint64, requested []string) (summaries sna
 			found = true
 			if len(requested) == 0 || strutil.SortedListContains(requested, r.Snap) {
 				summaries = append(summaries, &snapshotSnapSummary{
-					filename: r.Name(),
-					snap:     r.Snap,
-					snapID:   r.SnapID,
-					epoch:    r.Epoch,
+					filename:  r.Name(),
+					snap:      r.Snap,
+					snapID:    r.SnapID,
+					epoch:     r.Epoch,
+					encrypted: r.Encryption != nil,
 				})
 			}
 		}
@@ -307,36 +311,78 @@ func checkSnapshotConflict(st *state.State, setID uint64, conflictingKinds ...st
 	return nil
 }
 
-// List valid snapshots.
+// List valid snapshots. Snapshots that are to be removed automatically,
+// because they expire or because of the retention policies, have their
+// expiry time set. For the latter, that is when expired snapshots are next
+// removed.
 // Note that the state must be locked by the caller.
 func List(ctx context.Context, st *state.State, setID uint64, snapNames []string) ([]client.SnapshotSet, error) {
 	sets, err := backendList(ctx, setID, snapNames)
 	if err != nil {
 		return nil, err
 	}
+	if err := decorateSnapshotSets(st, sets); err != nil {
+		return nil, err
+	}
 
+	rp, err := snapshotRetentionPolicies(st)
+	if err != nil {
+		return nil, err
+	}
+	if len(rp) == 0 {
+		return sets, nil
+	}
+	// the retention policies apply to all the snapshots of a snap
+	all := sets
+	if setID != 0 {
+		if all, err = backendList(ctx, 0, snapNames); err != nil {
+			return nil, err
+		}
+		if err := decorateSnapshotSets(st, all); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	expired := expiredByRetention(all, rp, now)
+	next := nextForgetExpiredSnapshotTime(st, now)
+	for _, sset := range sets {
+		for _, snapshot := range sset.Snapshots {
+			if expired[snapshotRef{setID: snapshot.SetID, snap: snapshot.Snap}] {
+				snapshot.Expires = &next
+			}
+		}
+	}
+
+	return sets, nil
+}
+
+// decorateSnapshotSets sets the "auto" flag and the expiry time of the
+// snapshots which have an expiry time set in the state.
+// The state needs to be locked by the caller.
+func decorateSnapshotSets(st *state.State, sets []client.SnapshotSet) error {
 	var snapshots map[uint64]*snapshotState
 	if err := st.Get("snapshots", &snapshots); err != nil && !errors.Is(err, state.ErrNoState) {
-		return nil, err
+		return err
 	}
 
-	// decorate all snapshots with "auto" flag if we have expiry time set for them.
 	for _, sset := range sets {
 		// at the moment we only keep records with expiry time so checking non-zero
 		// expiry-time is not strictly necessary, but it makes it future-proof in case
 		// we add more attributes to these entries.
 		if snapshotState, ok := snapshots[sset.ID]; ok && !snapshotState.ExpiryTime.IsZero() {
+			expiryTime := snapshotState.ExpiryTime
 			for _, snapshot := range sset.Snapshots {
 				snapshot.Auto = true
+				snapshot.Expires = &expiryTime
 			}
 		}
 	}
-
-	return sets, nil
+	return nil
 }
 
-// Import a given snapshot ID from an exported snapshot
-func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, snapNames []string, err error) {
+// Import a given snapshot ID from an exported snapshot. Encrypted snapshots
+// are decrypted with the given key, or kept encrypted if it is nil.
+func Import(ctx context.Context, st *state.State, r io.Reader, key *client.SnapshotKey) (setID uint64, snapNames []string, err error) {
 	st.Lock()
 	setID, err = newSnapshotSetID(st)
 	// note, this is a new set id which is not exposed yet, no need to mark it
@@ -347,7 +393,11 @@ func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, sn
 		return 0, nil, err
 	}
 
-	snapNames, err = backendImport(ctx, setID, r, nil)
+	var flags *backend.ImportFlags
+	if key != nil {
+		flags = &backend.ImportFlags{Key: key}
+	}
+	snapNames, err = backendImport(ctx, setID, r, flags)
 	if err != nil {
 		if dupErr, ok := err.(backend.DuplicatedSnapshotImportError); ok {
 			st.Lock()
@@ -356,7 +406,7 @@ func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, sn
 			if err := checkSnapshotConflict(st, dupErr.SetID, "forget-snapshot"); err != nil {
 				// we found an existing snapshot but it's being forgotten, so
 				// retry the import without checking for existing snapshot.
-				flags := &backend.ImportFlags{NoDuplicatedImportCheck: true}
+				flags := &backend.ImportFlags{NoDuplicatedImportCheck: true, Key: key}
 				st.Unlock()
 				snapNames, err = backendImport(ctx, setID, r, flags)
 				st.Lock()
@@ -381,6 +431,22 @@ func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, sn
 // Save creates a taskset for taking snapshots of snaps' data.
 // Note that the state must be locked by the caller.
 func Save(st *state.State, instanceNames []string, users []string, options map[string]*snap.SnapshotOptions) (setID uint64, snapsSaved []string, ts *state.TaskSet, err error) {
+	return SaveTo(st, instanceNames, users, options, "")
+}
+
+// SaveTo creates a taskset for taking snapshots of snaps' data like Save,
+// which then moves the snapshot set to the given snapshot target if it is
+// not empty, see backend.OpenStore. If moving the set fails it is kept on
+// the device.
+// Note that the state must be locked by the caller.
+func SaveTo(st *state.State, instanceNames []string, users []string, options map[string]*snap.SnapshotOptions, target string) (setID uint64, snapsSaved []string, ts *state.TaskSet, err error) {
+	if target != "" {
+		// fail early on targets that cannot be used
+		if _, err := snapshotStore(st, target); err != nil {
+			return 0, nil, nil, err
+		}
+	}
+
 	if len(instanceNames) == 0 {
 		instanceNames, err = allActiveSnapNames(st)
 		if err != nil {
@@ -437,9 +503,67 @@ func Save(st *state.State, instanceNames []string, users []string, options map[s
 		ts.AddTask(task)
 	}
 
+	if target != "" {
+		desc := fmt.Sprintf("Move snapshot set #%d to %s", setID, target)
+		task := st.NewTask("push-snapshot", desc)
+		task.Set("snapshot-setup", &snapshotSetup{
+			SetID:  setID,
+			Target: target,
+		})
+		task.WaitAll(ts)
+		// the snapshot set is kept when moving it fails: in its own
+		// lane the push is not part of the undo chain of the saves
+		task.JoinLane(st.NewLane())
+		ts.AddTask(task)
+	}
+
 	return setID, instanceNames, ts, nil
 }
 
+// snapshotStore returns the store of the given snapshot target, using the
+// snapshots.s3.* system options for S3 targets.
+// The state needs to be locked by the caller.
+func snapshotStore(st *state.State, target string) (backend.SnapshotStore, error) {
+	var s3cfg backend.S3Config
+	tr := config.NewTransaction(st)
+	for _, opt := range []struct {
+		name  string
+		value *string
+	}{
+		{"snapshots.s3.endpoint", &s3cfg.Endpoint},
+		{"snapshots.s3.region", &s3cfg.Region},
+		{"snapshots.s3.access-key-id", &s3cfg.AccessKeyID},
+		{"snapshots.s3.secret-access-key", &s3cfg.SecretAccessKey},
+	} {
+		if err := tr.Get("core", opt.name, opt.value); err != nil && !config.IsNoOption(err) {
+			return nil, err
+		}
+	}
+	s3cfg.Proxy = proxyconf.New(st).Conf
+	return backendOpenStore(target, &s3cfg)
+}
+
+// Fetch imports the given snapshot set from the given snapshot target,
+// where it was moved with SaveTo. Like with Import, encrypted snapshots
+// are decrypted with the given key, or kept encrypted if it is nil.
+func Fetch(ctx context.Context, st *state.State, target string, setID uint64, key *client.SnapshotKey) (newSetID uint64, snapNames []string, err error) {
+	st.Lock()
+	store, err := snapshotStore(st, target)
+	st.Unlock()
+	if err != nil {
+		return 0, nil, err
+	}
+	r, err := store.Get(ctx, backend.StoreName(setID))
+	if err != nil {
+		if errors.Is(err, backend.ErrNotInStore) {
+			return 0, nil, client.ErrSnapshotSetNotFound
+		}
+		return 0, nil, fmt.Errorf("cannot fetch snapshot set #%d from %s: %v", setID, target, err)
+	}
+	defer r.Close()
+	return Import(ctx, st, r, key)
+}
+
 func AutomaticSnapshot(st *state.State, snapName string) (ts *state.TaskSet, err error) {
 	expiration, err := AutomaticSnapshotExpiration(st)
 	if err != nil {
@@ -467,9 +591,10 @@ func AutomaticSnapshot(st *state.State, snapName string) (ts *state.TaskSet, err
 	return ts, nil
 }
 
-// Restore creates a taskset for restoring a snapshot's data.
+// Restore creates a taskset for restoring a snapshot's data. Encrypted
+// snapshots need their key, which is only kept in memory.
 // Note that the state must be locked by the caller.
-func Restore(st *state.State, setID uint64, snapNames []string, users []string) (snapsFound []string, ts *state.TaskSet, err error) {
+func Restore(st *state.State, setID uint64, snapNames []string, users []string, key *client.SnapshotKey) (snapsFound []string, ts *state.TaskSet, err error) {
 	summaries, err := snapSummariesInSnapshotSet(setID, snapNames)
 	if err != nil {
 		return nil, nil, err
@@ -493,6 +618,9 @@ func Restore(st *state.State, setID uint64, snapNames []string, users []string)
 	ts = state.NewTaskSet()
 
 	for _, summary := range summaries {
+		if summary.encrypted && key == nil {
+			return nil, nil, fmt.Errorf("cannot restore snapshot for %q: %w", summary.snap, client.ErrSnapshotKeyRequired)
+		}
 		var current snap.Revision
 		if snapst, ok := all[summary.snap]; ok {
 			info, err := snapst.CurrentInfo()
@@ -519,8 +647,10 @@ func Restore(st *state.State, setID uint64, snapNames []string, users []string)
 			Users:    users,
 			Filename: summary.filename,
 			Current:  current,
+			KeyGiven: key != nil,
 		}
 		task.Set("snapshot-setup", &snapshot)
+		setSnapshotKey(st, task, key)
 		// see the note about snapshots not using lanes, above.
 		ts.AddTask(task)
 	}
@@ -532,15 +662,20 @@ func Restore(st *state.State, setID uint64, snapNames []string, users []string)
 		desc := fmt.Sprintf("Cleanup after restore from snapshot set #%d", setID)
 		task := st.NewTask("cleanup-after-restore", desc)
 		task.WaitAll(ts)
+		// the snapshot set is kept when moving it fails: in its own
+		// lane the push is not part of the undo chain of the saves
+		task.JoinLane(st.NewLane())
 		ts.AddTask(task)
 	}
 
 	return snapsFound, ts, nil
 }
 
-// Check creates a taskset for checking a snapshot's data.
+// Check creates a taskset for checking a snapshot's data. The content of
+// encrypted snapshots is only checked if their key is given, which is only
+// kept in memory.
 // Note that the state must be locked by the caller.
-func Check(st *state.State, setID uint64, snapNames []string, users []string) (snapsFound []string, ts *state.TaskSet, err error) {
+func Check(st *state.State, setID uint64, snapNames []string, users []string, key *client.SnapshotKey) (snapsFound []string, ts *state.TaskSet, err error) {
 	// check needs to conflict with forget of itself
 	if err := checkSnapshotConflict(st, setID, "forget-snapshot"); err != nil {
 		return nil, nil, err
@@ -561,8 +696,10 @@ func Check(st *state.State, setID uint64, snapNames []string, users []string) (s
 			Snap:     summary.snap,
 			Users:    users,
 			Filename: summary.filename,
+			KeyGiven: key != nil,
 		}
 		task.Set("snapshot-setup", &snapshot)
+		setSnapshotKey(st, task, key)
 		ts.AddTask(task)
 	}
 
@@ -628,15 +765,55 @@ func UnsetSnapshotOpInProgress(st *state.State, setID uint64) string {
 	return op
 }
 
-// Export exports a given snapshot ID
+// setSnapshotKey keeps the key of the snapshot the given task operates on
+// in the state cache, as keys must not be persisted. The state must be
+// locked by the caller.
+func setSnapshotKey(st *state.State, task *state.Task, key *client.SnapshotKey) {
+	if key == nil {
+		return
+	}
+	keys, _ := st.Cached("snapshot-keys").(map[string]*client.SnapshotKey)
+	if keys == nil {
+		keys = make(map[string]*client.SnapshotKey)
+		st.Cache("snapshot-keys", keys)
+	}
+	keys[task.ID()] = key
+}
+
+// snapshotKey returns the key of the snapshot the given task operates on,
+// or nil if there is none. The state must be locked by the caller.
+func snapshotKey(st *state.State, task *state.Task) *client.SnapshotKey {
+	keys, _ := st.Cached("snapshot-keys").(map[string]*client.SnapshotKey)
+	return keys[task.ID()]
+}
+
+// taskSnapshotKey is like snapshotKey, but fails if a key was given for the
+// task and is gone, as happens when snapd restarts before the task runs. The
+// state must be locked by the caller.
+func taskSnapshotKey(st *state.State, task *state.Task, snapshot *snapshotSetup) (*client.SnapshotKey, error) {
+	key := snapshotKey(st, task)
+	if key == nil && snapshot.KeyGiven {
+		return nil, fmt.Errorf("snapshot key required: the key given for snapshot set #%d is not kept across restarts of snapd, try again with the key", snapshot.SetID)
+	}
+	return key, nil
+}
+
+// Export exports a given snapshot ID, encrypting it with the given key if
+// it is not nil.
 // Note that the state must be locked by the caller.
-func Export(ctx context.Context, st *state.State, setID uint64) (se *backend.SnapshotExport, err error) {
+func Export(ctx context.Context, st *state.State, setID uint64, key *client.SnapshotKey) (se *backend.SnapshotExport, err error) {
 	if err := checkSnapshotConflict(st, setID, "forget-snapshot"); err != nil {
 		return nil, err
 	}
 
 	setSnapshotOpInProgress(st, setID, "export-snapshot")
 	se, err = backendNewSnapshotExport(ctx, setID)
+	if err == nil && key != nil {
+		if err = se.Encrypt(key); err != nil {
+			se.Close()
+			se = nil
+		}
+	}
 	if err != nil {
 		UnsetSnapshotOpInProgress(st, setID)
 	}
@@ -35,6 +35,7 @@ import (
 	"time"
 
 	"gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/snapasserts"
@@ -597,6 +598,124 @@ func (s snapshotSuite) TestSaveOneSnap(c *check.C) {
 	})
 }
 
+func (s snapshotSuite) TestSaveToAddsPush(c *check.C) {
+	var s3cfg *backend.S3Config
+	defer snapshotstate.MockBackendOpenStore(func(target string, cfg *backend.S3Config) (backend.SnapshotStore, error) {
+		c.Check(target, check.Equals, "s3://bucket")
+		s3cfg = cfg
+		return backend.NewDirStore(c.MkDir()), nil
+	})()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	snapstate.Set(st, "a-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{
+			{RealName: "a-snap", Revision: snap.R(1)},
+		}),
+		Current: snap.R(1),
+	})
+	tr := config.NewTransaction(st)
+	tr.Set("core", "snapshots.s3.endpoint", "https://s3.example.com")
+	tr.Set("core", "snapshots.s3.access-key-id", "key-id")
+	tr.Set("core", "snapshots.s3.secret-access-key", "secret")
+	tr.Commit()
+
+	setID, saved, taskset, err := snapshotstate.SaveTo(st, []string{"a-snap"}, nil, nil, "s3://bucket")
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(1))
+	c.Check(saved, check.DeepEquals, []string{"a-snap"})
+	// the proxy configured for snapd is used
+	c.Check(s3cfg.Proxy, check.NotNil)
+	s3cfg.Proxy = nil
+	c.Check(s3cfg, check.DeepEquals, &backend.S3Config{
+		Endpoint:        "https://s3.example.com",
+		AccessKeyID:     "key-id",
+		SecretAccessKey: "secret",
+	})
+	tasks := taskset.Tasks()
+	c.Assert(tasks, check.HasLen, 2)
+	c.Check(tasks[0].Kind(), check.Equals, "save-snapshot")
+	c.Check(tasks[1].Kind(), check.Equals, "push-snapshot")
+	c.Check(tasks[1].Summary(), check.Equals, `Move snapshot set #1 to s3://bucket`)
+	c.Check(tasks[1].WaitTasks(), check.DeepEquals, []*state.Task{tasks[0]})
+	// the push is not in the lane of the saves
+	c.Check(tasks[0].Lanes(), check.DeepEquals, []int{0})
+	c.Check(tasks[1].Lanes(), check.DeepEquals, []int{1})
+	var snapshot map[string]any
+	c.Check(tasks[1].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]any{
+		"set-id":  1.,
+		"snap":    "",
+		"current": "unset",
+		"target":  "s3://bucket",
+	})
+}
+
+func (snapshotSuite) TestSaveToPushFailsKeepsSnapshot(c *check.C) {
+	c.Assert(os.MkdirAll(dirs.SnapshotsDir, 0755), check.IsNil)
+
+	o := overlord.Mock()
+	st := o.State()
+
+	stmgr, err := snapstate.Manager(st, o.TaskRunner())
+	c.Assert(err, check.IsNil)
+	o.AddManager(stmgr)
+	shmgr := snapshotstate.Manager(st, o.TaskRunner())
+	o.AddManager(shmgr)
+	o.AddManager(o.TaskRunner())
+
+	st.Lock()
+	defer st.Unlock()
+
+	sideInfo := &snap.SideInfo{RealName: "a-snap", Revision: snap.R(1)}
+	snapstate.Set(st, "a-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{sideInfo}),
+		Current:  sideInfo.Revision,
+		SnapType: "app",
+	})
+	snapInfo := snaptest.MockSnap(c, "{name: a-snap, version: v1}", sideInfo)
+	c.Assert(os.MkdirAll(snapInfo.DataDir(), 0755), check.IsNil)
+
+	// the target has a set with the same name already
+	target := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(target, "1.snapshot"), nil, 0644), check.IsNil)
+
+	setID, _, taskset, err := snapshotstate.SaveTo(st, []string{"a-snap"}, nil, nil, target)
+	c.Assert(err, check.IsNil)
+	chg := st.NewChange("save-snapshot", "...")
+	chg.AddAll(taskset)
+
+	st.Unlock()
+	c.Assert(o.Settle(5*time.Second), check.IsNil)
+	st.Lock()
+
+	c.Check(chg.Err(), check.ErrorMatches, `(?s).*cannot move snapshot set #1 to .*, it is kept on this device: .*already exists in snapshot store.*`)
+	tasks := taskset.Tasks()
+	c.Check(tasks[0].Status(), check.Equals, state.DoneStatus)
+	c.Check(tasks[1].Status(), check.Equals, state.ErrorStatus)
+
+	// the saved snapshot set is still there
+	sets, err := backend.List(context.TODO(), setID, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(sets, check.HasLen, 1)
+	c.Check(sets[0].Snapshots[0].Snap, check.Equals, "a-snap")
+}
+
+func (s snapshotSuite) TestSaveToInvalidTarget(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	_, _, _, err := snapshotstate.SaveTo(st, []string{"a-snap"}, nil, nil, "relative/path")
+	c.Check(err, check.ErrorMatches, `invalid snapshot target "relative/path": .*`)
+	_, _, _, err = snapshotstate.SaveTo(st, []string{"a-snap"}, nil, nil, "s3://bucket")
+	c.Check(err, check.ErrorMatches, `cannot use snapshot target "s3://bucket": no S3 endpoint is configured`)
+}
+
 func (snapshotSuite) TestSaveIntegration(c *check.C) {
 	if os.Geteuid() == 0 {
 		c.Skip("this test cannot run as root (runuser will fail)")
@@ -928,7 +1047,7 @@ func (snapshotSuite) TestRestoreChecksIterError(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	_, _, err := snapshotstate.Restore(st, 42, nil, nil)
+	_, _, err := snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.ErrorMatches, "bzzt")
 }
 
@@ -936,7 +1055,7 @@ func (s snapshotSuite) TestRestoreChecksSnapstateConflicts(c *check.C) {
 	st, restore := s.createConflictingChange(c)
 	defer restore()
 
-	_, _, err := snapshotstate.Restore(st, 42, nil, nil)
+	_, _, err := snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.NotNil)
 	c.Check(err, check.FitsTypeOf, &snapstate.ChangeConflictError{})
 
@@ -993,7 +1112,7 @@ func (snapshotSuite) TestRestoreConflictsWithSnapstate(c *check.C) {
 	})
 
 	chg := st.NewChange("snapshot-restore", "...")
-	_, restoreTasks, err := snapshotstate.Restore(st, 42, nil, nil)
+	_, restoreTasks, err := snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.IsNil)
 	chg.AddAll(restoreTasks)
 
@@ -1025,7 +1144,7 @@ func (snapshotSuite) TestRestoreChecksForgetConflicts(c *check.C) {
 	tsk.Set("snapshot-setup", map[string]int{"set-id": 42})
 	chg.AddTask(tsk)
 
-	_, _, err = snapshotstate.Restore(st, 42, nil, nil)
+	_, _, err = snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.ErrorMatches, `cannot operate on snapshot set #42 while change \"1\" is in progress`)
 }
 
@@ -1060,7 +1179,7 @@ func (snapshotSuite) TestRestoreChecksChangesToSnapID(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	_, _, err = snapshotstate.Restore(st, 42, nil, nil)
+	_, _, err = snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.ErrorMatches, `cannot restore snapshot for "a-snap": current snap \(ID 1234567…\) does not match snapshot \(ID 0987654…\)`)
 }
 
@@ -1101,7 +1220,7 @@ func (snapshotSuite) TestRestoreChecksChangesToEpoch(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	_, _, err = snapshotstate.Restore(st, 42, nil, nil)
+	_, _, err = snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.ErrorMatches, `cannot restore snapshot for "a-snap": current snap \(epoch 17\) cannot read snapshot data \(epoch 42\)`)
 }
 
@@ -1142,7 +1261,7 @@ func (snapshotSuite) TestRestoreWorksWithCompatibleEpoch(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	found, taskset, err := snapshotstate.Restore(st, 42, nil, nil)
+	found, taskset, err := snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.IsNil)
 	c.Check(found, check.DeepEquals, []string{"a-snap"})
 	tasks := taskset.Tasks()
@@ -1179,7 +1298,7 @@ func (snapshotSuite) TestRestore(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	found, taskset, err := snapshotstate.Restore(st, 42, []string{"a-snap", "b-snap"}, []string{"a-user"})
+	found, taskset, err := snapshotstate.Restore(st, 42, []string{"a-snap", "b-snap"}, []string{"a-user"}, nil)
 	c.Assert(err, check.IsNil)
 	c.Check(found, check.DeepEquals, []string{"a-snap"})
 	tasks := taskset.Tasks()
@@ -1198,6 +1317,34 @@ func (snapshotSuite) TestRestore(c *check.C) {
 	})
 }
 
+func (snapshotSuite) TestRestoreEncrypted(c *check.C) {
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "yadda.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+	defer snapshotstate.MockBackendIter(func(_ context.Context, f func(*backend.Reader) error) error {
+		return f(&backend.Reader{
+			Snapshot: client.Snapshot{SetID: 42, Snap: "a-snap", Encryption: &client.SnapshotEncryption{}},
+			File:     shotfile,
+		})
+	})()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	_, _, err = snapshotstate.Restore(st, 42, nil, nil, nil)
+	c.Assert(err, check.ErrorMatches, `cannot restore snapshot for "a-snap": snapshot is encrypted and no key was given`)
+	c.Check(errors.Is(err, client.ErrSnapshotKeyRequired), check.Equals, true)
+
+	key := &client.SnapshotKey{KeyFile: []byte("0123456789abcdef")}
+	_, taskset, err := snapshotstate.Restore(st, 42, nil, nil, key)
+	c.Assert(err, check.IsNil)
+	tasks := taskset.Tasks()
+	c.Assert(tasks, check.HasLen, 2)
+	c.Check(snapshotstate.SnapshotKey(st, tasks[0]), check.Equals, key)
+	c.Check(snapshotstate.SnapshotKey(st, tasks[1]), check.IsNil)
+}
+
 func (snapshotSuite) TestRestoreIntegration(c *check.C) {
 	testRestoreIntegration(c, dirs.UserHomeSnapDir, nil)
 }
@@ -1270,7 +1417,7 @@ func testRestoreIntegration(c *check.C, snapDataDir string, opts *dirs.SnapDirOp
 	// remove b-user's home
 	c.Assert(os.RemoveAll(homedirB), check.IsNil)
 
-	found, taskset, err := snapshotstate.Restore(st, 42, nil, []string{"a-user", "b-user"})
+	found, taskset, err := snapshotstate.Restore(st, 42, nil, []string{"a-user", "b-user"}, nil)
 	c.Assert(err, check.IsNil)
 	sort.Strings(found)
 	c.Check(found, check.DeepEquals, []string{"one-snap", "too-snap", "tri-snap"})
@@ -1350,7 +1497,7 @@ func (snapshotSuite) TestRestoreIntegrationFails(c *check.C) {
 	c.Assert(os.MkdirAll(filepath.Join(homedir, "snap"), 0755), check.IsNil)
 	c.Assert(os.MkdirAll(filepath.Join(homedir, "snap", "too-snap"), 0), check.IsNil)
 
-	found, taskset, err := snapshotstate.Restore(st, 42, nil, []string{"a-user"})
+	found, taskset, err := snapshotstate.Restore(st, 42, nil, []string{"a-user"}, nil)
 	c.Assert(err, check.IsNil)
 	sort.Strings(found)
 	c.Check(found, check.DeepEquals, []string{"one-snap", "too-snap", "tri-snap"})
@@ -1404,7 +1551,7 @@ func (snapshotSuite) TestCheckChecksIterError(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	_, _, err := snapshotstate.Check(st, 42, nil, nil)
+	_, _, err := snapshotstate.Check(st, 42, nil, nil, nil)
 	c.Assert(err, check.ErrorMatches, "bzzt")
 }
 
@@ -1412,7 +1559,7 @@ func (s snapshotSuite) TestCheckDoesNotTriggerSnapstateConflict(c *check.C) {
 	st, restore := s.createConflictingChange(c)
 	defer restore()
 
-	_, _, err := snapshotstate.Check(st, 42, nil, nil)
+	_, _, err := snapshotstate.Check(st, 42, nil, nil, nil)
 	c.Assert(err, check.IsNil)
 }
 
@@ -1440,7 +1587,7 @@ func (snapshotSuite) TestCheckChecksForgetConflicts(c *check.C) {
 	tsk.Set("snapshot-setup", map[string]int{"set-id": 42})
 	chg.AddTask(tsk)
 
-	_, _, err = snapshotstate.Check(st, 42, nil, nil)
+	_, _, err = snapshotstate.Check(st, 42, nil, nil, nil)
 	c.Assert(err, check.ErrorMatches, `cannot operate on snapshot set #42 while change \"1\" is in progress`)
 }
 
@@ -1463,7 +1610,7 @@ func (snapshotSuite) TestCheck(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	found, taskset, err := snapshotstate.Check(st, 42, []string{"a-snap", "b-snap"}, []string{"a-user"})
+	found, taskset, err := snapshotstate.Check(st, 42, []string{"a-snap", "b-snap"}, []string{"a-user"}, nil)
 	c.Assert(err, check.IsNil)
 	c.Check(found, check.DeepEquals, []string{"a-snap"})
 	tasks := taskset.Tasks()
@@ -1481,6 +1628,85 @@ func (snapshotSuite) TestCheck(c *check.C) {
 	})
 }
 
+func (snapshotSuite) TestCheckEncryptedWithKey(c *check.C) {
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "yadda.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+	defer snapshotstate.MockBackendIter(func(_ context.Context, f func(*backend.Reader) error) error {
+		return f(&backend.Reader{
+			Snapshot: client.Snapshot{SetID: 42, Snap: "a-snap", Encryption: &client.SnapshotEncryption{}},
+			File:     shotfile,
+		})
+	})()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	key := &client.SnapshotKey{Passphrase: "sekrit"}
+	_, taskset, err := snapshotstate.Check(st, 42, nil, nil, key)
+	c.Assert(err, check.IsNil)
+	tasks := taskset.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	c.Check(snapshotstate.SnapshotKey(st, tasks[0]), check.Equals, key)
+
+	// the key is not persisted
+	var snapshot map[string]any
+	c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot["key"], check.IsNil)
+
+	// checking without key is possible
+	_, taskset, err = snapshotstate.Check(st, 42, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(snapshotstate.SnapshotKey(st, taskset.Tasks()[0]), check.IsNil)
+}
+
+func (snapshotSuite) TestEncryptedTasksFailWhenKeyIsLost(c *check.C) {
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "yadda.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+	defer snapshotstate.MockBackendIter(func(_ context.Context, f func(*backend.Reader) error) error {
+		return f(&backend.Reader{
+			Snapshot: client.Snapshot{SetID: 42, Snap: "a-snap", Encryption: &client.SnapshotEncryption{}},
+			File:     shotfile,
+		})
+	})()
+	defer snapshotstate.MockBackendOpen(func(string, uint64) (*backend.Reader, error) {
+		return &backend.Reader{
+			Snapshot: client.Snapshot{SetID: 42, Snap: "a-snap", Encryption: &client.SnapshotEncryption{}},
+		}, nil
+	})()
+	defer snapshotstate.MockBackendSetKey(func(*backend.Reader, *client.SnapshotKey) error {
+		c.Fatal("unexpected call to set the key")
+		return nil
+	})()
+	defer snapshotstate.MockBackendCheck(func(*backend.Reader, context.Context, []string) error {
+		c.Fatal("unexpected call to check")
+		return nil
+	})()
+
+	st := state.New(nil)
+	st.Lock()
+	key := &client.SnapshotKey{Passphrase: "sekrit"}
+	_, checkTs, err := snapshotstate.Check(st, 42, nil, nil, key)
+	c.Assert(err, check.IsNil)
+	_, restoreTs, err := snapshotstate.Restore(st, 42, nil, nil, key)
+	c.Assert(err, check.IsNil)
+	chg := st.NewChange("restore-snapshot", "...")
+	chg.AddAll(checkTs)
+	chg.AddAll(restoreTs)
+
+	// snapd restarts before the tasks run, and with it goes the key
+	st.Cache("snapshot-keys", nil)
+	st.Unlock()
+
+	const msg = `snapshot key required: the key given for snapshot set #42 is not kept across restarts of snapd, try again with the key`
+	err = snapshotstate.DoCheck(checkTs.Tasks()[0], &tomb.Tomb{})
+	c.Check(err, check.ErrorMatches, msg)
+	err = snapshotstate.DoRestore(restoreTs.Tasks()[0], &tomb.Tomb{})
+	c.Check(err, check.ErrorMatches, msg)
+}
+
 func (snapshotSuite) TestForgetChecksIterError(c *check.C) {
 	defer snapshotstate.MockBackendIter(func(context.Context, func(*backend.Reader) error) error {
 		return errors.New("bzzt")
@@ -1850,12 +2076,37 @@ func (snapshotSuite) TestImportSnapshotHappy(c *check.C) {
 	})
 	defer restore()
 
-	sid, names, err := snapshotstate.Import(context.TODO(), st, buf)
+	sid, names, err := snapshotstate.Import(context.TODO(), st, buf, nil)
 	c.Assert(err, check.IsNil)
 	c.Check(sid, check.Equals, uint64(1))
 	c.Check(names, check.DeepEquals, fakeSnapNames)
 }
 
+func (snapshotSuite) TestFetch(c *check.C) {
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "12.snapshot"), []byte("fake-export-data"), 0644), check.IsNil)
+
+	restore := snapshotstate.MockBackendImport(func(ctx context.Context, id uint64, r io.Reader, flags *backend.ImportFlags) ([]string, error) {
+		d, err := io.ReadAll(r)
+		c.Assert(err, check.IsNil)
+		c.Check(string(d), check.Equals, "fake-export-data")
+		return []string{"foo"}, nil
+	})
+	defer restore()
+
+	st := state.New(nil)
+	setID, names, err := snapshotstate.Fetch(context.TODO(), st, dir, 12, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(1))
+	c.Check(names, check.DeepEquals, []string{"foo"})
+
+	_, _, err = snapshotstate.Fetch(context.TODO(), st, dir, 13, nil)
+	c.Check(err, check.Equals, client.ErrSnapshotSetNotFound)
+
+	_, _, err = snapshotstate.Fetch(context.TODO(), st, "s3://bucket", 12, nil)
+	c.Check(err, check.ErrorMatches, `cannot use snapshot target "s3://bucket": no S3 endpoint is configured`)
+}
+
 func (snapshotSuite) TestImportSnapshotImportError(c *check.C) {
 	st := state.New(nil)
 
@@ -1865,7 +2116,7 @@ func (snapshotSuite) TestImportSnapshotImportError(c *check.C) {
 	defer restore()
 
 	r := bytes.NewBufferString("faked-import-data")
-	sid, _, err := snapshotstate.Import(context.TODO(), st, r)
+	sid, _, err := snapshotstate.Import(context.TODO(), st, r, nil)
 	c.Assert(err, check.NotNil)
 	c.Assert(err.Error(), check.Equals, "some-error")
 	c.Check(sid, check.Equals, uint64(0))
@@ -1886,7 +2137,7 @@ func (snapshotSuite) TestImportSnapshotDuplicate(c *check.C) {
 	})
 	st.Unlock()
 
-	sid, snapNames, err := snapshotstate.Import(context.TODO(), st, bytes.NewBufferString(""))
+	sid, snapNames, err := snapshotstate.Import(context.TODO(), st, bytes.NewBufferString(""), nil)
 	c.Assert(err, check.IsNil)
 	c.Check(sid, check.Equals, uint64(3))
 	c.Check(snapNames, check.DeepEquals, []string{"foo-snap"})
@@ -2004,7 +2255,7 @@ func (snapshotSuite) TestExportSnapshotConflictsWithForget(c *check.C) {
 	tsk.Set("snapshot-setup", map[string]int{"set-id": 42})
 	chg.AddTask(tsk)
 
-	_, err := snapshotstate.Export(context.TODO(), st, 42)
+	_, err := snapshotstate.Export(context.TODO(), st, 42, nil)
 	c.Assert(err, check.NotNil)
 	c.Assert(err.Error(), check.Equals, `cannot operate on snapshot set #42 while change "1" is in progress`)
 }
@@ -2020,7 +2271,7 @@ func (snapshotSuite) TestImportSnapshotDuplicatedNoConflict(c *check.C) {
 	defer restore()
 
 	st := state.New(nil)
-	setID, snaps, err := snapshotstate.Import(context.TODO(), st, buf)
+	setID, snaps, err := snapshotstate.Import(context.TODO(), st, buf, nil)
 	c.Check(importCalls, check.Equals, 1)
 	c.Assert(err, check.IsNil)
 	c.Check(setID, check.Equals, uint64(42))
@@ -2060,7 +2311,7 @@ func (snapshotSuite) TestImportSnapshotConflictsWithForget(c *check.C) {
 	chg.AddTask(tsk)
 
 	st.Unlock()
-	setID, snaps, err := snapshotstate.Import(context.TODO(), st, buf)
+	setID, snaps, err := snapshotstate.Import(context.TODO(), st, buf, nil)
 	st.Lock()
 	c.Check(importCalls, check.Equals, 2)
 	c.Assert(err, check.IsNil)
@@ -2078,7 +2329,7 @@ func (snapshotSuite) TestExportSnapshotSetsOpInProgress(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	_, err := snapshotstate.Export(context.TODO(), st, 42)
+	_, err := snapshotstate.Export(context.TODO(), st, 42, nil)
 	c.Assert(err, check.IsNil)
 
 	ops := st.Cached("snapshot-ops")
@@ -139,6 +139,7 @@ type autoRefresh struct {
 	state *state.State
 
 	lastRefreshSchedule string
+	lastRefreshWindows  string
 	nextRefresh         time.Time
 	lastRefreshAttempt  time.Time
 
@@ -338,15 +339,30 @@ func (m *autoRefresh) Ensure() (err error) {
 		m.nextRefresh = time.Time{}
 		return nil
 	}
+
+	// auto-refreshes are also attempted when the refresh windows open
+	windows, err := getRefreshWindows(m.state)
+	if err != nil {
+		return err
+	}
+	refreshWindowsStr := refreshWindowsString(windows)
+	for _, window := range windows {
+		refreshSchedule = append(refreshSchedule, window.sched...)
+	}
+
 	// we already have a refresh time, check if we got a new config
 	if !m.nextRefresh.IsZero() {
 		if m.lastRefreshSchedule != refreshScheduleStr {
 			// the refresh schedule has changed
 			logger.Debugf("Refresh timer changed.")
 			m.nextRefresh = time.Time{}
+		} else if m.lastRefreshWindows != refreshWindowsStr {
+			logger.Debugf("Refresh windows changed.")
+			m.nextRefresh = time.Time{}
 		}
 	}
 	m.lastRefreshSchedule = refreshScheduleStr
+	m.lastRefreshWindows = refreshWindowsStr
 
 	// ensure nothing is in flight already
 	if autoRefreshInFlight(m.state) {
@@ -378,6 +394,12 @@ func (m *autoRefresh) Ensure() (err error) {
 		return err
 	}
 
+	// the next wave of a staged auto-refresh doesn't wait for the schedule
+	staged, err := stagedAutoRefreshToContinue(m.state)
+	if err != nil {
+		return err
+	}
+
 	// do refresh attempt (if needed)
 	if !held {
 		if !holdTime.IsZero() {
@@ -396,7 +418,7 @@ func (m *autoRefresh) Ensure() (err error) {
 		// !After() because that is true in the case that the next refresh is
 		// before now, and the next refresh is equal to now without requiring an
 		// or operation
-		if !m.nextRefresh.After(now) {
+		if staged != nil || !m.nextRefresh.After(now) {
 			var can bool
 			can, err = m.canRefreshRespectingMetered(now, lastRefresh)
 			if err != nil {
@@ -408,7 +430,7 @@ func (m *autoRefresh) Ensure() (err error) {
 				return nil
 			}
 
-			err = m.launchAutoRefresh()
+			err = m.launchAutoRefresh(staged)
 			if _, ok := err.(*httputil.PersistentNetworkError); ok {
 				// refresh will be retried after refreshRetryDelay
 				return err
@@ -595,8 +617,10 @@ func (tooSoonError) Is(err error) bool {
 	return ok
 }
 
-// launchAutoRefresh creates the auto-refresh taskset and a change for it.
-func (m *autoRefresh) launchAutoRefresh() error {
+// launchAutoRefresh creates the auto-refresh taskset and a change for it. If
+// refresh waves are configured, only the first wave with updates is
+// refreshed, or the next wave of the given staged auto-refresh.
+func (m *autoRefresh) launchAutoRefresh(staged *stagedAutoRefresh) error {
 	// Check that we have reasonable delays between attempts.
 	// If the store is under stress we need to make sure we do not
 	// hammer it too often
@@ -615,7 +639,7 @@ func (m *autoRefresh) launchAutoRefresh() error {
 	}()
 
 	// NOTE: this will unlock and re-lock state for network ops
-	updated, updateTss, err := AutoRefresh(auth.EnsureContextTODO(), m.state)
+	updated, updateTss, pendingWaves, err := m.autoRefreshWave(auth.EnsureContextTODO(), staged)
 
 	// TODO: we should have some way to lock just creating and starting changes,
 	//       as that would alleviate this race condition we are guarding against
@@ -646,6 +670,10 @@ func (m *autoRefresh) launchAutoRefresh() error {
 		return err
 	}
 
+	// the following waves are refreshed once this one is done
+	nextWaves := &stagedAutoRefresh{Waves: pendingWaves}
+	setStagedAutoRefresh(m.state, nextWaves)
+
 	if _, err = createPreDownloadChange(m.state, updateTss); err != nil {
 		return err
 	}
@@ -671,6 +699,10 @@ func (m *autoRefresh) launchAutoRefresh() error {
 	for _, ts := range updateTss.Refresh {
 		chg.AddAll(ts)
 	}
+	if len(nextWaves.Waves) > 0 {
+		nextWaves.Change = chg.ID()
+		setStagedAutoRefresh(m.state, nextWaves)
+	}
 	chg.Set("snap-names", updated)
 	chg.Set("api-data", map[string]any{"snap-names": updated})
 	state.TagTimingsWithChange(perfTimings, chg)
@@ -800,6 +832,13 @@ func maxInhibitionDuration(st *state.State) time.Duration {
 // took place. Apps can inhibit refreshes for up to "maxInhibition", beyond
 // that period the refresh will go ahead despite application activity.
 func inhibitRefresh(st *state.State, snapst *SnapState, snapsup *SnapSetup, info *snap.Info) (inhibitionTimeout bool, err error) {
+	return checkRefreshInhibition(st, snapst, snapsup, info, true)
+}
+
+// checkRefreshInhibition implements inhibitRefresh. If record is false,
+// the state is left alone and no notification is sent, as for refreshes
+// that are only planned.
+func checkRefreshInhibition(st *state.State, snapst *SnapState, snapsup *SnapSetup, info *snap.Info, record bool) (inhibitionTimeout bool, err error) {
 	checkerErr := refreshAppsCheck(info)
 	if checkerErr == nil {
 		return false, nil
@@ -825,9 +864,11 @@ func inhibitRefresh(st *state.State, snapst *SnapState, snapsup *SnapSetup, info
 		// window, during which refreshes are postponed, by storing the current
 		// time in the snap state's RefreshInhibitedTime field. This field is
 		// reset to nil on successful refresh.
-		snapst.RefreshInhibitedTime = &now
-		busyErr.timeRemaining = (maxInhibitionDurationValue - now.Sub(*snapst.RefreshInhibitedTime)).Truncate(time.Second)
-		Set(st, info.InstanceName(), snapst)
+		busyErr.timeRemaining = maxInhibitionDurationValue.Truncate(time.Second)
+		if record {
+			snapst.RefreshInhibitedTime = &now
+			Set(st, info.InstanceName(), snapst)
+		}
 	case now.Sub(*snapst.RefreshInhibitedTime) < maxInhibitionDurationValue:
 		// If we are still in the allowed window then just return the error but
 		// don't change the snap state again.
@@ -838,8 +879,10 @@ func inhibitRefresh(st *state.State, snapst *SnapState, snapsup *SnapSetup, info
 		// XXX: should we drop this notification?
 		// if the refresh inhibition window has ended, notify the user that the
 		// refresh is happening now and ignore the error
-		refreshInfo := busyErr.PendingSnapRefreshInfo()
-		maybeAsyncPendingRefreshNotification(context.TODO(), st, refreshInfo)
+		if record {
+			refreshInfo := busyErr.PendingSnapRefreshInfo()
+			maybeAsyncPendingRefreshNotification(context.TODO(), st, refreshInfo)
+		}
 		// important to return "nil" type here instead of
 		// setting busyErr to nil as otherwise we return a nil
 		// interface which is not the nil type
@@ -0,0 +1,400 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/timeutil"
+)
+
+// Auto-refreshes can be staged with the system options
+//
+//	refresh.windows.<snap>
+//	refresh.waves
+//
+// A refresh window uses the same syntax as refresh.timer and restricts when
+// the snap is auto-refreshed: an auto-refresh is also attempted when one of
+// the windows opens, and snaps are only refreshed within their windows. Snaps
+// without a window are refreshed by any auto-refresh.
+//
+// The refresh waves are a ";" separated list of waves, each a "," separated
+// list of snap names, "@<type>" for the snaps of a type or "*" for all the
+// other snaps, for example "*;@kernel,@gadget" to refresh the kernel and
+// gadget last. Snaps not matched by any wave are in the first one. The waves
+// with updates are refreshed one after the other by separate auto-refresh
+// changes, each started once the previous one succeeded.
+
+// refreshWaveTypes are the snap types that can be used in refresh waves.
+var refreshWaveTypes = []snap.Type{
+	snap.TypeApp, snap.TypeGadget, snap.TypeKernel, snap.TypeBase, snap.TypeOS, snap.TypeSnapd,
+}
+
+type refreshWave struct {
+	names []string
+	types []snap.Type
+	rest  bool
+}
+
+type refreshWaves []refreshWave
+
+// ValidateRefreshWaves checks that the given refresh.waves value is valid.
+func ValidateRefreshWaves(wavesStr string) error {
+	_, err := parseRefreshWaves(wavesStr)
+	return err
+}
+
+func parseRefreshWaves(wavesStr string) (refreshWaves, error) {
+	if wavesStr == "" {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var waves refreshWaves
+	for i, waveStr := range strings.Split(wavesStr, ";") {
+		entries := strutil.CommaSeparatedList(waveStr)
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("cannot parse refresh waves %q: wave %d is empty", wavesStr, i+1)
+		}
+
+		var wave refreshWave
+		for _, entry := range entries {
+			if seen[entry] {
+				return nil, fmt.Errorf("cannot parse refresh waves %q: %q is in more than one wave", wavesStr, entry)
+			}
+			seen[entry] = true
+
+			switch {
+			case entry == "*":
+				wave.rest = true
+			case strings.HasPrefix(entry, "@"):
+				typ := snap.Type(strings.TrimPrefix(entry, "@"))
+				if !refreshWaveTypeKnown(typ) {
+					return nil, fmt.Errorf("cannot parse refresh waves %q: unknown snap type %q", wavesStr, typ)
+				}
+				wave.types = append(wave.types, typ)
+			default:
+				if err := snap.ValidateInstanceName(entry); err != nil {
+					return nil, fmt.Errorf("cannot parse refresh waves %q: %v", wavesStr, err)
+				}
+				wave.names = append(wave.names, entry)
+			}
+		}
+		waves = append(waves, wave)
+	}
+	return waves, nil
+}
+
+func refreshWaveTypeKnown(typ snap.Type) bool {
+	for _, known := range refreshWaveTypes {
+		if typ == known {
+			return true
+		}
+	}
+	return false
+}
+
+// index returns the index of the wave of the given snap. Snap names take
+// precedence over types, which take precedence over "*".
+func (waves refreshWaves) index(name string, typ snap.Type) int {
+	byType, rest := -1, -1
+	for i, wave := range waves {
+		if strutil.ListContains(wave.names, name) {
+			return i
+		}
+		if byType < 0 {
+			for _, waveType := range wave.types {
+				if waveType == typ {
+					byType = i
+					break
+				}
+			}
+		}
+		if rest < 0 && wave.rest {
+			rest = i
+		}
+	}
+	switch {
+	case byType >= 0:
+		return byType
+	case rest >= 0:
+		return rest
+	default:
+		return 0
+	}
+}
+
+// getRefreshWaves returns the configured refresh waves, if any.
+func getRefreshWaves(st *state.State) (wavesStr string, waves refreshWaves, err error) {
+	tr := config.NewTransaction(st)
+	if err := tr.Get("core", "refresh.waves", &wavesStr); err != nil && !config.IsNoOption(err) {
+		return "", nil, err
+	}
+
+	waves, err = parseRefreshWaves(wavesStr)
+	if err != nil {
+		// log instead of fail in order not to prevent auto-refreshes
+		logger.Noticef("cannot use refresh.waves configuration: %v", err)
+		return "", nil, nil
+	}
+	return wavesStr, waves, nil
+}
+
+// RefreshWindow is the refresh window of a snap.
+type RefreshWindow struct {
+	Snap string
+	// Window is the refresh.windows.<snap> setting.
+	Window string
+	// Next is the start of the next window.
+	Next time.Time
+}
+
+type refreshWindow struct {
+	window string
+	sched  []*timeutil.Schedule
+}
+
+// getRefreshWindows returns the configured refresh windows keyed by snap
+// name. Invalid windows are logged and ignored, they cannot be set in the
+// first place.
+func getRefreshWindows(st *state.State) (map[string]refreshWindow, error) {
+	var cfg map[string]any
+	tr := config.NewTransaction(st)
+	if err := tr.Get("core", "refresh.windows", &cfg); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+
+	windows := make(map[string]refreshWindow, len(cfg))
+	for snapName, v := range cfg {
+		window, ok := v.(string)
+		if !ok || window == "" {
+			continue
+		}
+		sched, err := timeutil.ParseSchedule(window)
+		if err != nil {
+			logger.Noticef("cannot use refresh window of snap %q: %v", snapName, err)
+			continue
+		}
+		windows[snapName] = refreshWindow{window: window, sched: sched}
+	}
+	return windows, nil
+}
+
+// refreshWindowsString returns a string representation of the windows which
+// changes whenever any of them does.
+func refreshWindowsString(windows map[string]refreshWindow) string {
+	entries := make([]string, 0, len(windows))
+	for snapName, window := range windows {
+		entries = append(entries, snapName+"="+window.window)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ";")
+}
+
+// RefreshWindows returns the refresh windows of the snaps which have one,
+// sorted by snap name.
+func (m *autoRefresh) RefreshWindows() ([]RefreshWindow, error) {
+	windows, err := getRefreshWindows(m.state)
+	if err != nil {
+		return nil, err
+	}
+
+	now := timeNow()
+	res := make([]RefreshWindow, 0, len(windows))
+	for snapName, window := range windows {
+		var next time.Time
+		for _, sched := range window.sched {
+			start := sched.Next(now).Start
+			if next.IsZero() || start.Before(next) {
+				next = start
+			}
+		}
+		res = append(res, RefreshWindow{Snap: snapName, Window: window.window, Next: next})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Snap < res[j].Snap })
+	return res, nil
+}
+
+// RefreshWaves returns the refresh.waves setting and the snaps with updates
+// in each of the waves of the current staged auto-refresh which are still to
+// be refreshed.
+func (m *autoRefresh) RefreshWaves() (wavesStr string, pending [][]string, err error) {
+	wavesStr, _, err = getRefreshWaves(m.state)
+	if err != nil {
+		return "", nil, err
+	}
+	staged, err := getStagedAutoRefresh(m.state)
+	if err != nil {
+		return "", nil, err
+	}
+	if staged != nil {
+		pending = staged.Waves
+	}
+	return wavesStr, pending, nil
+}
+
+// stagedAutoRefresh is the state of an auto-refresh whose waves are still
+// being refreshed.
+type stagedAutoRefresh struct {
+	// Change is the ID of the auto-refresh change of the previous wave, if
+	// one was created.
+	Change string `json:"change,omitempty"`
+	// Waves holds the names of the snaps with updates in each of the waves
+	// still to be refreshed.
+	Waves [][]string `json:"waves"`
+}
+
+func getStagedAutoRefresh(st *state.State) (*stagedAutoRefresh, error) {
+	var staged stagedAutoRefresh
+	if err := st.Get("staged-auto-refresh", &staged); err != nil {
+		if errors.Is(err, state.ErrNoState) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &staged, nil
+}
+
+func setStagedAutoRefresh(st *state.State, staged *stagedAutoRefresh) {
+	if staged == nil || len(staged.Waves) == 0 {
+		st.Set("staged-auto-refresh", nil)
+		return
+	}
+	st.Set("staged-auto-refresh", staged)
+}
+
+// stagedAutoRefreshToContinue returns the staged auto-refresh whose next
+// wave can be refreshed, if any. A staged auto-refresh is dropped if the
+// change of its previous wave didn't succeed, the snaps of the next waves are
+// then refreshed by the next scheduled auto-refresh.
+func stagedAutoRefreshToContinue(st *state.State) (*stagedAutoRefresh, error) {
+	staged, err := getStagedAutoRefresh(st)
+	if err != nil || staged == nil {
+		return nil, err
+	}
+
+	if staged.Change != "" {
+		chg := st.Change(staged.Change)
+		if chg != nil && !chg.IsReady() {
+			return nil, nil
+		}
+		// the change may have been pruned already, which only happens once
+		// it's ready
+		if chg != nil && chg.Status() != state.DoneStatus {
+			logger.Noticef("Cannot continue staged auto-refresh: change %s did not succeed", chg.ID())
+			setStagedAutoRefresh(st, nil)
+			return nil, nil
+		}
+	}
+	return staged, nil
+}
+
+// autoRefreshWave creates the tasks to refresh the snaps of the first
+// refresh wave with updates or, if staged is set, of its next wave. Snaps
+// outside of their refresh window are not refreshed. It returns the snaps
+// with updates in each of the following waves, to be refreshed once this
+// one is done.
+// The state needs to be locked by the caller, it will be unlocked and
+// re-locked for network operations.
+func (m *autoRefresh) autoRefreshWave(ctx context.Context, staged *stagedAutoRefresh) (updated []string, updateTss *UpdateTaskSets, pending [][]string, err error) {
+	windows, err := getRefreshWindows(m.state)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	_, waves, err := getRefreshWaves(m.state)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(windows) == 0 && len(waves) == 0 && staged == nil {
+		updated, updateTss, err = AutoRefresh(ctx, m.state)
+		return updated, updateTss, nil, err
+	}
+
+	now := timeNow()
+	inWindow := func(name string) bool {
+		window, ok := windows[name]
+		return !ok || timeutil.Includes(window.sched, now)
+	}
+
+	if staged != nil {
+		pending = staged.Waves
+	} else {
+		// query the updates of all waves, refreshing the first one
+		byWave := make(map[int][]string)
+		updated, updateTss, err = autoRefreshFiltered(ctx, m.state, func(info *snap.Info, snapst *SnapState) bool {
+			name := info.InstanceName()
+			if !inWindow(name) {
+				return false
+			}
+			wave := waves.index(name, info.Type())
+			byWave[wave] = append(byWave[wave], name)
+			return wave == 0
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		indexes := make([]int, 0, len(byWave))
+		for wave := range byWave {
+			indexes = append(indexes, wave)
+		}
+		sort.Ints(indexes)
+		for _, wave := range indexes {
+			names := byWave[wave]
+			sort.Strings(names)
+			pending = append(pending, names)
+		}
+		if len(indexes) == 0 || indexes[0] == 0 {
+			// the first wave had updates, if any
+			if len(pending) > 0 {
+				pending = pending[1:]
+			}
+			return updated, updateTss, pending, nil
+		}
+	}
+
+	// refresh the next wave with updates, the snaps of a wave can have no
+	// refresh anymore if they were held or refreshed in the meantime
+	for len(pending) > 0 {
+		wave := pending[0]
+		pending = pending[1:]
+		updated, updateTss, err = autoRefreshFiltered(ctx, m.state, func(info *snap.Info, snapst *SnapState) bool {
+			name := info.InstanceName()
+			return strutil.ListContains(wave, name) && inWindow(name)
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if len(updated) > 0 || len(updateTss.PreDownload) > 0 {
+			break
+		}
+	}
+	return updated, updateTss, pending, nil
+}
@@ -0,0 +1,248 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+func (s *autoRefreshTestSuite) setCoreConfig(c *C, key string, value any) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", key, value), IsNil)
+	tr.Commit()
+}
+
+func autoRefreshSnapNames(c *C, st *state.State) [][]string {
+	var names [][]string
+	for _, chg := range st.Changes() {
+		if chg.Kind() != "auto-refresh" {
+			continue
+		}
+		var snapNames []string
+		c.Assert(chg.Get("snap-names", &snapNames), IsNil)
+		names = append(names, snapNames)
+	}
+	return names
+}
+
+func setChangeStatus(chg *state.Change, status state.Status) {
+	for _, t := range chg.Tasks() {
+		t.SetStatus(status)
+	}
+}
+
+func (s *autoRefreshTestSuite) TestValidateRefreshWaves(c *C) {
+	for _, valid := range []string{
+		"",
+		"foo",
+		"foo,bar;baz",
+		"*;@kernel,@gadget",
+		"@snapd;foo_instance, bar ;*",
+	} {
+		c.Check(snapstate.ValidateRefreshWaves(valid), IsNil, Commentf("%q", valid))
+	}
+
+	for _, tc := range []struct {
+		waves, err string
+	}{
+		{"foo;;bar", `cannot parse refresh waves "foo;;bar": wave 2 is empty`},
+		{"foo;", `cannot parse refresh waves "foo;": wave 2 is empty`},
+		{"foo;foo", `cannot parse refresh waves "foo;foo": "foo" is in more than one wave`},
+		{"*;*", `cannot parse refresh waves "\*;\*": "\*" is in more than one wave`},
+		{"@potato", `cannot parse refresh waves "@potato": unknown snap type "potato"`},
+		{"Foo", `cannot parse refresh waves "Foo": invalid snap name: "Foo"`},
+	} {
+		c.Check(snapstate.ValidateRefreshWaves(tc.waves), ErrorMatches, tc.err, Commentf("%q", tc.waves))
+	}
+}
+
+func (s *autoRefreshTestSuite) TestAutoRefreshWaves(c *C) {
+	restore := snapstate.MockRefreshRetryDelay(0)
+	defer restore()
+
+	s.addRefreshableSnap("foo", "bar", "baz")
+	// baz isn't in any wave so it's in the first one
+	s.setCoreConfig(c, "refresh.waves", "foo;bar")
+
+	af := snapstate.NewAutoRefresh(s.state)
+	c.Assert(af.Ensure(), IsNil)
+
+	s.state.Lock()
+	c.Check(autoRefreshSnapNames(c, s.state), DeepEquals, [][]string{{"baz", "foo"}})
+	chg := s.state.Changes()[0]
+
+	wavesStr, pending, err := af.RefreshWaves()
+	c.Assert(err, IsNil)
+	c.Check(wavesStr, Equals, "foo;bar")
+	c.Check(pending, DeepEquals, [][]string{{"bar"}})
+
+	// nothing happens until the first wave is done
+	s.state.Unlock()
+	c.Assert(af.Ensure(), IsNil)
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 1)
+
+	setChangeStatus(chg, state.DoneStatus)
+	s.state.Unlock()
+
+	// the next wave doesn't wait for the refresh schedule
+	c.Assert(af.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(autoRefreshSnapNames(c, s.state), HasLen, 2)
+	for _, chg := range s.state.Changes() {
+		if chg.Status() != state.DoneStatus {
+			var names []string
+			c.Assert(chg.Get("snap-names", &names), IsNil)
+			c.Check(names, DeepEquals, []string{"bar"})
+		}
+	}
+
+	_, pending, err = af.RefreshWaves()
+	c.Assert(err, IsNil)
+	c.Check(pending, HasLen, 0)
+	c.Check(s.store.ops, DeepEquals, []string{"list-refresh", "list-refresh"})
+}
+
+func (s *autoRefreshTestSuite) TestAutoRefreshWavesFirstWaveWithoutUpdates(c *C) {
+	s.addRefreshableSnap("bar", "baz")
+	s.setCoreConfig(c, "refresh.waves", "foo;bar;baz")
+
+	af := snapstate.NewAutoRefresh(s.state)
+	c.Assert(af.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the store is queried again for the first wave with updates
+	c.Check(s.store.ops, DeepEquals, []string{"list-refresh", "list-refresh"})
+	c.Check(autoRefreshSnapNames(c, s.state), DeepEquals, [][]string{{"bar"}})
+
+	_, pending, err := af.RefreshWaves()
+	c.Assert(err, IsNil)
+	c.Check(pending, DeepEquals, [][]string{{"baz"}})
+}
+
+func (s *autoRefreshTestSuite) TestAutoRefreshWavesTypes(c *C) {
+	s.addRefreshableSnap("foo", "bar")
+	s.setCoreConfig(c, "refresh.waves", "*;@app;bar")
+
+	af := snapstate.NewAutoRefresh(s.state)
+	c.Assert(af.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// foo is in the @app wave, bar in its own which comes after it
+	c.Check(autoRefreshSnapNames(c, s.state), DeepEquals, [][]string{{"foo"}})
+	_, pending, err := af.RefreshWaves()
+	c.Assert(err, IsNil)
+	c.Check(pending, DeepEquals, [][]string{{"bar"}})
+}
+
+func (s *autoRefreshTestSuite) TestAutoRefreshWavesPreviousWaveFailed(c *C) {
+	restore := snapstate.MockRefreshRetryDelay(0)
+	defer restore()
+
+	s.addRefreshableSnap("foo", "bar")
+	s.setCoreConfig(c, "refresh.waves", "foo;bar")
+
+	af := snapstate.NewAutoRefresh(s.state)
+	c.Assert(af.Ensure(), IsNil)
+
+	s.state.Lock()
+	c.Assert(s.state.Changes(), HasLen, 1)
+	setChangeStatus(s.state.Changes()[0], state.ErrorStatus)
+	s.state.Unlock()
+
+	c.Assert(af.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the staged auto-refresh is dropped, the next one is scheduled as usual
+	c.Check(s.state.Changes(), HasLen, 1)
+	_, pending, err := af.RefreshWaves()
+	c.Assert(err, IsNil)
+	c.Check(pending, HasLen, 0)
+	c.Check(af.NextRefresh().After(time.Now()), Equals, true)
+}
+
+func (s *autoRefreshTestSuite) TestAutoRefreshWindows(c *C) {
+	// a Wednesday
+	now := time.Date(2025, time.October, 15, 12, 0, 0, 0, time.Local)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.addRefreshableSnap("foo", "bar", "baz")
+	s.setCoreConfig(c, "refresh.windows.foo", "sun,23:00-01:00")
+	s.setCoreConfig(c, "refresh.windows.bar", "wed,11:00-13:00")
+
+	af := snapstate.NewAutoRefresh(s.state)
+	c.Assert(af.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// foo is outside of its window
+	c.Check(autoRefreshSnapNames(c, s.state), DeepEquals, [][]string{{"bar", "baz"}})
+
+	windows, err := af.RefreshWindows()
+	c.Assert(err, IsNil)
+	c.Assert(windows, HasLen, 2)
+	c.Check(windows[0].Snap, Equals, "bar")
+	c.Check(windows[0].Window, Equals, "wed,11:00-13:00")
+	c.Check(windows[1].Snap, Equals, "foo")
+	c.Check(windows[1].Window, Equals, "sun,23:00-01:00")
+	c.Check(windows[1].Next.Weekday(), Equals, time.Sunday)
+	c.Check(windows[1].Next.Hour(), Equals, 23)
+}
+
+func (s *autoRefreshTestSuite) TestAutoRefreshWindowsSchedule(c *C) {
+	weekdays := []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	today := int(time.Now().Weekday())
+	timerDay := time.Weekday((today + 4) % 7)
+	windowDay := time.Weekday((today + 2) % 7)
+	s.setCoreConfig(c, "refresh.timer", weekdays[timerDay]+",10:00-11:00")
+
+	af := snapstate.NewAutoRefresh(s.state)
+	s.state.Lock()
+	s.state.Set("last-refresh", time.Now())
+	s.state.Unlock()
+	c.Assert(af.Ensure(), IsNil)
+	next := af.NextRefresh()
+	c.Check(next.Weekday(), Equals, timerDay)
+
+	// the next refresh is also attempted when a window opens
+	s.setCoreConfig(c, "refresh.windows.some-snap", weekdays[windowDay]+",20:00-21:00")
+	c.Assert(af.Ensure(), IsNil)
+	c.Check(af.NextRefresh().Before(next), Equals, true)
+	c.Check(af.NextRefresh().Weekday(), Equals, windowDay)
+}
@@ -196,7 +196,10 @@ type fakeStore struct {
 	fakeCurrentProgress int
 	fakeTotalProgress   int
 	// snap -> error map for simulating download errors
-	downloadError   map[string]error
+	downloadError map[string]error
+	// downloadBytes is the size of the simulated downloads, accounted
+	// for in the download budget
+	downloadBytes   int64
 	state           *state.State
 	seenPrivacyKeys map[string]bool
 
@@ -937,6 +940,14 @@ func (f *fakeStore) Download(ctx context.Context, name, targetFn string, snapInf
 		pb.Set(float64(f.fakeCurrentProgress))
 	}
 
+	if dlOpts != nil && dlOpts.Budget != nil && f.downloadBytes > 0 {
+		if remaining := dlOpts.Budget.Remaining(); remaining < f.downloadBytes {
+			dlOpts.Budget.Consume(remaining)
+			return store.ErrDownloadBudgetExhausted
+		}
+		dlOpts.Budget.Consume(f.downloadBytes)
+	}
+
 	if e, ok := f.downloadError[name]; ok {
 		return e
 	}
@@ -0,0 +1,303 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/timeutil"
+)
+
+// The downloads of auto-refreshes, including pre-downloads, are limited
+// by the system options
+//
+//	refresh.rate-limit
+//	refresh.rate-schedule
+//	refresh.daily-download-limit
+//
+// The rate schedule is a ";" separated list of <timer>=<rate> entries, where
+// <timer> uses the syntax of refresh.timer and <rate> is a byte size per
+// second or "unlimited", for example "01:00-05:00=unlimited;9:00-17:00=20KB".
+// The first entry whose timer includes the current time sets the rate,
+// refresh.rate-limit applies outside of all of them.
+//
+// The daily download limit is the number of bytes that can be downloaded
+// for auto-refreshes each day. Downloads reaching it are paused until the
+// next day, when they resume from where they stopped. The downloads running
+// at the same time share the budget of the day, which is kept by the
+// SnapManager.
+
+type rateScheduleEntry struct {
+	sched []*timeutil.Schedule
+	limit int64
+}
+
+// ValidateRefreshRateSchedule checks that the given refresh.rate-schedule
+// value is valid.
+func ValidateRefreshRateSchedule(scheduleStr string) error {
+	_, err := parseRateSchedule(scheduleStr)
+	return err
+}
+
+func parseRateSchedule(scheduleStr string) ([]rateScheduleEntry, error) {
+	if scheduleStr == "" {
+		return nil, nil
+	}
+
+	var entries []rateScheduleEntry
+	for _, entryStr := range strings.Split(scheduleStr, ";") {
+		entryStr = strings.TrimSpace(entryStr)
+		idx := strings.LastIndex(entryStr, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("cannot parse rate schedule %q: %q is not <timer>=<rate>", scheduleStr, entryStr)
+		}
+		sched, err := timeutil.ParseSchedule(entryStr[:idx])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse rate schedule %q: %v", scheduleStr, err)
+		}
+		var limit int64
+		if rate := entryStr[idx+1:]; rate != "unlimited" {
+			limit, err = strutil.ParseByteSize(rate)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse rate schedule %q: %v", scheduleStr, err)
+			}
+			if limit == 0 {
+				return nil, fmt.Errorf(`cannot parse rate schedule %q: rate cannot be 0, use "unlimited" for no limit`, scheduleStr)
+			}
+		}
+		entries = append(entries, rateScheduleEntry{sched: sched, limit: limit})
+	}
+	return entries, nil
+}
+
+// rateSchedule implements store.RateScheduler for refresh.rate-schedule.
+type rateSchedule struct {
+	entries []rateScheduleEntry
+	// fallback is the refresh.rate-limit that applies outside the entries
+	fallback int64
+}
+
+func (rs *rateSchedule) RateLimit(now time.Time) int64 {
+	for _, entry := range rs.entries {
+		if timeutil.Includes(entry.sched, now) {
+			return entry.limit
+		}
+	}
+	return rs.fallback
+}
+
+// downloadUsage is the number of bytes downloaded for auto-refreshes on a
+// given day.
+type downloadUsage struct {
+	Day   string `json:"day"`
+	Bytes int64  `json:"bytes"`
+}
+
+func day(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// nextDay returns the start of the day after the given time.
+func nextDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+}
+
+func getDownloadUsage(st *state.State) (*downloadUsage, error) {
+	var usage downloadUsage
+	if err := st.Get("auto-refresh-download-usage", &usage); err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// downloadBudget implements store.DownloadBudget for
+// refresh.daily-download-limit.
+type downloadBudget struct {
+	day string
+
+	mu        sync.Mutex
+	limit     int64
+	remaining int64
+	// consumed is what was downloaded and not yet recorded in the state
+	consumed int64
+}
+
+func (b *downloadBudget) Remaining() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.remaining
+}
+
+func (b *downloadBudget) Consume(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remaining -= n
+	b.consumed += n
+}
+
+// setLimit changes the limit of the budget, keeping what was used of it.
+func (b *downloadBudget) setLimit(limit int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remaining += limit - b.limit
+	b.limit = limit
+}
+
+// exhausted returns whether there is a budget and it's been used up.
+func (b *downloadBudget) exhausted() bool {
+	return b != nil && b.Remaining() <= 0
+}
+
+// downloadBudget returns the budget left today by the daily download
+// limit, or nil if there is no limit. The same budget is returned to all
+// the downloads of a day, so that concurrent ones cannot go over it.
+func (m *SnapManager) downloadBudget(st *state.State) (*downloadBudget, error) {
+	var limitStr string
+	tr := config.NewTransaction(st)
+	if err := tr.GetMaybe("core", "refresh.daily-download-limit", &limitStr); err != nil {
+		return nil, err
+	}
+	if limitStr == "" {
+		return nil, nil
+	}
+	limit, err := strutil.ParseByteSize(limitStr)
+	if err != nil {
+		logger.Noticef("cannot use daily download limit: %v", err)
+		return nil, nil
+	}
+
+	m.downloadBudgetMu.Lock()
+	defer m.downloadBudgetMu.Unlock()
+	today := day(timeNow())
+	if b := m.dailyDownloadBudget; b != nil && b.day == today {
+		b.setLimit(limit)
+		return b, nil
+	}
+
+	usage, err := getDownloadUsage(st)
+	if err != nil {
+		return nil, err
+	}
+	b := &downloadBudget{day: today, limit: limit, remaining: limit}
+	if usage.Day == b.day {
+		b.remaining -= usage.Bytes
+	}
+	m.dailyDownloadBudget = b
+	return b, nil
+}
+
+// account records the bytes downloaded within the budget, if any, and turns
+// its exhaustion into a retry of the task the next day.
+func (b *downloadBudget) account(t *state.Task, err error) error {
+	if b == nil {
+		return err
+	}
+	st := t.State()
+
+	b.mu.Lock()
+	consumed := b.consumed
+	b.consumed = 0
+	b.mu.Unlock()
+
+	usage, uerr := getDownloadUsage(st)
+	if uerr != nil {
+		return uerr
+	}
+	// what is downloaded after midnight counts for the day the download
+	// started, unless the usage of the next day was already recorded
+	if usage.Day < b.day {
+		usage = &downloadUsage{Day: b.day}
+	}
+	if usage.Day == b.day {
+		usage.Bytes += consumed
+		st.Set("auto-refresh-download-usage", usage)
+	}
+
+	if errors.Is(err, store.ErrDownloadBudgetExhausted) {
+		return b.pause(t)
+	}
+	return err
+}
+
+func accountDownloadUnlocked(t *state.Task, budget *downloadBudget, err error) error {
+	if budget == nil {
+		return err
+	}
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+	return budget.account(t, err)
+}
+
+// pause logs that the download of the task is paused by the daily download
+// limit and returns a retry of the task the next day.
+func (b *downloadBudget) pause(t *state.Task) error {
+	now := timeNow()
+	next := nextDay(now)
+	t.Logf("Daily download limit reached, download paused until %s", next.Format(time.RFC3339))
+	return &state.Retry{After: next.Sub(now), Reason: "daily download limit reached"}
+}
+
+// autoRefreshDownloadOptions returns the options of the downloads made for
+// auto-refreshes, along with the budget of the download if the daily
+// download limit is set. The budget needs to be accounted for once the
+// download is over.
+func (m *SnapManager) autoRefreshDownloadOptions(st *state.State) (*store.DownloadOptions, *downloadBudget, error) {
+	dlOpts := &store.DownloadOptions{
+		Scheduled: true,
+		// NOTE rate is never negative
+		RateLimit: autoRefreshRateLimited(st),
+	}
+
+	var scheduleStr string
+	tr := config.NewTransaction(st)
+	if err := tr.GetMaybe("core", "refresh.rate-schedule", &scheduleStr); err != nil {
+		return nil, nil, err
+	}
+	entries, err := parseRateSchedule(scheduleStr)
+	if err != nil {
+		logger.Noticef("cannot use refresh rate schedule: %v", err)
+	}
+	if len(entries) > 0 {
+		dlOpts.RateSchedule = &rateSchedule{entries: entries, fallback: dlOpts.RateLimit}
+	}
+
+	budget, err := m.downloadBudget(st)
+	if err != nil {
+		return nil, nil, err
+	}
+	if budget != nil {
+		dlOpts.Budget = budget
+		// what was downloaded until the budget ran out is kept to be
+		// resumed
+		dlOpts.LeavePartialOnError = true
+	}
+	return dlOpts, budget, nil
+}
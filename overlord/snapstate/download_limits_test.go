@@ -0,0 +1,360 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *downloadSnapSuite) setupAutoRefreshDownload(c *C, conf map[string]any) *state.Task {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	for k, v := range conf {
+		tr.Set("core", k, v)
+	}
+	tr.Commit()
+
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "foo-id",
+			Revision: snap.R(11),
+		},
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+		},
+		Flags: snapstate.Flags{
+			IsAutoRefresh: true,
+		},
+	})
+	s.state.NewChange("sample", "...").AddTask(t)
+	return t
+}
+
+func (s *downloadSnapSuite) TestDoDownloadRateSchedule(c *C) {
+	s.setupAutoRefreshDownload(c, map[string]any{
+		"refresh.rate-limit":    "1234B",
+		"refresh.rate-schedule": "01:00-05:00=unlimited;9:00-17:00=20KB",
+	})
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	c.Assert(s.fakeStore.downloads, HasLen, 1)
+	opts := s.fakeStore.downloads[0].opts
+	c.Assert(opts, NotNil)
+	c.Check(opts.Scheduled, Equals, true)
+	c.Check(opts.RateLimit, Equals, int64(1234))
+	c.Check(opts.Budget, IsNil)
+	c.Check(opts.LeavePartialOnError, Equals, false)
+	c.Assert(opts.RateSchedule, NotNil)
+
+	at := func(clock string) time.Time {
+		t, err := time.ParseInLocation("2006-01-02 15:04", "2026-10-14 "+clock, time.Local)
+		c.Assert(err, IsNil)
+		return t
+	}
+	c.Check(opts.RateSchedule.RateLimit(at("02:00")), Equals, int64(0))
+	c.Check(opts.RateSchedule.RateLimit(at("10:30")), Equals, int64(20000))
+	c.Check(opts.RateSchedule.RateLimit(at("20:00")), Equals, int64(1234))
+}
+
+func (s *downloadSnapSuite) TestDoDownloadDailyLimit(c *C) {
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.Local)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	t := s.setupAutoRefreshDownload(c, map[string]any{
+		"refresh.daily-download-limit": "1000B",
+	})
+	s.state.Lock()
+	s.state.Set("auto-refresh-download-usage", map[string]any{"day": "2026-10-14", "bytes": 400})
+	s.state.Unlock()
+	s.fakeStore.downloadBytes = 300
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(t.Status(), Equals, state.DoneStatus)
+
+	c.Assert(s.fakeStore.downloads, HasLen, 1)
+	opts := s.fakeStore.downloads[0].opts
+	c.Assert(opts, NotNil)
+	c.Check(opts.Budget, NotNil)
+	c.Check(opts.LeavePartialOnError, Equals, true)
+
+	var usage map[string]any
+	c.Assert(s.state.Get("auto-refresh-download-usage", &usage), IsNil)
+	c.Check(usage, DeepEquals, map[string]any{"day": "2026-10-14", "bytes": 700.0})
+}
+
+func (s *downloadSnapSuite) TestDoDownloadDailyLimitReached(c *C) {
+	now := time.Date(2026, 10, 14, 22, 0, 0, 0, time.Local)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	t := s.setupAutoRefreshDownload(c, map[string]any{
+		"refresh.daily-download-limit": "1000B",
+	})
+	s.state.Lock()
+	// the usage of a previous day does not count
+	s.state.Set("auto-refresh-download-usage", map[string]any{"day": "2026-10-13", "bytes": 1000})
+	s.state.Unlock()
+	s.fakeStore.downloadBytes = 1500
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	// the download is paused until the next day
+	c.Check(t.Status(), Equals, state.DoingStatus)
+	c.Check(t.AtTime().IsZero(), Equals, false)
+	c.Assert(t.Log(), HasLen, 1)
+	next := time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local)
+	c.Check(t.Log()[0], testutil.Contains, "Daily download limit reached, download paused until "+next.Format(time.RFC3339))
+	c.Assert(s.fakeStore.downloads, HasLen, 1)
+
+	var usage map[string]any
+	c.Assert(s.state.Get("auto-refresh-download-usage", &usage), IsNil)
+	c.Check(usage, DeepEquals, map[string]any{"day": "2026-10-14", "bytes": 1000.0})
+}
+
+func (s *downloadSnapSuite) TestDoDownloadDailyLimitParallel(c *C) {
+	now := time.Date(2026, 10, 14, 22, 0, 0, 0, time.Local)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	t1 := s.setupAutoRefreshDownload(c, map[string]any{
+		"refresh.daily-download-limit": "1000B",
+	})
+	s.state.Lock()
+	t2 := s.state.NewTask("download-snap", "test")
+	t2.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "bar",
+			SnapID:   "bar-id",
+			Revision: snap.R(3),
+		},
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/bar",
+		},
+		Flags: snapstate.Flags{
+			IsAutoRefresh: true,
+		},
+	})
+	s.state.NewChange("other", "...").AddTask(t2)
+	s.state.Unlock()
+	s.fakeStore.downloadBytes = 600
+
+	// both downloads start before any of them is accounted for
+	var started sync.WaitGroup
+	started.Add(2)
+	s.fakeStore.downloadCallback = func() {
+		started.Done()
+		started.Wait()
+	}
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(s.fakeStore.downloads, HasLen, 2)
+
+	// they shared the budget, so only one could complete
+	var statuses []state.Status
+	for _, t := range []*state.Task{t1, t2} {
+		statuses = append(statuses, t.Status())
+	}
+	c.Check(statuses, testutil.DeepUnsortedMatches, []state.Status{state.DoneStatus, state.DoingStatus})
+
+	var usage map[string]any
+	c.Assert(s.state.Get("auto-refresh-download-usage", &usage), IsNil)
+	c.Check(usage, DeepEquals, map[string]any{"day": "2026-10-14", "bytes": 1000.0})
+}
+
+func (s *downloadSnapSuite) TestDoDownloadDailyLimitChanged(c *C) {
+	now := time.Date(2026, 10, 14, 22, 0, 0, 0, time.Local)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	t := s.setupAutoRefreshDownload(c, map[string]any{
+		"refresh.daily-download-limit": "1000B",
+	})
+	s.fakeStore.downloadBytes = 1500
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	c.Check(t.Status(), Equals, state.DoingStatus)
+	// raising the limit lets the download resume the same day
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.daily-download-limit", "2000B")
+	tr.Commit()
+	t.At(time.Time{})
+	s.fakeStore.downloadBytes = 500
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(t.Status(), Equals, state.DoneStatus)
+
+	var usage map[string]any
+	c.Assert(s.state.Get("auto-refresh-download-usage", &usage), IsNil)
+	c.Check(usage, DeepEquals, map[string]any{"day": "2026-10-14", "bytes": 1500.0})
+}
+
+func (s *downloadSnapSuite) TestDoDownloadDailyLimitAlreadyReached(c *C) {
+	now := time.Date(2026, 10, 14, 22, 0, 0, 0, time.Local)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	t := s.setupAutoRefreshDownload(c, map[string]any{
+		"refresh.daily-download-limit": "1000B",
+	})
+	s.state.Lock()
+	s.state.Set("auto-refresh-download-usage", map[string]any{"day": "2026-10-14", "bytes": 1000})
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(t.Status(), Equals, state.DoingStatus)
+	c.Assert(t.Log(), HasLen, 1)
+	c.Check(t.Log()[0], testutil.Contains, "Daily download limit reached")
+	// nothing was downloaded
+	c.Check(s.fakeStore.downloads, HasLen, 0)
+}
+
+func (s *downloadSnapSuite) TestDoDownloadDailyLimitNotAutoRefresh(c *C) {
+	s.state.Lock()
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.daily-download-limit", "1000B")
+	tr.Commit()
+	s.state.Set("auto-refresh-download-usage", map[string]any{"day": time.Now().Format("2006-01-02"), "bytes": 1000})
+
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "foo-id",
+			Revision: snap.R(11),
+		},
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+		},
+	})
+	s.state.NewChange("sample", "...").AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	// downloads asked for by the user are not limited
+	c.Check(t.Status(), Equals, state.DoneStatus)
+	c.Assert(s.fakeStore.downloads, HasLen, 1)
+	c.Check(s.fakeStore.downloads[0].opts, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestPreDownloadDailyLimitReached(c *C) {
+	now := time.Date(2026, 10, 14, 22, 0, 0, 0, time.Local)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.daily-download-limit", "1000B")
+	tr.Commit()
+	s.fakeStore.downloadBytes = 1500
+	s.fakeStore.fakeTotalProgress = 1500
+	s.fakeStore.fakeCurrentProgress = 1000
+
+	si := &snap.SideInfo{
+		RealName: "foo",
+		SnapID:   "foo-id",
+		Revision: snap.R(1),
+	}
+	snaptest.MockSnap(c, `name: foo`, si)
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active:   true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{si}),
+		Current:  si.Revision,
+	})
+
+	preDlChg := s.state.NewChange("pre-download", "pre-download change")
+	preDlTask := s.state.NewTask("pre-download-snap", "pre-download task")
+	preDlTask.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			Revision: snap.R(2),
+		},
+		Flags:        snapstate.Flags{IsAutoRefresh: true},
+		DownloadInfo: &snap.DownloadInfo{DownloadURL: "my-url"},
+	})
+	preDlChg.AddTask(preDlTask)
+
+	s.state.Unlock()
+	s.se.Ensure()
+	s.se.Wait()
+	s.state.Lock()
+
+	// the pre-download is paused until the next day, and resumes from
+	// where it stopped
+	c.Check(preDlTask.Status(), Equals, state.DoingStatus)
+	c.Assert(preDlTask.Log(), HasLen, 1)
+	c.Check(preDlTask.Log()[0], testutil.Contains, "Daily download limit reached")
+	c.Assert(s.fakeStore.downloads, HasLen, 1)
+	opts := s.fakeStore.downloads[0].opts
+	c.Check(opts.Scheduled, Equals, true)
+	c.Check(opts.LeavePartialOnError, Equals, true)
+
+	// its progress is reported
+	label, done, total := preDlTask.Progress()
+	c.Check(label, Equals, "")
+	c.Check(done, Equals, 1000)
+	c.Check(total, Equals, 1500)
+}
@@ -601,3 +601,11 @@ type CustomInstallGoal struct {
 func (c *CustomInstallGoal) toInstall(ctx context.Context, st *state.State, opts Options) ([]Target, error) {
 	return c.ToInstall(ctx, st, opts)
 }
+
+func MockInactiveServices(f func(info *snap.Info) ([]string, error)) (restore func()) {
+	return testutil.Mock(&inactiveServices, f)
+}
+
+func MockHealthRollbackRetryInterval(d time.Duration) (restore func()) {
+	return testutil.Mock(&healthRollbackRetryInterval, d)
+}
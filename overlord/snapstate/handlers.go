@@ -758,20 +758,23 @@ func maybeCloudName(st *state.State) (name string, err error) {
 
 func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	st := t.State()
-	var rate int64
 	var cloud string
 
 	st.Lock()
 	perfTimings := state.TimingsForTask(t)
 	snapsup, theStore, user, err := downloadSnapParams(st, t)
-	if snapsup != nil && snapsup.IsAutoRefresh {
-		// NOTE rate is never negative
-		rate = autoRefreshRateLimited(st)
+	dlOpts := &store.DownloadOptions{}
+	var budget *downloadBudget
+	if err == nil && snapsup.IsAutoRefresh {
+		dlOpts, budget, err = m.autoRefreshDownloadOptions(st)
 	}
 
 	if err == nil {
 		cloud, err = maybeCloudName(st)
 	}
+	if err == nil && budget.exhausted() {
+		err = budget.pause(t)
+	}
 	st.Unlock()
 
 	if err != nil {
@@ -787,10 +790,6 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	targetIconFn := backend.IconDownloadFilename(snapsup.SideInfo.SnapID)
 	iconURL := snapsup.Media.IconURL()
 
-	dlOpts := &store.DownloadOptions{
-		Scheduled: snapsup.IsAutoRefresh,
-		RateLimit: rate,
-	}
 	if snapsup.DownloadInfo == nil {
 		vsets, err := EnforcedValidationSets(st)
 		if err != nil {
@@ -817,6 +816,7 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 		timings.Run(perfTimings, "download", fmt.Sprintf("download snap %q", snapsup.SnapName()), func(timings.Measurer) {
 			err = theStore.Download(tomb.Context(nil), snapsup.SnapName(), targetFn, &result.DownloadInfo, meter, user, dlOpts)
 		})
+		err = accountDownloadUnlocked(t, budget, err)
 		snapsup.SideInfo = &result.SideInfo
 		if err != nil {
 			return err
@@ -826,6 +826,7 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 		timings.Run(perfTimings, "download", fmt.Sprintf("download snap %q", snapsup.SnapName()), func(timings.Measurer) {
 			err = theStore.Download(ctx, snapsup.SnapName(), targetFn, snapsup.DownloadInfo, meter, user, dlOpts)
 		})
+		err = accountDownloadUnlocked(t, budget, err)
 		if err != nil {
 			return err
 		}
@@ -902,18 +903,25 @@ func (m *SnapManager) doPreDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	}
 
 	targetFn := snapsup.BlobPath()
-	dlOpts := &store.DownloadOptions{
-		// pre-downloads are only triggered in auto-refreshes
-		Scheduled: true,
-		RateLimit: autoRefreshRateLimited(st),
+	// pre-downloads are only triggered in auto-refreshes
+	dlOpts, budget, err := m.autoRefreshDownloadOptions(st)
+	if err != nil {
+		return err
+	}
+	// pre-downloads resume from where they stopped, also across reboots
+	dlOpts.LeavePartialOnError = true
+	if budget.exhausted() {
+		return budget.pause(t)
 	}
 
+	meter := NewTaskProgressAdapterUnlocked(t)
 	perfTimings := state.TimingsForTask(t)
 	st.Unlock()
 	timings.Run(perfTimings, "pre-download", fmt.Sprintf("pre-download snap %q", snapsup.SnapName()), func(timings.Measurer) {
-		err = theStore.Download(tomb.Context(nil), snapsup.SnapName(), targetFn, snapsup.DownloadInfo, nil, user, dlOpts)
+		err = theStore.Download(tomb.Context(nil), snapsup.SnapName(), targetFn, snapsup.DownloadInfo, meter, user, dlOpts)
 	})
 	st.Lock()
+	err = budget.account(t, err)
 	if err != nil {
 		return err
 	}
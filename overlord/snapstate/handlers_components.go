@@ -186,9 +186,16 @@ func (m *SnapManager) doDownloadComponent(t *state.Task, tomb *tomb.Tomb) error
 		return fmt.Errorf("cannot get user for user ID %d: %w", snapsup.UserID, err)
 	}
 
-	var rate int64
+	opts := &store.DownloadOptions{}
+	var budget *downloadBudget
 	if snapsup.IsAutoRefresh {
-		rate = autoRefreshRateLimited(st)
+		opts, budget, err = m.autoRefreshDownloadOptions(st)
+		if err != nil {
+			return err
+		}
+		if budget.exhausted() {
+			return budget.pause(t)
+		}
 	}
 
 	target := compsup.BlobPath(snapsup.InstanceName())
@@ -200,15 +207,13 @@ func (m *SnapManager) doDownloadComponent(t *state.Task, tomb *tomb.Tomb) error
 	st.Unlock()
 	timings.Run(perf, "download", fmt.Sprintf("download component %q", compsup.ComponentName()), func(timings.Measurer) {
 		compRef := compsup.CompSideInfo.Component.String()
-		opts := &store.DownloadOptions{
-			Scheduled: snapsup.IsAutoRefresh,
-			RateLimit: rate,
-		}
-
 		err = sto.Download(tomb.Context(nil), compRef, target, compsup.DownloadInfo, meter, user, opts)
 	})
 	st.Lock()
-	if err != nil {
+	if err = budget.account(t, err); err != nil {
+		if _, ok := err.(*state.Retry); ok {
+			return err
+		}
 		return fmt.Errorf("cannot download component %q: %w", compsup.ComponentName(), err)
 	}
 
@@ -0,0 +1,188 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/systemd"
+)
+
+// Snaps opt into being reverted when unhealthy after an auto-refresh either
+// by being listed in the refresh.health-rollback system option, or with
+//
+//	refresh:
+//	  health-rollback: true
+//
+// in their snap.yaml. The refresh.health-rollback-window system option
+// sets how long the snap has to become healthy.
+
+const defaultHealthRollbackWindow = 5 * time.Minute
+
+var healthRollbackRetryInterval = 10 * time.Second
+
+// SnapHealth returns the health status last reported by the given revision
+// of the snap, one of "unknown", "okay", "waiting", "blocked" or "error",
+// and its message.
+var SnapHealth = func(st *state.State, snapName string, rev snap.Revision) (status, message string, err error) {
+	panic("internal error: snapstate.SnapHealth is unset")
+}
+
+// inactiveServices returns the services of the snap which should be running
+// but are not. Services started on demand, or which are not meant to keep
+// running, are not looked at.
+var inactiveServices = func(info *snap.Info) ([]string, error) {
+	var units []string
+	for _, app := range info.Services() {
+		if app.DaemonScope != snap.SystemDaemon || app.Daemon == "oneshot" ||
+			app.Timer != nil || len(app.Sockets) > 0 || len(app.ActivatesOn) > 0 {
+			continue
+		}
+		units = append(units, app.ServiceName())
+	}
+	if len(units) == 0 {
+		return nil, nil
+	}
+	sts, err := systemd.New(systemd.SystemMode, progress.Null).Status(units)
+	if err != nil {
+		return nil, err
+	}
+	var inactive []string
+	for _, st := range sts {
+		// services disabled by the user are not expected to be running
+		if st.Enabled && !st.Active {
+			inactive = append(inactive, st.Name)
+		}
+	}
+	return inactive, nil
+}
+
+// healthRollbackEnabled returns whether the snap opted into being reverted
+// when unhealthy after an auto-refresh.
+func healthRollbackEnabled(st *state.State, snapName string, info *snap.Info) bool {
+	if info != nil && info.RefreshHealthRollback {
+		return true
+	}
+	var snaps string
+	if err := config.NewTransaction(st).Get("core", "refresh.health-rollback", &snaps); err != nil && !config.IsNoOption(err) {
+		logger.Noticef("cannot get refresh.health-rollback system option: %v", err)
+		return false
+	}
+	return strutil.ListContains(strutil.CommaSeparatedList(snaps), snapName)
+}
+
+func healthRollbackWindow(st *state.State) time.Duration {
+	var window string
+	if err := config.NewTransaction(st).Get("core", "refresh.health-rollback-window", &window); err != nil && !config.IsNoOption(err) {
+		logger.Noticef("cannot get refresh.health-rollback-window system option: %v", err)
+	}
+	if window == "" {
+		return defaultHealthRollbackWindow
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		logger.Noticef("invalid refresh.health-rollback-window system option %q, using the default", window)
+		return defaultHealthRollbackWindow
+	}
+	return d
+}
+
+// doCheckRefreshHealth watches over a snap just refreshed to, failing, and
+// so reverting the refresh, if the snap reports an error health status, or
+// if its services are not all active by the end of the window.
+func (m *SnapManager) doCheckRefreshHealth(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	snapsup, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+
+	var since time.Time
+	if err := t.Get("health-check-since", &since); err != nil {
+		if !errors.Is(err, state.ErrNoState) {
+			return err
+		}
+		since = timeNow()
+		t.Set("health-check-since", since)
+	}
+	deadline := since.Add(healthRollbackWindow(st))
+
+	status, message, err := SnapHealth(st, snapsup.InstanceName(), info.Revision)
+	if err != nil {
+		return err
+	}
+	if status == "error" {
+		reason := "snap reported an error health status"
+		if message != "" {
+			reason += fmt.Sprintf(" (%s)", message)
+		}
+		return healthRollback(t, snapsup, reason)
+	}
+
+	st.Unlock()
+	inactive, err := inactiveServices(info)
+	st.Lock()
+	if err != nil {
+		// not being able to tell is no reason to revert
+		logger.Noticef("cannot get the status of the services of snap %q: %v", snapsup.InstanceName(), err)
+	}
+
+	now := timeNow()
+	if now.Before(deadline) {
+		// snaps which never report their health, like those without
+		// a check-health hook, are healthy once their services are
+		if len(inactive) == 0 && err == nil && (status == "okay" || status == "unknown") {
+			return nil
+		}
+		return &state.Retry{After: healthRollbackRetryInterval}
+	}
+	if len(inactive) > 0 {
+		return healthRollback(t, snapsup, fmt.Sprintf("services %s did not become active within %s",
+			strutil.Quoted(inactive), deadline.Sub(since)))
+	}
+	return nil
+}
+
+// healthRollback records a warning about the snap being reverted, and
+// returns the error that makes the refresh be undone.
+func healthRollback(t *state.Task, snapsup *SnapSetup, reason string) error {
+	st := t.State()
+	t.Logf("Reverting the refresh: %s", reason)
+	st.AddWarning(fmt.Sprintf("snap %q was reverted after its automatic refresh to revision %s: %s",
+		snapsup.InstanceName(), snapsup.Revision(), reason), nil)
+	return fmt.Errorf("snap %q is not healthy after refresh: %s", snapsup.InstanceName(), reason)
+}
@@ -0,0 +1,269 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/healthstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) setUpHealthRollback(c *C) *snapstate.SnapState {
+	s.AddCleanup(snapstate.MockRefreshAppsCheck(func(*snap.Info) error { return nil }))
+
+	si := &snap.SideInfo{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)}
+	snapst := &snapstate.SnapState{
+		Active:   true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{si}),
+		Current:  si.Revision,
+		SnapType: string(snap.TypeApp),
+	}
+	snapstate.Set(s.state, "some-snap", snapst)
+	return snapst
+}
+
+func (s *snapmgrTestSuite) TestDoInstallAutoRefreshChecksHealth(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapst := s.setUpHealthRollback(c)
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.health-rollback", "other-snap,some-snap")
+	tr.Commit()
+
+	snapsup := snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(2)},
+		Flags:    snapstate.Flags{IsAutoRefresh: true},
+	}
+	ts, err := snapstate.DoInstall(s.state, snapst, snapsup, nil, 0, "", inUseCheck, nil)
+	c.Assert(err, IsNil)
+
+	tasks := ts.Tasks()
+	last := tasks[len(tasks)-1]
+	c.Check(last.Kind(), Equals, "check-refresh-health")
+	c.Check(last.Summary(), Equals, `Check health of snap "some-snap" (2) after refresh`)
+	c.Assert(last.WaitTasks(), HasLen, 1)
+	c.Check(last.WaitTasks()[0].Kind(), Equals, "run-hook")
+	endEdge, err := ts.Edge(snapstate.EndEdge)
+	c.Assert(err, IsNil)
+	c.Check(endEdge, Equals, last)
+	snapsupTask, err := ts.Edge(snapstate.SnapSetupEdge)
+	c.Assert(err, IsNil)
+	var snapsupTaskID string
+	c.Assert(last.Get("snap-setup-task", &snapsupTaskID), IsNil)
+	c.Check(snapsupTaskID, Equals, snapsupTask.ID())
+
+	// manual refreshes are not checked
+	snapsup.Flags.IsAutoRefresh = false
+	ts, err = snapstate.DoInstall(s.state, snapst, snapsup, nil, 0, "", inUseCheck, nil)
+	c.Assert(err, IsNil)
+	for _, t := range ts.Tasks() {
+		c.Check(t.Kind(), Not(Equals), "check-refresh-health")
+	}
+}
+
+func (s *snapmgrTestSuite) TestDoInstallAutoRefreshChecksHealthNotEnabled(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapst := s.setUpHealthRollback(c)
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.health-rollback", "other-snap")
+	tr.Commit()
+
+	snapsup := snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(2)},
+		Flags:    snapstate.Flags{IsAutoRefresh: true},
+	}
+	ts, err := snapstate.DoInstall(s.state, snapst, snapsup, nil, 0, "", inUseCheck, nil)
+	c.Assert(err, IsNil)
+	for _, t := range ts.Tasks() {
+		c.Check(t.Kind(), Not(Equals), "check-refresh-health")
+	}
+}
+
+func (s *snapmgrTestSuite) TestDoInstallAutoRefreshChecksHealthDeclared(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapst := s.setUpHealthRollback(c)
+	s.AddCleanup(snapstate.MockSnapReadInfo(func(name string, si *snap.SideInfo) (*snap.Info, error) {
+		info, err := s.fakeBackend.ReadInfo(name, si)
+		if err != nil {
+			return nil, err
+		}
+		info.RefreshHealthRollback = true
+		return info, nil
+	}))
+
+	snapsup := snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(2)},
+		Flags:    snapstate.Flags{IsAutoRefresh: true},
+	}
+	ts, err := snapstate.DoInstall(s.state, snapst, snapsup, nil, 0, "", inUseCheck, nil)
+	c.Assert(err, IsNil)
+	tasks := ts.Tasks()
+	c.Check(tasks[len(tasks)-1].Kind(), Equals, "check-refresh-health")
+}
+
+// runCheckRefreshHealth runs a check-refresh-health task for some-snap
+// refreshed from revision 1 to 2.
+func (s *snapmgrTestSuite) runCheckRefreshHealth(c *C, window string) *state.Change {
+	si1 := &snap.SideInfo{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)}
+	si2 := &snap.SideInfo{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(2)}
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{si1, si2}),
+		Current:  si2.Revision,
+		SnapType: string(snap.TypeApp),
+	})
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.health-rollback-window", window)
+	tr.Commit()
+
+	chg := s.state.NewChange("auto-refresh", "...")
+	t := s.state.NewTask("check-refresh-health", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: si2,
+		Flags:    snapstate.Flags{IsAutoRefresh: true},
+	})
+	chg.AddTask(t)
+
+	s.settle(c)
+	return chg
+}
+
+func (s *snapmgrTestSuite) TestCheckRefreshHealthHealthy(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.AddCleanup(snapstate.MockInactiveServices(func(info *snap.Info) ([]string, error) {
+		c.Check(info.Revision, Equals, snap.R(2))
+		return nil, nil
+	}))
+	s.state.Set("health", map[string]*healthstate.HealthState{
+		"some-snap": {Revision: snap.R(2), Status: healthstate.OkayStatus, Timestamp: time.Now()},
+	})
+
+	// healthy snaps are not watched over for the whole window
+	chg := s.runCheckRefreshHealth(c, "1h")
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestCheckRefreshHealthNeverReported(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.AddCleanup(snapstate.MockInactiveServices(func(info *snap.Info) ([]string, error) {
+		return nil, nil
+	}))
+
+	// snaps without a check-health hook are done once their services
+	// are active
+	chg := s.runCheckRefreshHealth(c, "1h")
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestCheckRefreshHealthWaiting(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.AddCleanup(snapstate.MockHealthRollbackRetryInterval(time.Millisecond))
+	calls := 0
+	s.AddCleanup(snapstate.MockInactiveServices(func(info *snap.Info) ([]string, error) {
+		calls++
+		return nil, nil
+	}))
+	s.state.Set("health", map[string]*healthstate.HealthState{
+		"some-snap": {Revision: snap.R(2), Status: healthstate.WaitingStatus, Timestamp: time.Now()},
+	})
+
+	// a snap which is not ready yet is watched over for the whole window
+	chg := s.runCheckRefreshHealth(c, "50ms")
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(calls > 1, Equals, true)
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestCheckRefreshHealthError(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.AddCleanup(snapstate.MockInactiveServices(func(info *snap.Info) ([]string, error) {
+		return nil, nil
+	}))
+	s.state.Set("health", map[string]*healthstate.HealthState{
+		"some-snap": {Revision: snap.R(2), Status: healthstate.ErrorStatus, Message: "database is gone", Timestamp: time.Now()},
+	})
+
+	chg := s.runCheckRefreshHealth(c, "1h")
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*snap "some-snap" is not healthy after refresh: snap reported an error health status \(database is gone\).*`)
+	warnings := s.state.AllWarnings()
+	c.Assert(warnings, HasLen, 1)
+	c.Check(warnings[0].String(), Equals, `snap "some-snap" was reverted after its automatic refresh to revision 2: snap reported an error health status (database is gone)`)
+}
+
+func (s *snapmgrTestSuite) TestCheckRefreshHealthIgnoresOtherRevisions(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.AddCleanup(snapstate.MockHealthRollbackRetryInterval(time.Millisecond))
+	s.AddCleanup(snapstate.MockInactiveServices(func(info *snap.Info) ([]string, error) {
+		return nil, nil
+	}))
+	// what the previous revision reported does not count
+	s.state.Set("health", map[string]*healthstate.HealthState{
+		"some-snap": {Revision: snap.R(1), Status: healthstate.ErrorStatus, Timestamp: time.Now()},
+	})
+
+	chg := s.runCheckRefreshHealth(c, "50ms")
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestCheckRefreshHealthInactiveServices(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.AddCleanup(snapstate.MockHealthRollbackRetryInterval(time.Millisecond))
+	calls := 0
+	s.AddCleanup(snapstate.MockInactiveServices(func(info *snap.Info) ([]string, error) {
+		calls++
+		return []string{"snap.some-snap.svc.service"}, nil
+	}))
+
+	chg := s.runCheckRefreshHealth(c, "50ms")
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*snap "some-snap" is not healthy after refresh: services "snap.some-snap.svc.service" did not become active within 50ms.*`)
+	c.Check(calls > 1, Equals, true)
+	warnings := s.state.AllWarnings()
+	c.Assert(warnings, HasLen, 1)
+	c.Check(warnings[0].String(), Matches, `snap "some-snap" was reverted after its automatic refresh to revision 2: services .* did not become active within 50ms`)
+}
@@ -215,3 +215,13 @@ func softCheckNothingRunningForRefresh(st *state.State, snapst *SnapState, snaps
 		return err
 	})
 }
+
+// checkNothingRunningForPlannedRefresh is like
+// softCheckNothingRunningForRefresh but leaves the state alone, for refreshes
+// that are only planned.
+func checkNothingRunningForPlannedRefresh(st *state.State, snapst *SnapState, snapsup *SnapSetup, info *snap.Info) error {
+	return backend.WithSnapLock(info, func() error {
+		_, err := checkRefreshInhibition(st, snapst, snapsup, info, false)
+		return err
+	})
+}
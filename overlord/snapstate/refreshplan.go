@@ -0,0 +1,265 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/restart"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// RefreshPlan describes what a refresh would do, without any change
+// having been queued for it.
+type RefreshPlan struct {
+	// Snaps are the snaps that would be refreshed, sorted by name.
+	Snaps []PlannedRefresh
+	// TaskSets are the task sets the refresh would be made of, in the
+	// order they were created.
+	TaskSets []PlannedTaskSet
+	// Held maps the snaps that have updates but are kept back by refresh
+	// holds to the snaps holding them ("system" for holds set by the
+	// user).
+	Held map[string][]string
+	// Prerequisites are the snaps that would be installed because the
+	// refreshed snaps need them and they are not installed yet.
+	Prerequisites []string
+}
+
+// PlannedRefresh describes the refresh of one snap in a RefreshPlan.
+type PlannedRefresh struct {
+	InstanceName string
+	Type         snap.Type
+	Channel      string
+	// Current is the installed revision, unset for snaps that are not
+	// installed yet.
+	Current  snap.Revision
+	Revision snap.Revision
+	// DownloadSize is the size of the snap to download, or zero if the
+	// refresh does not need a download.
+	DownloadSize int64
+	// Reboot is set if the refresh requires a reboot of the system.
+	Reboot bool
+	// Services are the services that would be restarted, as
+	// <snap>.<app>.
+	Services []string
+	// ValidationSets are the enforced validation sets constraining the
+	// snap.
+	ValidationSets []string
+}
+
+// PlannedTaskSet is one of the task sets of a RefreshPlan.
+type PlannedTaskSet struct {
+	// Snap is the snap the task set operates on, if any.
+	Snap  string
+	Tasks []PlannedTask
+}
+
+// PlannedTask is a task of a PlannedTaskSet.
+type PlannedTask struct {
+	Kind    string
+	Summary string
+}
+
+// PlanUpdate works out what UpdateWithGoal would do for the given goal and
+// options and describes it in a RefreshPlan. The task sets created along
+// the way are discarded, so the state is left as it was. If the options are
+// the ones of an auto-refresh, the snaps are held according to the
+// auto-refresh holds, including the ones set by gating snaps.
+func PlanUpdate(ctx context.Context, st *state.State, goal UpdateGoal, opts Options) (*RefreshPlan, error) {
+	opts.planOnly = true
+
+	candidates := make(map[string]*snap.Info)
+	snapStates := make(map[string]*SnapState)
+	collect := func(info *snap.Info, snapst *SnapState) bool {
+		candidates[info.InstanceName()] = info
+		snapStates[info.InstanceName()] = snapst
+		return true
+	}
+
+	updated, uts, err := UpdateWithGoal(ctx, st, goal, collect, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, ts := range append(uts.PreDownload, uts.Refresh...) {
+			st.DiscardTasks(ts.Tasks())
+		}
+	}()
+
+	plan := &RefreshPlan{}
+
+	// candidates that were filtered out of the update and that are held
+	// were kept back by a hold
+	holdLevel := HoldGeneral
+	if opts.Flags.IsAutoRefresh {
+		holdLevel = HoldAutoRefresh
+	}
+	heldSnaps, err := HeldSnaps(st, holdLevel)
+	if err != nil {
+		return nil, err
+	}
+	for name := range candidates {
+		if strutil.ListContains(updated, name) {
+			continue
+		}
+		if holding, ok := heldSnaps[name]; ok {
+			if plan.Held == nil {
+				plan.Held = make(map[string][]string)
+			}
+			sort.Strings(holding)
+			plan.Held[name] = holding
+		}
+	}
+
+	planned := make(map[string]*PlannedRefresh, len(updated))
+	for _, ts := range uts.Refresh {
+		pts := PlannedTaskSet{}
+		var snapsup *SnapSetup
+		var download, reboot bool
+		for _, t := range ts.Tasks() {
+			pts.Tasks = append(pts.Tasks, PlannedTask{Kind: t.Kind(), Summary: t.Summary()})
+			if snapsup == nil && t.Has("snap-setup") {
+				if snapsup, err = TaskSnapSetup(t); err != nil {
+					return nil, err
+				}
+			}
+			switch {
+			case t.Kind() == "download-snap":
+				download = true
+			case restart.TaskIsRestartBoundary(t, restart.RestartBoundaryDirectionDo):
+				reboot = true
+			}
+		}
+		if snapsup != nil {
+			pts.Snap = snapsup.InstanceName()
+			pr, err := plannedRefresh(st, snapsup, candidates, snapStates)
+			if err != nil {
+				return nil, err
+			}
+			if download && snapsup.DownloadInfo != nil {
+				pr.DownloadSize = snapsup.DownloadInfo.Size
+			}
+			pr.Reboot = reboot
+			planned[pr.InstanceName] = pr
+
+			for _, prereq := range snapsup.Prereq {
+				if err := addPlannedPrerequisite(st, plan, prereq); err != nil {
+					return nil, err
+				}
+			}
+			if snapsup.Base != "" && snapsup.Base != "none" {
+				if err := addPlannedPrerequisite(st, plan, snapsup.Base); err != nil {
+					return nil, err
+				}
+			}
+		}
+		plan.TaskSets = append(plan.TaskSets, pts)
+	}
+
+	for _, pr := range planned {
+		plan.Snaps = append(plan.Snaps, *pr)
+	}
+	sort.Slice(plan.Snaps, func(i, j int) bool {
+		return plan.Snaps[i].InstanceName < plan.Snaps[j].InstanceName
+	})
+	sort.Strings(plan.Prerequisites)
+
+	return plan, nil
+}
+
+// PlanAutoRefresh works out what an auto-refresh of all snaps would do and
+// describes it in a RefreshPlan, like PlanUpdate. Unlike AutoRefresh, it
+// doesn't refresh the assertions or run the gate-auto-refresh hooks of the
+// snaps, so gating snaps can only keep back updates with the holds they
+// already set.
+func PlanAutoRefresh(ctx context.Context, st *state.State) (*RefreshPlan, error) {
+	return PlanUpdate(ctx, st, StoreUpdateGoal(), Options{
+		Flags: Flags{
+			IsAutoRefresh: true,
+			Transaction:   client.TransactionPerSnap,
+		},
+	})
+}
+
+func plannedRefresh(st *state.State, snapsup *SnapSetup, candidates map[string]*snap.Info, snapStates map[string]*SnapState) (*PlannedRefresh, error) {
+	name := snapsup.InstanceName()
+	pr := &PlannedRefresh{
+		InstanceName: name,
+		Type:         snapsup.Type,
+		Channel:      snapsup.Channel,
+		Revision:     snapsup.Revision(),
+	}
+
+	snapst := snapStates[name]
+	if snapst != nil && snapst.IsInstalled() {
+		pr.Current = snapst.Current
+	}
+
+	if info := candidates[name]; info != nil {
+		if snapst != nil && snapst.Active {
+			for _, app := range info.Services() {
+				if strutil.ListContains(snapst.LastActiveDisabledServices, app.Name) {
+					continue
+				}
+				pr.Services = append(pr.Services, app.String())
+			}
+			sort.Strings(pr.Services)
+		}
+
+		if EnforcedValidationSets != nil {
+			vsets, err := EnforcedValidationSets(st)
+			if err != nil {
+				return nil, err
+			}
+			pres, err := vsets.Presence(info)
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range pres.Sets {
+				pr.ValidationSets = append(pr.ValidationSets, key.String())
+			}
+			sort.Strings(pr.ValidationSets)
+		}
+	}
+
+	return pr, nil
+}
+
+func addPlannedPrerequisite(st *state.State, plan *RefreshPlan, name string) error {
+	if strutil.ListContains(plan.Prerequisites, name) {
+		return nil
+	}
+	var snapst SnapState
+	err := Get(st, name, &snapst)
+	if err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	if snapst.IsInstalled() {
+		return nil
+	}
+	plan.Prerequisites = append(plan.Prerequisites, name)
+	return nil
+}
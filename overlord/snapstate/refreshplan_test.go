@@ -0,0 +1,296 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+	userclient "github.com/snapcore/snapd/usersession/client"
+)
+
+func (s *snapmgrTestSuite) TestPlanUpdate(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, name := range []string{"services-snap", "some-snap"} {
+		si := &snap.SideInfo{
+			RealName: name,
+			SnapID:   fmt.Sprintf("%s-id", name),
+			Revision: snap.R(7),
+		}
+		snaptest.MockSnap(c, fmt.Sprintf("name: %s", name), si)
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active:                     true,
+			Sequence:                   snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{si}),
+			Current:                    si.Revision,
+			TrackingChannel:            "channel-for-base/stable",
+			LastActiveDisabledServices: []string{"svc2"},
+		})
+	}
+
+	s.fakeStore.mutateSnapInfo = func(info *snap.Info) error {
+		info.Size = 1024
+		if info.SnapName() == "services-snap" {
+			info.Apps = map[string]*snap.AppInfo{
+				"svc1": {Snap: info, Name: "svc1", Daemon: "simple", DaemonScope: snap.SystemDaemon},
+				"svc2": {Snap: info, Name: "svc2", Daemon: "simple", DaemonScope: snap.SystemDaemon},
+				"cmd":  {Snap: info, Name: "cmd"},
+			}
+		}
+		return nil
+	}
+
+	err := snapstate.HoldRefreshesBySystem(s.state, snapstate.HoldGeneral, "forever", []string{"some-snap"})
+	c.Assert(err, IsNil)
+
+	taskCount := s.state.TaskCount()
+
+	goal := snapstate.StoreUpdateGoal()
+	plan, err := snapstate.PlanUpdate(context.Background(), s.state, goal, snapstate.Options{})
+	c.Assert(err, IsNil)
+
+	c.Check(plan.Snaps, DeepEquals, []snapstate.PlannedRefresh{{
+		InstanceName: "services-snap",
+		Type:         snap.TypeApp,
+		Channel:      "channel-for-base/stable",
+		Current:      snap.R(7),
+		Revision:     snap.R(11),
+		DownloadSize: 1024,
+		Services:     []string{"services-snap.svc1"},
+	}})
+	c.Check(plan.Held, DeepEquals, map[string][]string{"some-snap": {"system"}})
+	c.Check(plan.Prerequisites, DeepEquals, []string{"some-base"})
+
+	c.Assert(plan.TaskSets, HasLen, 2)
+	c.Check(plan.TaskSets[0].Snap, Equals, "services-snap")
+	var kinds []string
+	for _, t := range plan.TaskSets[0].Tasks {
+		kinds = append(kinds, t.Kind)
+	}
+	c.Check(kinds[:2], DeepEquals, []string{"prerequisites", "download-snap"})
+	c.Check(kinds, testutil.Contains, "start-snap-services")
+	c.Check(plan.TaskSets[1], DeepEquals, snapstate.PlannedTaskSet{
+		Tasks: []snapstate.PlannedTask{{Kind: "check-rerefresh", Summary: `Monitoring snap "services-snap" to determine whether extra refresh steps are required`}},
+	})
+
+	// the state is left as it was
+	c.Check(s.state.TaskCount(), Equals, taskCount)
+	c.Check(s.state.Changes(), HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestPlanAutoRefresh(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, name := range []string{"services-snap", "some-snap"} {
+		si := &snap.SideInfo{
+			RealName: name,
+			SnapID:   fmt.Sprintf("%s-id", name),
+			Revision: snap.R(7),
+		}
+		snaptest.MockSnap(c, fmt.Sprintf("name: %s", name), si)
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active:          true,
+			Sequence:        snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{si}),
+			Current:         si.Revision,
+			TrackingChannel: "channel-for-base/stable",
+		})
+	}
+
+	// the snap holds its own auto-refreshes
+	_, err := snapstate.HoldRefresh(s.state, snapstate.HoldAutoRefresh, "some-snap", 0, "some-snap")
+	c.Assert(err, IsNil)
+
+	// which doesn't keep it back from general refreshes
+	plan, err := snapstate.PlanUpdate(context.Background(), s.state, snapstate.StoreUpdateGoal(), snapstate.Options{})
+	c.Assert(err, IsNil)
+	c.Assert(plan.Snaps, HasLen, 2)
+	c.Check(plan.Held, IsNil)
+
+	plan, err = snapstate.PlanAutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Snaps, HasLen, 1)
+	c.Check(plan.Snaps[0].InstanceName, Equals, "services-snap")
+	c.Check(plan.Held, DeepEquals, map[string][]string{"some-snap": {"some-snap"}})
+
+	// the auto-refresh candidates are not recorded
+	var candidates map[string]any
+	c.Check(s.state.Get("refresh-candidates", &candidates), testutil.ErrorIs, state.ErrNoState)
+	c.Check(s.state.TaskCount(), Equals, 0)
+}
+
+func (s *snapmgrTestSuite) TestPlanUpdateLeavesBusyAndHeldSnapsAlone(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.gate-auto-refresh-hook", true)
+	tr.Commit()
+
+	for _, name := range []string{"services-snap", "some-snap"} {
+		si := &snap.SideInfo{
+			RealName: name,
+			SnapID:   fmt.Sprintf("%s-id", name),
+			Revision: snap.R(7),
+		}
+		snaptest.MockSnap(c, fmt.Sprintf("name: %s", name), si)
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active:          true,
+			Sequence:        snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{si}),
+			Current:         si.Revision,
+			TrackingChannel: "channel-for-base/stable",
+		})
+	}
+
+	// some-snap is busy
+	restore := snapstate.MockRefreshAppsCheck(func(info *snap.Info) error {
+		if info.InstanceName() == "some-snap" {
+			return snapstate.NewBusySnapError(info, []int{123}, nil, nil)
+		}
+		return nil
+	})
+	defer restore()
+	restore = snapstate.MockAsyncPendingRefreshNotification(func(context.Context, *userclient.PendingSnapRefreshInfo) {
+		c.Fatal("unexpected pending refresh notification")
+	})
+	defer restore()
+
+	// and services-snap holds its own auto-refreshes
+	_, err := snapstate.HoldRefresh(s.state, snapstate.HoldAutoRefresh, "services-snap", 0, "services-snap")
+	c.Assert(err, IsNil)
+	var holds map[string]any
+	c.Assert(s.state.Get("snaps-hold", &holds), IsNil)
+
+	// a general refresh would refresh services-snap despite its hold
+	plan, err := snapstate.PlanUpdate(context.Background(), s.state, snapstate.StoreUpdateGoal(), snapstate.Options{})
+	c.Assert(err, IsNil)
+	c.Assert(plan.Snaps, HasLen, 1)
+	c.Check(plan.Snaps[0].InstanceName, Equals, "services-snap")
+
+	// while an auto-refresh would wait for some-snap to be closed
+	plan, err = snapstate.PlanAutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(plan.Snaps, HasLen, 0)
+	c.Check(plan.Held, DeepEquals, map[string][]string{"services-snap": {"services-snap"}})
+
+	// the hold is kept
+	var holdsAfter map[string]any
+	c.Assert(s.state.Get("snaps-hold", &holdsAfter), IsNil)
+	c.Check(holdsAfter, DeepEquals, holds)
+
+	// and the inhibition of the refresh of some-snap isn't started
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.RefreshInhibitedTime, IsNil)
+	c.Check(s.state.TaskCount(), Equals, 0)
+}
+
+func (s *snapmgrTestSuite) TestPlanUpdateReboot(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	r := snapstatetest.MockDeviceModel(DefaultModel())
+	defer r()
+
+	si := &snap.SideInfo{
+		RealName: "brand-gadget",
+		SnapID:   "brand-gadget-id",
+		Revision: snap.R(7),
+	}
+	snapstate.Set(s.state, "brand-gadget", &snapstate.SnapState{
+		Active:   true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{si}),
+		Current:  si.Revision,
+		SnapType: "gadget",
+	})
+
+	goal := snapstate.StoreUpdateGoal(snapstate.StoreUpdate{InstanceName: "brand-gadget"})
+	plan, err := snapstate.PlanUpdate(context.Background(), s.state, goal, snapstate.Options{})
+	c.Assert(err, IsNil)
+
+	c.Assert(plan.Snaps, HasLen, 1)
+	c.Check(plan.Snaps[0].InstanceName, Equals, "brand-gadget")
+	c.Check(plan.Snaps[0].Type, Equals, snap.TypeGadget)
+	c.Check(plan.Snaps[0].Reboot, Equals, true)
+	c.Check(plan.Held, IsNil)
+	c.Check(s.state.TaskCount(), Equals, 0)
+}
+
+func (s *snapmgrTestSuite) TestPlanUpdateError(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	goal := snapstate.StoreUpdateGoal(snapstate.StoreUpdate{InstanceName: "some-snap"})
+	_, err := snapstate.PlanUpdate(context.Background(), s.state, goal, snapstate.Options{})
+	c.Assert(err, ErrorMatches, `snap "some-snap" is not installed`)
+}
+
+func (s *validationSetsSuite) TestPlanUpdateValidationSets(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapID := snaptest.AssertedSnapID("some-snap")
+	s.fakeStore.registerID("some-snap", snapID)
+
+	si := &snap.SideInfo{
+		RealName: "some-snap",
+		SnapID:   snapID,
+		Revision: snap.R(1),
+	}
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{si}),
+		Current:  si.Revision,
+		SnapType: "app",
+	})
+
+	restore := snapstate.MockEnforcedValidationSets(func(st *state.State, extraVss ...*asserts.ValidationSet) (*snapasserts.ValidationSets, error) {
+		vs := snapasserts.NewValidationSets()
+		vsa := s.mockValidationSetAssert(c, "bar", "1", map[string]any{
+			"id":       snapID,
+			"name":     "some-snap",
+			"presence": "optional",
+			"revision": "11",
+		})
+		c.Assert(vs.Add(vsa.(*asserts.ValidationSet)), IsNil)
+		return vs, nil
+	})
+	defer restore()
+
+	goal := snapstate.StoreUpdateGoal(snapstate.StoreUpdate{InstanceName: "some-snap"})
+	plan, err := snapstate.PlanUpdate(context.Background(), s.state, goal, snapstate.Options{})
+	c.Assert(err, IsNil)
+
+	c.Assert(plan.Snaps, HasLen, 1)
+	c.Check(plan.Snaps[0].Revision, Equals, snap.R(11))
+	c.Check(plan.Snaps[0].ValidationSets, DeepEquals, []string{"16/foo/bar/1"})
+}
@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/tomb.v2"
@@ -84,6 +85,12 @@ type SnapManager struct {
 	ensuredDownloadsCleaned    bool
 
 	changeCallbackID int
+
+	// downloadBudgetMu protects dailyDownloadBudget, the budget of
+	// refresh.daily-download-limit shared by the downloads of
+	// auto-refreshes
+	downloadBudgetMu    sync.Mutex
+	dailyDownloadBudget *downloadBudget
 }
 
 // SnapSetup holds the necessary snap details to perform most snap manager tasks.
@@ -855,6 +862,7 @@ func Manager(st *state.State, runner *state.TaskRunner) (*SnapManager, error) {
 	runner.AddHandler("switch-snap-channel", m.doSwitchSnapChannel, nil)
 	runner.AddHandler("toggle-snap-flags", m.doToggleSnapFlags, nil)
 	runner.AddHandler("check-rerefresh", m.doCheckReRefresh, nil)
+	runner.AddHandler("check-refresh-health", m.doCheckRefreshHealth, nil)
 	runner.AddHandler("conditional-auto-refresh", m.doConditionalAutoRefresh, nil)
 
 	// specific set-up for the kernel snap
@@ -1068,6 +1076,18 @@ func (m *SnapManager) NextRefresh() time.Time {
 	return m.autoRefresh.NextRefresh()
 }
 
+// RefreshWindows returns the refresh windows of the snaps which have one.
+func (m *SnapManager) RefreshWindows() ([]RefreshWindow, error) {
+	return m.autoRefresh.RefreshWindows()
+}
+
+// RefreshWaves returns the refresh.waves setting and the snaps with updates
+// in each of the waves of the current staged auto-refresh which are still to
+// be refreshed.
+func (m *SnapManager) RefreshWaves() (waves string, pending [][]string, err error) {
+	return m.autoRefresh.RefreshWaves()
+}
+
 // EffectiveRefreshHold returns the time until to which refreshes are
 // held if refresh.hold configuration is set.
 // The caller should be holding the state lock.
@@ -64,6 +64,9 @@ import (
 const (
 	skipConfigure = 1 << iota
 	noRestartBoundaries
+	// onlyPlan leaves the state alone apart from the new tasks, which
+	// are discarded by the caller
+	onlyPlan
 )
 
 // control flags for "Configure()"
@@ -409,7 +412,11 @@ func doInstall(st *state.State, snapst *SnapState, snapsup SnapSetup, compsups [
 			// Note that because we are modifying the snap state inside
 			// softCheckNothingRunningForRefresh, this block must be located
 			// after the conflict check done above.
-			if err := softCheckNothingRunningForRefresh(st, snapst, &snapsup, info); err != nil {
+			checkNothingRunning := softCheckNothingRunningForRefresh
+			if flags&onlyPlan != 0 {
+				checkNothingRunning = checkNothingRunningForPlannedRefresh
+			}
+			if err := checkNothingRunning(st, snapst, &snapsup, info); err != nil {
 				// snap is running; schedule its downloading before notifying to close
 				var busyErr *timedBusySnapError
 				if errors.As(err, &busyErr) && snapsup.IsAutoRefresh {
@@ -439,7 +446,7 @@ func doInstall(st *state.State, snapst *SnapState, snapsup SnapSetup, compsups [
 			}
 		}
 
-		if experimentalGateAutoRefreshHook {
+		if experimentalGateAutoRefreshHook && flags&onlyPlan == 0 {
 			// If this snap was held, then remove it from snaps-hold.
 			if err := resetGatingForRefreshed(st, snapsup.InstanceName()); err != nil {
 				return nil, err
@@ -860,7 +867,25 @@ func doInstall(st *state.State, snapst *SnapState, snapsup SnapSetup, compsups [
 	healthCheck := CheckHealthHook(st, snapsup.InstanceName(), snapsup.Revision())
 	healthCheck.WaitAll(installSet)
 	installSet.AddTask(healthCheck)
-	installSet.MarkEdge(healthCheck, EndEdge)
+	endTask := healthCheck
+
+	// snaps can ask to be reverted if they are not healthy after an
+	// auto-refresh; the declaration of the revision being refreshed from
+	// is what counts, the one of the new revision is not known yet
+	if snapsup.IsAutoRefresh && snapst.IsInstalled() && !snapsup.Flags.Revert {
+		var currentInfo *snap.Info
+		if info, err := snapst.CurrentInfo(); err == nil {
+			currentInfo = info
+		}
+		if healthRollbackEnabled(st, snapsup.InstanceName(), currentInfo) {
+			checkRefreshHealth := st.NewTask("check-refresh-health", fmt.Sprintf(i18n.G("Check health of snap %q%s after refresh"), snapsup.InstanceName(), revisionStr))
+			checkRefreshHealth.Set("snap-setup-task", prepare.ID())
+			checkRefreshHealth.WaitFor(healthCheck)
+			installSet.AddTask(checkRefreshHealth)
+			endTask = checkRefreshHealth
+		}
+	}
+	installSet.MarkEdge(endTask, EndEdge)
 
 	return installSet, nil
 }
@@ -2452,7 +2477,11 @@ func doUpdate(st *state.State, requested []string, updates []update, opts Option
 
 		// Do not set any default restart boundaries, we do it when we have access to all
 		// the task-sets in preparation for single-reboot.
-		ts, err := doInstall(st, &up.SnapState, up.Setup, up.Components, noRestartBoundaries, opts.FromChange, inUseFor(opts.DeviceCtx), opts.DeviceCtx)
+		instFlags := noRestartBoundaries
+		if opts.planOnly {
+			instFlags |= onlyPlan
+		}
+		ts, err := doInstall(st, &up.SnapState, up.Setup, up.Components, instFlags, opts.FromChange, inUseFor(opts.DeviceCtx), opts.DeviceCtx)
 		if err != nil {
 			if errors.Is(err, &timedBusySnapError{}) && ts != nil {
 				// snap is busy and pre-download tasks were made for it
@@ -3091,6 +3120,12 @@ type AutoRefreshOptions struct {
 // snaps on the system. In addition to that it will also refresh important
 // assertions.
 func AutoRefresh(ctx context.Context, st *state.State) ([]string, *UpdateTaskSets, error) {
+	return autoRefreshFiltered(ctx, st, nil)
+}
+
+// autoRefreshFiltered is like AutoRefresh but only refreshes the snaps
+// accepted by the filter, if any.
+func autoRefreshFiltered(ctx context.Context, st *state.State, filter updateFilter) ([]string, *UpdateTaskSets, error) {
 	userID := 0
 
 	if AutoRefreshAssertions != nil {
@@ -3108,14 +3143,14 @@ func AutoRefresh(ctx context.Context, st *state.State) ([]string, *UpdateTaskSet
 	}
 	if !gateAutoRefreshHook {
 		// old-style refresh (gate-auto-refresh-hook feature disabled)
-		return updateManyFiltered(ctx, st, nil, nil, userID, nil, &Flags{IsAutoRefresh: true}, "")
+		return updateManyFiltered(ctx, st, nil, nil, userID, filter, &Flags{IsAutoRefresh: true}, "")
 	}
 
 	// TODO: rename to autoRefreshTasks when old auto refresh logic gets removed.
 	// TODO2: pass "IsContinuedAutoRefresh" so that the SnapSetup of
 	//        gate-auto-refresh contains this field (required so that
 	//        the update-finished notifications work)
-	updated, tss, err := autoRefreshPhase1(ctx, st, "")
+	updated, tss, err := autoRefreshPhase1Filtered(ctx, st, "", filter)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -3128,6 +3163,12 @@ func AutoRefresh(ctx context.Context, st *state.State) ([]string, *UpdateTaskSet
 // to the snaps affecting the given snap only; it defaults to all snaps if nil.
 // The state needs to be locked by the caller.
 func autoRefreshPhase1(ctx context.Context, st *state.State, forGatingSnap string) ([]string, []*state.TaskSet, error) {
+	return autoRefreshPhase1Filtered(ctx, st, forGatingSnap, nil)
+}
+
+// autoRefreshPhase1Filtered is like autoRefreshPhase1 but only considers the
+// updates of the snaps accepted by the filter, if any.
+func autoRefreshPhase1Filtered(ctx context.Context, st *state.State, forGatingSnap string, filter updateFilter) ([]string, []*state.TaskSet, error) {
 	user, err := userFromUserID(st, 0)
 	if err != nil {
 		return nil, nil, err
@@ -3172,6 +3213,9 @@ func autoRefreshPhase1(ctx context.Context, st *state.State, forGatingSnap strin
 			// filtered out by refreshHintsFromCandidates
 			continue
 		}
+		if filter != nil && !filter(t.info, &t.snapst) {
+			continue
+		}
 
 		if err := checkChangeConflictIgnoringOneChange(st, name, &t.snapst, fromChange); err != nil {
 			logger.Noticef("cannot refresh snap %q: %v", name, err)
@@ -65,6 +65,10 @@ type Options struct {
 	// pre-existing behavior of calling InstallMany with one snap vs calling
 	// Install.
 	ExpectOneSnap bool
+
+	// planOnly is set when the operation is only planned and its tasks are
+	// discarded, so that no other state is modified along the way.
+	planOnly bool
 }
 
 func (opts *Options) setDefaultLane(st *state.State) error {
@@ -750,6 +754,9 @@ func InstallWithGoal(ctx context.Context, st *state.State, goal InstallGoal, opt
 		if opts.Flags.SkipConfigure {
 			instFlags |= skipConfigure
 		}
+		if opts.planOnly {
+			instFlags |= onlyPlan
+		}
 
 		ts, err := doInstall(st, &t.snapst, snapsup, compsups, instFlags, opts.FromChange, inUseFor(opts.DeviceCtx), opts.DeviceCtx)
 		if err != nil {
@@ -1110,7 +1117,7 @@ func UpdateWithGoal(ctx context.Context, st *state.State, goal UpdateGoal, filte
 
 	// save the candidates so the auto-refresh can be continued if it's inhibited
 	// by a running snap.
-	if opts.Flags.IsAutoRefresh {
+	if opts.Flags.IsAutoRefresh && !opts.planOnly {
 		hints, err := refreshHintsFromUpdatePlan(st, plan, opts.DeviceCtx)
 		if err != nil {
 			return nil, nil, err
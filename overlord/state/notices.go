@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path"
 	"sort"
 	"strconv"
 	"time"
@@ -307,11 +308,19 @@ const (
 	// expired. The key for interfaces-requests-rule-update notices is the
 	// rule ID.
 	InterfacesRequestsRuleUpdateNotice NoticeType = "interfaces-requests-rule-update"
+
+	// Recorded whenever the health status of a snap changes. The key for
+	// snap-health-change notices is the snap instance name.
+	SnapHealthChangeNotice NoticeType = "snap-health-change"
+
+	// Recorded whenever processes of a quota group are killed for running
+	// out of memory. The key for quota-oom notices is the quota group name.
+	QuotaOOMNotice NoticeType = "quota-oom"
 )
 
 func (t NoticeType) Valid() bool {
 	switch t {
-	case ChangeUpdateNotice, WarningNotice, RefreshInhibitNotice, SnapRunInhibitNotice, InterfacesRequestsPromptNotice, InterfacesRequestsRuleUpdateNotice:
+	case ChangeUpdateNotice, WarningNotice, RefreshInhibitNotice, SnapRunInhibitNotice, InterfacesRequestsPromptNotice, InterfacesRequestsRuleUpdateNotice, SnapHealthChangeNotice, QuotaOOMNotice:
 		return true
 	}
 	return false
@@ -453,6 +462,14 @@ type NoticeFilter struct {
 	// Keys, if not empty, includes only notices whose key is one of these.
 	Keys []string
 
+	// KeyGlobs, if not empty, includes only notices whose key matches at
+	// least one of these shell patterns, as understood by path.Match.
+	KeyGlobs []string
+
+	// Data, if not empty, includes only notices whose last data contains all
+	// of these key-value pairs.
+	Data map[string]string
+
 	// After, if set, includes only notices that were last repeated after this time.
 	After time.Time
 
@@ -476,6 +493,12 @@ func (f *NoticeFilter) matches(n *Notice) bool {
 	if len(f.Keys) > 0 && !sliceContains(f.Keys, n.key) {
 		return false
 	}
+	if len(f.KeyGlobs) > 0 && !KeyMatchesGlobs(n.key, f.KeyGlobs) {
+		return false
+	}
+	if !DataContains(n.lastData, f.Data) {
+		return false
+	}
 	if !f.After.IsZero() && !n.lastRepeated.After(f.After) {
 		return false
 	}
@@ -495,6 +518,38 @@ func (f *NoticeFilter) matches(n *Notice) bool {
 	return true
 }
 
+// KeyMatchesGlobs reports whether the given key matches any of the given
+// patterns. Malformed patterns never match.
+func KeyMatchesGlobs(key string, globs []string) bool {
+	for _, glob := range globs {
+		if matched, _ := path.Match(glob, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// DataContains reports whether the given notice data contains all of the
+// key-value pairs in filter. An empty filter is contained in any data.
+func DataContains(data, filter map[string]string) bool {
+	for k, v := range filter {
+		if dv, ok := data[k]; !ok || dv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateKeyGlobs checks that the given notice key patterns are well-formed.
+func ValidateKeyGlobs(globs []string) error {
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid key pattern %q: %w", glob, err)
+		}
+	}
+	return nil
+}
+
 func sliceContains[T comparable](haystack []T, needle T) bool {
 	for _, v := range haystack {
 		if v == needle {
@@ -535,6 +535,81 @@ func (s *noticesSuite) TestNoticesFilterKey(c *C) {
 	c.Check(n["key"], Equals, "foo.com/baz")
 }
 
+func (s *noticesSuite) TestNoticesFilterKeyGlobs(c *C) {
+	st := state.New(nil)
+
+	st.Lock()
+	addNotice(c, st, nil, state.WarningNotice, "foo.com/bar", nil)
+	time.Sleep(time.Microsecond)
+	addNotice(c, st, nil, state.WarningNotice, "example.com/x", nil)
+	time.Sleep(time.Microsecond)
+	addNotice(c, st, nil, state.WarningNotice, "foo.com/baz", nil)
+	st.Unlock()
+
+	// One glob
+	notices := st.Notices(&state.NoticeFilter{KeyGlobs: []string{"foo.com/*"}})
+	c.Assert(notices, HasLen, 2)
+	c.Check(notices[0].Key(), Equals, "foo.com/bar")
+	c.Check(notices[1].Key(), Equals, "foo.com/baz")
+
+	// Multiple globs
+	notices = st.Notices(&state.NoticeFilter{KeyGlobs: []string{"*/x", "foo.com/ba?"}})
+	c.Assert(notices, HasLen, 3)
+
+	// Combined with keys
+	notices = st.Notices(&state.NoticeFilter{
+		Keys:     []string{"foo.com/bar", "example.com/x"},
+		KeyGlobs: []string{"foo.com/*"},
+	})
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].Key(), Equals, "foo.com/bar")
+
+	// Malformed globs never match
+	notices = st.Notices(&state.NoticeFilter{KeyGlobs: []string{"foo.com/["}})
+	c.Assert(notices, HasLen, 0)
+}
+
+func (s *noticesSuite) TestValidateKeyGlobs(c *C) {
+	c.Check(state.ValidateKeyGlobs(nil), IsNil)
+	c.Check(state.ValidateKeyGlobs([]string{"foo", "*.com/?"}), IsNil)
+	c.Check(state.ValidateKeyGlobs([]string{"foo", "bar["}), ErrorMatches, `invalid key pattern "bar\[": syntax error in pattern`)
+}
+
+func (s *noticesSuite) TestNoticesFilterData(c *C) {
+	st := state.New(nil)
+
+	st.Lock()
+	addNotice(c, st, nil, state.ChangeUpdateNotice, "1", &state.AddNoticeOptions{
+		Data: map[string]string{"kind": "install-snap", "status": "Done"},
+	})
+	time.Sleep(time.Microsecond)
+	addNotice(c, st, nil, state.ChangeUpdateNotice, "2", &state.AddNoticeOptions{
+		Data: map[string]string{"kind": "refresh-snap", "status": "Done"},
+	})
+	time.Sleep(time.Microsecond)
+	addNotice(c, st, nil, state.ChangeUpdateNotice, "3", nil)
+	st.Unlock()
+
+	// Empty data filter matches everything
+	notices := st.Notices(&state.NoticeFilter{Data: map[string]string{}})
+	c.Assert(notices, HasLen, 3)
+
+	// Single pair
+	notices = st.Notices(&state.NoticeFilter{Data: map[string]string{"status": "Done"}})
+	c.Assert(notices, HasLen, 2)
+	c.Check(notices[0].Key(), Equals, "1")
+	c.Check(notices[1].Key(), Equals, "2")
+
+	// All pairs must match
+	notices = st.Notices(&state.NoticeFilter{Data: map[string]string{"status": "Done", "kind": "refresh-snap"}})
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].Key(), Equals, "2")
+
+	// Empty values must be present to match
+	notices = st.Notices(&state.NoticeFilter{Data: map[string]string{"kind": ""}})
+	c.Assert(notices, HasLen, 0)
+}
+
 func (s *noticesSuite) TestNoticesFilterAfter(c *C) {
 	st := state.New(nil)
 
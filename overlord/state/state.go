@@ -126,8 +126,9 @@ type State struct {
 	pendingChangeByAttr map[string]func(*Change) bool
 
 	// task/changes observing
-	taskHandlers   map[int]func(t *Task, old, new Status) (remove bool)
-	changeHandlers map[int]func(chg *Change, old, new Status)
+	taskHandlers         map[int]func(t *Task, old, new Status) (remove bool)
+	changeHandlers       map[int]func(chg *Change, old, new Status)
+	taskProgressHandlers map[int]func(t *Task, label string, done, total int)
 
 	lockWaitStart int64
 	lockHoldStart int64
@@ -136,17 +137,18 @@ type State struct {
 // New returns a new empty state.
 func New(backend Backend) *State {
 	st := &State{
-		backend:             backend,
-		data:                make(customData),
-		changes:             make(map[string]*Change),
-		tasks:               make(map[string]*Task),
-		warnings:            make(map[string]*Warning),
-		notices:             make(map[noticeKey]*Notice),
-		modified:            true,
-		cache:               make(map[any]any),
-		pendingChangeByAttr: make(map[string]func(*Change) bool),
-		taskHandlers:        make(map[int]func(t *Task, old Status, new Status) bool),
-		changeHandlers:      make(map[int]func(chg *Change, old Status, new Status)),
+		backend:              backend,
+		data:                 make(customData),
+		changes:              make(map[string]*Change),
+		tasks:                make(map[string]*Task),
+		warnings:             make(map[string]*Warning),
+		notices:              make(map[noticeKey]*Notice),
+		modified:             true,
+		cache:                make(map[any]any),
+		pendingChangeByAttr:  make(map[string]func(*Change) bool),
+		taskHandlers:         make(map[int]func(t *Task, old Status, new Status) bool),
+		changeHandlers:       make(map[int]func(chg *Change, old Status, new Status)),
+		taskProgressHandlers: make(map[int]func(t *Task, label string, done, total int)),
 	}
 	// The noticeCond.L must be the same as the lock which is held during
 	// WaitNotices, since noticeCond.Wait() will unlock noticeCond.L.
@@ -461,6 +463,19 @@ func (s *State) TaskCount() int {
 	return len(s.tasks)
 }
 
+// DiscardTasks removes the given tasks from the state. It is meant for
+// tasks that were created only to be inspected and that will never run,
+// so it panics if any of them is linked to a change.
+func (s *State) DiscardTasks(tasks []*Task) {
+	s.writing()
+	for _, t := range tasks {
+		if t.Change() != nil {
+			panic(fmt.Sprintf("internal error: cannot discard task %s linked to change %s", t.ID(), t.Change().ID()))
+		}
+		delete(s.tasks, t.ID())
+	}
+}
+
 func (s *State) tasksIn(tids []string) []*Task {
 	res := make([]*Task, len(tids))
 	for i, tid := range tids {
@@ -639,6 +654,32 @@ func (s *State) notifyChangeStatusChangedHandlers(chg *Change, old, new Status)
 	}
 }
 
+// AddTaskProgressChangedHandler adds a callback function that will be invoked
+// whenever the progress of a task changes.
+// NOTE: Callbacks registered this way may be invoked in the context
+// of the taskrunner, so the callbacks should be as simple as possible, and return
+// as quickly as possible, and should avoid the use of i/o code or blocking, as this
+// will stop the entire task system.
+func (s *State) AddTaskProgressChangedHandler(f func(t *Task, label string, done, total int)) (id int) {
+	s.reading()
+	id = s.lastHandlerId
+	s.lastHandlerId++
+	s.taskProgressHandlers[id] = f
+	return id
+}
+
+func (s *State) RemoveTaskProgressChangedHandler(id int) {
+	s.reading()
+	delete(s.taskProgressHandlers, id)
+}
+
+func (s *State) notifyTaskProgressChangedHandlers(t *Task, label string, done, total int) {
+	s.reading()
+	for _, f := range s.taskProgressHandlers {
+		f(t, label, done, total)
+	}
+}
+
 // SaveTimings implements timings.GetSaver
 func (s *State) SaveTimings(timings any) {
 	s.Set("timings", timings)
@@ -661,5 +702,6 @@ func ReadState(backend Backend, r io.Reader) (*State, error) {
 	s.pendingChangeByAttr = make(map[string]func(*Change) bool)
 	s.changeHandlers = make(map[int]func(chg *Change, old Status, new Status))
 	s.taskHandlers = make(map[int]func(t *Task, old Status, new Status) bool)
+	s.taskProgressHandlers = make(map[int]func(t *Task, label string, done, total int))
 	return s, err
 }
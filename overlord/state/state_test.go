@@ -581,6 +581,7 @@ func (ss *stateSuite) TestEmptyStateDataAndCheckpointReadAndSet(c *C) {
 		"pendingChangeByAttr",
 		"taskHandlers",
 		"changeHandlers",
+		"taskProgressHandlers",
 	})
 }
 
@@ -743,6 +744,25 @@ func (ss *stateSuite) TestNewTaskHiddenUntilLinked(c *C) {
 	c.Check(st.Task(t1.ID()), IsNil)
 }
 
+func (ss *stateSuite) TestDiscardTasks(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	t1 := st.NewTask("check", "...")
+	t2 := st.NewTask("inst", "...")
+	c.Check(st.TaskCount(), Equals, 2)
+
+	st.DiscardTasks([]*state.Task{t1, t2})
+	c.Check(st.TaskCount(), Equals, 0)
+
+	chg := st.NewChange("install", "...")
+	t3 := st.NewTask("check", "...")
+	chg.AddTask(t3)
+	c.Check(func() { st.DiscardTasks([]*state.Task{t3}) }, PanicMatches, `internal error: cannot discard task 3 linked to change 1`)
+	c.Check(st.Task(t3.ID()), Equals, t3)
+}
+
 func (ss *stateSuite) TestMethodEntrance(c *C) {
 	st := state.New(&fakeStateBackend{})
 
@@ -761,6 +781,7 @@ func (ss *stateSuite) TestMethodEntrance(c *C) {
 		func() { st.UnshowAllWarnings() },
 		func() { st.AddNotice(nil, state.WarningNotice, "foo", nil) },
 		func() { st.DrainNotices(nil) },
+		func() { st.DiscardTasks(nil) },
 	}
 
 	reads := []func(){
@@ -1207,6 +1228,43 @@ type changeAndStatus struct {
 	old, new state.Status
 }
 
+func (ss *stateSuite) TestTaskProgressChangedHandler(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	type progressUpdate struct {
+		t     *state.Task
+		label string
+		done  int
+		total int
+	}
+	var observed []progressUpdate
+	oId := st.AddTaskProgressChangedHandler(func(t *state.Task, label string, done, total int) {
+		observed = append(observed, progressUpdate{t, label, done, total})
+	})
+
+	t1 := st.NewTask("foo", "...")
+	t1.SetProgress("x", 1, 10)
+	// identical progress does not trigger the handler
+	t1.SetProgress("x", 1, 10)
+	t1.SetProgress("x", 5, 10)
+	// invalid progress is dropped silently
+	t1.SetProgress("x", 11, 10)
+	t1.SetProgress("x", 10, 10)
+
+	st.RemoveTaskProgressChangedHandler(oId)
+
+	// must not appear in list.
+	t1.SetProgress("x", 2, 10)
+
+	c.Check(observed, DeepEquals, []progressUpdate{
+		{t1, "x", 1, 10},
+		{t1, "x", 5, 10},
+		{t1, "x", 10, 10},
+	})
+}
+
 func (ss *stateSuite) TestChangeChangedHandler(c *C) {
 	st := state.New(nil)
 	st.Lock()
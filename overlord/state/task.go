@@ -360,12 +360,16 @@ func (t *Task) SetProgress(label string, done, total int) {
 	} else {
 		t.state.reading()
 	}
+	old := t.progress
 	if total <= 0 || done > total {
 		// Doing math wrong is easy. Be conservative.
 		t.progress = nil
 	} else {
 		t.progress = &progress{Label: label, Done: done, Total: total}
 	}
+	if t.progress != nil && (old == nil || *old != *t.progress) {
+		t.state.notifyTaskProgressChangedHandlers(t, label, done, total)
+	}
 }
 
 // SpawnTime returns the time when the change was created.
@@ -26,6 +26,20 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/metrics"
+)
+
+var (
+	taskrunnerEnsureDuration = metrics.NewHistogram("snapd_taskrunner_ensure_duration_seconds",
+		"Duration of the task runner passes over the tasks.", nil)
+	taskrunnerRunningTasks = metrics.NewGauge("snapd_taskrunner_running_tasks",
+		"Number of tasks whose handler is running.")
+	taskrunnerPendingTasks = metrics.NewGauge("snapd_taskrunner_pending_tasks",
+		"Number of tasks waiting for other tasks, for their scheduled time or on a blocked predicate.")
+	taskrunnerHandlerRuns = metrics.NewCounter("snapd_taskrunner_handler_runs_total",
+		"Number of task handler runs, by task kind and result.", "kind", "result")
+	changesInFlight = metrics.NewGauge("snapd_changes_in_flight",
+		"Number of changes which are not ready yet, by kind.", "kind")
 )
 
 // HandlerFunc is the type of function for the handlers
@@ -253,6 +267,7 @@ func (r *TaskRunner) run(t *Task) {
 				err = &Retry{}
 			}
 		}
+		taskrunnerHandlerRuns.Inc(t.Kind(), handlerResult(err))
 
 		switch x := err.(type) {
 		case *Retry:
@@ -404,6 +419,13 @@ func (r *TaskRunner) Ensure() error {
 
 	ensureTime := timeNow()
 	nextTaskTime := time.Time{}
+	pending := 0
+	defer func() {
+		taskrunnerEnsureDuration.ObserveDuration(timeNow().Sub(ensureTime))
+		taskrunnerRunningTasks.Set(float64(len(r.tombs)))
+		taskrunnerPendingTasks.Set(float64(pending))
+		updateChangesInFlight(r.state)
+	}()
 ConsiderTasks:
 	for _, t := range r.state.Tasks() {
 		handlers := r.handlerPair(t)
@@ -441,6 +463,7 @@ ConsiderTasks:
 
 		if mustWait(t) {
 			// Dependencies still unhandled.
+			pending++
 			continue
 		}
 
@@ -461,6 +484,7 @@ ConsiderTasks:
 			if nextTaskTime.IsZero() || nextTaskTime.After(tWhen) {
 				nextTaskTime = tWhen
 			}
+			pending++
 			continue
 		}
 
@@ -469,6 +493,7 @@ ConsiderTasks:
 		for _, blocked := range r.blocked {
 			if blocked(t, running) {
 				r.someBlocked = true
+				pending++
 				continue ConsiderTasks
 			}
 		}
@@ -487,6 +512,21 @@ ConsiderTasks:
 	return nil
 }
 
+// handlerResult returns the result of a task handler run as reported in
+// metrics.
+func handlerResult(err error) string {
+	switch err.(type) {
+	case nil:
+		return "done"
+	case *Retry:
+		return "retry"
+	case *Wait:
+		return "wait"
+	default:
+		return "error"
+	}
+}
+
 // mustWait returns whether task t must wait for other tasks to be done.
 func mustWait(t *Task) bool {
 	switch t.Status() {
@@ -506,6 +546,21 @@ func mustWait(t *Task) bool {
 	return false
 }
 
+// updateChangesInFlight recomputes the number of changes which are not
+// ready yet. It must be called with the state lock held.
+func updateChangesInFlight(st *State) {
+	counts := make(map[string]int)
+	for _, chg := range st.changes {
+		if !chg.IsReady() {
+			counts[chg.Kind()]++
+		}
+	}
+	changesInFlight.Reset()
+	for kind, n := range counts {
+		changesInFlight.Set(float64(n), kind)
+	}
+}
+
 // wait expects to be called with th r.mu lock held
 func (r *TaskRunner) wait() {
 	for len(r.tombs) > 0 {
@@ -20,6 +20,7 @@
 package state_test
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"sort"
@@ -32,6 +33,7 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/metrics"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -1341,3 +1343,50 @@ func (ts *taskRunnerSuite) TestErrorCallbackNotCalled(c *C) {
 	c.Check(t1.Status(), Equals, state.DoneStatus)
 	c.Check(called, Equals, false)
 }
+
+func (ts *taskRunnerSuite) TestMetrics(c *C) {
+	sb := &stateBackend{}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+	defer r.Stop()
+
+	ch := make(chan bool)
+	r.AddHandler("metrics-ok", func(t *state.Task, tb *tomb.Tomb) error {
+		ch <- true
+		return nil
+	}, nil)
+	r.AddHandler("metrics-fail", func(t *state.Task, tb *tomb.Tomb) error {
+		return errors.New("boom")
+	}, nil)
+
+	st.Lock()
+	chg := st.NewChange("metrics-change", "...")
+	t1 := st.NewTask("metrics-ok", "...")
+	t2 := st.NewTask("metrics-fail", "...")
+	t2.WaitFor(t1)
+	chg.AddTask(t1)
+	chg.AddTask(t2)
+	st.Unlock()
+
+	metricsText := func() string {
+		var buf bytes.Buffer
+		c.Assert(metrics.Default.WriteText(&buf), IsNil)
+		return buf.String()
+	}
+
+	r.Ensure()
+	text := metricsText()
+	c.Check(text, testutil.Contains, "snapd_taskrunner_running_tasks 1\n")
+	c.Check(text, testutil.Contains, "snapd_taskrunner_pending_tasks 1\n")
+	c.Check(text, testutil.Contains, `snapd_changes_in_flight{kind="metrics-change"} 1`+"\n")
+
+	<-ch
+	ensureChange(c, r, sb, chg)
+
+	text = metricsText()
+	c.Check(text, testutil.Contains, "snapd_taskrunner_running_tasks 0\n")
+	c.Check(text, testutil.Contains, "snapd_taskrunner_pending_tasks 0\n")
+	c.Check(text, Not(testutil.Contains), `snapd_changes_in_flight{kind="metrics-change"}`)
+	c.Check(text, testutil.Contains, `snapd_taskrunner_handler_runs_total{kind="metrics-ok",result="done"} 1`+"\n")
+	c.Check(text, testutil.Contains, `snapd_taskrunner_handler_runs_total{kind="metrics-fail",result="error"} 1`+"\n")
+}
@@ -23,11 +23,20 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/metrics"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
+var (
+	ensureDuration = metrics.NewHistogram("snapd_ensure_duration_seconds",
+		"Duration of the ensure passes over all the managers.", nil)
+	ensureErrors = metrics.NewCounter("snapd_ensure_errors_total",
+		"Number of errors returned by the managers during ensure passes.")
+)
+
 // StateManager is implemented by types responsible for observing
 // the system and manipulating it to reflect the desired state.
 type StateManager interface {
@@ -154,6 +163,7 @@ func (se *StateEngine) Ensure() error {
 	if se.stopped {
 		return fmt.Errorf("state engine already stopped")
 	}
+	t0 := time.Now()
 	var errs []error
 	for _, m := range se.managers {
 		err := m.Ensure()
@@ -162,6 +172,8 @@ func (se *StateEngine) Ensure() error {
 			errs = append(errs, err)
 		}
 	}
+	ensureDuration.ObserveDuration(time.Since(t0))
+	ensureErrors.Add(float64(len(errs)))
 	if len(errs) != 0 {
 		return &ensureError{errs}
 	}
@@ -0,0 +1,153 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cgroup
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MemoryEvents holds the counters of the memory.events file of a cgroup v2
+// group. The counters include the events of all the descendant groups.
+type MemoryEvents struct {
+	// High is how many times the processes were throttled for going over
+	// the memory.high limit.
+	High uint64
+	// Max is how many times the memory usage was about to go over the
+	// memory.max limit.
+	Max uint64
+	// OOM is how many times the memory usage reached the limit and
+	// allocations failed.
+	OOM uint64
+	// OOMKill is how many processes were killed by the OOM killer.
+	OOMKill uint64
+}
+
+// MemoryPressure holds the "some" line of the memory.pressure file of a
+// cgroup v2 group, that is the share of time in percent during which at
+// least one process was stalled waiting for memory, averaged over 10, 60 and
+// 300 seconds.
+type MemoryPressure struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+}
+
+// SlicePath returns the path of the cgroup of the given systemd slice,
+// relative to the cgroup mount point. As with systemd, dashes in the slice
+// name denote its parent slices, e.g. the cgroup of "snap.foo-bar.slice" is
+// "snap.foo.slice/snap.foo-bar.slice".
+func SlicePath(slice string) string {
+	name := strings.TrimSuffix(slice, ".slice")
+	parts := strings.Split(name, "-")
+	elems := make([]string, 0, len(parts))
+	for i := range parts {
+		elems = append(elems, strings.Join(parts[:i+1], "-")+".slice")
+	}
+	return filepath.Join(elems...)
+}
+
+// ReadMemoryEvents returns the memory events of the given cgroup, as a path
+// relative to the cgroup mount point. It works with cgroup v2 only.
+func ReadMemoryEvents(cgroupPath string) (*MemoryEvents, error) {
+	f, err := os.Open(filepath.Join(rootPath, cgroupMountPoint, cgroupPath, "memory.events"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events MemoryEvents
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("cannot parse memory events line %q", scanner.Text())
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse memory events line %q: %v", scanner.Text(), err)
+		}
+		switch fields[0] {
+		case "high":
+			events.High = value
+		case "max":
+			events.Max = value
+		case "oom":
+			events.OOM = value
+		case "oom_kill":
+			events.OOMKill = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &events, nil
+}
+
+// ReadMemoryPressure returns the memory pressure stall information of the
+// given cgroup, as a path relative to the cgroup mount point. It works with
+// cgroup v2 only, on kernels with PSI enabled.
+func ReadMemoryPressure(cgroupPath string) (*MemoryPressure, error) {
+	f, err := os.Open(filepath.Join(rootPath, cgroupMountPoint, cgroupPath, "memory.pressure"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		var pressure MemoryPressure
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("cannot parse memory pressure line %q", scanner.Text())
+			}
+			var dst *float64
+			switch kv[0] {
+			case "avg10":
+				dst = &pressure.Avg10
+			case "avg60":
+				dst = &pressure.Avg60
+			case "avg300":
+				dst = &pressure.Avg300
+			default:
+				continue
+			}
+			value, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse memory pressure line %q: %v", scanner.Text(), err)
+			}
+			*dst = value
+		}
+		return &pressure, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("cannot find memory pressure information")
+}
@@ -0,0 +1,106 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cgroup_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type memoryEventsSuite struct {
+	testutil.BaseTest
+
+	rootDir string
+}
+
+var _ = Suite(&memoryEventsSuite{})
+
+func (s *memoryEventsSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	s.rootDir = c.MkDir()
+	dirs.SetRootDir(s.rootDir)
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+}
+
+func (s *memoryEventsSuite) mockCgroupFile(c *C, cgroupPath, name, content string) {
+	path := filepath.Join(s.rootDir, "/sys/fs/cgroup", cgroupPath, name)
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+	c.Assert(os.WriteFile(path, []byte(content), 0644), IsNil)
+}
+
+func (s *memoryEventsSuite) TestSlicePath(c *C) {
+	c.Check(cgroup.SlicePath("snap.foo.slice"), Equals, "snap.foo.slice")
+	c.Check(cgroup.SlicePath("snap.foo-bar-baz.slice"), Equals, "snap.foo.slice/snap.foo-bar.slice/snap.foo-bar-baz.slice")
+}
+
+func (s *memoryEventsSuite) TestReadMemoryEvents(c *C) {
+	s.mockCgroupFile(c, "snap.foo.slice", "memory.events", `low 0
+high 12
+max 3
+oom 2
+oom_kill 1
+oom_group_kill 0
+`)
+	events, err := cgroup.ReadMemoryEvents("snap.foo.slice")
+	c.Assert(err, IsNil)
+	c.Check(events, DeepEquals, &cgroup.MemoryEvents{High: 12, Max: 3, OOM: 2, OOMKill: 1})
+}
+
+func (s *memoryEventsSuite) TestReadMemoryEventsErrors(c *C) {
+	_, err := cgroup.ReadMemoryEvents("snap.foo.slice")
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	s.mockCgroupFile(c, "snap.foo.slice", "memory.events", "oom_kill many\n")
+	_, err = cgroup.ReadMemoryEvents("snap.foo.slice")
+	c.Check(err, ErrorMatches, `cannot parse memory events line "oom_kill many": .*`)
+
+	s.mockCgroupFile(c, "snap.foo.slice", "memory.events", "oom_kill\n")
+	_, err = cgroup.ReadMemoryEvents("snap.foo.slice")
+	c.Check(err, ErrorMatches, `cannot parse memory events line "oom_kill"`)
+}
+
+func (s *memoryEventsSuite) TestReadMemoryPressure(c *C) {
+	s.mockCgroupFile(c, "snap.foo.slice/snap.foo-bar.slice", "memory.pressure", `some avg10=12.50 avg60=4.00 avg300=1.25 total=123456
+full avg10=2.00 avg60=1.00 avg300=0.10 total=1234
+`)
+	pressure, err := cgroup.ReadMemoryPressure("snap.foo.slice/snap.foo-bar.slice")
+	c.Assert(err, IsNil)
+	c.Check(pressure, DeepEquals, &cgroup.MemoryPressure{Avg10: 12.5, Avg60: 4, Avg300: 1.25})
+}
+
+func (s *memoryEventsSuite) TestReadMemoryPressureErrors(c *C) {
+	_, err := cgroup.ReadMemoryPressure("snap.foo.slice")
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	s.mockCgroupFile(c, "snap.foo.slice", "memory.pressure", "full avg10=2.00\n")
+	_, err = cgroup.ReadMemoryPressure("snap.foo.slice")
+	c.Check(err, ErrorMatches, `cannot find memory pressure information`)
+
+	s.mockCgroupFile(c, "snap.foo.slice", "memory.pressure", "some avg10=high\n")
+	_, err = cgroup.ReadMemoryPressure("snap.foo.slice")
+	c.Check(err, ErrorMatches, `cannot parse memory pressure line "some avg10=high": .*`)
+}
@@ -393,6 +393,10 @@ type Info struct {
 	// Plugs or slots with issues (they are not included in Plugs or Slots)
 	BadInterfaces map[string]string // slot or plug => message
 
+	// RefreshHealthRollback is set when the snap asks to be reverted if
+	// it is not healthy after being automatically refreshed.
+	RefreshHealthRollback bool
+
 	// The information in all the remaining fields is not sourced from the snap
 	// blob itself.
 	SideInfo
@@ -1387,6 +1391,10 @@ type HookInfo struct {
 	Environment  strutil.OrderedMap
 	CommandChain []string
 
+	// Interval is how often the hook is run periodically, only supported
+	// for the check-health hook.
+	Interval timeout.Timeout
+
 	Explicit bool
 }
 
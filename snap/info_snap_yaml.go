@@ -57,11 +57,16 @@ type snapYaml struct {
 	SystemUsernames map[string]any           `yaml:"system-usernames,omitempty"`
 	Links           map[string][]string      `yaml:"links,omitempty"`
 	Components      map[string]componentYaml `yaml:"components,omitempty"`
+	Refresh         refreshYaml              `yaml:"refresh,omitempty"`
 
 	// TypoLayouts is used to detect the use of the incorrect plural form of "layout"
 	TypoLayouts typoDetector `yaml:"layouts,omitempty"`
 }
 
+type refreshYaml struct {
+	HealthRollback bool `yaml:"health-rollback,omitempty"`
+}
+
 type typoDetector struct {
 	Hint string
 }
@@ -116,6 +121,7 @@ type hookYaml struct {
 	SlotNames    []string           `yaml:"slots,omitempty"`
 	Environment  strutil.OrderedMap `yaml:"environment,omitempty"`
 	CommandChain []string           `yaml:"command-chain,omitempty"`
+	Interval     timeout.Timeout    `yaml:"interval,omitempty"`
 }
 
 type componentYaml struct {
@@ -309,6 +315,8 @@ func infoSkeletonFromSnapYaml(y snapYaml) *Info {
 		Environment:         y.Environment,
 		SystemUsernames:     make(map[string]*SystemUsernameInfo),
 		OriginalLinks:       make(map[string][]string),
+
+		RefreshHealthRollback: y.Refresh.HealthRollback,
 	}
 
 	sort.Strings(snap.Assumes)
@@ -561,6 +569,7 @@ func setHooksFromSnapYaml(y snapYaml, snap *Info, strk *scopedTracker) {
 			Name:         hookName,
 			Environment:  yHook.Environment,
 			CommandChain: yHook.CommandChain,
+			Interval:     yHook.Interval,
 			Explicit:     true,
 		}
 		if len(y.Plugs) > 0 || len(yHook.PlugNames) > 0 {
@@ -87,6 +87,19 @@ version: 1.0`))
 	c.Check(info.SnapProvenance, Equals, "delegated-prov")
 }
 
+func (s *InfoSnapYamlTestSuite) TestRefreshHealthRollback(c *C) {
+	info, err := snap.InfoFromSnapYaml(mockYaml)
+	c.Assert(err, IsNil)
+	c.Check(info.RefreshHealthRollback, Equals, false)
+
+	info, err = snap.InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+refresh:
+  health-rollback: true`))
+	c.Assert(err, IsNil)
+	c.Check(info.RefreshHealthRollback, Equals, true)
+}
+
 func (s *InfoSnapYamlTestSuite) TestFail(c *C) {
 	_, err := snap.InfoFromSnapYaml([]byte("random-crap"))
 	c.Assert(err, ErrorMatches, "(?m)cannot parse snap.yaml:.*")
@@ -1984,6 +1997,19 @@ hooks:
 	c.Check(hook.CommandChain, DeepEquals, []string{"hookchain1", "hookchain2"})
 }
 
+func (s *YamlSuite) TestSnapYamlHookInterval(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`name: wat
+version: 42
+hooks:
+ check-health:
+  interval: 2h
+ configure:
+`))
+	c.Assert(err, IsNil)
+	c.Check(info.Hooks["check-health"].Interval, Equals, timeout.Timeout(2*time.Hour))
+	c.Check(info.Hooks["configure"].Interval, Equals, timeout.Timeout(0))
+}
+
 func (s *YamlSuite) TestSnapYamlRestartDelay(c *C) {
 	yAutostart := []byte(`name: wat
 version: 42
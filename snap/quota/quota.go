@@ -83,6 +83,19 @@ type GroupQuotaJournal struct {
 	RatePeriod time.Duration `json:"rate-period,omitempty"`
 }
 
+// GroupQuotaIO contains the block IO limits of the group.
+type GroupQuotaIO struct {
+	// Weight is the relative weight of the group when competing for IO
+	// with its sibling groups, between 1 and 10000. A value of 0 means the
+	// default weight of systemd is used.
+	Weight int `json:"weight,omitempty"`
+
+	// Devices holds the bandwidth and IOPS limits of individual block
+	// devices. The limits of a sub-group for each device cannot exceed,
+	// summed up with those of its siblings, the limits of its parent group.
+	Devices []ResourceIODevice `json:"devices,omitempty"`
+}
+
 // Group is a quota group of snaps, services or sub-groups that are all subject
 // to specific resource quotas. The only quota resource types currently
 // supported is memory, but this can be expanded in the future.
@@ -109,6 +122,21 @@ type Group struct {
 	// ExhaustionBehavior. MemoryLimit is expressed in bytes.
 	MemoryLimit quantity.Size `json:"memory-limit,omitempty"`
 
+	// MemoryHighLimit is the soft memory limit of the group, above which
+	// its processes are throttled rather than killed. It is expressed in
+	// bytes and must be smaller than MemoryLimit.
+	MemoryHighLimit quantity.Size `json:"memory-high-limit,omitempty"`
+
+	// OOMPolicy is what snapd does when processes of the group are killed
+	// for running out of memory.
+	OOMPolicy OOMPolicy `json:"oom-policy,omitempty"`
+
+	// MemoryPressureThreshold is the memory pressure of the group, as the
+	// percentage of time its processes were stalled waiting for memory over
+	// the last 10 seconds, above which OOMPolicy is applied even though
+	// no process was killed yet.
+	MemoryPressureThreshold int `json:"memory-pressure-threshold,omitempty"`
+
 	// CPULimit is the quotas for the cpu and consists of a couple of nubs.
 	// It is possible to control the percentage of the cpu available for the group
 	// and which cores (requires cgroupsv2) are allowed to be used.
@@ -124,6 +152,10 @@ type Group struct {
 	// journald.
 	JournalLimit *GroupQuotaJournal `json:"journal-limit,omitempty"`
 
+	// IOLimit is the block IO limits of the group, throttling the
+	// bandwidth and IOPS available to the processes in the group.
+	IOLimit *GroupQuotaIO `json:"io-limit,omitempty"`
+
 	// ParentGroup is the the parent group that this group is a child of. If it
 	// is empty, then this is a "root" quota group.
 	ParentGroup string `json:"parent-group,omitempty"`
@@ -164,6 +196,15 @@ func (grp *Group) GetQuotaResources() Resources {
 	resourcesBuilder := NewResourcesBuilder()
 	if grp.MemoryLimit != 0 {
 		resourcesBuilder.WithMemoryLimit(grp.MemoryLimit)
+		if grp.MemoryHighLimit != 0 {
+			resourcesBuilder.WithMemoryHighLimit(grp.MemoryHighLimit)
+		}
+		if grp.OOMPolicy != "" {
+			resourcesBuilder.WithOOMPolicy(grp.OOMPolicy)
+		}
+		if grp.MemoryPressureThreshold != 0 {
+			resourcesBuilder.WithMemoryPressureThreshold(grp.MemoryPressureThreshold)
+		}
 	}
 	if grp.CPULimit != nil {
 		if grp.CPULimit.Count != 0 {
@@ -192,6 +233,14 @@ func (grp *Group) GetQuotaResources() Resources {
 			resourcesBuilder.WithJournalRate(grp.JournalLimit.RateCount, grp.JournalLimit.RatePeriod)
 		}
 	}
+	if grp.IOLimit != nil {
+		if grp.IOLimit.Weight != 0 {
+			resourcesBuilder.WithIOWeight(grp.IOLimit.Weight)
+		}
+		if len(grp.IOLimit.Devices) != 0 {
+			resourcesBuilder.WithIODeviceLimits(mergeIODevices(grp.IOLimit.Devices, nil))
+		}
+	}
 	return resourcesBuilder.Build()
 }
 
@@ -244,6 +293,26 @@ func (grp *Group) CurrentTaskUsage() (int, error) {
 	return int(count), nil
 }
 
+// CurrentCPUUsage returns the CPU time consumed so far by the quota group.
+// For quota groups which do not yet have a backing systemd slice on the
+// system (i.e. quota groups without any snaps in them), the CPU usage is
+// reported as 0.
+func (grp *Group) CurrentCPUUsage() (time.Duration, error) {
+	sysd := systemd.New(systemd.SystemMode, progress.Null)
+
+	// check if this group is actually active, it could not physically exist yet
+	// since it has no snaps in it
+	isActive, err := sysd.IsActive(grp.SliceFileName())
+	if err != nil {
+		return 0, err
+	}
+	if !isActive {
+		return 0, nil
+	}
+
+	return sysd.CurrentCPUUsage(grp.SliceFileName())
+}
+
 // SliceFileName returns the name of the slice file that should be used for this
 // quota group. This name will include all of the group's parents in the name.
 // For example, a group named "bar" that is a child of the "foo" group will have
@@ -371,6 +440,40 @@ type groupQuotaAllocations struct {
 
 	CPUSetLimit              []int
 	CPUSetReservedByChildren []int
+
+	IOLimits             map[string]ioDeviceLimits
+	IOReservedByChildren map[string]ioDeviceLimits
+}
+
+// ioDeviceLimits holds the limits of a block device in the order of
+// ioLimitNames, which is that of the fields of ResourceIODevice.
+type ioDeviceLimits [4]uint64
+
+var ioLimitNames = [4]string{"read bandwidth", "write bandwidth", "read iops", "write iops"}
+
+func newIODeviceLimits(dev ResourceIODevice) ioDeviceLimits {
+	return ioDeviceLimits{uint64(dev.ReadBandwidth), uint64(dev.WriteBandwidth), uint64(dev.ReadIOPS), uint64(dev.WriteIOPS)}
+}
+
+// formatIOLimit formats the value of the given kind of io limit.
+func formatIOLimit(kind int, value uint64) string {
+	if kind < 2 {
+		return quantity.Size(value).IECString() + "/s"
+	}
+	return fmt.Sprintf("%d", value)
+}
+
+// getLocalIOLimits returns the io limits of the group per device. This does
+// not include any limits of the parent groups.
+func (grp *Group) getLocalIOLimits() map[string]ioDeviceLimits {
+	if grp.IOLimit == nil || len(grp.IOLimit.Devices) == 0 {
+		return nil
+	}
+	limits := make(map[string]ioDeviceLimits, len(grp.IOLimit.Devices))
+	for _, dev := range grp.IOLimit.Devices {
+		limits[dev.Device] = newIODeviceLimits(dev)
+	}
+	return limits
 }
 
 func max(a, b int) int {
@@ -387,6 +490,13 @@ func maxq(a, b quantity.Size) quantity.Size {
 	return b
 }
 
+func maxu(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 // GetLocalCPUSetQuota returns the current CPU set quota for the group. This
 // does not return any inheritted CPU set quota.
 func (grp *Group) GetLocalCPUSetQuota() []int {
@@ -454,6 +564,7 @@ func (grp *Group) getQuotaAllocations(allQuotas map[string]*groupQuotaAllocation
 		CPULimit:     grp.getCurrentCPUAllocation(),
 		ThreadsLimit: grp.ThreadLimit,
 		CPUSetLimit:  grp.GetLocalCPUSetQuota(),
+		IOLimits:     grp.getLocalIOLimits(),
 	}
 
 	// sliceUniqueAndSort sorts an array of ints in ascending order and removes duplicates
@@ -483,6 +594,26 @@ func (grp *Group) getQuotaAllocations(allQuotas map[string]*groupQuotaAllocation
 		limits.CPUReservedByChildren += max(subGroupLimits.CPULimit, subGroupLimits.CPUReservedByChildren)
 		limits.ThreadsReservedByChildren += max(subGroupLimits.ThreadsLimit, subGroupLimits.ThreadsReservedByChildren)
 
+		// The io limits are reserved per device and per kind of limit.
+		subDevices := make(map[string]bool)
+		for device := range subGroupLimits.IOLimits {
+			subDevices[device] = true
+		}
+		for device := range subGroupLimits.IOReservedByChildren {
+			subDevices[device] = true
+		}
+		for device := range subDevices {
+			if limits.IOReservedByChildren == nil {
+				limits.IOReservedByChildren = make(map[string]ioDeviceLimits)
+			}
+			reserved := limits.IOReservedByChildren[device]
+			subLimits, subReserved := subGroupLimits.IOLimits[device], subGroupLimits.IOReservedByChildren[device]
+			for i := range reserved {
+				reserved[i] += maxu(subLimits[i], subReserved[i])
+			}
+			limits.IOReservedByChildren[device] = reserved
+		}
+
 		// We need to merge the allowed CPUs lists, but we need to make sure that the list is unique, since cpu cores
 		// can be reused between sub-groups.
 		if len(subGroupLimits.CPUSetLimit) > 0 {
@@ -720,6 +851,57 @@ func (grp *Group) validateThreadResourceFit(allQuotas map[string]*groupQuotaAllo
 	return nil
 }
 
+// validateIOResourceFit verifies that the new io limits of each device don't conflict with the current reserved
+// io limits of the group, and if not locates for each kind of limit the nearest parent group that has such a limit
+// for the device, and then verifies that the group has space available by checking what is already reserved
+// by its subgroups (excluding the one querying), the same way as for the memory limit.
+func (grp *Group) validateIOResourceFit(allQuotas map[string]*groupQuotaAllocations, devices []ResourceIODevice) error {
+	currentLimits := allQuotas[grp.Name]
+	localLimits := grp.getLocalIOLimits()
+	for _, dev := range devices {
+		for i, limit := range newIODeviceLimits(dev) {
+			if limit == 0 {
+				// the current limit, if any, is kept
+				continue
+			}
+
+			// make sure current usage does not exceed the new limit
+			current := localLimits[dev.Device][i]
+			reserved := current
+			if currentLimits != nil {
+				reservedByChildren := currentLimits.IOReservedByChildren[dev.Device][i]
+				if reservedByChildren > limit {
+					return fmt.Errorf("group io %s limit of %s for %s is too small to fit current subgroup usage of %s",
+						ioLimitNames[i], formatIOLimit(i, limit), dev.Device, formatIOLimit(i, reservedByChildren))
+				}
+
+				// if we are reducing the limit, then we don't need to check upper parents
+				if limit < current {
+					continue
+				}
+
+				reserved = maxu(reserved, reservedByChildren)
+			}
+
+			// now we check parents up the tree to make sure we also fit with any
+			// previous usage limits of our parents.
+			for parent := grp.parentGroup; parent != nil; parent = parent.parentGroup {
+				limits := allQuotas[parent.Name]
+				if limits == nil || limits.IOLimits[dev.Device][i] == 0 {
+					continue
+				}
+				available := limits.IOLimits[dev.Device][i] - (limits.IOReservedByChildren[dev.Device][i] - reserved)
+				if limit > available {
+					return fmt.Errorf("sub-group io %s limit of %s for %s is too large to fit inside group %q remaining quota space %s",
+						ioLimitNames[i], formatIOLimit(i, limit), dev.Device, parent.Name, formatIOLimit(i, available))
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
 // validateQuotasFit verifies that the given group's current limits fits correctly
 // into the group's parent group's limits. This is done in multiple steps, where the first
 // one is to get a statistics for the upper-most parent group, to get a combined overview
@@ -736,7 +918,7 @@ func (grp *Group) validateQuotasFit(resourceLimits Resources) error {
 
 	// for each limit we want to set, we need to find the closes parent
 	// limit that matches it, and then verify against it's usage if we have room
-	if resourceLimits.Memory != nil {
+	if resourceLimits.Memory != nil && resourceLimits.Memory.Limit != 0 {
 		if err := grp.validateMemoryResourceFit(allQuotas, resourceLimits.Memory.Limit); err != nil {
 			return err
 		}
@@ -756,6 +938,11 @@ func (grp *Group) validateQuotasFit(resourceLimits Resources) error {
 			return err
 		}
 	}
+	if resourceLimits.IO != nil && len(resourceLimits.IO.Devices) != 0 {
+		if err := grp.validateIOResourceFit(allQuotas, resourceLimits.IO.Devices); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -774,7 +961,18 @@ func (grp *Group) UpdateQuotaLimits(resourceLimits Resources) error {
 	}
 
 	if resourceLimits.Memory != nil {
-		grp.MemoryLimit = resourceLimits.Memory.Limit
+		if resourceLimits.Memory.Limit != 0 {
+			grp.MemoryLimit = resourceLimits.Memory.Limit
+		}
+		if resourceLimits.Memory.High != 0 {
+			grp.MemoryHighLimit = resourceLimits.Memory.High
+		}
+		if resourceLimits.Memory.OOMPolicy != "" {
+			grp.OOMPolicy = resourceLimits.Memory.OOMPolicy
+		}
+		if resourceLimits.Memory.PressureThreshold != 0 {
+			grp.MemoryPressureThreshold = resourceLimits.Memory.PressureThreshold
+		}
 	}
 	if resourceLimits.CPU != nil {
 		grp.CPULimit = &GroupQuotaCPU{
@@ -804,6 +1002,15 @@ func (grp *Group) UpdateQuotaLimits(resourceLimits Resources) error {
 			grp.JournalLimit.RatePeriod = resourceLimits.Journal.Rate.Period
 		}
 	}
+	if resourceLimits.IO != nil {
+		if grp.IOLimit == nil {
+			grp.IOLimit = &GroupQuotaIO{}
+		}
+		if resourceLimits.IO.Weight != 0 {
+			grp.IOLimit.Weight = resourceLimits.IO.Weight
+		}
+		grp.IOLimit.Devices = mergeIODevices(grp.IOLimit.Devices, resourceLimits.IO.Devices)
+	}
 	return nil
 }
 
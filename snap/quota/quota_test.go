@@ -1041,6 +1041,40 @@ func (ts *quotaTestSuite) TestCurrentMemoryUsage(c *C) {
 	c.Assert(currentMem, Equals, sixteenExb)
 }
 
+func (ts *quotaTestSuite) TestCurrentCPUUsage(c *C) {
+	systemctlCalls := 0
+	r := systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		systemctlCalls++
+		switch systemctlCalls {
+		case 1:
+			// the slice is inactive, so there is no cpu usage
+			c.Assert(args, DeepEquals, []string{"is-active", "snap.group.slice"})
+			return []byte("inactive"), systemctlInactiveServiceError{}
+		case 2:
+			c.Assert(args, DeepEquals, []string{"is-active", "snap.group.slice"})
+			return []byte("active"), nil
+		case 3:
+			c.Assert(args, DeepEquals, []string{"show", "--property", "CPUUsageNSec", "snap.group.slice"})
+			return []byte("CPUUsageNSec=2000000000"), nil
+		default:
+			c.Errorf("too many systemctl calls (%d) (current call is %+v)", systemctlCalls, args)
+			return []byte("broken test"), fmt.Errorf("broken test")
+		}
+	})
+	defer r()
+
+	grp1, err := quota.NewGroup("group", quota.NewResourcesBuilder().WithCPUPercentage(50).Build())
+	c.Assert(err, IsNil)
+
+	cpuUsage, err := grp1.CurrentCPUUsage()
+	c.Assert(err, IsNil)
+	c.Check(cpuUsage, Equals, time.Duration(0))
+
+	cpuUsage, err = grp1.CurrentCPUUsage()
+	c.Assert(err, IsNil)
+	c.Check(cpuUsage, Equals, 2*time.Second)
+}
+
 func (ts *quotaTestSuite) TestCurrentTaskUsage(c *C) {
 	systemctlCalls := 0
 	r := systemd.MockSystemctl(func(args ...string) ([]byte, error) {
@@ -1575,6 +1609,107 @@ func (ts *quotaTestSuite) TestJournalQuotasUpdatesCorrectly(c *C) {
 	c.Check(grp1.JournalLimit.RatePeriod, Equals, time.Microsecond*5)
 }
 
+func (ts *quotaTestSuite) TestIOQuotasSetAndUpdate(c *C) {
+	grp, err := quota.NewGroup("groot", quota.NewResourcesBuilder().WithIOWeight(200).Build())
+	c.Assert(err, IsNil)
+	c.Check(grp.IOLimit, DeepEquals, &quota.GroupQuotaIO{Weight: 200})
+
+	err = grp.UpdateQuotaLimits(quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", ReadBandwidth: quantity.SizeMiB, ReadIOPS: 100},
+	}).Build())
+	c.Assert(err, IsNil)
+	err = grp.UpdateQuotaLimits(quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", WriteBandwidth: quantity.SizeMiB},
+	}).Build())
+	c.Assert(err, IsNil)
+	c.Check(grp.IOLimit, DeepEquals, &quota.GroupQuotaIO{
+		Weight: 200,
+		Devices: []quota.ResourceIODevice{
+			{Device: "/dev/sda", ReadBandwidth: quantity.SizeMiB, WriteBandwidth: quantity.SizeMiB, ReadIOPS: 100},
+		},
+	})
+	c.Check(grp.GetQuotaResources().IO, DeepEquals, &quota.ResourceIO{
+		Weight:  200,
+		Devices: grp.IOLimit.Devices,
+	})
+}
+
+func (ts *quotaTestSuite) TestMemoryHighLimitAndOOMPolicy(c *C) {
+	grp, err := quota.NewGroup("groot", quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeGiB).WithMemoryHighLimit(768*quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+	c.Check(grp.MemoryLimit, Equals, quantity.SizeGiB)
+	c.Check(grp.MemoryHighLimit, Equals, 768*quantity.SizeMiB)
+	c.Check(grp.OOMPolicy, Equals, quota.OOMPolicy(""))
+
+	limits := grp.GetQuotaResources()
+	c.Assert(limits.Change(quota.NewResourcesBuilder().WithOOMPolicy(quota.OOMPolicyRestartService).Build()), IsNil)
+	c.Assert(grp.UpdateQuotaLimits(limits), IsNil)
+	c.Check(grp.MemoryLimit, Equals, quantity.SizeGiB)
+	c.Check(grp.MemoryHighLimit, Equals, 768*quantity.SizeMiB)
+	c.Check(grp.OOMPolicy, Equals, quota.OOMPolicyRestartService)
+	c.Check(grp.GetQuotaResources().Memory, DeepEquals, &quota.ResourceMemory{
+		Limit:     quantity.SizeGiB,
+		High:      768 * quantity.SizeMiB,
+		OOMPolicy: quota.OOMPolicyRestartService,
+	})
+
+	limits = grp.GetQuotaResources()
+	c.Assert(limits.Change(quota.NewResourcesBuilder().WithMemoryPressureThreshold(60).Build()), IsNil)
+	c.Assert(grp.UpdateQuotaLimits(limits), IsNil)
+	c.Check(grp.MemoryPressureThreshold, Equals, 60)
+	c.Check(grp.GetQuotaResources().Memory, DeepEquals, &quota.ResourceMemory{
+		Limit:             quantity.SizeGiB,
+		High:              768 * quantity.SizeMiB,
+		OOMPolicy:         quota.OOMPolicyRestartService,
+		PressureThreshold: 60,
+	})
+}
+
+func (ts *quotaTestSuite) TestIOQuotasNestedFit(c *C) {
+	grp, err := quota.NewGroup("groot", quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", ReadBandwidth: 100 * quantity.SizeMiB, WriteIOPS: 1000},
+	}).Build())
+	c.Assert(err, IsNil)
+
+	// a middle group without io limits
+	middle, err := grp.NewSubGroup("middle", quota.NewResourcesBuilder().WithThreadLimit(32).Build())
+	c.Assert(err, IsNil)
+
+	_, err = middle.NewSubGroup("sub1", quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", ReadBandwidth: 60 * quantity.SizeMiB, WriteIOPS: 600},
+		// not limited by the parent
+		{Device: "/dev/sdb", ReadBandwidth: quantity.SizeGiB},
+	}).Build())
+	c.Assert(err, IsNil)
+
+	_, err = middle.NewSubGroup("sub2", quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", ReadBandwidth: 50 * quantity.SizeMiB},
+	}).Build())
+	c.Check(err, ErrorMatches, `sub-group io read bandwidth limit of 50 MiB/s for /dev/sda is too large to fit inside group "groot" remaining quota space 40 MiB/s`)
+
+	_, err = middle.NewSubGroup("sub2", quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", WriteIOPS: 500},
+	}).Build())
+	c.Check(err, ErrorMatches, `sub-group io write iops limit of 500 for /dev/sda is too large to fit inside group "groot" remaining quota space 400`)
+
+	sub2, err := middle.NewSubGroup("sub2", quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", ReadBandwidth: 40 * quantity.SizeMiB, WriteIOPS: 400},
+	}).Build())
+	c.Assert(err, IsNil)
+
+	// the limits of the parent cannot go below what the sub-groups use
+	err = grp.UpdateQuotaLimits(quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", ReadBandwidth: 90 * quantity.SizeMiB},
+	}).Build())
+	c.Check(err, ErrorMatches, `group io read bandwidth limit of 90 MiB/s for /dev/sda is too small to fit current subgroup usage of 100 MiB/s`)
+
+	// sub-groups can lower their limits
+	err = sub2.UpdateQuotaLimits(quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", ReadBandwidth: 20 * quantity.SizeMiB},
+	}).Build())
+	c.Check(err, IsNil)
+}
+
 func (ts *quotaTestSuite) TestServiceMapEmptyOnEmptyGroup(c *C) {
 	rootGrp, err := quota.NewGroup("myroot", quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeGiB).Build())
 	c.Assert(err, IsNil)
@@ -21,6 +21,8 @@ package quota
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/snapcore/snapd/gadget/quantity"
@@ -39,8 +41,31 @@ func init() {
 	cgroupCheckMemoryCgroupErr = cgroup.CheckMemoryCgroup()
 }
 
+// OOMPolicy is what snapd does when processes of a quota group are killed
+// by the kernel for running out of memory.
+type OOMPolicy string
+
+const (
+	// OOMPolicyNotify only records a notice about the processes killed,
+	// this is the default.
+	OOMPolicyNotify OOMPolicy = "notify"
+	// OOMPolicyRestartService restarts the services which had processes
+	// killed.
+	OOMPolicyRestartService OOMPolicy = "restart-service"
+	// OOMPolicyRestartGroup restarts all the services of the quota group.
+	OOMPolicyRestartGroup OOMPolicy = "restart-group"
+)
+
 type ResourceMemory struct {
 	Limit quantity.Size `json:"limit"`
+	// High is the soft limit above which the processes of the group are
+	// throttled and put under heavy reclaim pressure.
+	High      quantity.Size `json:"high,omitempty"`
+	OOMPolicy OOMPolicy     `json:"oom-policy,omitempty"`
+	// PressureThreshold is the percentage of time the processes of the
+	// group can be stalled waiting for memory, averaged over 10 seconds,
+	// above which the OOM policy is applied.
+	PressureThreshold int `json:"pressure-threshold,omitempty"`
 }
 
 type ResourceCPU struct {
@@ -75,6 +100,25 @@ type ResourceJournal struct {
 	Rate *ResourceJournalRate `json:"rate,omitempty"`
 }
 
+// ResourceIODevice holds the IO limits of a single block device. A value of
+// 0 means that there is no such limit for the device.
+type ResourceIODevice struct {
+	// Device is the path to the device node of the block device.
+	Device string `json:"device"`
+	// ReadBandwidth and WriteBandwidth are expressed in bytes per second.
+	ReadBandwidth  quantity.Size `json:"read-bandwidth,omitempty"`
+	WriteBandwidth quantity.Size `json:"write-bandwidth,omitempty"`
+	ReadIOPS       int           `json:"read-iops,omitempty"`
+	WriteIOPS      int           `json:"write-iops,omitempty"`
+}
+
+// ResourceIO represents the block IO quotas, the weight of the group when
+// competing for IO with its siblings and the limits of individual devices.
+type ResourceIO struct {
+	Weight  int                `json:"weight,omitempty"`
+	Devices []ResourceIODevice `json:"devices,omitempty"`
+}
+
 // Resources are built up of multiple quota limits. Each quota limit is a pointer
 // value to indicate that their presence may be optional, and because we want to detect
 // whenever someone changes a limit to '0' explicitly.
@@ -84,6 +128,7 @@ type Resources struct {
 	CPUSet  *ResourceCPUSet  `json:"cpu-set,omitempty"`
 	Threads *ResourceThreads `json:"thread,omitempty"`
 	Journal *ResourceJournal `json:"journal,omitempty"`
+	IO      *ResourceIO      `json:"io,omitempty"`
 }
 
 const (
@@ -99,6 +144,10 @@ const (
 	// usage, but we have selected 64kB to protect against ridiculously small values.
 	journalLimitMin = 64 * quantity.SizeKiB
 	journalLimitMax = 4 * quantity.SizeGiB
+
+	// The range of IO weights accepted by systemd.
+	ioWeightMin = 1
+	ioWeightMax = 10000
 )
 
 func (qr *Resources) validateMemoryQuota() error {
@@ -106,6 +155,19 @@ func (qr *Resources) validateMemoryQuota() error {
 	if qr.Memory.Limit == 0 {
 		return fmt.Errorf("memory quota must have a limit set")
 	}
+	if qr.Memory.High != 0 && qr.Memory.High >= qr.Memory.Limit {
+		return fmt.Errorf("memory high limit %s must be smaller than the memory limit %s",
+			qr.Memory.High.IECString(), qr.Memory.Limit.IECString())
+	}
+	if qr.Memory.PressureThreshold < 0 || qr.Memory.PressureThreshold > 100 {
+		return fmt.Errorf("memory pressure threshold %d must be between 1 and 100", qr.Memory.PressureThreshold)
+	}
+	switch qr.Memory.OOMPolicy {
+	case "", OOMPolicyNotify, OOMPolicyRestartService, OOMPolicyRestartGroup:
+	default:
+		return fmt.Errorf("invalid oom policy %q: must be one of %q, %q or %q",
+			qr.Memory.OOMPolicy, OOMPolicyNotify, OOMPolicyRestartService, OOMPolicyRestartGroup)
+	}
 	return nil
 }
 
@@ -186,6 +248,67 @@ func (qr *Resources) validateJournalQuota() error {
 	return nil
 }
 
+func (qr *Resources) validateIOQuota() error {
+	if qr.IO.Weight == 0 && len(qr.IO.Devices) == 0 {
+		return fmt.Errorf("io quota must have a weight or a device limit set")
+	}
+	if qr.IO.Weight != 0 && (qr.IO.Weight < ioWeightMin || qr.IO.Weight > ioWeightMax) {
+		return fmt.Errorf("invalid io weight %d: must be between %d and %d", qr.IO.Weight, ioWeightMin, ioWeightMax)
+	}
+
+	seen := make(map[string]bool, len(qr.IO.Devices))
+	for _, dev := range qr.IO.Devices {
+		if !strings.HasPrefix(dev.Device, "/dev/") || filepath.Clean(dev.Device) != dev.Device {
+			return fmt.Errorf("invalid io quota device %q: must be a path under /dev", dev.Device)
+		}
+		if seen[dev.Device] {
+			return fmt.Errorf("io quota for device %q is set more than once", dev.Device)
+		}
+		seen[dev.Device] = true
+		if dev.ReadIOPS < 0 || dev.WriteIOPS < 0 {
+			return fmt.Errorf("invalid io quota for device %q: iops limits must be positive", dev.Device)
+		}
+		if dev == (ResourceIODevice{Device: dev.Device}) {
+			return fmt.Errorf("io quota for device %q must have a limit set", dev.Device)
+		}
+	}
+	return nil
+}
+
+// mergeIODevices returns the device limits resulting from applying the
+// updated limits on top of the current ones. Limits of a device which are
+// not set in the update are left as they are.
+func mergeIODevices(current, updates []ResourceIODevice) []ResourceIODevice {
+	if len(current) == 0 && len(updates) == 0 {
+		return nil
+	}
+	merged := make([]ResourceIODevice, len(current), len(current)+len(updates))
+	copy(merged, current)
+	for _, upd := range updates {
+		i := 0
+		for i < len(merged) && merged[i].Device != upd.Device {
+			i++
+		}
+		if i == len(merged) {
+			merged = append(merged, ResourceIODevice{Device: upd.Device})
+		}
+		dev := &merged[i]
+		if upd.ReadBandwidth != 0 {
+			dev.ReadBandwidth = upd.ReadBandwidth
+		}
+		if upd.WriteBandwidth != 0 {
+			dev.WriteBandwidth = upd.WriteBandwidth
+		}
+		if upd.ReadIOPS != 0 {
+			dev.ReadIOPS = upd.ReadIOPS
+		}
+		if upd.WriteIOPS != 0 {
+			dev.WriteIOPS = upd.WriteIOPS
+		}
+	}
+	return merged
+}
+
 // CheckFeatureRequirements checks if the current system meets the
 // requirements for the given resource request.
 //
@@ -202,6 +325,28 @@ func (qr *Resources) CheckFeatureRequirements() error {
 	if qr.Memory != nil && cgroupCheckMemoryCgroupErr != nil {
 		return fmt.Errorf("cannot use memory quota: %v", cgroupCheckMemoryCgroupErr)
 	}
+	if qr.Memory != nil && (qr.Memory.High != 0 || qr.Memory.PressureThreshold != 0) {
+		if cgroupVerErr != nil {
+			return cgroupVerErr
+		}
+		if cgroupVer < 2 {
+			what := "memory high limit"
+			if qr.Memory.High == 0 {
+				// memory pressure information is only available
+				// with cgroup v2
+				what = "memory pressure threshold"
+			}
+			return fmt.Errorf("cannot use %s with cgroup version %d", what, cgroupVer)
+		}
+	}
+	if qr.IO != nil {
+		if cgroupVerErr != nil {
+			return cgroupVerErr
+		}
+		if cgroupVer < 2 {
+			return fmt.Errorf("cannot use io quota with cgroup version %d", cgroupVer)
+		}
+	}
 
 	return nil
 }
@@ -251,6 +396,12 @@ func (qr *Resources) Validate() error {
 			return err
 		}
 	}
+
+	if qr.IO != nil {
+		if err := qr.validateIOQuota(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -260,8 +411,11 @@ func (qr *Resources) Validate() error {
 // or to make sure that certain/all limits are not removed.
 // We also require memory limits are above 640kB.
 func (qr *Resources) ValidateChange(newLimits Resources) error {
-	// Check that the memory limit is not being decreased
-	if newLimits.Memory != nil {
+	// Check that the memory limit is not being decreased, the high limit and
+	// the oom policy can be changed without repeating the limit
+	changesMemoryHighOrPolicy := newLimits.Memory != nil && newLimits.Memory.Limit == 0 &&
+		(newLimits.Memory.High != 0 || newLimits.Memory.OOMPolicy != "" || newLimits.Memory.PressureThreshold != 0)
+	if newLimits.Memory != nil && !changesMemoryHighOrPolicy {
 		if qr.Memory != nil && newLimits.Memory.Limit == 0 {
 			return fmt.Errorf("cannot remove memory limit from quota group")
 		}
@@ -354,7 +508,12 @@ func (qr *Resources) ValidateChange(newLimits Resources) error {
 func (qr *Resources) clone() Resources {
 	var resourcesCopy Resources
 	if qr.Memory != nil {
-		resourcesCopy.Memory = &ResourceMemory{Limit: qr.Memory.Limit}
+		resourcesCopy.Memory = &ResourceMemory{
+			Limit:             qr.Memory.Limit,
+			High:              qr.Memory.High,
+			OOMPolicy:         qr.Memory.OOMPolicy,
+			PressureThreshold: qr.Memory.PressureThreshold,
+		}
 	}
 	if qr.CPU != nil {
 		resourcesCopy.CPU = &ResourceCPU{Count: qr.CPU.Count, Percentage: qr.CPU.Percentage}
@@ -374,13 +533,30 @@ func (qr *Resources) clone() Resources {
 			resourcesCopy.Journal.Rate = &ResourceJournalRate{Count: qr.Journal.Rate.Count, Period: qr.Journal.Rate.Period}
 		}
 	}
+	if qr.IO != nil {
+		resourcesCopy.IO = &ResourceIO{Weight: qr.IO.Weight, Devices: mergeIODevices(qr.IO.Devices, nil)}
+	}
 	return resourcesCopy
 }
 
 // changeInternal applies each new limit provided
 func (qr *Resources) changeInternal(newLimits Resources) {
 	if newLimits.Memory != nil {
-		qr.Memory = newLimits.Memory
+		if qr.Memory == nil {
+			qr.Memory = &ResourceMemory{}
+		}
+		if newLimits.Memory.Limit != 0 {
+			qr.Memory.Limit = newLimits.Memory.Limit
+		}
+		if newLimits.Memory.High != 0 {
+			qr.Memory.High = newLimits.Memory.High
+		}
+		if newLimits.Memory.OOMPolicy != "" {
+			qr.Memory.OOMPolicy = newLimits.Memory.OOMPolicy
+		}
+		if newLimits.Memory.PressureThreshold != 0 {
+			qr.Memory.PressureThreshold = newLimits.Memory.PressureThreshold
+		}
 	}
 	if newLimits.CPU != nil {
 		qr.CPU = newLimits.CPU
@@ -402,6 +578,15 @@ func (qr *Resources) changeInternal(newLimits Resources) {
 			qr.Journal.Rate = newLimits.Journal.Rate
 		}
 	}
+	if newLimits.IO != nil {
+		if qr.IO == nil {
+			qr.IO = &ResourceIO{}
+		}
+		if newLimits.IO.Weight != 0 {
+			qr.IO.Weight = newLimits.IO.Weight
+		}
+		qr.IO.Devices = mergeIODevices(qr.IO.Devices, newLimits.IO.Devices)
+	}
 }
 
 // Change updates the current quota limits with the new limits. Additional verification
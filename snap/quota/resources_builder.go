@@ -29,6 +29,15 @@ type ResourcesBuilder struct {
 	MemoryLimit    quantity.Size
 	MemoryLimitSet bool
 
+	MemoryHighLimit    quantity.Size
+	MemoryHighLimitSet bool
+
+	OOMPolicy    OOMPolicy
+	OOMPolicySet bool
+
+	MemoryPressureThreshold    int
+	MemoryPressureThresholdSet bool
+
 	CPUCount    int
 	CPUCountSet bool
 
@@ -49,6 +58,12 @@ type ResourcesBuilder struct {
 	JournalRateCountLimit  int
 	JournalRatePeriodLimit time.Duration
 	JournalRateSet         bool
+
+	IOWeight    int
+	IOWeightSet bool
+
+	IODevices    []ResourceIODevice
+	IODevicesSet bool
 }
 
 func (rb *ResourcesBuilder) WithMemoryLimit(limit quantity.Size) *ResourcesBuilder {
@@ -57,6 +72,24 @@ func (rb *ResourcesBuilder) WithMemoryLimit(limit quantity.Size) *ResourcesBuild
 	return rb
 }
 
+func (rb *ResourcesBuilder) WithMemoryHighLimit(limit quantity.Size) *ResourcesBuilder {
+	rb.MemoryHighLimit = limit
+	rb.MemoryHighLimitSet = true
+	return rb
+}
+
+func (rb *ResourcesBuilder) WithOOMPolicy(policy OOMPolicy) *ResourcesBuilder {
+	rb.OOMPolicy = policy
+	rb.OOMPolicySet = true
+	return rb
+}
+
+func (rb *ResourcesBuilder) WithMemoryPressureThreshold(percentage int) *ResourcesBuilder {
+	rb.MemoryPressureThreshold = percentage
+	rb.MemoryPressureThresholdSet = true
+	return rb
+}
+
 func (rb *ResourcesBuilder) WithCPUCount(count int) *ResourcesBuilder {
 	rb.CPUCount = count
 	rb.CPUCountSet = true
@@ -99,11 +132,26 @@ func (rb *ResourcesBuilder) WithJournalRate(count int, period time.Duration) *Re
 	return rb
 }
 
+func (rb *ResourcesBuilder) WithIOWeight(weight int) *ResourcesBuilder {
+	rb.IOWeight = weight
+	rb.IOWeightSet = true
+	return rb
+}
+
+func (rb *ResourcesBuilder) WithIODeviceLimits(devices []ResourceIODevice) *ResourcesBuilder {
+	rb.IODevices = devices
+	rb.IODevicesSet = true
+	return rb
+}
+
 func (rb *ResourcesBuilder) Build() Resources {
 	var quotaResources Resources
-	if rb.MemoryLimitSet {
+	if rb.MemoryLimitSet || rb.MemoryHighLimitSet || rb.OOMPolicySet || rb.MemoryPressureThresholdSet {
 		quotaResources.Memory = &ResourceMemory{
-			Limit: rb.MemoryLimit,
+			Limit:             rb.MemoryLimit,
+			High:              rb.MemoryHighLimit,
+			OOMPolicy:         rb.OOMPolicy,
+			PressureThreshold: rb.MemoryPressureThreshold,
 		}
 	}
 	if rb.CPUCountSet || rb.CPUPercentageSet {
@@ -136,6 +184,12 @@ func (rb *ResourcesBuilder) Build() Resources {
 			}
 		}
 	}
+	if rb.IOWeightSet || rb.IODevicesSet {
+		quotaResources.IO = &ResourceIO{
+			Weight:  rb.IOWeight,
+			Devices: rb.IODevices,
+		}
+	}
 	return quotaResources
 }
 
@@ -50,6 +50,19 @@ func (s *resourcesTestSuite) TestQuotaValidationFails(c *C) {
 		{quota.NewResourcesBuilder().WithJournalRate(0, 1).Build(), `journal quota must have a period of at least 1 microsecond \(minimum resolution\)`},
 		{quota.NewResourcesBuilder().WithJournalRate(1, time.Nanosecond).Build(), `journal quota must have a period of at least 1 microsecond \(minimum resolution\)`},
 		{quota.NewResourcesBuilder().WithJournalSize(0).Build(), `journal size quota must have a limit set`},
+		{quota.NewResourcesBuilder().WithIOWeight(0).Build(), `io quota must have a weight or a device limit set`},
+		{quota.NewResourcesBuilder().WithIOWeight(10001).Build(), `invalid io weight 10001: must be between 1 and 10000`},
+		{quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{{Device: "sda", ReadIOPS: 1}}).Build(), `invalid io quota device "sda": must be a path under /dev`},
+		{quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{{Device: "/dev/../etc", ReadIOPS: 1}}).Build(), `invalid io quota device "/dev/../etc": must be a path under /dev`},
+		{quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{{Device: "/dev/sda"}}).Build(), `io quota for device "/dev/sda" must have a limit set`},
+		{quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{{Device: "/dev/sda", ReadIOPS: -1}}).Build(), `invalid io quota for device "/dev/sda": iops limits must be positive`},
+		{quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{{Device: "/dev/sda", ReadIOPS: 1}, {Device: "/dev/sda", WriteIOPS: 1}}).Build(), `io quota for device "/dev/sda" is set more than once`},
+		{quota.NewResourcesBuilder().WithMemoryHighLimit(quantity.SizeMiB).Build(), `memory quota must have a limit set`},
+		{quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).WithMemoryHighLimit(quantity.SizeMiB).Build(), `memory high limit 1 MiB must be smaller than the memory limit 1 MiB`},
+		{quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).WithOOMPolicy("reboot").Build(), `invalid oom policy "reboot": must be one of "notify", "restart-service" or "restart-group"`},
+		{quota.NewResourcesBuilder().WithMemoryPressureThreshold(40).Build(), `memory quota must have a limit set`},
+		{quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).WithMemoryPressureThreshold(101).Build(), `memory pressure threshold 101 must be between 1 and 100`},
+		{quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).WithMemoryPressureThreshold(-1).Build(), `memory pressure threshold -1 must be between 1 and 100`},
 	}
 
 	for _, t := range tests {
@@ -337,6 +350,92 @@ func (s *resourcesTestSuite) TestResourceCloneComplete(c *C) {
 	}
 }
 
+func (s *resourcesTestSuite) TestResourceCheckFeatureRequirementsIOCgroupv1(c *C) {
+	r := quota.MockCgroupVer(1)
+	defer r()
+
+	res := quota.NewResourcesBuilder().WithIOWeight(100).Build()
+	c.Check(res.CheckFeatureRequirements(), ErrorMatches, "cannot use io quota with cgroup version 1")
+
+	r = quota.MockCgroupVer(2)
+	defer r()
+	c.Check(res.CheckFeatureRequirements(), IsNil)
+}
+
+func (s *resourcesTestSuite) TestQuotaChangeIOMergesDevices(c *C) {
+	limits := quota.NewResourcesBuilder().WithIOWeight(100).WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", ReadBandwidth: 10 * quantity.SizeMiB},
+	}).Build()
+	err := limits.Change(quota.NewResourcesBuilder().WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", WriteIOPS: 500},
+		{Device: "/dev/sdb", ReadIOPS: 100},
+	}).Build())
+	c.Assert(err, IsNil)
+	c.Check(limits.IO, DeepEquals, &quota.ResourceIO{
+		Weight: 100,
+		Devices: []quota.ResourceIODevice{
+			{Device: "/dev/sda", ReadBandwidth: 10 * quantity.SizeMiB, WriteIOPS: 500},
+			{Device: "/dev/sdb", ReadIOPS: 100},
+		},
+	})
+
+	err = limits.Change(quota.NewResourcesBuilder().WithIOWeight(20000).Build())
+	c.Check(err, ErrorMatches, `invalid io weight 20000: must be between 1 and 10000`)
+	c.Check(limits.IO.Weight, Equals, 100)
+}
+
+func (s *resourcesTestSuite) TestResourceCheckFeatureRequirementsMemoryHighCgroupv1(c *C) {
+	r := quota.MockCgroupVer(1)
+	defer r()
+
+	res := quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeGiB).WithMemoryHighLimit(quantity.SizeMiB).Build()
+	c.Check(res.CheckFeatureRequirements(), ErrorMatches, "cannot use memory high limit with cgroup version 1")
+
+	res = quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeGiB).WithMemoryPressureThreshold(40).Build()
+	c.Check(res.CheckFeatureRequirements(), ErrorMatches, "cannot use memory pressure threshold with cgroup version 1")
+
+	// the oom policy alone works with either version
+	res = quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeGiB).WithOOMPolicy(quota.OOMPolicyRestartService).Build()
+	c.Check(res.CheckFeatureRequirements(), IsNil)
+}
+
+func (s *resourcesTestSuite) TestQuotaChangeMemoryHighAndOOMPolicy(c *C) {
+	limits := quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeGiB).Build()
+
+	// the high limit and the policy can be changed without the limit
+	err := limits.Change(quota.NewResourcesBuilder().WithMemoryHighLimit(512 * quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+	err = limits.Change(quota.NewResourcesBuilder().WithOOMPolicy(quota.OOMPolicyRestartGroup).Build())
+	c.Assert(err, IsNil)
+	c.Check(limits.Memory, DeepEquals, &quota.ResourceMemory{
+		Limit:     quantity.SizeGiB,
+		High:      512 * quantity.SizeMiB,
+		OOMPolicy: quota.OOMPolicyRestartGroup,
+	})
+
+	// raising the limit keeps the high limit and the policy
+	err = limits.Change(quota.NewResourcesBuilder().WithMemoryLimit(2 * quantity.SizeGiB).Build())
+	c.Assert(err, IsNil)
+	c.Check(limits.Memory, DeepEquals, &quota.ResourceMemory{
+		Limit:     2 * quantity.SizeGiB,
+		High:      512 * quantity.SizeMiB,
+		OOMPolicy: quota.OOMPolicyRestartGroup,
+	})
+
+	err = limits.Change(quota.NewResourcesBuilder().WithMemoryHighLimit(4 * quantity.SizeGiB).Build())
+	c.Check(err, ErrorMatches, `memory high limit 4 GiB must be smaller than the memory limit 2 GiB`)
+
+	// the pressure threshold can be changed without the limit too
+	err = limits.Change(quota.NewResourcesBuilder().WithMemoryPressureThreshold(40).Build())
+	c.Assert(err, IsNil)
+	c.Check(limits.Memory, DeepEquals, &quota.ResourceMemory{
+		Limit:             2 * quantity.SizeGiB,
+		High:              512 * quantity.SizeMiB,
+		OOMPolicy:         quota.OOMPolicyRestartGroup,
+		PressureThreshold: 40,
+	})
+}
+
 func (s *resourcesTestSuite) TestResourceBuilerWithJournalNamespaceOnly(c *C) {
 	r := quota.NewResourcesBuilder().WithJournalNamespace().Build()
 	c.Assert(r.Journal, NotNil)
@@ -0,0 +1,132 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package snapdelta creates and applies deltas between snap files, in the
+// xdelta3 format also served by the store.
+package snapdelta
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snapdtool"
+)
+
+// Format is the format of the deltas.
+const Format = "xdelta3"
+
+// ErrNoXdelta3 is returned when no working xdelta3 can be found.
+var ErrNoXdelta3 = errors.New("cannot find a working xdelta3, it is needed to create and apply deltas")
+
+var (
+	commandFromSystemSnap = snapdtool.CommandFromSystemSnap
+	execLookPath          = exec.LookPath
+)
+
+// MockCommandFromSystemSnap replaces the lookup of commands from the system
+// snap, for tests.
+func MockCommandFromSystemSnap(f func(name string, args ...string) (*exec.Cmd, error)) (restore func()) {
+	old := commandFromSystemSnap
+	commandFromSystemSnap = f
+	return func() {
+		commandFromSystemSnap = old
+	}
+}
+
+// Xdelta3Cmd returns a function building xdelta3 commands. The xdelta3
+// from the system snap is preferred to the one of the host, as long as it
+// works. ErrNoXdelta3 is returned if neither can be used.
+func Xdelta3Cmd() (func(args ...string) *exec.Cmd, error) {
+	cmd, err := commandFromSystemSnap("/usr/bin/xdelta3", "config")
+	if err == nil {
+		if runErr := cmd.Run(); runErr == nil {
+			// reuse the command exactly as we got it, minus the
+			// trailing "config" argument
+			exe := cmd.Path
+			args := cmd.Args[:len(cmd.Args)-1]
+			env := cmd.Env
+			dir := cmd.Dir
+			return func(xdelta3Args ...string) *exec.Cmd {
+				return &exec.Cmd{
+					Path: exe,
+					Args: append(append([]string(nil), args...), xdelta3Args...),
+					Env:  env,
+					Dir:  dir,
+				}
+			}, nil
+		} else {
+			logger.Noticef("unable to use system snap provided xdelta3, running config command failed: %v", runErr)
+		}
+	}
+
+	loc, err := execLookPath("xdelta3")
+	if err != nil {
+		logger.Noticef("no host system xdelta3 available")
+		return nil, ErrNoXdelta3
+	}
+	if err := exec.Command(loc, "config").Run(); err != nil {
+		logger.Noticef("unable to use host system xdelta3, running config command failed: %v", err)
+		return nil, ErrNoXdelta3
+	}
+	return func(args ...string) *exec.Cmd {
+		return exec.Command(loc, args...)
+	}, nil
+}
+
+func runXdelta3(args ...string) error {
+	xdelta3, err := Xdelta3Cmd()
+	if err != nil {
+		return err
+	}
+	if output, err := xdelta3(args...).CombinedOutput(); err != nil {
+		return osutil.OutputErr(output, err)
+	}
+	return nil
+}
+
+// Create writes to deltaPath the delta turning the snap at oldPath into
+// the one at newPath. An existing file at deltaPath is overwritten.
+func Create(oldPath, newPath, deltaPath string) error {
+	for _, p := range []string{oldPath, newPath} {
+		if !osutil.FileExists(p) {
+			return fmt.Errorf("cannot create delta: %q does not exist", p)
+		}
+	}
+	if err := runXdelta3("-e", "-f", "-s", oldPath, newPath, deltaPath); err != nil {
+		return fmt.Errorf("cannot create delta: %w", err)
+	}
+	return nil
+}
+
+// Apply reconstructs at targetPath the snap obtained by applying the delta
+// at deltaPath to the snap at sourcePath. An existing file at targetPath
+// is overwritten, and left behind on error. The reconstructed snap is not
+// verified in any way, that is up to the caller.
+func Apply(sourcePath, deltaPath, targetPath string) error {
+	if !osutil.FileExists(sourcePath) {
+		return fmt.Errorf("cannot apply delta: source snap %q does not exist", sourcePath)
+	}
+	if err := runXdelta3("-d", "-f", "-s", sourcePath, deltaPath, targetPath); err != nil {
+		return fmt.Errorf("cannot apply delta: %w", err)
+	}
+	return nil
+}
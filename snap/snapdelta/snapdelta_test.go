@@ -0,0 +1,163 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapdelta_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/snap/snapdelta"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type deltaSuite struct {
+	testutil.BaseTest
+
+	dir string
+}
+
+var _ = Suite(&deltaSuite{})
+
+func (s *deltaSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.dir = c.MkDir()
+
+	// no system snap xdelta3 unless a test says otherwise
+	s.AddCleanup(snapdelta.MockCommandFromSystemSnap(func(name string, args ...string) (*exec.Cmd, error) {
+		return nil, errors.New("no system snap")
+	}))
+
+	for _, name := range []string{"old.snap", "new.snap"} {
+		c.Assert(os.WriteFile(filepath.Join(s.dir, name), []byte(name), 0644), IsNil)
+	}
+}
+
+// mockXdelta3 mocks a host xdelta3 writing "delta" to its last argument
+func (s *deltaSuite) mockXdelta3(c *C) *testutil.MockCmd {
+	cmd := testutil.MockCommand(c, "xdelta3", `
+if [ "$1" = config ]; then exit 0; fi
+for arg; do out="$arg"; done
+echo delta > "$out"
+`)
+	s.AddCleanup(cmd.Restore)
+	return cmd
+}
+
+func (s *deltaSuite) TestCreate(c *C) {
+	xdelta3 := s.mockXdelta3(c)
+
+	oldPath := filepath.Join(s.dir, "old.snap")
+	newPath := filepath.Join(s.dir, "new.snap")
+	deltaPath := filepath.Join(s.dir, "new.delta")
+	err := snapdelta.Create(oldPath, newPath, deltaPath)
+	c.Assert(err, IsNil)
+	c.Check(deltaPath, testutil.FileEquals, "delta\n")
+	c.Check(xdelta3.Calls(), DeepEquals, [][]string{
+		{"xdelta3", "config"},
+		{"xdelta3", "-e", "-f", "-s", oldPath, newPath, deltaPath},
+	})
+}
+
+func (s *deltaSuite) TestCreateMissingSnap(c *C) {
+	xdelta3 := s.mockXdelta3(c)
+
+	missing := filepath.Join(s.dir, "missing.snap")
+	err := snapdelta.Create(filepath.Join(s.dir, "old.snap"), missing, filepath.Join(s.dir, "new.delta"))
+	c.Assert(err, ErrorMatches, `cannot create delta: ".*/missing.snap" does not exist`)
+	c.Check(xdelta3.Calls(), HasLen, 0)
+}
+
+func (s *deltaSuite) TestApply(c *C) {
+	xdelta3 := s.mockXdelta3(c)
+
+	sourcePath := filepath.Join(s.dir, "old.snap")
+	deltaPath := filepath.Join(s.dir, "new.delta")
+	targetPath := filepath.Join(s.dir, "target.snap")
+	err := snapdelta.Apply(sourcePath, deltaPath, targetPath)
+	c.Assert(err, IsNil)
+	c.Check(targetPath, testutil.FileEquals, "delta\n")
+	c.Check(xdelta3.Calls(), DeepEquals, [][]string{
+		{"xdelta3", "config"},
+		{"xdelta3", "-d", "-f", "-s", sourcePath, deltaPath, targetPath},
+	})
+}
+
+func (s *deltaSuite) TestApplyMissingSource(c *C) {
+	s.mockXdelta3(c)
+
+	err := snapdelta.Apply(filepath.Join(s.dir, "missing.snap"), filepath.Join(s.dir, "new.delta"), filepath.Join(s.dir, "target.snap"))
+	c.Assert(err, ErrorMatches, `cannot apply delta: source snap ".*/missing.snap" does not exist`)
+}
+
+func (s *deltaSuite) TestApplyFails(c *C) {
+	cmd := testutil.MockCommand(c, "xdelta3", `
+if [ "$1" = config ]; then exit 0; fi
+echo "xdelta3: target window checksum mismatch" >&2
+exit 1
+`)
+	s.AddCleanup(cmd.Restore)
+
+	err := snapdelta.Apply(filepath.Join(s.dir, "old.snap"), filepath.Join(s.dir, "new.delta"), filepath.Join(s.dir, "target.snap"))
+	c.Assert(err, ErrorMatches, `cannot apply delta: xdelta3: target window checksum mismatch`)
+}
+
+func (s *deltaSuite) TestNoXdelta3(c *C) {
+	s.AddCleanup(testutil.MockCommand(c, "xdelta3", "exit 1").Restore)
+
+	err := snapdelta.Apply(filepath.Join(s.dir, "old.snap"), filepath.Join(s.dir, "new.delta"), filepath.Join(s.dir, "target.snap"))
+	c.Assert(err, ErrorMatches, `cannot apply delta: cannot find a working xdelta3, it is needed to create and apply deltas`)
+	c.Check(errors.Is(err, snapdelta.ErrNoXdelta3), Equals, true)
+}
+
+func (s *deltaSuite) TestSystemSnapXdelta3(c *C) {
+	hostXdelta3 := s.mockXdelta3(c)
+	snapXdelta3 := testutil.MockCommand(c, "snap-xdelta3", `
+if [ "$3" = config ]; then exit 0; fi
+for arg; do out="$arg"; done
+echo snap-delta > "$out"
+`)
+	s.AddCleanup(snapXdelta3.Restore)
+
+	s.AddCleanup(snapdelta.MockCommandFromSystemSnap(func(name string, args ...string) (*exec.Cmd, error) {
+		c.Check(name, Equals, "/usr/bin/xdelta3")
+		// mimic the interpreter prefix set up for commands of the
+		// system snap
+		return exec.Command(snapXdelta3.Exe(), append([]string{"--library-path", "/snap/core/lib"}, args...)...), nil
+	}))
+
+	sourcePath := filepath.Join(s.dir, "old.snap")
+	deltaPath := filepath.Join(s.dir, "new.delta")
+	targetPath := filepath.Join(s.dir, "target.snap")
+	err := snapdelta.Apply(sourcePath, deltaPath, targetPath)
+	c.Assert(err, IsNil)
+	c.Check(targetPath, testutil.FileEquals, "snap-delta\n")
+	c.Check(hostXdelta3.Calls(), HasLen, 0)
+	c.Check(snapXdelta3.Calls(), DeepEquals, [][]string{
+		{"snap-xdelta3", "--library-path", "/snap/core/lib", "config"},
+		{"snap-xdelta3", "--library-path", "/snap/core/lib", "-d", "-f", "-s", sourcePath, deltaPath, targetPath},
+	})
+}
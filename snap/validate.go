@@ -29,6 +29,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/snapcore/snapd/osutil"
@@ -209,9 +210,22 @@ func ValidateHook(hook *HookInfo) error {
 		}
 	}
 
+	if hook.Interval != 0 {
+		if hook.Name != "check-health" {
+			return fmt.Errorf("cannot specify an interval for hook %q", hook.Name)
+		}
+		if time.Duration(hook.Interval) < minCheckHealthInterval {
+			return fmt.Errorf("check-health hook interval must be at least %s, not %s", minCheckHealthInterval, hook.Interval)
+		}
+	}
+
 	return nil
 }
 
+// minCheckHealthInterval is the shortest interval the check-health hook can
+// ask to be run at.
+const minCheckHealthInterval = 5 * time.Minute
+
 // ValidateAlias checks if a string can be used as an alias name.
 func ValidateAlias(alias string) error {
 	return naming.ValidateAlias(alias)
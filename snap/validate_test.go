@@ -25,6 +25,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	. "gopkg.in/check.v1"
@@ -34,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate"
 	. "github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timeout"
 )
 
 type ValidateSuite struct {
@@ -175,6 +177,15 @@ func (s *ValidateSuite) TestValidateHook(c *C) {
 	}
 }
 
+func (s *ValidateSuite) TestValidateHookInterval(c *C) {
+	err := ValidateHook(&HookInfo{Name: "check-health", Interval: timeout.Timeout(time.Hour)})
+	c.Check(err, IsNil)
+	err = ValidateHook(&HookInfo{Name: "check-health", Interval: timeout.Timeout(time.Minute)})
+	c.Check(err, ErrorMatches, `check-health hook interval must be at least 5m0s, not 1m0s`)
+	err = ValidateHook(&HookInfo{Name: "configure", Interval: timeout.Timeout(time.Hour)})
+	c.Check(err, ErrorMatches, `cannot specify an interval for hook "configure"`)
+}
+
 // ValidateApp
 
 func (s *ValidateSuite) TestValidateAppSockets(c *C) {
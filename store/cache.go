@@ -20,11 +20,14 @@
 package store
 
 import (
+	"crypto"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
+	"sync"
 	"syscall"
 	"time"
 
@@ -33,7 +36,10 @@ import (
 )
 
 // overridden in the unit tests
-var osRemove = os.Remove
+var (
+	osRemove = os.Remove
+	timeNow  = time.Now
+)
 
 // downloadCache is the interface that a store download cache must provide
 type downloadCache interface {
@@ -64,10 +70,51 @@ func (s changesByMtime) Len() int           { return len(s) }
 func (s changesByMtime) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s changesByMtime) Less(i, j int) bool { return s[i].ModTime().Before(s[j].ModTime()) }
 
+// CacheLimits holds the limits of the download cache which apply in
+// addition to its maximum number of items, and its optional secondary
+// directory.
+type CacheLimits struct {
+	// MaxSize is the maximum total size in bytes of the files which are only
+	// referenced by the cache, or 0 for no limit.
+	MaxSize int64
+	// MaxAge is the time after which files only referenced by the cache are
+	// removed if they weren't used, or 0 for no limit.
+	MaxAge time.Duration
+	// SecondaryDir is a read-only cache directory, for example shared over
+	// NFS by several systems, which is looked up when a download is not in
+	// the cache. Its files are named after their SHA3-384 digest, which is
+	// verified before they are used.
+	SecondaryDir string
+}
+
+// CacheStats holds the statistics of the download cache since it was
+// created.
+type CacheStats struct {
+	// Hits is the number of downloads found in the cache.
+	Hits int64 `json:"hits"`
+	// SecondaryHits is the number of downloads found in the secondary
+	// directory.
+	SecondaryHits int64 `json:"secondary-hits"`
+	// Misses is the number of downloads found in neither.
+	Misses int64 `json:"misses"`
+	// BytesSaved is the total size of the downloads found in the cache or
+	// the secondary directory.
+	BytesSaved int64 `json:"bytes-saved"`
+	// Items and Size are the current number and total size of the files in
+	// the cache.
+	Items int   `json:"items"`
+	Size  int64 `json:"size"`
+}
+
 // cacheManager implements a downloadCache via content based hard linking
 type CacheManager struct {
 	cacheDir string
 	maxItems int
+	// limits returns the additional limits of the cache, it may be nil
+	limits func() (*CacheLimits, error)
+
+	statsMu sync.Mutex
+	stats   CacheStats
 }
 
 // NewCacheManager returns a new CacheManager with the given cacheDir
@@ -82,6 +129,12 @@ type CacheManager struct {
 //  5. If cache dir has more than maxItems entries, remove oldest mtimes
 //     until it has maxItems
 //
+// If limits are set, entries older than the maximum age are removed as well,
+// as are the oldest entries until the cache is under its maximum size. Only
+// entries which are not hardlinked anywhere else count towards the limits.
+// If a secondary directory is set, it's looked up before downloading and any
+// file found there is copied into the target location and into the cache.
+//
 // The caching part is done here, the downloading happens in the store.go
 // code.
 func NewCacheManager(cacheDir string, maxItems int) *CacheManager {
@@ -91,8 +144,25 @@ func NewCacheManager(cacheDir string, maxItems int) *CacheManager {
 	}
 }
 
+// currentLimits returns the limits of the cache, if any.
+func (cm *CacheManager) currentLimits() *CacheLimits {
+	if cm.limits == nil {
+		return &CacheLimits{}
+	}
+	limits, err := cm.limits()
+	if err != nil {
+		logger.Noticef("cannot get download cache limits: %v", err)
+		return &CacheLimits{}
+	}
+	if limits == nil {
+		return &CacheLimits{}
+	}
+	return limits
+}
+
 // GetPath returns the full path of the given content in the cache
-// or empty string
+// or empty string. The secondary directory is not looked up, since its
+// content must be verified before being used.
 func (cm *CacheManager) GetPath(cacheKey string) string {
 	if _, err := os.Stat(cm.path(cacheKey)); os.IsNotExist(err) {
 		return ""
@@ -104,16 +174,119 @@ func (cm *CacheManager) GetPath(cacheKey string) string {
 // true if a cached file was moved to targetPath or if one was already there.
 func (cm *CacheManager) Get(cacheKey, targetPath string) bool {
 	if err := os.Link(cm.path(cacheKey), targetPath); err != nil && !errors.Is(err, os.ErrExist) {
+		if cm.getFromSecondary(cacheKey, targetPath) {
+			return true
+		}
+		cm.recordMiss()
 		return false
 	}
 
 	logger.Debugf("using cache for %s", targetPath)
-	now := time.Now()
+	now := timeNow()
 	// the modification time is updated on a best-effort basis
 	_ = os.Chtimes(targetPath, now, now)
+	cm.recordHit(targetPath, false)
 	return true
 }
 
+// getFromSecondary copies the given cacheKey content from the secondary
+// directory into targetPath, if it's there and its SHA3-384 digest matches
+// the cacheKey. The copy is then added to the cache.
+func (cm *CacheManager) getFromSecondary(cacheKey, targetPath string) bool {
+	secondaryDir := cm.currentLimits().SecondaryDir
+	if secondaryDir == "" || cacheKey == "" {
+		return false
+	}
+
+	src, err := os.Open(filepath.Join(secondaryDir, cacheKey))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Noticef("cannot use secondary download cache: %v", err)
+		}
+		return false
+	}
+	defer src.Close()
+
+	dst, err := osutil.NewAtomicFile(targetPath, 0600, 0, osutil.NoChown, osutil.NoChown)
+	if err != nil {
+		logger.Noticef("cannot use secondary download cache: %v", err)
+		return false
+	}
+	defer dst.Cancel()
+
+	h := crypto.SHA3_384.New()
+	if _, err := io.Copy(io.MultiWriter(dst, h), src); err != nil {
+		logger.Noticef("cannot copy %s from secondary download cache: %v", cacheKey, err)
+		return false
+	}
+	if actual := fmt.Sprintf("%x", h.Sum(nil)); actual != cacheKey {
+		logger.Noticef("cannot use %s from secondary download cache: digest mismatch (got %s)", cacheKey, actual)
+		return false
+	}
+	if err := dst.Commit(); err != nil {
+		logger.Noticef("cannot copy %s from secondary download cache: %v", cacheKey, err)
+		return false
+	}
+
+	logger.Debugf("using secondary cache for %s", targetPath)
+	cm.recordHit(targetPath, true)
+	if err := cm.Put(cacheKey, targetPath); err != nil {
+		logger.Noticef("cannot add %s to download cache: %v", cacheKey, err)
+	}
+	return true
+}
+
+func (cm *CacheManager) recordHit(targetPath string, secondary bool) {
+	var size int64
+	if fi, err := os.Stat(targetPath); err == nil {
+		size = fi.Size()
+	}
+
+	cm.statsMu.Lock()
+	defer cm.statsMu.Unlock()
+	if secondary {
+		cm.stats.SecondaryHits++
+	} else {
+		cm.stats.Hits++
+	}
+	cm.stats.BytesSaved += size
+}
+
+func (cm *CacheManager) recordMiss() {
+	cm.statsMu.Lock()
+	defer cm.statsMu.Unlock()
+	cm.stats.Misses++
+}
+
+// Stats returns the statistics of the cache since it was created, along with
+// its current usage.
+func (cm *CacheManager) Stats() (*CacheStats, error) {
+	cm.statsMu.Lock()
+	stats := cm.stats
+	cm.statsMu.Unlock()
+
+	entries, err := os.ReadDir(cm.cacheDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &stats, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		fi, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// removed by a cleanup in the meantime
+				continue
+			}
+			return nil, err
+		}
+		stats.Items++
+		stats.Size += fi.Size()
+	}
+	return &stats, nil
+}
+
 // Put adds a new file to the cache with the given cacheKey
 func (cm *CacheManager) Put(cacheKey, sourcePath string) error {
 	// always try to create the cache dir first or the following
@@ -127,7 +300,7 @@ func (cm *CacheManager) Put(cacheKey, sourcePath string) error {
 
 	err := os.Link(sourcePath, cm.path(cacheKey))
 	if os.IsExist(err) {
-		now := time.Now()
+		now := timeNow()
 		err := os.Chtimes(cm.path(cacheKey), now, now)
 		// this can happen if a cleanup happens in parallel, ie.
 		// the file was there but cleanup() removed it between
@@ -158,14 +331,16 @@ func (cm *CacheManager) path(cacheKey string) string {
 	return filepath.Join(cm.cacheDir, cacheKey)
 }
 
-// cleanup ensures that only maxItems are stored in the cache
+// cleanup ensures that only maxItems are stored in the cache and that it's
+// within its size and age limits, if any
 func (cm *CacheManager) cleanup() error {
 	entries, err := os.ReadDir(cm.cacheDir)
 	if err != nil {
 		return err
 	}
 
-	if len(entries) <= cm.maxItems {
+	limits := cm.currentLimits()
+	if len(entries) <= cm.maxItems && limits.MaxSize == 0 && limits.MaxAge == 0 {
 		return nil
 	}
 
@@ -190,9 +365,13 @@ func (cm *CacheManager) cleanup() error {
 		}
 	}
 
-	if len(pruneCandidates) <= cm.maxItems {
-		// nothing to prune
-		return nil
+	var size int64
+	for _, fi := range pruneCandidates {
+		size += fi.Size()
+	}
+	var cutoff time.Time
+	if limits.MaxAge > 0 {
+		cutoff = timeNow().Add(-limits.MaxAge)
 	}
 
 	var lastErr error
@@ -200,6 +379,15 @@ func (cm *CacheManager) cleanup() error {
 	numOwned := len(pruneCandidates)
 	deleted := 0
 	for _, fi := range pruneCandidates {
+		tooMany := numOwned-deleted > cm.maxItems
+		tooBig := limits.MaxSize > 0 && size > limits.MaxSize
+		// candidates are sorted from the oldest so once one isn't expired
+		// none of the following is
+		expired := !cutoff.IsZero() && fi.ModTime().Before(cutoff)
+		if !tooMany && !tooBig && !expired {
+			break
+		}
+
 		path := cm.path(fi.Name())
 		if err := osRemove(path); err != nil {
 			if !os.IsNotExist(err) {
@@ -211,9 +399,7 @@ func (cm *CacheManager) cleanup() error {
 			continue
 		}
 		deleted++
-		if numOwned-deleted <= cm.maxItems {
-			break
-		}
+		size -= fi.Size()
 	}
 	return lastErr
 }
@@ -20,10 +20,12 @@
 package store_test
 
 import (
+	"crypto"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -182,6 +184,126 @@ func (s *cacheSuite) TestCleanupContinuesOnError(c *C) {
 	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[0])), Equals, true)
 }
 
+func (s *cacheSuite) TestCleanupMaxSize(c *C) {
+	s.cm.SetLimits(func() (*store.CacheLimits, error) {
+		return &store.CacheLimits{MaxSize: 3}, nil
+	})
+
+	// each file is a single byte
+	cacheKeys, testFiles := s.makeTestFiles(c, 4)
+	for _, p := range testFiles {
+		c.Assert(os.Remove(p), IsNil)
+	}
+	c.Assert(s.cm.Cleanup(), IsNil)
+
+	// the oldest file is removed to get under the size limit, even though
+	// there are fewer files than the maximum
+	c.Check(s.cm.Count(), Equals, 3)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[0])), Equals, false)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[1])), Equals, true)
+}
+
+func (s *cacheSuite) TestCleanupMaxAge(c *C) {
+	s.cm.SetLimits(func() (*store.CacheLimits, error) {
+		return &store.CacheLimits{MaxAge: time.Hour}, nil
+	})
+
+	cacheKeys, testFiles := s.makeTestFiles(c, 3)
+	for _, p := range testFiles {
+		c.Assert(os.Remove(p), IsNil)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	c.Assert(os.Chtimes(filepath.Join(s.cm.CacheDir(), cacheKeys[1]), old, old), IsNil)
+
+	// files still referenced elsewhere are kept even if old
+	shared := s.makeTestFile(c, "shared", "shared")
+	c.Assert(s.cm.Put("shared-key", shared), IsNil)
+	c.Assert(os.Chtimes(filepath.Join(s.cm.CacheDir(), "shared-key"), old, old), IsNil)
+
+	c.Assert(s.cm.Cleanup(), IsNil)
+	c.Check(s.cm.Count(), Equals, 3)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[1])), Equals, false)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), "shared-key")), Equals, true)
+}
+
+func (s *cacheSuite) TestGetFromSecondary(c *C) {
+	secondaryDir := c.MkDir()
+	s.cm.SetLimits(func() (*store.CacheLimits, error) {
+		return &store.CacheLimits{SecondaryDir: secondaryDir}, nil
+	})
+
+	content := "some snap content"
+	h := crypto.SHA3_384.New()
+	h.Write([]byte(content))
+	digest := fmt.Sprintf("%x", h.Sum(nil))
+	s.makeTestFileInDir(c, secondaryDir, digest, content)
+
+	targetPath := filepath.Join(s.tmp, "new-location")
+	c.Check(s.cm.Get(digest, targetPath), Equals, true)
+	c.Check(targetPath, testutil.FileEquals, content)
+
+	// the file was added to the cache too
+	c.Check(filepath.Join(s.cm.CacheDir(), digest), testutil.FileEquals, content)
+	stats, err := s.cm.Stats()
+	c.Assert(err, IsNil)
+	c.Check(stats, DeepEquals, &store.CacheStats{
+		SecondaryHits: 1,
+		BytesSaved:    int64(len(content)),
+		Items:         1,
+		Size:          int64(len(content)),
+	})
+
+	// and is used from there the next time
+	otherPath := filepath.Join(s.tmp, "other-location")
+	c.Check(s.cm.Get(digest, otherPath), Equals, true)
+	stats, err = s.cm.Stats()
+	c.Assert(err, IsNil)
+	c.Check(stats.Hits, Equals, int64(1))
+	c.Check(stats.SecondaryHits, Equals, int64(1))
+	c.Check(stats.BytesSaved, Equals, int64(2*len(content)))
+}
+
+func (s *cacheSuite) TestGetFromSecondaryDigestMismatch(c *C) {
+	secondaryDir := c.MkDir()
+	s.cm.SetLimits(func() (*store.CacheLimits, error) {
+		return &store.CacheLimits{SecondaryDir: secondaryDir}, nil
+	})
+
+	digest := strings.Repeat("0", 96)
+	s.makeTestFileInDir(c, secondaryDir, digest, "corrupted content")
+
+	targetPath := filepath.Join(s.tmp, "new-location")
+	c.Check(s.cm.Get(digest, targetPath), Equals, false)
+	c.Check(osutil.FileExists(targetPath), Equals, false)
+	c.Check(s.cm.Count(), Equals, 0)
+
+	stats, err := s.cm.Stats()
+	c.Assert(err, IsNil)
+	c.Check(stats, DeepEquals, &store.CacheStats{Misses: 1})
+}
+
+func (s *cacheSuite) TestStats(c *C) {
+	stats, err := s.cm.Stats()
+	c.Assert(err, IsNil)
+	c.Check(stats, DeepEquals, &store.CacheStats{})
+
+	p := s.makeTestFile(c, "foo", "some content")
+	c.Assert(s.cm.Put("some-cache-key", p), IsNil)
+
+	c.Check(s.cm.Get("some-cache-key", filepath.Join(s.tmp, "target")), Equals, true)
+	c.Check(s.cm.Get("other-cache-key", filepath.Join(s.tmp, "other-target")), Equals, false)
+
+	stats, err = s.cm.Stats()
+	c.Assert(err, IsNil)
+	c.Check(stats, DeepEquals, &store.CacheStats{
+		Hits:       1,
+		Misses:     1,
+		BytesSaved: int64(len("some content")),
+		Items:      1,
+		Size:       int64(len("some content")),
+	})
+}
+
 func (s *cacheSuite) TestHardLinkCount(c *C) {
 	p := filepath.Join(s.tmp, "foo")
 	err := os.WriteFile(p, nil, 0644)
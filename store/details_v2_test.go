@@ -428,6 +428,7 @@ func (s *detailsV2Suite) TestInfoFromStoreSnap(c *C) {
 		"Layout",
 		"SideInfo.Channel",
 		"LegacyWebsite",
+		"RefreshHealthRollback",
 	}
 	var checker func(string, reflect.Value)
 	checker = func(pfx string, x reflect.Value) {
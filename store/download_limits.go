@@ -0,0 +1,115 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/juju/ratelimit"
+)
+
+// ErrDownloadBudgetExhausted is returned by Download when the budget of the
+// download has been used up before it completed.
+var ErrDownloadBudgetExhausted = errors.New("download budget exhausted")
+
+// RateScheduler provides the rate limit of downloads whose rate depends on
+// the time of day.
+type RateScheduler interface {
+	// RateLimit returns the rate limit in bytes per second at the given
+	// time, 0 meaning no limit.
+	RateLimit(now time.Time) int64
+}
+
+// DownloadBudget bounds the number of bytes downloads fetch from the
+// network.
+type DownloadBudget interface {
+	// Remaining returns how many more bytes can be downloaded.
+	Remaining() int64
+	// Consume records that n bytes were downloaded.
+	Consume(n int64)
+}
+
+// rateScheduleInterval is how often the rate limit of a download using a
+// RateScheduler is re-evaluated.
+var rateScheduleInterval = 1 * time.Minute
+
+// scheduledRateReader reads from r at the rate given by its schedule.
+type scheduledRateReader struct {
+	r     io.Reader
+	sched RateScheduler
+
+	limited   io.Reader
+	limit     int64
+	checkedAt time.Time
+}
+
+func (sr *scheduledRateReader) Read(p []byte) (int, error) {
+	now := timeNow()
+	if sr.limited == nil || now.Sub(sr.checkedAt) >= rateScheduleInterval {
+		sr.checkedAt = now
+		limit := sr.sched.RateLimit(now)
+		if sr.limited == nil || limit != sr.limit {
+			sr.limit = limit
+			sr.limited = sr.r
+			if limit > 0 {
+				bucket := ratelimit.NewBucketWithRate(float64(limit), 2*limit)
+				sr.limited = ratelimitReader(sr.r, bucket)
+			}
+		}
+	}
+	return sr.limited.Read(p)
+}
+
+// budgetReader reads from r as long as its budget allows.
+type budgetReader struct {
+	r      io.Reader
+	budget DownloadBudget
+}
+
+func (br *budgetReader) Read(p []byte) (int, error) {
+	remaining := br.budget.Remaining()
+	if remaining <= 0 {
+		return 0, ErrDownloadBudgetExhausted
+	}
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := br.r.Read(p)
+	br.budget.Consume(int64(n))
+	return n, err
+}
+
+// limitedDownloadReader applies the rate limit, rate schedule and budget of
+// the download options to the reading of r.
+func limitedDownloadReader(r io.Reader, dlOpts *DownloadOptions) io.Reader {
+	switch {
+	case dlOpts.RateSchedule != nil:
+		r = &scheduledRateReader{r: r, sched: dlOpts.RateSchedule}
+	case dlOpts.RateLimit > 0:
+		bucket := ratelimit.NewBucketWithRate(float64(dlOpts.RateLimit), 2*dlOpts.RateLimit)
+		r = ratelimitReader(r, bucket)
+	}
+	if dlOpts.Budget != nil {
+		r = &budgetReader{r: r, budget: dlOpts.Budget}
+	}
+	return r
+}
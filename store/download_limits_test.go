@@ -0,0 +1,191 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juju/ratelimit"
+	"golang.org/x/crypto/sha3"
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type rateSchedulerFunc func(now time.Time) int64
+
+func (f rateSchedulerFunc) RateLimit(now time.Time) int64 {
+	return f(now)
+}
+
+type testBudget struct {
+	remaining int64
+	consumed  int64
+}
+
+func (b *testBudget) Remaining() int64 {
+	return b.remaining
+}
+
+func (b *testBudget) Consume(n int64) {
+	b.remaining -= n
+	b.consumed += n
+}
+
+func (s *downloadSuite) TestActualDownloadRateScheduled(c *C) {
+	var rates []float64
+	restore := store.MockRatelimitReader(func(r io.Reader, bucket *ratelimit.Bucket) io.Reader {
+		rates = append(rates, bucket.Rate())
+		return r
+	})
+	defer restore()
+	// the rate is evaluated for every read
+	restore = store.MockRateScheduleInterval(0)
+	defer restore()
+
+	chunk := strings.Repeat("downloaded data ", 16)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 4; i++ {
+			io.WriteString(w, chunk)
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	calls := 0
+	sched := rateSchedulerFunc(func(now time.Time) int64 {
+		calls++
+		if calls == 1 {
+			// unlimited at first
+			return 0
+		}
+		return 100000
+	})
+
+	theStore := store.New(&store.Config{}, nil)
+	var buf SillyBuffer
+	err := store.Download(context.TODO(), "example-name", "", ts.URL, nil, theStore, &buf, 0, nil, &store.DownloadOptions{RateSchedule: sched, RateLimit: 1})
+	c.Assert(err, IsNil)
+	c.Check(buf.String(), Equals, strings.Repeat(chunk, 4))
+	c.Check(calls > 1, Equals, true)
+	// the schedule takes precedence over the constant rate limit, and the
+	// rate limiter is only set up again when the rate changes
+	c.Check(rates, DeepEquals, []float64{100000})
+}
+
+func (s *downloadSuite) TestActualDownloadBudget(c *C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "downloaded data")
+	}))
+	defer ts.Close()
+
+	theStore := store.New(&store.Config{}, nil)
+	var buf SillyBuffer
+	budget := &testBudget{remaining: 10}
+	err := store.Download(context.TODO(), "example-name", "", ts.URL, nil, theStore, &buf, 0, nil, &store.DownloadOptions{Budget: budget})
+	c.Assert(err, Equals, store.ErrDownloadBudgetExhausted)
+	c.Check(buf.String(), Equals, "downloaded")
+	c.Check(budget.consumed, Equals, int64(10))
+	c.Check(budget.remaining, Equals, int64(0))
+}
+
+func (s *downloadSuite) TestDownloadBudgetResume(c *C) {
+	content := "snap data downloaded in two sessions"
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "snap", time.Time{}, strings.NewReader(content))
+	}))
+	defer ts.Close()
+
+	info := &snap.DownloadInfo{
+		DownloadURL: ts.URL,
+		Size:        int64(len(content)),
+		Sha3_384:    fmt.Sprintf("%x", sha3.Sum384([]byte(content))),
+	}
+	path := filepath.Join(c.MkDir(), "foo.snap")
+	theStore := store.New(&store.Config{}, nil)
+
+	budget := &testBudget{remaining: 9}
+	dlOpts := &store.DownloadOptions{Budget: budget, LeavePartialOnError: true}
+	err := theStore.Download(context.TODO(), "foo", path, info, nil, nil, dlOpts)
+	c.Assert(err, Equals, store.ErrDownloadBudgetExhausted)
+	c.Check(path+".partial", testutil.FileEquals, "snap data")
+	c.Check(path, testutil.FileAbsent)
+
+	// the next day
+	budget.remaining = 100
+	err = theStore.Download(context.TODO(), "foo", path, info, nil, nil, dlOpts)
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, content)
+	c.Check(path+".partial", testutil.FileAbsent)
+	c.Check(ranges, DeepEquals, []string{"", "bytes=9-"})
+	c.Check(budget.consumed, Equals, int64(len(content)))
+}
+
+func (s *downloadSuite) TestDownloadBudgetHashErrorRetry(c *C) {
+	content := "snap data"
+	n := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if n == 1 {
+			io.WriteString(w, "snap dada")
+			return
+		}
+		io.WriteString(w, content)
+	}))
+	defer ts.Close()
+
+	info := &snap.DownloadInfo{
+		DownloadURL: ts.URL,
+		Size:        int64(len(content)),
+		Sha3_384:    fmt.Sprintf("%x", sha3.Sum384([]byte(content))),
+	}
+	path := filepath.Join(c.MkDir(), "foo.snap")
+	theStore := store.New(&store.Config{}, nil)
+
+	budget := &testBudget{remaining: 100}
+	err := theStore.Download(context.TODO(), "foo", path, info, nil, nil, &store.DownloadOptions{Budget: budget})
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+	c.Check(path, testutil.FileEquals, content)
+	// the download from scratch after the digest mismatch counts too
+	c.Check(budget.consumed, Equals, int64(2*len(content)))
+
+	// and is stopped once the budget is used up
+	n = 0
+	budget.remaining = int64(len(content) + 4)
+	c.Assert(os.Remove(path), IsNil)
+	err = theStore.Download(context.TODO(), "foo", path, info, nil, nil, &store.DownloadOptions{Budget: budget})
+	c.Assert(err, Equals, store.ErrDownloadBudgetExhausted)
+	c.Check(n, Equals, 2)
+	c.Check(budget.remaining, Equals, int64(0))
+}
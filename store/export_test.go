@@ -34,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snapdelta"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -69,11 +70,7 @@ var (
 )
 
 func MockSnapdtoolCommandFromSystemSnap(f func(name string, args ...string) (*exec.Cmd, error)) (restore func()) {
-	old := commandFromSystemSnap
-	commandFromSystemSnap = f
-	return func() {
-		commandFromSystemSnap = old
-	}
+	return snapdelta.MockCommandFromSystemSnap(f)
 }
 
 // MockDefaultRetryStrategy mocks the retry strategy used by several store requests
@@ -112,6 +109,17 @@ func MockDownloadSpeedParams(measureWindow time.Duration, minSpeed float64) (res
 	}
 }
 
+func MockPeerSpeedParams(measureWindow time.Duration, minSpeed float64) (restore func()) {
+	oldSpeedMeasureWindow := peerSpeedMeasureWindow
+	oldSpeedMin := peerSpeedMin
+	peerSpeedMeasureWindow = measureWindow
+	peerSpeedMin = minSpeed
+	return func() {
+		peerSpeedMeasureWindow = oldSpeedMeasureWindow
+		peerSpeedMin = oldSpeedMin
+	}
+}
+
 func IsTransferSpeedError(err error) (ok bool, speed float64) {
 	de, ok := err.(*transferSpeedError)
 	if !ok {
@@ -138,6 +146,14 @@ func (cm *CacheManager) Count() int {
 	return cm.count()
 }
 
+func (cm *CacheManager) SetLimits(limits func() (*CacheLimits, error)) {
+	cm.limits = limits
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	return testutil.Mock(&timeNow, f)
+}
+
 func MockOsRemove(f func(name string) error) func() {
 	oldOsRemove := osRemove
 	osRemove = f
@@ -279,3 +295,7 @@ type (
 )
 
 var ReportFetchAssertionsError = reportFetchAssertionsError
+
+func MockRateScheduleInterval(d time.Duration) (restore func()) {
+	return testutil.Mock(&rateScheduleInterval, d)
+}
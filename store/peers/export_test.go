@@ -0,0 +1,68 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peers
+
+// ServiceInstance is a PTR record pointing to a SRV record announcing a
+// peer, for tests.
+type ServiceInstance struct {
+	Instance string
+	Port     uint16
+}
+
+// MarshalAnswer returns a response announcing the given instances.
+func MarshalAnswer(instances ...ServiceInstance) []byte {
+	m := &message{response: true}
+	for _, inst := range instances {
+		name := inst.Instance + "." + ServiceName
+		m.records = append(m.records,
+			record{name: ServiceName, rtype: typePTR, target: name},
+			record{name: name, rtype: typeSRV, target: inst.Instance + ".local.", port: inst.Port})
+	}
+	return m.marshal()
+}
+
+// MarshalQuery returns a query for the given name and record type.
+func MarshalQuery(name string, qtype uint16) []byte {
+	m := &message{id: 42, questions: []question{{name: name, qtype: qtype}}}
+	return m.marshal()
+}
+
+// ParseAnswerPorts parses a response and returns the ports it announces.
+func ParseAnswerPorts(b []byte) ([]uint16, error) {
+	m, err := parseMessage(b)
+	if err != nil {
+		return nil, err
+	}
+	return m.servicePorts(), nil
+}
+
+// AsksForService parses a message and returns whether it's a query for
+// peers.
+func AsksForService(b []byte) (bool, error) {
+	m, err := parseMessage(b)
+	if err != nil {
+		return false, err
+	}
+	return m.asksForService(), nil
+}
+
+func ValidDigest(digest string) bool {
+	return validDigest(digest)
+}
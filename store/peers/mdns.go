@@ -0,0 +1,403 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peers
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+)
+
+// ServiceName is the DNS-SD service type advertised over mDNS by the snapd
+// instances sharing snaps.
+const ServiceName = "_snapd-peer._tcp.local."
+
+// MulticastAddr is the address of the IPv4 mDNS group.
+var MulticastAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+const (
+	typePTR = 12
+	typeSRV = 33
+	typeANY = 255
+
+	classIN = 1
+	// classCacheFlush is set in the class of records which are unique
+	// to their owner
+	classCacheFlush = 0x8000
+
+	recordTTL = 120
+
+	// maxLabelLen is the maximum length of a DNS label
+	maxLabelLen = 63
+)
+
+var errMalformedMessage = errors.New("malformed mDNS message")
+
+type question struct {
+	name  string
+	qtype uint16
+}
+
+// record is a PTR or SRV resource record, the only ones used to find
+// peers.
+type record struct {
+	name  string
+	rtype uint16
+	// target is the name pointed to by a PTR record, or the host of a SRV
+	// record
+	target string
+	// port is the port of a SRV record
+	port uint16
+}
+
+type message struct {
+	id        uint16
+	response  bool
+	questions []question
+	records   []record
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+func (m *message) marshal() []byte {
+	b := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(b[0:], m.id)
+	if m.response {
+		// response, authoritative answer
+		binary.BigEndian.PutUint16(b[2:], 0x8400)
+	}
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.records)))
+
+	for _, q := range m.questions {
+		b = appendName(b, q.name)
+		b = appendUint16(b, q.qtype)
+		b = appendUint16(b, classIN)
+	}
+	for _, r := range m.records {
+		class := uint16(classIN)
+		var rdata []byte
+		switch r.rtype {
+		case typePTR:
+			rdata = appendName(nil, r.target)
+		case typeSRV:
+			class |= classCacheFlush
+			// priority and weight
+			rdata = appendUint16(appendUint16(nil, 0), 0)
+			rdata = appendUint16(rdata, r.port)
+			rdata = appendName(rdata, r.target)
+		}
+		b = appendName(b, r.name)
+		b = appendUint16(b, r.rtype)
+		b = appendUint16(b, class)
+		b = appendUint32(b, recordTTL)
+		b = appendUint16(b, uint16(len(rdata)))
+		b = append(b, rdata...)
+	}
+	return b
+}
+
+// readName reads the possibly compressed name at the given offset of the
+// message, and returns it along with the offset following it.
+func readName(b []byte, off int) (name string, next int, err error) {
+	var labels []string
+	next = -1
+	for jumps := 0; ; {
+		if off >= len(b) {
+			return "", 0, errMalformedMessage
+		}
+		l := int(b[off])
+		switch {
+		case l == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case l&0xC0 == 0xC0:
+			if off+1 >= len(b) {
+				return "", 0, errMalformedMessage
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			// guard against pointer loops
+			jumps++
+			if jumps > 16 {
+				return "", 0, errMalformedMessage
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3FFF)
+		case l&0xC0 != 0:
+			return "", 0, errMalformedMessage
+		default:
+			if off+1+l > len(b) {
+				return "", 0, errMalformedMessage
+			}
+			labels = append(labels, string(b[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+}
+
+// parseMessage parses a mDNS message, keeping only its questions and its
+// PTR and SRV records.
+func parseMessage(b []byte) (*message, error) {
+	if len(b) < 12 {
+		return nil, errMalformedMessage
+	}
+	m := &message{
+		id:       binary.BigEndian.Uint16(b[0:]),
+		response: b[2]&0x80 != 0,
+	}
+	qdCount := int(binary.BigEndian.Uint16(b[4:]))
+	rrCount := int(binary.BigEndian.Uint16(b[6:])) + int(binary.BigEndian.Uint16(b[8:])) + int(binary.BigEndian.Uint16(b[10:]))
+
+	off := 12
+	for i := 0; i < qdCount; i++ {
+		name, next, err := readName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(b) {
+			return nil, errMalformedMessage
+		}
+		m.questions = append(m.questions, question{
+			name:  name,
+			qtype: binary.BigEndian.Uint16(b[next:]),
+		})
+		off = next + 4
+	}
+	for i := 0; i < rrCount; i++ {
+		name, next, err := readName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+10 > len(b) {
+			return nil, errMalformedMessage
+		}
+		rtype := binary.BigEndian.Uint16(b[next:])
+		rdLen := int(binary.BigEndian.Uint16(b[next+8:]))
+		off = next + 10
+		if off+rdLen > len(b) {
+			return nil, errMalformedMessage
+		}
+		r := record{name: name, rtype: rtype}
+		switch rtype {
+		case typePTR:
+			if r.target, _, err = readName(b, off); err != nil {
+				return nil, err
+			}
+			m.records = append(m.records, r)
+		case typeSRV:
+			if rdLen < 7 {
+				return nil, errMalformedMessage
+			}
+			r.port = binary.BigEndian.Uint16(b[off+4:])
+			if r.target, _, err = readName(b, off+6); err != nil {
+				return nil, err
+			}
+			m.records = append(m.records, r)
+		}
+		off += rdLen
+	}
+	return m, nil
+}
+
+// asksForService returns whether the message is a query for the peers.
+func (m *message) asksForService() bool {
+	if m.response {
+		return false
+	}
+	for _, q := range m.questions {
+		if (q.qtype == typePTR || q.qtype == typeANY) && strings.EqualFold(q.name, ServiceName) {
+			return true
+		}
+	}
+	return false
+}
+
+// Responder answers the mDNS queries for ServiceName with the port snaps
+// are shared on.
+type Responder struct {
+	conn     net.PacketConn
+	instance string
+	port     int
+}
+
+// NewResponder returns a Responder answering the queries received on conn
+// with the given instance name, typically the host name, and port.
+func NewResponder(conn net.PacketConn, instance string, port int) *Responder {
+	// the instance name is a single label
+	instance = strings.ReplaceAll(instance, ".", "-")
+	if len(instance) > maxLabelLen {
+		instance = instance[:maxLabelLen]
+	}
+	if instance == "" {
+		instance = "snapd"
+	}
+	return &Responder{
+		conn:     conn,
+		instance: instance,
+		port:     port,
+	}
+}
+
+func (r *Responder) answer(id uint16) []byte {
+	instanceName := r.instance + "." + ServiceName
+	m := &message{
+		id:       id,
+		response: true,
+		records: []record{
+			{name: ServiceName, rtype: typePTR, target: instanceName},
+			{name: instanceName, rtype: typeSRV, target: r.instance + ".local.", port: uint16(r.port)},
+		},
+	}
+	return m.marshal()
+}
+
+// Serve answers the queries received until the connection is closed. The
+// answers are sent back to the address of the query, as for legacy unicast
+// queries.
+func (r *Responder) Serve() error {
+	buf := make([]byte, 9000)
+	for {
+		n, addr, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		m, err := parseMessage(buf[:n])
+		if err != nil || !m.asksForService() {
+			continue
+		}
+		if _, err := r.conn.WriteTo(r.answer(m.id), addr); err != nil {
+			logger.Debugf("cannot answer mDNS query from %s: %v", addr, err)
+		}
+	}
+}
+
+// ListenMulticast returns a connection receiving the mDNS queries sent to
+// MulticastAddr.
+func ListenMulticast() (net.PacketConn, error) {
+	return net.ListenMulticastUDP("udp4", nil, MulticastAddr)
+}
+
+// Discover sends a query for ServiceName to addr over conn and returns the
+// addresses, as host:port, of the peers which answered before the context
+// is done. The host of a peer is the address its answer came from.
+func Discover(ctx context.Context, conn net.PacketConn, addr net.Addr) ([]string, error) {
+	query := &message{
+		id:        uint16(rand.Intn(1<<16-1) + 1),
+		questions: []question{{name: ServiceName, qtype: typePTR}},
+	}
+	if _, err := conn.WriteTo(query.marshal(), addr); err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	found := make(map[string]bool)
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+		udpAddr, ok := from.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		m, err := parseMessage(buf[:n])
+		if err != nil || !m.response {
+			continue
+		}
+		for _, port := range m.servicePorts() {
+			found[net.JoinHostPort(udpAddr.IP.String(), strconv.Itoa(int(port)))] = true
+		}
+	}
+
+	peers := make([]string, 0, len(found))
+	for peer := range found {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+	return peers, nil
+}
+
+// servicePorts returns the ports of the service instances announced in the
+// message.
+func (m *message) servicePorts() []uint16 {
+	instances := make(map[string]bool)
+	for _, r := range m.records {
+		if r.rtype == typePTR && strings.EqualFold(r.name, ServiceName) {
+			instances[strings.ToLower(r.target)] = true
+		}
+	}
+	var ports []uint16
+	for _, r := range m.records {
+		if r.rtype == typeSRV && instances[strings.ToLower(r.name)] && r.port != 0 {
+			ports = append(ports, r.port)
+		}
+	}
+	return ports
+}
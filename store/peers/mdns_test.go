@@ -0,0 +1,155 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peers_test
+
+import (
+	"context"
+	"net"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/store/peers"
+)
+
+type mdnsSuite struct{}
+
+var _ = Suite(&mdnsSuite{})
+
+func (s *mdnsSuite) TestAnswerRoundTrip(c *C) {
+	b := peers.MarshalAnswer(peers.ServiceInstance{Instance: "host-a", Port: 7380}, peers.ServiceInstance{Instance: "host-b", Port: 8000})
+	ports, err := peers.ParseAnswerPorts(b)
+	c.Assert(err, IsNil)
+	c.Check(ports, DeepEquals, []uint16{7380, 8000})
+
+	asks, err := peers.AsksForService(b)
+	c.Assert(err, IsNil)
+	c.Check(asks, Equals, false)
+}
+
+func (s *mdnsSuite) TestAsksForService(c *C) {
+	for _, t := range []struct {
+		name  string
+		qtype uint16
+		asks  bool
+	}{
+		{peers.ServiceName, 12, true},
+		{"_SNAPD-PEER._tcp.local.", 12, true},
+		{peers.ServiceName, 255, true},
+		{peers.ServiceName, 1, false},
+		{"_http._tcp.local.", 12, false},
+	} {
+		asks, err := peers.AsksForService(peers.MarshalQuery(t.name, t.qtype))
+		c.Assert(err, IsNil)
+		c.Check(asks, Equals, t.asks, Commentf("%s %d", t.name, t.qtype))
+	}
+}
+
+func (s *mdnsSuite) TestParseCompressedNames(c *C) {
+	b := []byte{
+		0, 0, 0x84, 0, // id, flags
+		0, 0, 0, 2, 0, 0, 0, 0, // counts
+	}
+	// PTR _snapd-peer._tcp.local. -> host._snapd-peer._tcp.local.
+	ptrOwner := len(b)
+	b = append(b, 11)
+	b = append(b, "_snapd-peer"...)
+	b = append(b, 4)
+	b = append(b, "_tcp"...)
+	b = append(b, 5)
+	b = append(b, "local"...)
+	b = append(b, 0)
+	b = append(b, 0, 12, 0, 1, 0, 0, 0, 120, 0, 7)
+	instance := len(b)
+	b = append(b, 4)
+	b = append(b, "host"...)
+	b = append(b, 0xC0, byte(ptrOwner))
+	// SRV host._snapd-peer._tcp.local. -> host.local.:1234
+	b = append(b, 0xC0, byte(instance))
+	b = append(b, 0, 33, 0x80, 1, 0, 0, 0, 120, 0, 8)
+	b = append(b, 0, 0, 0, 0, 0x04, 0xD2)
+	b = append(b, 0xC0, byte(instance))
+
+	ports, err := peers.ParseAnswerPorts(b)
+	c.Assert(err, IsNil)
+	c.Check(ports, DeepEquals, []uint16{1234})
+}
+
+func (s *mdnsSuite) TestParseMalformed(c *C) {
+	good := peers.MarshalAnswer(peers.ServiceInstance{Instance: "host", Port: 7380})
+	for i := 0; i < len(good); i++ {
+		// truncated messages are rejected rather than crashing
+		_, err := peers.ParseAnswerPorts(good[:i])
+		c.Check(err, ErrorMatches, "malformed mDNS message", Commentf("%d", i))
+	}
+
+	// pointer loop
+	loop := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0xC0, 12, 0, 12, 0, 1}
+	_, err := peers.AsksForService(loop)
+	c.Check(err, ErrorMatches, "malformed mDNS message")
+}
+
+func (s *mdnsSuite) TestResponderDiscover(c *C) {
+	var responders []*net.UDPConn
+	var addrs []net.Addr
+	for _, port := range []int{7380, 8000} {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		c.Assert(err, IsNil)
+		responders = append(responders, conn)
+		addrs = append(addrs, conn.LocalAddr())
+
+		done := make(chan error, 1)
+		r := peers.NewResponder(conn, "some.host", port)
+		go func() { done <- r.Serve() }()
+		defer func() {
+			conn.Close()
+			c.Check(<-done, IsNil)
+		}()
+	}
+
+	client, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	c.Assert(err, IsNil)
+	defer client.Close()
+
+	var found []string
+	for _, addr := range addrs {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		peerAddrs, err := peers.Discover(ctx, client, addr)
+		cancel()
+		c.Assert(err, IsNil)
+		found = append(found, peerAddrs...)
+	}
+	c.Check(found, DeepEquals, []string{"127.0.0.1:7380", "127.0.0.1:8000"})
+}
+
+func (s *mdnsSuite) TestDiscoverCancel(c *C) {
+	client, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	c.Assert(err, IsNil)
+	defer client.Close()
+	silent, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	c.Assert(err, IsNil)
+	defer silent.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	found, err := peers.Discover(ctx, client, silent.LocalAddr())
+	c.Assert(err, IsNil)
+	c.Check(found, HasLen, 0)
+}
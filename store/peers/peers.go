@@ -0,0 +1,114 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package peers implements the sharing of downloaded snaps between snapd
+// instances on the local network.
+//
+// Each instance sharing snaps serves files of its download cache, which are
+// named after their SHA3-384 digest, over HTTP, and can advertise itself
+// over mDNS. Since the digest of a snap is known from the store before it's
+// downloaded, and is verified once it's been fetched, peers do not need to
+// be trusted.
+package peers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultPort is the TCP port snaps are shared on by default.
+const DefaultPort = 7380
+
+const blobsPath = "/v1/blobs/"
+
+// BlobURL returns the URL of the blob with the given SHA3-384 digest on the
+// peer at the given host:port address.
+func BlobURL(addr, sha3_384 string) string {
+	return "http://" + addr + blobsPath + sha3_384
+}
+
+// Handler returns an http.Handler serving, under /v1/blobs/<digest>, the
+// files of dir which are named after their SHA3-384 digest and for which
+// shared returns true.
+func Handler(dir string, shared func(path string) bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(blobsPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		digest := strings.TrimPrefix(r.URL.Path, blobsPath)
+		if !validDigest(digest) {
+			http.NotFound(w, r)
+			return
+		}
+		path := filepath.Join(dir, digest)
+		if !shared(path) {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, path)
+	})
+	return mux
+}
+
+// validDigest returns whether the given string is a hex encoded SHA3-384
+// digest, which also makes it a safe file name.
+func validDigest(digest string) bool {
+	if len(digest) != 96 {
+		return false
+	}
+	for _, c := range digest {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseStaticPeers parses a comma separated list of peers given as host or
+// host:port, and returns their host:port addresses. DefaultPort is used for
+// the peers given without a port.
+func ParseStaticPeers(list string) ([]string, error) {
+	var addrs []string
+	for _, peer := range strings.Split(list, ",") {
+		peer = strings.TrimSpace(peer)
+		if peer == "" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(peer)
+		if err != nil {
+			// a host without port, possibly an IPv6 address
+			host, port = strings.Trim(peer, "[]"), strconv.Itoa(DefaultPort)
+		}
+		if host == "" || strings.ContainsAny(host, "/[] ") {
+			return nil, fmt.Errorf("invalid peer %q", peer)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("invalid port of peer %q", peer)
+		}
+		addrs = append(addrs, net.JoinHostPort(host, port))
+	}
+	return addrs, nil
+}
@@ -0,0 +1,116 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peers_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/store/peers"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type peersSuite struct{}
+
+var _ = Suite(&peersSuite{})
+
+var testDigest = strings.Repeat("0123456789abcdef", 6)
+
+func (s *peersSuite) TestBlobURL(c *C) {
+	c.Check(peers.BlobURL("192.168.1.2:7380", testDigest), Equals, "http://192.168.1.2:7380/v1/blobs/"+testDigest)
+}
+
+func (s *peersSuite) TestValidDigest(c *C) {
+	c.Check(peers.ValidDigest(testDigest), Equals, true)
+	c.Check(peers.ValidDigest(strings.ToUpper(testDigest)), Equals, false)
+	c.Check(peers.ValidDigest(testDigest[1:]), Equals, false)
+	c.Check(peers.ValidDigest("../"+testDigest[3:]), Equals, false)
+	c.Check(peers.ValidDigest(""), Equals, false)
+}
+
+func (s *peersSuite) TestHandler(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, testDigest), []byte("snap data"), 0644), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "other"), []byte("not shared"), 0644), IsNil)
+	private := strings.Repeat("f", 96)
+	c.Assert(os.WriteFile(filepath.Join(dir, private), []byte("private snap"), 0644), IsNil)
+
+	srv := httptest.NewServer(peers.Handler(dir, func(path string) bool {
+		return path != filepath.Join(dir, private)
+	}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	resp, err := http.Get(peers.BlobURL(addr, testDigest))
+	c.Assert(err, IsNil)
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Check(resp.StatusCode, Equals, 200)
+	c.Check(string(data), Equals, "snap data")
+
+	for _, path := range []string{
+		"/v1/blobs/" + strings.Repeat("e", 96),
+		"/v1/blobs/" + private,
+		"/v1/blobs/other",
+		"/v1/blobs/",
+		"/other",
+	} {
+		resp, err := http.Get(srv.URL + path)
+		c.Assert(err, IsNil, Commentf(path))
+		resp.Body.Close()
+		c.Check(resp.StatusCode, Equals, 404, Commentf(path))
+	}
+
+	resp, err = http.Post(peers.BlobURL(addr, testDigest), "text/plain", nil)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Check(resp.StatusCode, Equals, 405)
+	c.Check(resp.Header.Get("Allow"), Equals, "GET, HEAD")
+}
+
+func (s *peersSuite) TestParseStaticPeers(c *C) {
+	addrs, err := peers.ParseStaticPeers("")
+	c.Assert(err, IsNil)
+	c.Check(addrs, HasLen, 0)
+
+	addrs, err = peers.ParseStaticPeers("10.0.0.2:8000, host-b ,fe80::1,[fe80::2]:9000,")
+	c.Assert(err, IsNil)
+	c.Check(addrs, DeepEquals, []string{"10.0.0.2:8000", "host-b:7380", "[fe80::1]:7380", "[fe80::2]:9000"})
+
+	for _, list := range []string{
+		"host:0",
+		"host:port",
+		"host:65536",
+		":8000",
+		"http://host",
+	} {
+		_, err := peers.ParseStaticPeers(list)
+		c.Check(err, ErrorMatches, `invalid (port of )?peer .*`, Commentf(list))
+	}
+}
@@ -45,6 +45,7 @@ import (
 	"github.com/snapcore/snapd/httputil"
 	"github.com/snapcore/snapd/jsonutil"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/metrics"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/release"
@@ -70,6 +71,15 @@ const (
 
 var requestTimeout = 10 * time.Second
 
+var (
+	storeRequests = metrics.NewCounter("snapd_store_requests_total",
+		"Number of requests to the store which got a response, by method and status code.", "method", "code")
+	storeRequestErrors = metrics.NewCounter("snapd_store_request_errors_total",
+		"Number of requests to the store which failed without a response, by method.", "method")
+	storeDownloadBytes = metrics.NewCounter("snapd_store_download_bytes_total",
+		"Number of bytes downloaded from the store.")
+)
+
 // the LimitTime should be slightly more than 3 times of our http.Client
 // Timeout value
 var defaultRetryStrategy = retry.LimitCount(6, retry.LimitTime(38*time.Second,
@@ -111,10 +121,18 @@ type Config struct {
 
 	// CacheDownloads is the number of downloads that should be cached
 	CacheDownloads int
+	// CacheLimits returns the additional limits of the download cache and
+	// its secondary directory, if any
+	CacheLimits func() (*CacheLimits, error)
 
 	// Proxy returns the HTTP proxy to use when talking to the store
 	Proxy func(*http.Request) (*url.URL, error)
 
+	// Peers returns the host:port addresses of the peers on the local
+	// network that snaps are first downloaded from, before falling back
+	// to the store
+	Peers func() []string
+
 	// AssertionMaxFormats if set provides a way to override
 	// the assertion max formats sent to the store as supported.
 	AssertionMaxFormats map[string]int
@@ -739,8 +757,10 @@ func (s *Store) doRequest(ctx context.Context, client *http.Client, reqOptions *
 
 		resp, err := client.Do(req)
 		if err != nil {
+			storeRequestErrors.Inc(req.Method)
 			return nil, err
 		}
+		storeRequests.Inc(req.Method, strconv.Itoa(resp.StatusCode))
 
 		if resp.StatusCode == 401 && authRefreshes < 4 {
 			// 4 tries: 2 tries for each in case both user
@@ -30,7 +30,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -49,11 +48,9 @@ import (
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
-	"github.com/snapcore/snapd/snapdtool"
+	"github.com/snapcore/snapd/snap/snapdelta"
 )
 
-var commandFromSystemSnap = snapdtool.CommandFromSystemSnap
-
 var downloadRetryStrategy = retry.LimitCount(7, retry.LimitTime(90*time.Second,
 	retry.Exponential{
 		Initial: 500 * time.Millisecond,
@@ -107,55 +104,12 @@ func (s *Store) useDeltas() (use bool) {
 
 	// TODO: have a per-format checker instead, we currently only support
 	// xdelta3 as a format for deltas
-
-	// check if the xdelta3 config command works from the system snap
-	cmd, err := commandFromSystemSnap("/usr/bin/xdelta3", "config")
-	if err == nil {
-		// we have a xdelta3 from the system snap, make sure it works
-		if runErr := cmd.Run(); runErr == nil {
-			// success using the system snap provided one, setup the callback to
-			// use the cmd we got from CommandFromSystemSnap, but with a small
-			// tweak - this cmd to run xdelta3 from the system snap will likely
-			// have other arguments and a different main exe usually, so
-			// use it exactly as we got it from CommandFromSystemSnap,
-			// but drop the last arg which we know is "config"
-			exe := cmd.Path
-			args := cmd.Args[:len(cmd.Args)-1]
-			env := cmd.Env
-			dir := cmd.Dir
-			s.xdelta3CmdFunc = func(xDelta3args ...string) *exec.Cmd {
-				return &exec.Cmd{
-					Path: exe,
-					Args: append(args, xDelta3args...),
-					Env:  env,
-					Dir:  dir,
-				}
-			}
-			return true
-		} else {
-			logger.Noticef("unable to use system snap provided xdelta3, running config command failed: %v", runErr)
-		}
-	}
-
-	// we didn't have one from a system snap or it didn't work, fallback to
-	// trying xdelta3 from the system
-	loc, err := exec.LookPath("xdelta3")
+	xdelta3CmdFunc, err := snapdelta.Xdelta3Cmd()
 	if err != nil {
-		// no xdelta3 in the env, so no deltas
-		logger.Noticef("no host system xdelta3 available to use deltas")
-		return false
-	}
-
-	if err := exec.Command(loc, "config").Run(); err != nil {
-		// xdelta3 in the env failed to run, so no deltas
-		logger.Noticef("unable to use host system xdelta3, running config command failed: %v", err)
+		logger.Noticef("cannot use deltas: %v", err)
 		return false
 	}
-
-	// the xdelta3 in the env worked, so use that one
-	s.xdelta3CmdFunc = func(args ...string) *exec.Cmd {
-		return exec.Command(loc, args...)
-	}
+	s.xdelta3CmdFunc = xdelta3CmdFunc
 	return true
 }
 
@@ -185,7 +139,15 @@ func (e HashError) Error() string {
 }
 
 type DownloadOptions struct {
-	RateLimit           int64
+	RateLimit int64
+	// RateSchedule, if set, provides the rate limit as the download
+	// progresses, instead of RateLimit.
+	RateSchedule RateScheduler
+	// Budget, if set, bounds the bytes downloaded from the network. Once
+	// it's used up the download fails with ErrDownloadBudgetExhausted.
+	// Like the rate limits, it doesn't apply to snaps fetched from peers on
+	// the local network.
+	Budget              DownloadBudget
 	Scheduled           bool
 	LeavePartialOnError bool
 }
@@ -211,6 +173,15 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		return nil
 	}
 
+	if err := s.downloadFromPeers(ctx, name, targetPath, downloadInfo, pbar); err == nil {
+		if err := s.cacher.Put(downloadInfo.Sha3_384, targetPath); err != nil {
+			logger.Noticef("Cannot place blob for %s downloaded from a peer in cache: %v", name, err)
+		}
+		return nil
+	} else if err != errNoPeerHasBlob {
+		return err
+	}
+
 	if s.useDeltas() {
 		logger.Debugf("Available deltas returned by store: %v", downloadInfo.Deltas)
 
@@ -290,7 +261,7 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		if err != nil {
 			return err
 		}
-		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, 0, pbar, nil)
+		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, 0, pbar, dlOpts)
 		if err != nil {
 			logger.Debugf("download of %q failed: %#v", url, err)
 		}
@@ -630,16 +601,13 @@ func downloadImpl(ctx context.Context, name, sha3_384, downloadURL string, user
 		}
 		pbar.Start(name, dlSize)
 		mw := io.MultiWriter(w, h, pbar, tc)
-		var limiter io.Reader
-		limiter = resp.Body
-		if limit := dlOpts.RateLimit; limit > 0 {
-			bucket := ratelimit.NewBucketWithRate(float64(limit), 2*limit)
-			limiter = ratelimitReader(resp.Body, bucket)
-		}
+		limiter := limitedDownloadReader(resp.Body, dlOpts)
 
 		stopMonitorCh := tc.Monitor()
-		_, finalErr = io.Copy(mw, limiter)
+		var n int64
+		n, finalErr = io.Copy(mw, limiter)
 		close(stopMonitorCh)
+		storeDownloadBytes.Add(float64(n))
 		pbar.Finished()
 
 		if err := tc.Err(); err != nil {
@@ -972,8 +940,20 @@ func (s *Store) CacheDownloads() int {
 func (s *Store) SetCacheDownloads(fileCount int) {
 	s.cfg.CacheDownloads = fileCount
 	if fileCount > 0 {
-		s.cacher = NewCacheManager(dirs.SnapDownloadCacheDir, fileCount)
+		cm := NewCacheManager(dirs.SnapDownloadCacheDir, fileCount)
+		cm.limits = s.cfg.CacheLimits
+		s.cacher = cm
 	} else {
 		s.cacher = &nullCache{}
 	}
 }
+
+// CacheStats returns the statistics of the download cache, or nil if
+// downloads are not cached.
+func (s *Store) CacheStats() (*CacheStats, error) {
+	cm, ok := s.cacher.(*CacheManager)
+	if !ok {
+		return nil, nil
+	}
+	return cm.Stats()
+}
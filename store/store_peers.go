@@ -0,0 +1,154 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store/peers"
+)
+
+var (
+	peerDialTimeout     = 2 * time.Second
+	peerResponseTimeout = 5 * time.Second
+
+	// peers are on the local network, so they are expected to be fast; a
+	// peer that is slower than peerSpeedMin over peerSpeedMeasureWindow is
+	// given up on, so that the snap can still be fetched from the store
+	peerSpeedMeasureWindow = 30 * time.Second
+	peerSpeedMin           = float64(64 * 1024)
+)
+
+var errNoPeerHasBlob = errors.New("no peer has the snap")
+
+func newPeerHTTPClient() *http.Client {
+	// peers are on the local network, so the store proxy does not apply
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           (&net.Dialer{Timeout: peerDialTimeout}).DialContext,
+			ResponseHeaderTimeout: peerResponseTimeout,
+		},
+	}
+}
+
+// downloadFromPeers tries to fetch the snap addressed by download info from
+// the peers returned by Config.Peers, in order, into targetPath. What is
+// fetched from a peer is only kept if it has the expected size and
+// SHA3-384 digest, the same digest that is then checked against the
+// snap-revision assertion of the snap. Peers are not used if the size of
+// the snap is unknown, as nothing would bound what they send. The rate
+// limits and budget of the download options are meant for the connection to
+// the store and are not applied to the local network.
+func (s *Store) downloadFromPeers(ctx context.Context, name, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter) error {
+	if s.cfg.Peers == nil || downloadInfo.Sha3_384 == "" || downloadInfo.Size <= 0 {
+		return errNoPeerHasBlob
+	}
+	peerAddrs := s.cfg.Peers()
+	if len(peerAddrs) == 0 {
+		return errNoPeerHasBlob
+	}
+	if pbar == nil {
+		pbar = progress.Null
+	}
+
+	client := newPeerHTTPClient()
+	defer client.CloseIdleConnections()
+	for _, addr := range peerAddrs {
+		err := downloadFromPeer(ctx, client, addr, name, targetPath, downloadInfo, pbar)
+		if err == nil {
+			logger.Noticef("Downloaded %s from peer %s.", name, addr)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger.Debugf("Cannot download %s from peer %s: %v", name, addr, err)
+	}
+	return errNoPeerHasBlob
+}
+
+func downloadFromPeer(ctx context.Context, client *http.Client, addr, name, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter) (err error) {
+	tc, peerCtx := NewTransferSpeedMonitoringWriterAndContext(ctx, peerSpeedMeasureWindow, peerSpeedMin)
+	req, err := http.NewRequestWithContext(peerCtx, "GET", peers.BlobURL(addr, downloadInfo.Sha3_384), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength >= 0 && resp.ContentLength != downloadInfo.Size {
+		return fmt.Errorf("unexpected size %d, expected %d", resp.ContentLength, downloadInfo.Size)
+	}
+
+	peerPath := targetPath + ".peer"
+	w, err := os.OpenFile(peerPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(peerPath)
+		}
+	}()
+
+	h := crypto.SHA3_384.New()
+	// do not let a peer fill up the disk
+	body := io.LimitReader(resp.Body, downloadInfo.Size+1)
+	pbar.Start(name, float64(downloadInfo.Size))
+	quit := tc.Monitor()
+	n, err := io.Copy(io.MultiWriter(w, h, pbar, tc), body)
+	close(quit)
+	pbar.Finished()
+	if err != nil {
+		if tc.Err() != nil {
+			return tc.Err()
+		}
+		return err
+	}
+	if n != downloadInfo.Size {
+		return fmt.Errorf("unexpected size %d, expected %d", n, downloadInfo.Size)
+	}
+	if actualSha3 := fmt.Sprintf("%x", h.Sum(nil)); actualSha3 != downloadInfo.Sha3_384 {
+		return HashError{name, actualSha3, downloadInfo.Sha3_384}
+	}
+	if err := w.Sync(); err != nil {
+		return err
+	}
+	return os.Rename(peerPath, targetPath)
+}
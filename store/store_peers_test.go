@@ -0,0 +1,235 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/peers"
+	"github.com/snapcore/snapd/testutil"
+)
+
+// mockPeer serves the given content as the blob with the given digest, and
+// returns its host:port address.
+func (s *storeDownloadSuite) mockPeer(c *C, digest string, content []byte) string {
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, digest), content, 0644), IsNil)
+	srv := httptest.NewServer(peers.Handler(dir, func(string) bool { return true }))
+	s.AddCleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func (s *storeDownloadSuite) unreachablePeer(c *C) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func (s *storeDownloadSuite) TestDownloadFromPeer(c *C) {
+	content := []byte("snap from a peer")
+	digest := fmt.Sprintf("%x", sha3.Sum384(content))
+
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Fatal("unexpected download from the store")
+		return nil
+	})
+	defer restore()
+
+	peerAddrs := []string{
+		s.unreachablePeer(c),
+		// a peer without the blob
+		s.mockPeer(c, strings.Repeat("f", 96), content),
+		s.mockPeer(c, digest, content),
+	}
+	sto := store.New(&store.Config{Peers: func() []string { return peerAddrs }}, nil)
+
+	info := &snap.Info{}
+	info.RealName = "foo"
+	info.DownloadURL = "URL"
+	info.Sha3_384 = digest
+	info.Size = int64(len(content))
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(s.ctx, "foo", path, &info.DownloadInfo, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, content)
+	c.Check(path+".peer", testutil.FileAbsent)
+	c.Check(s.logbuf.String(), testutil.Contains, fmt.Sprintf("Downloaded foo from peer %s.", peerAddrs[2]))
+}
+
+func (s *storeDownloadSuite) TestDownloadFromPeersFallsBackToStore(c *C) {
+	content := []byte("snap from the store")
+	digest := fmt.Sprintf("%x", sha3.Sum384(content))
+
+	downloaded := 0
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		downloaded++
+		c.Check(url, Equals, "URL")
+		w.Write(content)
+		return nil
+	})
+	defer restore()
+
+	peerAddrs := []string{
+		// the content does not match the digest
+		s.mockPeer(c, digest, []byte("tampered snap")),
+		// the content is too long
+		s.mockPeer(c, digest, append(content, "and more"...)),
+		s.unreachablePeer(c),
+	}
+	sto := store.New(&store.Config{Peers: func() []string { return peerAddrs }}, nil)
+
+	info := &snap.Info{}
+	info.RealName = "foo"
+	info.DownloadURL = "URL"
+	info.Sha3_384 = digest
+	info.Size = int64(len(content))
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(s.ctx, "foo", path, &info.DownloadInfo, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(downloaded, Equals, 1)
+	c.Check(path, testutil.FileEquals, content)
+	c.Check(path+".peer", testutil.FileAbsent)
+}
+
+func (s *storeDownloadSuite) TestDownloadFromPeersNoPeers(c *C) {
+	content := []byte("snap from the store")
+
+	downloaded := 0
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		downloaded++
+		w.Write(content)
+		return nil
+	})
+	defer restore()
+
+	sto := store.New(&store.Config{Peers: func() []string { return nil }}, nil)
+
+	info := &snap.Info{}
+	info.RealName = "foo"
+	info.DownloadURL = "URL"
+	info.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384(content))
+	info.Size = int64(len(content))
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(s.ctx, "foo", path, &info.DownloadInfo, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(downloaded, Equals, 1)
+	c.Check(path, testutil.FileEquals, content)
+}
+
+func (s *storeDownloadSuite) TestDownloadFromPeersUnknownSize(c *C) {
+	content := []byte("snap from the store")
+
+	downloaded := 0
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		downloaded++
+		w.Write(content)
+		return nil
+	})
+	defer restore()
+
+	sto := store.New(&store.Config{Peers: func() []string {
+		c.Fatal("unexpected use of peers")
+		return nil
+	}}, nil)
+
+	info := &snap.Info{}
+	info.RealName = "foo"
+	info.DownloadURL = "URL"
+	info.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384(content))
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(s.ctx, "foo", path, &info.DownloadInfo, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(downloaded, Equals, 1)
+	c.Check(path, testutil.FileEquals, content)
+}
+
+func (s *storeDownloadSuite) TestDownloadFromSlowPeerFallsBackToStore(c *C) {
+	restore := store.MockPeerSpeedParams(200*time.Millisecond, 1024)
+	defer restore()
+
+	content := []byte("snap from the store")
+	digest := fmt.Sprintf("%x", sha3.Sum384(content))
+
+	downloaded := 0
+	restore = store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		downloaded++
+		w.Write(content)
+		return nil
+	})
+	defer restore()
+
+	// a peer trickling the snap a byte at a time
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		for _, b := range content {
+			w.Write([]byte{b})
+			w.(http.Flusher).Flush()
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				return
+			case <-done:
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	peerAddrs := []string{strings.TrimPrefix(srv.URL, "http://")}
+	sto := store.New(&store.Config{Peers: func() []string { return peerAddrs }}, nil)
+
+	info := &snap.Info{}
+	info.RealName = "foo"
+	info.DownloadURL = "URL"
+	info.Sha3_384 = digest
+	info.Size = int64(len(content))
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(s.ctx, "foo", path, &info.DownloadInfo, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(downloaded, Equals, 1)
+	c.Check(path, testutil.FileEquals, content)
+	c.Check(path+".peer", testutil.FileAbsent)
+	c.Check(s.logbuf.String(), testutil.Contains, "download too slow")
+}
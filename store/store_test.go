@@ -32,6 +32,7 @@ import (
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -49,6 +50,7 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/httputil"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/metrics"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
@@ -479,6 +481,46 @@ func (s *storeTestSuite) TestDoRequestSetsAuth(c *C) {
 	c.Check(string(responseData), Equals, "response-data")
 }
 
+// metricValue returns the value of the given metric sample, or 0 if it was
+// not reported yet.
+func metricValue(c *C, sample string) float64 {
+	var buf bytes.Buffer
+	c.Assert(metrics.Default.WriteText(&buf), IsNil)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, sample+" "); ok {
+			v, err := strconv.ParseFloat(value, 64)
+			c.Assert(err, IsNil)
+			return v
+		}
+	}
+	return 0
+}
+
+func (s *storeTestSuite) TestDoRequestMetrics(c *C) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(418)
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	sto := store.New(&store.Config{}, nil)
+	endpoint, _ := url.Parse(mockServer.URL)
+
+	requests := metricValue(c, `snapd_store_requests_total{method="POST",code="418"}`)
+	reqErrors := metricValue(c, `snapd_store_request_errors_total{method="POST"}`)
+
+	response, err := sto.DoRequest(s.ctx, sto.Client(), store.NewRequestOptions("POST", endpoint), nil)
+	c.Assert(err, IsNil)
+	response.Body.Close()
+	c.Check(metricValue(c, `snapd_store_requests_total{method="POST",code="418"}`), Equals, requests+1)
+
+	mockServer.Close()
+	_, err = sto.DoRequest(s.ctx, sto.Client(), store.NewRequestOptions("POST", endpoint), nil)
+	c.Assert(err, NotNil)
+	c.Check(metricValue(c, `snapd_store_requests_total{method="POST",code="418"}`), Equals, requests+1)
+	c.Check(metricValue(c, `snapd_store_request_errors_total{method="POST"}`), Equals, reqErrors+1)
+}
+
 func (s *storeTestSuite) TestDoRequestDoesNotSetAuthForLocalOnlyUser(c *C) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		c.Check(r.UserAgent(), Equals, userAgent)
@@ -449,6 +449,9 @@ type Systemd interface {
 	// threads if enabled, etc) part of the unit, which can be a service or a
 	// slice.
 	CurrentTasksCount(unit string) (uint64, error)
+	// CurrentCPUUsage returns the CPU time consumed so far by the specified
+	// unit.
+	CurrentCPUUsage(unit string) (time.Duration, error)
 	// Run a command
 	Run(command []string, opts *RunOptions) ([]byte, error)
 	// Set log level for the system
@@ -934,6 +937,19 @@ func (s *systemd) CurrentMemoryUsage(unit string) (quantity.Size, error) {
 	return quantity.Size(memBytes), nil
 }
 
+func (s *systemd) CurrentCPUUsage(unit string) (time.Duration, error) {
+	cpuNSec, err := s.getPropertyUintValue(unit, "CPUUsageNSec")
+	if err != nil && err != errNotSet {
+		return 0, err
+	}
+
+	if err == errNotSet {
+		return 0, fmt.Errorf("cpu usage unavailable")
+	}
+
+	return time.Duration(cpuNSec), nil
+}
+
 func (s *systemd) InactiveEnterTimestamp(unit string) (time.Time, error) {
 	timeStr, err := s.getPropertyStringValue(unit, "InactiveEnterTimestamp")
 	if err != nil {
@@ -2546,6 +2546,27 @@ func (s *SystemdTestSuite) TestCurrentUsageFamilyHappy(c *C) {
 	})
 }
 
+func (s *SystemdTestSuite) TestCurrentCPUUsage(c *C) {
+	s.outs = [][]byte{
+		[]byte(`CPUUsageNSec=1500000000`),
+		[]byte(`CPUUsageNSec=[not set]`),
+		[]byte(`CPUUsageNSec=lots`),
+	}
+	sysd := New(SystemMode, s.rep)
+	cpuUsage, err := sysd.CurrentCPUUsage("bar.slice")
+	c.Assert(err, IsNil)
+	c.Check(cpuUsage, Equals, 1500*time.Millisecond)
+	_, err = sysd.CurrentCPUUsage("bar.slice")
+	c.Check(err, ErrorMatches, "cpu usage unavailable")
+	_, err = sysd.CurrentCPUUsage("bar.slice")
+	c.Check(err, ErrorMatches, `invalid property value from systemd for CPUUsageNSec: cannot parse "lots" as an integer`)
+	c.Check(s.argses, DeepEquals, [][]string{
+		{"show", "--property", "CPUUsageNSec", "bar.slice"},
+		{"show", "--property", "CPUUsageNSec", "bar.slice"},
+		{"show", "--property", "CPUUsageNSec", "bar.slice"},
+	})
+}
+
 func (s *SystemdTestSuite) TestInactiveEnterTimestampZero(c *C) {
 	s.outs = [][]byte{
 		[]byte(`InactiveEnterTimestamp=`),
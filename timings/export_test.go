@@ -38,3 +38,11 @@ func MockTimeNow(nowFunc func() time.Time) func() {
 		timeNow = old
 	}
 }
+
+func MockCryptoTokenBytes(f func(nbytes int) ([]byte, error)) func() {
+	old := cryptoTokenBytes
+	cryptoTokenBytes = f
+	return func() {
+		cryptoTokenBytes = old
+	}
+}
@@ -0,0 +1,415 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package timings
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/randutil"
+)
+
+// An Exporter sends Timings to an external tracing system as they are
+// saved. Export is called with the state lock held, so implementations
+// must not block.
+type Exporter interface {
+	Export(t *Timings)
+	// Close sends any pending timings and releases the exporter.
+	Close() error
+}
+
+var (
+	exporterMu sync.Mutex
+	exporter   Exporter
+)
+
+// SetExporter sets the exporter which is given all Timings on Save, nil
+// disables exporting. It returns the previous exporter.
+func SetExporter(e Exporter) Exporter {
+	exporterMu.Lock()
+	defer exporterMu.Unlock()
+	old := exporter
+	exporter = e
+	return old
+}
+
+func currentExporter() Exporter {
+	exporterMu.Lock()
+	defer exporterMu.Unlock()
+	return exporter
+}
+
+// The types below are the subset of the OTLP/JSON trace encoding, as
+// described by the opentelemetry-proto ExportTraceServiceRequest message,
+// which is needed to represent Timings.
+
+type otlpTracesData struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource      `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope   `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID      string         `json:"traceId"`
+	SpanID       string         `json:"spanId"`
+	ParentSpanID string         `json:"parentSpanId,omitempty"`
+	Name         string         `json:"name"`
+	Kind         int            `json:"kind"`
+	StartTime    string         `json:"startTimeUnixNano"`
+	EndTime      string         `json:"endTimeUnixNano"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string        `json:"key"`
+	Value otlpAnyString `json:"value"`
+}
+
+type otlpAnyString struct {
+	StringValue string `json:"stringValue"`
+}
+
+// otlpSpanKindInternal is SPAN_KIND_INTERNAL, all timings measure work done
+// within snapd.
+const otlpSpanKindInternal = 1
+
+var cryptoTokenBytes = randutil.CryptoTokenBytes
+
+func randomID(nbytes int) (string, error) {
+	b, err := cryptoTokenBytes(nbytes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func otlpTime(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func otlpAttributes(tags map[string]string) []otlpKeyValue {
+	if len(tags) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]otlpKeyValue, len(keys))
+	for i, k := range keys {
+		attrs[i] = otlpKeyValue{Key: k, Value: otlpAnyString{StringValue: tags[k]}}
+	}
+	return attrs
+}
+
+// traceID returns the trace ID for the given Timings. Timings tagged with a
+// change-id, i.e. those of the ensure that created the change and of all its
+// tasks, share the same trace, which is derived from the change ID and the
+// given salt. Other Timings get a trace of their own.
+func traceID(t *Timings, salt []byte) (string, error) {
+	if chgID := t.tags["change-id"]; chgID != "" {
+		h := sha256.New()
+		h.Write(salt)
+		h.Write([]byte(chgID))
+		return hex.EncodeToString(h.Sum(nil)[:16]), nil
+	}
+	return randomID(16)
+}
+
+// changeSpanID returns the ID of the span standing for the change with the
+// given ID, which is derived from it and the given salt like its trace.
+func changeSpanID(chgID string, salt []byte) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte("span:"))
+	h.Write([]byte(chgID))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// otlpSpans converts the given Timings into OTLP spans. Nested spans link to
+// their parent span. Top-level spans of Timings tagged with a change-id link
+// to the span of the change, which is exported along with the Timings of the
+// ensure that created the change and spans them; other top-level spans have
+// no parent. The tags of the Timings are set as attributes on all spans, so
+// that every span can be related to its change or task on its own.
+func (t *Timings) otlpSpans(salt []byte) ([]*otlpSpan, error) {
+	trace, err := traceID(t, salt)
+	if err != nil {
+		return nil, err
+	}
+	var spans []*otlpSpan
+	var rootID string
+	if chgID := t.tags["change-id"]; chgID != "" {
+		rootID = changeSpanID(chgID, salt)
+		if _, isTask := t.tags["task-id"]; !isTask && len(t.timings) > 0 {
+			start, stop := t.timings[0].start, t.timings[0].stop
+			for _, tm := range t.timings[1:] {
+				if tm.stop.After(stop) {
+					stop = tm.stop
+				}
+			}
+			if stop.IsZero() {
+				stop = start
+			}
+			spans = append(spans, &otlpSpan{
+				TraceID:    trace,
+				SpanID:     rootID,
+				Name:       "change " + chgID,
+				Kind:       otlpSpanKindInternal,
+				StartTime:  otlpTime(start),
+				EndTime:    otlpTime(stop),
+				Attributes: otlpAttributes(t.tags),
+			})
+		}
+	}
+	var addSpans func(timings []*Span, parentID string) error
+	addSpans = func(timings []*Span, parentID string) error {
+		for _, tm := range timings {
+			id, err := randomID(8)
+			if err != nil {
+				return err
+			}
+			attrs := otlpAttributes(t.tags)
+			if tm.summary != "" {
+				attrs = append(attrs, otlpKeyValue{Key: "summary", Value: otlpAnyString{StringValue: tm.summary}})
+			}
+			stop := tm.stop
+			if stop.IsZero() {
+				// the span was never stopped
+				stop = tm.start
+			}
+			spans = append(spans, &otlpSpan{
+				TraceID:      trace,
+				SpanID:       id,
+				ParentSpanID: parentID,
+				Name:         tm.label,
+				Kind:         otlpSpanKindInternal,
+				StartTime:    otlpTime(tm.start),
+				EndTime:      otlpTime(stop),
+				Attributes:   attrs,
+			})
+			if err := addSpans(tm.timings, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := addSpans(t.timings, rootID); err != nil {
+		return nil, err
+	}
+	return spans, nil
+}
+
+// MarshalOTLP returns the OTLP/JSON encoding of the given Timings as a
+// single ExportTraceServiceRequest. The salt is used to derive the trace IDs
+// of Timings related to a change, see traceID.
+func MarshalOTLP(salt []byte, ts ...*Timings) ([]byte, error) {
+	scope := &otlpScopeSpans{
+		Scope: otlpScope{Name: "github.com/snapcore/snapd/timings"},
+		Spans: []*otlpSpan{},
+	}
+	for _, t := range ts {
+		spans, err := t.otlpSpans(salt)
+		if err != nil {
+			return nil, fmt.Errorf("cannot generate span ID: %v", err)
+		}
+		scope.Spans = append(scope.Spans, spans...)
+	}
+	data := &otlpTracesData{
+		ResourceSpans: []*otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes(map[string]string{"service.name": "snapd"}),
+			},
+			ScopeSpans: []*otlpScopeSpans{scope},
+		}},
+	}
+	return json.Marshal(data)
+}
+
+// otlpQueueSize is the number of exported Timings which may be pending
+// before further ones are dropped.
+var otlpQueueSize = 64
+
+// otlpSendTimeout bounds sending a single request to a collector.
+var otlpSendTimeout = 5 * time.Second
+
+type otlpExporter struct {
+	salt  []byte
+	send  func(payload []byte) error
+	close func() error
+
+	queue chan []byte
+	done  chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+}
+
+// NewOTLPExporter returns an Exporter writing Timings as OTLP/JSON traces to
+// the given target, which is either:
+//
+//   - file:<path>, to append one ExportTraceServiceRequest per line to the
+//     file, in the format of the OpenTelemetry collector file exporter, or
+//   - unix:<path>, to post each ExportTraceServiceRequest to the OTLP/HTTP
+//     receiver of a collector listening on the given unix socket.
+//
+// Timings are encoded when exported and sent in the background.
+func NewOTLPExporter(target string) (Exporter, error) {
+	kind, path, ok := strings.Cut(target, ":")
+	if !ok || !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid OTLP export target %q: expected file:<path> or unix:<path>", target)
+	}
+	salt, err := cryptoTokenBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	e := &otlpExporter{
+		salt:  salt,
+		queue: make(chan []byte, otlpQueueSize),
+		done:  make(chan struct{}),
+	}
+	switch kind {
+	case "file":
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open OTLP export file: %v", err)
+		}
+		e.send = func(payload []byte) error {
+			_, err := f.Write(append(payload, '\n'))
+			return err
+		}
+		e.close = f.Close
+	case "unix":
+		client := &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", path)
+				},
+			},
+			Timeout: otlpSendTimeout,
+		}
+		e.send = func(payload []byte) error {
+			rsp, err := client.Post("http://localhost/v1/traces", "application/json", bytes.NewReader(payload))
+			if err != nil {
+				return err
+			}
+			defer rsp.Body.Close()
+			if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+				return fmt.Errorf("collector responded with %q", rsp.Status)
+			}
+			return nil
+		}
+		e.close = func() error {
+			client.CloseIdleConnections()
+			return nil
+		}
+	default:
+		return nil, fmt.Errorf("invalid OTLP export target %q: expected file:<path> or unix:<path>", target)
+	}
+	go e.loop()
+	return e, nil
+}
+
+func (e *otlpExporter) loop() {
+	defer close(e.done)
+	for payload := range e.queue {
+		if err := e.send(payload); err != nil {
+			logger.Noticef("cannot export timings: %v", err)
+		}
+	}
+}
+
+// Export encodes the given Timings and queues them to be sent. Timings
+// without spans are ignored, as are Timings exported once the queue is full.
+func (e *otlpExporter) Export(t *Timings) {
+	if len(t.timings) == 0 {
+		return
+	}
+	payload, err := MarshalOTLP(e.salt, t)
+	if err != nil {
+		logger.Noticef("cannot export timings: %v", err)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.queue <- payload:
+	default:
+		e.dropped++
+	}
+}
+
+// Close sends the queued Timings and closes the target.
+func (e *otlpExporter) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	close(e.queue)
+	dropped := e.dropped
+	e.mu.Unlock()
+
+	<-e.done
+	if dropped > 0 {
+		logger.Noticef("dropped %d timings while exporting", dropped)
+	}
+	return e.close()
+}
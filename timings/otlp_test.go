@@ -0,0 +1,350 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package timings_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
+)
+
+type otlpSuite struct {
+	testutil.BaseTest
+	fakeTime time.Time
+	nextID   byte
+}
+
+var _ = Suite(&otlpSuite{})
+
+func (s *otlpSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	s.fakeTime = time.Date(2019, 3, 11, 9, 1, 0, 0, time.UTC)
+	s.AddCleanup(timings.MockTimeNow(func() time.Time {
+		s.fakeTime = s.fakeTime.Add(time.Millisecond)
+		return s.fakeTime
+	}))
+	// IDs are 1, 2, 3... repeated over the requested length
+	s.nextID = 0
+	s.AddCleanup(timings.MockCryptoTokenBytes(func(nbytes int) ([]byte, error) {
+		s.nextID++
+		return bytes.Repeat([]byte{s.nextID}, nbytes), nil
+	}))
+}
+
+func (s *otlpSuite) mockTimings() *timings.Timings {
+	troot := timings.New(map[string]string{"task-id": "3"})
+	troot.AddTag("change-id", "12")
+	meas := troot.StartSpan("doing something", "summary of something")
+	nested := meas.StartSpan("nested", "")
+	nested.Stop()
+	meas.Stop()
+	troot.StartSpan("other", "never stopped")
+	return troot
+}
+
+func (s *otlpSuite) TestMarshalOTLP(c *C) {
+	salt := []byte("salt")
+	data, err := timings.MarshalOTLP(salt, s.mockTimings())
+	c.Assert(err, IsNil)
+
+	var traces map[string]any
+	c.Assert(json.Unmarshal(data, &traces), IsNil)
+
+	tags := []any{
+		map[string]any{"key": "change-id", "value": map[string]any{"stringValue": "12"}},
+		map[string]any{"key": "task-id", "value": map[string]any{"stringValue": "3"}},
+	}
+	withSummary := func(summary string) []any {
+		return append(append([]any(nil), tags...),
+			map[string]any{"key": "summary", "value": map[string]any{"stringValue": summary}})
+	}
+	// the trace ID is derived from the salt and the change ID, and so is
+	// the ID of the span of the change, the parent of the top-level spans
+	traceID := "ed68b5515855537ab29028c7975d75cb"
+	changeSpanID := "5b945edba3cb2f34"
+	c.Check(traces, DeepEquals, map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": []any{
+				map[string]any{"key": "service.name", "value": map[string]any{"stringValue": "snapd"}},
+			}},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/snapcore/snapd/timings"},
+				"spans": []any{
+					map[string]any{
+						"traceId":           traceID,
+						"spanId":            "0101010101010101",
+						"parentSpanId":      changeSpanID,
+						"name":              "doing something",
+						"kind":              1.0,
+						"startTimeUnixNano": "1552294860001000000",
+						"endTimeUnixNano":   "1552294860004000000",
+						"attributes":        withSummary("summary of something"),
+					},
+					map[string]any{
+						"traceId":           traceID,
+						"spanId":            "0202020202020202",
+						"parentSpanId":      "0101010101010101",
+						"name":              "nested",
+						"kind":              1.0,
+						"startTimeUnixNano": "1552294860002000000",
+						"endTimeUnixNano":   "1552294860003000000",
+						"attributes":        tags,
+					},
+					map[string]any{
+						"traceId":           traceID,
+						"spanId":            "0303030303030303",
+						"parentSpanId":      changeSpanID,
+						"name":              "other",
+						"kind":              1.0,
+						"startTimeUnixNano": "1552294860005000000",
+						"endTimeUnixNano":   "1552294860005000000",
+						"attributes":        withSummary("never stopped"),
+					},
+				},
+			}},
+		}},
+	})
+}
+
+func (s *otlpSuite) TestMarshalOTLPTraceIDs(c *C) {
+	traceIDs := func(data []byte) []string {
+		var traces struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []struct {
+						TraceID string `json:"traceId"`
+					} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		c.Assert(json.Unmarshal(data, &traces), IsNil)
+		var ids []string
+		for _, span := range traces.ResourceSpans[0].ScopeSpans[0].Spans {
+			ids = append(ids, span.TraceID)
+		}
+		return ids
+	}
+
+	ensure := timings.New(map[string]string{"ensure": "auto-refresh", "change-id": "12"})
+	ensure.StartSpan("ensure", "").Stop()
+	task := timings.New(map[string]string{"task-id": "3", "change-id": "12"})
+	task.StartSpan("task", "").Stop()
+	other := timings.New(map[string]string{"startup": "load-state"})
+	other.StartSpan("startup", "").Stop()
+
+	ids := traceIDs(mustMarshal(timings.MarshalOTLP([]byte("salt"), ensure, task, other)))
+	// the ensure comes with the span of the change
+	c.Assert(ids, HasLen, 4)
+	// the ensure and task of the same change share their trace
+	c.Check(ids[0], Equals, ids[1])
+	c.Check(ids[0], Equals, ids[2])
+	// other timings get a random one
+	c.Check(ids[3], Equals, "03030303030303030303030303030303")
+
+	// a different salt gives different traces for the same change
+	ids2 := traceIDs(mustMarshal(timings.MarshalOTLP([]byte("other-salt"), task)))
+	c.Check(ids2[0], Not(Equals), ids[0])
+}
+
+func (s *otlpSuite) TestMarshalOTLPChangeSpan(c *C) {
+	ensure := timings.New(map[string]string{"ensure": "auto-refresh", "change-id": "12"})
+	ensure.StartSpan("ensure", "").Stop()
+	ensure.StartSpan("more", "").Stop()
+	task := timings.New(map[string]string{"task-id": "3", "change-id": "12"})
+	task.StartSpan("task", "").Stop()
+
+	type span struct {
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+		StartTime    string `json:"startTimeUnixNano"`
+		EndTime      string `json:"endTimeUnixNano"`
+	}
+	marshalSpans := func(t *timings.Timings) []span {
+		var traces struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []span `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		data := mustMarshal(timings.MarshalOTLP([]byte("salt"), t))
+		c.Assert(json.Unmarshal(data, &traces), IsNil)
+		return traces.ResourceSpans[0].ScopeSpans[0].Spans
+	}
+
+	// the ensure and the task are exported separately, as they are saved
+	spans := marshalSpans(ensure)
+	c.Assert(spans, HasLen, 3)
+	change := spans[0]
+	c.Check(change.Name, Equals, "change 12")
+	c.Check(change.ParentSpanID, Equals, "")
+	// the span of the change covers the ensure that created it
+	c.Check(change.StartTime, Equals, spans[1].StartTime)
+	c.Check(change.EndTime, Equals, spans[2].EndTime)
+	c.Check(spans[1].ParentSpanID, Equals, change.SpanID)
+	c.Check(spans[2].ParentSpanID, Equals, change.SpanID)
+
+	spans = marshalSpans(task)
+	c.Assert(spans, HasLen, 1)
+	c.Check(spans[0].Name, Equals, "task")
+	c.Check(spans[0].ParentSpanID, Equals, change.SpanID)
+
+	// timings not related to a change have no parent
+	other := timings.New(map[string]string{"startup": "load-state"})
+	other.StartSpan("startup", "").Stop()
+	spans = marshalSpans(other)
+	c.Assert(spans, HasLen, 1)
+	c.Check(spans[0].ParentSpanID, Equals, "")
+}
+
+func mustMarshal(data []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func (s *otlpSuite) TestFileExporter(c *C) {
+	path := filepath.Join(c.MkDir(), "traces.json")
+	exporter, err := timings.NewOTLPExporter("file:" + path)
+	c.Assert(err, IsNil)
+	restore := timings.SetExporter(exporter)
+	defer timings.SetExporter(restore)
+
+	st := state.New(nil)
+	st.Lock()
+	s.mockTimings().Save(st)
+	// timings without spans are not exported
+	timings.New(map[string]string{"change-id": "13"}).Save(st)
+	troot := timings.New(nil)
+	troot.StartSpan("short", "").Stop()
+	troot.Save(st)
+	st.Unlock()
+
+	c.Assert(exporter.Close(), IsNil)
+	// closing twice is fine
+	c.Assert(exporter.Close(), IsNil)
+
+	data, err := os.ReadFile(path)
+	c.Assert(err, IsNil)
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	c.Assert(lines, HasLen, 2)
+	var names []string
+	for _, line := range lines {
+		var traces struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []struct {
+						Name string `json:"name"`
+					} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		c.Assert(json.Unmarshal(line, &traces), IsNil)
+		for _, span := range traces.ResourceSpans[0].ScopeSpans[0].Spans {
+			names = append(names, span.Name)
+		}
+	}
+	c.Check(names, DeepEquals, []string{"doing something", "nested", "other", "short"})
+
+	// exporting after close is a no-op
+	exporter.Export(s.mockTimings())
+}
+
+func (s *otlpSuite) TestSetExporterWhileSaving(c *C) {
+	exporter, err := timings.NewOTLPExporter("file:" + filepath.Join(c.MkDir(), "traces.json"))
+	c.Assert(err, IsNil)
+	defer exporter.Close()
+	defer timings.SetExporter(timings.SetExporter(nil))
+
+	// exporting can be set up and torn down while timings are saved
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			timings.SetExporter(exporter)
+			timings.SetExporter(nil)
+		}
+	}()
+	st := state.New(nil)
+	st.Lock()
+	for i := 0; i < 10; i++ {
+		troot := timings.New(nil)
+		troot.StartSpan("short", "").Stop()
+		troot.Save(st)
+	}
+	st.Unlock()
+	<-done
+}
+
+func (s *otlpSuite) TestSocketExporter(c *C) {
+	sock := filepath.Join(c.MkDir(), "collector.sock")
+	l, err := net.Listen("unix", sock)
+	c.Assert(err, IsNil)
+
+	received := make(chan []byte, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/v1/traces")
+		c.Check(r.Header.Get("Content-Type"), Equals, "application/json")
+		body, err := io.ReadAll(r.Body)
+		c.Check(err, IsNil)
+		received <- body
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	exporter, err := timings.NewOTLPExporter("unix:" + sock)
+	c.Assert(err, IsNil)
+	defer exporter.Close()
+	exporter.Export(s.mockTimings())
+
+	select {
+	case body := <-received:
+		var traces map[string]any
+		c.Check(json.Unmarshal(body, &traces), IsNil)
+		c.Check(traces["resourceSpans"], HasLen, 1)
+	case <-time.After(testutil.HostScaledTimeout(5 * time.Second)):
+		c.Fatal("collector did not receive traces")
+	}
+}
+
+func (s *otlpSuite) TestNewOTLPExporterErrors(c *C) {
+	for _, target := range []string{"", "foo", "file:", "file:relative", "http:/foo"} {
+		_, err := timings.NewOTLPExporter(target)
+		c.Check(err, ErrorMatches, `invalid OTLP export target ".*": expected file:<path> or unix:<path>`, Commentf(target))
+	}
+
+	_, err := timings.NewOTLPExporter("file:/does/not/exist/traces.json")
+	c.Check(err, ErrorMatches, "cannot open OTLP export file: .*")
+}
@@ -70,9 +70,14 @@ MemoryAccounting=true
 		valuesTemplate := `MemoryMax=%[1]d
 # for compatibility with older versions of systemd
 MemoryLimit=%[1]d
-
 `
 		fmt.Fprintf(buf, valuesTemplate, grp.MemoryLimit)
+		if grp.MemoryHighLimit != 0 {
+			// processes are throttled above the soft limit instead of
+			// being killed, this needs cgroup v2
+			fmt.Fprintf(buf, "MemoryHigh=%d\n", grp.MemoryHighLimit)
+		}
+		buf.WriteString("\n")
 	}
 	return buf.String()
 }
@@ -90,6 +95,34 @@ TasksAccounting=true
 	return buf.String()
 }
 
+func formatIOGroupSlice(grp *quota.Group) string {
+	if grp.IOLimit == nil {
+		return ""
+	}
+	header := `# Always enable io accounting, so the following io quota options have an effect
+IOAccounting=true
+`
+	buf := bytes.NewBufferString(header)
+	if grp.IOLimit.Weight != 0 {
+		fmt.Fprintf(buf, "IOWeight=%d\n", grp.IOLimit.Weight)
+	}
+	for _, dev := range grp.IOLimit.Devices {
+		if dev.ReadBandwidth != 0 {
+			fmt.Fprintf(buf, "IOReadBandwidthMax=%s %d\n", dev.Device, dev.ReadBandwidth)
+		}
+		if dev.WriteBandwidth != 0 {
+			fmt.Fprintf(buf, "IOWriteBandwidthMax=%s %d\n", dev.Device, dev.WriteBandwidth)
+		}
+		if dev.ReadIOPS != 0 {
+			fmt.Fprintf(buf, "IOReadIOPSMax=%s %d\n", dev.Device, dev.ReadIOPS)
+		}
+		if dev.WriteIOPS != 0 {
+			fmt.Fprintf(buf, "IOWriteIOPSMax=%s %d\n", dev.Device, dev.WriteIOPS)
+		}
+	}
+	return buf.String()
+}
+
 // GenerateQuotaSliceUnitFile generates a systemd slice unit definition for the
 // specified quota group.
 func GenerateQuotaSliceUnitFile(grp *quota.Group) []byte {
@@ -98,6 +131,7 @@ func GenerateQuotaSliceUnitFile(grp *quota.Group) []byte {
 	cpuOptions := formatCpuGroupSlice(grp)
 	memoryOptions := formatMemoryGroupSlice(grp)
 	taskOptions := formatTaskGroupSlice(grp)
+	ioOptions := formatIOGroupSlice(grp)
 	template := `[Unit]
 Description=Slice for snap quota group %[1]s
 Before=slices.target
@@ -107,6 +141,6 @@ X-Snappy=yes
 `
 
 	fmt.Fprintf(&buf, template, grp.Name)
-	fmt.Fprint(&buf, cpuOptions, memoryOptions, taskOptions)
+	fmt.Fprint(&buf, cpuOptions, memoryOptions, taskOptions, ioOptions)
 	return buf.Bytes()
 }
@@ -0,0 +1,92 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2025 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package internal_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/wrappers/internal"
+)
+
+type quotaSliceGenSuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&quotaSliceGenSuite{})
+
+func (s *quotaSliceGenSuite) TestGenerateQuotaSliceUnitFileIO(c *C) {
+	grp, err := quota.NewGroup("foogroup", quota.NewResourcesBuilder().WithIOWeight(500).WithIODeviceLimits([]quota.ResourceIODevice{
+		{Device: "/dev/sda", ReadBandwidth: 10 * quantity.SizeMiB, WriteIOPS: 200},
+		{Device: "/dev/nvme0n1", WriteBandwidth: quantity.SizeMiB, ReadIOPS: 1000},
+	}).Build())
+	c.Assert(err, IsNil)
+
+	c.Check(string(internal.GenerateQuotaSliceUnitFile(grp)), Equals, `[Unit]
+Description=Slice for snap quota group foogroup
+Before=slices.target
+X-Snappy=yes
+
+[Slice]
+# Always enable cpu accounting, so the following cpu quota options have an effect
+CPUAccounting=true
+
+# Always enable memory accounting otherwise the MemoryMax setting does nothing.
+MemoryAccounting=true
+# Always enable task accounting in order to be able to count the processes/
+# threads, etc for a slice
+TasksAccounting=true
+# Always enable io accounting, so the following io quota options have an effect
+IOAccounting=true
+IOWeight=500
+IOReadBandwidthMax=/dev/sda 10485760
+IOWriteIOPSMax=/dev/sda 200
+IOWriteBandwidthMax=/dev/nvme0n1 1048576
+IOReadIOPSMax=/dev/nvme0n1 1000
+`)
+}
+
+func (s *quotaSliceGenSuite) TestGenerateQuotaSliceUnitFileMemoryHigh(c *C) {
+	grp, err := quota.NewGroup("foogroup", quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeGiB).WithMemoryHighLimit(512*quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+
+	c.Check(string(internal.GenerateQuotaSliceUnitFile(grp)), Equals, `[Unit]
+Description=Slice for snap quota group foogroup
+Before=slices.target
+X-Snappy=yes
+
+[Slice]
+# Always enable cpu accounting, so the following cpu quota options have an effect
+CPUAccounting=true
+
+# Always enable memory accounting otherwise the MemoryMax setting does nothing.
+MemoryAccounting=true
+MemoryMax=1073741824
+# for compatibility with older versions of systemd
+MemoryLimit=1073741824
+MemoryHigh=536870912
+
+# Always enable task accounting in order to be able to count the processes/
+# threads, etc for a slice
+TasksAccounting=true
+`)
+}